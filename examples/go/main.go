@@ -1,79 +1,3332 @@
 // Shield Go Integration Example
 //
 // Usage:
-//   1. Download the Shield binary for your platform
-//   2. Place it in this directory as ./shield (or ./shield.exe on Windows)
-//   3. Run: go run main.go
+//  1. Download the Shield binary for your platform
+//  2. Place it in this directory as ./shield (or ./shield.exe on Windows)
+//  3. Run: go run main.go
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
+// ApiVersions lists the protocol versions this client can speak, oldest
+// first. The binary rejects any other with error code
+// UNSUPPORTED_API_VERSION.
+var ApiVersions = []string{"1.0"}
+
 type ShieldRequest struct {
 	ApiVersion          string `json:"apiVersion"`
 	Operation           string `json:"operation"`
 	YieldId             string `json:"yieldId,omitempty"`
 	UnsignedTransaction string `json:"unsignedTransaction,omitempty"`
-	UserAddress         string `json:"userAddress,omitempty"`
+	// RawTransaction replaces UnsignedTransaction in validate requests with
+	// an RLP-encoded EVM transaction, signed or not, as 0x-prefixed hex or
+	// base64. The result's Transaction holds the fields it decoded to. On
+	// Cosmos it is a base64 TxRaw, and on Solana a serialized transaction,
+	// validated as it is.
+	RawTransaction string `json:"rawTransaction,omitempty"`
+	// AccountNumber is the signer's Cosmos account number, which its
+	// SIGN_MODE_DIRECT signature commits to. A signed Cosmos RawTransaction
+	// fails with ReasonSignatureInvalid without it.
+	AccountNumber string `json:"accountNumber,omitempty"`
+	// UserAddress must be the transaction's sender, or validation fails
+	// with reason SENDER_MISMATCH. Leaving it empty skips the check and
+	// adds a SENDER_NOT_VERIFIED warning.
+	UserAddress string `json:"userAddress,omitempty"`
+	RequestId   string `json:"requestId,omitempty"`
+	// EchoRequest has the response carry NormalizedRequest, for debugging
+	// a request that validates otherwise than expected.
+	EchoRequest bool `json:"echoRequest,omitempty"`
+	// RiskThreshold, when set (1-100), rejects otherwise valid transactions
+	// whose risk score reaches it.
+	RiskThreshold int `json:"riskThreshold,omitempty"`
+	// Policy, when set, is checked on top of Shield's built-in rules.
+	Policy *Policy `json:"policy,omitempty"`
+	// Strict rejects otherwise valid transactions that carry any warning,
+	// with reason STRICT_MODE_WARNING and the warning codes in
+	// Details.warningCodes.
+	Strict bool `json:"strict,omitempty"`
+	// StrictSeverities narrows Strict to warnings of these severities, e.g.
+	// only SeverityCritical. Every severity rejects when it is empty.
+	StrictSeverities []WarningSeverity `json:"strictSeverities,omitempty"`
+	// ExpectedAmount is the amount, in base units, the user intends to move.
+	// A transaction moving a different amount or ExpectedAmountToken, beyond
+	// AmountToleranceBps of ExpectedAmount or AmountTolerance base units,
+	// whichever is larger, fails with ReasonAmountMismatch.
+	ExpectedAmount      string `json:"expectedAmount,omitempty"`
+	ExpectedAmountToken string `json:"expectedAmountToken,omitempty"`
+	AmountToleranceBps  int    `json:"amountToleranceBps,omitempty"`
+	AmountTolerance     string `json:"amountTolerance,omitempty"`
+	// ExpectedNonce, when set, is the nonce the transaction must use, or
+	// validation fails with ReasonNonceMismatch.
+	ExpectedNonce *uint64 `json:"expectedNonce,omitempty"`
+	// IncludeTiming fills ShieldResult.Timing with where validation spent
+	// its time.
+	IncludeTiming bool `json:"includeTiming,omitempty"`
+	// IncludeSignature sets Decoded.Selector and Decoded.FunctionSignature
+	// on validate results for EVM calls. On decode, a call no ABI matches
+	// then decodes to its selector, with FunctionSignature "unknown".
+	IncludeSignature bool `json:"includeSignature,omitempty"`
+	// IncludeAudit sets Meta.Audit on validate responses, the record of the
+	// decision to retain for audits.
+	IncludeAudit bool `json:"includeAudit,omitempty"`
+	// Locale is the BCP-47 tag, e.g. "de-DE", of the language reasons,
+	// warnings and the summary should be written in. Messages Shield has
+	// no translation for stay in English; ShieldResult.Locale names the
+	// locale used.
+	Locale string `json:"locale,omitempty"`
+	// StructuredMessages keeps Reason and warning messages in English and
+	// adds ReasonMessage to the result, and with a Locale, each message
+	// in that language as MessageLocalized, errors' included.
+	StructuredMessages bool `json:"structuredMessages,omitempty"`
+	// ExpectedMemo is the memo the transaction must carry, e.g. the tag of
+	// an exchange deposit. A transaction without one fails with
+	// ReasonMissingMemo, one with another memo with ReasonMemoMismatch.
+	ExpectedMemo string `json:"expectedMemo,omitempty"`
+	// Observe never rejects: results are always valid, with what the
+	// verdict would have been in ShieldResult.WouldReject and the
+	// WouldRejectReason fields.
+	Observe bool `json:"observe,omitempty"`
+	// BeneficiaryAddress is the account a stake or deposit must credit
+	// when UserAddress, e.g. a custodian, sends it on the account's
+	// behalf. Another fails with ReasonBeneficiaryMismatch.
+	BeneficiaryAddress string `json:"beneficiaryAddress,omitempty"`
+	// ActualBytecodeHash is the keccak256 hash of the code deployed at the
+	// transaction's recipient, fetched by the caller. Where the registry
+	// pins a hash for the recipient, another fails with
+	// ReasonBytecodeMismatch, without Shield going to the network.
+	ActualBytecodeHash string `json:"actualBytecodeHash,omitempty"`
+	// LenientUnknownYield marks a YieldID this Shield does not know, e.g.
+	// one newer than the binary, with an UNKNOWN_YIELD warning. The result
+	// still fails with ReasonYieldNotFound.
+	LenientUnknownYield bool `json:"lenientUnknownYield,omitempty"`
+	// ContractOverrides maps a yield ID to the address its contract was
+	// redeployed to, for this request only. A transaction sent there is
+	// matched as if sent to the registered contract, and reports
+	// ShieldResult.ContractOverride. Each entry is logged on stderr.
+	ContractOverrides map[string]string `json:"contractOverrides,omitempty"`
+	// AddressLookupTables maps the address of each lookup table a
+	// versioned Solana transaction loads accounts from to the addresses
+	// it holds, in order. Tables left out are fetched through RpcURL, or
+	// fail the transaction with ReasonAltResolutionRequired.
+	AddressLookupTables map[string][]string `json:"addressLookupTables,omitempty"`
+	// RegistryOverride registers vaults over the built-in registry for
+	// this request only, over any of WithRegistry.
+	RegistryOverride *RegistryOverride `json:"registryOverride,omitempty"`
+	// TypedData is the EIP-712 payload of a validateTypedData request.
+	TypedData *TypedData `json:"typedData,omitempty"`
+	// Simulate executes a valid transaction with eth_call against RpcUrl
+	// and fills ShieldResult.Simulation. It is only honored by the
+	// standalone binary and adds a network round trip.
+	Simulate bool `json:"simulate,omitempty"`
+	// CheckNonce fetches UserAddress's next nonce from RpcUrl and adds a
+	// NONCE_TOO_LOW or NONCE_GAP warning when the transaction's nonce is
+	// below or above it. An unreachable node fails with
+	// ReasonNonceCheckFailed. Like Simulate, only the binary honors it.
+	CheckNonce bool `json:"checkNonce,omitempty"`
+	// ExpectedRecipientEns is the ENS name the user was shown as the
+	// recipient. It is resolved through RpcUrl, as is a transaction whose to
+	// is a name, and must resolve to the contract the transaction calls, or
+	// validation fails with ReasonRecipientEnsMismatch. Only the binary
+	// honors it.
+	ExpectedRecipientEns string `json:"expectedRecipientEns,omitempty"`
+	// RpcUrl is the node Simulate, CheckNonce and ExpectedRecipientEns go
+	// through. With it, the binary also fetches the code of each contract a
+	// transaction sends calldata to, and one without code fails with
+	// ReasonRecipientNotAContract. It also reads the staked balance an
+	// unstake draws on, and one of more fails with
+	// ReasonUnstakeExceedsBalance.
+	RpcUrl string `json:"rpcUrl,omitempty"`
+	// BlockNumber pins every EVM read through RpcUrl to that block rather
+	// than the latest, so that a validation can be repeated against the
+	// same state. The result reports it back.
+	BlockNumber *uint64 `json:"blockNumber,omitempty"`
+	// ChainId narrows a getSupportedYieldIds or detectYields request to one
+	// chain, in the format of YieldCapabilities.ChainId (e.g. "42161" for
+	// Arbitrum). A preflight request fails with ReasonChainIdMismatch when
+	// the yield is on another chain.
+	ChainId string `json:"chainId,omitempty"`
+	// IfNoneMatch is the RegistryHash of a getSupportedYieldIds result the
+	// caller holds. While the list is unchanged, the result is NotModified
+	// and carries no yields.
+	IfNoneMatch string `json:"ifNoneMatch,omitempty"`
+	// PageSize splits a getSupportedYieldIds result into pages of at most
+	// that many yields, up to 1000. PageToken is the NextPageToken of the
+	// previous page. Without a PageSize the whole list is one page.
+	PageSize  int    `json:"pageSize,omitempty"`
+	PageToken string `json:"pageToken,omitempty"`
+	// TransactionType narrows a getYieldAbi request to one transaction type.
+	TransactionType DetectedType `json:"transactionType,omitempty"`
+	// YieldIds are the yields a getYields request describes. A validate
+	// request may send them in place of YieldId: each is tried in order, and
+	// the first the transaction passes for answers, named in the result's
+	// YieldId. None passing fails with ReasonAllCandidatesFailed. They cannot
+	// be combined with RawTransaction or RpcUrl.
+	YieldIds []string `json:"yieldIds,omitempty"`
+	// UserOperation is the ERC-4337 operation of a validateUserOperation
+	// request. Paymasters lists the paymasters expected to sponsor it.
+	UserOperation *UserOperation `json:"userOperation,omitempty"`
+	Paymasters    []string       `json:"paymasters,omitempty"`
+	// SendCalls is the EIP-5792 batch of a validateSendCalls request.
+	SendCalls *SendCalls `json:"sendCalls,omitempty"`
+	// ResponseFields names the only ShieldResult fields, by JSON name, a
+	// validate response carries besides isValid, e.g. {"reasonCode"} for
+	// the smallest responses. The others are left at their zero values.
+	ResponseFields []string `json:"responseFields,omitempty"`
+	// Intent is what a compareIntent request checks UnsignedTransaction
+	// against.
+	Intent *TransactionIntent `json:"intent,omitempty"`
+	// DetectedType is the transaction type a preflight request checks, and
+	// Amount what it stakes, in base units.
+	DetectedType DetectedType `json:"detectedType,omitempty"`
+	Amount       string       `json:"amount,omitempty"`
+}
+
+// TransactionIntent is what the user asked for before the transaction was
+// built, e.g. staking 1 ETH with Lido. Amount is in base units; Amount and
+// Token are only compared when set.
+type TransactionIntent struct {
+	Action  DetectedType `json:"action"`
+	YieldId string       `json:"yieldId"`
+	Amount  string       `json:"amount,omitempty"`
+	Token   string       `json:"token,omitempty"`
+}
+
+// UserOperation is an ERC-4337 UserOperation for EntryPoint v0.6, as passed
+// to eth_sendUserOperation. Integers are hex quantities.
+type UserOperation struct {
+	Sender               string `json:"sender"`
+	Nonce                string `json:"nonce"`
+	InitCode             string `json:"initCode,omitempty"`
+	CallData             string `json:"callData"`
+	CallGasLimit         string `json:"callGasLimit,omitempty"`
+	VerificationGasLimit string `json:"verificationGasLimit,omitempty"`
+	PreVerificationGas   string `json:"preVerificationGas,omitempty"`
+	MaxFeePerGas         string `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas string `json:"maxPriorityFeePerGas,omitempty"`
+	PaymasterAndData     string `json:"paymasterAndData,omitempty"`
+	Signature            string `json:"signature,omitempty"`
+}
+
+// SendCalls is the params of an EIP-5792 wallet_sendCalls request.
+// Integers are hex quantities. From defaults to the request's UserAddress,
+// and AtomicRequired to false, which lets the wallet execute the calls one
+// by one.
+type SendCalls struct {
+	Version        string          `json:"version,omitempty"`
+	ChainId        string          `json:"chainId,omitempty"`
+	From           string          `json:"from,omitempty"`
+	AtomicRequired bool            `json:"atomicRequired"`
+	Calls          []SendCallsCall `json:"calls"`
+	Capabilities   map[string]any  `json:"capabilities,omitempty"`
+}
+
+// SendCallsCall is one call of a SendCalls batch.
+type SendCallsCall struct {
+	To           string         `json:"to"`
+	Data         string         `json:"data,omitempty"`
+	Value        string         `json:"value,omitempty"`
+	Capabilities map[string]any `json:"capabilities,omitempty"`
+}
+
+// TypedData is an EIP-712 payload, as passed to eth_signTypedData_v4.
+// Integers in Domain.ChainId and Message may be JSON numbers or strings.
+type TypedData struct {
+	Domain      TypedDataDomain             `json:"domain"`
+	Types       map[string][]TypedDataField `json:"types"`
+	PrimaryType string                      `json:"primaryType"`
+	Message     map[string]any              `json:"message"`
+}
+
+type TypedDataDomain struct {
+	Name              string `json:"name,omitempty"`
+	Version           string `json:"version,omitempty"`
+	ChainId           any    `json:"chainId,omitempty"`
+	VerifyingContract string `json:"verifyingContract,omitempty"`
+	Salt              string `json:"salt,omitempty"`
+}
+
+type TypedDataField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Policy restricts which contracts a valid transaction may call. A listed
+// blocked contract fails with reason CONTRACT_BLOCKED; with a non-empty
+// allowlist, any other contract fails with reason CONTRACT_NOT_ALLOWED.
+// BlockDelegateCall turns a DELEGATECALL_USED warning into a failure with
+// reason DELEGATECALL_BLOCKED. ERC-2771 forwarded calls are only unwrapped
+// through TrustedForwarders, and fail with reason UNTRUSTED_FORWARDER
+// through any other forwarder. Deadlines further out than
+// MaxDeadlineSeconds, a day when zero, add a LONG_DEADLINE warning.
+// With MinContractAgeSeconds and an rpcUrl, a called contract deployed
+// more recently than that adds a RECENTLY_DEPLOYED_CONTRACT warning.
+// Calldata longer than MaxCalldataBytes fails with reason
+// CALLDATA_TOO_LARGE; when zero, the limit is 8 KiB, or 128 KiB for Safe
+// transactions and multicalls. Swaps that accept more than MaxSlippageBps
+// below the output they expect, 500 when zero, or any output at all, add a
+// LOW_SLIPPAGE_PROTECTION warning. With MaxApprovalExcessBps set, a flow
+// or multicall that approves more than it then pulls, by more than that
+// share of the pull, fails with reason APPROVAL_EXCEEDS_STAKE; zero
+// requires an exact approval. AmountLimits bounds the amount a transaction
+// of each yield ID moves: more than MaxAmount fails with reason
+// AMOUNT_ABOVE_LIMIT, less than MinAmount with AMOUNT_BELOW_MINIMUM.
+type Policy struct {
+	AllowedContracts      []string `json:"allowedContracts,omitempty"`
+	BlockedContracts      []string `json:"blockedContracts,omitempty"`
+	BlockDelegateCall     bool     `json:"blockDelegateCall,omitempty"`
+	TrustedForwarders     []string `json:"trustedForwarders,omitempty"`
+	MaxDeadlineSeconds    int64    `json:"maxDeadlineSeconds,omitempty"`
+	MinContractAgeSeconds int64    `json:"minContractAgeSeconds,omitempty"`
+	MaxCalldataBytes      int64    `json:"maxCalldataBytes,omitempty"`
+	MaxSlippageBps        int64    `json:"maxSlippageBps,omitempty"`
+	// A pointer, since zero is the strictest limit rather than none.
+	MaxApprovalExcessBps *int64                  `json:"maxApprovalExcessBps,omitempty"`
+	AmountLimits         map[string]AmountLimits `json:"amountLimits,omitempty"`
+}
+
+// AmountLimits are in whole units of the token moved, e.g. "32" or "0.5".
+// An empty limit is not checked.
+type AmountLimits struct {
+	MinAmount string `json:"minAmount,omitempty"`
+	MaxAmount string `json:"maxAmount,omitempty"`
+}
+
+type ShieldResult struct {
+	IsValid bool `json:"isValid"`
+	// Reason is a display string whose wording may change between
+	// releases; switch on ReasonCode instead.
+	Reason     string     `json:"reason,omitempty"`
+	ReasonCode ReasonCode `json:"reasonCode,omitempty"`
+	// ReasonMessage is ReasonCode and Reason together, with
+	// ShieldRequest.StructuredMessages.
+	ReasonMessage *LocalizedMessage `json:"reasonMessage,omitempty"`
+	DetectedType  DetectedType      `json:"detectedType,omitempty"`
+	// DetectedTypes is every action the transaction takes, in order: each
+	// call of a multicall, approvals included, or just DetectedType.
+	DetectedTypes []DetectedType `json:"detectedTypes,omitempty"`
+	// YieldId is the candidate a validate request's YieldIds passed for.
+	YieldId string `json:"yieldId,omitempty"`
+	// ExpectedRecipient is the contract a valid transaction was matched
+	// against. Transactions that call several contracts or programs, such as
+	// Solana transactions with a compute budget instruction, fill
+	// ExpectedRecipients instead. Both are empty for Tron and Cosmos SDK
+	// transactions, which call no contract.
+	ExpectedRecipient  string   `json:"expectedRecipient,omitempty"`
+	ExpectedRecipients []string `json:"expectedRecipients,omitempty"`
+	// ContractOverride is set when the request's ContractOverrides moved
+	// the yield's contract to the address the transaction is sent to.
+	ContractOverride *ContractOverride `json:"contractOverride,omitempty"`
+	// ContractDeployments is set when Policy.MinContractAgeSeconds was
+	// checked: when each contract called was deployed.
+	ContractDeployments map[string]ContractDeployment `json:"contractDeployments,omitempty"`
+	Warnings            []ShieldWarning               `json:"warnings,omitempty"`
+	RiskScore           int                           `json:"riskScore"`
+	RiskLevel           RiskLevel                     `json:"riskLevel,omitempty"`
+	// Decoded lists the validated instructions or messages of Solana and
+	// Cosmos SDK transactions.
+	Decoded *DecodedTransaction `json:"decoded,omitempty"`
+	// Simulation is only set when the request asked for one. A reverted
+	// call fails with reason SIMULATION_REVERTED, an unreachable node with
+	// SIMULATION_FAILED, and a call that credits nothing with
+	// SIMULATION_NO_BALANCE_CHANGE.
+	Simulation *SimulationResult `json:"simulation,omitempty"`
+	YieldIds   []string          `json:"yieldIds,omitempty"`
+	// Wrapper is set when the transaction is a Gnosis Safe execTransaction
+	// or an ERC-2771 forwarder's execute; DetectedType is then that of the
+	// call the Safe executes or the forwarder relays.
+	Wrapper *TransactionWrapper `json:"wrapper,omitempty"`
+	// SafeTxHash is set when ValidateTypedData was given a SafeTx: the
+	// EIP-712 hash the Safe's owners sign.
+	SafeTxHash string `json:"safeTxHash,omitempty"`
+	// Multicall is set when the transaction batches calls through
+	// Multicall3 or a contract's own multicall; SubResults then holds each
+	// call's result, and DetectedType is that of the last call other than
+	// an approval.
+	Multicall  *Multicall     `json:"multicall,omitempty"`
+	SubResults []ShieldResult `json:"subResults,omitempty"`
+	// Yields holds the same yields as YieldIds, each with its chain.
+	Yields []SupportedYield `json:"yields,omitempty"`
+	// RegistryHash identifies the getSupportedYieldIds list, to send back
+	// as IfNoneMatch. NotModified is set, and the lists left empty, when it
+	// was the request's IfNoneMatch.
+	RegistryHash string `json:"registryHash,omitempty"`
+	NotModified  bool   `json:"notModified,omitempty"`
+	// NextPageToken is set while a getSupportedYieldIds request with a
+	// PageSize has pages left, to send back as PageToken.
+	NextPageToken string `json:"nextPageToken,omitempty"`
+	// Matches holds the same yields as YieldIds for detectYields, each with
+	// the type the transaction matches as.
+	Matches []YieldMatch `json:"matches,omitempty"`
+	// Amount is what a matched transaction moves from the user, when Shield
+	// can decode it.
+	Amount *DecodedAmount `json:"amount,omitempty"`
+	// AmountDelta is Amount less the request's ExpectedAmount, in base
+	// units, e.g. "-3". It is set whenever both are known and in the same
+	// token, also when the difference is within tolerance.
+	AmountDelta string `json:"amountDelta,omitempty"`
+	// ClaimedPositions lists the positions a claim redeems, such as Lido
+	// withdrawal requests. PositionOwnershipVerified is whether the owner
+	// of each was read with an RPCURL and found to be the user; without
+	// one it is false.
+	ClaimedPositions          *ClaimedPositions `json:"claimedPositions,omitempty"`
+	PositionOwnershipVerified *bool             `json:"positionOwnershipVerified,omitempty"`
+	// Positions is set when a multicall claims positions, naming the call
+	// that claims each and, once verified, its owner.
+	Positions []ClaimedPosition `json:"positions,omitempty"`
+	// BridgeLeg is set when the transaction bridges funds to the yield's
+	// chain with a message that stakes them there, detected as
+	// DetectedTypeBridge. StakingLeg then holds the result of each call the
+	// message makes, validated as a flow.
+	BridgeLeg  *BridgeLeg     `json:"bridgeLeg,omitempty"`
+	StakingLeg []ShieldResult `json:"stakingLeg,omitempty"`
+	// EmptyCalldata is set when an EVM transaction carries no calldata. It
+	// is detected as DetectedTypeTransfer, and only passes when sent to a
+	// contract that stakes what it receives, such as Lido's stETH; others
+	// fail with ReasonNakedTransferNotSupported.
+	EmptyCalldata bool `json:"emptyCalldata,omitempty"`
+	// Transaction is what a request's RawTransaction decoded to. It is nil
+	// when the RLP could not be decoded, which fails with
+	// ReasonMalformedRawTransaction.
+	Transaction *RawTransactionFields `json:"transaction,omitempty"`
+	// RecoveredAddress and SignatureValid are set for a signed
+	// RawTransaction. A signer other than UserAddress fails with
+	// ReasonSignatureSenderMismatch, and a signature that recovers no signer
+	// with ReasonSignatureInvalid and SignatureValid false. A Cosmos or
+	// Solana transaction lists all its signers in RecoveredAddresses, the
+	// fee payer first, which must be UserAddress.
+	RecoveredAddress   string   `json:"recoveredAddress,omitempty"`
+	RecoveredAddresses []string `json:"recoveredAddresses,omitempty"`
+	SignatureValid     *bool    `json:"signatureValid,omitempty"`
+	// Timing is only set when IncludeTiming was requested.
+	Timing *Timing `json:"timing,omitempty"`
+	// ResolvedRecipient is what an ENS name the request named as the
+	// recipient resolved to.
+	ResolvedRecipient *ResolvedRecipient `json:"resolvedRecipient,omitempty"`
+	// YieldName and Summary are set with DetectedType. Summary describes the
+	// action for display, e.g. "You are staking 2 ETH with Lido"; its
+	// wording may change, so decisions should use the structured fields.
+	YieldName string `json:"yieldName,omitempty"`
+	Summary   string `json:"summary,omitempty"`
+	// Yield is also set with DetectedType, naming the yield validated
+	// against.
+	Yield *Yield `json:"yield,omitempty"`
+	// MatchedRule is set with Yield, saying why the transaction was
+	// trusted.
+	MatchedRule *MatchedRule `json:"matchedRule,omitempty"`
+	// Fingerprint is set on valid results: the SHA-256, in hex, that
+	// identifies the transaction however it was encoded. See the README for
+	// how it is computed.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// BlockNumber is the block state was read at, when the request pinned
+	// one.
+	BlockNumber *uint64 `json:"blockNumber,omitempty"`
+	// Locale is set when the request named one.
+	Locale string `json:"locale,omitempty"`
+	// Memo is the memo the transaction carries, e.g. on Cosmos.
+	Memo string `json:"memo,omitempty"`
+	// Deadline is when a permit, or a call that carries a deadline, stops
+	// being usable.
+	Deadline *Deadline `json:"deadline,omitempty"`
+	// Lock is the unlock time a vote-escrow lock sets, with the bounds of
+	// the yield it was checked against.
+	Lock *TransactionLock `json:"lock,omitempty"`
+	// Compound is set on RESTAKE_REWARDS results: what is restaked, and the
+	// rewards claimed when the request's args.amount names them. Multicalls
+	// that claim and restake report it too, summed over their calls.
+	Compound *CompoundAmounts `json:"compound,omitempty"`
+	// WouldReject is only set in observe mode, where IsValid is always
+	// true. When it is true, WouldRejectReason and WouldRejectReasonCode
+	// hold the Reason and ReasonCode enforcing would have reported.
+	WouldReject           *bool      `json:"wouldReject,omitempty"`
+	WouldRejectReason     string     `json:"wouldRejectReason,omitempty"`
+	WouldRejectReasonCode ReasonCode `json:"wouldRejectReasonCode,omitempty"`
+	// Trace is only set by explain requests.
+	Trace []ExplainEntry `json:"trace,omitempty"`
+	// Cached is set when a process started with --cache-size answered the
+	// request from an earlier result.
+	Cached bool `json:"cached,omitempty"`
+}
+
+// RawTransactionFields are the fields of an RLP-encoded EVM transaction.
+// Quantities are decimal strings, and To is empty for contract creation.
+// From is recovered from a signed transaction's signature and is the
+// request's UserAddress otherwise.
+type RawTransactionFields struct {
+	Type                 int               `json:"type"`
+	ChainId              int64             `json:"chainId"`
+	Nonce                uint64            `json:"nonce"`
+	To                   string            `json:"to"`
+	From                 string            `json:"from,omitempty"`
+	Value                string            `json:"value"`
+	Data                 string            `json:"data"`
+	GasLimit             string            `json:"gasLimit"`
+	GasPrice             string            `json:"gasPrice,omitempty"`
+	MaxFeePerGas         string            `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas string            `json:"maxPriorityFeePerGas,omitempty"`
+	AccessList           []AccessListEntry `json:"accessList,omitempty"`
+}
+
+// ClaimedPositions are the positions a claim redeems, by their IDs on
+// Contract.
+type ClaimedPositions struct {
+	Contract    string   `json:"contract"`
+	PositionIDs []string `json:"positionIds"`
+}
+
+// ClaimedPosition is one position a multicall claims. SubCall indexes the
+// call that claims it.
+type ClaimedPosition struct {
+	PositionID string `json:"positionId"`
+	SubCall    *int   `json:"subCall,omitempty"`
+	Owner      string `json:"owner,omitempty"`
+}
+
+// BridgeLeg is the bridge call of a bridge-then-stake transaction.
+// Amounts are in base units, and FillDeadline is in Unix seconds.
+type BridgeLeg struct {
+	Protocol           string `json:"protocol"`
+	Contract           string `json:"contract"`
+	SourceChainId      string `json:"sourceChainId"`
+	DestinationChainId string `json:"destinationChainId"`
+	Depositor          string `json:"depositor"`
+	Recipient          string `json:"recipient"`
+	InputToken         string `json:"inputToken"`
+	InputAmount        string `json:"inputAmount"`
+	OutputToken        string `json:"outputToken"`
+	OutputAmount       string `json:"outputAmount"`
+	FillDeadline       int64  `json:"fillDeadline"`
+	FallbackRecipient  string `json:"fallbackRecipient,omitempty"`
+}
+
+// DecodedAmount is what a transaction moves. Raw is in base units. Token is
+// the token contract or denomination, or "native" for the chain's own asset.
+// Normalized is Raw in whole units, e.g. "1.5" or "100.0"; it, Symbol and
+// Decimals are empty when Shield does not know the token's decimals.
+// Formatted is Normalized as ShieldRequest.Locale writes numbers, e.g.
+// "1.234,5" for "de-DE", and only set with a Locale. It is for display;
+// parse Normalized.
+type DecodedAmount struct {
+	Token      string `json:"token"`
+	Raw        string `json:"amount"`
+	Normalized string `json:"normalized,omitempty"`
+	Formatted  string `json:"formatted,omitempty"`
+	Symbol     string `json:"symbol,omitempty"`
+	Decimals   int    `json:"decimals,omitempty"`
+}
+
+type SupportedYield struct {
+	YieldId string `json:"yieldId"`
+	ChainId string `json:"chainId"`
+}
+
+type YieldMatch struct {
+	YieldId      string       `json:"yieldId"`
+	DetectedType DetectedType `json:"detectedType"`
+}
+
+// TransactionWrapper identifies the Safe or forwarder a validated inner
+// call is made through. Operation DELEGATECALL adds a DELEGATECALL_USED
+// warning. Forwarded calls report the Relayer sending the transaction, if
+// it names one, and the Signer the forward request recovers to, which is
+// the relayed call's sender.
+type TransactionWrapper struct {
+	DetectedType string  `json:"detectedType"` // SAFE_EXEC_TRANSACTION or ERC2771_FORWARD
+	Address      string  `json:"address"`
+	Operation    string  `json:"operation"` // CALL or DELEGATECALL
+	Relayer      string  `json:"relayer,omitempty"`
+	Signer       *string `json:"signer,omitempty"`
+}
+
+// Multicall is the batch of calls a multicall transaction makes. Calls of
+// a MULTICALL3_AGGREGATE are sent by the Multicall3 contract, those of a
+// MULTICALL by the transaction's sender. Values are in base units.
+type Multicall struct {
+	DetectedType string          `json:"detectedType"` // MULTICALL3_AGGREGATE or MULTICALL
+	Address      string          `json:"address"`
+	FunctionName string          `json:"functionName"` // e.g. aggregate3
+	Value        string          `json:"value"`
+	Calls        []MulticallCall `json:"calls"`
+}
+
+type MulticallCall struct {
+	Target       string `json:"target"`
+	Value        string `json:"value"`
+	Data         string `json:"data"`
+	AllowFailure bool   `json:"allowFailure"`
+}
+
+// RegistryOverride lists vaults in the format of Shield's embedded vault
+// registry, e.g. testnet deployments. Each is validated as an ERC4626 vault
+// and replaces any yield of the same YieldId.
+type RegistryOverride struct {
+	Version     int             `json:"version,omitempty"`
+	GeneratedAt string          `json:"generatedAt,omitempty"`
+	Vaults      []RegistryVault `json:"vaults"`
+}
+
+type RegistryVault struct {
+	YieldId            string `json:"yieldId"`
+	Address            string `json:"address"`
+	ChainId            int    `json:"chainId"`
+	Protocol           string `json:"protocol"`
+	Network            string `json:"network"`
+	InputTokenAddress  string `json:"inputTokenAddress"`
+	InputTokenSymbol   string `json:"inputTokenSymbol,omitempty"`
+	InputTokenDecimals *int   `json:"inputTokenDecimals,omitempty"`
+	VaultTokenAddress  string `json:"vaultTokenAddress"`
+	IsWethVault        bool   `json:"isWethVault"`
+	CanEnter           *bool  `json:"canEnter,omitempty"`
+	CanExit            *bool  `json:"canExit,omitempty"`
+	// Status is "active" when empty, or "paused" or "deprecated" to have
+	// stakes into the vault warned about.
+	Status          string   `json:"status,omitempty"`
+	AllocatorVaults []string `json:"allocatorVaults,omitempty"`
+	// BytecodeHashes pins the keccak256 hash of the runtime code of the
+	// vault's contracts, by address, for checking ActualBytecodeHash.
+	BytecodeHashes map[string]string `json:"bytecodeHashes,omitempty"`
+	// PrivilegedFunctions lists the canonical signatures of the owner- or
+	// admin-only functions of the vault's contracts, by address, that
+	// fail with ReasonPrivilegedFunctionCall.
+	PrivilegedFunctions map[string][]string `json:"privilegedFunctions,omitempty"`
+}
+
+// Timing is where a validation spent its time, in milliseconds. DecodeMs
+// is one decode of the transaction, which MatchMs also includes; SimulateMs
+// is only set when the transaction was simulated. Path names the code path
+// that matched the transaction, e.g. evm-abi-match or evm-safe-wrapper.
+type Timing struct {
+	DecodeMs   float64  `json:"decodeMs"`
+	MatchMs    float64  `json:"matchMs"`
+	SimulateMs *float64 `json:"simulateMs,omitempty"`
+	TotalMs    float64  `json:"totalMs"`
+	Path       string   `json:"path"`
+}
+
+type ResolvedRecipient struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+// ContractOverride names the contract the registry lists and the address
+// a request's ContractOverrides replaced it with.
+type ContractOverride struct {
+	Registered string `json:"registered"`
+	Address    string `json:"address"`
+}
+
+// ContractDeployment is the first block at which a contract has code, and
+// that block's timestamp in unix seconds.
+type ContractDeployment struct {
+	Block     int64 `json:"block"`
+	Timestamp int64 `json:"timestamp"`
+}
+
+// Deadline is in unix seconds. ISO is empty for deadlines past what a date
+// can hold, e.g. the maximum uint256 some permits use for "never".
+type Deadline struct {
+	Timestamp string `json:"timestamp"`
+	ISO       string `json:"iso,omitempty"`
+}
+
+// CompoundAmounts are in base units of the staking denomination.
+type CompoundAmounts struct {
+	Claimed  *DecodedAmount `json:"claimed,omitempty"`
+	Restaked DecodedAmount  `json:"restaked"`
+}
+
+// TransactionLock holds an unlock time in unix seconds and the seconds from
+// now until it. Locks outside MinLockSeconds and MaxLockSeconds fail with
+// ReasonLockDurationOutOfRange.
+type TransactionLock struct {
+	UnlockTime      string `json:"unlockTime"`
+	UnlockTimeISO   string `json:"unlockTimeIso,omitempty"`
+	DurationSeconds int64  `json:"durationSeconds"`
+	MinLockSeconds  int64  `json:"minLockSeconds"`
+	MaxLockSeconds  int64  `json:"maxLockSeconds"`
+}
+
+// ExplainEntry is one check of an explain trace. Status is "pass", "fail",
+// "warn" or "skip"; checks after the one that failed are skipped.
+type ExplainEntry struct {
+	Check  string `json:"check"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+type SimulationResult struct {
+	Success bool `json:"success"`
+	// ReturnData is the call's return data, or its revert data when
+	// Success is false.
+	ReturnData   string `json:"returnData"`
+	RevertReason string `json:"revertReason,omitempty"`
+	// BalanceChange is what the call credits to the user, when Shield can
+	// tell, e.g. the shares an ERC4626 deposit mints.
+	BalanceChange *BalanceChange `json:"balanceChange,omitempty"`
+}
+
+type BalanceChange struct {
+	Token  string `json:"token"`
+	Amount string `json:"amount"`
+}
+
+// DetectedType is the transaction type Shield matched. The constants below
+// mirror the TransactionType enum in src/types/index.ts and must be kept in
+// sync with it; values are the exact strings the binary emits.
+type DetectedType string
+
+const (
+	DetectedTypeSwap                      DetectedType = "SWAP"
+	DetectedTypeDeposit                   DetectedType = "DEPOSIT"
+	DetectedTypeApproval                  DetectedType = "APPROVAL"
+	DetectedTypePermit                    DetectedType = "PERMIT"
+	DetectedTypePermit2                   DetectedType = "PERMIT2"
+	DetectedTypeStake                     DetectedType = "STAKE"
+	DetectedTypeClaimUnstaked             DetectedType = "CLAIM_UNSTAKED"
+	DetectedTypeClaimRewards              DetectedType = "CLAIM_REWARDS"
+	DetectedTypeRestakeRewards            DetectedType = "RESTAKE_REWARDS"
+	DetectedTypeUnstake                   DetectedType = "UNSTAKE"
+	DetectedTypeSplit                     DetectedType = "SPLIT"
+	DetectedTypeMerge                     DetectedType = "MERGE"
+	DetectedTypeLock                      DetectedType = "LOCK"
+	DetectedTypeUnlock                    DetectedType = "UNLOCK"
+	DetectedTypeSupply                    DetectedType = "SUPPLY"
+	DetectedTypeBridge                    DetectedType = "BRIDGE"
+	DetectedTypeVote                      DetectedType = "VOTE"
+	DetectedTypeRevoke                    DetectedType = "REVOKE"
+	DetectedTypeRestake                   DetectedType = "RESTAKE"
+	DetectedTypeRebond                    DetectedType = "REBOND"
+	DetectedTypeWithdraw                  DetectedType = "WITHDRAW"
+	DetectedTypeWithdrawAll               DetectedType = "WITHDRAW_ALL"
+	DetectedTypeCreateAccount             DetectedType = "CREATE_ACCOUNT"
+	DetectedTypeReveal                    DetectedType = "REVEAL"
+	DetectedTypeMigrate                   DetectedType = "MIGRATE"
+	DetectedTypeDelegate                  DetectedType = "DELEGATE"
+	DetectedTypeUndelegate                DetectedType = "UNDELEGATE"
+	DetectedTypeUTXOPToCImport            DetectedType = "UTXO_P_TO_C_IMPORT"
+	DetectedTypeUTXOCToPImport            DetectedType = "UTXO_C_TO_P_IMPORT"
+	DetectedTypeWrap                      DetectedType = "WRAP"
+	DetectedTypeUnwrap                    DetectedType = "UNWRAP"
+	DetectedTypeUnfreezeLegacy            DetectedType = "UNFREEZE_LEGACY"
+	DetectedTypeUnfreezeLegacyBandwidth   DetectedType = "UNFREEZE_LEGACY_BANDWIDTH"
+	DetectedTypeUnfreezeLegacyEnergy      DetectedType = "UNFREEZE_LEGACY_ENERGY"
+	DetectedTypeUnfreezeBandwidth         DetectedType = "UNFREEZE_BANDWIDTH"
+	DetectedTypeUnfreezeEnergy            DetectedType = "UNFREEZE_ENERGY"
+	DetectedTypeFreezeBandwidth           DetectedType = "FREEZE_BANDWIDTH"
+	DetectedTypeFreezeEnergy              DetectedType = "FREEZE_ENERGY"
+	DetectedTypeDelegateBandwidth         DetectedType = "DELEGATE_BANDWIDTH"
+	DetectedTypeDelegateEnergy            DetectedType = "DELEGATE_ENERGY"
+	DetectedTypeUndelegateBandwidth       DetectedType = "UNDELEGATE_BANDWIDTH"
+	DetectedTypeUndelegateEnergy          DetectedType = "UNDELEGATE_ENERGY"
+	DetectedTypeP2PNodeRequest            DetectedType = "P2P_NODE_REQUEST"
+	DetectedTypeCreateEigenPod            DetectedType = "CREATE_EIGENPOD"
+	DetectedTypeVerifyWithdrawCredentials DetectedType = "VERIFY_WITHDRAW_CREDENTIALS"
+	DetectedTypeStartCheckpoint           DetectedType = "START_CHECKPOINT"
+	DetectedTypeVerifyCheckpointProofs    DetectedType = "VERIFY_CHECKPOINT_PROOFS"
+	DetectedTypeQueueWithdrawals          DetectedType = "QUEUE_WITHDRAWALS"
+	DetectedTypeCompleteQueuedWithdrawals DetectedType = "COMPLETE_QUEUED_WITHDRAWALS"
+	DetectedTypeLuganodesProvision        DetectedType = "LUGANODES_PROVISION"
+	DetectedTypeLuganodesExitRequest      DetectedType = "LUGANODES_EXIT_REQUEST"
+	DetectedTypeInfstonesProvision        DetectedType = "INFSTONES_PROVISION"
+	DetectedTypeInfstonesExitRequest      DetectedType = "INFSTONES_EXIT_REQUEST"
+	DetectedTypeInfstonesClaimRequest     DetectedType = "INFSTONES_CLAIM_REQUEST"
+	DetectedTypeTransfer                  DetectedType = "TRANSFER"
+)
+
+var knownDetectedTypes = map[DetectedType]bool{
+	DetectedTypeSwap:                      true,
+	DetectedTypeDeposit:                   true,
+	DetectedTypeApproval:                  true,
+	DetectedTypePermit:                    true,
+	DetectedTypeStake:                     true,
+	DetectedTypeClaimUnstaked:             true,
+	DetectedTypeClaimRewards:              true,
+	DetectedTypeRestakeRewards:            true,
+	DetectedTypeUnstake:                   true,
+	DetectedTypeSplit:                     true,
+	DetectedTypeMerge:                     true,
+	DetectedTypeLock:                      true,
+	DetectedTypeUnlock:                    true,
+	DetectedTypeSupply:                    true,
+	DetectedTypeBridge:                    true,
+	DetectedTypeVote:                      true,
+	DetectedTypeRevoke:                    true,
+	DetectedTypeRestake:                   true,
+	DetectedTypeRebond:                    true,
+	DetectedTypeWithdraw:                  true,
+	DetectedTypeWithdrawAll:               true,
+	DetectedTypeCreateAccount:             true,
+	DetectedTypeReveal:                    true,
+	DetectedTypeMigrate:                   true,
+	DetectedTypeDelegate:                  true,
+	DetectedTypeUndelegate:                true,
+	DetectedTypeUTXOPToCImport:            true,
+	DetectedTypeUTXOCToPImport:            true,
+	DetectedTypeWrap:                      true,
+	DetectedTypeUnwrap:                    true,
+	DetectedTypeUnfreezeLegacy:            true,
+	DetectedTypeUnfreezeLegacyBandwidth:   true,
+	DetectedTypeUnfreezeLegacyEnergy:      true,
+	DetectedTypeUnfreezeBandwidth:         true,
+	DetectedTypeUnfreezeEnergy:            true,
+	DetectedTypeFreezeBandwidth:           true,
+	DetectedTypeFreezeEnergy:              true,
+	DetectedTypeDelegateBandwidth:         true,
+	DetectedTypeDelegateEnergy:            true,
+	DetectedTypeUndelegateBandwidth:       true,
+	DetectedTypeUndelegateEnergy:          true,
+	DetectedTypeP2PNodeRequest:            true,
+	DetectedTypeCreateEigenPod:            true,
+	DetectedTypeVerifyWithdrawCredentials: true,
+	DetectedTypeStartCheckpoint:           true,
+	DetectedTypeVerifyCheckpointProofs:    true,
+	DetectedTypeQueueWithdrawals:          true,
+	DetectedTypeCompleteQueuedWithdrawals: true,
+	DetectedTypeLuganodesProvision:        true,
+	DetectedTypeLuganodesExitRequest:      true,
+	DetectedTypeInfstonesProvision:        true,
+	DetectedTypeInfstonesExitRequest:      true,
+	DetectedTypeInfstonesClaimRequest:     true,
+	DetectedTypeTransfer:                  true,
+}
+
+// IsKnown reports whether t is one of the DetectedType constants above. A
+// newer Shield binary may emit types this code predates, so check IsKnown
+// instead of assuming the set is closed.
+func (t DetectedType) IsKnown() bool {
+	return knownDetectedTypes[t]
+}
+
+// ReasonCode is the stable code of an invalid result. Like DetectedType, the
+// set is open: a newer Shield binary may emit codes this file predates.
+type ReasonCode string
+
+const (
+	ReasonInvalidRequest                 ReasonCode = "INVALID_REQUEST"
+	ReasonYieldNotFound                  ReasonCode = "YIELD_NOT_FOUND"
+	ReasonYieldPaused                    ReasonCode = "YIELD_PAUSED"
+	ReasonOperationNotSupportedForYield  ReasonCode = "OPERATION_NOT_SUPPORTED_FOR_YIELD" // Reason lists the supported types
+	ReasonChainIdMismatch                ReasonCode = "CHAIN_ID_MISMATCH"
+	ReasonMalformedTransaction           ReasonCode = "MALFORMED_TRANSACTION"
+	ReasonMalformedNumeric               ReasonCode = "MALFORMED_NUMERIC"
+	ReasonMalformedRawTransaction        ReasonCode = "MALFORMED_RAW_TRANSACTION"
+	ReasonInvalidGasFields               ReasonCode = "INVALID_GAS_FIELDS"
+	ReasonSenderMismatch                 ReasonCode = "SENDER_MISMATCH"
+	ReasonSignatureInvalid               ReasonCode = "SIGNATURE_INVALID"
+	ReasonSignatureSenderMismatch        ReasonCode = "SIGNATURE_SENDER_MISMATCH"
+	ReasonApprovalSpenderMismatch        ReasonCode = "APPROVAL_SPENDER_MISMATCH"
+	ReasonApprovalInsufficientForDeposit ReasonCode = "APPROVAL_INSUFFICIENT_FOR_DEPOSIT"
+	ReasonApprovalExceedsStake           ReasonCode = "APPROVAL_EXCEEDS_STAKE"
+	ReasonUnexpectedProxyUpgrade         ReasonCode = "UNEXPECTED_PROXY_UPGRADE"
+	ReasonPrivilegedFunctionCall         ReasonCode = "PRIVILEGED_FUNCTION_CALL"
+	ReasonRewardRecipientMismatch        ReasonCode = "REWARD_RECIPIENT_MISMATCH"
+	ReasonWithdrawalRecipientMismatch    ReasonCode = "WITHDRAWAL_RECIPIENT_MISMATCH"
+	ReasonAmountMismatch                 ReasonCode = "AMOUNT_MISMATCH"
+	ReasonIntentMismatch                 ReasonCode = "INTENT_MISMATCH"
+	ReasonNonceMismatch                  ReasonCode = "NONCE_MISMATCH"
+	ReasonNonceCheckFailed               ReasonCode = "NONCE_CHECK_FAILED"
+	ReasonRecipientEnsMismatch           ReasonCode = "RECIPIENT_ENS_MISMATCH"
+	ReasonEnsResolutionFailed            ReasonCode = "ENS_RESOLUTION_FAILED"
+	ReasonRecipientNotAContract          ReasonCode = "RECIPIENT_NOT_A_CONTRACT"
+	ReasonCodeCheckFailed                ReasonCode = "CODE_CHECK_FAILED"
+	ReasonBytecodeMismatch               ReasonCode = "BYTECODE_MISMATCH"
+	ReasonUnstakeExceedsBalance          ReasonCode = "UNSTAKE_EXCEEDS_BALANCE"
+	ReasonBalanceCheckFailed             ReasonCode = "BALANCE_CHECK_FAILED"
+	ReasonClaimPositionNotOwned          ReasonCode = "CLAIM_POSITION_NOT_OWNED"
+	ReasonPositionCheckFailed            ReasonCode = "POSITION_CHECK_FAILED"
+	ReasonAltResolutionRequired          ReasonCode = "ALT_RESOLUTION_REQUIRED"
+	ReasonAltResolutionFailed            ReasonCode = "ALT_RESOLUTION_FAILED"
+	ReasonAmountAboveLimit               ReasonCode = "AMOUNT_ABOVE_LIMIT"
+	ReasonAmountBelowMinimum             ReasonCode = "AMOUNT_BELOW_MINIMUM"
+	ReasonMissingMemo                    ReasonCode = "MISSING_MEMO"
+	ReasonMemoMismatch                   ReasonCode = "MEMO_MISMATCH"
+	ReasonUnexpectedNativeValue          ReasonCode = "UNEXPECTED_NATIVE_VALUE"
+	ReasonCalldataTooLarge               ReasonCode = "CALLDATA_TOO_LARGE"
+	ReasonDeadlineInPast                 ReasonCode = "DEADLINE_IN_PAST"
+	ReasonLockDurationOutOfRange         ReasonCode = "LOCK_DURATION_OUT_OF_RANGE"
+	ReasonRecipientMismatch              ReasonCode = "RECIPIENT_MISMATCH"
+	ReasonSelectorMismatch               ReasonCode = "SELECTOR_MISMATCH"
+	ReasonTokenMismatch                  ReasonCode = "TOKEN_MISMATCH" // Details.expected lists the yield's tokens
+	ReasonNakedTransferNotSupported      ReasonCode = "NAKED_TRANSFER_NOT_SUPPORTED"
+	ReasonSelectorNotAllowed             ReasonCode = "SELECTOR_NOT_ALLOWED"
+	ReasonBridgeDestinationMismatch      ReasonCode = "BRIDGE_DESTINATION_MISMATCH"
+	ReasonBridgeRecipientMismatch        ReasonCode = "BRIDGE_RECIPIENT_MISMATCH" // Details.field names the account
+	ReasonBridgeMessageInvalid           ReasonCode = "BRIDGE_MESSAGE_INVALID"
+	ReasonDelegationTargetNotAllowed     ReasonCode = "DELEGATION_TARGET_NOT_ALLOWED"
+	ReasonAuthorityMismatch              ReasonCode = "AUTHORITY_MISMATCH"
+	ReasonBeneficiaryMismatch            ReasonCode = "BENEFICIARY_MISMATCH"
+	ReasonContractTypeNotSupported       ReasonCode = "CONTRACT_TYPE_NOT_SUPPORTED"
+	ReasonPalletNotAllowed               ReasonCode = "PALLET_NOT_ALLOWED"
+	ReasonModuleNotAllowed               ReasonCode = "MODULE_NOT_ALLOWED"
+	ReasonUnexpectedValidator            ReasonCode = "UNEXPECTED_VALIDATOR"
+	ReasonCompoundDestinationMismatch    ReasonCode = "COMPOUND_DESTINATION_MISMATCH"
+	ReasonNoMatchingPattern              ReasonCode = "NO_MATCHING_PATTERN"
+	ReasonAmbiguousPattern               ReasonCode = "AMBIGUOUS_PATTERN"
+	ReasonNestedMultisig                 ReasonCode = "NESTED_MULTISIG"
+	ReasonUntrustedForwarder             ReasonCode = "UNTRUSTED_FORWARDER"
+	ReasonForwardSignatureMismatch       ReasonCode = "FORWARD_SIGNATURE_MISMATCH"
+	ReasonMulticallCallMissing           ReasonCode = "MULTICALL_CALL_MISSING"
+	ReasonMulticallCallInvalid           ReasonCode = "MULTICALL_CALL_INVALID" // Details.subCall is its index
+	ReasonMulticallValueMismatch         ReasonCode = "MULTICALL_VALUE_MISMATCH"
+	ReasonContractBlocked                ReasonCode = "CONTRACT_BLOCKED"
+	ReasonContractNotAllowed             ReasonCode = "CONTRACT_NOT_ALLOWED"
+	ReasonDelegateCallBlocked            ReasonCode = "DELEGATECALL_BLOCKED"
+	ReasonRiskThresholdExceeded          ReasonCode = "RISK_THRESHOLD_EXCEEDED"
+	ReasonStrictModeWarning              ReasonCode = "STRICT_MODE_WARNING"
+	ReasonFlowStepInvalid                ReasonCode = "FLOW_STEP_INVALID"
+	ReasonAllCandidatesFailed            ReasonCode = "ALL_CANDIDATES_FAILED" // Details.candidates gives each yield's reason
+	ReasonUnsupportedAccountCall         ReasonCode = "UNSUPPORTED_ACCOUNT_CALL"
+	ReasonTypedDataInvalid               ReasonCode = "TYPED_DATA_INVALID"
+	ReasonSimulationUnsupported          ReasonCode = "SIMULATION_UNSUPPORTED"
+	ReasonSimulationFailed               ReasonCode = "SIMULATION_FAILED"
+	ReasonSimulationReverted             ReasonCode = "SIMULATION_REVERTED"
+	ReasonSimulationNoBalanceChange      ReasonCode = "SIMULATION_NO_BALANCE_CHANGE"
+	ReasonInternalError                  ReasonCode = "INTERNAL_ERROR"
+)
+
+// RiskLevel buckets ShieldResult.RiskScore: LOW below 30, MEDIUM below 70,
+// HIGH otherwise.
+type RiskLevel string
+
+const (
+	RiskLevelLow    RiskLevel = "LOW"
+	RiskLevelMedium RiskLevel = "MEDIUM"
+	RiskLevelHigh   RiskLevel = "HIGH"
+)
+
+// ShieldWarning is a non-blocking concern about a transaction, such as
+// INFINITE_APPROVAL or HIGH_GAS_LIMIT. A result can be valid and still carry
+// warnings, e.g. to ask the user for a soft confirmation.
+type ShieldWarning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// MessageLocalized is Message in ShieldRequest.Locale, with
+	// StructuredMessages.
+	MessageLocalized string         `json:"messageLocalized,omitempty"`
+	Details          map[string]any `json:"details,omitempty"`
+	// Severity rates the warning by its code, for a UI to order warnings
+	// by and ShieldRequest.StrictSeverities to pick from.
+	Severity WarningSeverity `json:"severity"`
+}
+
+// WarningSeverity is how much a ShieldWarning matters: SeverityCritical
+// for the likes of INFINITE_APPROVAL, SeverityWarning for HIGH_GAS_LIMIT,
+// SeverityInfo for what is merely worth knowing.
+type WarningSeverity string
+
+const (
+	SeverityInfo     WarningSeverity = "info"
+	SeverityWarning  WarningSeverity = "warning"
+	SeverityCritical WarningSeverity = "critical"
+)
+
+// ShieldError is the error of an ok:false response. Code is one of the
+// JSON protocol's error codes; errors.Is matches it against the sentinel
+// errors below, and errors.As gets the code, message and details back.
+type ShieldError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// MessageLocalized is Message in ShieldRequest.Locale, with
+	// StructuredMessages.
+	MessageLocalized string          `json:"messageLocalized,omitempty"`
+	Details          json.RawMessage `json:"details,omitempty"`
+}
+
+// LocalizedMessage is a code with its English message and, with
+// ShieldRequest.Locale set, the message in that language.
+type LocalizedMessage struct {
+	Code             string `json:"code"`
+	Message          string `json:"message"`
+	MessageLocalized string `json:"messageLocalized,omitempty"`
+}
+
+func (e *ShieldError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// Unwrap returns the sentinel error of e.Code, or nil for a code this
+// package predates.
+func (e *ShieldError) Unwrap() error {
+	return errorsByCode[e.Code]
+}
+
+// Sentinel errors a *ShieldError matches with errors.Is, by its Code.
+// ErrInvalidRequest covers every code for a request Shield could not read.
+var (
+	ErrInvalidRequest         = errors.New("shield: invalid request")
+	ErrInputTimeout           = errors.New("shield: input timed out")
+	ErrUnsupportedYield       = errors.New("shield: unsupported yield")
+	ErrUnsupportedApiVersion  = errors.New("shield: unsupported API version")
+	ErrSimulationUnavailable  = errors.New("shield: simulation unavailable")
+	ErrReloadUnavailable      = errors.New("shield: registry reload unavailable")
+	ErrReloadFailed           = errors.New("shield: registry reload failed")
+	ErrAttestationUnavailable = errors.New("shield: attestation unavailable")
+	ErrInternal               = errors.New("shield: internal error")
+)
+
+var errorsByCode = map[string]error{
+	"PARSE_ERROR":             ErrInvalidRequest,
+	"EMPTY_INPUT":             ErrInvalidRequest,
+	"INPUT_TOO_LARGE":         ErrInvalidRequest,
+	"SCHEMA_VALIDATION_ERROR": ErrInvalidRequest,
+	"MISSING_REQUIRED_FIELD":  ErrInvalidRequest,
+	"MISSING_REQUEST_ID":      ErrInvalidRequest,
+	"INVALID_PAGE_TOKEN":      ErrInvalidRequest,
+	"INPUT_TIMEOUT":           ErrInputTimeout,
+	"YIELD_NOT_FOUND":         ErrUnsupportedYield,
+	"UNSUPPORTED_API_VERSION": ErrUnsupportedApiVersion,
+	"SIMULATION_UNAVAILABLE":  ErrSimulationUnavailable,
+	"RELOAD_UNAVAILABLE":      ErrReloadUnavailable,
+	"RELOAD_FAILED":           ErrReloadFailed,
+	"ATTESTATION_UNAVAILABLE": ErrAttestationUnavailable,
+	"INTERNAL_ERROR":          ErrInternal,
+}
+
+// AsShieldError returns the error of an ok:false response as a
+// *ShieldError, or nil when resp is ok.
+//
+//	if err := AsShieldError(resp); errors.Is(err, ErrUnsupportedApiVersion) {
+//		// negotiate another version
+//	}
+func AsShieldError(resp *ShieldResponse) error {
+	return responseError(resp.Ok, resp.Error)
+}
+
+// responseError is the error of a response of any operation, as
+// AsShieldError returns it.
+func responseError(ok bool, shieldError *ShieldError) error {
+	switch {
+	case ok:
+		return nil
+	case shieldError != nil:
+		return shieldError
+	default:
+		return errors.New("shield returned ok:false without an error")
+	}
+}
+
+// ShieldMeta accompanies every response. Warnings are about the request
+// itself rather than its result, e.g. API_VERSION_DEPRECATED. StableHash
+// is the same for responses that mean the same, whatever their timings or
+// wording, for snapshot tests.
+type ShieldMeta struct {
+	RequestHash string          `json:"requestHash"`
+	StableHash  string          `json:"stableHash"`
+	Warnings    []ShieldWarning `json:"warnings,omitempty"`
+	Audit       *AuditRecord    `json:"audit,omitempty"`
+}
+
+// AuditRecord is one validate decision, to retain for audits. Its fields
+// marshal in the order Shield writes them, and RecordHash is the hex
+// SHA-256 of the record's JSON without it. TransactionHash is that of the
+// transaction as sent; RegistryHash is that of the embedded registry, and
+// RegistryOverrideHash that of the vaults registered over it, if any.
+type AuditRecord struct {
+	Timestamp            string     `json:"timestamp"`
+	RequestId            string     `json:"requestId,omitempty"`
+	YieldId              string     `json:"yieldId,omitempty"`
+	UserAddress          string     `json:"userAddress,omitempty"`
+	TransactionHash      string     `json:"transactionHash"`
+	Verdict              string     `json:"verdict"` // "valid" or "invalid"
+	ReasonCode           ReasonCode `json:"reasonCode,omitempty"`
+	RegistryHash         string     `json:"registryHash"`
+	RegistryOverrideHash string     `json:"registryOverrideHash,omitempty"`
+	Version              string     `json:"version"`
+	RecordHash           string     `json:"recordHash"`
+}
+
+type ShieldResponse struct {
+	Ok        bool         `json:"ok"`
+	Result    ShieldResult `json:"result"`
+	Error     *ShieldError `json:"error,omitempty"`
+	Meta      ShieldMeta   `json:"meta"`
+	RequestId string       `json:"requestId,omitempty"`
+	// NormalizedRequest is the request as Shield read it, only set when it
+	// asked with EchoRequest: the transaction with its quantities as
+	// decimal strings, and defaults filled in for fields left out.
+	NormalizedRequest *ShieldRequest `json:"normalizedRequest,omitempty"`
+}
+
+// ShieldBatchTransaction is a single entry of a validateBatch request.
+type ShieldBatchTransaction struct {
+	YieldId             string              `json:"yieldId"`
+	UnsignedTransaction string              `json:"unsignedTransaction"`
+	UserAddress         string              `json:"userAddress,omitempty"`
+	RiskThreshold       int                 `json:"riskThreshold,omitempty"`
+	Policy              *Policy             `json:"policy,omitempty"`
+	Strict              bool                `json:"strict,omitempty"`
+	StrictSeverities    []WarningSeverity   `json:"strictSeverities,omitempty"`
+	ExpectedAmount      string              `json:"expectedAmount,omitempty"`
+	ExpectedAmountToken string              `json:"expectedAmountToken,omitempty"`
+	AmountToleranceBps  int                 `json:"amountToleranceBps,omitempty"`
+	AmountTolerance     string              `json:"amountTolerance,omitempty"`
+	ExpectedNonce       *uint64             `json:"expectedNonce,omitempty"`
+	IncludeTiming       bool                `json:"includeTiming,omitempty"`
+	Locale              string              `json:"locale,omitempty"`
+	ExpectedMemo        string              `json:"expectedMemo,omitempty"`
+	BeneficiaryAddress  string              `json:"beneficiaryAddress,omitempty"`
+	ActualBytecodeHash  string              `json:"actualBytecodeHash,omitempty"`
+	AddressLookupTables map[string][]string `json:"addressLookupTables,omitempty"`
+}
+
+type ShieldBatchRequest struct {
+	ApiVersion   string                   `json:"apiVersion"`
+	Operation    string                   `json:"operation"`
+	Transactions []ShieldBatchTransaction `json:"transactions"`
+}
+
+// ShieldBatchResponse carries one result per request transaction, in the
+// same order. Each result is independent of the others. Summary counts
+// them, so a caller can tell at a glance whether any need a closer look.
+type ShieldBatchResponse struct {
+	Ok     bool `json:"ok"`
+	Result struct {
+		Results []ShieldResult `json:"results"`
+		Summary BatchSummary   `json:"summary"`
+	} `json:"result"`
+	Error *ShieldError `json:"error,omitempty"`
+	Meta  ShieldMeta   `json:"meta"`
+}
+
+// BatchSummary counts the results of a validateBatch request. Valid,
+// Invalid and Errored add up to Total: Invalid results failed validation,
+// while Errored ones could not be checked at all, e.g. for an unknown
+// yield or a malformed transaction (reason INVALID_REQUEST,
+// YIELD_NOT_FOUND, MALFORMED_TRANSACTION, MALFORMED_NUMERIC or
+// INTERNAL_ERROR). Warned counts the valid results that carry warnings.
+type BatchSummary struct {
+	Total   int `json:"total"`
+	Valid   int `json:"valid"`
+	Invalid int `json:"invalid"`
+	Warned  int `json:"warned"`
+	Errored int `json:"errored"`
+}
+
+// ShieldFlowTransaction is a single step of a validateFlow request. Steps
+// share the yieldId and userAddress of the request.
+type ShieldFlowTransaction struct {
+	UnsignedTransaction string `json:"unsignedTransaction"`
+}
+
+type ShieldFlowRequest struct {
+	ApiVersion       string                  `json:"apiVersion"`
+	Operation        string                  `json:"operation"`
+	YieldId          string                  `json:"yieldId"`
+	UserAddress      string                  `json:"userAddress,omitempty"`
+	Transactions     []ShieldFlowTransaction `json:"transactions"`
+	RiskThreshold    int                     `json:"riskThreshold,omitempty"`
+	Policy           *Policy                 `json:"policy,omitempty"`
+	Strict           bool                    `json:"strict,omitempty"`
+	StrictSeverities []WarningSeverity       `json:"strictSeverities,omitempty"`
+}
+
+// ShieldFlowResponse carries one result per step, in order, plus the verdict
+// for the flow as a whole. IsValid is false when any step fails
+// (FLOW_STEP_INVALID) or when a later step pulls more tokens than an earlier
+// approval allows (APPROVAL_INSUFFICIENT_FOR_DEPOSIT) or pulls them through a
+// different spender (APPROVAL_SPENDER_MISMATCH). Details.step is the index of
+// the offending step. With Policy.MaxApprovalExcessBps set, an approval that
+// outweighs what the flow pulls fails with APPROVAL_EXCEEDS_STAKE, and
+// Details holds the approved and staked amounts.
+type ShieldFlowResponse struct {
+	Ok     bool `json:"ok"`
+	Result struct {
+		IsValid    bool           `json:"isValid"`
+		Reason     string         `json:"reason,omitempty"`
+		ReasonCode ReasonCode     `json:"reasonCode,omitempty"`
+		Details    map[string]any `json:"details,omitempty"`
+		Steps      []ShieldResult `json:"steps"`
+	} `json:"result"`
+	Error *ShieldError `json:"error,omitempty"`
+	Meta  ShieldMeta   `json:"meta"`
+}
+
+// ShieldUserOperationResponse carries one result per call the smart account
+// makes, in order, checked as a flow like ShieldFlowResponse. DetectedType
+// is that of the last call. CallData other than SimpleAccount-style execute
+// and executeBatch fails with reason UNSUPPORTED_ACCOUNT_CALL, and a
+// paymaster outside the request's Paymasters adds an UNKNOWN_PAYMASTER
+// warning.
+type ShieldUserOperationResponse struct {
+	Ok     bool `json:"ok"`
+	Result struct {
+		IsValid       bool            `json:"isValid"`
+		Reason        string          `json:"reason,omitempty"`
+		ReasonCode    ReasonCode      `json:"reasonCode,omitempty"`
+		Details       map[string]any  `json:"details,omitempty"`
+		DetectedType  DetectedType    `json:"detectedType,omitempty"`
+		DetectedTypes []DetectedType  `json:"detectedTypes,omitempty"`
+		Paymaster     string          `json:"paymaster,omitempty"`
+		Warnings      []ShieldWarning `json:"warnings"`
+		Steps         []ShieldResult  `json:"steps"`
+	} `json:"result"`
+	Error *ShieldError `json:"error,omitempty"`
+	Meta  ShieldMeta   `json:"meta"`
+}
+
+// ShieldSendCallsResponse carries one result per call of a wallet_sendCalls
+// batch, in order, checked as a flow like ShieldFlowResponse. Unless
+// AtomicRequired, every approval a later call spends adds a
+// NON_ATOMIC_BATCH warning, and capabilities Shield does not know add
+// UNKNOWN_CAPABILITY warnings.
+type ShieldSendCallsResponse struct {
+	Ok     bool `json:"ok"`
+	Result struct {
+		IsValid        bool            `json:"isValid"`
+		Reason         string          `json:"reason,omitempty"`
+		ReasonCode     ReasonCode      `json:"reasonCode,omitempty"`
+		Details        map[string]any  `json:"details,omitempty"`
+		DetectedType   DetectedType    `json:"detectedType,omitempty"`
+		DetectedTypes  []DetectedType  `json:"detectedTypes,omitempty"`
+		AtomicRequired bool            `json:"atomicRequired"`
+		Warnings       []ShieldWarning `json:"warnings"`
+		Steps          []ShieldResult  `json:"steps"`
+	} `json:"result"`
+	Error *ShieldError `json:"error,omitempty"`
+	Meta  ShieldMeta   `json:"meta"`
+}
+
+// ShieldIntentResponse compares a transaction with the intent it was built
+// for. Match is true when the transaction passes validation and
+// ActionMatch, AmountMatch and RecipientMatch all hold; otherwise reason
+// INTENT_MISMATCH lists the diverging fields in Details["fields"], or a
+// failed Validation gives its own reason.
+type ShieldIntentResponse struct {
+	Ok     bool `json:"ok"`
+	Result struct {
+		Match          bool           `json:"match"`
+		ActionMatch    bool           `json:"actionMatch"`
+		AmountMatch    bool           `json:"amountMatch"`
+		RecipientMatch bool           `json:"recipientMatch"`
+		Reason         string         `json:"reason,omitempty"`
+		ReasonCode     ReasonCode     `json:"reasonCode,omitempty"`
+		Details        map[string]any `json:"details,omitempty"`
+		Validation     ShieldResult   `json:"validation"`
+	} `json:"result"`
+	Error *ShieldError `json:"error,omitempty"`
+	Meta  ShieldMeta   `json:"meta"`
+}
+
+// ShieldPreflightResponse is the response of a preflight request. Allowed is
+// false, with a reason, when a transaction of the type could not pass
+// validation whatever it carried, such as an amount below the policy's
+// minimum or a yield paused with ReasonYieldPaused. ExpectedRecipient is the
+// contract the transaction must be sent to, when the yield has one.
+type ShieldPreflightResponse struct {
+	Ok     bool `json:"ok"`
+	Result struct {
+		Allowed           bool            `json:"allowed"`
+		Reason            string          `json:"reason,omitempty"`
+		ReasonCode        ReasonCode      `json:"reasonCode,omitempty"`
+		Details           map[string]any  `json:"details,omitempty"`
+		Warnings          []ShieldWarning `json:"warnings"`
+		ChainId           string          `json:"chainId,omitempty"`
+		ExpectedRecipient string          `json:"expectedRecipient,omitempty"`
+		Amount            *DecodedAmount  `json:"amount,omitempty"`
+	} `json:"result"`
+	Error *ShieldError `json:"error,omitempty"`
+	Meta  ShieldMeta   `json:"meta"`
+}
+
+// DecodedTransaction describes what Shield understands a transaction to do,
+// independent of whether it passes validation. EVM transactions fill
+// FunctionName, Selector and Args, Solana transactions fill Instructions,
+// Cosmos SDK transactions fill Messages, NEAR transactions fill Actions,
+// Substrate extrinsics fill Calls, Aptos transactions fill EntryFunction and
+// Bitcoin transactions fill Outputs.
+type DecodedTransaction struct {
+	FunctionName string `json:"functionName,omitempty"`
+	Selector     string `json:"selector,omitempty"`
+	// FunctionSignature is the canonical signature of the function called,
+	// e.g. "submit(address)", or "unknown" when no ABI Shield knows has one
+	// for Selector.
+	FunctionSignature string            `json:"functionSignature,omitempty"`
+	Args              []DecodedArgument `json:"args,omitempty"`
+	// DecodedArgs holds the same arguments by name, or by position where
+	// the ABI names none: addresses as hex, integers as decimal strings and
+	// arrays and tuples as []any. It is set on validate results too.
+	DecodedArgs  map[string]any       `json:"decodedArgs,omitempty"`
+	Instructions []DecodedInstruction `json:"instructions,omitempty"`
+	Messages     []DecodedMessage     `json:"messages,omitempty"`
+	// Delegations is set on matched Cosmos stakes, one per MsgDelegate.
+	Delegations   []ValidatorDelegation `json:"delegations,omitempty"`
+	Actions       []DecodedAction       `json:"actions,omitempty"`
+	Calls         []DecodedCall         `json:"calls,omitempty"`
+	EntryFunction *DecodedEntryFunction `json:"entryFunction,omitempty"`
+	Outputs       []DecodedOutput       `json:"outputs,omitempty"`
+	Approval      *TokenApproval        `json:"approval,omitempty"`
+	// ImplementationChange is set on proxy upgrades the yield expects, which
+	// also carry an IMPLEMENTATION_CHANGE warning.
+	ImplementationChange *ImplementationChange `json:"implementationChange,omitempty"`
+	// Permit2 is set on validated Permit2 typed data.
+	Permit2 *Permit2Permit `json:"permit2,omitempty"`
+	// Recipient is set on matched CLAIM_REWARDS and CLAIM_UNSTAKED results.
+	Recipient *ClaimRecipient `json:"recipient,omitempty"`
+	// Withdrawal is set on matched unstake and withdraw calls.
+	Withdrawal *Withdrawal `json:"withdrawal,omitempty"`
+	// RequestIds is set on matched claims of withdrawal NFTs, e.g. Lido
+	// withdrawal request IDs, in the order they are claimed.
+	RequestIds []string          `json:"requestIds,omitempty"`
+	AccessList []AccessListEntry `json:"accessList,omitempty"`
+	// AuthorizationList is set on EIP-7702 transactions.
+	AuthorizationList []Delegation `json:"authorizationList,omitempty"`
+	// Value is the native value EVM contract calls send, in base units.
+	Value         string `json:"value,omitempty"`
+	CalldataBytes int    `json:"calldataBytes,omitempty"`
+	// Slippage is set on swaps, and zaps that swap before staking.
+	Slippage *SwapSlippage `json:"slippage,omitempty"`
+	// DetectedType is only set when a yieldId was given and the transaction
+	// matches one of that yield's transaction types.
+	DetectedType DetectedType `json:"detectedType,omitempty"`
+}
+
+// ClaimRecipient is who a claim pays out to, and Variant the claim called,
+// e.g. "getReward(address)". Implicit is set when the call names no
+// recipient and the protocol pays its sender, in which case the recipient
+// check holds trivially.
+type ClaimRecipient struct {
+	Address  string `json:"address"`
+	Implicit bool   `json:"implicit"`
+	Variant  string `json:"variant"`
+}
+
+// Withdrawal is what an unstake or withdraw call pays out, and to whom.
+// Phase is "REQUEST" for the first step of a delayed withdrawal, which a
+// CLAIM_UNSTAKED transaction completes, and "WITHDRAW" for calls that pay
+// out at once. Amount is in base units of Token, the token given up.
+type Withdrawal struct {
+	Phase     string `json:"phase"`
+	Recipient string `json:"recipient"`
+	Token     string `json:"token"`
+	Amount    string `json:"amount"`
+}
+
+// TokenApproval is a decoded ERC-20 approve(spender, amount). Amount is in
+// base units; IsUnlimited is set for allowances at or near 2^256-1, which
+// also carry an INFINITE_APPROVAL warning.
+type TokenApproval struct {
+	Token       string `json:"token"`
+	Spender     string `json:"spender"`
+	Amount      string `json:"amount"`
+	IsUnlimited bool   `json:"isUnlimited"`
+}
+
+// ImplementationChange is a call that points Proxy at the code of
+// Implementation: upgradeTo or upgradeToAndCall on the proxy itself, or
+// upgrade or upgradeAndCall on its ProxyAdmin. Upgrades a yield does not
+// expect fail with reason UNEXPECTED_PROXY_UPGRADE, with the same fields in
+// Details.
+type ImplementationChange struct {
+	Proxy          string `json:"proxy"`
+	Implementation string `json:"implementation"`
+	FunctionName   string `json:"functionName"`
+}
+
+// Permit2Permit is a decoded Permit2 PermitSingle or PermitBatch: Spender
+// may pull each of Details' tokens until its Expiration, if the signature
+// is used by SigDeadline. Times are Unix seconds as decimal strings, and an
+// Expiration of "0" lasts for the block the permit is used in.
+type Permit2Permit struct {
+	Spender     string           `json:"spender"`
+	SigDeadline string           `json:"sigDeadline"`
+	Details     []Permit2Details `json:"details"`
+}
+
+// Permit2Details is one token allowance of a Permit2Permit. IsUnlimited is
+// set for the maximum uint160 amount, which also carries an
+// INFINITE_APPROVAL warning.
+type Permit2Details struct {
+	Token       string `json:"token"`
+	Amount      string `json:"amount"`
+	Expiration  string `json:"expiration"`
+	Nonce       string `json:"nonce"`
+	IsUnlimited bool   `json:"isUnlimited"`
+}
+
+// AccessListEntry is one address of an EIP-2930 access list. Entries for
+// addresses outside the yield add an ACCESS_LIST_UNEXPECTED_ADDRESS warning.
+type AccessListEntry struct {
+	Address     string   `json:"address"`
+	StorageKeys []string `json:"storageKeys"`
+}
+
+// Delegation is an EIP-7702 authorization of a type 4 transaction:
+// Authority, recovered from its signature and empty when none recovers,
+// has its account run Address's code.
+type Delegation struct {
+	ChainID   string `json:"chainId"`
+	Address   string `json:"address"`
+	Nonce     string `json:"nonce"`
+	Authority string `json:"authority,omitempty"`
+}
+
+// SwapSlippage is what a swap puts in and the least it accepts back, in
+// base units. ExpectedAmountOut is only set when the call is quoted at an
+// output, as Rocket Pool's swapTo is.
+type SwapSlippage struct {
+	InputToken        string `json:"inputToken"`
+	InputAmount       string `json:"inputAmount"`
+	OutputToken       string `json:"outputToken"`
+	MinAmountOut      string `json:"minAmountOut"`
+	ExpectedAmountOut string `json:"expectedAmountOut,omitempty"`
+}
+
+// DecodedInstruction is one Solana instruction, in execution order. Program
+// names well-known programs such as "Stake" and is empty otherwise.
+type DecodedInstruction struct {
+	ProgramId       string   `json:"programId"`
+	Program         string   `json:"program,omitempty"`
+	InstructionType string   `json:"instructionType"`
+	Accounts        []string `json:"accounts"`
+}
+
+// DecodedOutput is one output of a Bitcoin transaction. Value is in
+// satoshis and Script is the scriptPubKey, as hex.
+type DecodedOutput struct {
+	Value  string `json:"value"`
+	Script string `json:"script"`
+}
+
+// DecodedMessage is one Cosmos SDK message, e.g. a MsgDelegate.
+// ValidatorDstAddress is only set for MsgBeginRedelegate.
+type DecodedMessage struct {
+	TypeUrl             string      `json:"typeUrl"`
+	DelegatorAddress    string      `json:"delegatorAddress,omitempty"`
+	ValidatorAddress    string      `json:"validatorAddress,omitempty"`
+	ValidatorDstAddress string      `json:"validatorDstAddress,omitempty"`
+	Amount              *ShieldCoin `json:"amount,omitempty"`
+}
+
+// ValidatorDelegation is what one MsgDelegate of a stake delegates, and to
+// which validator. A stake's Amount is the total of its delegations.
+type ValidatorDelegation struct {
+	MessageIndex     int           `json:"messageIndex"`
+	ValidatorAddress string        `json:"validatorAddress"`
+	Amount           DecodedAmount `json:"amount"`
+}
+
+// DecodedAction is one NEAR action, e.g. a FunctionCall. MethodName, Args and
+// Gas are only set for FunctionCall actions; Deposit is in yoctoNEAR.
+type DecodedAction struct {
+	Type       string         `json:"type"`
+	MethodName string         `json:"methodName,omitempty"`
+	Args       map[string]any `json:"args,omitempty"`
+	Gas        string         `json:"gas,omitempty"`
+	Deposit    string         `json:"deposit,omitempty"`
+}
+
+// DecodedCall is one Substrate call, e.g. staking.bond, with the calls of
+// utility batches flattened. Args holds amounts as decimal strings and
+// accounts as SS58 addresses.
+type DecodedCall struct {
+	Pallet string         `json:"pallet"`
+	Method string         `json:"method"`
+	Args   map[string]any `json:"args"`
+}
+
+// DecodedEntryFunction is the entry function an Aptos transaction calls.
+// Module is "<address>::<module>" with the address in its long form, and
+// Arguments holds u64 amounts as decimal strings.
+type DecodedEntryFunction struct {
+	Module        string   `json:"module"`
+	FunctionName  string   `json:"functionName"`
+	TypeArguments []string `json:"typeArguments"`
+	Arguments     []any    `json:"arguments"`
+}
+
+// ShieldCoin is an amount in a chain's base denomination, e.g. uatom.
+type ShieldCoin struct {
+	Denom  string `json:"denom"`
+	Amount string `json:"amount"`
+}
+
+// DecodedArgument is a named calldata argument. Integers are decimal strings.
+type DecodedArgument struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+// ShieldDecodeResponse is the reply to a decode request. Decoded is nil, and
+// Reason explains why, when no known ABI matches the transaction.
+type ShieldDecodeResponse struct {
+	Ok     bool `json:"ok"`
+	Result struct {
+		Decoded *DecodedTransaction `json:"decoded"`
+		Reason  string              `json:"reason,omitempty"`
+	} `json:"result"`
+	Error *ShieldError `json:"error,omitempty"`
+	Meta  ShieldMeta   `json:"meta"`
+}
+
+// YieldCapabilities describes what a yield accepts. ChainId is a decimal EVM
+// chain ID, a Cosmos chain ID, or e.g. "solana-mainnet"; Contracts lists the
+// contracts or programs its transactions may call. Status is "active",
+// "paused" or "deprecated"; stakes into a yield that is not active are
+// warned about with YIELD_PAUSED or YIELD_DEPRECATED.
+type YieldCapabilities struct {
+	YieldId                string         `json:"yieldId"`
+	Name                   string         `json:"name"`
+	Protocol               string         `json:"protocol"`
+	Network                string         `json:"network"`
+	SupportedTypes         []DetectedType `json:"supportedTypes"`
+	SupportsPartialAmounts bool           `json:"supportsPartialAmounts"`
+	ChainId                string         `json:"chainId"`
+	Contracts              []string       `json:"contracts"`
+	Status                 string         `json:"status"`
+}
+
+// Yield is the yield a result was validated against. Protocol and Network
+// are lowercase identifiers, e.g. "lido" and "ethereum", and Protocol is
+// "native" for a network's own staking.
+type Yield struct {
+	Name     string `json:"name"`
+	Protocol string `json:"protocol"`
+	Network  string `json:"network"`
+}
+
+// MatchedRule is the rule a valid transaction matched. ID is stable across
+// releases, e.g. "ethereum-eth-lido-staking:STAKE:submit(address)", so logs
+// can be grepped by it; Description is for people and may change.
+type MatchedRule struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+}
+
+// ShieldCapabilitiesResponse is the reply to a getYieldCapabilities request.
+// Unknown yields come back as ok:false with error code YIELD_NOT_FOUND.
+type ShieldCapabilitiesResponse struct {
+	Ok     bool              `json:"ok"`
+	Result YieldCapabilities `json:"result"`
+	Error  *ShieldError      `json:"error,omitempty"`
+	Meta   ShieldMeta        `json:"meta"`
+}
+
+// ShieldYieldsResponse is the reply to a getYields request. Yields is in
+// the order of the request's YieldIds; Unknown lists those of no supported
+// yield rather than failing the request.
+type ShieldYieldsResponse struct {
+	Ok     bool `json:"ok"`
+	Result struct {
+		Yields  []YieldCapabilities `json:"yields"`
+		Unknown []string            `json:"unknown"`
+	} `json:"result"`
+	Error *ShieldError `json:"error,omitempty"`
+	Meta  ShieldMeta   `json:"meta"`
+}
+
+// AbiFunction is a contract function a yield's TransactionType transactions
+// call. Signature is the canonical form the Selector is derived from, and
+// Payable whether the function accepts native value.
+type AbiFunction struct {
+	TransactionType DetectedType `json:"transactionType"`
+	Name            string       `json:"name"`
+	Selector        string       `json:"selector"`
+	Signature       string       `json:"signature"`
+	Inputs          []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"inputs"`
+	Payable bool `json:"payable"`
+}
+
+// ShieldAbiResponse is the reply to a getYieldAbi request. Functions is
+// empty for yields whose transactions call no contracts, e.g. Cosmos
+// staking; unknown yields come back as ok:false with YIELD_NOT_FOUND.
+type ShieldAbiResponse struct {
+	Ok     bool `json:"ok"`
+	Result struct {
+		YieldId   string        `json:"yieldId"`
+		Functions []AbiFunction `json:"functions"`
+	} `json:"result"`
+	Error *ShieldError `json:"error,omitempty"`
+	Meta  ShieldMeta   `json:"meta"`
+}
+
+// VersionInfo identifies the Shield build that produced a result. Include it
+// in bug reports. GitCommit and BuildDate are "unknown" for builds made
+// outside a git checkout.
+type VersionInfo struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"gitCommit"`
+	BuildDate string `json:"buildDate"`
+	// SupportedApiVersions lists the request apiVersion values the binary
+	// accepts, oldest first. Requests for a deprecated version succeed with
+	// an API_VERSION_DEPRECATED warning in the response meta.
+	SupportedApiVersions  []string `json:"supportedApiVersions"`
+	DeprecatedApiVersions []string `json:"deprecatedApiVersions"`
+	Registry              struct {
+		Version     int    `json:"version"`
+		GeneratedAt string `json:"generatedAt"`
+		// Hash is the SHA-256 of the embedded vault registry; equal hashes
+		// validate the same vaults.
+		Hash       string `json:"hash"`
+		YieldCount int    `json:"yieldCount"`
+		// OverrideActive reports whether vaults were registered over the
+		// embedded registry, with WithRegistry or a request's
+		// RegistryOverride; OverrideHash is then the override's SHA-256.
+		OverrideActive bool   `json:"overrideActive"`
+		OverrideHash   string `json:"overrideHash,omitempty"`
+	} `json:"registry"`
+	// Profile is set when Shield runs with a profile, e.g. from WithProfile.
+	// Its Hash is the profile's SHA-256.
+	Profile *struct {
+		Name string `json:"name,omitempty"`
+		Hash string `json:"hash"`
+	} `json:"profile,omitempty"`
+}
+
+type ShieldVersionResponse struct {
+	Ok     bool         `json:"ok"`
+	Result VersionInfo  `json:"result"`
+	Error  *ShieldError `json:"error,omitempty"`
+	Meta   ShieldMeta   `json:"meta"`
+}
+
+// RegistryProblem is a mistake CheckRegistry found. Severity is "error"
+// for problems that make the yield validate wrongly or not at all, and
+// "warning" otherwise, such as Code "YIELD_REPLACED".
+type RegistryProblem struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	YieldId  string `json:"yieldId"`
+	Message  string `json:"message"`
+}
+
+type ShieldCheckRegistryResponse struct {
+	Ok     bool `json:"ok"`
+	Result struct {
+		// IsValid is false when any problem is an error.
+		IsValid    bool              `json:"isValid"`
+		YieldCount int               `json:"yieldCount"`
+		Problems   []RegistryProblem `json:"problems"`
+	} `json:"result"`
+	Error *ShieldError `json:"error,omitempty"`
+	Meta  ShieldMeta   `json:"meta"`
+}
+
+// OperationInfo describes an operation the binary supports. Field names are
+// the JSON names of ShieldRequest's fields.
+type OperationInfo struct {
+	Name           string   `json:"name"`
+	Description    string   `json:"description"`
+	RequiredFields []string `json:"requiredFields"`
+	OptionalFields []string `json:"optionalFields"`
+}
+
+// ShieldOperationsResponse is the reply to a listOperations request.
+type ShieldOperationsResponse struct {
+	Ok     bool `json:"ok"`
+	Result struct {
+		Operations []OperationInfo `json:"operations"`
+	} `json:"result"`
+	Error *ShieldError `json:"error,omitempty"`
+	Meta  ShieldMeta   `json:"meta"`
+}
+
+// ShieldHealthResponse is the reply to a health request. Ready is false,
+// with a Reason, after a registry reload failed.
+type ShieldHealthResponse struct {
+	Ok     bool `json:"ok"`
+	Result struct {
+		Ready  bool   `json:"ready"`
+		Reason string `json:"reason,omitempty"`
+	} `json:"result"`
+	Error *ShieldError `json:"error,omitempty"`
+	Meta  ShieldMeta   `json:"meta"`
+}
+
+// ShieldAttestResponse is the reply to an attest request: the SHA-256 of
+// the file the binary runs, which is the binary itself for release builds,
+// and whether the release's checksum file next to it matches. Checksum
+// status is "match", "mismatch" or "absent".
+type ShieldAttestResponse struct {
+	Ok     bool `json:"ok"`
+	Result struct {
+		Path     string `json:"path"`
+		Kind     string `json:"kind"` // "binary", or "script" when node runs Path
+		Sha256   string `json:"sha256"`
+		Checksum struct {
+			Status string `json:"status"`
+			Path   string `json:"path,omitempty"`
+		} `json:"checksum"`
+	} `json:"result"`
+	Error *ShieldError `json:"error,omitempty"`
+	Meta  ShieldMeta   `json:"meta"`
+}
+
+// ShieldExecError is returned when the Shield process exits with a non-zero
+// status without writing a JSON response. Stderr holds the diagnostics the
+// binary wrote before exiting.
+type ShieldExecError struct {
+	ExitCode int
+	Stderr   string
+}
+
+func (e *ShieldExecError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("exit status %d", e.ExitCode)
+	}
+	return fmt.Sprintf("exit status %d: %s", e.ExitCode, e.Stderr)
+}
+
+// Runner executes a single Shield invocation: it feeds stdin to the process
+// and returns what it wrote to stdout and stderr. A non-zero exit status is
+// reported as an *exec.ExitError, as ExecRunner does, or as a
+// *ShieldExecError. Client uses an ExecRunner unless given another.
+type Runner interface {
+	Run(ctx context.Context, stdin []byte) (stdout []byte, stderr []byte, err error)
+}
+
+// RunnerFunc adapts a function to a Runner.
+type RunnerFunc func(ctx context.Context, stdin []byte) ([]byte, []byte, error)
+
+func (f RunnerFunc) Run(ctx context.Context, stdin []byte) ([]byte, []byte, error) {
+	return f(ctx, stdin)
+}
+
+// ExecRunner runs the binary at Path with Args, with Env appended to the
+// current environment, and kills it when ctx is done.
+type ExecRunner struct {
+	Path string
+	Args []string
+	Env  []string
+}
+
+func (r *ExecRunner) Run(ctx context.Context, stdin []byte) ([]byte, []byte, error) {
+	cmd := exec.CommandContext(ctx, r.Path, r.Args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	if len(r.Env) > 0 {
+		cmd.Env = append(os.Environ(), r.Env...)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+// FileRunner is ExecRunner passing the request and response through
+// temporary files, with --input and --output, instead of stdin and stdout.
+// It suits sandboxes whose pipes cannot be wired up cleanly.
+type FileRunner struct {
+	Path string
+	Env  []string
+}
+
+func (r *FileRunner) Run(ctx context.Context, stdin []byte) ([]byte, []byte, error) {
+	dir, err := os.MkdirTemp("", "shield-")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "request.json")
+	outputPath := filepath.Join(dir, "response.json")
+	if err := os.WriteFile(inputPath, stdin, 0o600); err != nil {
+		return nil, nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, r.Path, "--input", inputPath, "--output", outputPath)
+	if len(r.Env) > 0 {
+		cmd.Env = append(os.Environ(), r.Env...)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	runErr := cmd.Run()
+	output, err := os.ReadFile(outputPath)
+	if err != nil && runErr == nil {
+		return nil, stderr.Bytes(), fmt.Errorf("failed to read response: %w", err)
+	}
+	return output, stderr.Bytes(), runErr
+}
+
+// FakeRunner answers with canned responses instead of running a binary, so
+// code built on Client can be tested hermetically. Responses maps an
+// operation, e.g. "validate", to the stdout returned for it; when Err is set
+// it is returned instead. It is safe for concurrent use.
+type FakeRunner struct {
+	Responses map[string]string
+	Err       error
+
+	mu       sync.Mutex
+	requests [][]byte
+}
+
+func (f *FakeRunner) Run(ctx context.Context, stdin []byte) ([]byte, []byte, error) {
+	f.mu.Lock()
+	f.requests = append(f.requests, append([]byte(nil), stdin...))
+	f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	if f.Err != nil {
+		return nil, nil, f.Err
+	}
+
+	var request struct {
+		Operation string `json:"operation"`
+	}
+	if err := json.Unmarshal(stdin, &request); err != nil {
+		return nil, nil, fmt.Errorf("fake runner: invalid request: %w", err)
+	}
+	response, ok := f.Responses[request.Operation]
+	if !ok {
+		return nil, nil, fmt.Errorf("fake runner: no response for operation %q", request.Operation)
+	}
+	return []byte(response), nil, nil
+}
+
+// Requests returns the stdin of every call so far, in order.
+func (f *FakeRunner) Requests() [][]byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]byte(nil), f.requests...)
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithTimeout bounds every call, on top of any deadline the caller's
+// context already carries.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) { c.timeout = timeout }
+}
+
+// WithEnv adds "KEY=value" entries to the Shield process environment. It
+// has no effect together with WithRunner.
+func WithEnv(env ...string) Option {
+	return func(c *Client) { c.env = append(c.env, env...) }
+}
+
+// WithApiVersion sets the apiVersion of every request the Client builds,
+// e.g. one picked by NegotiateApiVersion. It defaults to the newest of
+// ApiVersions.
+func WithApiVersion(version string) Option {
+	return func(c *Client) {
+		c.apiVersion = version
+	}
+}
+
+// WithCompression gzips every request and has Shield gzip its response,
+// which speeds up large validateBatch payloads. Responses are inflated
+// before they are decoded, so callers see no difference. Together with
+// WithRunner only requests are compressed, unless the runner passes
+// --compress itself.
+func WithCompression() Option {
+	return func(c *Client) { c.compress = true }
+}
+
+// WithRegistry has Shield load the registry override file at path, in the
+// format of RegistryOverride, and register its vaults for every call. A
+// file Shield cannot read or parse fails each call with a *ShieldExecError.
+// It has no effect together with WithRunner.
+func WithRegistry(path string) Option {
+	return func(c *Client) { c.registry = path }
+}
+
+// WithProfile has Shield load the profile file at path, a JSON object of
+// strict, policy, locale and responseFields, and use each as the default of
+// every request that leaves it out. A file Shield cannot read or parse
+// fails each call with a *ShieldExecError. It has no effect together with
+// WithRunner.
+func WithProfile(path string) Option {
+	return func(c *Client) { c.profile = path }
+}
+
+// WithRunner replaces how the Shield binary is executed, e.g. with a
+// FakeRunner in tests.
+func WithRunner(runner Runner) Option {
+	return func(c *Client) { c.runner = runner }
+}
+
+// WithRetry runs a call up to retries more times when the Shield process
+// could not be started for lack of resources, i.e. fork/exec failed with
+// EAGAIN, ENOMEM, EMFILE, ENFILE or ETXTBSY. Retry n waits backoff*2^(n-1)
+// first. Nothing else is retried: not responses, ok:false ones included,
+// not a process that ran and exited non-zero, not a missing or
+// non-executable binary, and not a cancelled or expired ctx.
+func WithRetry(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// WithExpectedSHA256 has the Client run VerifyBinary with sha256 before its
+// first call. Calls fail with an error wrapping ErrBinaryHashMismatch from
+// then on if the binary is not that one.
+func WithExpectedSHA256(sha256 string) Option {
+	return func(c *Client) { c.expectedSHA256 = strings.ToLower(sha256) }
+}
+
+// WithAuditSink has Validate, and the calls built on it, ask for the audit
+// record of every decision and write it to w as a line of JSON before
+// returning. A record that cannot be written fails the call, so no
+// decision goes unrecorded. Writes are serialized, so w need not be safe
+// for concurrent use.
+func WithAuditSink(w io.Writer) Option {
+	return func(c *Client) { c.auditSink = w }
+}
+
+// ErrQueueFull is returned by a Client created WithMaxProcesses when a call
+// finds maxQueued others already waiting for a process.
+var ErrQueueFull = errors.New("too many calls waiting for a shield process")
+
+// WithMaxProcesses bounds how many Shield processes the Client runs at
+// once. A call beyond maxActive waits until a process exits, or fails with
+// its ctx.Err() if ctx is done first; a call finding maxQueued calls
+// already waiting fails with ErrQueueFull. maxQueued 0 lets any number
+// wait. Share one Client between goroutines for the bound to hold.
+func WithMaxProcesses(maxActive, maxQueued int) Option {
+	return func(c *Client) {
+		c.slots = make(chan struct{}, maxActive)
+		c.maxQueued = int64(maxQueued)
+	}
+}
+
+// Client runs one Shield process per call. It holds no process between
+// calls and is safe for concurrent use.
+type Client struct {
+	shieldPath string
+	timeout    time.Duration
+	env        []string
+	runner     Runner
+	apiVersion string
+	compress   bool
+	registry   string
+	profile    string
+	retries    int
+	backoff    time.Duration
+	slots      chan struct{} // One per running process, nil when unbounded
+	maxQueued  int64
+	active     atomic.Int64
+	queued     atomic.Int64
+
+	// Numbers the RequestIds ValidateAsync and ValidateStream assign
+	nextRequestId atomic.Uint64
+
+	// Set by WithExpectedSHA256; verifyErr holds a mismatch once verified
+	expectedSHA256 string
+	verifyMu       sync.Mutex
+	verified       bool
+	verifyErr      error
+
+	// The last list of SupportedYieldIdsCached and its RegistryHash
+	yieldIdsMu   sync.Mutex
+	yieldIds     []string
+	yieldIdsHash string
+
+	// Set by WithAuditSink
+	auditMu   sync.Mutex
+	auditSink io.Writer
+}
+
+// PoolStats is a snapshot of the processes a Client runs.
+type PoolStats struct {
+	Active int // Shield processes running
+	Queued int // Calls waiting for a process
+	Max    int // maxActive of WithMaxProcesses, 0 when unbounded
+}
+
+// Stats reports the Client's running processes and waiting calls, e.g. to
+// export as gauges.
+func (c *Client) Stats() PoolStats {
+	return PoolStats{
+		Active: int(c.active.Load()),
+		Queued: int(c.queued.Load()),
+		Max:    cap(c.slots),
+	}
+}
+
+// NewClient returns a Client for the Shield binary at shieldPath.
+func NewClient(shieldPath string, opts ...Option) *Client {
+	c := &Client{shieldPath: shieldPath, apiVersion: ApiVersions[len(ApiVersions)-1]}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.runner == nil {
+		runner := &ExecRunner{Path: shieldPath, Env: c.env}
+		if c.compress {
+			runner.Args = append(runner.Args, "--compress")
+		}
+		if c.registry != "" {
+			runner.Args = append(runner.Args, "--registry", c.registry)
+		}
+		if c.profile != "" {
+			runner.Args = append(runner.Args, "--profile", c.profile)
+		}
+		c.runner = runner
+	}
+	return c
+}
+
+// Send runs request as-is, whatever its operation. If ctx is cancelled or
+// its deadline passes, the process is killed and the returned error wraps
+// ctx.Err(), so callers can tell a timeout apart from a Shield failure with
+// errors.Is.
+func (c *Client) Send(ctx context.Context, request ShieldRequest) (*ShieldResponse, error) {
+	var response ShieldResponse
+	if err := c.call(ctx, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Validate sends request as a validate operation.
+func (c *Client) Validate(ctx context.Context, request ShieldRequest) (*ShieldResponse, error) {
+	request.ApiVersion = c.apiVersion
+	request.Operation = "validate"
+	if c.auditSink != nil {
+		request.IncludeAudit = true
+	}
+	response, err := c.Send(ctx, request)
+	if err != nil || c.auditSink == nil || response.Meta.Audit == nil {
+		return response, err
+	}
+	if err := c.writeAudit(response.Meta.Audit); err != nil {
+		return nil, fmt.Errorf("writing audit record: %w", err)
+	}
+	return response, nil
+}
+
+func (c *Client) writeAudit(record *AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	c.auditMu.Lock()
+	defer c.auditMu.Unlock()
+	_, err = c.auditSink.Write(append(line, '\n'))
+	return err
+}
+
+// Explain validates request like Validate, without simulating it or
+// fetching any chain state, and returns the outcome of every check in
+// Result.Trace.
+func (c *Client) Explain(ctx context.Context, request ShieldRequest) (*ShieldResponse, error) {
+	request.ApiVersion = c.apiVersion
+	request.Operation = "explain"
+	return c.Send(ctx, request)
+}
+
+// ValidateBatch validates all transactions of request in a single Shield
+// invocation.
+func (c *Client) ValidateBatch(ctx context.Context, request ShieldBatchRequest) (*ShieldBatchResponse, error) {
+	request.ApiVersion = c.apiVersion
+	request.Operation = "validateBatch"
+
+	var response ShieldBatchResponse
+	if err := c.call(ctx, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Result is the outcome of a validation started by ValidateAsync or
+// ValidateStream: Response, or Err as Validate would return it. RequestId
+// is the request's, so results can be matched to requests whatever order
+// they finish in, and Index is the request's position in the slice given
+// to ValidateStream.
+type Result struct {
+	RequestId string
+	Index     int
+	Response  *ShieldResponse
+	Err       error
+}
+
+// ValidateAsync runs Validate in its own goroutine and returns a channel
+// that receives its Result once and is then closed. A request without a
+// RequestId is given one. The channel is buffered, so a Result nobody
+// reads does not hold up the goroutine.
+func (c *Client) ValidateAsync(ctx context.Context, request ShieldRequest) <-chan Result {
+	results := make(chan Result, 1)
+	request = c.withRequestId(request)
+	go func() {
+		defer close(results)
+		results <- c.validateResult(ctx, 0, request)
+	}()
+	return results
+}
+
+// ValidateStream validates each of requests in a Shield process of its
+// own, unlike ValidateBatch, and sends each Result as soon as it finishes.
+// Results arrive in the order they finish, and the channel is closed after
+// the last. Requests without a RequestId are given one. On a Client
+// created WithMaxProcesses, at most maxActive requests run at once and the
+// rest wait their turn without counting against maxQueued; otherwise all
+// of them start at once. Cancelling ctx fails the requests still running
+// or waiting with its error.
+func (c *Client) ValidateStream(ctx context.Context, requests []ShieldRequest) <-chan Result {
+	results := make(chan Result, len(requests))
+	workers := len(requests)
+	if c.slots != nil && cap(c.slots) < workers {
+		workers = cap(c.slots)
+	}
+
+	requests = append([]ShieldRequest(nil), requests...)
+	indexes := make(chan int, len(requests))
+	for i := range requests {
+		requests[i] = c.withRequestId(requests[i])
+		indexes <- i
+	}
+	close(indexes)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results <- c.validateResult(ctx, i, requests[i])
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+func (c *Client) validateResult(ctx context.Context, index int, request ShieldRequest) Result {
+	response, err := c.Validate(ctx, request)
+	return Result{
+		RequestId: request.RequestId,
+		Index:     index,
+		Response:  response,
+		Err:       err,
+	}
+}
+
+// withRequestId is request with a RequestId unique to this Client, unless
+// it already has one.
+func (c *Client) withRequestId(request ShieldRequest) ShieldRequest {
+	if request.RequestId == "" {
+		request.RequestId = "go-" + strconv.FormatUint(c.nextRequestId.Add(1), 10)
+	}
+	return request
+}
+
+// StreamValidate pipes the newline-delimited JSON requests of r through a
+// single `shield --stream` process and copies its responses to w, one line
+// per request and in the same order, until r is exhausted. Neither side is
+// buffered whole, so r can hold millions of requests. Lines are passed
+// through as written, so each must be a complete request, apiVersion
+// included. A request that fails is answered by its error response and the
+// stream goes on, so the returned error is only for the process itself, r
+// or w. A Runner given with WithRunner is not used, and neither is
+// WithTimeout.
+func (c *Client) StreamValidate(r io.Reader, w io.Writer) error {
+	args := []string{"--stream"}
+	if c.registry != "" {
+		args = append(args, "--registry", c.registry)
+	}
+	if c.profile != "" {
+		args = append(args, "--profile", c.profile)
+	}
+	cmd := exec.Command(c.shieldPath, args...)
+	cmd.Stdin = r
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+	if len(c.env) > 0 {
+		cmd.Env = append(os.Environ(), c.env...)
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("shield stream failed: %w", err)
+	}
+	return nil
+}
+
+// ValidateFlow validates transactions as one ordered flow for yieldId, such
+// as an approval followed by the deposit it pays for.
+func (c *Client) ValidateFlow(ctx context.Context, yieldId, userAddress string, transactions []string) (*ShieldFlowResponse, error) {
+	request := ShieldFlowRequest{
+		ApiVersion:   c.apiVersion,
+		Operation:    "validateFlow",
+		YieldId:      yieldId,
+		UserAddress:  userAddress,
+		Transactions: make([]ShieldFlowTransaction, len(transactions)),
+	}
+	for i, tx := range transactions {
+		request.Transactions[i].UnsignedTransaction = tx
+	}
+
+	var response ShieldFlowResponse
+	if err := c.call(ctx, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Decode asks Shield what unsignedTransaction does without validating it.
+// yieldId is optional; pass "" to try every known ABI.
+func (c *Client) Decode(ctx context.Context, unsignedTransaction, yieldId string) (*ShieldDecodeResponse, error) {
+	request := ShieldRequest{
+		ApiVersion:          c.apiVersion,
+		Operation:           "decode",
+		YieldId:             yieldId,
+		UnsignedTransaction: unsignedTransaction,
+	}
+
+	var response ShieldDecodeResponse
+	if err := c.call(ctx, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// SupportedYieldIds lists every yield the binary supports. A Shield error
+// response is returned as a *ShieldError.
+func (c *Client) SupportedYieldIds(ctx context.Context) ([]string, error) {
+	return c.SupportedYieldIdsOnChain(ctx, "")
+}
+
+// SupportedYieldIdsOnChain lists the yields on chainId, e.g. "8453" for
+// Base, or every yield when chainId is "".
+func (c *Client) SupportedYieldIdsOnChain(ctx context.Context, chainId string) ([]string, error) {
+	result, err := c.supportedYields(ctx, ShieldRequest{ChainId: chainId})
+	if err != nil {
+		return nil, err
+	}
+	return result.YieldIds, nil
+}
+
+// SupportedYieldIdsPaged is SupportedYieldIdsOnChain one page of pageSize
+// yields at a time, so that no single response grows with the registry.
+// It yields every ID in order, or ends with the error of a page that
+// failed. A list that changes while it is paged through fails with error
+// code INVALID_PAGE_TOKEN; start again to read the new list.
+func (c *Client) SupportedYieldIdsPaged(ctx context.Context, chainId string, pageSize int) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		pageToken := ""
+		for {
+			result, err := c.supportedYields(ctx, ShieldRequest{
+				ChainId:   chainId,
+				PageSize:  pageSize,
+				PageToken: pageToken,
+			})
+			if err != nil {
+				yield("", err)
+				return
+			}
+			for _, yieldId := range result.YieldIds {
+				if !yield(yieldId, nil) {
+					return
+				}
+			}
+			if result.NextPageToken == "" {
+				return
+			}
+			pageToken = result.NextPageToken
+		}
+	}
+}
+
+// SupportedYields is SupportedYieldIdsOnChain with each yield's chain, so
+// a multi-chain wallet can group yields without a Capabilities call each.
+func (c *Client) SupportedYields(ctx context.Context, chainId string) ([]SupportedYield, error) {
+	result, err := c.supportedYields(ctx, ShieldRequest{ChainId: chainId})
+	if err != nil {
+		return nil, err
+	}
+	return result.Yields, nil
+}
+
+// SupportedYieldIdsCached is SupportedYieldIds, but sends the RegistryHash
+// of the last list it returned as IfNoneMatch, so an unchanged list is
+// neither transferred nor parsed again. The returned slice is shared
+// between calls and must not be modified.
+func (c *Client) SupportedYieldIdsCached(ctx context.Context) ([]string, error) {
+	c.yieldIdsMu.Lock()
+	yieldIds, hash := c.yieldIds, c.yieldIdsHash
+	c.yieldIdsMu.Unlock()
+
+	result, err := c.supportedYields(ctx, ShieldRequest{IfNoneMatch: hash})
+	if err != nil {
+		return nil, err
+	}
+	if result.NotModified {
+		return yieldIds, nil
+	}
+
+	c.yieldIdsMu.Lock()
+	c.yieldIds, c.yieldIdsHash = result.YieldIds, result.RegistryHash
+	c.yieldIdsMu.Unlock()
+	return result.YieldIds, nil
+}
+
+// supportedYields sends request as a getSupportedYieldIds request.
+func (c *Client) supportedYields(ctx context.Context, request ShieldRequest) (*ShieldResult, error) {
+	request.ApiVersion = c.apiVersion
+	request.Operation = "getSupportedYieldIds"
+	response, err := c.Send(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if err := responseError(response.Ok, response.Error); err != nil {
+		return nil, err
+	}
+	return &response.Result, nil
+}
+
+// DetectYields lists the yields whose rules unsignedTransaction matches,
+// for a transaction whose yield is not known yet, optionally only among
+// those on chainId. No match is not an error.
+func (c *Client) DetectYields(ctx context.Context, unsignedTransaction, chainId string) ([]YieldMatch, error) {
+	response, err := c.Send(ctx, ShieldRequest{
+		ApiVersion:          c.apiVersion,
+		Operation:           "detectYields",
+		UnsignedTransaction: unsignedTransaction,
+		ChainId:             chainId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := responseError(response.Ok, response.Error); err != nil {
+		return nil, err
+	}
+	return response.Result.Matches, nil
+}
+
+// Capabilities asks Shield which transaction types, chain and contracts
+// yieldId supports.
+func (c *Client) Capabilities(ctx context.Context, yieldId string) (*ShieldCapabilitiesResponse, error) {
+	request := ShieldRequest{
+		ApiVersion: c.apiVersion,
+		Operation:  "getYieldCapabilities",
+		YieldId:    yieldId,
+	}
+
+	var response ShieldCapabilitiesResponse
+	if err := c.call(ctx, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Yields describes each of yieldIds in one Shield invocation, by yieldId,
+// and lists the ids Shield does not support. A Shield error response is
+// returned as a *ShieldError.
+func (c *Client) Yields(ctx context.Context, yieldIds []string) (map[string]YieldCapabilities, []string, error) {
+	request := ShieldRequest{
+		ApiVersion: c.apiVersion,
+		Operation:  "getYields",
+		YieldIds:   yieldIds,
+	}
+
+	var response ShieldYieldsResponse
+	if err := c.call(ctx, request, &response); err != nil {
+		return nil, nil, err
+	}
+	if err := responseError(response.Ok, response.Error); err != nil {
+		return nil, nil, err
+	}
+
+	yields := make(map[string]YieldCapabilities, len(response.Result.Yields))
+	for _, yield := range response.Result.Yields {
+		yields[yield.YieldId] = yield
+	}
+	return yields, response.Result.Unknown, nil
+}
+
+// Abi asks Shield for the functions yieldId's transactions call, e.g. to
+// build calldata or an allowlist of selectors. An empty transactionType
+// lists the functions of every type.
+func (c *Client) Abi(ctx context.Context, yieldId string, transactionType DetectedType) (*ShieldAbiResponse, error) {
+	request := ShieldRequest{
+		ApiVersion:      c.apiVersion,
+		Operation:       "getYieldAbi",
+		YieldId:         yieldId,
+		TransactionType: transactionType,
+	}
+
+	var response ShieldAbiResponse
+	if err := c.call(ctx, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Version reports which Shield build and registry snapshot the binary is.
+func (c *Client) Version(ctx context.Context) (*ShieldVersionResponse, error) {
+	request := ShieldRequest{
+		ApiVersion: c.apiVersion,
+		Operation:  "getVersion",
+	}
+
+	var response ShieldVersionResponse
+	if err := c.call(ctx, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// CheckRegistry lints the registry the binary validates against, with the
+// file of WithRegistry, if any, merged over the built-in one, e.g. to
+// check an override in CI before deploying it.
+func (c *Client) CheckRegistry(ctx context.Context) (*ShieldCheckRegistryResponse, error) {
+	request := ShieldRequest{
+		ApiVersion: c.apiVersion,
+		Operation:  "checkRegistry",
+	}
+
+	var response ShieldCheckRegistryResponse
+	if err := c.call(ctx, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Operations lists the operations the binary supports, so callers can check
+// for one before relying on it rather than assume every binary has it.
+func (c *Client) Operations(ctx context.Context) (*ShieldOperationsResponse, error) {
+	request := ShieldRequest{
+		ApiVersion: c.apiVersion,
+		Operation:  "listOperations",
+	}
+
+	var response ShieldOperationsResponse
+	if err := c.call(ctx, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Health reports whether the binary is ready to validate.
+func (c *Client) Health(ctx context.Context) (*ShieldHealthResponse, error) {
+	request := ShieldRequest{
+		ApiVersion: c.apiVersion,
+		Operation:  "health",
+	}
+
+	var response ShieldHealthResponse
+	if err := c.call(ctx, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// ErrBinaryHashMismatch is returned by VerifyBinary, and by every call of a
+// Client created WithExpectedSHA256, when the binary is not the expected
+// one.
+var ErrBinaryHashMismatch = errors.New("shield binary does not have the expected SHA-256")
+
+// Attest asks the binary for the SHA-256 of the file it runs.
+func (c *Client) Attest(ctx context.Context) (*ShieldAttestResponse, error) {
+	request := ShieldRequest{
+		ApiVersion: c.apiVersion,
+		Operation:  "attest",
+	}
+
+	var response ShieldAttestResponse
+	if err := c.exec(ctx, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// VerifyBinary checks that the binary is the one whose SHA-256 is
+// expected, e.g. the hash of the release's .sha256 file, before any of its
+// results are trusted. The binary reports its own hash through Attest;
+// since a tampered binary could report any, a Client running the binary
+// with ExecRunner or FileRunner also hashes the file itself. Either hash
+// differing fails with an error wrapping ErrBinaryHashMismatch.
+func (c *Client) VerifyBinary(ctx context.Context, expected string) error {
+	expected = strings.ToLower(expected)
+
+	var path string
+	switch runner := c.runner.(type) {
+	case *ExecRunner:
+		path = runner.Path
+	case *FileRunner:
+		path = runner.Path
+	}
+	if path != "" {
+		actual, err := hashFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to hash shield binary: %w", err)
+		}
+		if actual != expected {
+			return fmt.Errorf("%w: %s has %s, expected %s", ErrBinaryHashMismatch, path, actual, expected)
+		}
+	}
+
+	response, err := c.Attest(ctx)
+	if err != nil {
+		return err
+	}
+	if err := responseError(response.Ok, response.Error); err != nil {
+		return err
+	}
+	if response.Result.Sha256 != expected {
+		return fmt.Errorf("%w: shield reports %s, expected %s", ErrBinaryHashMismatch, response.Result.Sha256, expected)
+	}
+	return nil
 }
 
-type ShieldResponse struct {
-	Ok     bool `json:"ok"`
-	Result struct {
-		IsValid      bool     `json:"isValid"`
-		Reason       string   `json:"reason,omitempty"`
-		DetectedType string   `json:"detectedType,omitempty"`
-		YieldIds     []string `json:"yieldIds,omitempty"`
-	} `json:"result"`
-	Error *struct {
-		Code    string `json:"code"`
-		Message string `json:"message"`
-	} `json:"error,omitempty"`
+// verify runs VerifyBinary for a Client created WithExpectedSHA256, until
+// it succeeds or finds a mismatch. Other errors, e.g. a timeout, fail only
+// the call at hand, so that the next one checks again.
+func (c *Client) verify(ctx context.Context) error {
+	if c.expectedSHA256 == "" {
+		return nil
+	}
+
+	c.verifyMu.Lock()
+	defer c.verifyMu.Unlock()
+	if c.verified {
+		return c.verifyErr
+	}
+	err := c.VerifyBinary(ctx, c.expectedSHA256)
+	if err == nil || errors.Is(err, ErrBinaryHashMismatch) {
+		c.verified, c.verifyErr = true, err
+	}
+	return err
+}
+
+// hashFile returns the SHA-256 of the file at path, looked up in PATH as
+// exec does when it has no separator.
+func hashFile(path string) (string, error) {
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return "", err
+	}
+	file, err := os.Open(resolved)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// NegotiateApiVersion returns the newest API version that both this client
+// (ApiVersions) and the binary support, and whether the binary has
+// deprecated it. Pass the result to WithApiVersion.
+func (c *Client) NegotiateApiVersion(ctx context.Context) (string, bool, error) {
+	response, err := c.Version(ctx)
+	if err != nil {
+		return "", false, err
+	}
+
+	var supported, deprecated []string
+	switch {
+	case response.Ok:
+		supported = response.Result.SupportedApiVersions
+		deprecated = response.Result.DeprecatedApiVersions
+	case response.Error != nil && response.Error.Code == "UNSUPPORTED_API_VERSION":
+		// The binary no longer speaks c.apiVersion, but says what it speaks
+		var details struct {
+			SupportedApiVersions []string `json:"supportedApiVersions"`
+		}
+		if err := json.Unmarshal(response.Error.Details, &details); err != nil {
+			return "", false, response.Error
+		}
+		supported = details.SupportedApiVersions
+	default:
+		return "", false, responseError(false, response.Error)
+	}
+
+	for i := len(ApiVersions) - 1; i >= 0; i-- {
+		if contains(supported, ApiVersions[i]) {
+			return ApiVersions[i], contains(deprecated, ApiVersions[i]), nil
+		}
+	}
+	return "", false, fmt.Errorf("no common API version: client speaks %v, shield speaks %v", ApiVersions, supported)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTypedData validates an EIP-2612 permit that userAddress is asked
+// to sign for yieldId. Valid permits come back with DetectedType PERMIT and
+// the allowance in Decoded.Approval and its deadline in Deadline. A
+// deadline more than a day away adds a LONG_DEADLINE warning, and one that
+// has passed fails with ReasonDeadlineInPast. A Gnosis Safe SafeTx is
+// validated as the execTransaction it confirms, userAddress being the Safe,
+// and reports the hash its owners sign in SafeTxHash.
+func (c *Client) ValidateTypedData(ctx context.Context, yieldId, userAddress string, typedData TypedData) (*ShieldResponse, error) {
+	return c.Send(ctx, ShieldRequest{
+		ApiVersion:  c.apiVersion,
+		Operation:   "validateTypedData",
+		YieldId:     yieldId,
+		UserAddress: userAddress,
+		TypedData:   &typedData,
+	})
+}
+
+// ValidateUserOperation validates the calls an ERC-4337 smart account makes
+// for yieldId. paymasters may be nil when the account pays its own gas.
+func (c *Client) ValidateUserOperation(ctx context.Context, yieldId string, userOperation UserOperation, paymasters []string) (*ShieldUserOperationResponse, error) {
+	request := ShieldRequest{
+		ApiVersion:    c.apiVersion,
+		Operation:     "validateUserOperation",
+		YieldId:       yieldId,
+		UserOperation: &userOperation,
+		Paymasters:    paymasters,
+	}
+
+	var response ShieldUserOperationResponse
+	if err := c.call(ctx, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// ValidateSendCalls validates the calls of an EIP-5792 wallet_sendCalls
+// batch for yieldId.
+func (c *Client) ValidateSendCalls(ctx context.Context, yieldId string, sendCalls SendCalls) (*ShieldSendCallsResponse, error) {
+	request := ShieldRequest{
+		ApiVersion: c.apiVersion,
+		Operation:  "validateSendCalls",
+		YieldId:    yieldId,
+		SendCalls:  &sendCalls,
+	}
+
+	var response ShieldSendCallsResponse
+	if err := c.call(ctx, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// CompareIntent checks that unsignedTransaction carries out intent, as
+// validated for intent.YieldId on behalf of userAddress.
+func (c *Client) CompareIntent(ctx context.Context, intent TransactionIntent, unsignedTransaction, userAddress string) (*ShieldIntentResponse, error) {
+	request := ShieldRequest{
+		ApiVersion:          c.apiVersion,
+		Operation:           "compareIntent",
+		UnsignedTransaction: unsignedTransaction,
+		UserAddress:         userAddress,
+		Intent:              &intent,
+	}
+
+	var response ShieldIntentResponse
+	if err := c.call(ctx, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Preflight checks that a transaction of detectedType for yieldId would be
+// allowed before it is built. userAddress and amount, in base units, may be
+// empty.
+func (c *Client) Preflight(ctx context.Context, yieldId string, detectedType DetectedType, userAddress, amount string) (*ShieldPreflightResponse, error) {
+	request := ShieldRequest{
+		ApiVersion:   c.apiVersion,
+		Operation:    "preflight",
+		YieldId:      yieldId,
+		DetectedType: detectedType,
+		UserAddress:  userAddress,
+		Amount:       amount,
+	}
+
+	var response ShieldPreflightResponse
+	if err := c.call(ctx, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (c *Client) call(ctx context.Context, request, response any) error {
+	if err := c.verify(ctx); err != nil {
+		return err
+	}
+	return c.exec(ctx, request, response)
+}
+
+// exec is call without the WithExpectedSHA256 check, which itself calls
+// the binary.
+func (c *Client) exec(ctx context.Context, request, response any) error {
+	inputJSON, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	if c.compress {
+		if inputJSON, err = gzipBytes(inputJSON); err != nil {
+			return fmt.Errorf("failed to compress request: %w", err)
+		}
+	}
+
+	output, stderr, err := c.run(ctx, inputJSON)
+	output, gzipErr := gunzipIfCompressed(output)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("shield process aborted: %w", ctxErr)
+		}
+		// Shield writes an ok:false response even when it exits non-zero;
+		// its error code says more than the exit status
+		if json.Valid(output) && json.Unmarshal(output, response) == nil {
+			return nil
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			err = &ShieldExecError{
+				ExitCode: exitErr.ExitCode(),
+				Stderr:   strings.TrimSpace(string(stderr)),
+			}
+		}
+		return fmt.Errorf("shield process failed: %w", err)
+	}
+
+	if gzipErr != nil {
+		return fmt.Errorf("failed to decompress response: %w", gzipErr)
+	}
+	if err := json.Unmarshal(output, response); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return nil
+}
+
+// run invokes the runner, retrying spawn failures as WithRetry allows. A
+// process that never started has done nothing, so starting it again can
+// neither repeat a side effect nor change a deterministic result.
+func (c *Client) run(ctx context.Context, stdin []byte) ([]byte, []byte, error) {
+	output, stderr, err := c.runOnce(ctx, stdin)
+	for retry := 0; retry < c.retries && isSpawnError(err); retry++ {
+		select {
+		case <-ctx.Done():
+			return output, stderr, err
+		case <-time.After(c.backoff << retry):
+		}
+		output, stderr, err = c.runOnce(ctx, stdin)
+	}
+	return output, stderr, err
+}
+
+// runOnce holds a process slot for the duration of one runner invocation,
+// so a call backing off before a retry leaves its slot to others.
+func (c *Client) runOnce(ctx context.Context, stdin []byte) ([]byte, []byte, error) {
+	if c.slots != nil {
+		select {
+		case c.slots <- struct{}{}:
+		default:
+			if err := c.wait(ctx); err != nil {
+				return nil, nil, err
+			}
+		}
+		defer func() { <-c.slots }()
+	}
+
+	c.active.Add(1)
+	defer c.active.Add(-1)
+	return c.runner.Run(ctx, stdin)
+}
+
+func (c *Client) wait(ctx context.Context) error {
+	defer c.queued.Add(-1)
+	if queued := c.queued.Add(1); c.maxQueued > 0 && queued > c.maxQueued {
+		return ErrQueueFull
+	}
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// spawnErrnos are the fork/exec failures that clear up once the system is
+// under less load
+var spawnErrnos = []syscall.Errno{
+	syscall.EAGAIN,
+	syscall.ENOMEM,
+	syscall.EMFILE,
+	syscall.ENFILE,
+	syscall.ETXTBSY,
+}
+
+func isSpawnError(err error) bool {
+	var exitErr *exec.ExitError
+	if err == nil || errors.As(err, &exitErr) {
+		return false
+	}
+	for _, errno := range spawnErrnos {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
 }
 
+var gzipMagic = []byte{0x1f, 0x8b}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gunzipIfCompressed inflates data that starts with the gzip magic bytes
+// and returns anything else unchanged.
+func gunzipIfCompressed(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// The functions below predate Client and are kept for existing callers.
+
 func CallShield(shieldPath string, request ShieldRequest) (*ShieldResponse, error) {
+	return CallShieldContext(context.Background(), shieldPath, request)
+}
+
+// CallShieldContext is NewClient(shieldPath).Send(ctx, request).
+func CallShieldContext(ctx context.Context, shieldPath string, request ShieldRequest) (*ShieldResponse, error) {
+	return NewClient(shieldPath).Send(ctx, request)
+}
+
+// CallShieldFile is CallShieldContext with a FileRunner, for environments
+// where the request cannot be piped through stdin.
+func CallShieldFile(ctx context.Context, shieldPath string, request ShieldRequest) (*ShieldResponse, error) {
+	return NewClient(shieldPath, WithRunner(&FileRunner{Path: shieldPath})).Send(ctx, request)
+}
+
+// CallShieldBatch is NewClient(shieldPath).ValidateBatch(ctx, request).
+func CallShieldBatch(ctx context.Context, shieldPath string, request ShieldBatchRequest) (*ShieldBatchResponse, error) {
+	return NewClient(shieldPath).ValidateBatch(ctx, request)
+}
+
+// CallShieldFlow is NewClient(shieldPath).ValidateFlow(ctx, yieldId,
+// userAddress, transactions).
+func CallShieldFlow(ctx context.Context, shieldPath, yieldId, userAddress string, transactions []string) (*ShieldFlowResponse, error) {
+	return NewClient(shieldPath).ValidateFlow(ctx, yieldId, userAddress, transactions)
+}
+
+// CallShieldDecode is NewClient(shieldPath).Decode(ctx, unsignedTransaction,
+// yieldId).
+func CallShieldDecode(ctx context.Context, shieldPath, unsignedTransaction, yieldId string) (*ShieldDecodeResponse, error) {
+	return NewClient(shieldPath).Decode(ctx, unsignedTransaction, yieldId)
+}
+
+// CallShieldCapabilities is NewClient(shieldPath).Capabilities(ctx, yieldId).
+func CallShieldCapabilities(ctx context.Context, shieldPath, yieldId string) (*ShieldCapabilitiesResponse, error) {
+	return NewClient(shieldPath).Capabilities(ctx, yieldId)
+}
+
+// CallShieldYields is NewClient(shieldPath).Yields(ctx, yieldIds).
+func CallShieldYields(ctx context.Context, shieldPath string, yieldIds []string) (map[string]YieldCapabilities, []string, error) {
+	return NewClient(shieldPath).Yields(ctx, yieldIds)
+}
+
+// CallShieldAbi is NewClient(shieldPath).Abi(ctx, yieldId, transactionType).
+func CallShieldAbi(ctx context.Context, shieldPath, yieldId string, transactionType DetectedType) (*ShieldAbiResponse, error) {
+	return NewClient(shieldPath).Abi(ctx, yieldId, transactionType)
+}
+
+// CallShieldVersion is NewClient(shieldPath).Version(ctx).
+func CallShieldVersion(ctx context.Context, shieldPath string) (*ShieldVersionResponse, error) {
+	return NewClient(shieldPath).Version(ctx)
+}
+
+// CallShieldOperations is NewClient(shieldPath).Operations(ctx).
+func CallShieldOperations(ctx context.Context, shieldPath string) (*ShieldOperationsResponse, error) {
+	return NewClient(shieldPath).Operations(ctx)
+}
+
+// CallShieldTypedData is NewClient(shieldPath).ValidateTypedData(ctx,
+// yieldId, userAddress, typedData).
+func CallShieldTypedData(ctx context.Context, shieldPath, yieldId, userAddress string, typedData TypedData) (*ShieldResponse, error) {
+	return NewClient(shieldPath).ValidateTypedData(ctx, yieldId, userAddress, typedData)
+}
+
+// CallShieldUserOperation is NewClient(shieldPath).ValidateUserOperation(ctx,
+// yieldId, userOperation, paymasters).
+func CallShieldUserOperation(ctx context.Context, shieldPath, yieldId string, userOperation UserOperation, paymasters []string) (*ShieldUserOperationResponse, error) {
+	return NewClient(shieldPath).ValidateUserOperation(ctx, yieldId, userOperation, paymasters)
+}
+
+// CallShieldSendCalls is NewClient(shieldPath).ValidateSendCalls(ctx,
+// yieldId, sendCalls).
+func CallShieldSendCalls(ctx context.Context, shieldPath, yieldId string, sendCalls SendCalls) (*ShieldSendCallsResponse, error) {
+	return NewClient(shieldPath).ValidateSendCalls(ctx, yieldId, sendCalls)
+}
+
+// CallShieldCompareIntent is NewClient(shieldPath).CompareIntent(ctx,
+// intent, unsignedTransaction, userAddress).
+func CallShieldCompareIntent(ctx context.Context, shieldPath string, intent TransactionIntent, unsignedTransaction, userAddress string) (*ShieldIntentResponse, error) {
+	return NewClient(shieldPath).CompareIntent(ctx, intent, unsignedTransaction, userAddress)
+}
+
+// CallShieldPreflight is NewClient(shieldPath).Preflight(ctx, yieldId,
+// detectedType, userAddress, amount).
+func CallShieldPreflight(ctx context.Context, shieldPath, yieldId string, detectedType DetectedType, userAddress, amount string) (*ShieldPreflightResponse, error) {
+	return NewClient(shieldPath).Preflight(ctx, yieldId, detectedType, userAddress, amount)
+}
+
+// CallShieldHTTP sends request to a Shield instance started with
+// `shield --http <addr>`, e.g. baseURL "http://localhost:8080". The request
+// and response are identical to the stdin protocol; validation failures come
+// back as ok:false with HTTP 200, and any other HTTP status is an error.
+func CallShieldHTTP(ctx context.Context, baseURL string, request ShieldRequest) (*ShieldResponse, error) {
 	inputJSON, err := json.Marshal(request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	cmd := exec.Command(shieldPath)
-	cmd.Stdin = bytes.NewReader(inputJSON)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(baseURL, "/")+"/validate", bytes.NewReader(inputJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build http request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("shield http request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
 
-	output, err := cmd.Output()
+	body, err := io.ReadAll(httpResp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("shield process failed: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("shield http request failed: status %d: %s", httpResp.StatusCode, strings.TrimSpace(string(body)))
 	}
 
 	var response ShieldResponse
-	if err := json.Unmarshal(output, &response); err != nil {
+	if err := json.Unmarshal(body, &response); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
+	return &response, nil
+}
+
+// ShieldClient keeps a single `shield --serve` process running and
+// multiplexes requests over its stdin and stdout, avoiding the process
+// startup cost of CallShield. Responses are routed back to their caller by
+// requestId, so no ordering is assumed.
+//
+// It is safe for concurrent use: any number of goroutines may call Validate
+// at once, and Reset and Close may be called from any of them. A process
+// that exits is started again by the next Validate, and with
+// WithHealthCheck one that stops answering is restarted too.
+type ShieldClient struct {
+	shieldPath    string
+	healthEvery   time.Duration
+	healthTimeout time.Duration
+
+	mu     sync.Mutex
+	proc   *serveProcess
+	nextID uint64
+	closed bool
+
+	stop chan struct{} // Closed by Close to end the health probe
+}
+
+// serveProcess is one run of `shield --serve` and the requests in flight
+// on it.
+type serveProcess struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[string]chan serveResult
+	exited  bool
+	exitErr error // What in-flight requests fail with
+
+	done    chan struct{}
+	waitErr error
+}
+
+type serveResult struct {
+	line []byte
+	err  error
+}
+
+// ErrClientClosed is returned by ShieldClient.Validate after Close. A
+// request in flight when the process exits fails with it too.
+var ErrClientClosed = errors.New("shield client closed")
+
+// ErrClientReset is returned by every ShieldClient.Validate still in flight
+// when Reset kills the process, or the health probe restarts it.
+var ErrClientReset = errors.New("shield client reset")
+
+// shutdownTimeout bounds how long Close waits for the process to exit after
+// its stdin is closed before killing it.
+const shutdownTimeout = 5 * time.Second
+
+// ShieldClientOption configures a ShieldClient.
+type ShieldClientOption func(*ShieldClient)
+
+// WithHealthCheck has the ShieldClient send a health request every
+// interval, and Reset the process if it is not answered within timeout.
+// Without it a process that hangs is only replaced by calling Reset.
+func WithHealthCheck(interval, timeout time.Duration) ShieldClientOption {
+	return func(c *ShieldClient) {
+		c.healthEvery = interval
+		c.healthTimeout = timeout
+	}
+}
+
+// NewShieldClient starts shieldPath in serve mode.
+func NewShieldClient(shieldPath string, opts ...ShieldClientOption) (*ShieldClient, error) {
+	c := &ShieldClient{shieldPath: shieldPath, stop: make(chan struct{})}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	proc, err := startServeProcess(shieldPath)
+	if err != nil {
+		return nil, err
+	}
+	c.proc = proc
+	if c.healthEvery > 0 {
+		go c.probe()
+	}
+	return c, nil
+}
+
+func startServeProcess(shieldPath string) (*serveProcess, error) {
+	cmd := exec.Command(shieldPath, "--serve")
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open shield stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open shield stdout: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start shield process: %w", err)
+	}
+
+	p := &serveProcess{
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[string]chan serveResult),
+		done:    make(chan struct{}),
+	}
+	go p.readLoop(stdout)
+	return p, nil
+}
+
+// Validate sends request to the running Shield process and waits for its
+// response, first starting the process again if it has exited. If
+// request.RequestId is empty the client assigns one; a caller-supplied
+// RequestId must not be reused while it is still in flight.
+func (c *ShieldClient) Validate(request ShieldRequest) (*ShieldResponse, error) {
+	_, line, err := c.send(request, 0)
+	if err != nil {
+		return nil, err
+	}
 
+	var response ShieldResponse
+	if err := json.Unmarshal(line, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
 	return &response, nil
 }
 
+// errHealthTimeout is how send reports that timeout passed first.
+var errHealthTimeout = errors.New("shield process did not answer in time")
+
+// send writes request to the process and waits for its response line, at
+// most timeout if that is not 0. It returns the process it was sent to.
+func (c *ShieldClient) send(request ShieldRequest, timeout time.Duration) (*serveProcess, []byte, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, nil, ErrClientClosed
+	}
+	if c.proc.hasExited() {
+		if err := c.restartLocked(); err != nil {
+			c.mu.Unlock()
+			return nil, nil, err
+		}
+	}
+	proc := c.proc
+	if request.RequestId == "" {
+		request.RequestId = "go-" + strconv.FormatUint(c.nextID, 10)
+		c.nextID++
+	}
+	c.mu.Unlock()
+
+	ch := make(chan serveResult, 1)
+	if err := proc.register(request.RequestId, ch); err != nil {
+		return proc, nil, err
+	}
+
+	line, err := json.Marshal(request)
+	if err == nil {
+		err = proc.writeLine(line)
+	}
+	if err != nil {
+		proc.unregister(request.RequestId)
+		return proc, nil, err
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case res := <-ch:
+		return proc, res.line, res.err
+	case <-expired:
+		proc.unregister(request.RequestId)
+		return proc, nil, errHealthTimeout
+	}
+}
+
+// Reset kills the Shield process and starts a new one. Requests in flight
+// on the old process fail with ErrClientReset; ones sent after Reset
+// returns go to the new process. If the new process cannot be started,
+// the next Validate tries again.
+func (c *ShieldClient) Reset() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrClientClosed
+	}
+	return c.restartLocked()
+}
+
+// restartLocked replaces c.proc, killing it first if it is still running.
+// c.mu must be held.
+func (c *ShieldClient) restartLocked() error {
+	c.proc.kill(ErrClientReset)
+	proc, err := startServeProcess(c.shieldPath)
+	if err != nil {
+		return err
+	}
+	c.proc = proc
+	return nil
+}
+
+// probe sends a health request every healthEvery until Close, and restarts
+// a process that leaves it unanswered for healthTimeout.
+func (c *ShieldClient) probe() {
+	ticker := time.NewTicker(c.healthEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+
+		request := ShieldRequest{
+			ApiVersion: ApiVersions[len(ApiVersions)-1],
+			Operation:  "health",
+		}
+		proc, _, err := c.send(request, c.healthTimeout)
+		if err == nil || proc == nil || errors.Is(err, ErrClientReset) {
+			continue
+		}
+
+		// Another caller may have replaced the process in the meantime
+		c.mu.Lock()
+		if !c.closed && c.proc == proc {
+			c.restartLocked()
+		}
+		c.mu.Unlock()
+	}
+}
+
+// Close closes the process' stdin so it can exit cleanly, killing it if it
+// is still running after a short grace period. Requests still in flight
+// fail with ErrClientClosed.
+func (c *ShieldClient) Close() error {
+	c.mu.Lock()
+	proc := c.proc
+	if c.closed {
+		c.mu.Unlock()
+		<-proc.done
+		return nil
+	}
+	c.closed = true
+	close(c.stop)
+	c.mu.Unlock()
+
+	proc.stdin.Close()
+	select {
+	case <-proc.done:
+	case <-time.After(shutdownTimeout):
+		proc.cmd.Process.Kill()
+		<-proc.done
+	}
+	return proc.waitErr
+}
+
+func (p *serveProcess) hasExited() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.exited
+}
+
+func (p *serveProcess) register(requestId string, ch chan serveResult) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.exited {
+		return p.exitErr
+	}
+	if _, inFlight := p.pending[requestId]; inFlight {
+		return fmt.Errorf("request id %q is already in flight", requestId)
+	}
+	p.pending[requestId] = ch
+	return nil
+}
+
+func (p *serveProcess) unregister(requestId string) {
+	p.mu.Lock()
+	delete(p.pending, requestId)
+	p.mu.Unlock()
+}
+
+// writeLine writes a single request line. Writes are serialized so that
+// concurrent requests never interleave on the pipe.
+func (p *serveProcess) writeLine(line []byte) error {
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+
+	if _, err := p.stdin.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write request: %w", err)
+	}
+	return nil
+}
+
+// kill ends the process, failing the requests in flight with reason, and
+// waits for it to exit. A process that has already exited is left as is.
+func (p *serveProcess) kill(reason error) {
+	p.mu.Lock()
+	if !p.exited {
+		p.exitErr = reason
+	}
+	p.mu.Unlock()
+
+	p.cmd.Process.Kill()
+	<-p.done
+}
+
+// readLoop delivers each response line to the request with the matching
+// requestId. It runs until the process closes its stdout. Lines that cannot
+// be correlated (e.g. a MISSING_REQUEST_ID error) are dropped.
+func (p *serveProcess) readLoop(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := append([]byte(nil), scanner.Bytes()...)
+
+		var envelope struct {
+			RequestId string `json:"requestId"`
+		}
+		if err := json.Unmarshal(line, &envelope); err != nil || envelope.RequestId == "" {
+			continue
+		}
+
+		p.mu.Lock()
+		ch, ok := p.pending[envelope.RequestId]
+		delete(p.pending, envelope.RequestId)
+		p.mu.Unlock()
+
+		if ok {
+			ch <- serveResult{line: line}
+		}
+	}
+
+	p.mu.Lock()
+	p.exited = true
+	if p.exitErr == nil {
+		p.exitErr = scanner.Err()
+	}
+	if p.exitErr == nil {
+		p.exitErr = ErrClientClosed
+	}
+	for id, ch := range p.pending {
+		ch <- serveResult{err: p.exitErr}
+		delete(p.pending, id)
+	}
+	p.mu.Unlock()
+
+	p.waitErr = p.cmd.Wait()
+	close(p.done)
+}
+
 func main() {
+	client := NewClient("./shield", WithTimeout(10*time.Second))
+	ctx := context.Background()
+
 	// Example 1: Get supported yield IDs
-	resp, err := CallShield("./shield", ShieldRequest{
-		ApiVersion: "1.0",
-		Operation:  "getSupportedYieldIds",
-	})
+	yieldIds, err := client.SupportedYieldIds(ctx)
 	if err != nil {
 		panic(err)
 	}
-	fmt.Printf("Supported yields: %v\n", resp.Result.YieldIds)
+	fmt.Printf("Supported yields: %v\n", yieldIds)
+
+	if version, err := client.Version(ctx); err == nil && version.Ok {
+		fmt.Printf("Shield %s (%s), registry %s\n", version.Result.Version, version.Result.GitCommit, version.Result.Registry.Hash)
+	}
 
 	// Example 2: Validate a transaction
 	tx := `{"to":"0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84","from":"0x742d35cc6634c0532925a3b844bc9e7595f0beb8","value":"0xde0b6b3a7640000","data":"0xa1903eab000000000000000000000000742d35cc6634c0532925a3b844bc9e7595f0beb8","chainId":1}`
 
-	resp, err = CallShield("./shield", ShieldRequest{
-		ApiVersion:          "1.0",
-		Operation:           "validate",
+	resp, err := client.Validate(ctx, ShieldRequest{
 		YieldId:             "ethereum-eth-lido-staking",
 		UnsignedTransaction: tx,
 		UserAddress:         "0x742d35cc6634c0532925a3b844bc9e7595f0beb8",
@@ -83,11 +3336,15 @@ func main() {
 	}
 
 	if resp.Ok && resp.Result.IsValid {
-		fmt.Printf("✅ Valid transaction (type: %s)\n", resp.Result.DetectedType)
+		fmt.Printf("✅ Valid transaction (type: %s, risk: %s)\n", resp.Result.DetectedType, resp.Result.RiskLevel)
+		for _, w := range resp.Result.Warnings {
+			fmt.Printf("   ⚠️ %s: %s\n", w.Code, w.Message)
+		}
 	} else if resp.Ok {
-		fmt.Printf("❌ Invalid: %s\n", resp.Result.Reason)
+		fmt.Printf("❌ Invalid (%s): %s\n", resp.Result.ReasonCode, resp.Result.Reason)
+	} else if err := AsShieldError(resp); errors.Is(err, ErrUnsupportedApiVersion) {
+		fmt.Printf("⚠️ Upgrade the client or the binary: %v\n", err)
 	} else {
-		fmt.Printf("⚠️ Error: %s - %s\n", resp.Error.Code, resp.Error.Message)
+		fmt.Printf("⚠️ Error: %v\n", err)
 	}
 }
-
@@ -1,5 +1,6 @@
 import { defineConfig } from '@rslib/core';
 import path from 'node:path';
+import { getBuildDefines } from './scripts/build-info';
 
 export default defineConfig({
   lib: [
@@ -29,6 +30,7 @@ export default defineConfig({
   ],
   source: {
     tsconfigPath: path.join(__dirname, 'tsconfig.build.json'),
+    define: getBuildDefines(),
   },
   output: {
     cleanDistPath: true,
@@ -0,0 +1,2 @@
+// Injected wherever the bundle refers to the Buffer global
+export { Buffer } from 'buffer';
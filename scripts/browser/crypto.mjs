@@ -0,0 +1,21 @@
+import { concat, sha256, toUtf8Bytes } from 'ethers';
+
+// Shield only hashes with SHA-256, for requestHash and the registry hashes
+export function createHash(algorithm) {
+  if (algorithm !== 'sha256') {
+    throw new Error(`Unsupported hash in the browser: ${algorithm}`);
+  }
+  const chunks = [];
+  return {
+    update(data) {
+      chunks.push(typeof data === 'string' ? toUtf8Bytes(data) : data);
+      return this;
+    },
+    digest(encoding) {
+      if (encoding !== 'hex') {
+        throw new Error(`Unsupported digest encoding: ${encoding}`);
+      }
+      return sha256(concat(chunks)).slice(2);
+    },
+  };
+}
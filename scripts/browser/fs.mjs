@@ -0,0 +1,10 @@
+// attest and registry files need a file system, which a page has none of
+function unavailable() {
+  throw new Error('The file system is not available in the browser');
+}
+
+export const closeSync = unavailable;
+export const openSync = unavailable;
+export const readFileSync = unavailable;
+export const readSync = unavailable;
+export const realpathSync = unavailable;
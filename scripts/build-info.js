@@ -0,0 +1,39 @@
+const { execSync } = require('child_process');
+const { version } = require('../package.json');
+
+// Release builds run from a checkout; a source tarball has no git metadata
+function gitCommit() {
+  if (process.env.GITHUB_SHA) return process.env.GITHUB_SHA;
+  try {
+    return execSync('git rev-parse HEAD', {
+      stdio: ['ignore', 'pipe', 'ignore'],
+    })
+      .toString()
+      .trim();
+  } catch {
+    return 'unknown';
+  }
+}
+
+// SOURCE_DATE_EPOCH keeps the build date reproducible when it is set
+function buildDate() {
+  const epoch = process.env.SOURCE_DATE_EPOCH;
+  const date = /^\d+$/.test(epoch ?? '')
+    ? new Date(Number(epoch) * 1000)
+    : new Date();
+  return date.toISOString();
+}
+
+/**
+ * Compile-time constants read by src/version.ts, in the form both rslib's
+ * source.define and esbuild's define expect.
+ */
+function getBuildDefines() {
+  return {
+    __SHIELD_VERSION__: JSON.stringify(version),
+    __SHIELD_GIT_COMMIT__: JSON.stringify(gitCommit()),
+    __SHIELD_BUILD_DATE__: JSON.stringify(buildDate()),
+  };
+}
+
+module.exports = { getBuildDefines };
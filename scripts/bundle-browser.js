@@ -0,0 +1,35 @@
+const esbuild = require('esbuild');
+const path = require('path');
+const { getBuildDefines } = require('./build-info');
+
+const src = path.join(__dirname, '..', 'src');
+const shims = path.join(__dirname, 'browser');
+
+// Shield's own imports of Node built-ins go to the shims in scripts/browser.
+// Dependencies keep theirs, which their browser builds already replace
+const nodeShims = {
+  name: 'node-shims',
+  setup(build) {
+    build.onResolve({ filter: /^(crypto|fs)$/ }, (args) =>
+      args.importer.startsWith(src)
+        ? { path: path.join(shims, `${args.path}.mjs`) }
+        : undefined,
+    );
+  },
+};
+
+// Bundles the library into one ES module for the browser, with Buffer, which
+// the decoders use throughout, from the buffer package
+esbuild
+  .build({
+    entryPoints: ['src/index.ts'],
+    bundle: true,
+    platform: 'browser',
+    format: 'esm',
+    target: 'es2020',
+    outfile: 'dist/shield.browser.mjs',
+    plugins: [nodeShims],
+    inject: [path.join(shims, 'buffer.mjs')],
+    define: getBuildDefines(),
+  })
+  .catch(() => process.exit(1));
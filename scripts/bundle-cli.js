@@ -0,0 +1,12 @@
+const esbuild = require('esbuild');
+const { getBuildDefines } = require('./build-info');
+
+// Bundles the CLI into a single file for the SEA binary
+esbuild.buildSync({
+  entryPoints: ['src/cli.ts'],
+  bundle: true,
+  platform: 'node',
+  target: 'node20',
+  outfile: 'dist/cli.bundled.js',
+  define: getBuildDefines(),
+});
@@ -0,0 +1,48 @@
+import { createHash } from 'crypto';
+import { mkdtempSync, realpathSync, rmSync, writeFileSync } from 'fs';
+import { tmpdir } from 'os';
+import { join } from 'path';
+import { getAttestation } from './attestation';
+
+describe('getAttestation', () => {
+  const contents = 'console.log("shield");\n';
+  const sha256 = createHash('sha256').update(contents).digest('hex');
+
+  let dir: string;
+  let path: string;
+  beforeEach(() => {
+    // Resolved, as the temporary directory may be a symlink, e.g. on macOS
+    dir = realpathSync(mkdtempSync(join(tmpdir(), 'shield-attest-')));
+    path = join(dir, 'shield');
+    writeFileSync(path, contents);
+  });
+  afterEach(() => rmSync(dir, { recursive: true, force: true }));
+
+  it('should hash the file the process runs', () => {
+    const result = getAttestation(path);
+
+    expect(result.path).toBe(path);
+    expect(result.sha256).toBe(sha256);
+    expect(result.kind).toBe('script');
+    expect(result.checksum).toEqual({ status: 'absent' });
+  });
+
+  it('should compare the checksum file next to it', () => {
+    writeFileSync(`${path}.sha256`, `${sha256.toUpperCase()}  shield\n`);
+
+    expect(getAttestation(path).checksum).toEqual({
+      status: 'match',
+      path: `${path}.sha256`,
+    });
+  });
+
+  it('should report a checksum file that does not match', () => {
+    writeFileSync(`${path}.sha256`, `${'0'.repeat(64)}  shield\n`);
+
+    expect(getAttestation(path).checksum.status).toBe('mismatch');
+  });
+
+  it('should throw when the file cannot be read', () => {
+    expect(() => getAttestation(join(dir, 'missing'))).toThrow();
+  });
+});
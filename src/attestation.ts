@@ -0,0 +1,73 @@
+import { createHash } from 'crypto';
+import { closeSync, openSync, readFileSync, readSync, realpathSync } from 'fs';
+import type { AttestResult } from './json/types';
+
+// Read in chunks, since a single executable binary is tens of megabytes
+const CHUNK_SIZE = 1024 * 1024;
+
+// The file does not change while the process runs it, so it is hashed once
+const cache = new Map<string, AttestResult>();
+
+/**
+ * The SHA-256 of the file this process runs: the binary itself when it is
+ * a single executable application, else the script node was started with.
+ * Node passes a single executable its own path as that script, so both are
+ * process.argv[1]. A release's checksum file next to it, "<file>.sha256"
+ * as shasum writes it, is compared too. Throws when the file cannot be
+ * read.
+ */
+export function getAttestation(path = getExecutablePath()): AttestResult {
+  const file = realpathSync(path);
+  let result = cache.get(file);
+  if (!result) {
+    const sha256 = hashFile(file);
+    result = {
+      path: file,
+      kind: file === realpathSync(process.execPath) ? 'binary' : 'script',
+      sha256,
+      checksum: readChecksum(file, sha256),
+    };
+    cache.set(file, result);
+  }
+  return result;
+}
+
+function getExecutablePath(): string {
+  const script = process.argv[1];
+  if (script === undefined) {
+    throw new Error('The process was not started with a script');
+  }
+  return script;
+}
+
+function hashFile(path: string): string {
+  const hash = createHash('sha256');
+  const buffer = Buffer.alloc(CHUNK_SIZE);
+  const fd = openSync(path, 'r');
+  try {
+    let bytesRead: number;
+    while ((bytesRead = readSync(fd, buffer, 0, CHUNK_SIZE, null)) > 0) {
+      hash.update(buffer.subarray(0, bytesRead));
+    }
+  } finally {
+    closeSync(fd);
+  }
+  return hash.digest('hex');
+}
+
+function readChecksum(path: string, sha256: string): AttestResult['checksum'] {
+  const checksumPath = `${path}.sha256`;
+  let contents: string;
+  try {
+    contents = readFileSync(checksumPath, 'utf8');
+  } catch {
+    return { status: 'absent' };
+  }
+
+  // "<hex>  <name>", possibly preceded by a byte order mark on Windows
+  const expected = contents.replace(/^\uFEFF/, '').trim().split(/\s+/)[0];
+  return {
+    status: expected.toLowerCase() === sha256 ? 'match' : 'mismatch',
+    path: checksumPath,
+  };
+}
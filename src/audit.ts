@@ -0,0 +1,55 @@
+import { createHash } from 'crypto';
+import type { AuditRecord, JsonRequest, ValidateResult } from './json/types';
+import { getVersionInfo } from './version';
+import {
+  getRegistryOverrideHash,
+  type VaultRegistryOverride,
+} from './validators/evm/erc4626';
+
+// Neither changes while the process runs, and hashing the embedded registry
+// takes longer than a validation
+let build: { version: string; registryHash: string } | undefined;
+
+/**
+ * The audit record of a validate result, returned at timestamp for request,
+ * validated against the built-in registry with registryOverride, as it was
+ * merged for the request, registered over it.
+ */
+export function createAuditRecord(
+  request: JsonRequest,
+  result: ValidateResult,
+  registryOverride: VaultRegistryOverride | undefined,
+  timestamp = new Date(),
+): AuditRecord {
+  if (!build) {
+    const { version, registry } = getVersionInfo();
+    build = { version, registryHash: registry.hash };
+  }
+
+  // Keys in the order AuditRecord lists them, so that the same decision
+  // always serializes the same
+  const transaction = request.unsignedTransaction ?? request.rawTransaction;
+  const fields: Omit<AuditRecord, 'recordHash'> = {
+    timestamp: timestamp.toISOString(),
+    ...(request.requestId !== undefined && { requestId: request.requestId }),
+    ...((result.yieldId ?? request.yieldId) !== undefined && {
+      yieldId: result.yieldId ?? request.yieldId,
+    }),
+    ...(request.userAddress !== undefined && {
+      userAddress: request.userAddress,
+    }),
+    transactionHash: sha256(transaction ?? ''),
+    verdict: result.isValid ? 'valid' : 'invalid',
+    ...(result.reasonCode !== undefined && { reasonCode: result.reasonCode }),
+    registryHash: build.registryHash,
+    ...(registryOverride !== undefined && {
+      registryOverrideHash: getRegistryOverrideHash(registryOverride),
+    }),
+    version: build.version,
+  };
+  return { ...fields, recordHash: sha256(JSON.stringify(fields)) };
+}
+
+function sha256(data: string): string {
+  return createHash('sha256').update(data).digest('hex');
+}
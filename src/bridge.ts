@@ -0,0 +1,117 @@
+import { ethers } from 'ethers';
+import type { BridgeLeg } from './types';
+
+// Across V3 SpokePools, by address, with the chain each is deployed on
+const ACROSS_SPOKE_POOLS: Record<string, string> = {
+  '0x5c7bcd6e7de5423a257d81b442095a1a6ced35c5': '1', // Ethereum
+  '0x6f26bf09b1c792e3228e5467807a900a503c0281': '10', // Optimism
+  '0x9295ee1d8c5b022be115a2ad3c30c72e34e7f096': '137', // Polygon
+  '0x09aea4b2242abc8bb4bb78d537a67a245a7bec64': '8453', // Base
+  '0xe35e9842fceaca96570b734083f4a58e8f7c5f2a': '42161', // Arbitrum
+};
+
+// Across' MulticallHandler, deployed at this address on each chain above.
+// A deposit to it has it make the calls of the deposit's message with the
+// tokens bridged, and send what is left to the message's fallbackRecipient
+export const ACROSS_MULTICALL_HANDLER =
+  '0x924a9f036260DdD5808007E1AA95f08eD08aA569';
+
+const spokePoolInterface = new ethers.Interface([
+  'function depositV3(address depositor, address recipient, address inputToken, address outputToken, uint256 inputAmount, uint256 outputAmount, uint256 destinationChainId, address exclusiveRelayer, uint32 quoteTimestamp, uint32 fillDeadline, uint32 exclusivityDeadline, bytes message)',
+]);
+
+// The message MulticallHandler takes: abi.encode(Instructions)
+const INSTRUCTIONS =
+  'tuple(tuple(address target, bytes callData, uint256 value)[] calls, address fallbackRecipient)';
+
+export interface BridgedCall {
+  to: string;
+  value: string; // Hex quantity
+  data: string;
+}
+
+export interface BridgeCall {
+  leg: BridgeLeg;
+  // The calls the message has made on the destination chain, or null when
+  // the message is not MulticallHandler instructions
+  calls: BridgedCall[] | null;
+}
+
+/**
+ * The bridge leg of a transaction that calls a bridge Shield knows, with
+ * the calls its message makes on the destination chain. Returns null for
+ * any other transaction.
+ */
+export function decodeBridgeCall(tx: {
+  to?: unknown;
+  data?: unknown;
+}): BridgeCall | null {
+  if (typeof tx.to !== 'string' || typeof tx.data !== 'string') return null;
+  const sourceChainId = ACROSS_SPOKE_POOLS[tx.to.toLowerCase()];
+  if (sourceChainId === undefined) return null;
+
+  let parsed: ethers.TransactionDescription | null;
+  try {
+    parsed = spokePoolInterface.parseTransaction({ data: tx.data });
+  } catch {
+    return null;
+  }
+  if (!parsed) return null;
+
+  const [
+    depositor,
+    recipient,
+    inputToken,
+    outputToken,
+    inputAmount,
+    outputAmount,
+    destinationChainId,
+    ,
+    ,
+    fillDeadline,
+    ,
+    message,
+  ] = parsed.args;
+  const instructions = decodeInstructions(message);
+  return {
+    leg: {
+      protocol: 'across',
+      contract: tx.to,
+      sourceChainId,
+      destinationChainId: String(destinationChainId),
+      depositor,
+      recipient,
+      inputToken,
+      inputAmount: String(inputAmount),
+      outputToken,
+      outputAmount: String(outputAmount),
+      fillDeadline: Number(fillDeadline),
+      ...(instructions && {
+        fallbackRecipient: instructions.fallbackRecipient,
+      }),
+    },
+    calls: instructions?.calls ?? null,
+  };
+}
+
+function decodeInstructions(
+  message: string,
+): { calls: BridgedCall[]; fallbackRecipient: string } | null {
+  try {
+    const [[calls, fallbackRecipient]] =
+      ethers.AbiCoder.defaultAbiCoder().decode([INSTRUCTIONS], message);
+    return {
+      calls: Array.from(
+        calls,
+        ([to, data, value]: [string, string, bigint]) => ({
+          to,
+          value: ethers.toQuantity(value),
+          data,
+        }),
+      ),
+      fallbackRecipient,
+    };
+  } catch {
+    return null;
+  }
+}
@@ -1,52 +1,628 @@
 #!/usr/bin/env node
-import { handleJsonRequest, MAX_INPUT_SIZE } from './json';
+import { once } from 'events';
+import { openSync, writeSync } from 'fs';
+import { readFile, stat, writeFile } from 'fs/promises';
+import { gunzipSync, gzipSync } from 'zlib';
+import {
+  handleJsonRequestAsync,
+  type JsonHandlerOptions,
+  MAX_INPUT_SIZE,
+  parseProfile,
+  parseRegistryOverride,
+  ValidationCache,
+} from './json';
+import { createGrpcServer } from './grpc';
+import { createHttpServer, parseListenAddress } from './http';
+import { createJsonLogger, isLogLevel, LOG_LEVELS, Logger } from './logger';
+import { reloadRegistryOverride } from './registry';
+import { runSelfTest } from './selftest';
+import { Shield } from './shield';
+import type { VaultRegistryOverride } from './validators/evm/erc4626';
 
-async function readStdin(): Promise<string> {
+// What every request of this process is handled with
+type HandlerOptions = Pick<
+  JsonHandlerOptions,
+  | 'logger'
+  | 'registryOverride'
+  | 'reloadRegistry'
+  | 'health'
+  | 'validationCache'
+  | 'auditSink'
+  | 'profile'
+  | 'maxInputSize'
+>;
+
+// SECURITY: Output valid JSON even on catastrophic failure
+const INTERNAL_ERROR_RESPONSE = JSON.stringify({
+  ok: false,
+  apiVersion: '1.0',
+  error: {
+    code: 'INTERNAL_ERROR',
+    message: 'Failed to process request',
+  },
+  meta: { requestHash: 'unavailable' },
+});
+
+// Oversized input is reported like any other invalid request, without
+// being read to its end
+function inputTooLargeResponse(maxInputSize: number): string {
+  return JSON.stringify({
+    ok: false,
+    apiVersion: '1.0',
+    error: {
+      code: 'INPUT_TOO_LARGE',
+      message: `Input exceeds maximum size of ${maxInputSize} bytes`,
+    },
+    meta: { requestHash: 'unavailable' },
+  });
+}
+
+// Compressed input that does not inflate is as unparseable as bad JSON
+const INVALID_GZIP_RESPONSE = JSON.stringify({
+  ok: false,
+  apiVersion: '1.0',
+  error: {
+    code: 'PARSE_ERROR',
+    message: 'Invalid gzip input',
+  },
+  meta: { requestHash: 'unavailable' },
+});
+
+// Reported like empty input: there is no request to answer
+const INPUT_TIMEOUT_RESPONSE = JSON.stringify({
+  ok: false,
+  apiVersion: '1.0',
+  error: {
+    code: 'INPUT_TIMEOUT',
+    message: 'stdin did not end within --stdin-timeout',
+  },
+  meta: { requestHash: 'unavailable' },
+});
+
+// Every gzip stream starts with these bytes, and no JSON document does
+const GZIP_MAGIC = Buffer.from([0x1f, 0x8b]);
+
+class InputTooLargeError extends Error {}
+class InvalidGzipError extends Error {}
+class InputTimeoutError extends Error {}
+
+/**
+ * Reads stdin to its end, failing with InputTimeoutError if it has not
+ * ended after timeoutMs, so that a pipe nothing closes cannot hang the
+ * process forever, and with InputTooLargeError once it passes maxInputSize
+ * bytes.
+ */
+async function readStdin(
+  timeoutMs: number | undefined,
+  maxInputSize: number,
+): Promise<Buffer> {
   return new Promise((resolve, reject) => {
     const chunks: Buffer[] = [];
     let totalBytes = 0;
+    const timer =
+      timeoutMs === undefined
+        ? undefined
+        : setTimeout(
+            () => reject(new InputTimeoutError('stdin timed out')),
+            timeoutMs,
+          );
 
     // Don't set encoding - keep as Buffer for accurate byte counting
     process.stdin.on('data', (chunk: Buffer) => {
       totalBytes += chunk.length; // Buffer.length is actual bytes
       // SECURITY: Enforce size limit during streaming
-      if (totalBytes > MAX_INPUT_SIZE) {
-        reject(new Error('Input exceeds maximum size'));
+      if (totalBytes > maxInputSize) {
+        reject(new InputTooLargeError('Input exceeds maximum size'));
         return;
       }
       chunks.push(chunk);
     });
 
     process.stdin.on('end', () => {
-      const data = Buffer.concat(chunks).toString('utf8');
-      resolve(data);
+      clearTimeout(timer);
+      resolve(Buffer.concat(chunks));
     });
 
     process.stdin.on('error', reject);
   });
 }
 
-async function main(): Promise<void> {
+/**
+ * Reads the request from path, or from stdin when no path is given.
+ */
+async function readInput(
+  path: string | undefined,
+  stdinTimeoutMs: number | undefined,
+  maxInputSize: number,
+): Promise<Buffer> {
+  if (path === undefined) return readStdin(stdinTimeoutMs, maxInputSize);
+
+  // SECURITY: Same size limit as stdin, checked before reading the file
+  if ((await stat(path)).size > maxInputSize) {
+    throw new InputTooLargeError('Input exceeds maximum size');
+  }
+  return readFile(path);
+}
+
+/**
+ * Inflates gzip-compressed input, which is recognized by its magic bytes.
+ */
+function decodeInput(raw: Buffer, maxInputSize: number): string {
+  if (raw.subarray(0, 2).compare(GZIP_MAGIC) !== 0) return raw.toString('utf8');
+
   try {
-    const input = await readStdin();
-    const output = handleJsonRequest(input);
+    // SECURITY: The size limit applies to the inflated request too, so a
+    // small gzip bomb cannot expand past it
+    return gunzipSync(raw, { maxOutputLength: maxInputSize }).toString(
+      'utf8',
+    );
+  } catch (error) {
+    if (error instanceof RangeError) {
+      throw new InputTooLargeError('Input exceeds maximum size');
+    }
+    throw new InvalidGzipError('Invalid gzip input');
+  }
+}
+
+async function writeOutput(
+  path: string | undefined,
+  output: string,
+  compress: boolean,
+): Promise<void> {
+  const data = compress ? gzipSync(output + '\n') : output + '\n';
+  if (path === undefined) {
+    process.stdout.write(data);
+    return;
+  }
+  await writeFile(path, data);
+}
+
+/**
+ * Long-running mode: reads newline-delimited JSON requests from stdin and
+ * writes one JSON response per line to stdout until stdin is closed. Every
+ * request must carry a requestId, which is echoed on its response. The
+ * validator registry is loaded once for the lifetime of the process. A line
+ * over the size limit is answered with INPUT_TOO_LARGE, without a requestId
+ * since it is never parsed.
+ */
+async function serve(options: HandlerOptions): Promise<void> {
+  const maxInputSize = options.maxInputSize ?? MAX_INPUT_SIZE;
+  for await (const line of readLines(process.stdin, maxInputSize)) {
+    if (line === undefined) {
+      process.stdout.write(inputTooLargeResponse(maxInputSize) + '\n');
+      continue;
+    }
+    if (line.trim() === '') continue;
+
+    let output: string;
+    try {
+      output = await handleJsonRequestAsync(line, {
+        ...options,
+        requireRequestId: true,
+      });
+    } catch (error) {
+      logInternalError(options.logger, error);
+      output = INTERNAL_ERROR_RESPONSE;
+    }
     process.stdout.write(output + '\n');
+  }
+}
+
+/**
+ * One-shot pipeline mode: reads newline-delimited JSON requests from stdin
+ * and writes one JSON response per line to stdout, in request order, until
+ * stdin ends. Unlike serve mode no requestId is needed, and a request that
+ * fails is answered like any other without ending the stream. Input is read
+ * no faster than stdout takes the responses, so memory stays bounded
+ * however many requests are piped through.
+ */
+async function stream(options: HandlerOptions): Promise<void> {
+  const maxInputSize = options.maxInputSize ?? MAX_INPUT_SIZE;
+  for await (const line of readLines(process.stdin, maxInputSize)) {
+    let output: string;
+    if (line === undefined) {
+      output = inputTooLargeResponse(maxInputSize);
+    } else if (line.trim() === '') {
+      continue;
+    } else {
+      try {
+        output = await handleJsonRequestAsync(line, options);
+      } catch (error) {
+        logInternalError(options.logger, error);
+        output = INTERNAL_ERROR_RESPONSE;
+      }
+    }
+    if (!process.stdout.write(output + '\n')) {
+      await once(process.stdout, 'drain');
+    }
+  }
+}
+
+/**
+ * The lines of input, read only as fast as they are consumed. A line over
+ * maxInputSize bytes is yielded as undefined, and the rest of it is
+ * discarded as it arrives rather than buffered.
+ */
+async function* readLines(
+  input: NodeJS.ReadableStream,
+  maxInputSize: number,
+): AsyncGenerator<string | undefined> {
+  let pending: Buffer[] = [];
+  let pendingBytes = 0;
+  let oversized = false;
+
+  for await (const chunk of input as AsyncIterable<Buffer>) {
+    let start = 0;
+    let end: number;
+    while ((end = chunk.indexOf(0x0a, start)) !== -1) {
+      const part = chunk.subarray(start, end);
+      start = end + 1;
+      // SECURITY: Same size limit as a one-shot request, per line
+      yield oversized || pendingBytes + part.length > maxInputSize
+        ? undefined
+        : Buffer.concat([...pending, part]).toString('utf8');
+      pending = [];
+      pendingBytes = 0;
+      oversized = false;
+    }
+
+    const rest = chunk.subarray(start);
+    if (oversized) continue;
+    if (pendingBytes + rest.length > maxInputSize) {
+      pending = [];
+      pendingBytes = 0;
+      oversized = true;
+      continue;
+    }
+    pending.push(rest);
+    pendingBytes += rest.length;
+  }
+
+  if (oversized) yield undefined;
+  else if (pendingBytes > 0) yield Buffer.concat(pending).toString('utf8');
+}
+
+/**
+ * Serves the JSON protocol over HTTP until the process is terminated.
+ */
+function serveHttp(address: string, options: HandlerOptions): void {
+  const { host, port } = parseListenAddress(address);
+  createHttpServer(options).listen(port, host);
+}
+
+/**
+ * Serves the validate operation over gRPC until the process is terminated.
+ */
+function serveGrpc(address: string, options: HandlerOptions): void {
+  const { host, port } = parseListenAddress(address);
+  createGrpcServer(options).listen(port, host);
+}
+
+function getFlagValue(flag: string): string | undefined {
+  const index = process.argv.indexOf(flag);
+  return index === -1 ? undefined : process.argv[index + 1];
+}
+
+// Like getFlagValue, for flags that must be followed by a path
+function getPathFlag(flag: string): string | undefined {
+  const value = getFlagValue(flag);
+  if (process.argv.includes(flag) && (!value || value.startsWith('--'))) {
+    throw new Error(`${flag} requires a file path`);
+  }
+  return value;
+}
+
+/**
+ * The stderr logger --log-level or, failing that, SHIELD_LOG asks for, or
+ * undefined when neither is set: Shield logs nothing by default.
+ */
+function getLogger(): Logger | undefined {
+  const flagged = process.argv.includes('--log-level');
+  const level = flagged ? getFlagValue('--log-level') : process.env.SHIELD_LOG;
+  if (!flagged && !level) return undefined;
+  if (!isLogLevel(level)) {
+    throw new Error(
+      `Invalid log level: ${level}; expected one of ${LOG_LEVELS.join(', ')}`,
+    );
+  }
+  return createJsonLogger(level);
+}
+
+/**
+ * The vaults of the registry file at path, from --registry, to register
+ * over the built-in registry, or undefined without the flag.
+ */
+async function getRegistryOverride(
+  path: string | undefined,
+): Promise<VaultRegistryOverride | undefined> {
+  if (path === undefined) return undefined;
+  return parseRegistryOverride(await readFile(path, 'utf8'));
+}
+
+/**
+ * The profile of request defaults in the file --profile or, failing that,
+ * SHIELD_PROFILE names, or undefined when neither is set.
+ */
+async function getProfile(): Promise<JsonHandlerOptions['profile']> {
+  const path = getPathFlag('--profile') ?? process.env.SHIELD_PROFILE;
+  if (!path) return undefined;
+  return parseProfile(await readFile(path, 'utf8'));
+}
+
+/**
+ * The cache of validate results --cache-size asks for, holding that many
+ * results for --cache-ttl seconds (30 by default), or undefined without the
+ * flag: results are not cached by default.
+ */
+function getValidationCache(): ValidationCache | undefined {
+  if (!process.argv.includes('--cache-size')) return undefined;
+
+  const maxEntries = Number(getFlagValue('--cache-size'));
+  if (!Number.isInteger(maxEntries) || maxEntries < 1) {
+    throw new Error('--cache-size requires a positive integer');
+  }
+  const ttl = process.argv.includes('--cache-ttl')
+    ? Number(getFlagValue('--cache-ttl'))
+    : 30;
+  if (!(ttl > 0)) {
+    throw new Error('--cache-ttl requires a positive number of seconds');
+  }
+  return new ValidationCache({ maxEntries, ttlMs: ttl * 1000 });
+}
+
+/**
+ * Appends the audit record of every validate result to the file at path,
+ * from --audit-log, one JSON object per line, or undefined without the
+ * flag. Records are written before the response, so none is lost to a
+ * process killed after answering.
+ */
+function getAuditSink(
+  path: string | undefined,
+): JsonHandlerOptions['auditSink'] {
+  if (path === undefined) return undefined;
+  const fd = openSync(path, 'a');
+  return (record) => {
+    writeSync(fd, JSON.stringify(record) + '\n');
+  };
+}
+
+/**
+ * How long one-shot mode waits for stdin to end, from --stdin-timeout
+ * seconds, or undefined without the flag: it waits as long as it takes.
+ */
+function getStdinTimeout(): number | undefined {
+  if (!process.argv.includes('--stdin-timeout')) return undefined;
+  const timeout = Number(getFlagValue('--stdin-timeout'));
+  if (!(timeout > 0)) {
+    throw new Error('--stdin-timeout requires a positive number of seconds');
+  }
+  return timeout * 1000;
+}
+
+/**
+ * How many bytes a request may take, from --max-input-size, or undefined
+ * without the flag: MAX_INPUT_SIZE applies. The limit holds for the whole
+ * input in one-shot mode, once inflated, and for each line in serve and
+ * stream mode.
+ */
+function getMaxInputSize(): number | undefined {
+  if (!process.argv.includes('--max-input-size')) return undefined;
+  const size = Number(getFlagValue('--max-input-size'));
+  if (!Number.isInteger(size) || size < 1) {
+    throw new Error('--max-input-size requires a positive number of bytes');
+  }
+  return size;
+}
+
+/**
+ * How many times --selftest validates each corpus case, from --iterations,
+ * 10 by default.
+ */
+function getSelfTestIterations(): number {
+  if (!process.argv.includes('--iterations')) return 10;
+  const iterations = Number(getFlagValue('--iterations'));
+  if (!Number.isInteger(iterations) || iterations < 1) {
+    throw new Error('--iterations requires a positive integer');
+  }
+  return iterations;
+}
+
+/**
+ * Lets a long-running process reload the registry file at path, on SIGHUP
+ * or a reloadRegistry request, without a restart. A file that fails to
+ * load leaves the previous registry in place, but reports the process not
+ * ready until a reload succeeds. Every reload is logged on stderr, even
+ * when no log level is set.
+ */
+function enableRegistryReload(
+  path: string | undefined,
+  options: HandlerOptions,
+): void {
+  if (path === undefined) return;
+
+  const logger = options.logger ?? createJsonLogger('info');
+  options.reloadRegistry = () => {
+    try {
+      const result = reloadRegistryOverride(path, options);
+      options.health = undefined;
+      logger.log('info', 'registry reloaded', {
+        path,
+        added: result.added,
+        removed: result.removed,
+        yieldCount: result.registry.yieldCount,
+        overrideHash: result.registry.overrideHash,
+      });
+      return result;
+    } catch (error) {
+      const message = error instanceof Error ? error.message : String(error);
+      options.health = {
+        ready: false,
+        reason: `Registry reload failed: ${message}`,
+      };
+      logger.log('error', 'registry reload failed', { path, error: message });
+      throw error;
+    }
+  };
+
+  process.on('SIGHUP', () => {
+    try {
+      options.reloadRegistry!();
+    } catch {
+      // Already logged, and the previous registry stays loaded
+    }
+  });
+}
+
+// The handler answers every request it can; this is for those it cannot
+function logInternalError(logger: Logger | undefined, error: unknown): void {
+  logger?.log('error', 'request failed', {
+    errorCode: 'INTERNAL_ERROR',
+    error: error instanceof Error ? error.message : String(error),
+  });
+}
+
+async function main(): Promise<void> {
+  let logger: Logger | undefined;
+  let registryPath: string | undefined;
+  let registryOverride: VaultRegistryOverride | undefined;
+  let validationCache: ValidationCache | undefined;
+  let auditSink: JsonHandlerOptions['auditSink'];
+  let profile: JsonHandlerOptions['profile'];
+  let maxInputSize: number | undefined;
+  try {
+    logger = getLogger();
+    registryPath = getPathFlag('--registry');
+    registryOverride = await getRegistryOverride(registryPath);
+    validationCache = getValidationCache();
+    auditSink = getAuditSink(getPathFlag('--audit-log'));
+    profile = await getProfile();
+    maxInputSize = getMaxInputSize();
+  } catch (error) {
+    process.stderr.write(
+      `${error instanceof Error ? error.message : String(error)}\n`,
+    );
+    process.exit(2);
+  }
+  const options: HandlerOptions = {
+    logger,
+    registryOverride,
+    validationCache,
+    auditSink,
+    profile,
+    maxInputSize,
+  };
+
+  // A pre-deploy gate: the report goes to stdout, and any case whose
+  // verdict changed fails the run
+  if (process.argv.includes('--selftest')) {
+    let iterations: number;
+    try {
+      iterations = getSelfTestIterations();
+    } catch (error) {
+      process.stderr.write(
+        `${error instanceof Error ? error.message : String(error)}\n`,
+      );
+      process.exit(2);
+    }
+    const result = runSelfTest(new Shield({ registryOverride }), iterations);
+    process.stdout.write(`${JSON.stringify(result)}\n`);
+    process.exit(result.passed ? 0 : 1);
+  }
+
+  if (process.argv.includes('--http')) {
+    enableRegistryReload(registryPath, options);
+    try {
+      serveHttp(getFlagValue('--http') ?? '', options);
+    } catch (error) {
+      process.stderr.write(
+        `${error instanceof Error ? error.message : String(error)}\n`,
+      );
+      process.exit(2);
+    }
+    return;
+  }
+
+  if (process.argv.includes('--grpc')) {
+    enableRegistryReload(registryPath, options);
+    try {
+      serveGrpc(getFlagValue('--grpc') ?? '', options);
+    } catch (error) {
+      process.stderr.write(
+        `${error instanceof Error ? error.message : String(error)}\n`,
+      );
+      process.exit(2);
+    }
+    return;
+  }
+
+  if (process.argv.includes('--stream')) {
+    await stream(options);
+    process.exit(0);
+  }
+
+  if (process.argv.includes('--serve')) {
+    enableRegistryReload(registryPath, options);
+    await serve(options);
     process.exit(0);
+  }
+
+  // Compresses the response; gzip input is recognized without the flag
+  const compress = process.argv.includes('--compress');
+  let inputPath: string | undefined;
+  let outputPath: string | undefined;
+  let input: string;
+  try {
+    inputPath = getPathFlag('--input');
+    outputPath = getPathFlag('--output');
+    const limit = maxInputSize ?? MAX_INPUT_SIZE;
+    input = decodeInput(
+      await readInput(inputPath, getStdinTimeout(), limit),
+      limit,
+    );
+  } catch (error) {
+    process.stdin.destroy();
+    if (error instanceof InputTooLargeError) {
+      await writeOutput(
+        outputPath,
+        inputTooLargeResponse(maxInputSize ?? MAX_INPUT_SIZE),
+        compress,
+      );
+      process.exit(0);
+    }
+    if (error instanceof InvalidGzipError) {
+      await writeOutput(outputPath, INVALID_GZIP_RESPONSE, compress);
+      process.exit(0);
+    }
+    if (error instanceof InputTimeoutError) {
+      await writeOutput(outputPath, INPUT_TIMEOUT_RESPONSE, compress);
+      process.exit(0);
+    }
+    // A missing or unreadable file is a usage error, like a bad flag
+    process.stderr.write(
+      `${error instanceof Error ? error.message : String(error)}\n`,
+    );
+    process.exit(2);
+  }
+
+  let output = INTERNAL_ERROR_RESPONSE;
+  let exitCode = 1;
+  try {
+    output = await handleJsonRequestAsync(input, options);
+    exitCode = 0;
   } catch (error) {
-    // SECURITY: Output valid JSON even on catastrophic failure
-    const errorResponse = {
-      ok: false,
-      apiVersion: '1.0',
-      error: {
-        code: 'INTERNAL_ERROR',
-        message: 'Failed to process request',
-      },
-      meta: { requestHash: 'unavailable' },
-    };
-    process.stdout.write(JSON.stringify(errorResponse) + '\n');
+    logInternalError(logger, error);
     process.stdin.destroy();
-    process.exit(1);
   }
+
+  try {
+    await writeOutput(outputPath, output, compress);
+  } catch (error) {
+    process.stderr.write(
+      `${error instanceof Error ? error.message : String(error)}\n`,
+    );
+    process.exit(2);
+  }
+  process.exit(exitCode);
 }
 
 void main();
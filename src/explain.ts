@@ -0,0 +1,613 @@
+import {
+  ExplainEntry,
+  ReasonCode,
+  ValidationResult,
+  WarningCode,
+} from './types';
+import { isDefined, isNonEmptyString } from './utils/validation';
+import { BaseValidator } from './validators/base.validator';
+import type { ValidationRequest } from './shield';
+
+interface TraceContext {
+  request: ValidationRequest;
+  validator: BaseValidator;
+  result: ValidationResult;
+  unsignedTransaction: string; // The call a Safe executes, when wrapped
+  isMulticall: boolean;
+}
+
+interface TraceStep {
+  check: string;
+  codes: ReasonCode[]; // What the check fails a transaction with
+  warnings?: WarningCode[]; // What it warns with
+  // Why the check does not apply to the transaction, when it does not
+  skip?: (context: TraceContext) => string | undefined;
+  pass: (context: TraceContext) => string;
+}
+
+const PER_CALL = 'Checked for each batched call; see subResults';
+
+// In the order Shield runs them: a failing check stops the ones after it
+const TRACE_STEPS: TraceStep[] = [
+  {
+    check: 'request',
+    codes: ['INVALID_REQUEST'],
+    pass: () => 'The request parameters are well-formed',
+  },
+  {
+    check: 'chain-id',
+    codes: ['CHAIN_ID_MISMATCH'],
+    warnings: ['UNPROTECTED_REPLAY'],
+    skip: ({ request, validator }) =>
+      isDefined(validator.getChainId(request.unsignedTransaction))
+        ? undefined
+        : 'The transaction does not name its chain',
+    pass: ({ validator }) =>
+      `The transaction is for chain ${validator.getCapabilities().chainId}`,
+  },
+  {
+    check: 'transaction-format',
+    codes: [
+      'ALT_RESOLUTION_REQUIRED',
+      'MALFORMED_NUMERIC',
+      'MALFORMED_TRANSACTION',
+      'INVALID_GAS_FIELDS',
+      'CALLDATA_TOO_LARGE',
+    ],
+    pass: () => 'The transaction and its gas fields are well-formed',
+  },
+  {
+    check: 'safe-wrapper',
+    codes: [
+      'NESTED_MULTISIG',
+      'UNTRUSTED_FORWARDER',
+      'FORWARD_SIGNATURE_MISMATCH',
+    ],
+    warnings: ['DELEGATECALL_USED'],
+    skip: ({ request, validator }) =>
+      isDefined(validator.getWrappedTransaction(request.unsignedTransaction))
+        ? undefined
+        : 'Not a Safe or forwarded transaction',
+    pass: ({ result }) =>
+      result.wrapper?.detectedType === 'ERC2771_FORWARD'
+        ? `Validated by the call forwarder ${result.wrapper.address} relays`
+        : 'Validated by the call the Safe executes',
+  },
+  {
+    check: 'sender',
+    codes: ['SENDER_MISMATCH'],
+    warnings: ['SENDER_NOT_VERIFIED'],
+    skip: ({ validator, unsignedTransaction }) =>
+      isNonEmptyString(validator.getSigner(unsignedTransaction))
+        ? undefined
+        : 'The transaction does not name its sender',
+    pass: ({ validator, unsignedTransaction }) =>
+      `Sent by ${validator.getSigner(unsignedTransaction)}, the user`,
+  },
+  {
+    check: 'multicall',
+    codes: [
+      'MULTICALL_CALL_MISSING',
+      'MULTICALL_CALL_INVALID',
+      'MULTICALL_VALUE_MISMATCH',
+    ],
+    skip: ({ isMulticall }) => (isMulticall ? undefined : 'Not a multicall'),
+    pass: ({ result }) =>
+      `Each of the ${result.subResults?.length ?? 0} batched calls passed`,
+  },
+  {
+    check: 'proxy-upgrade',
+    codes: ['UNEXPECTED_PROXY_UPGRADE'],
+    warnings: ['IMPLEMENTATION_CHANGE'],
+    skip: ({ validator, unsignedTransaction, isMulticall }) => {
+      if (isMulticall) return PER_CALL;
+      return isDefined(validator.getImplementationChange(unsignedTransaction))
+        ? undefined
+        : 'Does not upgrade a proxy';
+    },
+    pass: ({ validator, unsignedTransaction }) =>
+      `Upgrades ${validator.getImplementationChange(unsignedTransaction)?.proxy}, as the yield's ABI expects`,
+  },
+  {
+    check: 'privileged-function',
+    codes: ['PRIVILEGED_FUNCTION_CALL'],
+    skip: ({ validator, unsignedTransaction, isMulticall }) => {
+      if (isMulticall) return PER_CALL;
+      return isDefined(validator.getPrivilegedCall(unsignedTransaction))
+        ? undefined
+        : 'Does not call a privileged function';
+    },
+    pass: ({ validator, unsignedTransaction }) =>
+      `Calls ${validator.getPrivilegedCall(unsignedTransaction)?.functionName}, as the yield's ABI expects`,
+  },
+  {
+    check: 'approval-spender',
+    codes: ['APPROVAL_SPENDER_MISMATCH'],
+    warnings: ['INFINITE_APPROVAL'],
+    skip: ({ validator, unsignedTransaction, isMulticall }) => {
+      if (isMulticall) return PER_CALL;
+      return isDefined(validator.getApproval(unsignedTransaction))
+        ? undefined
+        : 'Not an approval';
+    },
+    pass: ({ validator, unsignedTransaction }) =>
+      `Approves ${validator.getApproval(unsignedTransaction)?.spender}, a spender of the yield`,
+  },
+  {
+    check: 'reward-recipient',
+    codes: ['REWARD_RECIPIENT_MISMATCH'],
+    warnings: ['UNKNOWN_RECIPIENT'],
+    skip: ({ validator, unsignedTransaction, isMulticall }) => {
+      if (isMulticall) return PER_CALL;
+      return isDefined(validator.getClaimCall(unsignedTransaction))
+        ? undefined
+        : 'Not a claim';
+    },
+    pass: ({ validator, unsignedTransaction }) =>
+      `The claim, ${validator.getClaimCall(unsignedTransaction)?.variant}, pays the user`,
+  },
+  {
+    check: 'withdrawal-recipient',
+    codes: ['WITHDRAWAL_RECIPIENT_MISMATCH'],
+    skip: ({ validator, unsignedTransaction, isMulticall }) => {
+      if (isMulticall) return PER_CALL;
+      return isDefined(validator.getWithdrawal(unsignedTransaction))
+        ? undefined
+        : 'Not a withdrawal';
+    },
+    pass: () => 'The withdrawal pays the user',
+  },
+  {
+    check: 'beneficiary',
+    codes: ['BENEFICIARY_MISMATCH'],
+    skip: ({ request, validator, unsignedTransaction, isMulticall }) => {
+      if (isMulticall) return PER_CALL;
+      return isDefined(validator.getBeneficiary(unsignedTransaction)) ||
+        isDefined(request.beneficiaryAddress)
+        ? undefined
+        : 'Credits no account but the sender';
+    },
+    pass: ({ request }) =>
+      isDefined(request.beneficiaryAddress)
+        ? `Credits the beneficiary ${request.beneficiaryAddress}`
+        : 'Credits the user',
+  },
+  {
+    check: 'native-value',
+    codes: ['UNEXPECTED_NATIVE_VALUE'],
+    skip: ({ validator, unsignedTransaction, isMulticall }) => {
+      if (isMulticall) return PER_CALL;
+      return isDefined(validator.getNativeValue(unsignedTransaction))
+        ? undefined
+        : 'Not a call of a known function';
+    },
+    pass: ({ validator, unsignedTransaction }) =>
+      validator.getNativeValue(unsignedTransaction)?.payable
+        ? 'The function accepts native value'
+        : 'No native value is sent to a function that is not payable',
+  },
+  {
+    check: 'recipient',
+    codes: ['RECIPIENT_MISMATCH'],
+    skip: ({ validator, unsignedTransaction, isMulticall }) => {
+      if (isMulticall) return PER_CALL;
+      return validator.getContractAddresses(unsignedTransaction).length > 0
+        ? undefined
+        : 'The transaction calls no contract';
+    },
+    pass: ({ validator, unsignedTransaction }) =>
+      `Calls ${validator.getContractAddresses(unsignedTransaction).join(', ')}, a contract of the yield`,
+  },
+  {
+    check: 'selector',
+    codes: ['SELECTOR_MISMATCH', 'OPERATION_NOT_SUPPORTED_FOR_YIELD'],
+    skip: ({ validator, unsignedTransaction, isMulticall }) => {
+      if (isMulticall) return PER_CALL;
+      return isDefined(validator.getSelector(unsignedTransaction))
+        ? undefined
+        : 'The transaction calls no function';
+    },
+    pass: ({ validator, unsignedTransaction }) =>
+      `Function ${validator.getSelector(unsignedTransaction)} is in the yield's ABI`,
+  },
+  {
+    check: 'transaction-type',
+    codes: [
+      'NO_MATCHING_PATTERN',
+      'NAKED_TRANSFER_NOT_SUPPORTED',
+      'AMBIGUOUS_PATTERN',
+      'SELECTOR_NOT_ALLOWED',
+      'PALLET_NOT_ALLOWED',
+      'MODULE_NOT_ALLOWED',
+      'CONTRACT_TYPE_NOT_SUPPORTED',
+      'UNEXPECTED_VALIDATOR',
+      'COMPOUND_DESTINATION_MISMATCH',
+    ],
+    pass: ({ result }) =>
+      isDefined(result.detectedType)
+        ? `Matches ${result.detectedType} and no other transaction type`
+        : 'Matches exactly one transaction type',
+  },
+  {
+    check: 'stake-token',
+    codes: ['TOKEN_MISMATCH'],
+    skip: ({ validator, isMulticall }) => {
+      if (isMulticall) return PER_CALL;
+      return validator.getStakeTokens().length > 0
+        ? undefined
+        : 'The yield does not list the tokens it stakes';
+    },
+    pass: ({ validator }) =>
+      `Moves no token but ${validator.getStakeTokens().join(', ')} and the yield's own`,
+  },
+  {
+    check: 'amount',
+    codes: ['AMOUNT_MISMATCH'],
+    skip: ({ request }) =>
+      isDefined(request.expectedAmount) ? undefined : 'No expectedAmount given',
+    pass: ({ request }) =>
+      `Moves ${request.expectedAmount}, the expected amount`,
+  },
+  {
+    check: 'delegation',
+    codes: ['AUTHORITY_MISMATCH', 'DELEGATION_TARGET_NOT_ALLOWED'],
+    warnings: ['EIP7702_DELEGATION'],
+    skip: ({ request, validator }) =>
+      isDefined(validator.getDelegations(request.unsignedTransaction))
+        ? undefined
+        : 'Not an EIP-7702 transaction',
+    pass: () => "Every authorization is the user's, to an allowed contract",
+  },
+  {
+    check: 'ens-recipient',
+    codes: ['RECIPIENT_ENS_MISMATCH'],
+    skip: ({ request }) =>
+      isDefined(request.expectedRecipientEns) ? undefined : 'No ENS name given',
+    pass: ({ result }) =>
+      `${result.resolvedRecipient?.name} resolves to ${result.resolvedRecipient?.address}, the recipient`,
+  },
+  {
+    check: 'recipient-code',
+    codes: ['RECIPIENT_NOT_A_CONTRACT'],
+    skip: ({ request }) =>
+      isDefined(request.contractCode)
+        ? undefined
+        : 'No rpcUrl to fetch the code of the contracts called from',
+    pass: () => 'Every contract the calldata is sent to has code',
+  },
+  {
+    check: 'contract-paused',
+    codes: [],
+    warnings: ['CONTRACT_PAUSED'],
+    skip: ({ request }) =>
+      isDefined(request.contractPaused)
+        ? undefined
+        : 'No rpcUrl to read whether the contracts called are paused from',
+    pass: () => 'No contract the calldata is sent to is paused',
+  },
+  {
+    check: 'contract-age',
+    codes: [],
+    warnings: ['RECENTLY_DEPLOYED_CONTRACT'],
+    skip: ({ request }) => {
+      if (!isDefined(request.policy?.minContractAgeSeconds)) {
+        return 'No policy.minContractAgeSeconds given';
+      }
+      return isDefined(request.contractDeployments)
+        ? undefined
+        : 'No rpcUrl to find when the contracts called were deployed from';
+    },
+    pass: ({ request }) =>
+      `Every contract the calldata is sent to is at least ${request.policy?.minContractAgeSeconds} seconds old`,
+  },
+  {
+    check: 'yield-status',
+    codes: [],
+    warnings: ['YIELD_PAUSED', 'YIELD_DEPRECATED'],
+    pass: ({ validator }) => `The yield is ${validator.getStatus()}`,
+  },
+  {
+    check: 'bytecode-hash',
+    codes: ['BYTECODE_MISMATCH'],
+    skip: ({ request, validator }) => {
+      if (!isDefined(request.actualBytecodeHash)) {
+        return 'No actualBytecodeHash given';
+      }
+      const [recipient] = validator.getContractAddresses(
+        request.unsignedTransaction,
+      );
+      return isDefined(recipient) &&
+        isDefined(validator.getBytecodeHash(recipient))
+        ? undefined
+        : 'The registry pins no bytecode hash for the recipient';
+    },
+    pass: ({ request }) =>
+      `The recipient's code hashes to the pinned ${request.actualBytecodeHash}`,
+  },
+  {
+    check: 'unstake-balance',
+    codes: ['UNSTAKE_EXCEEDS_BALANCE'],
+    warnings: ['UNSTAKE_NEAR_FULL'],
+    skip: ({ request, validator }) => {
+      const call = validator.getStakedBalanceCall(request.unsignedTransaction);
+      if (!isDefined(call)) {
+        return 'Not an unstake Shield can read the balance of';
+      }
+      return isDefined(request.stakedBalance)
+        ? undefined
+        : 'No rpcUrl to read the staked balance from';
+    },
+    pass: ({ request, validator }) => {
+      const call = validator.getStakedBalanceCall(request.unsignedTransaction);
+      return `Unstakes ${call?.amount} of the staked balance of ${request.stakedBalance}`;
+    },
+  },
+  {
+    check: 'claim-position',
+    codes: ['CLAIM_POSITION_NOT_OWNED'],
+    skip: ({ request, validator }) => {
+      const { unsignedTransaction } = request;
+      const transactions = [
+        unsignedTransaction,
+        ...(validator.getMulticall(unsignedTransaction)?.unsignedTransactions ??
+          []),
+      ];
+      const claims = transactions.some((transaction) =>
+        isDefined(validator.getClaimedPositions(transaction)),
+      );
+      if (!claims) return 'Not a claim of positions';
+      return isDefined(request.positionOwners)
+        ? undefined
+        : 'No rpcUrl to read the owners of the claimed positions from';
+    },
+    pass: ({ result }) =>
+      `userAddress owns positions ${result.claimedPositions?.positionIds.join(', ')}`,
+  },
+  {
+    check: 'memo',
+    codes: ['MISSING_MEMO', 'MEMO_MISMATCH'],
+    skip: ({ request }) =>
+      isDefined(request.expectedMemo) ? undefined : 'No expectedMemo given',
+    pass: ({ request }) => `Carries the expected memo ${request.expectedMemo}`,
+  },
+  {
+    check: 'deadline',
+    codes: ['DEADLINE_IN_PAST'],
+    warnings: ['LONG_DEADLINE'],
+    skip: ({ result }) =>
+      isDefined(result.deadline)
+        ? undefined
+        : 'The transaction has no deadline',
+    pass: ({ result }) =>
+      `Stays valid until ${result.deadline?.iso ?? result.deadline?.timestamp}`,
+  },
+  {
+    check: 'lock-duration',
+    codes: ['LOCK_DURATION_OUT_OF_RANGE'],
+    warnings: ['LOCK_MAXED'],
+    skip: ({ result }) =>
+      isDefined(result.lock)
+        ? undefined
+        : 'The transaction sets no unlock time',
+    pass: ({ result }) =>
+      `Unlocks at ${result.lock?.unlockTimeIso ?? result.lock?.unlockTime}, within the lock durations the yield allows`,
+  },
+  {
+    check: 'nonce',
+    codes: ['NONCE_MISMATCH'],
+    warnings: ['NONCE_TOO_LOW', 'NONCE_GAP'],
+    skip: ({ request }) =>
+      isDefined(request.expectedNonce) || isDefined(request.accountNonce)
+        ? undefined
+        : 'No expected or account nonce given',
+    pass: () => 'The transaction uses the expected nonce',
+  },
+  {
+    check: 'policy',
+    codes: [
+      'CONTRACT_BLOCKED',
+      'CONTRACT_NOT_ALLOWED',
+      'DELEGATECALL_BLOCKED',
+      'AMOUNT_ABOVE_LIMIT',
+      'AMOUNT_BELOW_MINIMUM',
+    ],
+    skip: ({ request }) =>
+      isDefined(request.policy) ? undefined : 'No policy given',
+    pass: ({ request }) =>
+      isDefined(request.policy?.amountLimits?.[request.yieldId])
+        ? "Every contract called is allowed by the policy, and the amount is within the yield's limits"
+        : 'Every contract called is allowed by the policy',
+  },
+  {
+    check: 'risk-threshold',
+    codes: ['RISK_THRESHOLD_EXCEEDED'],
+    skip: ({ request, result }) =>
+      isDefined(request.riskThreshold)
+        ? undefined
+        : `Risk score ${result.riskScore}, with no riskThreshold given`,
+    pass: ({ request, result }) =>
+      `Risk score ${result.riskScore} is below the threshold of ${request.riskThreshold}`,
+  },
+  {
+    check: 'strict-mode',
+    codes: ['STRICT_MODE_WARNING'],
+    skip: ({ request }) => (request.strict ? undefined : 'strict is not set'),
+    pass: ({ request }) =>
+      isDefined(request.strictSeverities)
+        ? `The transaction carries no ${request.strictSeverities.join(' or ')} warnings`
+        : 'The transaction carries no warnings',
+  },
+];
+
+/**
+ * Every check validate ran on request, in order, with its outcome in
+ * result. Checks after the one that failed are reported as skipped, since
+ * validation stops there, and warnings no check accounts for are listed
+ * last.
+ */
+export function traceValidation(
+  request: ValidationRequest,
+  validator: BaseValidator | undefined,
+  result: ValidationResult,
+): ExplainEntry[] {
+  if (!validator) {
+    return [
+      {
+        check: result.reasonCode === 'YIELD_NOT_FOUND' ? 'yield' : 'request',
+        status: 'fail',
+        detail: result.reason ?? 'Unknown yield ID',
+      },
+    ];
+  }
+
+  if (isDefined(result.bridgeLeg)) return traceBridge(request, result);
+
+  const wrapped = isNonEmptyString(request.unsignedTransaction)
+    ? validator.getWrappedTransaction(request.unsignedTransaction)
+    : undefined;
+  const unsignedTransaction =
+    wrapped?.unsignedTransaction ?? request.unsignedTransaction;
+  const context: TraceContext = {
+    request,
+    validator,
+    result,
+    unsignedTransaction,
+    isMulticall:
+      isNonEmptyString(unsignedTransaction) &&
+      isDefined(validator.getMulticall(unsignedTransaction)),
+  };
+
+  const trace: ExplainEntry[] = [
+    {
+      check: 'yield',
+      status: 'pass',
+      detail: `Yield ${request.yieldId} is supported`,
+    },
+  ];
+  const warnings = result.warnings ?? [];
+  let failed = false;
+
+  for (const step of TRACE_STEPS) {
+    if (failed) {
+      trace.push({
+        check: step.check,
+        status: 'skip',
+        detail: 'Not reached: an earlier check failed',
+      });
+      continue;
+    }
+
+    if (
+      !result.isValid &&
+      isDefined(result.reasonCode) &&
+      step.codes.includes(result.reasonCode)
+    ) {
+      failed = true;
+      trace.push({
+        check: step.check,
+        status: 'fail',
+        detail: result.reason ?? result.reasonCode,
+      });
+      // Recipient and selector mismatches are found by matching too
+      trace.push(...getAttempts(result));
+      continue;
+    }
+
+    const skipped = step.skip?.(context);
+    if (isDefined(skipped)) {
+      trace.push({ check: step.check, status: 'skip', detail: skipped });
+      continue;
+    }
+
+    const warning = warnings.find((w) => step.warnings?.includes(w.code));
+    trace.push(
+      isDefined(warning)
+        ? { check: step.check, status: 'warn', detail: warning.message }
+        : { check: step.check, status: 'pass', detail: step.pass(context) },
+    );
+  }
+
+  const traced = TRACE_STEPS.flatMap((step) => step.warnings ?? []);
+  for (const warning of warnings) {
+    if (traced.includes(warning.code)) continue;
+    trace.push({ check: 'warning', status: 'warn', detail: warning.message });
+  }
+
+  // A failure no check above accounts for, e.g. from a validator's own rules
+  if (!result.isValid && !failed) {
+    trace.push({
+      check: 'validation',
+      status: 'fail',
+      detail: result.reason ?? 'Validation failed',
+    });
+  }
+
+  return trace;
+}
+
+// What a bridge call fails with before its staking leg is validated
+const BRIDGE_CODES: ReasonCode[] = [
+  'INVALID_REQUEST',
+  'CHAIN_ID_MISMATCH',
+  'SENDER_MISMATCH',
+  'BRIDGE_DESTINATION_MISMATCH',
+  'BRIDGE_MESSAGE_INVALID',
+  'BRIDGE_RECIPIENT_MISMATCH',
+];
+
+// A bridge-then-stake transaction is checked as its bridge call, then as
+// the flow of calls its message makes, which stakingLeg details
+function traceBridge(
+  request: ValidationRequest,
+  result: ValidationResult,
+): ExplainEntry[] {
+  const leg = result.bridgeLeg!;
+  const bridgeFailed =
+    !result.isValid &&
+    isDefined(result.reasonCode) &&
+    BRIDGE_CODES.includes(result.reasonCode);
+  const failure = result.reason ?? result.reasonCode ?? 'Validation failed';
+  return [
+    {
+      check: 'yield',
+      status: 'pass',
+      detail: `Yield ${request.yieldId} is supported`,
+    },
+    bridgeFailed
+      ? { check: 'bridge', status: 'fail', detail: failure }
+      : {
+          check: 'bridge',
+          status: 'pass',
+          detail: `Bridges through ${leg.protocol} from chain ${leg.sourceChainId} to chain ${leg.destinationChainId}, for the user`,
+        },
+    bridgeFailed
+      ? {
+          check: 'staking-leg',
+          status: 'skip',
+          detail: 'Not reached: an earlier check failed',
+        }
+      : result.isValid
+        ? {
+            check: 'staking-leg',
+            status: 'pass',
+            detail: "Every call of the bridge's message passes; see stakingLeg",
+          }
+        : { check: 'staking-leg', status: 'fail', detail: failure },
+    ...(result.warnings ?? []).map(
+      (warning): ExplainEntry => ({
+        check: 'warning',
+        status: 'warn',
+        detail: warning.message,
+      }),
+    ),
+  ];
+}
+
+// Why each transaction type did not match, when none did
+function getAttempts(result: ValidationResult): ExplainEntry[] {
+  return (result.details?.attempts ?? []).map((attempt) => ({
+    check: `transaction-type:${attempt.type}`,
+    status: 'fail' as const,
+    detail: attempt.reason ?? 'Did not match',
+  }));
+}
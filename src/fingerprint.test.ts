@@ -0,0 +1,41 @@
+import { computeFingerprint } from './fingerprint';
+
+describe('computeFingerprint', () => {
+  const stake = {
+    chainId: '1',
+    from: '0x742d35cc6634c0532925a3b844bc9e7595f0beb8',
+    to: ['0xae7ab96520de3a18e5e111b5eaab095312d7fe84'],
+    selector: '0xa1903eab',
+    token: 'native',
+    amount: '1000000000000000000',
+  };
+
+  it('should hash the fields as documented', () => {
+    expect(computeFingerprint(stake)).toBe(
+      'db7a76636e608775fcd7e02c26b6fb8239a8874ff3ee450bb46a50878c9a1aab',
+    );
+    expect(
+      computeFingerprint({
+        chainId: 'cosmoshub-4',
+        from: null,
+        to: [],
+        selector: null,
+        token: null,
+        amount: null,
+      }),
+    ).toBe('88af2d877e1c72c63c2e254ce23d1375251936afa8df8a3984df59cce2e1113d');
+  });
+
+  it('should tell every field apart', () => {
+    const reference = computeFingerprint(stake);
+
+    expect(computeFingerprint({ ...stake, chainId: '17000' })).not.toBe(
+      reference,
+    );
+    expect(computeFingerprint({ ...stake, to: [] })).not.toBe(reference);
+    expect(computeFingerprint({ ...stake, selector: null })).not.toBe(
+      reference,
+    );
+    expect(computeFingerprint({ ...stake, amount: '1' })).not.toBe(reference);
+  });
+});
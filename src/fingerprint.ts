@@ -0,0 +1,39 @@
+import { createHash } from 'crypto';
+
+// Changes whenever the fields or their encoding do, so that fingerprints
+// of different versions never collide
+export const FINGERPRINT_VERSION = 'shield-fingerprint-v1';
+
+/**
+ * What a fingerprint identifies a transaction by, each address in the form
+ * its chain compares addresses in, e.g. lowercase on EVM chains.
+ */
+export interface FingerprintFields {
+  chainId: string; // The yield's, as getYieldCapabilities reports it
+  from: string | null; // The sender, or userAddress when it names none
+  to: string[]; // The contracts or programs called, in order
+  selector: string | null; // 0x-prefixed and lowercase, on EVM chains
+  token: string | null; // The token moved, or 'native'
+  amount: string | null; // In base units, as a decimal string
+}
+
+/**
+ * The SHA-256, as 64 lowercase hex digits, of the JSON array
+ * [FINGERPRINT_VERSION, chainId, from, to, selector, token, amount] written
+ * without whitespace, absent fields as null. Transactions that only differ
+ * in their encoding, such as hex case or quantities written in decimal,
+ * share it; nonces, gas and signatures are left out.
+ */
+export function computeFingerprint(fields: FingerprintFields): string {
+  const { chainId, from, to, selector, token, amount } = fields;
+  const json = JSON.stringify([
+    FINGERPRINT_VERSION,
+    chainId,
+    from,
+    to,
+    selector,
+    token,
+    amount,
+  ]);
+  return createHash('sha256').update(json).digest('hex');
+}
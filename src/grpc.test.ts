@@ -0,0 +1,250 @@
+import { readFileSync } from 'fs';
+import { connect } from 'http2';
+import type { AddressInfo } from 'net';
+import { join } from 'path';
+import type { Http2Server } from 'http2';
+import {
+  createGrpcServer,
+  renderProto,
+  VALIDATE_REQUEST,
+  VALIDATE_RESPONSE,
+} from './grpc';
+import { listOperations } from './json/operations';
+import { decodeMessage, encodeMessage } from './protobuf';
+
+describe('renderProto', () => {
+  it('should match the shipped proto/shield.proto', () => {
+    const path = join(__dirname, '..', 'proto', 'shield.proto');
+    expect(renderProto()).toBe(readFileSync(path, 'utf8'));
+  });
+
+  it('should declare every field the validate operation takes', () => {
+    const validate = listOperations().find((op) => op.name === 'validate')!;
+    const fields = VALIDATE_REQUEST.fields.map((field) => field.name);
+
+    expect(fields.sort()).toEqual(
+      [
+        'apiVersion',
+        'requestId',
+        'echoRequest',
+        'structuredMessages',
+        ...validate.requiredFields,
+        ...validate.optionalFields,
+      ].sort(),
+    );
+  });
+});
+
+describe('createGrpcServer', () => {
+  const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+  const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+  const stakeTx = JSON.stringify({
+    to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84', // Lido stETH
+    from: userAddress,
+    value: '0xde0b6b3a7640000',
+    data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'), // submit
+    chainId: 1,
+  });
+  const stakeRequest = {
+    yieldId: 'ethereum-eth-lido-staking',
+    unsignedTransaction: stakeTx,
+    userAddress,
+  };
+
+  let server: Http2Server;
+  let url: string;
+
+  beforeAll((done) => {
+    server = createGrpcServer().listen(0, '127.0.0.1', () => {
+      const { port } = server.address() as AddressInfo;
+      url = `http://127.0.0.1:${port}`;
+      done();
+    });
+  });
+
+  afterAll((done) => {
+    server.close(done);
+  });
+
+  const frame = (data: Buffer, flag = 0) => {
+    const prefix = Buffer.alloc(5);
+    prefix[0] = flag;
+    prefix.writeUInt32BE(data.length, 1);
+    return Buffer.concat([prefix, data]);
+  };
+
+  // Sends the frames to path and resolves with the gRPC status and the
+  // decoded responses
+  const call = (
+    method: string,
+    frames: Buffer[],
+    contentType = 'application/grpc',
+  ): Promise<{
+    httpStatus?: number;
+    status?: string;
+    message?: string;
+    responses: Record<string, unknown>[];
+  }> =>
+    new Promise((resolve, reject) => {
+      const client = connect(url);
+      const stream = client.request({
+        ':method': 'POST',
+        ':path': `/shield.v1.Shield/${method}`,
+        'content-type': contentType,
+        te: 'trailers',
+      });
+      let httpStatus: number | undefined;
+      let status: string | undefined;
+      let message: string | undefined;
+      const read = (headers: Record<string, unknown>) => {
+        if (headers['grpc-status'] !== undefined) {
+          status = String(headers['grpc-status']);
+          message = decodeURIComponent(String(headers['grpc-message'] ?? ''));
+        }
+      };
+      const chunks: Buffer[] = [];
+      stream.on('response', (headers) => {
+        httpStatus = Number(headers[':status']);
+        read(headers);
+      });
+      stream.on('trailers', read);
+      stream.on('data', (chunk: Buffer) => chunks.push(chunk));
+      stream.on('end', () => {
+        client.close();
+        let data = Buffer.concat(chunks);
+        const responses = [];
+        while (data.length > 0) {
+          const length = data.readUInt32BE(1);
+          responses.push(
+            decodeMessage(VALIDATE_RESPONSE, data.subarray(5, 5 + length)),
+          );
+          data = data.subarray(5 + length);
+        }
+        resolve({ httpStatus, status, message, responses });
+      });
+      stream.on('error', reject);
+      for (const data of frames) stream.write(data);
+      stream.end();
+    });
+
+  const request = (value: Record<string, unknown>) =>
+    frame(encodeMessage(VALIDATE_REQUEST, value));
+
+  it('should validate a transaction', async () => {
+    const { status, responses } = await call('Validate', [
+      request({ ...stakeRequest, requestId: 'a' }),
+    ]);
+
+    expect(status).toBe('0');
+    expect(responses).toHaveLength(1);
+    expect(responses[0].ok).toBe(true);
+    expect(responses[0].requestId).toBe('a');
+    expect(responses[0].result).toMatchObject({
+      isValid: true,
+      detectedType: 'STAKE',
+    });
+    expect(
+      (responses[0].meta as { requestHash: string }).requestHash,
+    ).toMatch(/^[a-f0-9]{64}$/);
+  });
+
+  it('should pass Struct fields through as JSON objects', async () => {
+    const blocked = await call('Validate', [
+      request({
+        ...stakeRequest,
+        policy: {
+          blockedContracts: ['0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84'],
+        },
+      }),
+    ]);
+
+    expect(blocked.responses[0].result).toMatchObject({
+      isValid: false,
+      reasonCode: 'CONTRACT_BLOCKED',
+    });
+  });
+
+  it('should pass repeated fields through as JSON arrays', async () => {
+    const { responses } = await call('Validate', [
+      request({ ...stakeRequest, responseFields: ['detectedType'] }),
+    ]);
+
+    expect(responses[0].result).toEqual({
+      isValid: true,
+      detectedType: 'STAKE',
+    });
+  });
+
+  it('should answer request errors with ok false and status OK', async () => {
+    const { status, responses } = await call('Validate', [
+      request({ yieldId: 'ethereum-eth-lido-staking' }),
+    ]);
+
+    expect(status).toBe('0');
+    expect(responses[0].ok).toBe(false);
+    expect(responses[0].error).toMatchObject({
+      code: 'MISSING_REQUIRED_FIELD',
+    });
+  });
+
+  it('should answer a stream of requests in order', async () => {
+    const { status, responses } = await call(
+      'ValidateStream',
+      ['1', '2', '3'].map((requestId) =>
+        request({ ...stakeRequest, requestId }),
+      ),
+    );
+
+    expect(status).toBe('0');
+    expect(responses.map((response) => response.requestId)).toEqual([
+      '1',
+      '2',
+      '3',
+    ]);
+  });
+
+  it('should end an empty stream with status OK', async () => {
+    const { status, responses } = await call('ValidateStream', []);
+
+    expect(status).toBe('0');
+    expect(responses).toEqual([]);
+  });
+
+  it('should take exactly one request on Validate', async () => {
+    const none = await call('Validate', []);
+    const two = await call('Validate', [
+      request(stakeRequest),
+      request(stakeRequest),
+    ]);
+
+    expect(none.status).toBe('3');
+    expect(two.status).toBe('3');
+    expect(two.message).toBe('Validate takes one request');
+  });
+
+  it('should reject messages that cannot be decoded', async () => {
+    const { status, message } = await call('Validate', [
+      frame(Buffer.from('0a05', 'hex')),
+    ]);
+
+    expect(status).toBe('3');
+    expect(message).toMatch(/^Invalid ValidateRequest/);
+  });
+
+  it('should reject oversized and compressed messages', async () => {
+    const oversized = Buffer.alloc(5);
+    oversized.writeUInt32BE(0xffffffff, 1);
+
+    expect((await call('Validate', [oversized])).status).toBe('8');
+    expect(
+      (await call('Validate', [frame(Buffer.alloc(0), 1)])).status,
+    ).toBe('12');
+  });
+
+  it('should reject unknown methods and other content types', async () => {
+    expect((await call('Decode', [])).status).toBe('12');
+    expect(
+      (await call('Validate', [], 'application/json')).httpStatus,
+    ).toBe(415);
+  });
+});
@@ -0,0 +1,419 @@
+import {
+  createServer,
+  type Http2Server,
+  type IncomingHttpHeaders,
+  type ServerHttp2Stream,
+} from 'http2';
+import { handleJsonRequestAsync, MAX_INPUT_SIZE } from './json';
+import type { JsonHandlerOptions, JsonRequest } from './json';
+import { requestSchema } from './json/schema';
+import {
+  decodeMessage,
+  encodeMessage,
+  type Field,
+  type MessageType,
+} from './protobuf';
+
+const SERVICE = 'shield.v1.Shield';
+
+type GrpcOptions = Pick<
+  JsonHandlerOptions,
+  | 'logger'
+  | 'registryOverride'
+  | 'reloadRegistry'
+  | 'health'
+  | 'validationCache'
+  | 'auditSink'
+  | 'profile'
+  | 'maxInputSize'
+>;
+
+// https://grpc.github.io/grpc/core/md_doc_statuscodes.html
+const OK = 0;
+const INVALID_ARGUMENT = 3;
+const RESOURCE_EXHAUSTED = 8;
+const UNIMPLEMENTED = 12;
+const INTERNAL = 13;
+
+// A message is prefixed with a compressed flag and its length
+const PREFIX_SIZE = 5;
+
+// ValidateRequest numbers its fields by their position here, so fields are
+// only ever appended. Types come from the JSON request schema
+const VALIDATE_REQUEST_FIELDS: (keyof JsonRequest)[] = [
+  'apiVersion',
+  'requestId',
+  'yieldId',
+  'unsignedTransaction',
+  'rawTransaction',
+  'userAddress',
+  'args',
+  'context',
+  'riskThreshold',
+  'policy',
+  'strict',
+  'expectedAmount',
+  'expectedAmountToken',
+  'amountToleranceBps',
+  'expectedNonce',
+  'includeTiming',
+  'locale',
+  'expectedMemo',
+  'observe',
+  'beneficiaryAddress',
+  'expectedRecipientEns',
+  'simulate',
+  'checkNonce',
+  'rpcUrl',
+  'registryOverride',
+  'responseFields',
+  'actualBytecodeHash',
+  'accountNumber',
+  'yieldIds',
+  'amountTolerance',
+  'strictSeverities',
+  'echoRequest',
+  'lenientUnknownYield',
+  'contractOverrides',
+  'includeSignature',
+  'includeAudit',
+  'structuredMessages',
+  'addressLookupTables',
+  'blockNumber',
+];
+
+export const VALIDATE_REQUEST: MessageType = {
+  name: 'ValidateRequest',
+  fields: VALIDATE_REQUEST_FIELDS.map((name, index) => ({
+    name,
+    number: index + 1,
+    ...getFieldType(name),
+  })),
+};
+
+const ERROR: MessageType = {
+  name: 'Error',
+  fields: [
+    { name: 'code', number: 1, type: 'string' },
+    { name: 'message', number: 2, type: 'string' },
+    { name: 'details', number: 3, type: 'value' },
+  ],
+};
+
+// The JSON protocol's response, with result, meta and normalizedRequest as
+// Structs
+export const VALIDATE_RESPONSE: MessageType = {
+  name: 'ValidateResponse',
+  fields: [
+    { name: 'ok', number: 1, type: 'bool' },
+    { name: 'apiVersion', number: 2, type: 'string' },
+    { name: 'requestId', number: 3, type: 'string', optional: true },
+    { name: 'result', number: 4, type: 'struct' },
+    { name: 'error', number: 5, type: ERROR },
+    { name: 'meta', number: 6, type: 'struct' },
+    { name: 'normalizedRequest', number: 7, type: 'struct' },
+  ],
+};
+
+// Scalars have explicit presence, so that a field left out is not sent to
+// the JSON protocol as its zero value. An empty repeated field is left out
+// the same way
+function getFieldType(
+  name: string,
+): Pick<Field, 'type' | 'optional' | 'repeated'> {
+  const schema = (
+    requestSchema.properties as Record<
+      string,
+      { type: string; items?: { type: string } }
+    >
+  )[name];
+  switch (schema?.type) {
+    case 'string':
+      return { type: 'string', optional: true };
+    case 'boolean':
+      return { type: 'bool', optional: true };
+    case 'integer':
+      return { type: 'int64', optional: true };
+    case 'number':
+      return { type: 'double', optional: true };
+    case 'object':
+      return { type: 'struct' };
+    case 'array':
+      if (schema.items?.type === 'string') {
+        return { type: 'string', repeated: true };
+      }
+  }
+  throw new Error(`No protobuf type for request field ${name}`);
+}
+
+/**
+ * The .proto definition of the gRPC service, as shipped in
+ * proto/shield.proto.
+ */
+export function renderProto(): string {
+  const message = ({ name, fields }: MessageType) => [
+    `message ${name} {`,
+    ...fields.map((field) => {
+      const label = field.repeated
+        ? 'repeated '
+        : field.optional
+          ? 'optional '
+          : '';
+      const name = field.name.replace(/[A-Z]/g, (c) => `_${c.toLowerCase()}`);
+      return `  ${label}${getProtoType(field)} ${name} = ${field.number};`;
+    }),
+    '}',
+  ];
+
+  return [
+    '// Generated by renderProto in src/grpc.ts from the JSON request schema.',
+    '// Do not edit by hand.',
+    'syntax = "proto3";',
+    '',
+    'package shield.v1;',
+    '',
+    'import "google/protobuf/struct.proto";',
+    '',
+    "// The JSON protocol's validate operation. Objects such as args, policy",
+    '// and result are Structs shaped exactly as in the JSON protocol.',
+    'service Shield {',
+    '  rpc Validate(ValidateRequest) returns (ValidateResponse);',
+    '  // Answers every request in order',
+    '  rpc ValidateStream(stream ValidateRequest)',
+    '      returns (stream ValidateResponse);',
+    '}',
+    '',
+    ...message(VALIDATE_REQUEST),
+    '',
+    ...message(VALIDATE_RESPONSE),
+    '',
+    ...message(ERROR),
+    '',
+  ].join('\n');
+}
+
+function getProtoType({ type }: Field): string {
+  if (type === 'struct') return 'google.protobuf.Struct';
+  if (type === 'value') return 'google.protobuf.Value';
+  return typeof type === 'string' ? type : type.name;
+}
+
+class GrpcError extends Error {
+  constructor(
+    readonly status: number,
+    message: string,
+  ) {
+    super(message);
+  }
+}
+
+/**
+ * gRPC transport for the JSON protocol's validate operation, over
+ * cleartext HTTP/2.
+ *
+ * - shield.v1.Shield/Validate takes one ValidateRequest and returns its
+ *   ValidateResponse.
+ * - shield.v1.Shield/ValidateStream answers a stream of requests with a
+ *   stream of responses, in order.
+ *
+ * Each request is handled as a JSON validate request with the same fields,
+ * and answered with the JSON response, so failed validations and request
+ * errors are responses with ok false and gRPC status OK. Messages that
+ * cannot be decoded end the call with INVALID_ARGUMENT. Options are those
+ * of createHttpServer.
+ */
+export function createGrpcServer(options: GrpcOptions = {}): Http2Server {
+  const server = createServer();
+  server.on('stream', (stream, headers) => {
+    if (
+      headers[':method'] !== 'POST' ||
+      !headers['content-type']?.startsWith('application/grpc')
+    ) {
+      stream.respond({ ':status': 415 }, { endStream: true });
+      return;
+    }
+
+    const method = getMethod(headers);
+    if (method === undefined) {
+      endCall(
+        stream,
+        new GrpcError(UNIMPLEMENTED, `Unknown method: ${headers[':path']}`),
+      );
+      return;
+    }
+    handleCall(stream, method === 'ValidateStream', options);
+  });
+  return server;
+}
+
+function getMethod(
+  headers: IncomingHttpHeaders,
+): 'Validate' | 'ValidateStream' | undefined {
+  const path = headers[':path'];
+  if (path === `/${SERVICE}/Validate`) return 'Validate';
+  if (path === `/${SERVICE}/ValidateStream`) return 'ValidateStream';
+  return undefined;
+}
+
+function handleCall(
+  stream: ServerHttp2Stream,
+  streaming: boolean,
+  options: GrpcOptions,
+): void {
+  let ended = false;
+  let received = 0;
+  // Requests are handled one at a time, so responses keep their order
+  let pending = Promise.resolve();
+
+  const fail = (error: unknown) => {
+    if (ended) return;
+    ended = true;
+    endCall(
+      stream,
+      error instanceof GrpcError
+        ? error
+        : new GrpcError(INTERNAL, 'Failed to process request'),
+    );
+  };
+
+  readMessages(
+    stream,
+    options.maxInputSize ?? MAX_INPUT_SIZE,
+    (data) => {
+      received++;
+      if (!streaming && received > 1) {
+        throw new GrpcError(INVALID_ARGUMENT, 'Validate takes one request');
+      }
+
+      let request: Record<string, unknown>;
+      try {
+        request = decodeMessage(VALIDATE_REQUEST, data);
+      } catch (error) {
+        const message = error instanceof Error ? error.message : String(error);
+        throw new GrpcError(
+          INVALID_ARGUMENT,
+          `Invalid ValidateRequest: ${message}`,
+        );
+      }
+
+      pending = pending.then(async () => {
+        const json = JSON.stringify({
+          apiVersion: '1.0',
+          ...request,
+          operation: 'validate',
+        });
+        const output = await handleJsonRequestAsync(json, options);
+        if (ended) return;
+        writeMessage(
+          stream,
+          encodeMessage(VALIDATE_RESPONSE, JSON.parse(output)),
+        );
+      });
+      pending.catch(fail);
+    },
+    () => {
+      pending.then(() => {
+        if (ended) return;
+        if (received === 0 && !streaming) {
+          fail(new GrpcError(INVALID_ARGUMENT, 'Validate takes one request'));
+          return;
+        }
+        ended = true;
+        endCall(stream, undefined);
+      }, fail);
+    },
+    fail,
+  );
+}
+
+/**
+ * Calls onMessage with every message of the stream as it arrives, then
+ * onEnd. A stream that breaks the framing, sends a message over
+ * maxInputSize bytes, or a message onMessage throws on, calls onError
+ * instead, and nothing more is read.
+ */
+function readMessages(
+  stream: ServerHttp2Stream,
+  maxInputSize: number,
+  onMessage: (data: Buffer) => void,
+  onEnd: () => void,
+  onError: (error: unknown) => void,
+): void {
+  let buffer = Buffer.alloc(0);
+  let failed = false;
+
+  stream.on('data', (chunk: Buffer) => {
+    if (failed) return;
+    buffer = Buffer.concat([buffer, chunk]);
+    try {
+      while (buffer.length >= PREFIX_SIZE) {
+        if (buffer[0] !== 0) {
+          throw new GrpcError(
+            UNIMPLEMENTED,
+            'Compressed messages are not supported',
+          );
+        }
+        // SECURITY: Same size limit as the stdin interface, checked before
+        // the message is buffered
+        const length = buffer.readUInt32BE(1);
+        if (length > maxInputSize) {
+          throw new GrpcError(
+            RESOURCE_EXHAUSTED,
+            `Message exceeds maximum size of ${maxInputSize} bytes`,
+          );
+        }
+        if (buffer.length < PREFIX_SIZE + length) break;
+        onMessage(buffer.subarray(PREFIX_SIZE, PREFIX_SIZE + length));
+        buffer = buffer.subarray(PREFIX_SIZE + length);
+      }
+    } catch (error) {
+      failed = true;
+      buffer = Buffer.alloc(0);
+      onError(error);
+    }
+  });
+  stream.on('end', () => {
+    if (failed) return;
+    if (buffer.length > 0) {
+      onError(new GrpcError(INVALID_ARGUMENT, 'Incomplete message'));
+      return;
+    }
+    onEnd();
+  });
+  stream.on('error', onError);
+}
+
+function writeMessage(stream: ServerHttp2Stream, data: Buffer): void {
+  if (!stream.headersSent) {
+    stream.respond(
+      { ':status': 200, 'content-type': 'application/grpc' },
+      { waitForTrailers: true },
+    );
+  }
+  const prefix = Buffer.alloc(PREFIX_SIZE);
+  prefix.writeUInt32BE(data.length, 1);
+  stream.write(Buffer.concat([prefix, data]));
+}
+
+// Ends the call with the status of error, or OK without one
+function endCall(
+  stream: ServerHttp2Stream,
+  error: GrpcError | undefined,
+): void {
+  if (stream.destroyed) return;
+  const status = {
+    'grpc-status': String(error?.status ?? OK),
+    ...(error && { 'grpc-message': encodeURIComponent(error.message) }),
+  };
+
+  // Without a message sent, the status goes in the headers alone
+  if (!stream.headersSent) {
+    stream.respond(
+      { ':status': 200, 'content-type': 'application/grpc', ...status },
+      { endStream: true },
+    );
+    return;
+  }
+  stream.once('wantTrailers', () => stream.sendTrailers(status));
+  stream.end();
+}
@@ -0,0 +1,126 @@
+import { AddressInfo } from 'net';
+import { Server } from 'http';
+import { createHttpServer, parseListenAddress } from './http';
+
+describe('parseListenAddress', () => {
+  it('should parse a port-only address', () => {
+    expect(parseListenAddress(':8080')).toEqual({
+      host: undefined,
+      port: 8080,
+    });
+  });
+
+  it('should parse a host and port', () => {
+    expect(parseListenAddress('127.0.0.1:9000')).toEqual({
+      host: '127.0.0.1',
+      port: 9000,
+    });
+  });
+
+  it('should reject addresses without a valid port', () => {
+    expect(() => parseListenAddress('')).toThrow('Invalid listen address');
+    expect(() => parseListenAddress(':http')).toThrow('Invalid listen address');
+    expect(() => parseListenAddress(':70000')).toThrow(
+      'Invalid listen address',
+    );
+  });
+});
+
+describe('createHttpServer', () => {
+  let server: Server;
+  let baseUrl: string;
+
+  beforeAll((done) => {
+    server = createHttpServer().listen(0, '127.0.0.1', () => {
+      const { port } = server.address() as AddressInfo;
+      baseUrl = `http://127.0.0.1:${port}`;
+      done();
+    });
+  });
+
+  afterAll((done) => {
+    server.close(done);
+  });
+
+  it('should answer health checks', async () => {
+    const res = await fetch(`${baseUrl}/healthz`);
+    expect(res.status).toBe(200);
+    expect(await res.json()).toEqual({ ok: true, ready: true });
+  });
+
+  it('should answer health checks with 503 while not ready', async () => {
+    const health = { ready: false, reason: 'Registry reload failed' };
+    const unready = createHttpServer({ health });
+    await new Promise<void>((resolve) =>
+      unready.listen(0, '127.0.0.1', resolve),
+    );
+    const { port } = unready.address() as AddressInfo;
+
+    try {
+      const res = await fetch(`http://127.0.0.1:${port}/healthz`);
+      expect(res.status).toBe(503);
+      expect(await res.json()).toEqual({ ok: true, ...health });
+    } finally {
+      await new Promise((resolve) => unready.close(resolve));
+    }
+  });
+
+  it('should list supported yields', async () => {
+    const res = await fetch(`${baseUrl}/yields`);
+    const body = await res.json();
+    expect(res.status).toBe(200);
+    expect(body.ok).toBe(true);
+    expect(body.result.yieldIds).toContain('ethereum-eth-lido-staking');
+  });
+
+  it('should handle JSON protocol requests on /validate', async () => {
+    const res = await fetch(`${baseUrl}/validate`, {
+      method: 'POST',
+      body: JSON.stringify({
+        apiVersion: '1.0',
+        operation: 'isSupported',
+        yieldId: 'ethereum-eth-lido-staking',
+      }),
+    });
+    const body = await res.json();
+    expect(res.status).toBe(200);
+    expect(body.result.supported).toBe(true);
+  });
+
+  it('should return 200 with ok:false for request errors', async () => {
+    const res = await fetch(`${baseUrl}/validate`, {
+      method: 'POST',
+      body: '{ invalid json }',
+    });
+    const body = await res.json();
+    expect(res.status).toBe(200);
+    expect(body.ok).toBe(false);
+    expect(body.error.code).toBe('PARSE_ERROR');
+  });
+
+  it('should answer bodies over maxInputSize with 413', async () => {
+    const limited = createHttpServer({ maxInputSize: 64 });
+    await new Promise<void>((resolve) =>
+      limited.listen(0, '127.0.0.1', resolve),
+    );
+    const { port } = limited.address() as AddressInfo;
+
+    try {
+      const res = await fetch(`http://127.0.0.1:${port}/validate`, {
+        method: 'POST',
+        body: JSON.stringify({ operation: 'isSupported', pad: 'x'.repeat(64) }),
+      });
+      const body = await res.json();
+      expect(res.status).toBe(413);
+      expect(body.error.code).toBe('INPUT_TOO_LARGE');
+      expect(body.error.message).toContain('64 bytes');
+    } finally {
+      await new Promise((resolve) => limited.close(resolve));
+    }
+  });
+
+  it('should reject wrong methods and unknown paths', async () => {
+    expect((await fetch(`${baseUrl}/validate`)).status).toBe(405);
+    expect((await fetch(`${baseUrl}/nope`)).status).toBe(404);
+  });
+});
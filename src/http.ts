@@ -0,0 +1,166 @@
+import { createServer, IncomingMessage, Server, ServerResponse } from 'http';
+import {
+  getHealth,
+  handleJsonRequest,
+  handleJsonRequestAsync,
+  MAX_INPUT_SIZE,
+} from './json';
+import type { JsonHandlerOptions } from './json';
+
+const JSON_HEADERS = { 'Content-Type': 'application/json' };
+
+const GET_SUPPORTED_YIELD_IDS_REQUEST = JSON.stringify({
+  apiVersion: '1.0',
+  operation: 'getSupportedYieldIds',
+});
+
+/**
+ * HTTP transport for the JSON protocol.
+ *
+ * - POST /validate accepts the same JSON body as the stdin interface and
+ *   returns the same response. Validation failures and request errors are
+ *   answered with HTTP 200 and ok:false; only INTERNAL_ERROR maps to 500.
+ * - GET /yields returns the getSupportedYieldIds response.
+ * - GET /healthz returns {"ok":true} and the health result, with HTTP 503
+ *   while it is not ready.
+ *
+ * Responses are logged through options.logger, when given, and every
+ * request sees the vaults of options.registryOverride as it is at the time.
+ * reloadRegistry requests go to options.reloadRegistry, health comes from
+ * options.health, and validate results are cached in
+ * options.validationCache, when given. Bodies over options.maxInputSize
+ * bytes are answered with HTTP 413 and INPUT_TOO_LARGE.
+ */
+export function createHttpServer(
+  options: Pick<
+    JsonHandlerOptions,
+    | 'logger'
+    | 'registryOverride'
+    | 'reloadRegistry'
+    | 'health'
+    | 'validationCache'
+    | 'auditSink'
+    | 'profile'
+    | 'maxInputSize'
+  > = {},
+): Server {
+  const maxInputSize = options.maxInputSize ?? MAX_INPUT_SIZE;
+  return createServer((req, res) => {
+    const path = (req.url ?? '/').split('?')[0];
+
+    if (path === '/healthz') {
+      if (req.method !== 'GET') return methodNotAllowed(res);
+      const health = getHealth(options);
+      return send(
+        res,
+        health.ready ? 200 : 503,
+        JSON.stringify({ ok: true, ...health }),
+      );
+    }
+
+    if (path === '/yields') {
+      if (req.method !== 'GET') return methodNotAllowed(res);
+      return sendShieldResponse(
+        res,
+        handleJsonRequest(GET_SUPPORTED_YIELD_IDS_REQUEST, options),
+      );
+    }
+
+    if (path === '/validate') {
+      if (req.method !== 'POST') return methodNotAllowed(res);
+      readBody(req, maxInputSize)
+        .then(
+          (body) =>
+            handleJsonRequestAsync(body, options).then((output) =>
+              sendShieldResponse(res, output),
+            ),
+          () =>
+            send(
+              res,
+              413,
+              transportError(
+                'INPUT_TOO_LARGE',
+                `Input exceeds maximum size of ${maxInputSize} bytes`,
+              ),
+            ),
+        );
+      return;
+    }
+
+    send(res, 404, transportError('NOT_FOUND', `Unknown path: ${path}`));
+  });
+}
+
+/**
+ * Parses a listen address such as ":8080" or "127.0.0.1:8080".
+ */
+export function parseListenAddress(address: string): {
+  host?: string;
+  port: number;
+} {
+  const separator = address.lastIndexOf(':');
+  const host = separator > 0 ? address.slice(0, separator) : undefined;
+  const portText = address.slice(separator + 1);
+  const port = Number(portText);
+
+  if (
+    separator === -1 ||
+    !/^\d+$/.test(portText) ||
+    !Number.isInteger(port) ||
+    port > 65535
+  ) {
+    throw new Error(`Invalid listen address: ${address}`);
+  }
+  return { host, port };
+}
+
+function readBody(
+  req: IncomingMessage,
+  maxInputSize: number,
+): Promise<string> {
+  return new Promise((resolve, reject) => {
+    const chunks: Buffer[] = [];
+    let totalBytes = 0;
+
+    req.on('data', (chunk: Buffer) => {
+      totalBytes += chunk.length;
+      // SECURITY: Same size limit as the stdin interface. The rest of the
+      // body is drained without being buffered.
+      if (totalBytes > maxInputSize) {
+        chunks.length = 0;
+        return;
+      }
+      chunks.push(chunk);
+    });
+    req.on('end', () => {
+      if (totalBytes > maxInputSize) {
+        reject(new Error('Input exceeds maximum size'));
+        return;
+      }
+      resolve(Buffer.concat(chunks).toString('utf8'));
+    });
+    req.on('error', reject);
+  });
+}
+
+function sendShieldResponse(res: ServerResponse, output: string): void {
+  const { error } = JSON.parse(output) as { error?: { code?: string } };
+  send(res, error?.code === 'INTERNAL_ERROR' ? 500 : 200, output);
+}
+
+function methodNotAllowed(res: ServerResponse): void {
+  send(res, 405, transportError('METHOD_NOT_ALLOWED', 'Method not allowed'));
+}
+
+function transportError(code: string, message: string): string {
+  return JSON.stringify({
+    ok: false,
+    apiVersion: '1.0',
+    error: { code, message },
+  });
+}
+
+function send(res: ServerResponse, status: number, body: string): void {
+  res.writeHead(status, JSON_HEADERS);
+  res.end(body + '\n');
+}
@@ -1,21 +1,144 @@
 export { Shield } from './shield';
-export type { ValidationRequest } from './shield';
+export type {
+  ShieldOptions,
+  ValidationRequest,
+  DecodeRequest,
+  SimulationRequest,
+  FlowValidationRequest,
+  TypedDataValidationRequest,
+  UserOperationValidationRequest,
+  SendCallsValidationRequest,
+  IntentComparisonRequest,
+  RawTransactionValidationRequest,
+  CandidateValidationRequest,
+  PreflightRequest,
+} from './shield';
 export type {
   ValidationResult,
   ActionArguments,
   ValidationContext,
+  ValidationPolicy,
   FeeConfiguration,
+  ValidationWarning,
+  WarningSeverity,
+  WarningCode,
+  ReasonCode,
+  DecodeResult,
+  DecodedTransaction,
+  DecodedArgument,
+  DecodedInstruction,
+  DecodedMessage,
+  DecodedAction,
+  DecodedCall,
+  DecodedEntryFunction,
+  DecodedOutput,
+  AccessListEntry,
+  SignedAuthorization,
+  Delegation,
+  SwapSlippage,
+  RawTransactionFields,
+  TokenApproval,
+  Permit2Permit,
+  Permit2Details,
+  ClaimCall,
+  ClaimRecipient,
+  Withdrawal,
+  TokenSpend,
+  SimulationCall,
+  SimulationResult,
+  StakedBalanceCall,
+  ContractDeployment,
+  BalanceChange,
+  TransactionAmount,
+  AbiFunction,
+  FlowValidationResult,
+  TransactionWrapper,
+  ValidationTiming,
+  ResolvedRecipient,
+  Deadline,
+  ExplainEntry,
+  ExplainResult,
+  Multicall,
+  MulticallCall,
+  UserOperation,
+  UserOperationValidationResult,
+  SendCalls,
+  SendCallsCall,
+  SendCallsValidationResult,
+  TransactionIntent,
+  IntentComparisonResult,
+  TypedData,
+  TypedDataDomain,
+  TypedDataField,
+  YieldCapabilities,
+  YieldInfo,
+  SupportedYield,
+  YieldMatch,
+  VersionInfo,
+  RegistryCheckResult,
+  RegistryProblem,
+  RegistryProblemCode,
 } from './types';
-export { TronResourceType } from './types';
+export { TronResourceType, RiskLevel } from './types';
 
-export { handleJsonRequest } from './json';
+export {
+  handleJsonRequest,
+  handleJsonRequestAsync,
+  parseRegistryOverride,
+  parseProfile,
+  getHealth,
+  ValidationCache,
+} from './json';
+export type { ValidationCacheOptions } from './json';
+export { reloadRegistryOverride } from './registry';
+export { runSelfTest, SELF_TEST_CORPUS } from './selftest';
+export type {
+  SelfTestCase,
+  SelfTestFailure,
+  SelfTestResult,
+  SelfTestVerdict,
+} from './selftest';
+export type {
+  VaultRegistryEntry,
+  VaultRegistryOverride,
+} from './validators/evm/erc4626';
+export type { BabylonStakingParams } from './validators/bitcoin';
+export { createJsonLogger } from './logger';
+export type { Logger, LogLevel } from './logger';
 export type {
   JsonRequest,
   JsonResponse,
   JsonSuccessResponse,
   JsonErrorResponse,
   ValidateResult,
+  ExplainValidationResult,
+  ValidateBatchResult,
+  BatchSummary,
+  BatchTransaction,
+  FlowTransaction,
+  ValidateFlowResult,
+  ValidateUserOperationResult,
+  ValidateSendCallsResult,
+  CompareIntentResult,
+  PreflightResult,
+  DecodeTransactionResult,
   IsSupportedResult,
   GetSupportedYieldIdsResult,
+  GetYieldCapabilitiesResult,
+  GetYieldAbiResult,
+  GetYieldsResult,
+  DetectYieldsResult,
+  GetVersionResult,
+  ReloadRegistryResult,
+  CheckRegistryResult,
+  GetSchemaResult,
+  ListOperationsResult,
+  HealthResult,
+  AttestResult,
+  AuditRecord,
+  ProfileInfo,
+  ShieldProfile,
+  OperationInfo,
   ErrorCode,
+  JsonHandlerOptions,
 } from './json';
@@ -1,2 +1,3 @@
 // Centralized security limits
-export const MAX_INPUT_SIZE = 100 * 1024; // 100KB - used by CLI for stdin limit
+// 100KB, in bytes - the default of JsonHandlerOptions.maxInputSize
+export const MAX_INPUT_SIZE = 100 * 1024;
@@ -1,4 +1,15 @@
-import { handleJsonRequest } from './handler';
+import Ajv from 'ajv';
+import { createHash } from 'crypto';
+import { ethers } from 'ethers';
+import {
+  handleJsonRequest,
+  handleJsonRequestAsync,
+  parseProfile,
+  parseRegistryOverride,
+} from './handler';
+import { ValidationCache } from './validation-cache';
+import { DEPRECATED_API_VERSIONS } from '../version';
+import { createJsonLogger } from '../logger';
 
 describe('handleJsonRequest', () => {
   // Helper to parse response
@@ -46,9 +57,133 @@ describe('handleJsonRequest', () => {
       expect(response.ok).toBe(true);
       expect(response.result.isValid).toBe(true);
       expect(response.result.detectedType).toBe('STAKE');
+      expect(response.result.detectedTypes).toEqual(['STAKE']);
       expect(response.meta.requestHash).toMatch(/^[a-f0-9]{64}$/);
     });
 
+    it('should summarize a valid transaction for display', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify(validLidoStakeTx),
+        userAddress,
+      });
+
+      expect(response.result.yieldName).toBe('Lido');
+      expect(response.result.yield).toEqual({
+        name: 'Lido',
+        protocol: 'lido',
+        network: 'ethereum',
+      });
+      expect(response.result.summary).toBe('You are staking 1 ETH with Lido');
+    });
+
+    it('should report the rule a valid transaction matched', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify(validLidoStakeTx),
+        userAddress,
+      });
+
+      expect(response.result.matchedRule).toEqual({
+        id: 'ethereum-eth-lido-staking:STAKE:submit(address)',
+        description: expect.stringContaining('0xa1903eab'),
+      });
+    });
+
+    it('should report the locale its messages are in', () => {
+      const request = {
+        apiVersion: '1.0',
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify(validLidoStakeTx),
+        userAddress,
+      };
+
+      expect(call({ ...request, locale: 'de-DE' }).result.locale).toBe('en');
+      expect(call({ ...request, locale: 'not a tag' }).error.code).toBe(
+        'SCHEMA_VALIDATION_ERROR',
+      );
+    });
+
+    it('should report reasons and errors as structured messages', () => {
+      const request = {
+        apiVersion: '1.0',
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify(validLidoStakeTx),
+        userAddress: '0x0000000000000000000000000000000000000bad',
+        structuredMessages: true,
+      };
+
+      const plain = call(request);
+      expect(plain.result.reasonMessage).toEqual({
+        code: 'SENDER_MISMATCH',
+        message: plain.result.reason,
+      });
+
+      const localized = call({ ...request, locale: 'de-DE' });
+      expect(localized.result.reasonMessage).toEqual({
+        code: 'SENDER_MISMATCH',
+        message: plain.result.reason,
+        messageLocalized: plain.result.reason,
+      });
+      expect(localized.result.locale).toBe('en');
+
+      const error = call({
+        ...request,
+        locale: 'de-DE',
+        unsignedTransaction: undefined,
+      }).error;
+      expect(error.messageLocalized).toBe(error.message);
+      expect(
+        call({ ...request, unsignedTransaction: undefined }).error,
+      ).not.toHaveProperty('messageLocalized');
+    });
+
+    it('should reject an expectedMemo the transaction does not carry', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify(validLidoStakeTx),
+        userAddress,
+        expectedMemo: '104455',
+      });
+
+      expect(response.result.isValid).toBe(false);
+      expect(response.result.reasonCode).toBe('MISSING_MEMO');
+    });
+
+    it('should pass beneficiaryAddress on', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify(validLidoStakeTx),
+        userAddress,
+        beneficiaryAddress: '0x1111111111111111111111111111111111111111',
+      });
+
+      expect(response.result.isValid).toBe(false);
+      expect(response.result.reasonCode).toBe('BENEFICIARY_MISMATCH');
+    });
+
+    it('should return an empty warnings array when nothing is flagged', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify(validLidoStakeTx),
+        userAddress: userAddress,
+      });
+
+      expect(response.result.warnings).toEqual([]);
+    });
+
     it('should validate a correct Lido unstake transaction', () => {
       const response = call({
         apiVersion: '1.0',
@@ -84,6 +219,33 @@ describe('handleJsonRequest', () => {
       expect(response.result.reason).toContain(
         'No matching operation pattern found',
       );
+      expect(response.result.reasonCode).toBe('NO_MATCHING_PATTERN');
+    });
+
+    it('should tell an unknown yield from an unsupported operation', () => {
+      const deposit = {
+        ...validLidoStakeTx,
+        data: '0x6e553f65' + '00'.repeat(64), // ERC-4626 deposit
+      };
+      const validate = (yieldId: string) =>
+        call({
+          apiVersion: '1.0',
+          operation: 'validate',
+          yieldId,
+          unsignedTransaction: JSON.stringify(deposit),
+          userAddress: userAddress,
+        });
+
+      expect(validate('unknown-yield').result.reasonCode).toBe(
+        'YIELD_NOT_FOUND',
+      );
+      const unsupported = validate('ethereum-eth-lido-staking');
+      expect(unsupported.result.reasonCode).toBe(
+        'OPERATION_NOT_SUPPORTED_FOR_YIELD',
+      );
+      expect(unsupported.result.reason).toContain(
+        'Supported types: STAKE, UNSTAKE, CLAIM_UNSTAKED',
+      );
     });
 
     it('should reject transaction with wrong contract address', () => {
@@ -120,6 +282,7 @@ describe('handleJsonRequest', () => {
 
       expect(response.ok).toBe(true);
       expect(response.result.isValid).toBe(false);
+      expect(response.result.reason).toBe('SENDER_MISMATCH');
     });
 
     it('should return error for missing yieldId', () => {
@@ -146,16 +309,339 @@ describe('handleJsonRequest', () => {
       expect(response.error.code).toBe('MISSING_REQUIRED_FIELD');
     });
 
-    it('should return error for missing userAddress', () => {
+    it('should validate against the sender when userAddress is missing', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify(validLidoStakeTx),
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.isValid).toBe(true);
+      expect(response.result.warnings).toContainEqual(
+        expect.objectContaining({ code: 'SENDER_NOT_VERIFIED' }),
+      );
+    });
+
+    describe('with rawTransaction', () => {
+      const rawTransaction = ethers.Transaction.from({
+        type: 2,
+        chainId: 1,
+        nonce: 0,
+        to: validLidoStakeTx.to,
+        value: BigInt(validLidoStakeTx.value),
+        data: validLidoStakeTx.data.toLowerCase(),
+        gasLimit: 150000n,
+        maxFeePerGas: 30n * 10n ** 9n,
+        maxPriorityFeePerGas: 10n ** 9n,
+      }).unsignedSerialized;
+
+      it('should validate it and return its decoded fields', () => {
+        const response = call({
+          apiVersion: '1.0',
+          operation: 'validate',
+          yieldId: 'ethereum-eth-lido-staking',
+          rawTransaction,
+          userAddress,
+        });
+
+        expect(response.ok).toBe(true);
+        expect(response.result.isValid).toBe(true);
+        expect(response.result.detectedType).toBe('STAKE');
+        expect(response.result.transaction).toMatchObject({
+          type: 2,
+          chainId: 1,
+          to: validLidoStakeTx.to,
+          from: userAddress,
+          value: '1000000000000000000',
+        });
+      });
+
+      it('should report MALFORMED_RAW_TRANSACTION for bad RLP', () => {
+        const response = call({
+          apiVersion: '1.0',
+          operation: 'validate',
+          yieldId: 'ethereum-eth-lido-staking',
+          rawTransaction: '0x02c0ff',
+          userAddress,
+        });
+
+        expect(response.ok).toBe(true);
+        expect(response.result.isValid).toBe(false);
+        expect(response.result.reasonCode).toBe('MALFORMED_RAW_TRANSACTION');
+      });
+
+      it('should report the signer of a signed transaction', () => {
+        const wallet = new ethers.Wallet(
+          '0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318',
+        );
+        const tx = ethers.Transaction.from(rawTransaction);
+        tx.signature = wallet.signingKey.sign(tx.unsignedHash);
+        const response = call({
+          apiVersion: '1.0',
+          operation: 'validate',
+          yieldId: 'ethereum-eth-lido-staking',
+          rawTransaction: tx.serialized,
+          userAddress,
+        });
+
+        expect(response.ok).toBe(true);
+        expect(response.result.isValid).toBe(false);
+        expect(response.result.reasonCode).toBe('SIGNATURE_SENDER_MISMATCH');
+        expect(response.result.recoveredAddress).toBe(wallet.address);
+        expect(response.result.signatureValid).toBe(true);
+      });
+
+      it('should reject it alongside unsignedTransaction', () => {
+        const response = call({
+          apiVersion: '1.0',
+          operation: 'validate',
+          yieldId: 'ethereum-eth-lido-staking',
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          rawTransaction,
+          userAddress,
+        });
+
+        expect(response.ok).toBe(false);
+        expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+        expect(response.error.details).toEqual({ field: 'rawTransaction' });
+      });
+
+      it('should only be accepted by validate', () => {
+        const response = call({
+          apiVersion: '1.0',
+          operation: 'decode',
+          rawTransaction,
+        });
+
+        expect(response.ok).toBe(false);
+        expect(response.error.message).toBe(
+          "Field 'rawTransaction' is only accepted by validate",
+        );
+      });
+
+      it('should only take accountNumber with it', () => {
+        const response = call({
+          apiVersion: '1.0',
+          operation: 'validate',
+          yieldId: 'cosmos-atom-native-staking',
+          unsignedTransaction: '{}',
+          accountNumber: '42',
+        });
+
+        expect(response.ok).toBe(false);
+        expect(response.error.code).toBe('MISSING_REQUIRED_FIELD');
+        expect(response.error.details).toEqual({ field: 'rawTransaction' });
+      });
+    });
+
+    describe('with yieldIds', () => {
+      const request = {
+        apiVersion: '1.0',
+        operation: 'validate',
+        yieldIds: ['ethereum-eth-reth-staking', 'ethereum-eth-lido-staking'],
+        unsignedTransaction: JSON.stringify(validLidoStakeTx),
+        userAddress,
+      };
+
+      it('should name the candidate that passed', () => {
+        const response = call(request);
+
+        expect(response.ok).toBe(true);
+        expect(response.result.isValid).toBe(true);
+        expect(response.result.yieldId).toBe('ethereum-eth-lido-staking');
+      });
+
+      it('should list each candidate when none passes', () => {
+        const response = call({
+          ...request,
+          yieldIds: ['ethereum-eth-reth-staking'],
+        });
+
+        expect(response.result.reasonCode).toBe('ALL_CANDIDATES_FAILED');
+        expect(response.result.details.candidates).toHaveLength(1);
+      });
+
+      it('should not take yieldId or rpcUrl alongside', () => {
+        for (const extra of [
+          { yieldId: 'ethereum-eth-lido-staking' },
+          { rpcUrl: 'http://127.0.0.1:8545' },
+        ]) {
+          const response = call({ ...request, ...extra });
+
+          expect(response.ok).toBe(false);
+          expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+          expect(response.error.details).toEqual({
+            field: Object.keys(extra)[0],
+          });
+        }
+      });
+    });
+
+    it('should include timing only when includeTiming is set', () => {
+      const request = {
+        apiVersion: '1.0',
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify(validLidoStakeTx),
+        userAddress,
+      };
+
+      expect(call(request).result.timing).toBeUndefined();
+
+      const response = call({ ...request, includeTiming: true });
+      expect(response.ok).toBe(true);
+      expect(response.result.timing).toMatchObject({
+        decodeMs: expect.any(Number),
+        matchMs: expect.any(Number),
+        totalMs: expect.any(Number),
+        path: 'evm-abi-match',
+      });
+    });
+
+    it('should reject a non-boolean includeTiming', () => {
       const response = call({
         apiVersion: '1.0',
         operation: 'validate',
         yieldId: 'ethereum-eth-lido-staking',
         unsignedTransaction: JSON.stringify(validLidoStakeTx),
+        includeTiming: 'yes',
       });
 
       expect(response.ok).toBe(false);
-      expect(response.error.code).toBe('MISSING_REQUIRED_FIELD');
+      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+    });
+
+    describe('responseFields', () => {
+      const request = {
+        apiVersion: '1.0',
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify(validLidoStakeTx),
+        userAddress,
+      };
+
+      it('should answer with only the fields asked for and isValid', () => {
+        const valid = call({ ...request, responseFields: ['reasonCode'] });
+        const invalid = call({
+          ...request,
+          userAddress: '0x0000000000000000000000000000000000000001',
+          responseFields: ['reasonCode'],
+          requestId: 'mobile-1',
+        });
+
+        expect(valid.result).toEqual({ isValid: true });
+        expect(invalid).toMatchObject({ ok: true, requestId: 'mobile-1' });
+        expect(invalid.result).toEqual({
+          isValid: false,
+          reasonCode: 'SENDER_MISMATCH',
+        });
+        expect(invalid.meta.requestHash).toMatch(/^[a-f0-9]{64}$/);
+      });
+
+      it('should keep every field named', () => {
+        const response = call({
+          ...request,
+          responseFields: ['detectedType', 'warnings', 'riskLevel'],
+        });
+
+        expect(Object.keys(response.result).sort()).toEqual([
+          'detectedType',
+          'isValid',
+          'riskLevel',
+          'warnings',
+        ]);
+      });
+
+      it('should reject unknown fields and other operations', () => {
+        const unknown = call({ ...request, responseFields: ['verdict'] });
+        const explain = call({
+          ...request,
+          operation: 'explain',
+          responseFields: ['reasonCode'],
+        });
+
+        expect(unknown.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+        expect(unknown.error.message).toBe(
+          "Unknown validate result field 'verdict' in responseFields",
+        );
+        expect(explain.error.message).toBe(
+          "Field 'responseFields' is only accepted by validate",
+        );
+      });
+    });
+
+    describe('echoRequest', () => {
+      const request = {
+        apiVersion: '1.0',
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify(validLidoStakeTx),
+        userAddress,
+      };
+
+      it('should answer with the request as Shield read it', () => {
+        const response = call({ ...request, echoRequest: true });
+        const { normalizedRequest } = response;
+
+        expect(response.result.isValid).toBe(true);
+        expect(JSON.parse(normalizedRequest.unsignedTransaction)).toEqual({
+          ...validLidoStakeTx,
+          value: '1000000000000000000',
+          chainId: '1',
+        });
+        expect(normalizedRequest).toMatchObject({
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+          beneficiaryAddress: userAddress,
+          strict: false,
+          observe: false,
+          policy: {
+            maxCalldataBytes: 8192,
+            maxDeadlineSeconds: 86400,
+            maxSlippageBps: 500,
+          },
+        });
+      });
+
+      it('should keep the fields the request set', () => {
+        const { normalizedRequest } = call({
+          ...request,
+          strict: true,
+          policy: { maxSlippageBps: 50 },
+          echoRequest: true,
+        });
+
+        expect(normalizedRequest.strict).toBe(true);
+        expect(normalizedRequest.policy.maxSlippageBps).toBe(50);
+      });
+
+      it('should normalize each transaction of a batch', () => {
+        const { normalizedRequest } = call({
+          apiVersion: '1.0',
+          operation: 'validateBatch',
+          transactions: [
+            {
+              yieldId: request.yieldId,
+              unsignedTransaction: request.unsignedTransaction,
+            },
+          ],
+          echoRequest: true,
+        });
+
+        expect(
+          JSON.parse(normalizedRequest.transactions[0].unsignedTransaction)
+            .value,
+        ).toBe('1000000000000000000');
+      });
+
+      it('should be left out by default', () => {
+        expect(call(request)).not.toHaveProperty('normalizedRequest');
+        expect(
+          call({ apiVersion: '1.0', operation: 'health', echoRequest: false }),
+        ).not.toHaveProperty('normalizedRequest');
+      });
     });
   });
 
@@ -231,128 +717,2750 @@ describe('handleJsonRequest', () => {
       expect(response.ok).toBe(true);
       expect(response.result.isValid).toBe(true);
     });
-  });
 
-  describe('isSupported operation', () => {
-    it('should return supported: true for known yield', () => {
+    it('should forward policy parameter to Shield', () => {
       const response = call({
         apiVersion: '1.0',
-        operation: 'isSupported',
+        operation: 'validate',
         yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify(validLidoStakeTx),
+        userAddress: userAddress,
+        policy: { blockedContracts: [validLidoStakeTx.to] },
       });
 
       expect(response.ok).toBe(true);
-      expect(response.result.supported).toBe(true);
+      expect(response.result.isValid).toBe(false);
+      expect(response.result.reason).toBe('CONTRACT_BLOCKED');
     });
 
-    it('should return supported: false for unknown yield', () => {
+    it('should accept policy.blockDelegateCall', () => {
       const response = call({
         apiVersion: '1.0',
-        operation: 'isSupported',
-        yieldId: 'unknown-yield-xyz',
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify(validLidoStakeTx),
+        userAddress: userAddress,
+        policy: { blockDelegateCall: true },
       });
 
       expect(response.ok).toBe(true);
-      expect(response.result.supported).toBe(false);
+      expect(response.result.isValid).toBe(true);
     });
-  });
 
-  describe('getSupportedYieldIds operation', () => {
-    it('should return list of all supported yields', () => {
+    it('should report and check the amount', () => {
       const response = call({
         apiVersion: '1.0',
-        operation: 'getSupportedYieldIds',
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify(validLidoStakeTx),
+        userAddress: userAddress,
+        expectedAmount: '2000000000000000000',
+        amountToleranceBps: 50,
       });
 
       expect(response.ok).toBe(true);
-      expect(response.result.yieldIds).toContain('ethereum-eth-lido-staking');
-      expect(response.result.yieldIds).toContain(
-        'solana-sol-native-multivalidator-staking',
-      );
+      expect(response.result.reasonCode).toBe('AMOUNT_MISMATCH');
+      expect(response.result.amountDelta).toBe('-1000000000000000000');
+      expect(response.result.amount).toEqual({
+        token: 'native',
+        amount: '1000000000000000000',
+        symbol: 'ETH',
+        decimals: 18,
+        normalized: '1.0',
+      });
     });
-  });
 
-  describe('security: schema validation', () => {
-    it('should reject invalid JSON', () => {
-      const response = call('{ invalid json }');
+    it('should reject a non-integer expectedAmount by schema', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify(validLidoStakeTx),
+        expectedAmount: '1.5',
+      });
 
       expect(response.ok).toBe(false);
-      expect(response.error.code).toBe('PARSE_ERROR');
+      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
     });
 
-    it('should reject unknown apiVersion', () => {
+    it('should reject warnings in strict mode', () => {
       const response = call({
-        apiVersion: '2.0',
-        operation: 'getSupportedYieldIds',
+        apiVersion: '1.0',
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify(validLidoStakeTx),
+        strict: true,
       });
 
-      expect(response.ok).toBe(false);
-      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+      expect(response.ok).toBe(true);
+      expect(response.result.isValid).toBe(false);
+      expect(response.result.reason).toBe('STRICT_MODE_WARNING');
+      expect(response.result.details.warningCodes).toEqual([
+        'SENDER_NOT_VERIFIED',
+      ]);
     });
 
-    it('should reject unknown operation', () => {
+    it('should only reject the strictSeverities given', () => {
       const response = call({
         apiVersion: '1.0',
-        operation: 'deleteEverything',
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify(validLidoStakeTx),
+        strict: true,
+        strictSeverities: ['critical'],
       });
 
-      expect(response.ok).toBe(false);
-      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+      expect(response.result.isValid).toBe(true);
+      expect(response.result.warnings).toEqual([
+        expect.objectContaining({
+          code: 'SENDER_NOT_VERIFIED',
+          severity: 'info',
+        }),
+      ]);
     });
 
-    it('should reject unknown properties (no injection)', () => {
+    it('should reject an unknown severity', () => {
       const response = call({
         apiVersion: '1.0',
-        operation: 'getSupportedYieldIds',
-        __proto__: { admin: true }, // Attempted prototype pollution
-        maliciousField: 'value',
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify(validLidoStakeTx),
+        strict: true,
+        strictSeverities: ['high'],
       });
 
       expect(response.ok).toBe(false);
       expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
     });
 
-    it('should reject oversized yieldId', () => {
+    it('should report rejections as wouldReject in observe mode', () => {
       const response = call({
         apiVersion: '1.0',
-        operation: 'isSupported',
-        yieldId: 'x'.repeat(300), // Exceeds 256 char limit
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify(validLidoStakeTx),
+        strict: true,
+        observe: true,
       });
 
-      expect(response.ok).toBe(false);
-      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+      expect(response.ok).toBe(true);
+      expect(response.result.isValid).toBe(true);
+      expect(response.result.wouldReject).toBe(true);
+      expect(response.result.wouldRejectReason).toBe('STRICT_MODE_WARNING');
+      expect(response.result.wouldRejectReasonCode).toBe('STRICT_MODE_WARNING');
     });
   });
 
-  describe('security: tampering detection', () => {
+  describe('explain operation', () => {
     const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
     const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
-
-    const validLidoStakeTx = {
+    const stakeTx = JSON.stringify({
       to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
       from: userAddress,
       value: '0xde0b6b3a7640000',
       data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
       chainId: 1,
-    };
-
-    it('should reject transaction with appended data', () => {
-      const tamperedTx = {
-        ...validLidoStakeTx,
-        data: validLidoStakeTx.data + 'deadbeef',
-      };
+    });
 
+    it('should return the validate result with its trace', () => {
       const response = call({
         apiVersion: '1.0',
-        operation: 'validate',
+        operation: 'explain',
         yieldId: 'ethereum-eth-lido-staking',
-        unsignedTransaction: JSON.stringify(tamperedTx),
-        userAddress: userAddress,
+        unsignedTransaction: stakeTx,
+        userAddress,
       });
 
       expect(response.ok).toBe(true);
-      expect(response.result.isValid).toBe(false);
-    });
+      expect(response.result.isValid).toBe(true);
+      expect(response.result.detectedType).toBe('STAKE');
+      expect(response.result.trace).toContainEqual({
+        check: 'sender',
+        status: 'pass',
+        detail: `Sent by ${userAddress}, the user`,
+      });
+    });
+
+    it('should trace where an invalid transaction fails', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'explain',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: stakeTx,
+        userAddress: '0x0000000000000000000000000000000000000001',
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.reasonCode).toBe('SENDER_MISMATCH');
+      expect(response.result.trace).toContainEqual(
+        expect.objectContaining({ check: 'sender', status: 'fail' }),
+      );
+    });
+
+    it('should require unsignedTransaction', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'explain',
+        yieldId: 'ethereum-eth-lido-staking',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('MISSING_REQUIRED_FIELD');
+    });
+
+    it('should not fetch anything', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'explain',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: stakeTx,
+        userAddress,
+        simulate: true,
+        rpcUrl: 'https://eth.example.com',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+    });
+  });
+
+  describe('validateBatch operation', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const validLidoStakeTx = {
+      to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+      from: userAddress,
+      value: '0xde0b6b3a7640000',
+      data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+      chainId: 1,
+    };
+    const validItem = {
+      yieldId: 'ethereum-eth-lido-staking',
+      unsignedTransaction: JSON.stringify(validLidoStakeTx),
+      userAddress,
+    };
+
+    it('should return results aligned by index', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateBatch',
+        transactions: [
+          validItem,
+          { ...validItem, yieldId: 'unknown-yield-xyz' },
+          validItem,
+        ],
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.results).toHaveLength(3);
+      expect(response.result.results[0].isValid).toBe(true);
+      expect(response.result.results[0].detectedType).toBe('STAKE');
+      expect(response.result.results[1].isValid).toBe(false);
+      expect(response.result.results[1].reason).toBe('Unknown yield ID');
+      expect(response.result.results[2].isValid).toBe(true);
+    });
+
+    it('should summarize the results', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateBatch',
+        transactions: [
+          validItem,
+          { ...validItem, userAddress: undefined }, // SENDER_NOT_VERIFIED
+          { ...validItem, userAddress: referralAddress }, // SENDER_MISMATCH
+          {
+            ...validItem,
+            unsignedTransaction: JSON.stringify({
+              ...validLidoStakeTx,
+              value: 'one ether', // MALFORMED_NUMERIC
+            }),
+          },
+          { ...validItem, yieldId: 'unknown-yield-xyz' },
+        ],
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.results).toHaveLength(5);
+      expect(response.result.summary).toEqual({
+        total: 5,
+        valid: 2,
+        invalid: 1,
+        warned: 1,
+        errored: 2,
+      });
+    });
+
+    it('should count lenient unknown yields as invalid', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateBatch',
+        transactions: [
+          { ...validItem, yieldId: 'unknown-yield-xyz' },
+          {
+            ...validItem,
+            yieldId: 'unknown-yield-xyz',
+            lenientUnknownYield: true,
+          },
+        ],
+      });
+
+      expect(response.result.results[1]).toMatchObject({
+        isValid: false,
+        reasonCode: 'YIELD_NOT_FOUND',
+      });
+      expect(response.result.results[1].warnings).toEqual([
+        expect.objectContaining({ code: 'UNKNOWN_YIELD', severity: 'warning' }),
+      ]);
+      expect(response.result.summary).toMatchObject({
+        invalid: 1,
+        errored: 1,
+      });
+    });
+
+    it('should time only the items that ask for it', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateBatch',
+        transactions: [validItem, { ...validItem, includeTiming: true }],
+      });
+
+      expect(response.result.results[0].timing).toBeUndefined();
+      expect(response.result.results[1].timing.path).toBe('evm-abi-match');
+    });
+
+    it('should not let a malformed item abort the rest of the batch', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateBatch',
+        transactions: [
+          validItem,
+          { ...validItem, unsignedTransaction: '{ invalid json }' },
+          validItem,
+        ],
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.results[0].isValid).toBe(true);
+      expect(response.result.results[1].isValid).toBe(false);
+      expect(response.result.results[2].isValid).toBe(true);
+    });
+
+    it('should apply strict mode per item', () => {
+      const unverified = { ...validItem, userAddress: undefined };
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateBatch',
+        transactions: [{ ...unverified, strict: true }, unverified],
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.results[0].reason).toBe('STRICT_MODE_WARNING');
+      expect(response.result.results[1].isValid).toBe(true);
+    });
+
+    it('should apply observe mode per item', () => {
+      const unverified = { ...validItem, userAddress: undefined, strict: true };
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateBatch',
+        transactions: [{ ...unverified, observe: true }, unverified],
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.results[0].isValid).toBe(true);
+      expect(response.result.results[0].wouldReject).toBe(true);
+      expect(response.result.results[1].isValid).toBe(false);
+    });
+
+    it('should return error for missing transactions', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateBatch',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('MISSING_REQUIRED_FIELD');
+    });
+
+    it('should reject batch items missing required fields', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateBatch',
+        transactions: [{ yieldId: 'ethereum-eth-lido-staking' }],
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+    });
+  });
+
+  describe('decode operation', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const lidoStakeTx = JSON.stringify({
+      to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+      from: userAddress,
+      value: '0xde0b6b3a7640000',
+      data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+      chainId: 1,
+    });
+
+    it('should decode function name, selector and arguments', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'decode',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: lidoStakeTx,
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.decoded.functionName).toBe('submit');
+      expect(response.result.decoded.selector).toBe('0xa1903eab');
+      expect(response.result.decoded.args).toEqual([
+        { name: '_referral', type: 'address', value: referralAddress },
+      ]);
+      expect(response.result.decoded.decodedArgs).toEqual({
+        _referral: referralAddress,
+      });
+      expect(response.result.decoded.detectedType).toBe('STAKE');
+      expect(response.result.decoded.functionSignature).toBe(
+        'submit(address)',
+      );
+    });
+
+    it('should describe an unknown selector with includeSignature', () => {
+      const unknown = JSON.stringify({
+        ...JSON.parse(lidoStakeTx),
+        data: '0xdeadbeef',
+      });
+      const request = {
+        apiVersion: '1.0',
+        operation: 'decode',
+        unsignedTransaction: unknown,
+      };
+
+      expect(call(request).result.decoded).toBeNull();
+      const response = call({ ...request, includeSignature: true });
+      expect(response.ok).toBe(true);
+      expect(response.result.decoded).toEqual({
+        selector: '0xdeadbeef',
+        functionSignature: 'unknown',
+      });
+      expect(response.result.reason).toBe(
+        'No known ABI matches the transaction data',
+      );
+    });
+
+    it('should include the access list', () => {
+      const accessList = [
+        {
+          address: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+          storageKeys: ['0x' + '0'.repeat(64)],
+        },
+      ];
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'decode',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify({
+          ...JSON.parse(lidoStakeTx),
+          accessList,
+          type: 2,
+        }),
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.decoded.accessList).toEqual(accessList);
+    });
+
+    it('should decode a Safe execTransaction', () => {
+      const safeIface = new ethers.Interface([
+        'function execTransaction(address to, uint256 value, bytes data, uint8 operation, uint256 safeTxGas, uint256 baseGas, uint256 gasPrice, address gasToken, address refundReceiver, bytes signatures) returns (bool)',
+      ]);
+      const stake = JSON.parse(lidoStakeTx);
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'decode',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify({
+          to: '0x1111111111111111111111111111111111111111',
+          from: userAddress,
+          value: '0x0',
+          data: safeIface.encodeFunctionData('execTransaction', [
+            stake.to,
+            BigInt(stake.value),
+            stake.data,
+            0,
+            0n,
+            0n,
+            0n,
+            ethers.ZeroAddress,
+            ethers.ZeroAddress,
+            '0x',
+          ]),
+          chainId: 1,
+        }),
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.decoded.functionName).toBe('execTransaction');
+      expect(response.result.decoded.args[2]).toEqual({
+        name: 'data',
+        type: 'bytes',
+        value: stake.data.toLowerCase(),
+      });
+      expect(response.result.decoded.detectedType).toBe('STAKE');
+    });
+
+    it('should decode without a yieldId', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'decode',
+        unsignedTransaction: lidoStakeTx,
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.decoded.functionName).toBe('submit');
+      expect(response.result.decoded.detectedType).toBeUndefined();
+    });
+
+    it('should decode transactions that fail validation', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'decode',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify({
+          ...JSON.parse(lidoStakeTx),
+          chainId: 137,
+        }),
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.decoded.functionName).toBe('submit');
+      expect(response.result.decoded.detectedType).toBeUndefined();
+    });
+
+    it('should return decoded: null with a reason when no ABI matches', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'decode',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify({
+          ...JSON.parse(lidoStakeTx),
+          data: '0xdeadbeef',
+        }),
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.decoded).toBeNull();
+      expect(response.result.reason).toBe(
+        'No known ABI matches the transaction data',
+      );
+    });
+
+    it('should not fail on malformed transactions or unknown yields', () => {
+      const malformed = call({
+        apiVersion: '1.0',
+        operation: 'decode',
+        unsignedTransaction: '{ invalid json }',
+      });
+      const unknownYield = call({
+        apiVersion: '1.0',
+        operation: 'decode',
+        yieldId: 'unknown-yield-xyz',
+        unsignedTransaction: lidoStakeTx,
+      });
+
+      expect(malformed.ok).toBe(true);
+      expect(malformed.result.decoded).toBeNull();
+      expect(unknownYield.ok).toBe(true);
+      expect(unknownYield.result.decoded).toBeNull();
+      expect(unknownYield.result.reason).toBe('Unknown yield ID');
+    });
+
+    it('should return error for missing unsignedTransaction', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'decode',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('MISSING_REQUIRED_FIELD');
+    });
+  });
+
+  describe('isSupported operation', () => {
+    it('should return supported: true for known yield', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'isSupported',
+        yieldId: 'ethereum-eth-lido-staking',
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.supported).toBe(true);
+    });
+
+    it('should return supported: false for unknown yield', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'isSupported',
+        yieldId: 'unknown-yield-xyz',
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.supported).toBe(false);
+    });
+  });
+
+  describe('getSupportedYieldIds operation', () => {
+    it('should return list of all supported yields', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'getSupportedYieldIds',
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.yieldIds).toContain('ethereum-eth-lido-staking');
+      expect(response.result.yieldIds).toContain(
+        'solana-sol-native-multivalidator-staking',
+      );
+    });
+
+    it('should filter by chainId', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'getSupportedYieldIds',
+        chainId: '1',
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.yieldIds).toContain('ethereum-eth-lido-staking');
+      expect(response.result.yieldIds).not.toContain(
+        'solana-sol-native-multivalidator-staking',
+      );
+    });
+
+    it('should list each yield with its chainId', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'getSupportedYieldIds',
+        chainId: '1',
+      });
+
+      expect(response.result.yields).toContainEqual({
+        yieldId: 'ethereum-eth-lido-staking',
+        chainId: '1',
+      });
+      const yieldIds = response.result.yields.map(
+        (entry: { yieldId: string }) => entry.yieldId,
+      );
+      expect(yieldIds).toEqual(response.result.yieldIds);
+    });
+
+    it('should answer notModified while the list is unchanged', () => {
+      const request = { apiVersion: '1.0', operation: 'getSupportedYieldIds' };
+      const { registryHash } = call(request).result;
+      expect(registryHash).toMatch(/^[0-9a-f]{64}$/);
+
+      const cached = call({ ...request, ifNoneMatch: registryHash });
+      expect(cached.ok).toBe(true);
+      expect(cached.result).toEqual({
+        yieldIds: [],
+        yields: [],
+        registryHash,
+        notModified: true,
+      });
+
+      const stale = call({ ...request, ifNoneMatch: 'stale' });
+      expect(stale.result.notModified).toBeUndefined();
+      expect(stale.result.yieldIds).toContain('ethereum-eth-lido-staking');
+    });
+
+    it('should hash each chain and registry override apart', () => {
+      const request = { apiVersion: '1.0', operation: 'getSupportedYieldIds' };
+      const { registryHash } = call(request).result;
+      const onChain = call({
+        ...request,
+        chainId: '1',
+        ifNoneMatch: registryHash,
+      });
+      const overridden = call({
+        ...request,
+        ifNoneMatch: registryHash,
+        registryOverride: {
+          vaults: [
+            {
+              yieldId: 'sepolia-usdc-test-vault',
+              address: '0x1111111111111111111111111111111111111111',
+              chainId: 11155111,
+              protocol: 'morpho',
+              network: 'sepolia',
+              inputTokenAddress: '0x2222222222222222222222222222222222222222',
+              vaultTokenAddress: '0x1111111111111111111111111111111111111111',
+              isWethVault: false,
+            },
+          ],
+        },
+      });
+
+      expect(onChain.result.notModified).toBeUndefined();
+      expect(onChain.result.registryHash).not.toBe(registryHash);
+      expect(overridden.result.notModified).toBeUndefined();
+      expect(overridden.result.registryHash).not.toBe(registryHash);
+    });
+
+    it('should page through the list with pageSize and pageToken', () => {
+      const request = { apiVersion: '1.0', operation: 'getSupportedYieldIds' };
+      const all = call(request).result;
+
+      const yieldIds: string[] = [];
+      let pageToken: string | undefined;
+      do {
+        const page = call({ ...request, pageSize: 500, pageToken }).result;
+        expect(page.registryHash).toBe(all.registryHash);
+        expect(page.yieldIds.length).toBeLessThanOrEqual(500);
+        yieldIds.push(...page.yieldIds);
+        pageToken = page.nextPageToken;
+      } while (pageToken !== undefined);
+
+      expect(yieldIds).toEqual(all.yieldIds);
+      expect(all.nextPageToken).toBeUndefined();
+    });
+
+    it('should reject a malformed or stale pageToken', () => {
+      const request = { apiVersion: '1.0', operation: 'getSupportedYieldIds' };
+      const { nextPageToken } = call({ ...request, pageSize: 1 }).result;
+
+      const malformed = call({ ...request, pageToken: 'abc' });
+      expect(malformed.ok).toBe(false);
+      expect(malformed.error.code).toBe('INVALID_PAGE_TOKEN');
+
+      // The token pages through every yield, not those on one chain
+      const stale = call({
+        ...request,
+        chainId: '1',
+        pageToken: nextPageToken,
+      });
+      expect(stale.ok).toBe(false);
+      expect(stale.error.code).toBe('INVALID_PAGE_TOKEN');
+      expect(stale.error.message).toMatch(/changed since pageToken/);
+    });
+
+    it('should reject pageSize on other operations', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'getVersion',
+        pageSize: 10,
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.details.field).toBe('pageSize');
+    });
+
+    it('should reject ifNoneMatch on other operations', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'getVersion',
+        ifNoneMatch: 'abc',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.details.field).toBe('ifNoneMatch');
+    });
+
+    it('should reject chainId on other operations', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'isSupported',
+        yieldId: 'ethereum-eth-lido-staking',
+        chainId: '1',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+    });
+  });
+
+  describe('getYieldCapabilities operation', () => {
+    it('should return the capabilities of a supported yield', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'getYieldCapabilities',
+        yieldId: 'cosmos-atom-native-staking',
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result).toEqual({
+        yieldId: 'cosmos-atom-native-staking',
+        name: 'Cosmos Hub native staking',
+        protocol: 'native',
+        network: 'cosmos',
+        supportedTypes: [
+          'STAKE',
+          'UNSTAKE',
+          'CLAIM_REWARDS',
+          'RESTAKE_REWARDS',
+        ],
+        supportsPartialAmounts: true,
+        chainId: 'cosmoshub-4',
+        contracts: [],
+        status: 'active',
+      });
+    });
+
+    it('should return YIELD_NOT_FOUND for an unknown yield', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'getYieldCapabilities',
+        yieldId: 'unknown-yield',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('YIELD_NOT_FOUND');
+    });
+
+    it('should require yieldId', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'getYieldCapabilities',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('MISSING_REQUIRED_FIELD');
+    });
+  });
+
+  describe('getYields operation', () => {
+    it('should describe each yield in the order given', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'getYields',
+        yieldIds: ['cosmos-atom-native-staking', 'ethereum-eth-lido-staking'],
+      });
+
+      expect(response.ok).toBe(true);
+      expect(
+        response.result.yields.map((y: { yieldId: string }) => y.yieldId),
+      ).toEqual(['cosmos-atom-native-staking', 'ethereum-eth-lido-staking']);
+      expect(response.result.yields[0]).toEqual({
+        yieldId: 'cosmos-atom-native-staking',
+        name: 'Cosmos Hub native staking',
+        protocol: 'native',
+        network: 'cosmos',
+        supportedTypes: [
+          'STAKE',
+          'UNSTAKE',
+          'CLAIM_REWARDS',
+          'RESTAKE_REWARDS',
+        ],
+        supportsPartialAmounts: true,
+        chainId: 'cosmoshub-4',
+        contracts: [],
+        status: 'active',
+      });
+      expect(response.result.unknown).toEqual([]);
+    });
+
+    it('should list unknown yields rather than fail', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'getYields',
+        yieldIds: ['unknown-yield', 'ethereum-eth-lido-staking'],
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.yields).toHaveLength(1);
+      expect(response.result.unknown).toEqual(['unknown-yield']);
+    });
+
+    it('should require yieldIds', () => {
+      const response = call({ apiVersion: '1.0', operation: 'getYields' });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('MISSING_REQUIRED_FIELD');
+    });
+
+    it('should reject yieldIds on other operations', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'getYieldCapabilities',
+        yieldId: 'ethereum-eth-lido-staking',
+        yieldIds: ['ethereum-eth-lido-staking'],
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+      expect(response.error.details.field).toBe('yieldIds');
+    });
+  });
+
+  describe('getYieldAbi operation', () => {
+    it('should return the function fragments of a yield', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'getYieldAbi',
+        yieldId: 'ethereum-eth-lido-staking',
+        transactionType: 'STAKE',
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result).toEqual({
+        yieldId: 'ethereum-eth-lido-staking',
+        functions: [
+          {
+            transactionType: 'STAKE',
+            name: 'submit',
+            selector: '0xa1903eab',
+            signature: 'submit(address)',
+            inputs: [{ name: '_referral', type: 'address' }],
+            payable: true,
+          },
+        ],
+      });
+    });
+
+    it('should return an empty list for yields without contract calls', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'getYieldAbi',
+        yieldId: 'cosmos-atom-native-staking',
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.functions).toEqual([]);
+    });
+
+    it('should return YIELD_NOT_FOUND for an unknown yield', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'getYieldAbi',
+        yieldId: 'unknown-yield',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('YIELD_NOT_FOUND');
+    });
+
+    it('should reject an unknown transaction type', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'getYieldAbi',
+        yieldId: 'ethereum-eth-lido-staking',
+        transactionType: 'STAKE_ALL',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+    });
+
+    it('should reject transactionType on other operations', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'getYieldCapabilities',
+        yieldId: 'ethereum-eth-lido-staking',
+        transactionType: 'STAKE',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+    });
+  });
+
+  describe('detectYields operation', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const unsignedTransaction = JSON.stringify({
+      to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+      from: userAddress,
+      value: '0xde0b6b3a7640000',
+      data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+      chainId: 1,
+    });
+
+    it('should return the matching yields with their detected types', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'detectYields',
+        unsignedTransaction,
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result).toEqual({
+        yieldIds: ['ethereum-eth-lido-staking'],
+        matches: [
+          { yieldId: 'ethereum-eth-lido-staking', detectedType: 'STAKE' },
+        ],
+      });
+    });
+
+    it('should return an empty result when nothing matches', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'detectYields',
+        unsignedTransaction,
+        chainId: '42161',
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result).toEqual({ yieldIds: [], matches: [] });
+    });
+
+    it('should require unsignedTransaction', () => {
+      const response = call({ apiVersion: '1.0', operation: 'detectYields' });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('MISSING_REQUIRED_FIELD');
+    });
+  });
+
+  describe('getVersion operation', () => {
+    it('should identify the build and registry snapshot', () => {
+      const response = call({ apiVersion: '1.0', operation: 'getVersion' });
+
+      expect(response.ok).toBe(true);
+      expect(typeof response.result.version).toBe('string');
+      expect(typeof response.result.gitCommit).toBe('string');
+      expect(typeof response.result.buildDate).toBe('string');
+      expect(response.result.supportedApiVersions).toContain('1.0');
+      expect(response.result.registry.hash).toMatch(/^[0-9a-f]{64}$/);
+      expect(response.result.registry.yieldCount).toBe(
+        call({ apiVersion: '1.0', operation: 'getSupportedYieldIds' }).result
+          .yieldIds.length,
+      );
+    });
+
+    it('should report the same registry hash on every call', () => {
+      const first = call({ apiVersion: '1.0', operation: 'getVersion' });
+      const second = call({ apiVersion: '1.0', operation: 'getVersion' });
+
+      expect(second.result.registry).toEqual(first.result.registry);
+    });
+  });
+
+  describe('checkRegistry operation', () => {
+    it('should check the registry with the request override', () => {
+      const vault = {
+        yieldId: 'sepolia-weth-test-vault',
+        address: '0x3333333333333333333333333333333333333333',
+        chainId: 11155111,
+        protocol: 'morpho',
+        network: 'sepolia',
+        inputTokenAddress: '0x4444444444444444444444444444444444444444',
+        vaultTokenAddress: '0x3333333333333333333333333333333333333333',
+        isWethVault: true,
+      };
+      const clean = call({ apiVersion: '1.0', operation: 'checkRegistry' });
+      const duplicated = call({
+        apiVersion: '1.0',
+        operation: 'checkRegistry',
+        registryOverride: { vaults: [vault, vault] },
+      });
+
+      expect(clean.ok).toBe(true);
+      expect(clean.result.isValid).toBe(true);
+      expect(clean.result.problems).toEqual([]);
+      expect(duplicated.ok).toBe(true);
+      expect(duplicated.result.isValid).toBe(false);
+      expect(duplicated.result.problems[0]).toMatchObject({
+        severity: 'error',
+        code: 'DUPLICATE_YIELD_ID',
+      });
+    });
+  });
+
+  describe('registry override', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const eulerYieldId =
+      'arbitrum-arb-earb-1-0x7ed866d2d66c3149fafe854c30c68a8ba7cee8b9-4626-vault';
+    const testnetVault = {
+      yieldId: 'sepolia-usdc-test-vault',
+      address: '0x1111111111111111111111111111111111111111',
+      chainId: 11155111,
+      protocol: 'morpho',
+      network: 'sepolia',
+      inputTokenAddress: '0x2222222222222222222222222222222222222222',
+      vaultTokenAddress: '0x1111111111111111111111111111111111111111',
+      isWethVault: false,
+    };
+    const registryOverride = { vaults: [testnetVault] };
+
+    const vaultIface = new ethers.Interface([
+      'function deposit(uint256 assets, address receiver) returns (uint256)',
+    ]);
+    const depositTx = (to: string, chainId: number) =>
+      JSON.stringify({
+        to,
+        from: userAddress,
+        value: '0x0',
+        data: vaultIface.encodeFunctionData('deposit', [100n, userAddress]),
+        chainId,
+      });
+
+    it('should validate the vaults of an inline override', () => {
+      const request = {
+        apiVersion: '1.0',
+        operation: 'validate',
+        yieldId: testnetVault.yieldId,
+        unsignedTransaction: depositTx(testnetVault.address, 11155111),
+        userAddress,
+      };
+
+      expect(call(request).result.reason).toBe('Unknown yield ID');
+
+      const response = call({ ...request, registryOverride });
+      expect(response.ok).toBe(true);
+      expect(response.result.isValid).toBe(true);
+      expect(response.result.detectedType).toBe('SUPPLY');
+    });
+
+    it('should prefer the override for a built-in yieldId', () => {
+      const relocated = {
+        ...testnetVault,
+        yieldId: eulerYieldId,
+        chainId: 42161,
+      };
+      const request = {
+        apiVersion: '1.0',
+        operation: 'validate',
+        yieldId: eulerYieldId,
+        userAddress,
+        registryOverride: { vaults: [relocated] },
+      };
+
+      const builtIn = call({
+        ...request,
+        unsignedTransaction: depositTx(
+          '0x7ed866d2d66c3149fafe854c30c68a8ba7cee8b9',
+          42161,
+        ),
+      });
+      expect(builtIn.result.isValid).toBe(false);
+
+      const overridden = call({
+        ...request,
+        unsignedTransaction: depositTx(testnetVault.address, 42161),
+      });
+      expect(overridden.result.isValid).toBe(true);
+    });
+
+    it('should report in getVersion whether an override is active', () => {
+      const plain = call({ apiVersion: '1.0', operation: 'getVersion' });
+      expect(plain.result.registry.overrideActive).toBe(false);
+      expect(plain.result.registry.overrideHash).toBeUndefined();
+
+      const overridden = call({
+        apiVersion: '1.0',
+        operation: 'getVersion',
+        registryOverride,
+      });
+      expect(overridden.result.registry.overrideActive).toBe(true);
+      expect(overridden.result.registry.overrideHash).toMatch(
+        /^[0-9a-f]{64}$/,
+      );
+      expect(overridden.result.registry.yieldCount).toBe(
+        plain.result.registry.yieldCount + 1,
+      );
+    });
+
+    it('should merge an inline override over the handler option', () => {
+      const staging = { ...testnetVault, yieldId: 'sepolia-usdc-staging' };
+      const isSupported = (yieldId: string, inline?: object) =>
+        JSON.parse(
+          handleJsonRequest(
+            JSON.stringify({
+              apiVersion: '1.0',
+              operation: 'isSupported',
+              yieldId,
+              registryOverride: inline,
+            }),
+            { registryOverride },
+          ),
+        ).result.supported;
+
+      expect(isSupported(testnetVault.yieldId)).toBe(true);
+      expect(isSupported(staging.yieldId)).toBe(false);
+      expect(isSupported(staging.yieldId, { vaults: [staging] })).toBe(true);
+      expect(isSupported(testnetVault.yieldId, { vaults: [staging] })).toBe(
+        true,
+      );
+    });
+
+    it('should check actualBytecodeHash against the pinned hash', () => {
+      const pinnedHash = '0x' + '11'.repeat(32);
+      const request = {
+        apiVersion: '1.0',
+        operation: 'validate',
+        yieldId: testnetVault.yieldId,
+        unsignedTransaction: depositTx(testnetVault.address, 11155111),
+        userAddress,
+        registryOverride: {
+          vaults: [
+            {
+              ...testnetVault,
+              bytecodeHashes: { [testnetVault.address]: pinnedHash },
+            },
+          ],
+        },
+      };
+
+      const pinned = call({ ...request, actualBytecodeHash: pinnedHash });
+      expect(pinned.result.isValid).toBe(true);
+
+      const replaced = call({
+        ...request,
+        actualBytecodeHash: '0x' + '22'.repeat(32),
+      });
+      expect(replaced.result.isValid).toBe(false);
+      expect(replaced.result.reasonCode).toBe('BYTECODE_MISMATCH');
+
+      const malformed = call({ ...request, actualBytecodeHash: '0x11' });
+      expect(malformed.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+    });
+
+    it('should reject an override that does not match the schema', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'getVersion',
+        registryOverride: { vaults: [{ ...testnetVault, address: 'vault' }] },
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+      expect(response.error.details.field).toBe(
+        'registryOverride.vaults[0].address',
+      );
+    });
+
+    it('should parse registry files against the same schema', () => {
+      expect(parseRegistryOverride(JSON.stringify(registryOverride))).toEqual(
+        registryOverride,
+      );
+      expect(() => parseRegistryOverride('{"vaults":')).toThrow(
+        'Invalid registry override',
+      );
+      expect(() => parseRegistryOverride('{}')).toThrow(
+        "Invalid registry override: Missing required field 'vaults'",
+      );
+    });
+  });
+
+  describe('reloadRegistry operation', () => {
+    const request = { apiVersion: '1.0', operation: 'reloadRegistry' };
+    const registry = {
+      yieldCount: 1,
+      lastUpdated: '2026-01-01T00:00:00.000Z',
+      overrideActive: true,
+      overrideHash: 'abc',
+    };
+
+    it('should be unavailable without a registry file', () => {
+      const response = call(request);
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('RELOAD_UNAVAILABLE');
+    });
+
+    it('should report the yields a reload added and removed', () => {
+      const result = { added: ['a'], removed: ['b'], registry };
+      const response = JSON.parse(
+        handleJsonRequest(JSON.stringify(request), {
+          reloadRegistry: () => result,
+        }),
+      );
+
+      expect(response.ok).toBe(true);
+      expect(response.result).toEqual(result);
+    });
+
+    it('should report a reload that failed', () => {
+      const response = JSON.parse(
+        handleJsonRequest(JSON.stringify(request), {
+          reloadRegistry: () => {
+            throw new Error('Invalid registry override: Invalid JSON');
+          },
+        }),
+      );
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('RELOAD_FAILED');
+      expect(response.error.message).toBe(
+        'Invalid registry override: Invalid JSON',
+      );
+    });
+  });
+
+  describe('getSchema operation', () => {
+    const schemas = call({ apiVersion: '1.0', operation: 'getSchema' }).result;
+    const ajv = new Ajv({ allErrors: true, strict: true });
+    const validateRequest = ajv.compile(schemas.request);
+    const validateResponse = ajv.compile(schemas.response);
+    const validateResult = (operation: string, result: unknown) =>
+      ajv.compile({
+        definitions: schemas.response.definitions,
+        $ref: `#/definitions/${schemas.resultDefinitions[operation]}`,
+      })(result);
+
+    it('should describe requests and responses of the apiVersion', () => {
+      expect(schemas.apiVersion).toBe('1.0');
+      expect(schemas.request.title).toBe('ShieldRequest');
+      expect(schemas.response.title).toBe('ShieldResponse');
+      expect(schemas.request.properties.apiVersion).toEqual({ const: '1.0' });
+    });
+
+    it('should list operations, detected types and codes', () => {
+      const { definitions } = schemas.response;
+
+      expect(definitions.Operation.enum).toContain('getSchema');
+      expect(definitions.Operation.enum).toEqual(
+        schemas.request.properties.operation.enum,
+      );
+      expect(Object.keys(schemas.resultDefinitions)).toEqual(
+        definitions.Operation.enum,
+      );
+      expect(definitions.DetectedType.enum).toContain('STAKE');
+      expect(definitions.ReasonCode.enum).toContain('NO_MATCHING_PATTERN');
+      expect(definitions.WarningCode.enum).toContain('INFINITE_APPROVAL');
+      expect(definitions.ErrorCode.enum).toContain('SCHEMA_VALIDATION_ERROR');
+    });
+
+    it('should accept requests and responses the handler exchanges', () => {
+      const request = {
+        apiVersion: '1.0',
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify({
+          to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+          from: '0x742d35cc6634c0532925a3b844bc9e7595f0beb8',
+          value: '0xde0b6b3a7640000',
+          data: '0xa1903eab' + '0'.repeat(64),
+          chainId: 1,
+        }),
+        userAddress: '0x742d35cc6634c0532925a3b844bc9e7595f0beb8',
+      };
+      const response = call(request);
+
+      expect(validateRequest(request)).toBe(true);
+      expect(validateResponse(response)).toBe(true);
+      expect(validateResult('validate', response.result)).toBe(true);
+      expect(validateResponse(call({ apiVersion: '1.0' }))).toBe(true);
+    });
+
+    it('should accept the result of every read-only operation', () => {
+      for (const operation of ['getSupportedYieldIds', 'getVersion']) {
+        const response = call({ apiVersion: '1.0', operation });
+
+        expect(validateResult(operation, response.result)).toBe(true);
+      }
+      expect(validateResult('getSchema', schemas)).toBe(true);
+    });
+
+    it('should reject responses with an unknown error code', () => {
+      const response = call({ apiVersion: '1.0' });
+      response.error.code = 'NOT_A_CODE';
+
+      expect(validateResponse(response)).toBe(false);
+    });
+  });
+
+  describe('listOperations operation', () => {
+    const { operations } = call({
+      apiVersion: '1.0',
+      operation: 'listOperations',
+    }).result;
+    const find = (name: string) =>
+      operations.find((operation: { name: string }) => operation.name === name);
+
+    it('should list every operation the request schema accepts', () => {
+      const schemas = call({ apiVersion: '1.0', operation: 'getSchema' });
+
+      expect(
+        operations.map((operation: { name: string }) => operation.name),
+      ).toEqual(schemas.result.response.definitions.Operation.enum);
+      expect(find('listOperations')).toBeDefined();
+    });
+
+    it('should describe the fields of an operation', () => {
+      expect(find('getYieldAbi')).toEqual({
+        name: 'getYieldAbi',
+        description: "List the contract functions a yield's transactions call",
+        requiredFields: ['yieldId'],
+        optionalFields: ['transactionType', 'registryOverride'],
+      });
+      expect(find('validate').requiredFields).toEqual([
+        'yieldId',
+        'unsignedTransaction',
+      ]);
+      expect(find('validate').optionalFields).toContain('rawTransaction');
+      expect(find('getVersion').requiredFields).toEqual([]);
+    });
+
+    it('should only name fields the request schema accepts', () => {
+      const schemas = call({ apiVersion: '1.0', operation: 'getSchema' });
+      const fields = Object.keys(schemas.result.request.properties);
+
+      for (const operation of operations) {
+        for (const field of [
+          ...operation.requiredFields,
+          ...operation.optionalFields,
+        ]) {
+          expect(fields).toContain(field);
+        }
+      }
+    });
+  });
+
+  describe('health operation', () => {
+    const request = JSON.stringify({ apiVersion: '1.0', operation: 'health' });
+
+    it('should be ready with the built-in registry', () => {
+      const response = JSON.parse(handleJsonRequest(request));
+
+      expect(response.ok).toBe(true);
+      expect(response.result).toEqual({ ready: true });
+    });
+
+    it('should report why the process is not ready', () => {
+      const health = { ready: false, reason: 'Registry reload failed' };
+      const response = JSON.parse(handleJsonRequest(request, { health }));
+
+      expect(response.ok).toBe(true);
+      expect(response.result).toEqual(health);
+    });
+  });
+
+  describe('attest operation', () => {
+    it('should report the SHA-256 of the file the process runs', () => {
+      const response = call({ apiVersion: '1.0', operation: 'attest' });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.path).toEqual(expect.any(String));
+      expect(response.result.kind).toBe('script');
+      expect(response.result.sha256).toMatch(/^[0-9a-f]{64}$/);
+      expect(response.result.checksum).toEqual({ status: 'absent' });
+    });
+  });
+
+  describe('validateTypedData operation', () => {
+    const typedData = {
+      domain: {
+        name: 'Liquid staked Ether 2.0',
+        version: '2',
+        chainId: 1,
+        verifyingContract: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+      },
+      types: {
+        Permit: [
+          { name: 'owner', type: 'address' },
+          { name: 'spender', type: 'address' },
+          { name: 'value', type: 'uint256' },
+          { name: 'nonce', type: 'uint256' },
+          { name: 'deadline', type: 'uint256' },
+        ],
+      },
+      primaryType: 'Permit',
+      message: {
+        owner: '0x742d35cc6634c0532925a3b844bc9e7595f0beb8',
+        spender: '0x889edC2eDab5f40e902b864aD4d7AdE8E412F9B1',
+        value: '1000000000000000000',
+        nonce: 0,
+        deadline: Math.floor(Date.now() / 1000) + 3600,
+      },
+    };
+
+    it('should validate a permit', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateTypedData',
+        yieldId: 'ethereum-eth-lido-staking',
+        userAddress: '0x742d35cc6634c0532925a3b844bc9e7595f0beb8',
+        typedData,
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.isValid).toBe(true);
+      expect(response.result.detectedType).toBe('PERMIT');
+      expect(response.result.warnings).toEqual([]);
+      expect(response.result.deadline.timestamp).toBe(
+        String(typedData.message.deadline),
+      );
+    });
+
+    it('should apply the policy maxDeadlineSeconds', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateTypedData',
+        yieldId: 'ethereum-eth-lido-staking',
+        userAddress: '0x742d35cc6634c0532925a3b844bc9e7595f0beb8',
+        typedData,
+        policy: { maxDeadlineSeconds: 60 },
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.isValid).toBe(true);
+      expect(
+        response.result.warnings.map((w: { code: string }) => w.code),
+      ).toEqual(['LONG_DEADLINE']);
+    });
+
+    it('should require userAddress', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateTypedData',
+        yieldId: 'ethereum-eth-lido-staking',
+        typedData,
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('MISSING_REQUIRED_FIELD');
+    });
+
+    it('should reject typed data without a message', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateTypedData',
+        yieldId: 'ethereum-eth-lido-staking',
+        userAddress: '0x742d35cc6634c0532925a3b844bc9e7595f0beb8',
+        typedData: { ...typedData, message: undefined },
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+    });
+
+    it('should report the hash of a SafeTx', () => {
+      const safe = '0x1111111111111111111111111111111111111111';
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateTypedData',
+        yieldId: 'ethereum-eth-lido-staking',
+        userAddress: safe,
+        typedData: {
+          domain: { chainId: 1, verifyingContract: safe },
+          types: {
+            SafeTx: [
+              { name: 'to', type: 'address' },
+              { name: 'value', type: 'uint256' },
+              { name: 'data', type: 'bytes' },
+              { name: 'operation', type: 'uint8' },
+              { name: 'safeTxGas', type: 'uint256' },
+              { name: 'baseGas', type: 'uint256' },
+              { name: 'gasPrice', type: 'uint256' },
+              { name: 'gasToken', type: 'address' },
+              { name: 'refundReceiver', type: 'address' },
+              { name: 'nonce', type: 'uint256' },
+            ],
+          },
+          primaryType: 'SafeTx',
+          message: {
+            to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+            value: '1000000000000000000',
+            data: new ethers.Interface([
+              'function submit(address _referral) payable returns (uint256)',
+            ]).encodeFunctionData('submit', [
+              '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be',
+            ]),
+            operation: 0,
+            safeTxGas: '0',
+            baseGas: '0',
+            gasPrice: '0',
+            gasToken: ethers.ZeroAddress,
+            refundReceiver: ethers.ZeroAddress,
+            nonce: 0,
+          },
+        },
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.isValid).toBe(true);
+      expect(response.result.detectedType).toBe('STAKE');
+      expect(response.result.safeTxHash).toMatch(/^0x[0-9a-f]{64}$/);
+    });
+  });
+
+  describe('validateUserOperation operation', () => {
+    const account = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const callData = new ethers.Interface([
+      'function execute(address dest, uint256 value, bytes func)',
+    ]).encodeFunctionData('execute', [
+      '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84', // Lido stETH
+      10n ** 18n,
+      '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+    ]);
+    const userOperation = { sender: account, nonce: '0x0', callData };
+
+    it('should validate the calls of a user operation', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateUserOperation',
+        yieldId: 'ethereum-eth-lido-staking',
+        userOperation,
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.isValid).toBe(true);
+      expect(response.result.detectedType).toBe('STAKE');
+      expect(response.result.detectedTypes).toEqual(['STAKE']);
+      expect(response.result.steps).toHaveLength(1);
+      expect(response.result.warnings).toEqual([]);
+    });
+
+    it('should report an unexpected paymaster', () => {
+      const paymaster = '0x00000000000000fb866daaa79352cc568a005d96';
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateUserOperation',
+        yieldId: 'ethereum-eth-lido-staking',
+        userOperation: { ...userOperation, paymasterAndData: paymaster },
+        paymasters: ['0x0000000000000000000000000000000000000001'],
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.paymaster).toBe(paymaster);
+      expect(response.result.warnings[0].code).toBe('UNKNOWN_PAYMASTER');
+      expect(response.result.warnings[0].severity).toBe('warning');
+    });
+
+    it('should require a userOperation', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateUserOperation',
+        yieldId: 'ethereum-eth-lido-staking',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('MISSING_REQUIRED_FIELD');
+    });
+
+    it('should reject non-hex userOperation fields', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateUserOperation',
+        yieldId: 'ethereum-eth-lido-staking',
+        userOperation: { ...userOperation, nonce: '1' },
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+    });
+  });
+
+  describe('validateSendCalls operation', () => {
+    const account = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const sendCalls = {
+      version: '2.0.0',
+      chainId: '0x1',
+      from: account,
+      atomicRequired: true,
+      calls: [
+        {
+          to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84', // Lido stETH
+          value: '0xde0b6b3a7640000',
+          data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+        },
+      ],
+    };
+
+    it('should validate the calls of a wallet_sendCalls batch', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateSendCalls',
+        yieldId: 'ethereum-eth-lido-staking',
+        sendCalls,
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.isValid).toBe(true);
+      expect(response.result.atomicRequired).toBe(true);
+      expect(response.result.detectedTypes).toEqual(['STAKE']);
+      expect(response.result.steps).toHaveLength(1);
+      expect(response.result.warnings).toEqual([]);
+    });
+
+    it('should report capabilities Shield does not know', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateSendCalls',
+        yieldId: 'ethereum-eth-lido-staking',
+        sendCalls: { ...sendCalls, capabilities: { permissions: {} } },
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.warnings[0].code).toBe('UNKNOWN_CAPABILITY');
+      expect(response.result.warnings[0].details).toEqual({
+        capability: 'permissions',
+      });
+    });
+
+    it('should reject calls without a target', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateSendCalls',
+        yieldId: 'ethereum-eth-lido-staking',
+        sendCalls: { ...sendCalls, calls: [{ data: '0x' }] },
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+    });
+  });
+
+  describe('compareIntent operation', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const unsignedTransaction = JSON.stringify({
+      to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+      from: userAddress,
+      value: '0xde0b6b3a7640000',
+      data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+      chainId: 1,
+    });
+    const intent = {
+      action: 'STAKE',
+      yieldId: 'ethereum-eth-lido-staking',
+      amount: '1000000000000000000',
+      token: 'native',
+    };
+
+    it('should compare a transaction with the intent', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'compareIntent',
+        intent,
+        unsignedTransaction,
+        userAddress,
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result).toMatchObject({
+        match: true,
+        actionMatch: true,
+        amountMatch: true,
+        recipientMatch: true,
+      });
+      expect(response.result.validation.isValid).toBe(true);
+      expect(response.result.validation.warnings).toEqual([]);
+    });
+
+    it('should report a tampered amount as INTENT_MISMATCH', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'compareIntent',
+        intent: { ...intent, amount: '100000000000000000' },
+        unsignedTransaction,
+        userAddress,
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.match).toBe(false);
+      expect(response.result.reasonCode).toBe('INTENT_MISMATCH');
+      expect(response.result.details.fields).toEqual(['amount']);
+    });
+
+    it('should require an intent', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'compareIntent',
+        unsignedTransaction,
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('MISSING_REQUIRED_FIELD');
+    });
+
+    it('should reject an intent with an unknown action', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'compareIntent',
+        intent: { ...intent, action: 'BURN' },
+        unsignedTransaction,
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+    });
+  });
+
+  describe('preflight operation', () => {
+    it('should check a stake before it is built', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'preflight',
+        yieldId: 'ethereum-eth-lido-staking',
+        detectedType: 'STAKE',
+        userAddress: '0x742d35cc6634c0532925a3b844bc9e7595f0beb8',
+        amount: '1000000000000000000',
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result).toMatchObject({
+        allowed: true,
+        warnings: [],
+        chainId: '1',
+        expectedRecipient: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+      });
+      expect(response.result.amount.symbol).toBe('ETH');
+    });
+
+    it('should answer a refused preflight with ok true', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'preflight',
+        yieldId: 'ethereum-eth-lido-staking',
+        detectedType: 'STAKE',
+        chainId: '42161',
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.allowed).toBe(false);
+      expect(response.result.reasonCode).toBe('CHAIN_ID_MISMATCH');
+    });
+
+    it('should require a detectedType', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'preflight',
+        yieldId: 'ethereum-eth-lido-staking',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('MISSING_REQUIRED_FIELD');
+    });
+
+    it('should reject detectedType on other operations', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'isSupported',
+        yieldId: 'ethereum-eth-lido-staking',
+        detectedType: 'STAKE',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.details).toEqual({ field: 'detectedType' });
+    });
+  });
+
+  describe('validateFlow operation', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const validLidoStakeTx = {
+      to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+      from: userAddress,
+      value: '0xde0b6b3a7640000',
+      data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+      chainId: 1,
+    };
+    const validStep = { unsignedTransaction: JSON.stringify(validLidoStakeTx) };
+
+    it('should validate each step of the flow', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateFlow',
+        yieldId: 'ethereum-eth-lido-staking',
+        userAddress,
+        transactions: [validStep],
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.isValid).toBe(true);
+      expect(response.result.steps).toHaveLength(1);
+      expect(response.result.steps[0].detectedType).toBe('STAKE');
+    });
+
+    it('should report the failing step', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateFlow',
+        yieldId: 'ethereum-eth-lido-staking',
+        userAddress,
+        transactions: [validStep, { unsignedTransaction: '{ invalid json }' }],
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.isValid).toBe(false);
+      expect(response.result.reason).toBe('FLOW_STEP_INVALID');
+      expect(response.result.reasonCode).toBe('FLOW_STEP_INVALID');
+      expect(response.result.details.step).toBe(1);
+    });
+
+    it('should reject per-step yieldId', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateFlow',
+        yieldId: 'ethereum-eth-lido-staking',
+        transactions: [{ ...validStep, yieldId: 'ethereum-eth-lido-staking' }],
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+    });
+
+    it('should require yieldId', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validateFlow',
+        transactions: [validStep],
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('MISSING_REQUIRED_FIELD');
+    });
+  });
+
+  describe('simulation', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const request = {
+      apiVersion: '1.0',
+      operation: 'validate',
+      yieldId: 'ethereum-eth-lido-staking',
+      userAddress,
+      unsignedTransaction: JSON.stringify({
+        to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+        from: userAddress,
+        value: '0xde0b6b3a7640000',
+        data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+        chainId: 1,
+      }),
+    };
+    const callAsync = async (req: object) =>
+      JSON.parse(await handleJsonRequestAsync(JSON.stringify(req)));
+
+    const originalFetch = global.fetch;
+    afterAll(() => {
+      global.fetch = originalFetch;
+    });
+
+    const rpcResponse = (result: string) => ({
+      ok: true,
+      status: 200,
+      json: () => Promise.resolve({ jsonrpc: '2.0', id: 1, result }),
+    });
+    const pausedSelector = '0x5c975abb'; // paused()
+    // Answers eth_getCode with code and paused() with false, and every
+    // other call as global.fetch did before
+    const withContractCode = () => {
+      const answer = global.fetch;
+      global.fetch = ((url: string, init: RequestInit) => {
+        const { method, params } = JSON.parse(init.body as string);
+        if (method === 'eth_getCode') {
+          return Promise.resolve(rpcResponse('0x6080'));
+        }
+        return method === 'eth_call' && params[0].data === pausedSelector
+          ? Promise.resolve(rpcResponse('0x' + '0'.repeat(64)))
+          : answer(url, init);
+      }) as typeof fetch;
+    };
+
+    it('should attach the simulation to a validate result', async () => {
+      global.fetch = jest.fn().mockResolvedValue({
+        ok: true,
+        status: 200,
+        json: () => Promise.resolve({ jsonrpc: '2.0', id: 1, result: '0x' }),
+      }) as unknown as typeof fetch;
+      withContractCode();
+
+      const response = await callAsync({
+        ...request,
+        simulate: true,
+        rpcUrl: 'https://eth.example.com',
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.isValid).toBe(true);
+      expect(response.result.simulation).toEqual({
+        success: true,
+        returnData: '0x',
+      });
+    });
+
+    it('should answer requests without simulate like handleJsonRequest', async () => {
+      const response = await callAsync(request);
+
+      expect(response).toEqual(call(request));
+      expect(response.result.simulation).toBeUndefined();
+    });
+
+    it('should require rpcUrl', async () => {
+      const response = await callAsync({ ...request, simulate: true });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('MISSING_REQUIRED_FIELD');
+    });
+
+    it('should only accept http(s) RPC URLs', async () => {
+      const response = await callAsync({
+        ...request,
+        simulate: true,
+        rpcUrl: 'file:///etc/passwd',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+    });
+
+    it('should only simulate validate requests', async () => {
+      const response = await callAsync({
+        apiVersion: '1.0',
+        operation: 'decode',
+        unsignedTransaction: request.unsignedTransaction,
+        simulate: true,
+        rpcUrl: 'https://eth.example.com',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+    });
+
+    describe('blockNumber', () => {
+      it('should read state at the requested block', async () => {
+        global.fetch = jest
+          .fn()
+          .mockResolvedValue(rpcResponse('0x6080')) as unknown as typeof fetch;
+        const response = await callAsync({
+          ...request,
+          simulate: true,
+          rpcUrl: 'https://eth.example.com',
+          blockNumber: 20000000,
+        });
+
+        expect(response.ok).toBe(true);
+        expect(response.result.blockNumber).toBe(20000000);
+        const calls = (global.fetch as unknown as jest.Mock).mock.calls;
+        for (const [, init] of calls) {
+          const { method, params } = JSON.parse(init.body);
+          expect([method, params[params.length - 1]]).toEqual([
+            method,
+            '0x1312d00',
+          ]);
+        }
+      });
+
+      it('should require rpcUrl', async () => {
+        const response = await callAsync({ ...request, blockNumber: 1 });
+
+        expect(response.ok).toBe(false);
+        expect(response.error.code).toBe('MISSING_REQUIRED_FIELD');
+      });
+
+      it('should only accept blockNumber on validate requests', async () => {
+        const response = await callAsync({
+          apiVersion: '1.0',
+          operation: 'decode',
+          unsignedTransaction: request.unsignedTransaction,
+          rpcUrl: 'https://eth.example.com',
+          blockNumber: 1,
+        });
+
+        expect(response.ok).toBe(false);
+        expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+      });
+    });
+
+    describe('checkNonce', () => {
+      const nonceRequest = {
+        ...request,
+        unsignedTransaction: JSON.stringify({
+          ...JSON.parse(request.unsignedTransaction),
+          nonce: 3,
+        }),
+        checkNonce: true,
+        rpcUrl: 'https://eth.example.com',
+      };
+      const respondWithNonce = (result: string) => {
+        global.fetch = jest.fn().mockResolvedValue({
+          ok: true,
+          status: 200,
+          json: () => Promise.resolve({ jsonrpc: '2.0', id: 1, result }),
+        }) as unknown as typeof fetch;
+        withContractCode();
+      };
+
+      it('should warn when the nonce is below the account nonce', async () => {
+        respondWithNonce('0x5');
+        const response = await callAsync(nonceRequest);
+
+        expect(response.ok).toBe(true);
+        expect(response.result.isValid).toBe(true);
+        expect(response.result.warnings).toEqual([
+          expect.objectContaining({ code: 'NONCE_TOO_LOW' }),
+        ]);
+      });
+
+      it('should warn when the nonce skips ahead', async () => {
+        respondWithNonce('0x1');
+        const response = await callAsync(nonceRequest);
+
+        expect(response.result.warnings).toEqual([
+          expect.objectContaining({ code: 'NONCE_GAP' }),
+        ]);
+      });
+
+      it('should fail with NONCE_CHECK_FAILED when the node errors', async () => {
+        global.fetch = jest
+          .fn()
+          .mockRejectedValue(
+            new Error('connect ECONNREFUSED'),
+          ) as unknown as typeof fetch;
+        const response = await callAsync(nonceRequest);
+
+        expect(response.ok).toBe(true);
+        expect(response.result.isValid).toBe(false);
+        expect(response.result.reasonCode).toBe('NONCE_CHECK_FAILED');
+        expect(response.result.details.error).toBe('connect ECONNREFUSED');
+      });
+
+      it('should require userAddress', async () => {
+        const response = await callAsync({
+          ...nonceRequest,
+          userAddress: undefined,
+        });
+
+        expect(response.ok).toBe(false);
+        expect(response.error.code).toBe('MISSING_REQUIRED_FIELD');
+        expect(response.error.details).toEqual({ field: 'userAddress' });
+      });
+
+      it('should not check nonces from the synchronous handler', () => {
+        const response = call(nonceRequest);
+
+        expect(response.ok).toBe(false);
+        expect(response.error.code).toBe('SIMULATION_UNAVAILABLE');
+      });
+    });
+
+    describe('expectedRecipientEns', () => {
+      const stETH = '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84';
+      const ensRequest = {
+        ...request,
+        expectedRecipientEns: 'lido.eth',
+        rpcUrl: 'https://eth.example.com',
+      };
+      const word = (address: string) =>
+        '0x' + address.slice(2).toLowerCase().padStart(64, '0');
+      // The registry names a resolver, which returns address
+      const resolveTo = (address: string) => {
+        const resolver = word('0x231b0Ee14048e9dCcD1d247744d114a4EB5E8E63');
+        const fetchMock = jest.fn();
+        for (const result of [resolver, word(address)]) {
+          fetchMock.mockResolvedValueOnce({
+            ok: true,
+            status: 200,
+            json: () => Promise.resolve({ jsonrpc: '2.0', id: 1, result }),
+          });
+        }
+        global.fetch = fetchMock as unknown as typeof fetch;
+        withContractCode();
+      };
+
+      it('should accept a name that resolves to the recipient', async () => {
+        resolveTo(stETH);
+        const response = await callAsync(ensRequest);
+
+        expect(response.result.isValid).toBe(true);
+        expect(response.result.resolvedRecipient).toEqual({
+          name: 'lido.eth',
+          address: ethers.getAddress(stETH),
+        });
+      });
+
+      it('should reject a name that resolves elsewhere', async () => {
+        resolveTo(referralAddress);
+        const response = await callAsync(ensRequest);
+
+        expect(response.result.isValid).toBe(false);
+        expect(response.result.reasonCode).toBe('RECIPIENT_ENS_MISMATCH');
+      });
+
+      it('should resolve a transaction sent to a name', async () => {
+        resolveTo(stETH);
+        const response = await callAsync({
+          ...request,
+          rpcUrl: 'https://eth.example.com',
+          unsignedTransaction: JSON.stringify({
+            ...JSON.parse(request.unsignedTransaction),
+            to: 'lido.eth',
+          }),
+        });
+
+        expect(response.result.isValid).toBe(true);
+        expect(response.result.detectedType).toBe('STAKE');
+        expect(response.result.resolvedRecipient.name).toBe('lido.eth');
+      });
+
+      it('should fail with ENS_RESOLUTION_FAILED when the node errors', async () => {
+        global.fetch = jest
+          .fn()
+          .mockRejectedValue(
+            new Error('connect ECONNREFUSED'),
+          ) as unknown as typeof fetch;
+        const response = await callAsync(ensRequest);
+
+        expect(response.ok).toBe(true);
+        expect(response.result.isValid).toBe(false);
+        expect(response.result.reasonCode).toBe('ENS_RESOLUTION_FAILED');
+      });
+
+      it('should require rpcUrl', async () => {
+        const response = await callAsync({ ...ensRequest, rpcUrl: undefined });
+
+        expect(response.ok).toBe(false);
+        expect(response.error.code).toBe('MISSING_REQUIRED_FIELD');
+        expect(response.error.details).toEqual({ field: 'rpcUrl' });
+      });
+
+      it('should not resolve names from the synchronous handler', () => {
+        const response = call(ensRequest);
+
+        expect(response.ok).toBe(false);
+        expect(response.error.code).toBe('SIMULATION_UNAVAILABLE');
+      });
+    });
+
+    describe('contract code', () => {
+      const codeRequest = { ...request, rpcUrl: 'https://eth.example.com' };
+
+      it('should accept calldata sent to a contract', async () => {
+        global.fetch = jest
+          .fn()
+          .mockResolvedValue(rpcResponse('0x6080')) as unknown as typeof fetch;
+        const response = await callAsync(codeRequest);
+
+        expect(response.result.isValid).toBe(true);
+        const [, init] = (global.fetch as unknown as jest.Mock).mock.calls[0];
+        expect(JSON.parse(init.body)).toMatchObject({
+          method: 'eth_getCode',
+          params: ['0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84', 'latest'],
+        });
+      });
+
+      it('should reject calldata sent to an account without code', async () => {
+        global.fetch = jest
+          .fn()
+          .mockResolvedValue(rpcResponse('0x')) as unknown as typeof fetch;
+        const response = await callAsync(codeRequest);
+
+        expect(response.ok).toBe(true);
+        expect(response.result.isValid).toBe(false);
+        expect(response.result.reasonCode).toBe('RECIPIENT_NOT_A_CONTRACT');
+        expect(response.result.details.actual).toBe(
+          '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+        );
+      });
+
+      it('should warn about a contract that reports paused', async () => {
+        global.fetch = jest.fn(async (_url: string, init: RequestInit) =>
+          rpcResponse(
+            JSON.parse(init.body as string).method === 'eth_getCode'
+              ? '0x6080'
+              : '0x' + '1'.padStart(64, '0'),
+          ),
+        ) as unknown as typeof fetch;
+        const response = await callAsync(codeRequest);
+
+        expect(response.result.isValid).toBe(true);
+        expect(response.result.warnings).toEqual([
+          expect.objectContaining({
+            code: 'CONTRACT_PAUSED',
+            details: { contract: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84' },
+          }),
+        ]);
+      });
+
+      it('should fail with CODE_CHECK_FAILED when the node errors', async () => {
+        global.fetch = jest
+          .fn()
+          .mockRejectedValue(
+            new Error('connect ECONNREFUSED'),
+          ) as unknown as typeof fetch;
+        const response = await callAsync(codeRequest);
+
+        expect(response.result.isValid).toBe(false);
+        expect(response.result.reasonCode).toBe('CODE_CHECK_FAILED');
+      });
+
+      it('should not check code from the synchronous handler', () => {
+        const response = call(codeRequest);
+
+        expect(response.ok).toBe(false);
+        expect(response.error.code).toBe('SIMULATION_UNAVAILABLE');
+      });
+
+      describe('contract age', () => {
+        const ageRequest = {
+          ...codeRequest,
+          policy: { minContractAgeSeconds: 86400 },
+        };
+        const now = Math.floor(Date.now() / 1000);
+        // A chain of 1000 blocks whose contract appeared in block 900
+        const chain = (archive: boolean) =>
+          jest.fn(async (_url: string, init: RequestInit) => {
+            const { method, params } = JSON.parse(init.body as string);
+            if (method === 'eth_blockNumber') return rpcResponse('0x3e8');
+            if (method === 'eth_getCode') {
+              const block = params[1] === 'latest' ? 1000 : Number(params[1]);
+              if (!archive && block < 1000) {
+                return {
+                  ok: true,
+                  status: 200,
+                  json: () =>
+                    Promise.resolve({
+                      jsonrpc: '2.0',
+                      id: 1,
+                      error: { code: -32000, message: 'missing trie node' },
+                    }),
+                };
+              }
+              return rpcResponse(block >= 900 ? '0x6080' : '0x');
+            }
+            if (method === 'eth_getBlockByNumber') {
+              return {
+                ok: true,
+                status: 200,
+                json: () =>
+                  Promise.resolve({
+                    jsonrpc: '2.0',
+                    id: 1,
+                    result: { timestamp: ethers.toQuantity(now - 60) },
+                  }),
+              };
+            }
+            return rpcResponse('0x' + '0'.padStart(64, '0'));
+          }) as unknown as typeof fetch;
+
+        it('should warn about a contract deployed recently', async () => {
+          global.fetch = chain(true);
+          const response = await callAsync(ageRequest);
+
+          expect(response.result.isValid).toBe(true);
+          expect(response.result.contractDeployments).toEqual({
+            '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84': {
+              block: 900,
+              timestamp: now - 60,
+            },
+          });
+          expect(
+            response.result.warnings.map((w: { code: string }) => w.code),
+          ).toEqual(['RECENTLY_DEPLOYED_CONTRACT']);
+        });
+
+        it('should validate without the age when the node has no history', async () => {
+          global.fetch = chain(false);
+          const response = await callAsync(ageRequest);
+
+          expect(response.result.isValid).toBe(true);
+          expect(response.result.contractDeployments).toEqual({});
+          expect(response.result.warnings).toEqual([]);
+        });
+
+        it('should not look deployments up without the policy', async () => {
+          global.fetch = chain(true);
+          await callAsync(codeRequest);
+
+          const methods = (
+            global.fetch as unknown as jest.Mock
+          ).mock.calls.map(([, init]) => JSON.parse(init.body).method);
+          expect(methods).not.toContain('eth_blockNumber');
+        });
+      });
+    });
+
+    describe('staked balance', () => {
+      const unstakeRequest = {
+        ...request,
+        rpcUrl: 'https://eth.example.com',
+        unsignedTransaction: JSON.stringify({
+          to: '0x889edC2eDab5f40e902b864aD4d7AdE8E412F9B1',
+          from: userAddress,
+          value: '0x0',
+          data: new ethers.Interface([
+            'function requestWithdrawals(uint256[] _amounts, address _owner) returns (uint256[])',
+          ]).encodeFunctionData('requestWithdrawals', [
+            [10n ** 18n],
+            userAddress,
+          ]),
+          chainId: 1,
+        }),
+      };
+      // Answers the balance call with balance, returning its mock
+      const withBalance = (balance: bigint) => {
+        const fetchImpl = jest
+          .fn()
+          .mockResolvedValue(
+            rpcResponse('0x' + balance.toString(16).padStart(64, '0')),
+          );
+        global.fetch = fetchImpl as unknown as typeof fetch;
+        withContractCode();
+        return fetchImpl;
+      };
+
+      it('should read the balance an unstake draws on', async () => {
+        const fetchImpl = withBalance(2n * 10n ** 18n);
+        const response = await callAsync(unstakeRequest);
+
+        expect(response.result.isValid).toBe(true);
+        const [, init] = fetchImpl.mock.calls[0];
+        const { method, params } = JSON.parse(init.body);
+        expect(method).toBe('eth_call');
+        expect(params[0].to).toBe(
+          '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+        );
+      });
+
+      it('should reject an unstake of more than the balance', async () => {
+        withBalance(10n ** 17n);
+        const response = await callAsync(unstakeRequest);
+
+        expect(response.result.isValid).toBe(false);
+        expect(response.result.reasonCode).toBe('UNSTAKE_EXCEEDS_BALANCE');
+        expect(response.result.details).toMatchObject({
+          balance: (10n ** 17n).toString(),
+          amount: (10n ** 18n).toString(),
+        });
+      });
+
+      it('should fail with BALANCE_CHECK_FAILED when the call reverts', async () => {
+        global.fetch = jest.fn().mockResolvedValue({
+          ok: true,
+          status: 200,
+          json: () =>
+            Promise.resolve({
+              jsonrpc: '2.0',
+              id: 1,
+              error: { code: 3, message: 'execution reverted', data: '0x' },
+            }),
+        }) as unknown as typeof fetch;
+        withContractCode();
+        const response = await callAsync(unstakeRequest);
+
+        expect(response.result.isValid).toBe(false);
+        expect(response.result.reasonCode).toBe('BALANCE_CHECK_FAILED');
+      });
+
+      it('should not read balances from the synchronous handler', () => {
+        const response = call({ ...unstakeRequest, rpcUrl: undefined });
+        expect(response.result.isValid).toBe(true);
+
+        const withRpcUrl = call(unstakeRequest);
+        expect(withRpcUrl.ok).toBe(false);
+        expect(withRpcUrl.error.code).toBe('SIMULATION_UNAVAILABLE');
+      });
+    });
+
+    describe('claimed positions', () => {
+      const claimRequest = {
+        ...request,
+        rpcUrl: 'https://eth.example.com',
+        unsignedTransaction: JSON.stringify({
+          to: '0x889edC2eDab5f40e902b864aD4d7AdE8E412F9B1',
+          from: userAddress,
+          value: '0x0',
+          data:
+            '0xf8444436' + // claimWithdrawal(123)
+            '000000000000000000000000000000000000000000000000000000000000007b',
+          chainId: 1,
+        }),
+      };
+      // Answers the ownerOf call with owner, returning its mock
+      const withOwner = (owner: string) => {
+        const fetchImpl = jest
+          .fn()
+          .mockResolvedValue(
+            rpcResponse('0x' + owner.slice(2).toLowerCase().padStart(64, '0')),
+          );
+        global.fetch = fetchImpl as unknown as typeof fetch;
+        withContractCode();
+        return fetchImpl;
+      };
+
+      it('should read the owner of a claimed position', async () => {
+        const fetchImpl = withOwner(userAddress);
+        const response = await callAsync(claimRequest);
+
+        expect(response.result.isValid).toBe(true);
+        expect(response.result.positionOwnershipVerified).toBe(true);
+        expect(response.result.claimedPositions).toEqual({
+          contract: '0x889edC2eDab5f40e902b864aD4d7AdE8E412F9B1',
+          positionIds: ['123'],
+        });
+        const [, init] = fetchImpl.mock.calls[0];
+        const { method, params } = JSON.parse(init.body);
+        expect(method).toBe('eth_call');
+        expect(params[0].data).toBe(
+          '0x6352211e' +
+            '000000000000000000000000000000000000000000000000000000000000007b',
+        );
+      });
+
+      it('should reject a claim of a position owned by someone else', async () => {
+        withOwner('0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be');
+        const response = await callAsync(claimRequest);
+
+        expect(response.result.isValid).toBe(false);
+        expect(response.result.reasonCode).toBe('CLAIM_POSITION_NOT_OWNED');
+        expect(response.result.details).toMatchObject({ positionId: '123' });
+      });
+
+      it('should fail with POSITION_CHECK_FAILED when the RPC fails', async () => {
+        global.fetch = jest
+          .fn()
+          .mockRejectedValue(new Error('connect ECONNREFUSED'));
+        withContractCode();
+        const response = await callAsync(claimRequest);
+
+        expect(response.result.isValid).toBe(false);
+        expect(response.result.reasonCode).toBe('POSITION_CHECK_FAILED');
+      });
+
+      it('should not read owners from the synchronous handler', () => {
+        const response = call({ ...claimRequest, rpcUrl: undefined });
+        expect(response.result.isValid).toBe(true);
+        expect(response.result.positionOwnershipVerified).toBe(false);
+
+        const withRpcUrl = call(claimRequest);
+        expect(withRpcUrl.ok).toBe(false);
+        expect(withRpcUrl.error.code).toBe('SIMULATION_UNAVAILABLE');
+      });
+    });
+
+    it('should check expectedNonce without an rpcUrl', () => {
+      const response = call({ ...request, expectedNonce: 1 });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.isValid).toBe(false);
+      expect(response.result.reasonCode).toBe('NONCE_MISMATCH');
+    });
+
+    it('should not simulate from the synchronous handler', () => {
+      const response = call({
+        ...request,
+        simulate: true,
+        rpcUrl: 'https://eth.example.com',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('SIMULATION_UNAVAILABLE');
+    });
+  });
+
+  describe('security: schema validation', () => {
+    it('should reject invalid JSON', () => {
+      const response = call('{ invalid json }');
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('PARSE_ERROR');
+    });
+
+    it('should reject empty and whitespace-only input', () => {
+      for (const input of ['', ' \n\t ']) {
+        const response = call(input);
+
+        expect(response.ok).toBe(false);
+        expect(response.error.code).toBe('EMPTY_INPUT');
+      }
+    });
+
+    it('should reject unknown apiVersion with the supported versions', () => {
+      const response = call({
+        apiVersion: '2.0',
+        operation: 'getSupportedYieldIds',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('UNSUPPORTED_API_VERSION');
+      expect(response.error.message).toContain('1.0');
+      expect(response.error.details).toEqual({
+        requested: '2.0',
+        supportedApiVersions: ['1.0'],
+      });
+    });
+
+    it('should check apiVersion before the rest of the schema', () => {
+      const response = call({
+        apiVersion: '2.0',
+        operation: 'someFutureOperation',
+      });
+
+      expect(response.error.code).toBe('UNSUPPORTED_API_VERSION');
+    });
+
+    it('should reject malformed apiVersion', () => {
+      const response = call({
+        apiVersion: 'latest',
+        operation: 'getSupportedYieldIds',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+    });
+
+    it('should warn about a deprecated apiVersion', () => {
+      DEPRECATED_API_VERSIONS.push('1.0');
+      try {
+        const response = call({
+          apiVersion: '1.0',
+          operation: 'getSupportedYieldIds',
+        });
+
+        expect(response.ok).toBe(true);
+        expect(response.meta.warnings).toEqual([
+          expect.objectContaining({ code: 'API_VERSION_DEPRECATED' }),
+        ]);
+      } finally {
+        DEPRECATED_API_VERSIONS.pop();
+      }
+    });
+
+    it('should not warn about a current apiVersion', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'getSupportedYieldIds',
+      });
+
+      expect(response.meta.warnings).toBeUndefined();
+    });
+
+    it('should reject unknown operation', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'deleteEverything',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+    });
+
+    it('should reject unknown properties (no injection)', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'getSupportedYieldIds',
+        __proto__: { admin: true }, // Attempted prototype pollution
+        maliciousField: 'value',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+    });
+
+    it('should reject unknown policy fields', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: '{}',
+        policy: { trustedContracts: ['0x1'] },
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+    });
+
+    it('should name the offending field', () => {
+      const cases = [
+        {
+          request: { operation: 'getSupportedYieldIds' },
+          field: 'apiVersion',
+          message: "Missing required field 'apiVersion'",
+        },
+        {
+          request: {
+            apiVersion: '1.0',
+            operation: 'isSupported',
+            yieldId: 42,
+          },
+          field: 'yieldId',
+          message: "Field 'yieldId' must be string",
+        },
+        {
+          request: {
+            apiVersion: '1.0',
+            operation: 'getSupportedYieldIds',
+            maliciousField: 'value',
+          },
+          field: 'maliciousField',
+          message: "Unknown field 'maliciousField'",
+        },
+        {
+          request: {
+            apiVersion: '1.0',
+            operation: 'validateBatch',
+            transactions: [{ unsignedTransaction: '{}' }],
+          },
+          field: 'transactions[0].yieldId',
+          message: "Missing required field 'transactions[0].yieldId'",
+        },
+      ];
+
+      for (const { request, field, message } of cases) {
+        const response = call(request);
+
+        expect(response.ok).toBe(false);
+        expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+        expect(response.error.message).toBe(message);
+        expect(response.error.details.field).toBe(field);
+      }
+    });
+
+    it('should name the missing operation field', () => {
+      const response = call({ apiVersion: '1.0', operation: 'validate' });
+
+      expect(response.error.code).toBe('MISSING_REQUIRED_FIELD');
+      expect(response.error.details).toEqual({ field: 'yieldId' });
+    });
+
+    it('should answer a non-object request with a structured error', () => {
+      for (const input of ['[]', '"validate"', 'null', '']) {
+        const response = call(input);
+
+        expect(response.ok).toBe(false);
+        expect(typeof response.error.code).toBe('string');
+        expect(typeof response.error.message).toBe('string');
+      }
+    });
+
+    it('should reject oversized yieldId', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'isSupported',
+        yieldId: 'x'.repeat(300), // Exceeds 256 char limit
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+    });
+  });
+
+  describe('security: tampering detection', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+
+    const validLidoStakeTx = {
+      to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+      from: userAddress,
+      value: '0xde0b6b3a7640000',
+      data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+      chainId: 1,
+    };
+
+    it('should reject transaction with appended data', () => {
+      const tamperedTx = {
+        ...validLidoStakeTx,
+        data: validLidoStakeTx.data + 'deadbeef',
+      };
+
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify(tamperedTx),
+        userAddress: userAddress,
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.isValid).toBe(false);
+    });
 
     it('should reject transaction with modified function selector', () => {
       const tamperedTx = {
@@ -417,9 +3525,453 @@ describe('handleJsonRequest', () => {
       const response = JSON.parse(handleJsonRequest(hugeInput));
 
       expect(response.ok).toBe(false);
-      expect(response.error.code).toBe('SCHEMA_VALIDATION_ERROR');
+      expect(response.error.code).toBe('INPUT_TOO_LARGE');
       expect(response.error.message).toContain('exceeds maximum size');
     });
+
+    it('should take the limit from maxInputSize, counted in bytes', () => {
+      const request = (pad: string) =>
+        JSON.stringify({
+          apiVersion: '1.0',
+          operation: 'getSupportedYieldIds',
+          requestId: pad,
+        });
+      // 200 characters, but 400 bytes of UTF-8
+      const input = request('é'.repeat(200));
+      const maxInputSize = Buffer.byteLength(input) - 1;
+
+      const rejected = JSON.parse(handleJsonRequest(input, { maxInputSize }));
+      expect(rejected.error).toEqual({
+        code: 'INPUT_TOO_LARGE',
+        message: `Input exceeds maximum size of ${maxInputSize} bytes`,
+      });
+      const accepted = handleJsonRequest(request('e'.repeat(200)), {
+        maxInputSize,
+      });
+      expect(JSON.parse(accepted).ok).toBe(true);
+    });
+  });
+
+  describe('validation cache', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const request = {
+      apiVersion: '1.0',
+      operation: 'validate',
+      yieldId: 'ethereum-eth-lido-staking',
+      unsignedTransaction: JSON.stringify({
+        to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84', // Lido stETH
+        from: userAddress,
+        value: '0xde0b6b3a7640000',
+        data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+        chainId: 1,
+      }),
+      userAddress,
+    };
+    const callCached = (req: object, validationCache: ValidationCache) =>
+      JSON.parse(handleJsonRequest(JSON.stringify(req), { validationCache }));
+
+    it('should answer a repeated request from the cache', () => {
+      const validationCache = new ValidationCache({
+        maxEntries: 10,
+        ttlMs: 60_000,
+      });
+
+      const first = callCached({ ...request, requestId: '1' }, validationCache);
+      const second = callCached(
+        { ...request, requestId: '2' },
+        validationCache,
+      );
+
+      expect(first.result).not.toHaveProperty('cached');
+      expect(second.requestId).toBe('2');
+      expect(second.result).toEqual({ ...first.result, cached: true });
+      expect(second.meta.requestHash).not.toBe(first.meta.requestHash);
+    });
+
+    it('should validate again when any other field differs', () => {
+      const validationCache = new ValidationCache({
+        maxEntries: 10,
+        ttlMs: 60_000,
+      });
+
+      callCached(request, validationCache);
+      const strict = callCached({ ...request, strict: true }, validationCache);
+
+      expect(strict.result).not.toHaveProperty('cached');
+      expect(validationCache.size).toBe(2);
+    });
+
+    it('should not cache requests with includeTiming', () => {
+      const validationCache = new ValidationCache({
+        maxEntries: 10,
+        ttlMs: 60_000,
+      });
+      const timed = { ...request, includeTiming: true };
+
+      callCached(timed, validationCache);
+      const second = callCached(timed, validationCache);
+
+      expect(second.result).not.toHaveProperty('cached');
+      expect(second.result.timing).toBeDefined();
+      expect(validationCache.size).toBe(0);
+    });
+  });
+
+  describe('audit records', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const unsignedTransaction = JSON.stringify({
+      to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84', // Lido stETH
+      from: userAddress,
+      value: '0xde0b6b3a7640000',
+      data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+      chainId: 1,
+    });
+    const request = {
+      apiVersion: '1.0',
+      operation: 'validate',
+      requestId: 'audit-1',
+      yieldId: 'ethereum-eth-lido-staking',
+      unsignedTransaction,
+      userAddress,
+    };
+    const sha256 = (data: string) =>
+      createHash('sha256').update(data).digest('hex');
+
+    it('should add the record of the decision to meta with includeAudit', () => {
+      const response = call({ ...request, includeAudit: true });
+      const { recordHash, ...fields } = response.meta.audit;
+
+      expect(Object.keys(response.meta.audit)).toEqual([
+        'timestamp',
+        'requestId',
+        'yieldId',
+        'userAddress',
+        'transactionHash',
+        'verdict',
+        'registryHash',
+        'version',
+        'recordHash',
+      ]);
+      expect(fields).toEqual({
+        timestamp: expect.any(String),
+        requestId: 'audit-1',
+        yieldId: 'ethereum-eth-lido-staking',
+        userAddress,
+        transactionHash: sha256(unsignedTransaction),
+        verdict: 'valid',
+        registryHash: expect.any(String),
+        version: 'unknown',
+      });
+      expect(new Date(fields.timestamp).toISOString()).toBe(fields.timestamp);
+      expect(fields.registryHash).toMatch(/^[0-9a-f]{64}$/);
+      expect(recordHash).toBe(sha256(JSON.stringify(fields)));
+      expect(call(request).meta).not.toHaveProperty('audit');
+    });
+
+    it('should record the reason code of a rejection', () => {
+      const response = call({
+        ...request,
+        includeAudit: true,
+        userAddress: '0x0000000000000000000000000000000000000bad',
+      });
+
+      expect(response.meta.audit).toMatchObject({
+        verdict: 'invalid',
+        reasonCode: response.result.reasonCode,
+      });
+    });
+
+    it('should hand every validate record to auditSink', () => {
+      const records: unknown[] = [];
+      const auditSink = (record: unknown) => records.push(record);
+      const registryOverride = parseRegistryOverride(
+        JSON.stringify({ vaults: [] }),
+      );
+
+      const response = JSON.parse(
+        handleJsonRequest(JSON.stringify(request), {
+          auditSink,
+          registryOverride,
+        }),
+      );
+      handleJsonRequest(
+        JSON.stringify({ apiVersion: '1.0', operation: 'getVersion' }),
+        { auditSink },
+      );
+      handleJsonRequest(
+        JSON.stringify({ ...request, unsignedTransaction: undefined }),
+        { auditSink },
+      );
+
+      expect(response.meta).not.toHaveProperty('audit');
+      expect(records).toHaveLength(1);
+      expect(records[0]).toMatchObject({
+        requestId: 'audit-1',
+        verdict: 'valid',
+      });
+      expect(
+        (records[0] as { registryOverrideHash: string }).registryOverrideHash,
+      ).toMatch(/^[0-9a-f]{64}$/);
+    });
+  });
+
+  describe('profiles', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const stETH = '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84';
+    const request = {
+      apiVersion: '1.0',
+      operation: 'validate',
+      yieldId: 'ethereum-eth-lido-staking',
+      unsignedTransaction: JSON.stringify({
+        to: stETH,
+        from: userAddress,
+        value: '0xde0b6b3a7640000',
+        data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+        chainId: 1,
+      }),
+      userAddress,
+    };
+    const profile = parseProfile(
+      JSON.stringify({
+        name: 'payments',
+        locale: 'de',
+        policy: { blockedContracts: [stETH], maxCalldataBytes: 4096 },
+        responseFields: ['reasonCode', 'locale'],
+      }),
+    );
+    const callWith = (req: object) =>
+      JSON.parse(handleJsonRequest(JSON.stringify(req), { profile }));
+
+    it('should fill in the fields a request leaves out', () => {
+      const { result } = callWith(request);
+
+      // Messages fall back to English, but the locale was requested
+      expect(result).toEqual({
+        isValid: false,
+        reasonCode: 'CONTRACT_BLOCKED',
+        locale: 'en',
+      });
+    });
+
+    it("should let a request's own fields replace the profile's", () => {
+      const { result } = callWith({
+        ...request,
+        policy: { blockedContracts: [] },
+        responseFields: ['detectedType'],
+      });
+
+      expect(result).toEqual({ isValid: true, detectedType: 'STAKE' });
+    });
+
+    it('should only apply fields the operation takes', () => {
+      const response = callWith({
+        apiVersion: '1.0',
+        operation: 'explain',
+        yieldId: request.yieldId,
+        unsignedTransaction: request.unsignedTransaction,
+        userAddress,
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.result.reasonCode).toBe('CONTRACT_BLOCKED');
+      expect(response.result).toHaveProperty('trace');
+    });
+
+    it('should report the active profile from getVersion', () => {
+      const { result } = callWith({
+        apiVersion: '1.0',
+        operation: 'getVersion',
+      });
+
+      expect(result.profile).toEqual({
+        name: 'payments',
+        hash: createHash('sha256')
+          .update(JSON.stringify(profile))
+          .digest('hex'),
+      });
+      expect(
+        call({ apiVersion: '1.0', operation: 'getVersion' }).result,
+      ).not.toHaveProperty('profile');
+    });
+
+    it('should reject profiles with unknown fields', () => {
+      expect(() => parseProfile('{"riskThreshold":50}')).toThrow(
+        "Invalid profile: Unknown field 'riskThreshold'",
+      );
+      expect(() => parseProfile('{"responseFields":["nonsense"]}')).toThrow(
+        "Unknown validate result field 'nonsense'",
+      );
+    });
+  });
+
+  describe('requestId correlation', () => {
+    it('should echo requestId on success responses', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'getSupportedYieldIds',
+        requestId: 'req-42',
+      });
+
+      expect(response.ok).toBe(true);
+      expect(response.requestId).toBe('req-42');
+    });
+
+    it('should echo requestId on error responses', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'isSupported',
+        requestId: 'req-43',
+      });
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('MISSING_REQUIRED_FIELD');
+      expect(response.requestId).toBe('req-43');
+    });
+
+    it('should omit requestId when none was supplied', () => {
+      const response = call({
+        apiVersion: '1.0',
+        operation: 'getSupportedYieldIds',
+      });
+
+      expect(response).not.toHaveProperty('requestId');
+    });
+
+    it('should require requestId when requested by the caller', () => {
+      const response = JSON.parse(
+        handleJsonRequest(
+          JSON.stringify({
+            apiVersion: '1.0',
+            operation: 'getSupportedYieldIds',
+          }),
+          { requireRequestId: true },
+        ),
+      );
+
+      expect(response.ok).toBe(false);
+      expect(response.error.code).toBe('MISSING_REQUEST_ID');
+    });
+  });
+
+  describe('logging', () => {
+    const entries: Record<string, unknown>[] = [];
+    const logger = createJsonLogger('debug', (line) =>
+      entries.push(JSON.parse(line)),
+    );
+    const callLogged = (req: object) =>
+      JSON.parse(handleJsonRequest(JSON.stringify(req), { logger }));
+
+    beforeEach(() => {
+      entries.length = 0;
+    });
+
+    it('should log the outcome of each request with its requestId', () => {
+      const response = callLogged({
+        apiVersion: '1.0',
+        operation: 'isSupported',
+        yieldId: 'ethereum-eth-lido-staking',
+        requestId: 'req-50',
+      });
+
+      expect(entries).toHaveLength(1);
+      expect(entries[0]).toMatchObject({
+        level: 'info',
+        msg: 'request handled',
+        requestId: 'req-50',
+        requestHash: response.meta.requestHash,
+        operation: 'isSupported',
+        yieldId: 'ethereum-eth-lido-staking',
+      });
+      expect(typeof entries[0].durationMs).toBe('number');
+      expect(typeof entries[0].time).toBe('string');
+    });
+
+    it('should log request errors as warnings', () => {
+      callLogged({ apiVersion: '1.0', operation: 'isSupported' });
+
+      expect(entries).toHaveLength(1);
+      expect(entries[0]).toMatchObject({
+        level: 'warn',
+        msg: 'request failed',
+        errorCode: 'MISSING_REQUIRED_FIELD',
+      });
+    });
+
+    it('should log the checks behind a validate result at debug level', () => {
+      callLogged({
+        apiVersion: '1.0',
+        operation: 'validate',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify({
+          to: '0x0000000000000000000000000000000000000bad',
+          from: '0x742d35cc6634c0532925a3b844bc9e7595f0beb8',
+          value: '0x0',
+          data: '0xdeadbeef',
+          chainId: 1,
+        }),
+        userAddress: '0x742d35cc6634c0532925a3b844bc9e7595f0beb8',
+      });
+
+      expect(entries.map((entry) => entry.msg)).toEqual([
+        'request handled',
+        'validation checks',
+      ]);
+      expect(entries[0]).toMatchObject({
+        isValid: false,
+        reasonCode: 'RECIPIENT_MISMATCH',
+      });
+      expect(entries[1].attempts).toHaveLength(3);
+      expect(entries[1]).not.toHaveProperty('unsignedTransaction');
+    });
+
+    it('should leave out entries below the logger level', () => {
+      const lines: string[] = [];
+      handleJsonRequest(
+        JSON.stringify({
+          apiVersion: '1.0',
+          operation: 'getSupportedYieldIds',
+        }),
+        { logger: createJsonLogger('warn', (line) => lines.push(line)) },
+      );
+
+      expect(lines).toEqual([]);
+    });
+
+    it('should log each contract override of a request', () => {
+      const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+      const referral = '371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+      const moved = '0x1111111111111111111111111111111111111111';
+      const response = callLogged({
+        apiVersion: '1.0',
+        operation: 'validate',
+        requestId: 'req-51',
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify({
+          to: moved,
+          from: userAddress,
+          value: '0xde0b6b3a7640000',
+          data: '0xa1903eab' + referral.padStart(64, '0'),
+          chainId: 1,
+        }),
+        userAddress,
+        contractOverrides: { 'ethereum-eth-lido-staking': moved },
+      });
+
+      expect(response.result).toMatchObject({
+        isValid: true,
+        expectedRecipient: moved,
+      });
+      expect(entries[0]).toMatchObject({
+        level: 'warn',
+        msg: 'contract override',
+        requestId: 'req-51',
+        yieldId: 'ethereum-eth-lido-staking',
+        address: moved,
+      });
+    });
   });
 
   describe('response integrity', () => {
@@ -446,4 +3998,77 @@ describe('handleJsonRequest', () => {
       expect(response1.meta.requestHash).not.toBe(response2.meta.requestHash);
     });
   });
+
+  describe('response stability', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const request = {
+      apiVersion: '1.0',
+      operation: 'validate',
+      yieldId: 'ethereum-eth-lido-staking',
+      unsignedTransaction: JSON.stringify({
+        to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+        from: userAddress,
+        value: '0xde0b6b3a7640000',
+        data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+        chainId: 1,
+      }),
+      userAddress,
+    };
+
+    it('should write keys in the order the response schema lists them', () => {
+      const { result: schemas } = call({
+        apiVersion: '1.0',
+        operation: 'getSchema',
+      });
+      const listed = Object.keys(
+        schemas.response.definitions.ValidateResult.properties,
+      );
+      const response = call({ ...request, requestId: 'a' });
+      const keys = Object.keys(response.result);
+
+      expect(Object.keys(response)).toEqual([
+        'ok',
+        'apiVersion',
+        'result',
+        'meta',
+        'requestId',
+      ]);
+      expect(Object.keys(response.meta)).toEqual(['requestHash', 'stableHash']);
+      expect(keys).toEqual(listed.filter((key) => keys.includes(key)));
+    });
+
+    it('should share a stableHash across runs and display changes', () => {
+      const plain = call(request);
+      const timed = call({ ...request, includeTiming: true });
+      const localized = call({ ...request, locale: 'de-DE' });
+
+      expect(plain.meta.stableHash).toMatch(/^[0-9a-f]{64}$/);
+      expect(timed.result.timing).toBeDefined();
+      expect(timed.meta.stableHash).toBe(plain.meta.stableHash);
+      expect(timed.meta.requestHash).not.toBe(plain.meta.requestHash);
+      // The locale is part of the result; its formatted amount is not
+      expect(localized.meta.stableHash).not.toBe(plain.meta.stableHash);
+    });
+
+    it('should change the stableHash with the verdict', () => {
+      const valid = call(request);
+      const invalid = call({
+        ...request,
+        userAddress: '0x0000000000000000000000000000000000000001',
+      });
+
+      expect(invalid.result.isValid).toBe(false);
+      expect(invalid.meta.stableHash).not.toBe(valid.meta.stableHash);
+    });
+
+    it('should hash errors by code and details', () => {
+      const empty = JSON.parse(handleJsonRequest(''));
+      const again = JSON.parse(handleJsonRequest('  '));
+
+      expect(empty.ok).toBe(false);
+      expect(empty.meta.stableHash).toBe(again.meta.stableHash);
+      expect(empty.meta.requestHash).not.toBe(again.meta.requestHash);
+    });
+  });
 });
@@ -1,27 +1,262 @@
-import Ajv from 'ajv';
+import Ajv, { type ErrorObject } from 'ajv';
 import { createHash } from 'crypto';
 import { Shield } from '../shield';
-import { requestSchema, operationRequirements } from './schema';
+import type { ValidationRequest } from '../shield';
 import type {
+  ClaimedPositions,
+  ContractDeployment,
+  ReasonCode,
+  StakedBalanceCall,
+  ValidationResult,
+} from '../types';
+import {
+  requestSchema,
+  operationRequirements,
+  profileSchema,
+  registryOverrideSchema,
+} from './schema';
+import {
+  getJsonSchemas,
+  orderResultFields,
+  VALIDATE_RESULT_FIELDS,
+} from './response-schema';
+import { MAX_INPUT_SIZE } from './constants';
+import { getOptionalFields, listOperations } from './operations';
+import { getCacheKey, type ValidationCache } from './validation-cache';
+import { getAttestation } from '../attestation';
+import { getMessageCatalog } from '../locales';
+import { createAuditRecord } from '../audit';
+import { DEPRECATED_API_VERSIONS, SUPPORTED_API_VERSIONS } from '../version';
+import type {
+  AuditRecord,
   JsonRequest,
   JsonResponse,
   JsonSuccessResponse,
   JsonErrorResponse,
   ValidateResult,
+  ExplainValidationResult,
+  ValidateBatchResult,
+  BatchSummary,
+  BatchTransaction,
+  FlowTransaction,
+  ValidateFlowResult,
+  ValidateUserOperationResult,
+  ValidateSendCallsResult,
+  CompareIntentResult,
+  PreflightResult,
+  DecodeTransactionResult,
   IsSupportedResult,
   GetSupportedYieldIdsResult,
+  GetYieldCapabilitiesResult,
+  GetYieldAbiResult,
+  GetYieldsResult,
+  DetectYieldsResult,
+  GetVersionResult,
+  ReloadRegistryResult,
+  GetSchemaResult,
+  ListOperationsResult,
+  HealthResult,
+  AttestResult,
   ErrorCode,
+  JsonHandlerOptions,
+  ProtocolWarning,
+  ShieldProfile,
 } from './types';
+import { isDefined, isNonEmptyString } from '../utils/validation';
+import { createJsonLogger, type Logger } from '../logger';
+import type { VaultRegistryOverride } from '../validators/evm/erc4626';
 
 // SECURITY: Pre-compiled schema validator (prevents ReDoS on repeated calls)
 const ajv = new Ajv({ allErrors: true, strict: true });
 const validateSchema = ajv.compile(requestSchema);
+const validateRegistryOverride = ajv.compile(registryOverrideSchema);
+const validateProfile = ajv.compile(profileSchema);
 
-// SECURITY: Input size limit (100KB)
-const MAX_INPUT_SIZE = 100 * 1024;
+// Must match the requestId limit in the request schema
+const MAX_REQUEST_ID_LENGTH = 256;
 
 // Single Shield instance (stateless, safe to reuse)
-const shield = new Shield();
+const defaultShield = new Shield();
+
+// Shields for JsonHandlerOptions.registryOverride, built once per override
+const overrideShields = new WeakMap<VaultRegistryOverride, Shield>();
+
+/**
+ * The Shield to answer request with: the default one, or one with the
+ * vaults of options.registryOverride and then of request.registryOverride
+ * registered over the built-in registry.
+ */
+function getShield(request: JsonRequest, options: JsonHandlerOptions): Shield {
+  const shared = options.registryOverride;
+  if (!isDefined(request.registryOverride)) {
+    if (!isDefined(shared)) return defaultShield;
+
+    let shield = overrideShields.get(shared);
+    if (!shield) {
+      shield = new Shield({ registryOverride: shared });
+      overrideShields.set(shared, shield);
+    }
+    return shield;
+  }
+  return new Shield({
+    registryOverride: getRegistryOverride(request, options),
+  });
+}
+
+// The vaults getShield registers over the built-in registry for request
+function getRegistryOverride(
+  request: JsonRequest,
+  options: JsonHandlerOptions,
+): VaultRegistryOverride | undefined {
+  const shared = options.registryOverride;
+  const own = request.registryOverride;
+  if (!isDefined(own)) return shared;
+  return isDefined(shared)
+    ? { ...own, vaults: [...shared.vaults, ...own.vaults] }
+    : own;
+}
+
+/**
+ * Names the field behind a schema error, as a path such as
+ * 'transactions[0].yieldId', with a message a client can show as is.
+ */
+function describeSchemaError(error: ErrorObject): {
+  field: string;
+  message: string;
+} {
+  const path = error.instancePath
+    .split('/')
+    .slice(1)
+    .map((segment) => (/^[0-9]+$/.test(segment) ? `[${segment}]` : segment))
+    .join('.')
+    .replace(/\.\[/g, '[');
+  const join = (name: string) => (path === '' ? name : `${path}.${name}`);
+
+  if (error.keyword === 'required') {
+    const field = join(String(error.params.missingProperty));
+    return { field, message: `Missing required field '${field}'` };
+  }
+  if (error.keyword === 'additionalProperties') {
+    const field = join(String(error.params.additionalProperty));
+    return { field, message: `Unknown field '${field}'` };
+  }
+  return {
+    field: path,
+    message:
+      path === ''
+        ? `Request ${error.message}`
+        : `Field '${path}' ${error.message}`,
+  };
+}
+
+/**
+ * Parses a registry override, such as the file given to --registry, and
+ * checks it against the schema of inline ones. Throws an Error naming the
+ * first problem found.
+ */
+export function parseRegistryOverride(json: string): VaultRegistryOverride {
+  let override: unknown;
+  try {
+    override = JSON.parse(json);
+  } catch (e) {
+    const message = e instanceof Error ? e.message : String(e);
+    throw new Error(`Invalid registry override: ${message}`);
+  }
+  if (!validateRegistryOverride(override)) {
+    const [error] = validateRegistryOverride.errors ?? [];
+    const message = error
+      ? describeSchemaError(error).message
+      : 'Registry override does not match expected schema';
+    throw new Error(`Invalid registry override: ${message}`);
+  }
+  return override as VaultRegistryOverride;
+}
+
+/**
+ * Parses a profile, such as the file given to --profile, and checks it
+ * against the profile schema. Throws an Error naming the first problem
+ * found.
+ */
+export function parseProfile(json: string): ShieldProfile {
+  let profile: unknown;
+  try {
+    profile = JSON.parse(json);
+  } catch (e) {
+    const message = e instanceof Error ? e.message : String(e);
+    throw new Error(`Invalid profile: ${message}`);
+  }
+  if (!validateProfile(profile)) {
+    const [error] = validateProfile.errors ?? [];
+    const message = error
+      ? describeSchemaError(error).message
+      : 'Profile does not match expected schema';
+    throw new Error(`Invalid profile: ${message}`);
+  }
+  const unknown = (profile as ShieldProfile).responseFields?.find(
+    (field) => !VALIDATE_RESULT_FIELDS.includes(field),
+  );
+  if (unknown !== undefined) {
+    throw new Error(
+      `Invalid profile: Unknown validate result field '${unknown}' in responseFields`,
+    );
+  }
+  return profile as ShieldProfile;
+}
+
+// The fields a profile sets defaults for
+type ProfileField = Exclude<keyof ShieldProfile, 'name'>;
+const PROFILE_FIELDS: ProfileField[] = [
+  'strict',
+  'policy',
+  'locale',
+  'responseFields',
+];
+
+/**
+ * request with the defaults of profile filled in, for the fields its
+ * operation takes, and those of a validateBatch request's items. Nothing
+ * the request sets is replaced.
+ */
+function applyProfile(
+  request: JsonRequest,
+  profile: ShieldProfile | undefined,
+): JsonRequest {
+  if (!isDefined(profile)) return request;
+
+  const optional = getOptionalFields(request.operation);
+  const applied = withProfile(
+    request,
+    profile,
+    PROFILE_FIELDS.filter((field) => optional.includes(field)),
+  );
+  if (request.operation === 'validateBatch') {
+    applied.transactions = (request.transactions as BatchTransaction[]).map(
+      (item) => withProfile(item, profile, ['strict', 'policy', 'locale']),
+    );
+  }
+  return applied;
+}
+
+function withProfile<T extends Partial<Pick<JsonRequest, ProfileField>>>(
+  target: T,
+  profile: ShieldProfile,
+  fields: ProfileField[],
+): T {
+  const applied: Record<string, unknown> = { ...target };
+  for (const field of fields) {
+    if (!isDefined(profile[field])) continue;
+    applied[field] =
+      field === 'policy'
+        ? { ...profile.policy, ...target.policy }
+        : (target[field] ?? profile[field]);
+  }
+  return applied as T;
+}
+
+// SHA-256 of profile, as getVersion reports it
+function getProfileHash(profile: ShieldProfile): string {
+  return createHash('sha256').update(JSON.stringify(profile)).digest('hex');
+}
 
 /**
  * Computes SHA-256 hash of request for integrity verification.
@@ -31,6 +266,53 @@ function computeRequestHash(input: string): string {
   return createHash('sha256').update(input).digest('hex');
 }
 
+// Fields that differ between runs, and display strings whose wording may
+// change between releases
+const RUN_FIELDS = new Set(['timing', 'cached']);
+const DISPLAY_FIELDS = new Set([
+  'reason',
+  'wouldRejectReason',
+  'summary',
+  'message',
+  'messageLocalized',
+  'description',
+  'detail',
+  'formatted',
+]);
+
+/**
+ * SHA-256 of what a response means: its ok, apiVersion and result or
+ * error, without timings, cache hits and display strings, as JSON with
+ * keys sorted at every level. Responses that differ only in those, or in
+ * key order, share it.
+ */
+function computeStableHash(response: JsonResponse<unknown>): string {
+  const meaning = response.ok
+    ? { ok: true, apiVersion: response.apiVersion, result: response.result }
+    : { ok: false, apiVersion: response.apiVersion, error: response.error };
+  return createHash('sha256').update(toStableJson(meaning)).digest('hex');
+}
+
+function toStableJson(value: unknown): string {
+  if (Array.isArray(value)) return `[${value.map(toStableJson).join(',')}]`;
+  if (typeof value !== 'object' || value === null) {
+    return JSON.stringify(value) ?? 'null';
+  }
+
+  const fields = Object.entries(value)
+    .filter(
+      ([key, field]) =>
+        field !== undefined &&
+        !RUN_FIELDS.has(key) &&
+        !(DISPLAY_FIELDS.has(key) && typeof field === 'string'),
+    )
+    .sort(([a], [b]) => (a < b ? -1 : a > b ? 1 : 0));
+  const members = fields.map(
+    ([key, field]) => `${JSON.stringify(key)}:${toStableJson(field)}`,
+  );
+  return `{${members.join(',')}}`;
+}
+
 /**
  * Main entry point for JSON interface.
  *
@@ -41,87 +323,224 @@ function computeRequestHash(input: string): string {
  * 4. All string inputs have length limits
  * 5. Function is pure (no side effects, no network calls)
  */
-export function handleJsonRequest(jsonInput: string): string {
-  const requestHash = computeRequestHash(jsonInput);
+export function handleJsonRequest(
+  jsonInput: string,
+  options: JsonHandlerOptions = {},
+): string {
+  const parsed = parseRequest(jsonInput, options);
+  if ('output' in parsed) return parsed.output;
 
-  // SECURITY: Check input size before parsing
-  if (jsonInput.length > MAX_INPUT_SIZE) {
-    return JSON.stringify(
+  const { request, requestHash, respond } = parsed;
+  const shield = getShield(request, options);
+  if (request.simulate) {
+    return respond(
       errorResponse(
-        'SCHEMA_VALIDATION_ERROR',
-        `Input exceeds maximum size of ${MAX_INPUT_SIZE} bytes`,
+        'SIMULATION_UNAVAILABLE',
+        'Simulation is only available through handleJsonRequestAsync',
         requestHash,
       ),
     );
   }
-
-  // Step 1: Parse JSON
-  let request: unknown;
-  try {
-    request = JSON.parse(jsonInput);
-  } catch (e) {
-    return JSON.stringify(
-      errorResponse('PARSE_ERROR', 'Invalid JSON syntax', requestHash, {
-        parseError: e instanceof Error ? e.message : String(e),
-      }),
+  if (request.checkNonce) {
+    return respond(
+      errorResponse(
+        'SIMULATION_UNAVAILABLE',
+        'Nonce checks are only available through handleJsonRequestAsync',
+        requestHash,
+      ),
     );
   }
-
-  // Step 2: Validate against schema (SECURITY: strict validation)
-  if (!validateSchema(request)) {
-    return JSON.stringify(
+  if (getLookupTableAddresses(shield, request).length > 0) {
+    return respond(
       errorResponse(
-        'SCHEMA_VALIDATION_ERROR',
-        'Request does not match expected schema',
+        'SIMULATION_UNAVAILABLE',
+        'Lookup table fetches are only available through handleJsonRequestAsync',
+        requestHash,
+      ),
+    );
+  }
+  if (getEnsNames(shield, request).length > 0) {
+    return respond(
+      errorResponse(
+        'SIMULATION_UNAVAILABLE',
+        'ENS resolution is only available through handleJsonRequestAsync',
+        requestHash,
+      ),
+    );
+  }
+  if (getCodeAddresses(shield, request).length > 0) {
+    return respond(
+      errorResponse(
+        'SIMULATION_UNAVAILABLE',
+        'Contract code checks are only available through handleJsonRequestAsync',
+        requestHash,
+      ),
+    );
+  }
+  if (isDefined(getStakedBalanceCall(shield, request))) {
+    return respond(
+      errorResponse(
+        'SIMULATION_UNAVAILABLE',
+        'Staked balance checks are only available through handleJsonRequestAsync',
+        requestHash,
+      ),
+    );
+  }
+  if (isDefined(getClaimedPositions(shield, request))) {
+    return respond(
+      errorResponse(
+        'SIMULATION_UNAVAILABLE',
+        'Position ownership checks are only available through handleJsonRequestAsync',
         requestHash,
-        { validationErrors: validateSchema.errors },
       ),
     );
   }
+  return respond(routeRequest(shield, request, requestHash, options));
+}
 
-  const validRequest = request as unknown as JsonRequest;
+/**
+ * Same as handleJsonRequest, except that validate requests with
+ * simulate: true are also executed against their rpcUrl, those with
+ * checkNonce: true have the sender's nonce fetched from it, and the ENS
+ * names of those with an rpcUrl are resolved through it, as are the code
+ * of the contracts they send calldata to and whether those are paused, the
+ * staked balance they unstake from and the owners of the positions they
+ * claim. Those are the only network calls this module makes; every other
+ * request is answered exactly as handleJsonRequest would. With blockNumber,
+ * every EVM read is made at that block rather than the latest.
+ */
+export async function handleJsonRequestAsync(
+  jsonInput: string,
+  options: JsonHandlerOptions = {},
+): Promise<string> {
+  const parsed = parseRequest(jsonInput, options);
+  if ('output' in parsed) return parsed.output;
 
-  // Step 3: Check operation-specific required fields
-  const requiredFields = operationRequirements[validRequest.operation];
-  for (const field of requiredFields) {
-    if (
-      !(field in validRequest) ||
-      validRequest[field as keyof JsonRequest] === undefined
-    ) {
-      return JSON.stringify(
-        errorResponse(
-          'MISSING_REQUIRED_FIELD',
-          `Operation '${validRequest.operation}' requires field '${field}'`,
-          requestHash,
-        ),
-      );
-    }
+  const { request, requestHash, respond } = parsed;
+  const shield = getShield(request, options);
+  const lookupTables = getLookupTableAddresses(shield, request);
+  const ensNames = getEnsNames(shield, request);
+  const balanceCall = getStakedBalanceCall(shield, request);
+  const positions = getClaimedPositions(shield, request);
+  if (
+    !request.simulate &&
+    !request.checkNonce &&
+    lookupTables.length === 0 &&
+    ensNames.length === 0 &&
+    getCodeAddresses(shield, request).length === 0 &&
+    !isDefined(balanceCall) &&
+    !isDefined(positions)
+  ) {
+    return respond(routeRequest(shield, request, requestHash, options));
   }
 
-  // Step 4: Route to appropriate handler
   try {
-    switch (validRequest.operation) {
-      case 'validate':
-        return JSON.stringify(handleValidate(validRequest, requestHash));
-      case 'isSupported':
-        return JSON.stringify(handleIsSupported(validRequest, requestHash));
-      case 'getSupportedYieldIds':
-        return JSON.stringify(handleGetSupportedYieldIds(requestHash));
-      default: {
-        // SECURITY: Defense-in-depth - schema validation should prevent this
-        const exhaustiveCheck: never = validRequest.operation;
-        return JSON.stringify(
-          errorResponse(
-            'INTERNAL_ERROR',
-            `Unknown operation: ${exhaustiveCheck}`,
-            requestHash,
-          ),
+    const fetched: FetchedState = {};
+    // Every other check reads the transaction with its lookup tables
+    // resolved
+    if (lookupTables.length > 0) {
+      try {
+        fetched.addressLookupTables = await fetchLookupTables(
+          shield,
+          request,
+          lookupTables,
+        );
+      } catch (error) {
+        return respond(
+          fetchFailure('ALT_RESOLUTION_FAILED', request, error, requestHash),
         );
       }
     }
-  } catch (e) {
-    // SECURITY: Never expose internal error details in production
-    return JSON.stringify(
+    if (request.checkNonce) {
+      try {
+        fetched.accountNonce = await shield.fetchAccountNonce(
+          request.rpcUrl!,
+          request.userAddress!,
+          request.blockNumber,
+        );
+      } catch (error) {
+        return respond(
+          fetchFailure('NONCE_CHECK_FAILED', request, error, requestHash),
+        );
+      }
+    }
+    if (ensNames.length > 0) {
+      try {
+        fetched.ensAddresses = await resolveEnsNames(
+          shield,
+          request.rpcUrl!,
+          request.blockNumber,
+          ensNames,
+        );
+      } catch (error) {
+        return respond(
+          fetchFailure('ENS_RESOLUTION_FAILED', request, error, requestHash),
+        );
+      }
+    }
+    // Only once ENS names are resolved is a recipient given as one known
+    const codeAddresses = getCodeAddresses(shield, request, fetched);
+    if (codeAddresses.length > 0) {
+      try {
+        fetched.contractCode = await fetchContractCode(
+          shield,
+          request.rpcUrl!,
+          request.blockNumber,
+          codeAddresses,
+        );
+        fetched.contractPaused = await fetchContractPaused(
+          shield,
+          request.rpcUrl!,
+          request.blockNumber,
+          codeAddresses.filter((address) => fetched.contractCode![address]),
+        );
+      } catch (error) {
+        return respond(
+          fetchFailure('CODE_CHECK_FAILED', request, error, requestHash),
+        );
+      }
+      if (isDefined(request.policy?.minContractAgeSeconds)) {
+        fetched.contractDeployments = await fetchContractDeployments(
+          shield,
+          request.rpcUrl!,
+          request.blockNumber,
+          codeAddresses.filter((address) => fetched.contractCode![address]),
+        );
+      }
+    }
+    if (isDefined(balanceCall)) {
+      try {
+        fetched.stakedBalance = await shield.fetchStakedBalance(
+          request.rpcUrl!,
+          balanceCall,
+          request.blockNumber,
+        );
+      } catch (error) {
+        return respond(
+          fetchFailure('BALANCE_CHECK_FAILED', request, error, requestHash),
+        );
+      }
+    }
+    if (isDefined(positions)) {
+      try {
+        fetched.positionOwners = await shield.fetchPositionOwners(
+          request.rpcUrl!,
+          positions,
+          request.blockNumber,
+        );
+      } catch (error) {
+        return respond(
+          fetchFailure('POSITION_CHECK_FAILED', request, error, requestHash),
+        );
+      }
+    }
+    return respond(
+      request.simulate
+        ? await handleSimulatedValidate(shield, request, requestHash, fetched)
+        : handleValidate(shield, request, requestHash, fetched),
+    );
+  } catch {
+    return respond(
       errorResponse(
         'INTERNAL_ERROR',
         'An unexpected error occurred',
@@ -131,53 +550,1613 @@ export function handleJsonRequest(jsonInput: string): string {
   }
 }
 
-function handleValidate(
+// What the async handler fetched from rpcUrl before validating
+type FetchedState = Pick<
+  ValidationRequest,
+  | 'addressLookupTables'
+  | 'accountNonce'
+  | 'ensAddresses'
+  | 'contractCode'
+  | 'contractPaused'
+  | 'contractDeployments'
+  | 'stakedBalance'
+  | 'positionOwners'
+>;
+
+// The lookup tables a validate request with an rpcUrl needs fetched
+function getLookupTableAddresses(
+  shield: Shield,
   request: JsonRequest,
-  requestHash: string,
-): JsonResponse<ValidateResult> {
-  const result = shield.validate({
+): string[] {
+  if (
+    request.operation !== 'validate' ||
+    request.rpcUrl === undefined ||
+    request.unsignedTransaction === undefined
+  ) {
+    return [];
+  }
+  return shield.getLookupTableAddresses({
     yieldId: request.yieldId!,
-    unsignedTransaction: request.unsignedTransaction!,
-    userAddress: request.userAddress!,
-    args: request.args,
-    context: request.context,
+    unsignedTransaction: request.unsignedTransaction,
+    addressLookupTables: request.addressLookupTables,
   });
+}
 
-  return successResponse(
-    {
-      isValid: result.isValid,
-      reason: result.reason,
-      details: result.details,
-      detectedType: result.detectedType,
-    },
-    requestHash,
-  );
+// The request's own tables, with those it left out
+async function fetchLookupTables(
+  shield: Shield,
+  request: JsonRequest,
+  addresses: string[],
+): Promise<Record<string, string[]>> {
+  const lookupTables = { ...request.addressLookupTables };
+  for (const address of addresses) {
+    lookupTables[address] = await shield.fetchLookupTable(
+      request.rpcUrl!,
+      address,
+    );
+  }
+  return lookupTables;
 }
 
-function handleIsSupported(
+// The ENS names a validate request with an rpcUrl needs resolved
+function getEnsNames(shield: Shield, request: JsonRequest): string[] {
+  if (request.operation !== 'validate' || request.rpcUrl === undefined) {
+    return [];
+  }
+  return shield.getEnsNames({
+    yieldId: request.yieldId!,
+    unsignedTransaction: request.unsignedTransaction ?? '',
+    expectedRecipientEns: request.expectedRecipientEns,
+  });
+}
+
+async function resolveEnsNames(
+  shield: Shield,
+  rpcUrl: string,
+  block: number | undefined,
+  names: string[],
+): Promise<Record<string, string>> {
+  const ensAddresses: Record<string, string> = {};
+  for (const name of names) {
+    const address = await shield.resolveEnsName(rpcUrl, name, block);
+    if (address !== null) ensAddresses[name] = address;
+  }
+  return ensAddresses;
+}
+
+// The contracts a validate request with an rpcUrl needs the code of
+function getCodeAddresses(
+  shield: Shield,
   request: JsonRequest,
-  requestHash: string,
-): JsonResponse<IsSupportedResult> {
-  return successResponse(
-    {
-      supported: shield.isSupported(request.yieldId!),
-      yieldId: request.yieldId!,
-    },
-    requestHash,
-  );
+  fetched: FetchedState = {},
+): string[] {
+  if (
+    request.operation !== 'validate' ||
+    request.rpcUrl === undefined ||
+    request.unsignedTransaction === undefined
+  ) {
+    return [];
+  }
+  return shield.getCodeAddresses({
+    yieldId: request.yieldId!,
+    unsignedTransaction: request.unsignedTransaction,
+    ensAddresses: fetched.ensAddresses,
+  });
 }
 
-function handleGetSupportedYieldIds(
+// The balance a validate request with an rpcUrl needs read, if it unstakes
+function getStakedBalanceCall(
+  shield: Shield,
+  request: JsonRequest,
+): StakedBalanceCall | undefined {
+  if (
+    request.operation !== 'validate' ||
+    request.rpcUrl === undefined ||
+    request.unsignedTransaction === undefined
+  ) {
+    return undefined;
+  }
+  return shield.getStakedBalanceCall({
+    yieldId: request.yieldId!,
+    unsignedTransaction: request.unsignedTransaction,
+  });
+}
+
+// The positions a validate request with an rpcUrl needs the owners of, if
+// it claims any
+function getClaimedPositions(
+  shield: Shield,
+  request: JsonRequest,
+): ClaimedPositions | undefined {
+  if (
+    request.operation !== 'validate' ||
+    request.rpcUrl === undefined ||
+    request.unsignedTransaction === undefined
+  ) {
+    return undefined;
+  }
+  return shield.getClaimedPositions({
+    yieldId: request.yieldId!,
+    unsignedTransaction: request.unsignedTransaction,
+  });
+}
+
+async function fetchContractCode(
+  shield: Shield,
+  rpcUrl: string,
+  block: number | undefined,
+  addresses: string[],
+): Promise<Record<string, boolean>> {
+  const contractCode: Record<string, boolean> = {};
+  for (const address of addresses) {
+    contractCode[address] = await shield.hasContractCode(
+      rpcUrl,
+      address,
+      block,
+    );
+  }
+  return contractCode;
+}
+
+async function fetchContractPaused(
+  shield: Shield,
+  rpcUrl: string,
+  block: number | undefined,
+  addresses: string[],
+): Promise<Record<string, boolean>> {
+  const contractPaused: Record<string, boolean> = {};
+  for (const address of addresses) {
+    contractPaused[address] = await shield.isContractPaused(
+      rpcUrl,
+      address,
+      block,
+    );
+  }
+  return contractPaused;
+}
+
+// A contract whose deployment cannot be found, e.g. on a node without
+// archive state, is left out rather than failing the request: the age is
+// a heuristic, not a rule
+async function fetchContractDeployments(
+  shield: Shield,
+  rpcUrl: string,
+  block: number | undefined,
+  addresses: string[],
+): Promise<Record<string, ContractDeployment>> {
+  const contractDeployments: Record<string, ContractDeployment> = {};
+  for (const address of addresses) {
+    try {
+      const deployment = await shield.fetchContractDeployment(
+        rpcUrl,
+        address,
+        block,
+      );
+      if (deployment !== null) contractDeployments[address] = deployment;
+    } catch {
+      // Checked without it
+    }
+  }
+  return contractDeployments;
+}
+
+// A validate result for a request whose rpcUrl could not be queried
+function fetchFailure(
+  reasonCode:
+    | 'NONCE_CHECK_FAILED'
+    | 'ENS_RESOLUTION_FAILED'
+    | 'CODE_CHECK_FAILED'
+    | 'BALANCE_CHECK_FAILED'
+    | 'POSITION_CHECK_FAILED'
+    | 'ALT_RESOLUTION_FAILED',
+  request: JsonRequest,
+  error: unknown,
   requestHash: string,
-): JsonResponse<GetSupportedYieldIdsResult> {
+): JsonResponse<ValidateResult> {
   return successResponse(
     {
-      yieldIds: shield.getSupportedYieldIds(),
+      isValid: false,
+      reason: reasonCode,
+      reasonCode,
+      details: {
+        yieldId: request.yieldId,
+        error: error instanceof Error ? error.message : String(error),
+      },
+      warnings: [],
     },
     requestHash,
   );
 }
 
+type ParsedRequest =
+  | { output: string }
+  | {
+      request: JsonRequest;
+      requestHash: string;
+      respond: (response: JsonResponse<unknown>) => string;
+    };
+
+// Steps 1-3 of handling a request: everything short of running it
+function parseRequest(
+  jsonInput: string,
+  options: JsonHandlerOptions,
+): ParsedRequest {
+  const startedAt = performance.now();
+  const requestHash = computeRequestHash(jsonInput);
+
+  // Echo the caller's requestId on every response that can be correlated
+  let request: unknown;
+  let requestId: string | undefined;
+  let warnings: ProtocolWarning[] = [];
+  let normalizedRequest: JsonRequest | undefined;
+  const respond = (response: JsonResponse<unknown>): string => {
+    if (options.logger) {
+      logResponse(options.logger, request, response, {
+        requestId,
+        requestHash,
+        durationMs: Math.round((performance.now() - startedAt) * 100) / 100,
+      });
+    }
+    const audit = getAuditRecord(request, response, options);
+    if (audit) options.auditSink?.(audit);
+    // Keys are always written in this order, so responses can be compared
+    // as text
+    const shaped = selectResponseFields(request, response);
+    const outcome = shaped.ok
+      ? {
+          ok: true,
+          apiVersion: shaped.apiVersion,
+          result: orderResultFields(
+            (request as JsonRequest).operation,
+            shaped.result,
+          ),
+        }
+      : {
+          ok: false,
+          apiVersion: shaped.apiVersion,
+          error: localizeError(request, shaped.error),
+        };
+    return JSON.stringify({
+      ...outcome,
+      meta: {
+        requestHash: shaped.meta.requestHash,
+        stableHash: computeStableHash(shaped),
+        ...(warnings.length > 0 && { warnings }),
+        ...(audit && (request as JsonRequest).includeAudit && { audit }),
+      },
+      normalizedRequest,
+      requestId,
+    });
+  };
+  const fail = (response: JsonErrorResponse) => ({ output: respond(response) });
+
+  // SECURITY: Check input size before parsing, in bytes as transports
+  // count them
+  const maxInputSize = options.maxInputSize ?? MAX_INPUT_SIZE;
+  if (Buffer.byteLength(jsonInput, 'utf8') > maxInputSize) {
+    return fail(
+      errorResponse(
+        'INPUT_TOO_LARGE',
+        `Input exceeds maximum size of ${maxInputSize} bytes`,
+        requestHash,
+      ),
+    );
+  }
+
+  // An orchestrator that pipes nothing gets a clearer error than a parse
+  // failure at position 0
+  if (jsonInput.trim() === '') {
+    return fail(errorResponse('EMPTY_INPUT', 'Input is empty', requestHash));
+  }
+
+  // Step 1: Parse JSON
+  try {
+    request = JSON.parse(jsonInput);
+  } catch (e) {
+    return fail(
+      errorResponse('PARSE_ERROR', 'Invalid JSON syntax', requestHash, {
+        parseError: e instanceof Error ? e.message : String(e),
+      }),
+    );
+  }
+
+  requestId = extractRequestId(request);
+  if (options.requireRequestId && requestId === undefined) {
+    return fail(
+      errorResponse(
+        'MISSING_REQUEST_ID',
+        'Request must include a requestId so its response can be correlated',
+        requestHash,
+      ),
+    );
+  }
+
+  // A request for another API version may not match this version's schema,
+  // so the version is checked first
+  const apiVersion = extractApiVersion(request);
+  const supported = SUPPORTED_API_VERSIONS.join(', ');
+  if (
+    apiVersion !== undefined &&
+    !SUPPORTED_API_VERSIONS.includes(apiVersion)
+  ) {
+    return fail(
+      errorResponse(
+        'UNSUPPORTED_API_VERSION',
+        `API version '${apiVersion}' is not supported; supported versions: ${supported}`,
+        requestHash,
+        { requested: apiVersion, supportedApiVersions: SUPPORTED_API_VERSIONS },
+      ),
+    );
+  }
+  if (
+    apiVersion !== undefined &&
+    DEPRECATED_API_VERSIONS.includes(apiVersion)
+  ) {
+    warnings = [
+      {
+        code: 'API_VERSION_DEPRECATED',
+        message: `API version '${apiVersion}' is deprecated and will be removed; supported versions: ${supported}`,
+      },
+    ];
+  }
+
+  // Step 2: Validate against schema (SECURITY: strict validation)
+  if (!validateSchema(request)) {
+    const errors = validateSchema.errors ?? [];
+    const { field, message } =
+      errors.length > 0
+        ? describeSchemaError(errors[0])
+        : { field: '', message: 'Request does not match expected schema' };
+    return fail(
+      errorResponse('SCHEMA_VALIDATION_ERROR', message, requestHash, {
+        field,
+        validationErrors: errors,
+      }),
+    );
+  }
+
+  const validRequest = request as unknown as JsonRequest;
+
+  // Step 3: Check operation-specific required fields
+  const requiredFields = operationRequirements[validRequest.operation];
+  for (const field of requiredFields) {
+    if (
+      field === 'unsignedTransaction' &&
+      validRequest.rawTransaction !== undefined
+    ) {
+      continue;
+    }
+    if (
+      field === 'yieldId' &&
+      validRequest.operation === 'validate' &&
+      validRequest.yieldIds !== undefined
+    ) {
+      continue;
+    }
+    if (
+      !(field in validRequest) ||
+      validRequest[field as keyof JsonRequest] === undefined
+    ) {
+      return fail(
+        errorResponse(
+          'MISSING_REQUIRED_FIELD',
+          `Operation '${validRequest.operation}' requires field '${field}'`,
+          requestHash,
+          { field },
+        ),
+      );
+    }
+  }
+
+  if (
+    validRequest.chainId !== undefined &&
+    validRequest.operation !== 'getSupportedYieldIds' &&
+    validRequest.operation !== 'detectYields' &&
+    validRequest.operation !== 'preflight'
+  ) {
+    return fail(
+      errorResponse(
+        'SCHEMA_VALIDATION_ERROR',
+        "Field 'chainId' is only accepted by getSupportedYieldIds, detectYields and preflight",
+        requestHash,
+        { field: 'chainId' },
+      ),
+    );
+  }
+
+  for (const field of ['ifNoneMatch', 'pageSize', 'pageToken'] as const) {
+    if (
+      validRequest[field] !== undefined &&
+      validRequest.operation !== 'getSupportedYieldIds'
+    ) {
+      return fail(
+        errorResponse(
+          'SCHEMA_VALIDATION_ERROR',
+          `Field '${field}' is only accepted by getSupportedYieldIds`,
+          requestHash,
+          { field },
+        ),
+      );
+    }
+  }
+
+  for (const field of ['detectedType', 'amount'] as const) {
+    if (
+      validRequest[field] !== undefined &&
+      validRequest.operation !== 'preflight'
+    ) {
+      return fail(
+        errorResponse(
+          'SCHEMA_VALIDATION_ERROR',
+          `Field '${field}' is only accepted by preflight`,
+          requestHash,
+          { field },
+        ),
+      );
+    }
+  }
+
+  if (
+    validRequest.yieldIds !== undefined &&
+    validRequest.operation !== 'getYields'
+  ) {
+    if (validRequest.operation !== 'validate') {
+      return fail(
+        errorResponse(
+          'SCHEMA_VALIDATION_ERROR',
+          "Field 'yieldIds' is only accepted by getYields and validate",
+          requestHash,
+          { field: 'yieldIds' },
+        ),
+      );
+    }
+    // Candidates are only tried against what the request carries, so
+    // nothing is fetched for each of them
+    for (const field of ['yieldId', 'rawTransaction', 'rpcUrl'] as const) {
+      if (validRequest[field] !== undefined) {
+        return fail(
+          errorResponse(
+            'SCHEMA_VALIDATION_ERROR',
+            `Fields 'yieldIds' and '${field}' cannot both be set`,
+            requestHash,
+            { field },
+          ),
+        );
+      }
+    }
+  }
+
+  if (
+    validRequest.transactionType !== undefined &&
+    validRequest.operation !== 'getYieldAbi'
+  ) {
+    return fail(
+      errorResponse(
+        'SCHEMA_VALIDATION_ERROR',
+        "Field 'transactionType' is only accepted by getYieldAbi",
+        requestHash,
+        { field: 'transactionType' },
+      ),
+    );
+  }
+
+  if (validRequest.rawTransaction !== undefined) {
+    if (validRequest.operation !== 'validate') {
+      return fail(
+        errorResponse(
+          'SCHEMA_VALIDATION_ERROR',
+          "Field 'rawTransaction' is only accepted by validate",
+          requestHash,
+          { field: 'rawTransaction' },
+        ),
+      );
+    }
+    if (validRequest.unsignedTransaction !== undefined) {
+      return fail(
+        errorResponse(
+          'SCHEMA_VALIDATION_ERROR',
+          "Fields 'unsignedTransaction' and 'rawTransaction' cannot both be set",
+          requestHash,
+          { field: 'rawTransaction' },
+        ),
+      );
+    }
+  }
+
+  if (
+    validRequest.accountNumber !== undefined &&
+    validRequest.rawTransaction === undefined
+  ) {
+    return fail(
+      errorResponse(
+        'MISSING_REQUIRED_FIELD',
+        "Field 'accountNumber' requires field 'rawTransaction'",
+        requestHash,
+        { field: 'rawTransaction' },
+      ),
+    );
+  }
+
+  if (validRequest.checkNonce) {
+    if (validRequest.operation !== 'validate') {
+      return fail(
+        errorResponse(
+          'SCHEMA_VALIDATION_ERROR',
+          "Field 'checkNonce' is only accepted by validate",
+          requestHash,
+          { field: 'checkNonce' },
+        ),
+      );
+    }
+    for (const field of ['rpcUrl', 'userAddress'] as const) {
+      if (validRequest[field] === undefined) {
+        return fail(
+          errorResponse(
+            'MISSING_REQUIRED_FIELD',
+            `Field 'checkNonce' requires field '${field}'`,
+            requestHash,
+            { field },
+          ),
+        );
+      }
+    }
+  }
+
+  if (validRequest.blockNumber !== undefined) {
+    if (validRequest.operation !== 'validate') {
+      return fail(
+        errorResponse(
+          'SCHEMA_VALIDATION_ERROR',
+          "Field 'blockNumber' is only accepted by validate",
+          requestHash,
+          { field: 'blockNumber' },
+        ),
+      );
+    }
+    if (validRequest.rpcUrl === undefined) {
+      return fail(
+        errorResponse(
+          'MISSING_REQUIRED_FIELD',
+          "Field 'blockNumber' requires field 'rpcUrl'",
+          requestHash,
+          { field: 'rpcUrl' },
+        ),
+      );
+    }
+  }
+
+  if (validRequest.expectedRecipientEns !== undefined) {
+    if (validRequest.operation !== 'validate') {
+      return fail(
+        errorResponse(
+          'SCHEMA_VALIDATION_ERROR',
+          "Field 'expectedRecipientEns' is only accepted by validate",
+          requestHash,
+          { field: 'expectedRecipientEns' },
+        ),
+      );
+    }
+    if (validRequest.rpcUrl === undefined) {
+      return fail(
+        errorResponse(
+          'MISSING_REQUIRED_FIELD',
+          "Field 'expectedRecipientEns' requires field 'rpcUrl'",
+          requestHash,
+          { field: 'rpcUrl' },
+        ),
+      );
+    }
+  }
+
+  if (validRequest.responseFields !== undefined) {
+    if (validRequest.operation !== 'validate') {
+      return fail(
+        errorResponse(
+          'SCHEMA_VALIDATION_ERROR',
+          "Field 'responseFields' is only accepted by validate",
+          requestHash,
+          { field: 'responseFields' },
+        ),
+      );
+    }
+    const unknown = validRequest.responseFields.find(
+      (field) => !VALIDATE_RESULT_FIELDS.includes(field),
+    );
+    if (unknown !== undefined) {
+      return fail(
+        errorResponse(
+          'SCHEMA_VALIDATION_ERROR',
+          `Unknown validate result field '${unknown}' in responseFields`,
+          requestHash,
+          { field: 'responseFields', value: unknown },
+        ),
+      );
+    }
+  }
+
+  if (validRequest.simulate) {
+    if (validRequest.operation !== 'validate') {
+      return fail(
+        errorResponse(
+          'SCHEMA_VALIDATION_ERROR',
+          `Operation '${validRequest.operation}' cannot be simulated`,
+          requestHash,
+        ),
+      );
+    }
+    if (validRequest.rawTransaction !== undefined) {
+      return fail(
+        errorResponse(
+          'SCHEMA_VALIDATION_ERROR',
+          "Field 'rawTransaction' cannot be simulated; send its fields as unsignedTransaction",
+          requestHash,
+          { field: 'rawTransaction' },
+        ),
+      );
+    }
+    if (validRequest.rpcUrl === undefined) {
+      return fail(
+        errorResponse(
+          'MISSING_REQUIRED_FIELD',
+          "Field 'simulate' requires field 'rpcUrl'",
+          requestHash,
+          { field: 'rpcUrl' },
+        ),
+      );
+    }
+  }
+
+  logContractOverrides(validRequest, requestId, options.logger);
+
+  // Applied once the request's own fields are checked, so that its errors
+  // name what it sent. Responses are shaped by what was applied
+  const profiled = applyProfile(validRequest, options.profile);
+  request = profiled;
+
+  if (profiled.echoRequest) {
+    normalizedRequest = getNormalizedRequest(
+      getShield(profiled, options),
+      profiled,
+    );
+  }
+  return { request: profiled, requestHash, respond };
+}
+
+/**
+ * Logs every contractOverrides entry of request and of its batch items at
+ * warn level, for an audit trail of what was validated against an address
+ * the registry does not list. Entries go to stderr when no logger is set.
+ */
+function logContractOverrides(
+  request: JsonRequest,
+  requestId: string | undefined,
+  logger: Logger | undefined,
+): void {
+  const items =
+    request.operation === 'validateBatch'
+      ? (request.transactions as BatchTransaction[])
+      : [];
+  const overrides = [request, ...items].flatMap(({ contractOverrides }) =>
+    Object.entries(contractOverrides ?? {}),
+  );
+  if (overrides.length === 0) return;
+
+  const audit = logger ?? createJsonLogger('warn');
+  for (const [yieldId, address] of overrides) {
+    audit.log('warn', 'contract override', {
+      requestId,
+      operation: request.operation,
+      yieldId,
+      address,
+    });
+  }
+}
+
+// What an echoRequest request is answered with: the request as Shield read
+// it, each batched or flow transaction included
+function getNormalizedRequest(
+  shield: Shield,
+  request: JsonRequest,
+): JsonRequest {
+  const normalized = shield.normalizeRequest(request);
+  if (!isDefined(request.transactions)) return normalized;
+
+  const transactions =
+    request.operation === 'validateBatch'
+      ? (request.transactions as BatchTransaction[]).map((item) =>
+          shield.normalizeRequest(item),
+        )
+      : (request.transactions as FlowTransaction[]).map((step) => ({
+          unsignedTransaction: shield.normalizeRequest({
+            yieldId: request.yieldId,
+            unsignedTransaction: step.unsignedTransaction,
+          }).unsignedTransaction,
+        }));
+  return { ...normalized, transactions };
+}
+
+// Step 4: Route to appropriate handler
+function routeRequest(
+  shield: Shield,
+  request: JsonRequest,
+  requestHash: string,
+  options: JsonHandlerOptions,
+): JsonResponse<unknown> {
+  try {
+    switch (request.operation) {
+      case 'validate':
+        return handleValidate(
+          shield,
+          request,
+          requestHash,
+          {},
+          options.validationCache,
+        );
+      case 'explain':
+        return handleExplain(shield, request, requestHash);
+      case 'validateBatch':
+        return handleValidateBatch(shield, request, requestHash);
+      case 'decode':
+        return handleDecode(shield, request, requestHash);
+      case 'isSupported':
+        return handleIsSupported(shield, request, requestHash);
+      case 'getSupportedYieldIds':
+        return handleGetSupportedYieldIds(shield, request, requestHash);
+      case 'getYieldCapabilities':
+        return handleGetYieldCapabilities(shield, request, requestHash);
+      case 'getYieldAbi':
+        return handleGetYieldAbi(shield, request, requestHash);
+      case 'getYields':
+        return handleGetYields(shield, request, requestHash);
+      case 'detectYields':
+        return handleDetectYields(shield, request, requestHash);
+      case 'validateTypedData':
+        return handleValidateTypedData(shield, request, requestHash);
+      case 'validateFlow':
+        return handleValidateFlow(shield, request, requestHash);
+      case 'validateUserOperation':
+        return handleValidateUserOperation(shield, request, requestHash);
+      case 'validateSendCalls':
+        return handleValidateSendCalls(shield, request, requestHash);
+      case 'compareIntent':
+        return handleCompareIntent(shield, request, requestHash);
+      case 'preflight':
+        return handlePreflight(shield, request, requestHash);
+      case 'getVersion':
+        return handleGetVersion(shield, requestHash, options.profile);
+      case 'reloadRegistry':
+        return handleReloadRegistry(options, requestHash);
+      case 'checkRegistry':
+        return successResponse(shield.checkRegistry(), requestHash);
+      case 'getSchema':
+        return handleGetSchema(request, requestHash);
+      case 'listOperations':
+        return handleListOperations(requestHash);
+      case 'health':
+        return successResponse(getHealth(options), requestHash);
+      case 'attest':
+        return handleAttest(requestHash);
+      default: {
+        // SECURITY: Defense-in-depth - schema validation should prevent this
+        const exhaustiveCheck: never = request.operation;
+        return errorResponse(
+          'INTERNAL_ERROR',
+          `Unknown operation: ${exhaustiveCheck}`,
+          requestHash,
+        );
+      }
+    }
+  } catch (e) {
+    // SECURITY: Never expose internal error details in production
+    return errorResponse(
+      'INTERNAL_ERROR',
+      'An unexpected error occurred',
+      requestHash,
+    );
+  }
+}
+
+// Only well-formed versions are negotiated; anything else fails the schema
+function extractApiVersion(request: unknown): string | undefined {
+  if (typeof request !== 'object' || request === null) return undefined;
+  const { apiVersion } = request as { apiVersion?: unknown };
+  return typeof apiVersion === 'string' && /^\d{1,4}\.\d{1,4}$/.test(apiVersion)
+    ? apiVersion
+    : undefined;
+}
+
+/**
+ * Logs what a response says about its request: at info level for results,
+ * warn for request errors and error for INTERNAL_ERROR. Validate results
+ * are followed at debug level by the transaction types tried and the
+ * warnings raised. Logs carry identifiers and outcomes, never transactions.
+ */
+function logResponse(
+  logger: Logger,
+  request: unknown,
+  response: JsonResponse<unknown>,
+  fields: Record<string, unknown>,
+): void {
+  const { operation, yieldId } = (
+    typeof request === 'object' && request !== null ? request : {}
+  ) as { operation?: unknown; yieldId?: unknown };
+  const context = {
+    ...fields,
+    operation: isNonEmptyString(operation) ? operation : undefined,
+    yieldId: isNonEmptyString(yieldId) ? yieldId : undefined,
+  };
+
+  if (!response.ok) {
+    const { code } = response.error;
+    logger.log(
+      code === 'INTERNAL_ERROR' ? 'error' : 'warn',
+      'request failed',
+      { ...context, errorCode: code },
+    );
+    return;
+  }
+
+  const result = response.result as Partial<ValidateResult>;
+  logger.log('info', 'request handled', {
+    ...context,
+    isValid: result.isValid,
+    reasonCode: result.reasonCode,
+    detectedType: result.detectedType,
+    riskLevel: result.riskLevel,
+  });
+
+  if (!Array.isArray(result.warnings)) return;
+  const details = result.details as ValidationResult['details'];
+  logger.log('debug', 'validation checks', {
+    requestId: fields.requestId,
+    attempts: details?.attempts,
+    warningCodes: result.warnings.map((warning) => warning.code),
+  });
+}
+
+// The audit record of a validate result, when the request asks for it or
+// options.auditSink takes it. Only requests that passed validation are
+// answered with ok, so request is a JsonRequest then
+function getAuditRecord(
+  request: unknown,
+  response: JsonResponse<unknown>,
+  options: JsonHandlerOptions,
+): AuditRecord | undefined {
+  if (!response.ok) return undefined;
+  const validRequest = request as JsonRequest;
+  if (validRequest.operation !== 'validate') return undefined;
+  if (!validRequest.includeAudit && !options.auditSink) return undefined;
+  return createAuditRecord(
+    validRequest,
+    response.result as ValidateResult,
+    getRegistryOverride(validRequest, options),
+  );
+}
+
+/**
+ * Returns the caller-supplied requestId, if any. The value is opaque and is
+ * only ever copied back onto the response.
+ */
+// Leaves the fields a request's responseFields does not name out of its
+// result, once the full result has been logged. Only requests that passed
+// validation are answered with ok, so request is a JsonRequest then
+function selectResponseFields(
+  request: unknown,
+  response: JsonResponse<unknown>,
+): JsonResponse<unknown> {
+  const fields = response.ok
+    ? (request as JsonRequest).responseFields
+    : undefined;
+  if (fields === undefined) return response;
+
+  const result = Object.entries(response.result as Record<string, unknown>);
+  return {
+    ...response,
+    result: Object.fromEntries(
+      result.filter(([key]) => key === 'isValid' || fields.includes(key)),
+    ),
+  };
+}
+
+// error with the message of the request's locale as messageLocalized, for
+// structuredMessages requests with a locale
+function localizeError(
+  request: unknown,
+  error: JsonErrorResponse['error'],
+): JsonErrorResponse['error'] {
+  if (typeof request !== 'object' || request === null) return error;
+  const { structuredMessages, locale } = request as {
+    structuredMessages?: unknown;
+    locale?: unknown;
+  };
+  if (structuredMessages !== true || !isNonEmptyString(locale)) return error;
+  return {
+    ...error,
+    messageLocalized:
+      getMessageCatalog(locale).errors?.[error.code] ?? error.message,
+  };
+}
+
+function extractRequestId(request: unknown): string | undefined {
+  if (typeof request !== 'object' || request === null) return undefined;
+  const { requestId } = request as { requestId?: unknown };
+  if (!isNonEmptyString(requestId)) return undefined;
+  return requestId.length <= MAX_REQUEST_ID_LENGTH ? requestId : undefined;
+}
+
+// Only what the request alone decides is cached: not what was fetched for
+// it, nor timings, which differ on every run
+function handleValidate(
+  shield: Shield,
+  request: JsonRequest,
+  requestHash: string,
+  fetched: FetchedState = {},
+  cache?: ValidationCache,
+): JsonResponse<ValidateResult> {
+  const key =
+    cache && !request.includeTiming ? getCacheKey(request) : undefined;
+  const cached = key === undefined ? undefined : cache!.get(key);
+  if (cached) return successResponse({ ...cached, cached: true }, requestHash);
+
+  const shared = { ...getValidationFields(request), ...fetched };
+  const result =
+    request.rawTransaction !== undefined
+      ? shield.validateRawTransaction({
+          ...shared,
+          rawTransaction: request.rawTransaction,
+          accountNumber: request.accountNumber,
+        })
+      : request.yieldIds !== undefined
+        ? shield.validateCandidates({
+            ...shared,
+            yieldIds: request.yieldIds,
+            unsignedTransaction: request.unsignedTransaction!,
+          })
+        : shield.validate({
+            ...shared,
+            unsignedTransaction: request.unsignedTransaction!,
+          });
+
+  const validateResult = toValidateResult(result);
+  if (key !== undefined) cache!.set(key, validateResult);
+  return successResponse(validateResult, requestHash);
+}
+
+async function handleSimulatedValidate(
+  shield: Shield,
+  request: JsonRequest,
+  requestHash: string,
+  fetched: FetchedState,
+): Promise<JsonResponse<ValidateResult>> {
+  const result = await shield.validateAndSimulate({
+    ...getValidationFields(request),
+    ...fetched,
+    unsignedTransaction: request.unsignedTransaction!,
+    rpcUrl: request.rpcUrl!,
+  });
+
+  return successResponse(toValidateResult(result), requestHash);
+}
+
+// A dry run of validate: nothing is fetched, so the trace covers only what
+// the request itself carries
+function handleExplain(
+  shield: Shield,
+  request: JsonRequest,
+  requestHash: string,
+): JsonResponse<ExplainValidationResult> {
+  const result = shield.explain({
+    ...getValidationFields(request),
+    unsignedTransaction: request.unsignedTransaction!,
+  });
+
+  return successResponse(
+    { ...toValidateResult(result), trace: result.trace },
+    requestHash,
+  );
+}
+
+// The fields validate and explain requests pass through to Shield as given
+function getValidationFields(request: JsonRequest) {
+  return {
+    yieldId: request.yieldId!,
+    userAddress: request.userAddress,
+    args: request.args,
+    context: request.context,
+    riskThreshold: request.riskThreshold,
+    policy: request.policy,
+    strict: request.strict,
+    strictSeverities: request.strictSeverities,
+    expectedAmount: request.expectedAmount,
+    expectedAmountToken: request.expectedAmountToken,
+    amountToleranceBps: request.amountToleranceBps,
+    amountTolerance: request.amountTolerance,
+    expectedNonce: request.expectedNonce,
+    includeTiming: request.includeTiming,
+    includeSignature: request.includeSignature,
+    expectedRecipientEns: request.expectedRecipientEns,
+    locale: request.locale,
+    structuredMessages: request.structuredMessages,
+    expectedMemo: request.expectedMemo,
+    observe: request.observe,
+    beneficiaryAddress: request.beneficiaryAddress,
+    actualBytecodeHash: request.actualBytecodeHash,
+    lenientUnknownYield: request.lenientUnknownYield,
+    contractOverrides: request.contractOverrides,
+    addressLookupTables: request.addressLookupTables,
+    blockNumber: request.blockNumber,
+  };
+}
+
+// Steps are validated in order and then checked against each other
+function handleValidateFlow(
+  shield: Shield,
+  request: JsonRequest,
+  requestHash: string,
+): JsonResponse<ValidateFlowResult> {
+  const transactions = request.transactions as FlowTransaction[];
+  const result = shield.validateFlow({
+    yieldId: request.yieldId!,
+    transactions: transactions.map((t) => t.unsignedTransaction),
+    userAddress: request.userAddress,
+    args: request.args,
+    context: request.context,
+    riskThreshold: request.riskThreshold,
+    policy: request.policy,
+    strict: request.strict,
+    strictSeverities: request.strictSeverities,
+  });
+
+  return successResponse(
+    {
+      isValid: result.isValid,
+      reason: result.reason,
+      reasonCode: result.reasonCode,
+      details: result.details,
+      steps: result.steps.map(toValidateResult),
+    },
+    requestHash,
+  );
+}
+
+function handleValidateUserOperation(
+  shield: Shield,
+  request: JsonRequest,
+  requestHash: string,
+): JsonResponse<ValidateUserOperationResult> {
+  const result = shield.validateUserOperation({
+    yieldId: request.yieldId!,
+    userOperation: request.userOperation!,
+    userAddress: request.userAddress,
+    paymasters: request.paymasters,
+    args: request.args,
+    context: request.context,
+    riskThreshold: request.riskThreshold,
+    policy: request.policy,
+    strict: request.strict,
+    strictSeverities: request.strictSeverities,
+  });
+
+  return successResponse(
+    {
+      isValid: result.isValid,
+      reason: result.reason,
+      reasonCode: result.reasonCode,
+      details: result.details,
+      detectedType: result.detectedType,
+      detectedTypes: result.detectedTypes,
+      paymaster: result.paymaster,
+      warnings: result.warnings ?? [],
+      steps: result.steps.map(toValidateResult),
+    },
+    requestHash,
+  );
+}
+
+function handleValidateSendCalls(
+  shield: Shield,
+  request: JsonRequest,
+  requestHash: string,
+): JsonResponse<ValidateSendCallsResult> {
+  const result = shield.validateSendCalls({
+    yieldId: request.yieldId!,
+    sendCalls: request.sendCalls!,
+    userAddress: request.userAddress,
+    args: request.args,
+    context: request.context,
+    riskThreshold: request.riskThreshold,
+    policy: request.policy,
+    strict: request.strict,
+    strictSeverities: request.strictSeverities,
+  });
+
+  return successResponse(
+    {
+      isValid: result.isValid,
+      reason: result.reason,
+      reasonCode: result.reasonCode,
+      details: result.details,
+      detectedType: result.detectedType,
+      detectedTypes: result.detectedTypes,
+      atomicRequired: result.atomicRequired,
+      warnings: result.warnings ?? [],
+      steps: result.steps.map(toValidateResult),
+    },
+    requestHash,
+  );
+}
+
+function handleCompareIntent(
+  shield: Shield,
+  request: JsonRequest,
+  requestHash: string,
+): JsonResponse<CompareIntentResult> {
+  const result = shield.compareIntent({
+    intent: request.intent!,
+    unsignedTransaction: request.unsignedTransaction!,
+    userAddress: request.userAddress,
+    args: request.args,
+    context: request.context,
+    riskThreshold: request.riskThreshold,
+    policy: request.policy,
+    strict: request.strict,
+    strictSeverities: request.strictSeverities,
+  });
+
+  return successResponse(
+    { ...result, validation: toValidateResult(result.validation) },
+    requestHash,
+  );
+}
+
+function handlePreflight(
+  shield: Shield,
+  request: JsonRequest,
+  requestHash: string,
+): JsonResponse<PreflightResult> {
+  const result = shield.preflight({
+    yieldId: request.yieldId!,
+    detectedType: request.detectedType!,
+    userAddress: request.userAddress,
+    amount: request.amount,
+    chainId: request.chainId,
+    policy: request.policy,
+  });
+  return successResponse(result, requestHash);
+}
+
+// Typed data results share the validate result shape; detectedType is PERMIT
+function handleValidateTypedData(
+  shield: Shield,
+  request: JsonRequest,
+  requestHash: string,
+): JsonResponse<ValidateResult> {
+  const result = shield.validateTypedData({
+    yieldId: request.yieldId!,
+    typedData: request.typedData!,
+    userAddress: request.userAddress!,
+    policy: request.policy,
+    strict: request.strict,
+    strictSeverities: request.strictSeverities,
+  });
+
+  return successResponse(toValidateResult(result), requestHash);
+}
+
+function handleValidateBatch(
+  shield: Shield,
+  request: JsonRequest,
+  requestHash: string,
+): JsonResponse<ValidateBatchResult> {
+  const results = (request.transactions as BatchTransaction[]).map((item) =>
+    validateBatchItem(shield, item, request.structuredMessages),
+  );
+  return successResponse({ results, summary: summarize(results) }, requestHash);
+}
+
+// Reasons a batch item could not be checked at all, as opposed to failing
+// validation. An unknown yield asked to be lenient about fails validation
+const ERRORED_REASONS = new Set<ReasonCode | undefined>([
+  'INVALID_REQUEST',
+  'YIELD_NOT_FOUND',
+  'MALFORMED_TRANSACTION',
+  'MALFORMED_NUMERIC',
+  'INTERNAL_ERROR',
+]);
+
+function summarize(results: ValidateResult[]): BatchSummary {
+  const summary = {
+    total: results.length,
+    valid: 0,
+    invalid: 0,
+    warned: 0,
+    errored: 0,
+  };
+  for (const result of results) {
+    if (result.isValid) {
+      summary.valid++;
+      if (result.warnings?.length) summary.warned++;
+    } else if (
+      ERRORED_REASONS.has(result.reasonCode) &&
+      !result.warnings?.some(({ code }) => code === 'UNKNOWN_YIELD')
+    ) {
+      summary.errored++;
+    } else {
+      summary.invalid++;
+    }
+  }
+  return summary;
+}
+
+// Each item is validated in isolation: a failure on one entry never affects
+// the results of the others.
+function validateBatchItem(
+  shield: Shield,
+  item: BatchTransaction,
+  structuredMessages: boolean | undefined,
+): ValidateResult {
+  try {
+    const result = shield.validate({
+      yieldId: item.yieldId,
+      unsignedTransaction: item.unsignedTransaction,
+      userAddress: item.userAddress,
+      args: item.args,
+      context: item.context,
+      riskThreshold: item.riskThreshold,
+      policy: item.policy,
+      strict: item.strict,
+      strictSeverities: item.strictSeverities,
+      expectedAmount: item.expectedAmount,
+      expectedAmountToken: item.expectedAmountToken,
+      amountToleranceBps: item.amountToleranceBps,
+      amountTolerance: item.amountTolerance,
+      expectedNonce: item.expectedNonce,
+      includeTiming: item.includeTiming,
+      locale: item.locale,
+      structuredMessages,
+      expectedMemo: item.expectedMemo,
+      observe: item.observe,
+      beneficiaryAddress: item.beneficiaryAddress,
+      actualBytecodeHash: item.actualBytecodeHash,
+      lenientUnknownYield: item.lenientUnknownYield,
+      contractOverrides: item.contractOverrides,
+      addressLookupTables: item.addressLookupTables,
+    });
+
+    return toValidateResult(result);
+  } catch {
+    return {
+      isValid: false,
+      reason: 'An unexpected error occurred while validating transaction',
+      reasonCode: 'INTERNAL_ERROR',
+      warnings: [],
+    };
+  }
+}
+
+function toValidateResult(result: ValidationResult): ValidateResult {
+  return {
+    isValid: result.isValid,
+    reason: result.reason,
+    reasonCode: result.reasonCode,
+    reasonMessage: result.reasonMessage,
+    details: result.details,
+    detectedType: result.detectedType,
+    detectedTypes: result.detectedTypes,
+    yieldId: result.yieldId,
+    expectedRecipient: result.expectedRecipient,
+    expectedRecipients: result.expectedRecipients,
+    contractOverride: result.contractOverride,
+    contractDeployments: result.contractDeployments,
+    warnings: result.warnings ?? [],
+    riskScore: result.riskScore,
+    riskLevel: result.riskLevel,
+    decoded: result.decoded,
+    simulation: result.simulation,
+    wrapper: result.wrapper,
+    safeTxHash: result.safeTxHash,
+    multicall: result.multicall,
+    subResults: result.subResults?.map(toValidateResult),
+    amount: result.amount,
+    amountDelta: result.amountDelta,
+    claimedPositions: result.claimedPositions,
+    positionOwnershipVerified: result.positionOwnershipVerified,
+    positions: result.positions,
+    bridgeLeg: result.bridgeLeg,
+    stakingLeg: result.stakingLeg?.map(toValidateResult),
+    emptyCalldata: result.emptyCalldata,
+    transaction: result.transaction,
+    recoveredAddress: result.recoveredAddress,
+    recoveredAddresses: result.recoveredAddresses,
+    signatureValid: result.signatureValid,
+    timing: result.timing,
+    resolvedRecipient: result.resolvedRecipient,
+    yieldName: result.yieldName,
+    yield: result.yield,
+    matchedRule: result.matchedRule,
+    fingerprint: result.fingerprint,
+    blockNumber: result.blockNumber,
+    summary: result.summary,
+    locale: result.locale,
+    memo: result.memo,
+    deadline: result.deadline,
+    lock: result.lock,
+    compound: result.compound,
+    wouldReject: result.wouldReject,
+    wouldRejectReason: result.wouldRejectReason,
+    wouldRejectReasonCode: result.wouldRejectReasonCode,
+  };
+}
+
+// Decoding is informational: it reports decoded: null instead of failing
+function handleDecode(
+  shield: Shield,
+  request: JsonRequest,
+  requestHash: string,
+): JsonResponse<DecodeTransactionResult> {
+  let result: DecodeTransactionResult;
+  try {
+    result = shield.decode({
+      unsignedTransaction: request.unsignedTransaction!,
+      yieldId: request.yieldId,
+      includeSignature: request.includeSignature,
+    });
+  } catch {
+    result = {
+      decoded: null,
+      reason: 'An unexpected error occurred while decoding transaction',
+    };
+  }
+
+  return successResponse(result, requestHash);
+}
+
+function handleIsSupported(
+  shield: Shield,
+  request: JsonRequest,
+  requestHash: string,
+): JsonResponse<IsSupportedResult> {
+  return successResponse(
+    {
+      supported: shield.isSupported(request.yieldId!),
+      yieldId: request.yieldId!,
+    },
+    requestHash,
+  );
+}
+
+function handleGetSupportedYieldIds(
+  shield: Shield,
+  request: JsonRequest,
+  requestHash: string,
+): JsonResponse<GetSupportedYieldIdsResult> {
+  const yields = shield.getSupportedYields(request.chainId);
+  const registryHash = createHash('sha256')
+    .update(JSON.stringify(yields))
+    .digest('hex');
+
+  // The client already holds this list, so spare sending it again
+  if (request.ifNoneMatch === registryHash) {
+    return successResponse(
+      { yieldIds: [], yields: [], registryHash, notModified: true },
+      requestHash,
+    );
+  }
+
+  let offset = 0;
+  if (request.pageToken !== undefined) {
+    const page = readPageToken(request.pageToken);
+    if (!page || page.offset > yields.length) {
+      return errorResponse(
+        'INVALID_PAGE_TOKEN',
+        'Invalid pageToken',
+        requestHash,
+        { field: 'pageToken' },
+      );
+    }
+    if (page.registryHash !== registryHash) {
+      return errorResponse(
+        'INVALID_PAGE_TOKEN',
+        'The list of yields changed since pageToken was issued; start again without it',
+        requestHash,
+        { field: 'pageToken' },
+      );
+    }
+    offset = page.offset;
+  }
+
+  // Without a pageSize, everything from the page token on is one page
+  const end = offset + (request.pageSize ?? yields.length);
+  const page = yields.slice(offset, end);
+  return successResponse(
+    {
+      yieldIds: page.map(({ yieldId }) => yieldId),
+      yields: page,
+      registryHash,
+      ...(end < yields.length && {
+        nextPageToken: createPageToken({ offset: end, registryHash }),
+      }),
+    },
+    requestHash,
+  );
+}
+
+interface PageToken {
+  offset: number;
+  registryHash: string; // Of the list the token pages through
+}
+
+// Page tokens are opaque to clients: base64url JSON of where the next page
+// starts, in the list since changed if registryHash no longer matches
+function createPageToken(page: PageToken): string {
+  return Buffer.from(JSON.stringify(page)).toString('base64url');
+}
+
+function readPageToken(token: string): PageToken | undefined {
+  try {
+    const page = JSON.parse(Buffer.from(token, 'base64url').toString('utf8'));
+    return Number.isSafeInteger(page?.offset) &&
+      page.offset > 0 &&
+      typeof page.registryHash === 'string'
+      ? { offset: page.offset, registryHash: page.registryHash }
+      : undefined;
+  } catch {
+    return undefined;
+  }
+}
+
+function handleGetYieldCapabilities(
+  shield: Shield,
+  request: JsonRequest,
+  requestHash: string,
+): JsonResponse<GetYieldCapabilitiesResult> {
+  const capabilities = shield.getYieldCapabilities(request.yieldId!);
+  if (!capabilities) {
+    return errorResponse(
+      'YIELD_NOT_FOUND',
+      `Unknown yield ID: ${request.yieldId}`,
+      requestHash,
+    );
+  }
+
+  return successResponse(capabilities, requestHash);
+}
+
+function handleGetYieldAbi(
+  shield: Shield,
+  request: JsonRequest,
+  requestHash: string,
+): JsonResponse<GetYieldAbiResult> {
+  const functions = shield.getYieldAbi(
+    request.yieldId!,
+    request.transactionType,
+  );
+  if (!functions) {
+    return errorResponse(
+      'YIELD_NOT_FOUND',
+      `Unknown yield ID: ${request.yieldId}`,
+      requestHash,
+    );
+  }
+
+  return successResponse({ yieldId: request.yieldId!, functions }, requestHash);
+}
+
+// Unknown ids are listed rather than failing the others
+function handleGetYields(
+  shield: Shield,
+  request: JsonRequest,
+  requestHash: string,
+): JsonResponse<GetYieldsResult> {
+  const result: GetYieldsResult = { yields: [], unknown: [] };
+  for (const yieldId of request.yieldIds!) {
+    const capabilities = shield.getYieldCapabilities(yieldId);
+    if (capabilities) {
+      result.yields.push(capabilities);
+    } else {
+      result.unknown.push(yieldId);
+    }
+  }
+  return successResponse(result, requestHash);
+}
+
+function handleDetectYields(
+  shield: Shield,
+  request: JsonRequest,
+  requestHash: string,
+): JsonResponse<DetectYieldsResult> {
+  const matches = shield.detectYields(
+    request.unsignedTransaction!,
+    request.chainId,
+  );
+  return successResponse(
+    { yieldIds: matches.map(({ yieldId }) => yieldId), matches },
+    requestHash,
+  );
+}
+
+function handleGetVersion(
+  shield: Shield,
+  requestHash: string,
+  profile: ShieldProfile | undefined,
+): JsonResponse<GetVersionResult> {
+  return successResponse(
+    {
+      ...shield.getVersion(),
+      ...(isDefined(profile) && {
+        profile: {
+          ...(isDefined(profile.name) && { name: profile.name }),
+          hash: getProfileHash(profile),
+        },
+      }),
+    },
+    requestHash,
+  );
+}
+
+// The registry lives in the process, not the request, so reloading it is
+// up to the caller of the handler
+function handleReloadRegistry(
+  options: JsonHandlerOptions,
+  requestHash: string,
+): JsonResponse<ReloadRegistryResult> {
+  if (!options.reloadRegistry) {
+    return errorResponse(
+      'RELOAD_UNAVAILABLE',
+      'This process has no registry file to reload; start it with --registry',
+      requestHash,
+    );
+  }
+
+  try {
+    return successResponse(options.reloadRegistry(), requestHash);
+  } catch (error) {
+    return errorResponse(
+      'RELOAD_FAILED',
+      error instanceof Error ? error.message : String(error),
+      requestHash,
+    );
+  }
+}
+
+function handleGetSchema(
+  request: JsonRequest,
+  requestHash: string,
+): JsonResponse<GetSchemaResult> {
+  return successResponse(getJsonSchemas(request.apiVersion), requestHash);
+}
+
+function handleListOperations(
+  requestHash: string,
+): JsonResponse<ListOperationsResult> {
+  return successResponse({ operations: listOperations() }, requestHash);
+}
+
+function handleAttest(requestHash: string): JsonResponse<AttestResult> {
+  try {
+    return successResponse(getAttestation(), requestHash);
+  } catch (error) {
+    return errorResponse(
+      'ATTESTATION_UNAVAILABLE',
+      error instanceof Error ? error.message : String(error),
+      requestHash,
+    );
+  }
+}
+
+const READY: HealthResult = { ready: true };
+
+/**
+ * Whether a process handling requests with options is ready to, as health
+ * requests report it. Reads options only, so that it is cheap enough to
+ * poll.
+ */
+export function getHealth(
+  options: Pick<JsonHandlerOptions, 'health'>,
+): HealthResult {
+  return options.health ?? READY;
+}
+
 // Helper functions for consistent response formatting
 function successResponse<T>(
   result: T,
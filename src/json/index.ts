@@ -1,12 +1,47 @@
-export { handleJsonRequest } from './handler';
+export {
+  handleJsonRequest,
+  handleJsonRequestAsync,
+  parseRegistryOverride,
+  parseProfile,
+  getHealth,
+} from './handler';
 export { MAX_INPUT_SIZE } from './constants';
+export { ValidationCache } from './validation-cache';
+export type { ValidationCacheOptions } from './validation-cache';
 export type {
   JsonRequest,
   JsonResponse,
   JsonSuccessResponse,
   JsonErrorResponse,
   ValidateResult,
+  ExplainValidationResult,
+  ValidateBatchResult,
+  BatchSummary,
+  BatchTransaction,
+  FlowTransaction,
+  ValidateFlowResult,
+  ValidateUserOperationResult,
+  ValidateSendCallsResult,
+  CompareIntentResult,
+  PreflightResult,
+  DecodeTransactionResult,
   IsSupportedResult,
   GetSupportedYieldIdsResult,
+  GetYieldCapabilitiesResult,
+  GetYieldAbiResult,
+  GetYieldsResult,
+  DetectYieldsResult,
+  GetVersionResult,
   ErrorCode,
+  JsonHandlerOptions,
+  ReloadRegistryResult,
+  CheckRegistryResult,
+  GetSchemaResult,
+  ListOperationsResult,
+  HealthResult,
+  AttestResult,
+  AuditRecord,
+  ProfileInfo,
+  ShieldProfile,
+  OperationInfo,
 } from './types';
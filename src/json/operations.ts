@@ -0,0 +1,196 @@
+import { operationRequirements, requestSchema } from './schema';
+import type { JsonRequest, OperationInfo } from './types';
+
+type Field = keyof JsonRequest;
+
+// What validate and the operations built on it take besides the
+// transaction, as getValidationFields reads them
+const VALIDATION_FIELDS: Field[] = [
+  'userAddress',
+  'args',
+  'context',
+  'riskThreshold',
+  'policy',
+  'strict',
+  'strictSeverities',
+  'expectedAmount',
+  'expectedAmountToken',
+  'amountToleranceBps',
+  'amountTolerance',
+  'expectedNonce',
+  'includeTiming',
+  'includeSignature',
+  'locale',
+  'expectedMemo',
+  'observe',
+  'beneficiaryAddress',
+  'actualBytecodeHash',
+  'lenientUnknownYield',
+  'contractOverrides',
+  'addressLookupTables',
+];
+
+// Those validateFlow, validateUserOperation and validateSendCalls apply to
+// every step, and compareIntent to its one transaction
+const FLOW_FIELDS: Field[] = [
+  'userAddress',
+  'args',
+  'context',
+  'riskThreshold',
+  'policy',
+  'strict',
+  'strictSeverities',
+];
+
+// A Record, so that an operation added to JsonRequest does not compile
+// until it is described here
+const OPERATIONS: Record<
+  JsonRequest['operation'],
+  { description: string; optionalFields: Field[] }
+> = {
+  validate: {
+    description: 'Validate a transaction',
+    optionalFields: [
+      'rawTransaction', // In place of unsignedTransaction
+      'yieldIds', // In place of yieldId
+      'accountNumber', // With a signed Cosmos rawTransaction
+      ...VALIDATION_FIELDS,
+      'expectedRecipientEns',
+      'simulate',
+      'checkNonce',
+      'rpcUrl',
+      'blockNumber',
+      'registryOverride',
+      'responseFields',
+      'includeAudit',
+    ],
+  },
+  explain: {
+    description: 'Validate a transaction and trace the outcome of every check',
+    optionalFields: [...VALIDATION_FIELDS, 'registryOverride'],
+  },
+  validateBatch: {
+    description: 'Validate many transactions in one call',
+    optionalFields: ['registryOverride'],
+  },
+  decode: {
+    description: 'Describe a transaction without validating it',
+    optionalFields: ['yieldId', 'includeSignature', 'registryOverride'],
+  },
+  isSupported: {
+    description: 'Check if a yield is supported',
+    optionalFields: ['registryOverride'],
+  },
+  getSupportedYieldIds: {
+    description: 'List all supported yields, or those on one chain',
+    optionalFields: [
+      'chainId',
+      'ifNoneMatch',
+      'pageSize',
+      'pageToken',
+      'registryOverride',
+    ],
+  },
+  getYieldCapabilities: {
+    description: 'Describe what a yield accepts',
+    optionalFields: ['registryOverride'],
+  },
+  getYieldAbi: {
+    description: "List the contract functions a yield's transactions call",
+    optionalFields: ['transactionType', 'registryOverride'],
+  },
+  getYields: {
+    description: 'Describe what each of a list of yields accepts',
+    optionalFields: ['registryOverride'],
+  },
+  detectYields: {
+    description: 'List the yields a transaction matches',
+    optionalFields: ['chainId', 'registryOverride'],
+  },
+  validateTypedData: {
+    description: 'Validate a permit the user is asked to sign',
+    optionalFields: [
+      'policy',
+      'strict',
+      'strictSeverities',
+      'registryOverride',
+    ],
+  },
+  validateFlow: {
+    description: 'Validate an ordered flow, such as approve then deposit',
+    optionalFields: [...FLOW_FIELDS, 'registryOverride'],
+  },
+  validateUserOperation: {
+    description: 'Validate the calls of an ERC-4337 user operation',
+    optionalFields: [...FLOW_FIELDS, 'paymasters', 'registryOverride'],
+  },
+  validateSendCalls: {
+    description: 'Validate the calls of an EIP-5792 wallet_sendCalls batch',
+    optionalFields: [...FLOW_FIELDS, 'registryOverride'],
+  },
+  compareIntent: {
+    description: 'Check that a transaction does what the user intended',
+    optionalFields: [...FLOW_FIELDS, 'registryOverride'],
+  },
+  preflight: {
+    description: 'Check a transaction would be allowed before building it',
+    optionalFields: [
+      'userAddress',
+      'amount',
+      'chainId',
+      'policy',
+      'registryOverride',
+    ],
+  },
+  getVersion: {
+    description: 'Identify the build and registry snapshot',
+    optionalFields: ['registryOverride'],
+  },
+  reloadRegistry: {
+    description: 'Reload the registry file of a serve or HTTP process',
+    optionalFields: [],
+  },
+  checkRegistry: {
+    description: 'Check the loaded registry and any override for mistakes',
+    optionalFields: ['registryOverride'],
+  },
+  getSchema: {
+    description: 'Return JSON Schema documents for requests and responses',
+    optionalFields: [],
+  },
+  listOperations: {
+    description: 'List the operations this build supports',
+    optionalFields: [],
+  },
+  health: {
+    description: 'Report whether the process is ready to validate',
+    optionalFields: [],
+  },
+  attest: {
+    description: 'Report the SHA-256 of the file the process runs',
+    optionalFields: [],
+  },
+};
+
+/**
+ * The operations the request schema accepts, in its order, with the fields
+ * each requires and takes. Every request also takes requestId, echoRequest
+ * and structuredMessages.
+ */
+export function listOperations(): OperationInfo[] {
+  const names = requestSchema.properties.operation
+    .enum as JsonRequest['operation'][];
+  return names.map((name) => ({
+    name,
+    description: OPERATIONS[name].description,
+    requiredFields: [...operationRequirements[name]],
+    optionalFields: [...OPERATIONS[name].optionalFields],
+  }));
+}
+
+// The fields besides those it requires that operation takes
+export function getOptionalFields(
+  operation: JsonRequest['operation'],
+): readonly Field[] {
+  return OPERATIONS[operation].optionalFields;
+}
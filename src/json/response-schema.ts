@@ -0,0 +1,719 @@
+import { RiskLevel, TransactionType } from '../types';
+import type { ReasonCode, WarningCode } from '../types';
+import type { ErrorCode, JsonRequest } from './types';
+import { requestSchema } from './schema';
+import { isDefined } from '../utils/validation';
+
+// Records rather than arrays, so that the compiler flags a code added to
+// its type but not here, or the other way round
+const REASON_CODES: Record<ReasonCode, true> = {
+  INVALID_REQUEST: true,
+  YIELD_NOT_FOUND: true,
+  YIELD_PAUSED: true,
+  OPERATION_NOT_SUPPORTED_FOR_YIELD: true,
+  CHAIN_ID_MISMATCH: true,
+  MALFORMED_TRANSACTION: true,
+  MALFORMED_NUMERIC: true,
+  MALFORMED_RAW_TRANSACTION: true,
+  INVALID_GAS_FIELDS: true,
+  SENDER_MISMATCH: true,
+  SIGNATURE_INVALID: true,
+  SIGNATURE_SENDER_MISMATCH: true,
+  APPROVAL_SPENDER_MISMATCH: true,
+  APPROVAL_INSUFFICIENT_FOR_DEPOSIT: true,
+  APPROVAL_EXCEEDS_STAKE: true,
+  UNEXPECTED_PROXY_UPGRADE: true,
+  PRIVILEGED_FUNCTION_CALL: true,
+  REWARD_RECIPIENT_MISMATCH: true,
+  WITHDRAWAL_RECIPIENT_MISMATCH: true,
+  AMOUNT_MISMATCH: true,
+  INTENT_MISMATCH: true,
+  NONCE_MISMATCH: true,
+  NONCE_CHECK_FAILED: true,
+  RECIPIENT_ENS_MISMATCH: true,
+  ENS_RESOLUTION_FAILED: true,
+  RECIPIENT_NOT_A_CONTRACT: true,
+  CODE_CHECK_FAILED: true,
+  BYTECODE_MISMATCH: true,
+  UNSTAKE_EXCEEDS_BALANCE: true,
+  BALANCE_CHECK_FAILED: true,
+  CLAIM_POSITION_NOT_OWNED: true,
+  POSITION_CHECK_FAILED: true,
+  ALT_RESOLUTION_REQUIRED: true,
+  ALT_RESOLUTION_FAILED: true,
+  AMOUNT_ABOVE_LIMIT: true,
+  AMOUNT_BELOW_MINIMUM: true,
+  MISSING_MEMO: true,
+  MEMO_MISMATCH: true,
+  UNEXPECTED_NATIVE_VALUE: true,
+  CALLDATA_TOO_LARGE: true,
+  SELECTOR_NOT_ALLOWED: true,
+  BRIDGE_DESTINATION_MISMATCH: true,
+  BRIDGE_RECIPIENT_MISMATCH: true,
+  BRIDGE_MESSAGE_INVALID: true,
+  DELEGATION_TARGET_NOT_ALLOWED: true,
+  AUTHORITY_MISMATCH: true,
+  BENEFICIARY_MISMATCH: true,
+  DEADLINE_IN_PAST: true,
+  LOCK_DURATION_OUT_OF_RANGE: true,
+  RECIPIENT_MISMATCH: true,
+  SELECTOR_MISMATCH: true,
+  TOKEN_MISMATCH: true,
+  NAKED_TRANSFER_NOT_SUPPORTED: true,
+  CONTRACT_TYPE_NOT_SUPPORTED: true,
+  PALLET_NOT_ALLOWED: true,
+  MODULE_NOT_ALLOWED: true,
+  UNEXPECTED_VALIDATOR: true,
+  COMPOUND_DESTINATION_MISMATCH: true,
+  NO_MATCHING_PATTERN: true,
+  AMBIGUOUS_PATTERN: true,
+  NESTED_MULTISIG: true,
+  UNTRUSTED_FORWARDER: true,
+  FORWARD_SIGNATURE_MISMATCH: true,
+  MULTICALL_CALL_MISSING: true,
+  MULTICALL_CALL_INVALID: true,
+  MULTICALL_VALUE_MISMATCH: true,
+  CONTRACT_BLOCKED: true,
+  CONTRACT_NOT_ALLOWED: true,
+  DELEGATECALL_BLOCKED: true,
+  RISK_THRESHOLD_EXCEEDED: true,
+  STRICT_MODE_WARNING: true,
+  FLOW_STEP_INVALID: true,
+  ALL_CANDIDATES_FAILED: true,
+  UNSUPPORTED_ACCOUNT_CALL: true,
+  TYPED_DATA_INVALID: true,
+  SIMULATION_UNSUPPORTED: true,
+  SIMULATION_FAILED: true,
+  SIMULATION_REVERTED: true,
+  SIMULATION_NO_BALANCE_CHANGE: true,
+  INTERNAL_ERROR: true,
+};
+
+const WARNING_CODES: Record<WarningCode, true> = {
+  INFINITE_APPROVAL: true,
+  HIGH_GAS_LIMIT: true,
+  UNKNOWN_RECIPIENT: true,
+  SENDER_NOT_VERIFIED: true,
+  LONG_DEADLINE: true,
+  ACCESS_LIST_UNEXPECTED_ADDRESS: true,
+  UNKNOWN_PAYMASTER: true,
+  DELEGATECALL_USED: true,
+  NONCE_TOO_LOW: true,
+  NONCE_GAP: true,
+  EIP7702_DELEGATION: true,
+  LOW_SLIPPAGE_PROTECTION: true,
+  UNPROTECTED_REPLAY: true,
+  IMPLEMENTATION_CHANGE: true,
+  UNSTAKE_NEAR_FULL: true,
+  LOCK_MAXED: true,
+  UNKNOWN_YIELD: true,
+  YIELD_PAUSED: true,
+  YIELD_DEPRECATED: true,
+  CONTRACT_PAUSED: true,
+  RECENTLY_DEPLOYED_CONTRACT: true,
+  UNKNOWN_CAPABILITY: true,
+  NON_ATOMIC_BATCH: true,
+};
+
+const ERROR_CODES: Record<ErrorCode, true> = {
+  PARSE_ERROR: true,
+  EMPTY_INPUT: true,
+  INPUT_TIMEOUT: true,
+  INPUT_TOO_LARGE: true,
+  SCHEMA_VALIDATION_ERROR: true,
+  MISSING_REQUIRED_FIELD: true,
+  MISSING_REQUEST_ID: true,
+  YIELD_NOT_FOUND: true,
+  UNSUPPORTED_API_VERSION: true,
+  SIMULATION_UNAVAILABLE: true,
+  RELOAD_UNAVAILABLE: true,
+  RELOAD_FAILED: true,
+  ATTESTATION_UNAVAILABLE: true,
+  INVALID_PAGE_TOKEN: true,
+  INTERNAL_ERROR: true,
+};
+
+const OPERATIONS: Record<JsonRequest['operation'], true> = {
+  validate: true,
+  explain: true,
+  validateBatch: true,
+  decode: true,
+  isSupported: true,
+  getSupportedYieldIds: true,
+  getYieldCapabilities: true,
+  getYieldAbi: true,
+  getYields: true,
+  detectYields: true,
+  validateTypedData: true,
+  validateFlow: true,
+  validateUserOperation: true,
+  validateSendCalls: true,
+  compareIntent: true,
+  preflight: true,
+  getVersion: true,
+  reloadRegistry: true,
+  checkRegistry: true,
+  getSchema: true,
+  listOperations: true,
+  health: true,
+  attest: true,
+};
+
+const JSON_SCHEMA_DRAFT = 'http://json-schema.org/draft-07/schema#';
+
+const ref = (name: string) => ({ $ref: `#/definitions/${name}` });
+const list = (items: object) => ({ type: 'array', items });
+const STRING = { type: 'string' };
+const STRINGS = list(STRING);
+const OBJECT = { type: 'object' };
+const COUNT = { type: 'integer', minimum: 0 };
+
+const validationWarningSchema = {
+  type: 'object',
+  required: ['code', 'message', 'severity'],
+  properties: {
+    code: ref('WarningCode'),
+    message: STRING,
+    messageLocalized: STRING,
+    details: OBJECT,
+    severity: { type: 'string', enum: ['info', 'warning', 'critical'] },
+  },
+};
+
+// Fields are listed, not closed: later releases may add some
+const validateResultSchema = {
+  type: 'object',
+  // warnings is only left out when responseFields does not name it
+  required: ['isValid'],
+  properties: {
+    isValid: { type: 'boolean' },
+    reason: STRING,
+    reasonCode: ref('ReasonCode'),
+    reasonMessage: {
+      type: 'object',
+      required: ['code', 'message'],
+      properties: {
+        code: ref('ReasonCode'),
+        message: STRING,
+        messageLocalized: STRING,
+      },
+    },
+    details: OBJECT,
+    detectedType: ref('DetectedType'),
+    detectedTypes: list(ref('DetectedType')),
+    yieldId: STRING,
+    expectedRecipient: STRING,
+    expectedRecipients: STRINGS,
+    contractOverride: {
+      type: 'object',
+      required: ['registered', 'address'],
+      properties: { registered: STRING, address: STRING },
+    },
+    contractDeployments: OBJECT,
+    warnings: list(ref('ValidationWarning')),
+    riskScore: { type: 'number', minimum: 0, maximum: 100 },
+    riskLevel: { type: 'string', enum: Object.values(RiskLevel) },
+    decoded: OBJECT,
+    simulation: OBJECT,
+    wrapper: OBJECT,
+    safeTxHash: STRING,
+    multicall: OBJECT,
+    subResults: list(ref('ValidateResult')),
+    amount: OBJECT,
+    amountDelta: STRING,
+    claimedPositions: OBJECT,
+    positionOwnershipVerified: { type: 'boolean' },
+    positions: list({
+      type: 'object',
+      required: ['positionId'],
+      properties: { positionId: STRING, subCall: COUNT, owner: STRING },
+    }),
+    bridgeLeg: OBJECT,
+    stakingLeg: list(ref('ValidateResult')),
+    emptyCalldata: { type: 'boolean' },
+    transaction: OBJECT,
+    recoveredAddress: STRING,
+    recoveredAddresses: STRINGS,
+    signatureValid: { type: 'boolean' },
+    timing: OBJECT,
+    resolvedRecipient: OBJECT,
+    yieldName: STRING,
+    yield: {
+      type: 'object',
+      required: ['name', 'protocol', 'network'],
+      properties: { name: STRING, protocol: STRING, network: STRING },
+    },
+    matchedRule: {
+      type: 'object',
+      required: ['id', 'description'],
+      properties: { id: STRING, description: STRING },
+    },
+    fingerprint: STRING,
+    blockNumber: COUNT,
+    summary: STRING,
+    locale: STRING,
+    memo: STRING,
+    deadline: {
+      type: 'object',
+      required: ['timestamp'],
+      properties: { timestamp: STRING, iso: STRING },
+    },
+    lock: {
+      type: 'object',
+      required: [
+        'unlockTime',
+        'durationSeconds',
+        'minLockSeconds',
+        'maxLockSeconds',
+      ],
+      properties: {
+        unlockTime: STRING,
+        unlockTimeIso: STRING,
+        durationSeconds: { type: 'integer' }, // Negative once passed
+        minLockSeconds: COUNT,
+        maxLockSeconds: COUNT,
+      },
+    },
+    compound: {
+      type: 'object',
+      required: ['restaked'],
+      properties: { claimed: OBJECT, restaked: OBJECT },
+    },
+    wouldReject: { type: 'boolean' },
+    wouldRejectReason: STRING,
+    wouldRejectReasonCode: ref('ReasonCode'),
+    cached: { type: 'boolean' },
+    trace: list({
+      type: 'object',
+      required: ['check', 'status', 'detail'],
+      properties: {
+        check: STRING,
+        status: { type: 'string', enum: ['pass', 'fail', 'warn', 'skip'] },
+        detail: STRING,
+      },
+    }),
+  },
+};
+
+// What a validate request's responseFields may name
+export const VALIDATE_RESULT_FIELDS = Object.keys(
+  validateResultSchema.properties,
+);
+
+const flowResultSchema = {
+  type: 'object',
+  required: ['isValid', 'steps'],
+  properties: {
+    isValid: { type: 'boolean' },
+    reason: STRING,
+    reasonCode: ref('ReasonCode'),
+    details: OBJECT,
+    detectedType: ref('DetectedType'),
+    detectedTypes: list(ref('DetectedType')),
+    paymaster: STRING,
+    atomicRequired: { type: 'boolean' },
+    warnings: list(ref('ValidationWarning')),
+    steps: list(ref('ValidateResult')),
+  },
+};
+
+const versionInfoSchema = {
+  type: 'object',
+  required: [
+    'version',
+    'gitCommit',
+    'buildDate',
+    'supportedApiVersions',
+    'deprecatedApiVersions',
+    'registry',
+  ],
+  properties: {
+    version: STRING,
+    gitCommit: STRING,
+    buildDate: STRING,
+    supportedApiVersions: STRINGS,
+    deprecatedApiVersions: STRINGS,
+    registry: OBJECT,
+    profile: OBJECT,
+  },
+};
+
+// The result each operation answers with, by operation
+const RESULT_DEFINITIONS: Record<JsonRequest['operation'], string> = {
+  validate: 'ValidateResult',
+  explain: 'ValidateResult',
+  validateBatch: 'ValidateBatchResult',
+  decode: 'DecodeTransactionResult',
+  isSupported: 'IsSupportedResult',
+  getSupportedYieldIds: 'GetSupportedYieldIdsResult',
+  getYieldCapabilities: 'GetYieldCapabilitiesResult',
+  getYieldAbi: 'GetYieldAbiResult',
+  getYields: 'GetYieldsResult',
+  detectYields: 'DetectYieldsResult',
+  validateTypedData: 'ValidateResult',
+  validateFlow: 'ValidateFlowResult',
+  validateUserOperation: 'ValidateFlowResult',
+  validateSendCalls: 'ValidateFlowResult',
+  compareIntent: 'CompareIntentResult',
+  preflight: 'PreflightResult',
+  getVersion: 'GetVersionResult',
+  reloadRegistry: 'ReloadRegistryResult',
+  checkRegistry: 'CheckRegistryResult',
+  getSchema: 'GetSchemaResult',
+  listOperations: 'ListOperationsResult',
+  health: 'HealthResult',
+  attest: 'AttestResult',
+};
+
+const definitions = {
+  Operation: { type: 'string', enum: Object.keys(OPERATIONS) },
+  DetectedType: { type: 'string', enum: Object.values(TransactionType) },
+  ReasonCode: { type: 'string', enum: Object.keys(REASON_CODES) },
+  WarningCode: { type: 'string', enum: Object.keys(WARNING_CODES) },
+  ErrorCode: { type: 'string', enum: Object.keys(ERROR_CODES) },
+  ValidationWarning: validationWarningSchema,
+  ValidateResult: validateResultSchema,
+  ValidateBatchResult: {
+    type: 'object',
+    required: ['results', 'summary'],
+    properties: {
+      results: list(ref('ValidateResult')),
+      summary: {
+        type: 'object',
+        required: ['total', 'valid', 'invalid', 'warned', 'errored'],
+        properties: {
+          total: COUNT,
+          valid: COUNT,
+          invalid: COUNT,
+          warned: COUNT,
+          errored: COUNT,
+        },
+      },
+    },
+  },
+  ValidateFlowResult: flowResultSchema,
+  CompareIntentResult: {
+    type: 'object',
+    required: [
+      'match',
+      'actionMatch',
+      'amountMatch',
+      'recipientMatch',
+      'validation',
+    ],
+    properties: {
+      match: { type: 'boolean' },
+      actionMatch: { type: 'boolean' },
+      amountMatch: { type: 'boolean' },
+      recipientMatch: { type: 'boolean' },
+      reason: STRING,
+      reasonCode: ref('ReasonCode'),
+      details: OBJECT,
+      validation: ref('ValidateResult'),
+    },
+  },
+  PreflightResult: {
+    type: 'object',
+    required: ['allowed', 'warnings'],
+    properties: {
+      allowed: { type: 'boolean' },
+      reason: STRING,
+      reasonCode: ref('ReasonCode'),
+      details: OBJECT,
+      warnings: list(ref('ValidationWarning')),
+      chainId: STRING,
+      expectedRecipient: STRING,
+      amount: OBJECT,
+    },
+  },
+  DecodeTransactionResult: {
+    type: 'object',
+    required: ['decoded'],
+    properties: {
+      decoded: { anyOf: [OBJECT, { type: 'null' }] },
+      reason: STRING,
+    },
+  },
+  IsSupportedResult: {
+    type: 'object',
+    required: ['supported', 'yieldId'],
+    properties: { supported: { type: 'boolean' }, yieldId: STRING },
+  },
+  GetSupportedYieldIdsResult: {
+    type: 'object',
+    required: ['yieldIds', 'yields', 'registryHash'],
+    properties: {
+      yieldIds: STRINGS,
+      yields: list(OBJECT),
+      registryHash: STRING,
+      notModified: { const: true },
+      nextPageToken: STRING,
+    },
+  },
+  GetYieldCapabilitiesResult: {
+    type: 'object',
+    required: [
+      'yieldId',
+      'name',
+      'protocol',
+      'network',
+      'supportedTypes',
+      'supportsPartialAmounts',
+      'chainId',
+      'contracts',
+      'status',
+    ],
+    properties: {
+      yieldId: STRING,
+      name: STRING,
+      protocol: STRING,
+      network: STRING,
+      supportedTypes: list(ref('DetectedType')),
+      supportsPartialAmounts: { type: 'boolean' },
+      chainId: STRING,
+      contracts: STRINGS,
+      status: { type: 'string', enum: ['active', 'paused', 'deprecated'] },
+    },
+  },
+  GetYieldAbiResult: {
+    type: 'object',
+    required: ['yieldId', 'functions'],
+    properties: { yieldId: STRING, functions: list(OBJECT) },
+  },
+  GetYieldsResult: {
+    type: 'object',
+    required: ['yields', 'unknown'],
+    properties: {
+      yields: list(ref('GetYieldCapabilitiesResult')),
+      unknown: STRINGS,
+    },
+  },
+  DetectYieldsResult: {
+    type: 'object',
+    required: ['yieldIds', 'matches'],
+    properties: { yieldIds: STRINGS, matches: list(OBJECT) },
+  },
+  GetVersionResult: versionInfoSchema,
+  ReloadRegistryResult: {
+    type: 'object',
+    required: ['added', 'removed', 'registry'],
+    properties: { added: STRINGS, removed: STRINGS, registry: OBJECT },
+  },
+  CheckRegistryResult: {
+    type: 'object',
+    required: ['isValid', 'yieldCount', 'problems'],
+    properties: {
+      isValid: { type: 'boolean' },
+      yieldCount: COUNT,
+      problems: list({
+        type: 'object',
+        required: ['severity', 'code', 'yieldId', 'message'],
+        properties: {
+          severity: { type: 'string', enum: ['error', 'warning'] },
+          code: STRING,
+          yieldId: STRING,
+          message: STRING,
+        },
+      }),
+    },
+  },
+  GetSchemaResult: {
+    type: 'object',
+    required: ['apiVersion', 'request', 'response', 'resultDefinitions'],
+    properties: {
+      apiVersion: STRING,
+      request: OBJECT,
+      response: OBJECT,
+      resultDefinitions: OBJECT,
+    },
+  },
+  ListOperationsResult: {
+    type: 'object',
+    required: ['operations'],
+    properties: {
+      operations: list({
+        type: 'object',
+        required: ['name', 'description', 'requiredFields', 'optionalFields'],
+        properties: {
+          name: ref('Operation'),
+          description: STRING,
+          requiredFields: STRINGS,
+          optionalFields: STRINGS,
+        },
+      }),
+    },
+  },
+  HealthResult: {
+    type: 'object',
+    required: ['ready'],
+    properties: { ready: { type: 'boolean' }, reason: STRING },
+  },
+  AttestResult: {
+    type: 'object',
+    required: ['path', 'kind', 'sha256', 'checksum'],
+    properties: {
+      path: STRING,
+      kind: { type: 'string', enum: ['binary', 'script'] },
+      sha256: { type: 'string', pattern: '^[0-9a-f]{64}$' },
+      checksum: {
+        type: 'object',
+        required: ['status'],
+        properties: {
+          status: { type: 'string', enum: ['match', 'mismatch', 'absent'] },
+          path: STRING,
+        },
+      },
+    },
+  },
+};
+
+const SHA256 = { type: 'string', pattern: '^[0-9a-f]{64}$' };
+
+const metaSchema = {
+  type: 'object',
+  required: ['requestHash', 'stableHash'],
+  properties: {
+    requestHash: STRING,
+    stableHash: SHA256,
+    warnings: list({
+      type: 'object',
+      required: ['code', 'message'],
+      properties: {
+        code: { type: 'string', enum: ['API_VERSION_DEPRECATED'] },
+        message: STRING,
+      },
+    }),
+    audit: {
+      type: 'object',
+      required: [
+        'timestamp',
+        'transactionHash',
+        'verdict',
+        'registryHash',
+        'version',
+        'recordHash',
+      ],
+      properties: {
+        timestamp: STRING,
+        requestId: STRING,
+        yieldId: STRING,
+        userAddress: STRING,
+        transactionHash: SHA256,
+        verdict: { type: 'string', enum: ['valid', 'invalid'] },
+        reasonCode: ref('ReasonCode'),
+        registryHash: SHA256,
+        registryOverrideHash: SHA256,
+        version: STRING,
+        recordHash: SHA256,
+      },
+    },
+  },
+};
+
+interface FieldSchema {
+  $ref?: string;
+  properties?: Record<string, FieldSchema>;
+  items?: FieldSchema;
+}
+
+/**
+ * result with its fields in the order the response schema lists them for
+ * operation, at every level the schema describes. Fields it does not list
+ * follow in alphabetical order; free-form objects, such as details, keep
+ * their own order.
+ */
+export function orderResultFields(
+  operation: JsonRequest['operation'],
+  result: unknown,
+): unknown {
+  const name = RESULT_DEFINITIONS[operation];
+  return isDefined(name) ? orderFields(result, ref(name)) : result;
+}
+
+function orderFields(value: unknown, schema: FieldSchema | undefined): unknown {
+  const resolved = isDefined(schema?.$ref)
+    ? (definitions as Record<string, FieldSchema>)[
+        schema.$ref.slice('#/definitions/'.length)
+      ]
+    : schema;
+  if (Array.isArray(value)) {
+    return value.map((item) => orderFields(item, resolved?.items));
+  }
+  const properties = resolved?.properties;
+  if (typeof value !== 'object' || value === null || !properties) {
+    return value;
+  }
+
+  const fields = value as Record<string, unknown>;
+  const listed = Object.keys(properties).filter((key) => key in fields);
+  const unlisted = Object.keys(fields)
+    .filter((key) => !Object.hasOwn(properties, key))
+    .sort();
+  return Object.fromEntries(
+    [...listed, ...unlisted].map((key) => [
+      key,
+      orderFields(fields[key], properties[key]),
+    ]),
+  );
+}
+
+/**
+ * JSON Schema documents of the requests the JSON interface accepts and the
+ * responses it returns for apiVersion, for generating bindings and
+ * checking payloads. The response schema's definitions hold the result of
+ * each operation, named by resultDefinitions, and the authoritative lists
+ * of operations, detected types, reason, warning and error codes.
+ */
+export function getJsonSchemas(apiVersion: string) {
+  const version = { const: apiVersion };
+  return {
+    apiVersion,
+    request: {
+      $schema: JSON_SCHEMA_DRAFT,
+      title: 'ShieldRequest',
+      ...requestSchema,
+      properties: { ...requestSchema.properties, apiVersion: version },
+    },
+    response: {
+      $schema: JSON_SCHEMA_DRAFT,
+      title: 'ShieldResponse',
+      oneOf: [
+        {
+          type: 'object',
+          required: ['ok', 'apiVersion', 'result', 'meta'],
+          properties: {
+            ok: { const: true },
+            apiVersion: version,
+            result: OBJECT,
+            meta: metaSchema,
+            requestId: STRING,
+            normalizedRequest: OBJECT,
+          },
+        },
+        {
+          type: 'object',
+          required: ['ok', 'apiVersion', 'error', 'meta'],
+          properties: {
+            ok: { const: false },
+            apiVersion: version,
+            error: {
+              type: 'object',
+              required: ['code', 'message'],
+              properties: {
+                code: ref('ErrorCode'),
+                message: STRING,
+                messageLocalized: STRING,
+                details: {},
+              },
+            },
+            meta: metaSchema,
+            requestId: STRING,
+            normalizedRequest: OBJECT,
+          },
+        },
+      ],
+      definitions,
+    },
+    resultDefinitions: RESULT_DEFINITIONS,
+  };
+}
@@ -1,3 +1,441 @@
+import { TransactionType } from '../types';
+import { SUPPORTED_API_VERSIONS } from '../version';
+
+// Shared sub-schemas for the validator inputs
+const argsSchema = {
+  type: 'object',
+  additionalProperties: false, // Security: reject unknown fields
+  properties: {
+    // Currently used by Tron
+    validatorAddress: { type: 'string', maxLength: 128 },
+    validatorAddresses: {
+      type: 'array',
+      items: { type: 'string', maxLength: 128 },
+      maxItems: 100,
+    },
+
+    // Future use - include for forward compatibility
+    amount: { type: 'string', maxLength: 78 }, // Max uint256 is 78 digits
+    tronResource: { type: 'string', enum: ['BANDWIDTH', 'ENERGY'] },
+    providerId: { type: 'string', maxLength: 256 },
+    duration: { type: 'number', minimum: 0 },
+    inputToken: { type: 'string', maxLength: 128 },
+    subnetId: { type: 'number', minimum: 0 },
+    feeConfigurationId: { type: 'string', maxLength: 256 },
+    cosmosPubKey: { type: 'string', maxLength: 256 },
+    tezosPubKey: { type: 'string', maxLength: 256 },
+    nominatorAddress: { type: 'string', maxLength: 128 },
+    nftIds: {
+      type: 'array',
+      items: { type: 'string', maxLength: 256 },
+      maxItems: 100,
+    },
+  },
+};
+
+const contextSchema = {
+  type: 'object',
+  additionalProperties: false,
+  properties: {
+    feeConfiguration: {
+      type: 'array',
+      maxItems: 100,
+      items: {
+        type: 'object',
+        additionalProperties: false,
+        properties: {
+          depositFeeBps: { type: 'number', minimum: 0, maximum: 10000 },
+          feeRecipientAddress: { type: 'string', maxLength: 128 },
+          allocatorVaultAddress: { type: 'string', maxLength: 128 },
+        },
+      },
+    },
+  },
+};
+
+// Caller contract policy, layered on top of the built-in rules
+const contractListSchema = {
+  type: 'array',
+  items: { type: 'string', minLength: 1, maxLength: 128 },
+  maxItems: 1000,
+};
+
+// Whole units of a token, e.g. "32" or "0.5"
+const wholeUnitsSchema = {
+  type: 'string',
+  pattern: '^[0-9]+(\\.[0-9]+)?$',
+  maxLength: 100,
+};
+const amountLimitsSchema = {
+  type: 'object',
+  additionalProperties: false,
+  properties: {
+    minAmount: wholeUnitsSchema,
+    maxAmount: wholeUnitsSchema,
+  },
+};
+
+const policySchema = {
+  type: 'object',
+  additionalProperties: false,
+  properties: {
+    allowedContracts: contractListSchema,
+    blockedContracts: contractListSchema,
+    blockDelegateCall: { type: 'boolean' },
+    trustedForwarders: contractListSchema,
+    maxDeadlineSeconds: {
+      type: 'integer',
+      minimum: 0,
+      maximum: Number.MAX_SAFE_INTEGER,
+    },
+    minContractAgeSeconds: {
+      type: 'integer',
+      minimum: 0,
+      maximum: Number.MAX_SAFE_INTEGER,
+    },
+    maxCalldataBytes: {
+      type: 'integer',
+      minimum: 0,
+      maximum: Number.MAX_SAFE_INTEGER,
+    },
+    maxSlippageBps: { type: 'integer', minimum: 0, maximum: 10000 },
+    maxApprovalExcessBps: {
+      type: 'integer',
+      minimum: 0,
+      maximum: Number.MAX_SAFE_INTEGER,
+    },
+    amountLimits: {
+      type: 'object',
+      maxProperties: 100,
+      additionalProperties: amountLimitsSchema,
+    },
+  },
+};
+
+// EIP-712 payload for validateTypedData, as passed to eth_signTypedData_v4
+const typedDataSchema = {
+  type: 'object',
+  required: ['domain', 'types', 'primaryType', 'message'],
+  additionalProperties: false,
+  properties: {
+    domain: {
+      type: 'object',
+      additionalProperties: false,
+      properties: {
+        name: { type: 'string', maxLength: 256 },
+        version: { type: 'string', maxLength: 64 },
+        chainId: {
+          oneOf: [
+            { type: 'integer', minimum: 0 },
+            { type: 'string', pattern: '^[0-9]{1,78}$' },
+          ],
+        },
+        verifyingContract: { type: 'string', maxLength: 128 },
+        salt: { type: 'string', maxLength: 66 },
+      },
+    },
+    types: {
+      type: 'object',
+      maxProperties: 32,
+      additionalProperties: {
+        type: 'array',
+        maxItems: 64,
+        items: {
+          type: 'object',
+          required: ['name', 'type'],
+          additionalProperties: false,
+          properties: {
+            name: { type: 'string', maxLength: 256 },
+            type: { type: 'string', maxLength: 256 },
+          },
+        },
+      },
+    },
+    primaryType: { type: 'string', minLength: 1, maxLength: 256 },
+    message: { type: 'object', maxProperties: 64 },
+  },
+};
+
+// Valid transactions scoring at or above this are rejected
+const riskThresholdSchema = { type: 'number', minimum: 1, maximum: 100 };
+
+// The warning severities strict mode rejects
+const strictSeveritiesSchema = {
+  type: 'array',
+  minItems: 1,
+  maxItems: 3,
+  uniqueItems: true,
+  items: { type: 'string', enum: ['info', 'warning', 'critical'] },
+};
+
+// Base units, as a decimal string; 78 digits covers uint256
+const expectedAmountSchema = {
+  type: 'string',
+  pattern: '^[0-9]+$',
+  maxLength: 78,
+};
+const expectedAmountTokenSchema = {
+  type: 'string',
+  minLength: 1,
+  maxLength: 128,
+};
+const amountToleranceBpsSchema = {
+  type: 'integer',
+  minimum: 0,
+  maximum: 10000,
+};
+const expectedNonceSchema = {
+  type: 'integer',
+  minimum: 0,
+  maximum: Number.MAX_SAFE_INTEGER,
+};
+
+// A vault of a registry override, in the format of the embedded registry
+const evmAddressSchema = { type: 'string', pattern: '^0x[0-9a-fA-F]{40}$' };
+// keccak256 of a contract's runtime code
+const bytecodeHashSchema = { type: 'string', pattern: '^0x[0-9a-fA-F]{64}$' };
+// Canonical, as in transferOwnership(address)
+const functionSignatureSchema = {
+  type: 'string',
+  pattern: '^[A-Za-z_$][A-Za-z0-9_$]*\\([A-Za-z0-9,()\\[\\]]*\\)$',
+  maxLength: 256,
+};
+const vaultRegistryEntrySchema = {
+  type: 'object',
+  required: [
+    'yieldId',
+    'address',
+    'chainId',
+    'protocol',
+    'network',
+    'inputTokenAddress',
+    'vaultTokenAddress',
+    'isWethVault',
+  ],
+  additionalProperties: false,
+  properties: {
+    yieldId: { type: 'string', minLength: 1, maxLength: 256 },
+    address: evmAddressSchema,
+    chainId: { type: 'integer', minimum: 1 },
+    protocol: { type: 'string', minLength: 1, maxLength: 64 },
+    network: { type: 'string', minLength: 1, maxLength: 64 },
+    inputTokenAddress: evmAddressSchema,
+    inputTokenSymbol: { type: 'string', maxLength: 32 },
+    inputTokenDecimals: { type: 'integer', minimum: 0, maximum: 255 },
+    vaultTokenAddress: evmAddressSchema,
+    isWethVault: { type: 'boolean' },
+    canEnter: { type: 'boolean' },
+    canExit: { type: 'boolean' },
+    status: { type: 'string', enum: ['active', 'paused', 'deprecated'] },
+    allocatorVaults: { type: 'array', items: evmAddressSchema, maxItems: 100 },
+    bytecodeHashes: {
+      type: 'object',
+      propertyNames: evmAddressSchema,
+      additionalProperties: bytecodeHashSchema,
+      maxProperties: 100,
+    },
+    privilegedFunctions: {
+      type: 'object',
+      propertyNames: evmAddressSchema,
+      additionalProperties: {
+        type: 'array',
+        items: functionSignatureSchema,
+        maxItems: 100,
+      },
+      maxProperties: 100,
+    },
+  },
+};
+
+// Vaults registered over the embedded registry, inline or from --registry
+export const registryOverrideSchema = {
+  type: 'object',
+  required: ['vaults'],
+  additionalProperties: false,
+  properties: {
+    version: { type: 'integer', minimum: 1 },
+    generatedAt: { type: 'string', maxLength: 64 },
+    vaults: { type: 'array', items: vaultRegistryEntrySchema, maxItems: 10000 },
+  },
+};
+
+// A BCP-47 language tag, e.g. 'en' or 'pt-BR'
+const localeSchema = {
+  type: 'string',
+  maxLength: 35,
+  pattern: '^[A-Za-z]{2,8}(-[A-Za-z0-9]{1,8})*$',
+};
+
+// Names of validate result fields, as responseFields lists them
+const responseFieldsSchema = {
+  type: 'array',
+  items: { type: 'string', minLength: 1, maxLength: 64 },
+  minItems: 1,
+  maxItems: 64,
+  uniqueItems: true,
+};
+
+// Defaults for every request of a process, from --profile or SHIELD_PROFILE
+export const profileSchema = {
+  type: 'object',
+  additionalProperties: false,
+  properties: {
+    name: { type: 'string', minLength: 1, maxLength: 128 },
+    strict: { type: 'boolean' },
+    policy: policySchema,
+    locale: localeSchema,
+    responseFields: responseFieldsSchema,
+  },
+};
+
+// Cosmos SDK chains cap memos at 256 characters by default
+const expectedMemoSchema = { type: 'string', minLength: 1, maxLength: 512 };
+
+// Where an EVM yield's contract moved, by yieldId
+const contractOverridesSchema = {
+  type: 'object',
+  propertyNames: { type: 'string', minLength: 1, maxLength: 256 },
+  additionalProperties: evmAddressSchema,
+  maxProperties: 16,
+};
+
+// A Solana address, base58
+const solanaAddressSchema = {
+  type: 'string',
+  pattern: '^[1-9A-HJ-NP-Za-km-z]{32,44}$',
+};
+
+// The addresses of the lookup tables a versioned Solana transaction loads
+// accounts from, by table address
+const addressLookupTablesSchema = {
+  type: 'object',
+  propertyNames: solanaAddressSchema,
+  additionalProperties: {
+    type: 'array',
+    items: solanaAddressSchema,
+    maxItems: 256,
+  },
+  maxProperties: 16,
+};
+
+// A single entry of a validateBatch request
+const batchTransactionSchema = {
+  type: 'object',
+  required: ['yieldId', 'unsignedTransaction'],
+  additionalProperties: false,
+  properties: {
+    yieldId: { type: 'string', minLength: 1, maxLength: 256 },
+    unsignedTransaction: { type: 'string', minLength: 1, maxLength: 102400 },
+    userAddress: { type: 'string', minLength: 1, maxLength: 128 },
+    args: argsSchema,
+    context: contextSchema,
+    riskThreshold: riskThresholdSchema,
+    policy: policySchema,
+    strict: { type: 'boolean' },
+    strictSeverities: strictSeveritiesSchema,
+    expectedAmount: expectedAmountSchema,
+    expectedAmountToken: expectedAmountTokenSchema,
+    amountToleranceBps: amountToleranceBpsSchema,
+    amountTolerance: expectedAmountSchema,
+    expectedNonce: expectedNonceSchema,
+    includeTiming: { type: 'boolean' },
+    locale: localeSchema,
+    expectedMemo: expectedMemoSchema,
+    observe: { type: 'boolean' },
+    beneficiaryAddress: { type: 'string', minLength: 1, maxLength: 128 },
+    actualBytecodeHash: bytecodeHashSchema,
+    lenientUnknownYield: { type: 'boolean' },
+    contractOverrides: contractOverridesSchema,
+    addressLookupTables: addressLookupTablesSchema,
+  },
+};
+
+// A single step of a validateFlow request. yieldId, userAddress and args
+// are set once on the request and apply to every step
+const flowTransactionSchema = {
+  type: 'object',
+  required: ['unsignedTransaction'],
+  additionalProperties: false,
+  properties: {
+    unsignedTransaction: { type: 'string', minLength: 1, maxLength: 102400 },
+  },
+};
+
+// ERC-4337 v0.6 UserOperation for validateUserOperation; integers are hex
+// quantities and byte fields hex strings, as in eth_sendUserOperation
+const hexQuantitySchema = { type: 'string', pattern: '^0x[0-9a-fA-F]{1,64}$' };
+const hexBytesSchema = {
+  type: 'string',
+  maxLength: 102400,
+  pattern: '^0x([0-9a-fA-F]{2})*$',
+};
+
+const userOperationSchema = {
+  type: 'object',
+  required: ['sender', 'nonce', 'callData'],
+  additionalProperties: false,
+  properties: {
+    sender: { type: 'string', minLength: 1, maxLength: 128 },
+    nonce: hexQuantitySchema,
+    initCode: hexBytesSchema,
+    callData: hexBytesSchema,
+    callGasLimit: hexQuantitySchema,
+    verificationGasLimit: hexQuantitySchema,
+    preVerificationGas: hexQuantitySchema,
+    maxFeePerGas: hexQuantitySchema,
+    maxPriorityFeePerGas: hexQuantitySchema,
+    paymasterAndData: hexBytesSchema,
+    signature: hexBytesSchema,
+  },
+};
+
+// EIP-5792 wallet_sendCalls params for validateSendCalls. Capabilities are
+// passed through as the wallet would receive them
+const capabilitiesSchema = { type: 'object', maxProperties: 32 };
+
+const sendCallsSchema = {
+  type: 'object',
+  required: ['calls'],
+  additionalProperties: false,
+  properties: {
+    version: { type: 'string', maxLength: 32 },
+    chainId: hexQuantitySchema,
+    from: { type: 'string', minLength: 1, maxLength: 128 },
+    atomicRequired: { type: 'boolean' },
+    calls: {
+      type: 'array',
+      minItems: 1,
+      maxItems: 256,
+      items: {
+        type: 'object',
+        required: ['to'],
+        additionalProperties: false,
+        properties: {
+          to: { type: 'string', minLength: 1, maxLength: 128 },
+          data: hexBytesSchema,
+          value: hexQuantitySchema,
+          capabilities: capabilitiesSchema,
+        },
+      },
+    },
+    capabilities: capabilitiesSchema,
+  },
+};
+
+// What the user asked for, for compareIntent to check the transaction
+// against
+const intentSchema = {
+  type: 'object',
+  required: ['action', 'yieldId'],
+  additionalProperties: false,
+  properties: {
+    action: { type: 'string', enum: Object.values(TransactionType) },
+    yieldId: { type: 'string', minLength: 1, maxLength: 256 },
+    amount: expectedAmountSchema,
+    token: expectedAmountTokenSchema,
+  },
+};
+
 // JSON Schema for request validation (Ajv format)
 export const requestSchema = {
   type: 'object',
@@ -6,11 +444,35 @@ export const requestSchema = {
   properties: {
     apiVersion: {
       type: 'string',
-      enum: ['1.0'], // Explicit version whitelist
+      enum: SUPPORTED_API_VERSIONS, // Explicit version whitelist
     },
     operation: {
       type: 'string',
-      enum: ['validate', 'isSupported', 'getSupportedYieldIds'],
+      enum: [
+        'validate',
+        'explain',
+        'validateBatch',
+        'decode',
+        'isSupported',
+        'getSupportedYieldIds',
+        'getYieldCapabilities',
+        'getYieldAbi',
+        'getYields',
+        'detectYields',
+        'validateTypedData',
+        'validateFlow',
+        'validateUserOperation',
+        'validateSendCalls',
+        'compareIntent',
+        'preflight',
+        'getVersion',
+        'reloadRegistry',
+        'checkRegistry',
+        'getSchema',
+        'listOperations',
+        'health',
+        'attest',
+      ],
     },
     yieldId: {
       type: 'string',
@@ -22,66 +484,171 @@ export const requestSchema = {
       minLength: 1,
       maxLength: 102400, // 100KB limit for transaction data
     },
+    // RLP-encoded alternative to unsignedTransaction, as 0x hex or base64
+    rawTransaction: {
+      type: 'string',
+      minLength: 1,
+      maxLength: 102400,
+    },
+    // The Cosmos signer's account number, to verify a signed rawTransaction
+    accountNumber: {
+      type: 'string',
+      pattern: '^[0-9]{1,20}$',
+    },
     userAddress: {
       type: 'string',
       minLength: 1,
       maxLength: 128,
     },
-    args: {
-      type: 'object',
-      additionalProperties: false, // Security: reject unknown fields
-      properties: {
-        // Currently used by Tron
-        validatorAddress: { type: 'string', maxLength: 128 },
-        validatorAddresses: {
-          type: 'array',
-          items: { type: 'string', maxLength: 128 },
-          maxItems: 100,
-        },
-
-        // Future use - include for forward compatibility
-        amount: { type: 'string', maxLength: 78 }, // Max uint256 is 78 digits
-        tronResource: { type: 'string', enum: ['BANDWIDTH', 'ENERGY'] },
-        providerId: { type: 'string', maxLength: 256 },
-        duration: { type: 'number', minimum: 0 },
-        inputToken: { type: 'string', maxLength: 128 },
-        subnetId: { type: 'number', minimum: 0 },
-        feeConfigurationId: { type: 'string', maxLength: 256 },
-        cosmosPubKey: { type: 'string', maxLength: 256 },
-        tezosPubKey: { type: 'string', maxLength: 256 },
-        nominatorAddress: { type: 'string', maxLength: 128 },
-        nftIds: {
-          type: 'array',
-          items: { type: 'string', maxLength: 256 },
-          maxItems: 100,
-        },
-      },
+    args: argsSchema,
+    context: contextSchema,
+    riskThreshold: riskThresholdSchema,
+    policy: policySchema,
+    // Reject valid transactions that carry any warning
+    strict: { type: 'boolean' },
+    strictSeverities: strictSeveritiesSchema,
+    expectedAmount: expectedAmountSchema,
+    expectedAmountToken: expectedAmountTokenSchema,
+    amountToleranceBps: amountToleranceBpsSchema,
+    amountTolerance: expectedAmountSchema,
+    expectedNonce: expectedNonceSchema,
+    includeTiming: { type: 'boolean' },
+    includeSignature: { type: 'boolean' },
+    includeAudit: { type: 'boolean' },
+    locale: localeSchema,
+    expectedMemo: expectedMemoSchema,
+    // Report what would be rejected as wouldReject, rejecting nothing
+    observe: { type: 'boolean' },
+    // Account a stake or deposit sent on its behalf must credit
+    beneficiaryAddress: { type: 'string', minLength: 1, maxLength: 128 },
+    // Hash of the code at the recipient, checked against the registry's
+    actualBytecodeHash: bytecodeHashSchema,
+    // Answer an unknown yieldId with an UNKNOWN_YIELD warning
+    lenientUnknownYield: { type: 'boolean' },
+    // Where a yield's contract moved, for this request only
+    contractOverrides: contractOverridesSchema,
+    // The lookup tables of a versioned Solana transaction
+    addressLookupTables: addressLookupTablesSchema,
+    expectedRecipientEns: { type: 'string', minLength: 3, maxLength: 255 },
+    // The validate result fields to answer with; isValid always is
+    responseFields: responseFieldsSchema,
+    typedData: typedDataSchema,
+    userOperation: userOperationSchema,
+    sendCalls: sendCallsSchema,
+    intent: intentSchema,
+    // What preflight checks before the transaction is built
+    detectedType: { type: 'string', enum: Object.values(TransactionType) },
+    amount: expectedAmountSchema,
+    paymasters: contractListSchema, // Expected to sponsor a userOperation
+    requestId: {
+      type: 'string',
+      minLength: 1,
+      maxLength: 256, // Opaque, echoed back on the response
     },
-    context: {
-      type: 'object',
-      additionalProperties: false,
-      properties: {
-        feeConfiguration: {
-          type: 'array',
-          maxItems: 100,
-          items: {
-            type: 'object',
-            additionalProperties: false,
-            properties: {
-              depositFeeBps: { type: 'number', minimum: 0, maximum: 10000 },
-              feeRecipientAddress: { type: 'string', maxLength: 128 },
-              allocatorVaultAddress: { type: 'string', maxLength: 128 },
-            },
-          },
-        },
-      },
+    // Answer with the request as Shield read it, for debugging
+    echoRequest: { type: 'boolean' },
+    structuredMessages: { type: 'boolean' },
+    // getSupportedYieldIds and detectYields filter, in the format of
+    // capabilities' chainId
+    chainId: {
+      type: 'string',
+      minLength: 1,
+      maxLength: 64,
+    },
+    // registryHash of a getSupportedYieldIds response the client holds
+    ifNoneMatch: {
+      type: 'string',
+      minLength: 1,
+      maxLength: 128,
+    },
+    // getSupportedYieldIds pagination. Pages are capped so that no response
+    // outgrows the buffers of proxies in between
+    pageSize: {
+      type: 'integer',
+      minimum: 1,
+      maximum: 1000,
+    },
+    pageToken: {
+      type: 'string',
+      minLength: 1,
+      maxLength: 256,
+    },
+    // Yields getYields describes, or validate tries in place of yieldId
+    yieldIds: {
+      type: 'array',
+      minItems: 1,
+      maxItems: 1000,
+      items: { type: 'string', minLength: 1, maxLength: 256 },
+    },
+    // getYieldAbi filter
+    transactionType: {
+      type: 'string',
+      enum: Object.values(TransactionType),
+    },
+    registryOverride: registryOverrideSchema,
+    simulate: { type: 'boolean' },
+    // Warn when the nonce is stale or skips ahead of the account's, per rpcUrl
+    checkNonce: { type: 'boolean' },
+    rpcUrl: {
+      type: 'string',
+      minLength: 1,
+      maxLength: 2048,
+      pattern: '^https?://', // SECURITY: No file:, data: or other schemes
+    },
+    // Pins what is read from rpcUrl to a block
+    blockNumber: {
+      type: 'integer',
+      minimum: 0,
+      maximum: Number.MAX_SAFE_INTEGER,
+    },
+    transactions: {
+      type: 'array',
+      minItems: 1,
+      maxItems: 256,
+    },
+  },
+  // validateFlow steps take a different shape than validateBatch entries
+  if: {
+    type: 'object',
+    properties: { operation: { const: 'validateFlow' } },
+  },
+  then: {
+    type: 'object',
+    properties: {
+      transactions: { type: 'array', items: flowTransactionSchema },
+    },
+  },
+  else: {
+    type: 'object',
+    properties: {
+      transactions: { type: 'array', items: batchTransactionSchema },
     },
   },
 };
 
 // Operation-specific required fields
 export const operationRequirements = {
-  validate: ['yieldId', 'unsignedTransaction', 'userAddress'],
+  validate: ['yieldId', 'unsignedTransaction'], // or rawTransaction
+  explain: ['yieldId', 'unsignedTransaction'],
+  validateBatch: ['transactions'],
+  decode: ['unsignedTransaction'], // yieldId is optional
   isSupported: ['yieldId'],
   getSupportedYieldIds: [],
+  getYieldCapabilities: ['yieldId'],
+  getYieldAbi: ['yieldId'],
+  getYields: ['yieldIds'],
+  detectYields: ['unsignedTransaction'],
+  validateTypedData: ['yieldId', 'typedData', 'userAddress'],
+  validateFlow: ['yieldId', 'transactions'],
+  validateUserOperation: ['yieldId', 'userOperation'],
+  validateSendCalls: ['yieldId', 'sendCalls'],
+  compareIntent: ['intent', 'unsignedTransaction'],
+  preflight: ['yieldId', 'detectedType'],
+  getVersion: [],
+  reloadRegistry: [],
+  checkRegistry: [],
+  getSchema: [],
+  listOperations: [],
+  health: [],
+  attest: [],
 };
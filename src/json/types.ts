@@ -1,22 +1,187 @@
-import type { ActionArguments, ValidationContext } from '../types';
+import type {
+  AbiFunction,
+  ActionArguments,
+  ValidationContext,
+  ValidationPolicy,
+  ValidationWarning,
+  LocalizedMessage,
+  WarningSeverity,
+  RiskLevel,
+  ReasonCode,
+  DecodeResult,
+  TypedData,
+  UserOperation,
+  SendCalls,
+  DecodedTransaction,
+  SimulationResult,
+  TransactionWrapper,
+  Multicall,
+  TransactionAmount,
+  RawTransactionFields,
+  ResolvedRecipient,
+  Deadline,
+  TransactionLock,
+  CompoundAmounts,
+  ContractDeployment,
+  ContractOverride,
+  ExplainEntry,
+  ValidationTiming,
+  VersionInfo,
+  RegistryCheckResult,
+  ClaimedPosition,
+  ClaimedPositions,
+  BridgeLeg,
+  YieldCapabilities,
+  YieldInfo,
+  MatchedRule,
+  SupportedYield,
+  TransactionIntent,
+  PreflightResult as CorePreflightResult,
+  TransactionType,
+  YieldMatch,
+} from '../types';
+import type { Logger } from '../logger';
+import type { VaultRegistryOverride } from '../validators/evm/erc4626';
+import type { ValidationCache } from './validation-cache';
 
 export interface JsonRequest {
   apiVersion: '1.0';
-  operation: 'validate' | 'isSupported' | 'getSupportedYieldIds';
+  operation:
+    | 'validate'
+    | 'explain'
+    | 'validateBatch'
+    | 'decode'
+    | 'isSupported'
+    | 'getSupportedYieldIds'
+    | 'getYieldCapabilities'
+    | 'getYieldAbi'
+    | 'getYields'
+    | 'detectYields'
+    | 'validateTypedData'
+    | 'validateFlow'
+    | 'validateUserOperation'
+    | 'validateSendCalls'
+    | 'compareIntent'
+    | 'preflight'
+    | 'getVersion'
+    | 'reloadRegistry'
+    | 'checkRegistry'
+    | 'getSchema'
+    | 'listOperations'
+    | 'health'
+    | 'attest';
   yieldId?: string;
   unsignedTransaction?: string;
+  rawTransaction?: string; // RLP-encoded alternative, validate only
+  accountNumber?: string; // Cosmos signer's, with a signed rawTransaction
   userAddress?: string;
   args?: ActionArguments;
   context?: ValidationContext;
+  riskThreshold?: number;
+  policy?: ValidationPolicy;
+  strict?: boolean;
+  strictSeverities?: WarningSeverity[]; // Those strict rejects, if not all
+  expectedAmount?: string; // Base units the user intends to move
+  expectedAmountToken?: string;
+  amountToleranceBps?: number;
+  amountTolerance?: string; // Base units, the larger of the two applies
+  expectedNonce?: number; // Fails with NONCE_MISMATCH when the nonce differs
+  includeTiming?: boolean; // Adds timing to validate results
+  // Adds decoded.selector and decoded.functionSignature to validate results
+  includeSignature?: boolean;
+  includeAudit?: boolean; // Adds the audit record to meta of validate
+  locale?: string; // BCP-47 tag of the language of messages, e.g. 'de-DE'
+  expectedMemo?: string; // Fails with MISSING_MEMO or MEMO_MISMATCH
+  observe?: boolean; // Never reject; report the verdict as wouldReject
+  // Account credited when userAddress stakes on its behalf
+  beneficiaryAddress?: string;
+  // Hash of the code at the recipient. Fails with BYTECODE_MISMATCH when
+  // the registry pins another
+  actualBytecodeHash?: string;
+  // Warn UNKNOWN_YIELD on a yieldId this release does not know
+  lenientUnknownYield?: boolean;
+  // Where a yield's contract moved, by yieldId, for this request only
+  contractOverrides?: Record<string, string>;
+  // The addresses of the lookup tables a versioned Solana transaction
+  // loads accounts from, by table address, in place of fetching them
+  addressLookupTables?: Record<string, string[]>;
+  // ENS name the user was shown as the recipient, resolved through rpcUrl
+  expectedRecipientEns?: string;
+  // The validate result fields to answer with, for smaller responses
+  responseFields?: string[];
+  typedData?: TypedData;
+  userOperation?: UserOperation;
+  paymasters?: string[];
+  sendCalls?: SendCalls; // EIP-5792 wallet_sendCalls params
+  intent?: TransactionIntent; // What compareIntent checks the transaction for
+  // The type of transaction preflight checks, and the base units it stakes
+  detectedType?: TransactionType;
+  amount?: string;
+  transactions?: BatchTransaction[] | FlowTransaction[];
+  requestId?: string;
+  echoRequest?: boolean; // Adds normalizedRequest to the response
+  // Reasons, warnings and errors with English and localized messages
+  structuredMessages?: boolean;
+  chainId?: string; // Narrows getSupportedYieldIds or detectYields to a chain
+  ifNoneMatch?: string; // registryHash of the getSupportedYieldIds list held
+  pageSize?: number; // Splits getSupportedYieldIds into pages of this size
+  pageToken?: string; // nextPageToken of the previous page
+  transactionType?: TransactionType; // Narrows getYieldAbi to one type
+  // The yields getYields describes, or validate tries in place of yieldId
+  yieldIds?: string[];
+  // Vaults registered over the built-in registry for this request only
+  registryOverride?: VaultRegistryOverride;
+  // Execute a validate request against rpcUrl; only handleJsonRequestAsync
+  // does this
+  simulate?: boolean;
+  // Fetch the sender's nonce from rpcUrl and warn when the transaction's is
+  // stale or skips ahead; also handleJsonRequestAsync only
+  checkNonce?: boolean;
+  rpcUrl?: string;
+  // Read state from rpcUrl at this block rather than the latest, so the
+  // validation can be reproduced
+  blockNumber?: number;
+}
+
+// A single transaction of a validateBatch request
+export interface BatchTransaction {
+  yieldId: string;
+  unsignedTransaction: string;
+  userAddress?: string;
+  args?: ActionArguments;
+  context?: ValidationContext;
+  riskThreshold?: number;
+  policy?: ValidationPolicy;
+  strict?: boolean;
+  strictSeverities?: WarningSeverity[]; // Those strict rejects, if not all
+  expectedAmount?: string; // Base units the user intends to move
+  expectedAmountToken?: string;
+  amountToleranceBps?: number;
+  amountTolerance?: string;
+  expectedNonce?: number;
+  includeTiming?: boolean;
+  locale?: string;
+  expectedMemo?: string;
+  observe?: boolean;
+  beneficiaryAddress?: string;
+  actualBytecodeHash?: string;
+  lenientUnknownYield?: boolean;
+  contractOverrides?: Record<string, string>;
+  addressLookupTables?: Record<string, string[]>;
+}
+
+// A single step of a validateFlow request, which carries everything else
+export interface FlowTransaction {
+  unsignedTransaction: string;
 }
 
 export interface JsonSuccessResponse<T> {
   ok: true;
   apiVersion: '1.0';
   result: T;
-  meta: {
-    requestHash: string; // SHA-256 of request for integrity verification
-  };
+  meta: ResponseMeta;
+  requestId?: string; // Echoed verbatim from the request
+  normalizedRequest?: JsonRequest; // The request as read, with echoRequest
 }
 
 export interface JsonErrorResponse {
@@ -25,34 +190,317 @@ export interface JsonErrorResponse {
   error: {
     code: ErrorCode;
     message: string;
+    messageLocalized?: string; // With structuredMessages and a locale
     details?: unknown;
   };
-  meta: {
-    requestHash: string;
-  };
+  meta: ResponseMeta;
+  requestId?: string;
+  normalizedRequest?: JsonRequest;
+}
+
+export interface ResponseMeta {
+  requestHash: string; // SHA-256 of request for integrity verification
+  // SHA-256 of the result or error without timings and display strings,
+  // for comparing responses across runs. Set on every response written
+  stableHash?: string;
+  warnings?: ProtocolWarning[]; // About the request itself, not its result
+  audit?: AuditRecord; // On validate responses, with includeAudit
+}
+
+// One validate decision, to retain for audits. Keys are always in this
+// order, and recordHash is the SHA-256 of the record's JSON without it, so
+// a stored record can be checked for tampering
+export interface AuditRecord {
+  timestamp: string; // ISO 8601, when the result was returned
+  requestId?: string;
+  yieldId?: string; // The one of yieldIds that passed, for candidates
+  userAddress?: string;
+  // SHA-256 of unsignedTransaction, or rawTransaction, as sent
+  transactionHash: string;
+  verdict: 'valid' | 'invalid';
+  reasonCode?: ReasonCode;
+  registryHash: string; // SHA-256 of the embedded registry
+  registryOverrideHash?: string; // Of the vaults registered over it
+  version: string; // Of the build, 'unknown' running from source
+  recordHash: string;
+}
+
+// A non-blocking concern about how the request was made
+export interface ProtocolWarning {
+  code: 'API_VERSION_DEPRECATED';
+  message: string;
 }
 
 export type JsonResponse<T> = JsonSuccessResponse<T> | JsonErrorResponse;
 
+export interface JsonHandlerOptions {
+  // Reject requests without a requestId (serve mode)
+  requireRequestId?: boolean;
+  // Receives one entry per response, and at debug level the checks behind
+  // validate results
+  logger?: Logger;
+  // Vaults registered over the built-in registry for every request, e.g.
+  // from --registry. A request's own registryOverride is merged over it
+  registryOverride?: VaultRegistryOverride;
+  // Answers reloadRegistry requests, e.g. by re-reading the --registry
+  // file; without it they fail with RELOAD_UNAVAILABLE
+  reloadRegistry?: () => ReloadRegistryResult;
+  // What health requests report; ready when left out, since the built-in
+  // registry is loaded and validated with the module
+  health?: HealthResult;
+  // Answers validate requests seen before from their earlier result. Left
+  // out, every request is validated
+  validationCache?: ValidationCache;
+  // Receives the audit record of every validate result, e.g. to append it
+  // to the --audit-log file
+  auditSink?: (record: AuditRecord) => void;
+  // Defaults for every request, e.g. from --profile
+  profile?: ShieldProfile;
+  // Bytes a request may take, MAX_INPUT_SIZE when left out, e.g. from
+  // --max-input-size. Larger ones fail with INPUT_TOO_LARGE
+  maxInputSize?: number;
+}
+
+// What a request leaves out is taken from the profile, for the operations
+// that take the field. Its own value replaces the profile's, and policy
+// limits are replaced one by one
+export interface ShieldProfile {
+  name?: string; // Reported by getVersion
+  strict?: boolean;
+  policy?: ValidationPolicy;
+  locale?: string;
+  responseFields?: string[]; // validate only
+}
+
 export type ErrorCode =
   | 'PARSE_ERROR' // Invalid JSON syntax
+  | 'EMPTY_INPUT' // No input, or only whitespace
+  | 'INPUT_TIMEOUT' // stdin did not end within --stdin-timeout
+  | 'INPUT_TOO_LARGE' // Input over maxInputSize bytes
   | 'SCHEMA_VALIDATION_ERROR' // Failed Ajv validation
   | 'MISSING_REQUIRED_FIELD' // Operation-specific required field missing
+  | 'MISSING_REQUEST_ID' // Serve mode request without a requestId
+  | 'YIELD_NOT_FOUND' // getYieldCapabilities/getYieldAbi for an unknown yieldId
+  | 'UNSUPPORTED_API_VERSION' // apiVersion this build does not speak
+  | 'SIMULATION_UNAVAILABLE' // simulate sent to the synchronous handler
+  | 'RELOAD_UNAVAILABLE' // reloadRegistry without a registry file to reload
+  | 'RELOAD_FAILED' // The registry file could not be read or parsed
+  | 'ATTESTATION_UNAVAILABLE' // The file the process runs could not be read
+  | 'INVALID_PAGE_TOKEN' // pageToken is malformed or of a list since changed
   | 'INTERNAL_ERROR'; // Unexpected error (should never happen)
 
 // Result types for each operation
 export interface ValidateResult {
   isValid: boolean;
   reason?: string;
+  reasonCode?: ReasonCode; // Stable counterpart of reason, to switch on
+  reasonMessage?: LocalizedMessage; // With structuredMessages
   details?: unknown;
   detectedType?: string;
+  detectedTypes?: string[]; // Every action it takes, in order
+  yieldId?: string; // The candidate of yieldIds that passed
+  expectedRecipient?: string;
+  expectedRecipients?: string[]; // Transactions calling several contracts
+  contractOverride?: ContractOverride; // Set when contractOverrides applied
+  // Set when policy.minContractAgeSeconds was checked, by contract
+  contractDeployments?: Record<string, ContractDeployment>;
+  // Always present, empty when none apply, unless responseFields leaves it
+  // out
+  warnings: ValidationWarning[];
+  riskScore?: number; // 0 (lowest) to 100 (highest)
+  riskLevel?: RiskLevel;
+  decoded?: DecodedTransaction; // Instructions that were validated (Solana)
+  simulation?: SimulationResult; // Only when simulate was requested
+  wrapper?: TransactionWrapper; // Set for Safe and forwarded calls
+  safeTxHash?: string; // Set for SafeTx typed data, the hash owners sign
+  multicall?: Multicall; // Set for multicalls, with the calls they batch
+  subResults?: ValidateResult[]; // One per multicall call, aligned by index
+  amount?: TransactionAmount; // What the transaction moves, when decoded
+  amountDelta?: string; // amount less expectedAmount, e.g. '-3'
+  claimedPositions?: ClaimedPositions; // Set on claims of positions
+  positionOwnershipVerified?: boolean; // Whether the user owns each
+  positions?: ClaimedPosition[]; // Each of them, for multicalls
+  bridgeLeg?: BridgeLeg; // Set on bridge-then-stake transactions
+  stakingLeg?: ValidateResult[]; // One per call made on the destination
+  emptyCalldata?: boolean; // A plain EVM transfer, detected as TRANSFER
+  transaction?: RawTransactionFields; // What rawTransaction decoded to
+  recoveredAddress?: string; // Signer of a signed rawTransaction
+  recoveredAddresses?: string[]; // Its Cosmos or Solana signers, payer first
+  signatureValid?: boolean; // Only set for signed rawTransactions
+  timing?: ValidationTiming; // Only when includeTiming was requested
+  resolvedRecipient?: ResolvedRecipient; // Set when an ENS name was checked
+  yieldName?: string; // Set with detectedType
+  yield?: YieldInfo; // Set with detectedType
+  matchedRule?: MatchedRule; // Why a valid transaction was trusted
+  fingerprint?: string; // Identifies a valid transaction across encodings
+  blockNumber?: number; // The block the request pinned state to
+  summary?: string; // For display only; rely on the structured fields
+  locale?: string; // What messages are written in, when locale was requested
+  memo?: string; // Set when the transaction carries one
+  deadline?: Deadline; // For permits and calls that expire
+  lock?: TransactionLock; // For vote-escrow locks that set an unlock time
+  compound?: CompoundAmounts; // Claimed and restaked rewards of a compound
+  // Observe mode only: what the verdict would have been
+  wouldReject?: boolean;
+  wouldRejectReason?: string;
+  wouldRejectReasonCode?: ReasonCode;
+  cached?: boolean; // Served from the validation cache
+}
+
+// trace lists every check validate ran, in order, with its outcome
+export interface ExplainValidationResult extends ValidateResult {
+  trace: ExplainEntry[];
+}
+
+// Results are aligned by index with the request's transactions
+export interface ValidateBatchResult {
+  results: ValidateResult[];
+  summary: BatchSummary;
+}
+
+// Counts of a batch's results. valid, invalid and errored add up to total;
+// warned counts the valid results that carry warnings
+export interface BatchSummary {
+  total: number;
+  valid: number;
+  invalid: number; // Checked, and failed validation
+  warned: number;
+  errored: number; // Could not be checked, e.g. a malformed transaction
+}
+
+// steps are aligned by index with the request's transactions
+export interface ValidateFlowResult {
+  isValid: boolean;
+  reason?: string; // e.g. APPROVAL_INSUFFICIENT_FOR_DEPOSIT
+  reasonCode?: ReasonCode;
+  details?: Record<string, unknown>;
+  steps: ValidateResult[];
+}
+
+// steps are aligned with the calls the userOperation's callData makes
+export interface ValidateUserOperationResult extends ValidateFlowResult {
+  detectedType?: string; // The last call's, e.g. SUPPLY
+  detectedTypes?: string[]; // Each call's, in order
+  paymaster?: string;
+  warnings: ValidationWarning[]; // Always present, empty when none apply
 }
 
+// steps are aligned with sendCalls.calls
+export interface ValidateSendCallsResult extends ValidateFlowResult {
+  detectedType?: string; // The last call's, e.g. SUPPLY
+  detectedTypes?: string[]; // Each call's, in order
+  atomicRequired: boolean;
+  warnings: ValidationWarning[]; // Always present, empty when none apply
+}
+
+// validation is the transaction's own validate result
+export interface CompareIntentResult {
+  match: boolean;
+  actionMatch: boolean;
+  amountMatch: boolean;
+  recipientMatch: boolean;
+  reason?: string; // e.g. INTENT_MISMATCH
+  reasonCode?: ReasonCode;
+  details?: Record<string, unknown>;
+  validation: ValidateResult;
+}
+
+export type PreflightResult = CorePreflightResult;
+
+// decoded is null, with a reason, when no known ABI matches. With
+// includeSignature, an EVM call then decodes to its selector alone
+export type DecodeTransactionResult = DecodeResult;
+
 export interface IsSupportedResult {
   supported: boolean;
   yieldId: string;
 }
 
 export interface GetSupportedYieldIdsResult {
+  yieldIds: string[]; // Empty when notModified
+  yields: SupportedYield[]; // Same order as yieldIds
+  registryHash: string; // SHA-256 of yields, changing whenever the list does
+  notModified?: true; // ifNoneMatch was registryHash
+  nextPageToken?: string; // Set while pages with pageSize remain
+}
+
+export type GetYieldCapabilitiesResult = YieldCapabilities;
+
+export interface GetYieldAbiResult {
+  yieldId: string;
+  functions: AbiFunction[]; // Empty for yields without contract calls
+}
+
+// yields is in the order of the request's yieldIds, and unknown lists the
+// ids of no supported yield, in the same order
+export interface GetYieldsResult {
+  yields: YieldCapabilities[];
+  unknown: string[];
+}
+
+// Empty when no yield matches; matches is in the same order as yieldIds
+export interface DetectYieldsResult {
   yieldIds: string[];
+  matches: YieldMatch[];
+}
+
+export interface GetVersionResult extends VersionInfo {
+  profile?: ProfileInfo; // Set when the handler applies a profile
+}
+
+export interface ProfileInfo {
+  name?: string;
+  hash: string; // SHA-256 of the profile's JSON
+}
+
+// Yields are those that appeared in or vanished from getSupportedYieldIds;
+// registry is what getVersion reports from now on
+export interface ReloadRegistryResult {
+  added: string[];
+  removed: string[];
+  registry: VersionInfo['registry'];
+}
+
+export type CheckRegistryResult = RegistryCheckResult;
+
+// JSON Schema documents for the request's apiVersion. resultDefinitions
+// names, per operation, the response definition its result matches
+export interface GetSchemaResult {
+  apiVersion: string;
+  request: Record<string, unknown>;
+  response: Record<string, unknown>;
+  resultDefinitions: Record<JsonRequest['operation'], string>;
+}
+
+// Fields are named as in JsonRequest; apiVersion and operation are left out
+export interface OperationInfo {
+  name: JsonRequest['operation'];
+  description: string;
+  requiredFields: string[];
+  optionalFields: string[];
+}
+
+// ready is false, with a reason, while the process cannot yet be relied on,
+// e.g. after a registry reload failed
+export interface HealthResult {
+  ready: boolean;
+  reason?: string;
+}
+
+// What the process runs: kind is 'binary' for a single executable binary,
+// whose path is the binary, and 'script' when node runs path. checksum is
+// the release's "<path>.sha256" file compared with sha256; 'absent' when
+// there is none, so the hash must be compared with a trusted one instead
+export interface AttestResult {
+  path: string;
+  kind: 'binary' | 'script';
+  sha256: string;
+  checksum:
+    | { status: 'match' | 'mismatch'; path: string }
+    | { status: 'absent' };
+}
+
+// Every operation the build supports, in the order getSchema lists them
+export interface ListOperationsResult {
+  operations: OperationInfo[];
 }
@@ -0,0 +1,75 @@
+import { getCacheKey, ValidationCache } from './validation-cache';
+import type { JsonRequest, ValidateResult } from './types';
+
+describe('ValidationCache', () => {
+  const result = (isValid: boolean): ValidateResult => ({
+    isValid,
+    warnings: [],
+  });
+
+  it('should evict the least recently used result', () => {
+    const cache = new ValidationCache({ maxEntries: 2, ttlMs: 60_000 });
+    cache.set('a', result(true));
+    cache.set('b', result(true));
+    cache.get('a');
+    cache.set('c', result(false));
+
+    expect(cache.size).toBe(2);
+    expect(cache.get('a')).toEqual(result(true));
+    expect(cache.get('b')).toBeUndefined();
+    expect(cache.get('c')).toEqual(result(false));
+  });
+
+  it('should drop results older than ttlMs', async () => {
+    const cache = new ValidationCache({ maxEntries: 10, ttlMs: 20 });
+    cache.set('a', result(true));
+
+    expect(cache.get('a')).toEqual(result(true));
+    await new Promise((resolve) => setTimeout(resolve, 30));
+    expect(cache.get('a')).toBeUndefined();
+    expect(cache.size).toBe(0);
+  });
+
+  it('should reject invalid options', () => {
+    expect(() => new ValidationCache({ maxEntries: 0, ttlMs: 1 })).toThrow(
+      'maxEntries must be a positive integer',
+    );
+    expect(() => new ValidationCache({ maxEntries: 1, ttlMs: 0 })).toThrow(
+      'ttlMs must be positive',
+    );
+  });
+});
+
+describe('getCacheKey', () => {
+  const request: JsonRequest = {
+    apiVersion: '1.0',
+    operation: 'validate',
+    yieldId: 'ethereum-eth-lido-staking',
+    unsignedTransaction: '{}',
+    policy: { maxSlippageBps: 100, blockedContracts: [] },
+  };
+
+  it('should ignore requestId and key order', () => {
+    const reordered = JSON.parse(
+      JSON.stringify({
+        policy: { blockedContracts: [], maxSlippageBps: 100 },
+        requestId: 'retry-1',
+        unsignedTransaction: '{}',
+        yieldId: 'ethereum-eth-lido-staking',
+        operation: 'validate',
+        apiVersion: '1.0',
+      }),
+    );
+
+    expect(getCacheKey(reordered)).toBe(getCacheKey(request));
+  });
+
+  it('should differ with any other field', () => {
+    expect(getCacheKey({ ...request, strict: true })).not.toBe(
+      getCacheKey(request),
+    );
+    expect(getCacheKey({ ...request, unsignedTransaction: '[]' })).not.toBe(
+      getCacheKey(request),
+    );
+  });
+});
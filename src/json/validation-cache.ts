@@ -0,0 +1,81 @@
+import { createHash } from 'crypto';
+import type { JsonRequest, ValidateResult } from './types';
+
+export interface ValidationCacheOptions {
+  maxEntries: number; // The least recently used entry is evicted past this
+  ttlMs: number; // How long a result is served for after it was computed
+}
+
+/**
+ * Validate results by request, for a long-running process that sees the
+ * same request again, e.g. on retries. Shield validates deterministically
+ * for a given registry, so entries are only as good as the registry they
+ * were computed with: clear the cache whenever it changes.
+ */
+export class ValidationCache {
+  private readonly entries = new Map<
+    string,
+    { result: ValidateResult; expiresAt: number }
+  >();
+
+  constructor(private readonly options: ValidationCacheOptions) {
+    if (!Number.isInteger(options.maxEntries) || options.maxEntries < 1) {
+      throw new Error('maxEntries must be a positive integer');
+    }
+    if (!(options.ttlMs > 0)) throw new Error('ttlMs must be positive');
+  }
+
+  get size(): number {
+    return this.entries.size;
+  }
+
+  get(key: string): ValidateResult | undefined {
+    const entry = this.entries.get(key);
+    if (entry === undefined) return undefined;
+
+    this.entries.delete(key);
+    if (entry.expiresAt <= Date.now()) return undefined;
+    this.entries.set(key, entry); // Now the most recently used
+    return entry.result;
+  }
+
+  set(key: string, result: ValidateResult): void {
+    this.entries.delete(key);
+    this.entries.set(key, {
+      result,
+      expiresAt: Date.now() + this.options.ttlMs,
+    });
+
+    // A Map iterates in insertion order, so the first key is the oldest
+    while (this.entries.size > this.options.maxEntries) {
+      this.entries.delete(this.entries.keys().next().value!);
+    }
+  }
+
+  clear(): void {
+    this.entries.clear();
+  }
+}
+
+/**
+ * The cache key of a validate request: the SHA-256 of the request without
+ * its requestId, with object keys sorted so that their order does not
+ * matter.
+ */
+export function getCacheKey(request: JsonRequest): string {
+  const keyed: Partial<JsonRequest> = { ...request };
+  delete keyed.requestId;
+  return createHash('sha256').update(canonicalJson(keyed)).digest('hex');
+}
+
+function canonicalJson(value: unknown): string {
+  if (Array.isArray(value)) return `[${value.map(canonicalJson).join(',')}]`;
+  if (typeof value !== 'object' || value === null) return JSON.stringify(value);
+
+  const object = value as Record<string, unknown>;
+  const members = Object.keys(object)
+    .sort()
+    .filter((key) => object[key] !== undefined)
+    .map((key) => `${JSON.stringify(key)}:${canonicalJson(object[key])}`);
+  return `{${members.join(',')}}`;
+}
@@ -0,0 +1,5 @@
+import { summarize } from '../summary';
+import type { MessageCatalog } from './types';
+
+// Shield writes its messages in English, so only the summary is listed
+export const en: MessageCatalog = { summarize };
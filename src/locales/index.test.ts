@@ -0,0 +1,147 @@
+import {
+  DEFAULT_LOCALE,
+  formatAmount,
+  getMessageCatalog,
+  localizeResult,
+  resolveLocale,
+  structureResult,
+} from '.';
+import type { MessageCatalog } from '.';
+import { ValidationResult } from '../types';
+
+describe('resolveLocale', () => {
+  it('should fall back to shorter tags, then English', () => {
+    expect(resolveLocale('en')).toBe('en');
+    expect(resolveLocale('en-GB')).toBe('en');
+    expect(resolveLocale('EN-us')).toBe('en');
+    expect(resolveLocale('fr-CA')).toBe(DEFAULT_LOCALE);
+    expect(resolveLocale(undefined)).toBe(DEFAULT_LOCALE);
+  });
+
+  it('should not resolve tags to object properties', () => {
+    expect(resolveLocale('constructor')).toBe(DEFAULT_LOCALE);
+  });
+});
+
+describe('getMessageCatalog', () => {
+  it('should summarize in English by default', () => {
+    expect(getMessageCatalog('xx').summarize).toBeDefined();
+  });
+});
+
+describe('formatAmount', () => {
+  it('should group and separate digits as the locale does', () => {
+    expect(formatAmount('1234.5', 'en-US')).toBe('1,234.5');
+    expect(formatAmount('1234.5', 'de-DE')).toBe('1.234,5');
+    expect(formatAmount('1234567.25', 'en-IN')).toBe('12,34,567.25');
+  });
+
+  it('should keep every digit and drop a trailing .0', () => {
+    expect(formatAmount('0.000000000000000001', 'en')).toBe(
+      '0.000000000000000001',
+    );
+    expect(formatAmount('12345678901234567890.0', 'en')).toBe(
+      '12,345,678,901,234,567,890',
+    );
+  });
+
+  it('should write unknown locales as in English', () => {
+    expect(formatAmount('1234.5', 'xx-zzzzzzzzz')).toBe('1,234.5');
+  });
+});
+
+describe('localizeResult', () => {
+  const catalog: MessageCatalog = {
+    reasons: { SENDER_MISMATCH: 'Absender stimmt nicht überein' },
+    warnings: { SENDER_NOT_VERIFIED: 'Absender nicht geprüft' },
+  };
+
+  it('should rewrite the messages the catalog has', () => {
+    const result: ValidationResult = {
+      isValid: false,
+      reason: 'Sender mismatch',
+      reasonCode: 'SENDER_MISMATCH',
+      warnings: [
+        { code: 'SENDER_NOT_VERIFIED', message: 'Sender not verified' },
+        { code: 'INFINITE_APPROVAL', message: 'Unlimited approval' },
+      ],
+    };
+
+    expect(localizeResult(result, catalog)).toEqual({
+      ...result,
+      reason: 'Absender stimmt nicht überein',
+      warnings: [
+        { code: 'SENDER_NOT_VERIFIED', message: 'Absender nicht geprüft' },
+        { code: 'INFINITE_APPROVAL', message: 'Unlimited approval' },
+      ],
+    });
+  });
+
+  it('should localize subResults and keep messages it has no code for', () => {
+    const result: ValidationResult = {
+      isValid: false,
+      reason: 'Multicall call invalid',
+      reasonCode: 'MULTICALL_CALL_INVALID',
+      subResults: [
+        {
+          isValid: false,
+          reason: 'Sender mismatch',
+          reasonCode: 'SENDER_MISMATCH',
+        },
+      ],
+    };
+
+    const localized = localizeResult(result, catalog);
+    expect(localized.reason).toBe('Multicall call invalid');
+    expect(localized.subResults?.[0].reason).toBe(
+      'Absender stimmt nicht überein',
+    );
+  });
+});
+
+describe('structureResult', () => {
+  const catalog: MessageCatalog = {
+    reasons: { SENDER_MISMATCH: 'Absender stimmt nicht überein' },
+  };
+  const result: ValidationResult = {
+    isValid: false,
+    reason: 'Sender mismatch',
+    reasonCode: 'SENDER_MISMATCH',
+    warnings: [{ code: 'SENDER_NOT_VERIFIED', message: 'Sender not verified' }],
+  };
+
+  it('should keep English messages and add the localized ones', () => {
+    const structured = structureResult(
+      { ...result, subResults: [result] },
+      catalog,
+    );
+
+    expect(structured.reason).toBe('Sender mismatch');
+    expect(structured.reasonMessage).toEqual({
+      code: 'SENDER_MISMATCH',
+      message: 'Sender mismatch',
+      messageLocalized: 'Absender stimmt nicht überein',
+    });
+    // Codes the catalog has no message for are localized as in English
+    expect(structured.warnings).toEqual([
+      {
+        code: 'SENDER_NOT_VERIFIED',
+        message: 'Sender not verified',
+        messageLocalized: 'Sender not verified',
+      },
+    ]);
+    expect(structured.subResults?.[0].reasonMessage?.messageLocalized).toBe(
+      'Absender stimmt nicht überein',
+    );
+  });
+
+  it('should leave out messageLocalized without a catalog', () => {
+    const structured = structureResult(result);
+
+    expect(structured.reasonMessage).toEqual({
+      code: 'SENDER_MISMATCH',
+      message: 'Sender mismatch',
+    });
+    expect(structured.warnings?.[0]).not.toHaveProperty('messageLocalized');
+  });
+});
@@ -0,0 +1,131 @@
+import { ValidationResult } from '../types';
+import { isDefined } from '../utils/validation';
+import { en } from './en';
+import type { MessageCatalog } from './types';
+
+export type { MessageCatalog } from './types';
+
+export const DEFAULT_LOCALE = 'en';
+
+// To add a locale, add its catalog here under its lowercase BCP-47 tag,
+// e.g. 'de' or 'pt-br'
+const CATALOGS: Record<string, MessageCatalog> = { en };
+
+/**
+ * The closest locale Shield has messages for: locale itself, then each
+ * shorter tag, e.g. 'pt' for 'pt-BR', then DEFAULT_LOCALE.
+ */
+export function resolveLocale(locale?: string): string {
+  const subtags = (locale ?? '').toLowerCase().split('-');
+  for (let length = subtags.length; length > 0; length--) {
+    const tag = subtags.slice(0, length).join('-');
+    if (Object.hasOwn(CATALOGS, tag)) return tag;
+  }
+  return DEFAULT_LOCALE;
+}
+
+export function getMessageCatalog(locale?: string): MessageCatalog {
+  return CATALOGS[resolveLocale(locale)];
+}
+
+/**
+ * normalized, a dot-decimal amount such as "1234.5", written the way
+ * locale writes numbers: "1,234.5" for 'en-US', "1.234,5" for 'de-DE'.
+ * Every digit is kept, and a whole amount loses its ".0". Locales Intl
+ * does not know are written as in English.
+ */
+export function formatAmount(normalized: string, locale: string): string {
+  const format = getNumberFormat(locale);
+  const [whole, fraction] = normalized.replace(/\.0$/, '').split('.');
+  const formattedWhole = format.format(BigInt(whole));
+  if (!isDefined(fraction)) return formattedWhole;
+
+  const decimal =
+    format.formatToParts(0.5).find((part) => part.type === 'decimal')
+      ?.value ?? '.';
+  const digits = fraction.replace(/[0-9]/g, (digit) =>
+    format.format(Number(digit)),
+  );
+  return `${formattedWhole}${decimal}${digits}`;
+}
+
+function getNumberFormat(locale: string): Intl.NumberFormat {
+  try {
+    return new Intl.NumberFormat(locale);
+  } catch {
+    return new Intl.NumberFormat(DEFAULT_LOCALE);
+  }
+}
+
+/**
+ * result with its reason, warnings and those of its subResults in the
+ * language of catalog, wherever catalog has a message for their code.
+ */
+export function localizeResult(
+  result: ValidationResult,
+  catalog: MessageCatalog,
+): ValidationResult {
+  const reason = isDefined(result.reasonCode)
+    ? catalog.reasons?.[result.reasonCode]
+    : undefined;
+  return {
+    ...result,
+    ...(isDefined(reason) ? { reason } : {}),
+    ...(isDefined(result.warnings)
+      ? {
+          warnings: result.warnings.map((warning) => ({
+            ...warning,
+            message: catalog.warnings?.[warning.code] ?? warning.message,
+          })),
+        }
+      : {}),
+    ...(isDefined(result.subResults)
+      ? {
+          subResults: result.subResults.map((subResult) =>
+            localizeResult(subResult, catalog),
+          ),
+        }
+      : {}),
+  };
+}
+
+/**
+ * result for structuredMessages: its reason, warnings and those of its
+ * subResults stay in English, the reason also as reasonMessage, and each
+ * gains messageLocalized in the language of catalog, when one is given,
+ * or in English where catalog has no message for its code.
+ */
+export function structureResult(
+  result: ValidationResult,
+  catalog?: MessageCatalog,
+): ValidationResult {
+  const localized = (message: string, translation?: string) =>
+    isDefined(catalog) ? { messageLocalized: translation ?? message } : {};
+  return {
+    ...result,
+    ...(isDefined(result.reasonCode) && isDefined(result.reason)
+      ? {
+          reasonMessage: {
+            code: result.reasonCode,
+            message: result.reason,
+            ...localized(result.reason, catalog?.reasons?.[result.reasonCode]),
+          },
+        }
+      : {}),
+    ...(isDefined(result.warnings)
+      ? {
+          warnings: result.warnings.map((warning) => ({
+            ...warning,
+            ...localized(warning.message, catalog?.warnings?.[warning.code]),
+          })),
+        }
+      : {}),
+    ...(isDefined(result.subResults)
+      ? {
+          subResults: result.subResults.map((subResult) =>
+            structureResult(subResult, catalog),
+          ),
+        }
+      : {}),
+  };
+}
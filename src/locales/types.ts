@@ -0,0 +1,22 @@
+import type { ErrorCode } from '../json/types';
+import type {
+  ReasonCode,
+  TransactionAmount,
+  TransactionType,
+  WarningCode,
+} from '../types';
+
+/**
+ * The messages of one locale. Codes left out keep Shield's English message,
+ * so a catalog can be filled in a code at a time.
+ */
+export interface MessageCatalog {
+  reasons?: Partial<Record<ReasonCode, string>>;
+  warnings?: Partial<Record<WarningCode, string>>;
+  errors?: Partial<Record<ErrorCode, string>>; // With structuredMessages
+  summarize?: (
+    type: TransactionType,
+    yieldName: string,
+    amount?: TransactionAmount,
+  ) => string;
+}
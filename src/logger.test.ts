@@ -0,0 +1,40 @@
+import { createJsonLogger, isLogLevel } from './logger';
+
+describe('createJsonLogger', () => {
+  it('should write one JSON object per entry', () => {
+    const lines: string[] = [];
+    const logger = createJsonLogger('info', (line) => lines.push(line));
+
+    logger.log('info', 'request handled', { requestId: 'req-1' });
+
+    expect(lines).toHaveLength(1);
+    expect(JSON.parse(lines[0])).toEqual({
+      time: expect.any(String),
+      level: 'info',
+      msg: 'request handled',
+      requestId: 'req-1',
+    });
+  });
+
+  it('should only write entries at its level or above', () => {
+    const lines: string[] = [];
+    const logger = createJsonLogger('warn', (line) => lines.push(line));
+
+    for (const level of ['error', 'warn', 'info', 'debug'] as const) {
+      logger.log(level, level);
+    }
+
+    expect(lines.map((line) => JSON.parse(line).level)).toEqual([
+      'error',
+      'warn',
+    ]);
+  });
+});
+
+describe('isLogLevel', () => {
+  it('should accept the four levels only', () => {
+    expect(isLogLevel('debug')).toBe(true);
+    expect(isLogLevel('verbose')).toBe(false);
+    expect(isLogLevel(undefined)).toBe(false);
+  });
+});
@@ -0,0 +1,42 @@
+export type LogLevel = 'error' | 'warn' | 'info' | 'debug';
+
+// Most severe first: a logger writes entries of its level and those above
+export const LOG_LEVELS: readonly LogLevel[] = [
+  'error',
+  'warn',
+  'info',
+  'debug',
+];
+
+export interface Logger {
+  log(level: LogLevel, msg: string, fields?: Record<string, unknown>): void;
+}
+
+export function isLogLevel(value: unknown): value is LogLevel {
+  return LOG_LEVELS.includes(value as LogLevel);
+}
+
+/**
+ * A Logger writing each entry at level or above as one line of JSON,
+ * { time, level, msg, ...fields }. Lines go to stderr unless write is
+ * given, so stdout carries nothing but responses.
+ */
+export function createJsonLogger(
+  level: LogLevel,
+  write: (line: string) => void = (line) => process.stderr.write(line + '\n'),
+): Logger {
+  const threshold = LOG_LEVELS.indexOf(level);
+  return {
+    log(entryLevel, msg, fields = {}) {
+      if (LOG_LEVELS.indexOf(entryLevel) > threshold) return;
+      write(
+        JSON.stringify({
+          time: new Date().toISOString(),
+          level: entryLevel,
+          msg,
+          ...fields,
+        }),
+      );
+    },
+  };
+}
@@ -0,0 +1,99 @@
+import { decodeMessage, encodeMessage, type MessageType } from './protobuf';
+
+describe('protobuf', () => {
+  const inner: MessageType = {
+    name: 'Inner',
+    fields: [{ name: 'code', number: 1, type: 'string' }],
+  };
+  const type: MessageType = {
+    name: 'Test',
+    fields: [
+      { name: 'text', number: 1, type: 'string', optional: true },
+      { name: 'flag', number: 2, type: 'bool', optional: true },
+      { name: 'count', number: 3, type: 'int64', optional: true },
+      { name: 'ratio', number: 4, type: 'double', optional: true },
+      { name: 'object', number: 5, type: 'struct' },
+      { name: 'any', number: 6, type: 'value' },
+      { name: 'inner', number: 7, type: inner },
+      { name: 'list', number: 8, type: 'string', repeated: true },
+    ],
+  };
+
+  it('should round-trip every field type', () => {
+    const value = {
+      text: 'héllo',
+      flag: false,
+      count: -42,
+      ratio: 0.25,
+      object: {
+        list: [1, 'two', true, null, { nested: [] }],
+        empty: {},
+      },
+      any: 'text',
+      inner: { code: 'OK' },
+      list: ['a', 'b'],
+    };
+
+    expect(decodeMessage(type, encodeMessage(type, value))).toEqual(value);
+  });
+
+  it('should leave out undefined fields', () => {
+    const data = encodeMessage(type, { text: 'a', count: undefined });
+
+    expect(decodeMessage(type, data)).toEqual({ text: 'a' });
+  });
+
+  it('should encode the wire format', () => {
+    // Field 1 length-delimited "hi", field 3 varint 150
+    expect(encodeMessage(type, { text: 'hi', count: 150 })).toEqual(
+      Buffer.from('0a026869189601', 'hex'),
+    );
+  });
+
+  it('should skip unknown fields', () => {
+    // Field 15 varint 1, field 16 length-delimited, field 17 fixed32
+    const data = Buffer.from('7801820101ff8d01000000001803', 'hex');
+
+    expect(decodeMessage(type, data)).toEqual({ count: 3 });
+  });
+
+  it('should keep a __proto__ key a plain property', () => {
+    const data = encodeMessage(type, {
+      object: JSON.parse('{"__proto__":{"polluted":true}}'),
+    });
+    const decoded = decodeMessage(type, data).object as object;
+
+    expect(Object.getPrototypeOf(decoded)).toBe(Object.prototype);
+    expect(Object.keys(decoded)).toEqual(['__proto__']);
+    expect(({} as Record<string, unknown>).polluted).toBeUndefined();
+  });
+
+  it('should reject malformed data', () => {
+    // Truncated string, wrong wire type, invalid UTF-8, runaway varint
+    const malformed = ['0a05686869', '0801', '0a01ff', '18' + 'ff'.repeat(10)];
+    for (const hex of malformed) {
+      expect(() => decodeMessage(type, Buffer.from(hex, 'hex'))).toThrow();
+    }
+  });
+
+  it('should reject Structs nested too deeply', () => {
+    let value: unknown = 1;
+    for (let i = 0; i < 100; i++) value = { a: value };
+
+    const data = encodeMessage(type, { object: value as object });
+
+    expect(() => decodeMessage(type, data)).toThrow('nests deeper');
+  });
+
+  it('should reject values that do not match the field type', () => {
+    expect(() => encodeMessage(type, { flag: 'yes' })).toThrow(
+      'Field flag must be a boolean',
+    );
+    expect(() => encodeMessage(type, { list: 'a' })).toThrow(
+      'Field list must be an array',
+    );
+    expect(() => encodeMessage(type, { count: 1.5 })).toThrow(
+      'Field count must be an integer',
+    );
+  });
+});
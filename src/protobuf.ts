@@ -0,0 +1,305 @@
+import {
+  asBytes,
+  bytesField,
+  type ProtobufField,
+  readFields,
+} from './utils/protobuf';
+
+// Messages for the gRPC transport, over the wire format reader the
+// validators use: scalar fields, and google.protobuf.Struct and Value for
+// everything shaped as in the JSON protocol
+
+export type ScalarType = 'string' | 'bool' | 'int64' | 'double';
+
+export interface MessageType {
+  name: string;
+  fields: Field[];
+}
+
+export interface Field {
+  name: string; // As in the JSON protocol, e.g. yieldId
+  number: number;
+  type: ScalarType | 'struct' | 'value' | MessageType;
+  optional?: boolean; // proto3 explicit presence
+  repeated?: boolean; // An array of the type, e.g. repeated string
+}
+
+// Wire types
+const VARINT = 0;
+const FIXED64 = 1;
+const LENGTH_DELIMITED = 2;
+
+// SECURITY: Structs nest, and are decoded recursively
+const MAX_DEPTH = 64;
+
+// google.protobuf.Value field numbers
+const NULL_VALUE = 1;
+const NUMBER_VALUE = 2;
+const STRING_VALUE = 3;
+const BOOL_VALUE = 4;
+const STRUCT_VALUE = 5;
+const LIST_VALUE = 6;
+
+const utf8 = new TextDecoder('utf-8', { fatal: true });
+
+/**
+ * Encodes value as a message of type, leaving out fields that are
+ * undefined. Fields of value that type does not declare are ignored.
+ */
+export function encodeMessage(
+  type: MessageType,
+  value: Record<string, unknown>,
+): Buffer {
+  const writer = new Writer();
+  for (const field of type.fields) {
+    const fieldValue = value[field.name];
+    if (fieldValue === undefined) continue;
+
+    if (!field.repeated) {
+      writeField(writer, field, fieldValue);
+    } else if (Array.isArray(fieldValue)) {
+      for (const item of fieldValue) writeField(writer, field, item);
+    } else {
+      throw fieldError(field, 'an array');
+    }
+  }
+  return writer.finish();
+}
+
+/**
+ * Decodes a message of type into an object keyed by field name, without
+ * the fields data leaves out. Unknown fields are skipped, and the last of
+ * repeated occurrences wins, except in repeated fields, which collect
+ * them. Throws on malformed data.
+ */
+export function decodeMessage(
+  type: MessageType,
+  data: Buffer,
+  depth = 0,
+): Record<string, unknown> {
+  checkDepth(depth);
+  const fields = new Map(type.fields.map((field) => [field.number, field]));
+  const result: Record<string, unknown> = {};
+  for (const value of readFields(data)) {
+    const field = fields.get(value.field);
+    if (field === undefined) continue;
+
+    const fieldValue = readField(field, value, depth);
+    if (field.repeated) {
+      const list = (result[field.name] ?? []) as unknown[];
+      list.push(fieldValue);
+      result[field.name] = list;
+    } else {
+      result[field.name] = fieldValue;
+    }
+  }
+  return result;
+}
+
+function writeField(writer: Writer, field: Field, value: unknown): void {
+  const { type } = field;
+  if (type === 'string') {
+    if (typeof value !== 'string') throw fieldError(field, 'a string');
+    writer.tag(field.number, LENGTH_DELIMITED);
+    writer.bytes(Buffer.from(value, 'utf8'));
+  } else if (type === 'bool') {
+    if (typeof value !== 'boolean') throw fieldError(field, 'a boolean');
+    writer.tag(field.number, VARINT);
+    writer.varint(value ? 1n : 0n);
+  } else if (type === 'int64') {
+    if (!Number.isSafeInteger(value)) throw fieldError(field, 'an integer');
+    writer.tag(field.number, VARINT);
+    writer.varint(BigInt.asUintN(64, BigInt(value as number)));
+  } else if (type === 'double') {
+    if (typeof value !== 'number') throw fieldError(field, 'a number');
+    writer.tag(field.number, FIXED64);
+    writer.double(value);
+  } else if (type === 'struct') {
+    if (!isObject(value)) throw fieldError(field, 'an object');
+    writer.tag(field.number, LENGTH_DELIMITED);
+    writer.bytes(encodeStruct(value));
+  } else if (type === 'value') {
+    writer.tag(field.number, LENGTH_DELIMITED);
+    writer.bytes(encodeValue(value));
+  } else {
+    if (!isObject(value)) throw fieldError(field, 'an object');
+    writer.tag(field.number, LENGTH_DELIMITED);
+    writer.bytes(encodeMessage(type, value));
+  }
+}
+
+function readField(
+  field: Field,
+  value: ProtobufField,
+  depth: number,
+): unknown {
+  const { type } = field;
+  const expected =
+    type === 'bool' || type === 'int64'
+      ? VARINT
+      : type === 'double'
+        ? FIXED64
+        : LENGTH_DELIMITED;
+  if (value.wireType !== expected) {
+    throw new Error(`Field ${field.name} has wire type ${value.wireType}`);
+  }
+
+  if (type === 'bool') return value.value !== 0n;
+  if (type === 'int64') {
+    return Number(BigInt.asIntN(64, value.value as bigint));
+  }
+  if (type === 'double') return (value.value as Buffer).readDoubleLE(0);
+  const bytes = asBytes(value);
+  if (type === 'string') return utf8.decode(bytes);
+  if (type === 'struct') return decodeStruct(bytes, depth + 1);
+  if (type === 'value') return decodeValue(bytes, depth + 1);
+  return decodeMessage(type, bytes, depth + 1);
+}
+
+// Struct is map<string, Value> fields = 1, each entry a message of the key
+// as field 1 and the value as field 2
+function encodeStruct(value: Record<string, unknown>): Buffer {
+  const writer = new Writer();
+  for (const [key, entryValue] of Object.entries(value)) {
+    if (entryValue === undefined) continue;
+    const entry = new Writer();
+    entry.tag(1, LENGTH_DELIMITED);
+    entry.bytes(Buffer.from(key, 'utf8'));
+    entry.tag(2, LENGTH_DELIMITED);
+    entry.bytes(encodeValue(entryValue));
+    writer.tag(1, LENGTH_DELIMITED);
+    writer.bytes(entry.finish());
+  }
+  return writer.finish();
+}
+
+function encodeValue(value: unknown): Buffer {
+  const writer = new Writer();
+  if (value === null || value === undefined) {
+    writer.tag(NULL_VALUE, VARINT);
+    writer.varint(0n);
+  } else if (typeof value === 'number') {
+    writer.tag(NUMBER_VALUE, FIXED64);
+    writer.double(value);
+  } else if (typeof value === 'string') {
+    writer.tag(STRING_VALUE, LENGTH_DELIMITED);
+    writer.bytes(Buffer.from(value, 'utf8'));
+  } else if (typeof value === 'boolean') {
+    writer.tag(BOOL_VALUE, VARINT);
+    writer.varint(value ? 1n : 0n);
+  } else if (Array.isArray(value)) {
+    // ListValue is repeated Value values = 1
+    const list = new Writer();
+    for (const item of value) {
+      list.tag(1, LENGTH_DELIMITED);
+      list.bytes(encodeValue(item));
+    }
+    writer.tag(LIST_VALUE, LENGTH_DELIMITED);
+    writer.bytes(list.finish());
+  } else if (isObject(value)) {
+    writer.tag(STRUCT_VALUE, LENGTH_DELIMITED);
+    writer.bytes(encodeStruct(value));
+  } else {
+    throw new Error(`Cannot encode a ${typeof value} as a Value`);
+  }
+  return writer.finish();
+}
+
+function decodeStruct(
+  data: Buffer,
+  depth: number,
+): Record<string, unknown> {
+  checkDepth(depth);
+  const result: Record<string, unknown> = {};
+  for (const entry of readFields(data)) {
+    if (entry.field !== 1 || entry.wireType !== LENGTH_DELIMITED) continue;
+
+    const entryFields = readFields(asBytes(entry));
+    const key = bytesField(entryFields, 1);
+    const value = bytesField(entryFields, 2);
+    // SECURITY: A key of "__proto__" must stay a plain property
+    Object.defineProperty(result, key ? utf8.decode(key) : '', {
+      value: value ? decodeValue(value, depth + 1) : null,
+      enumerable: true,
+      writable: true,
+      configurable: true,
+    });
+  }
+  return result;
+}
+
+// A Value with no kind set decodes as null
+function decodeValue(data: Buffer, depth: number): unknown {
+  checkDepth(depth);
+  let value: unknown = null;
+  for (const { field, wireType, value: fieldValue } of readFields(data)) {
+    if (field === NULL_VALUE && wireType === VARINT) {
+      value = null;
+    } else if (field === NUMBER_VALUE && wireType === FIXED64) {
+      value = (fieldValue as Buffer).readDoubleLE(0);
+    } else if (field === BOOL_VALUE && wireType === VARINT) {
+      value = fieldValue !== 0n;
+    } else if (wireType === LENGTH_DELIMITED) {
+      const bytes = fieldValue as Buffer;
+      if (field === STRING_VALUE) value = utf8.decode(bytes);
+      if (field === STRUCT_VALUE) value = decodeStruct(bytes, depth + 1);
+      if (field === LIST_VALUE) value = decodeList(bytes, depth + 1);
+    }
+  }
+  return value;
+}
+
+function decodeList(data: Buffer, depth: number): unknown[] {
+  checkDepth(depth);
+  return readFields(data)
+    .filter((item) => item.field === 1 && item.wireType === LENGTH_DELIMITED)
+    .map((item) => decodeValue(asBytes(item), depth + 1));
+}
+
+function checkDepth(depth: number): void {
+  if (depth > MAX_DEPTH) {
+    throw new Error(`Message nests deeper than ${MAX_DEPTH} levels`);
+  }
+}
+
+function isObject(value: unknown): value is Record<string, unknown> {
+  return typeof value === 'object' && value !== null && !Array.isArray(value);
+}
+
+function fieldError(field: Field, expected: string): Error {
+  return new TypeError(`Field ${field.name} must be ${expected}`);
+}
+
+class Writer {
+  private readonly chunks: Buffer[] = [];
+
+  tag(number: number, wireType: number): void {
+    this.varint(BigInt((number << 3) | wireType));
+  }
+
+  varint(value: bigint): void {
+    const bytes: number[] = [];
+    while (value > 0x7fn) {
+      bytes.push(Number(value & 0x7fn) | 0x80);
+      value >>= 7n;
+    }
+    bytes.push(Number(value));
+    this.chunks.push(Buffer.from(bytes));
+  }
+
+  double(value: number): void {
+    const buffer = Buffer.alloc(8);
+    buffer.writeDoubleLE(value);
+    this.chunks.push(buffer);
+  }
+
+  // Length-prefixed
+  bytes(data: Buffer): void {
+    this.varint(BigInt(data.length));
+    this.chunks.push(data);
+  }
+
+  finish(): Buffer {
+    return Buffer.concat(this.chunks);
+  }
+}
@@ -0,0 +1,95 @@
+import { ethers } from 'ethers';
+import type { RawTransactionFields } from './types';
+
+const BASE64_PATTERN =
+  /^(?:[A-Za-z0-9+/]{4})*(?:[A-Za-z0-9+/]{2}==|[A-Za-z0-9+/]{3}=)?$/;
+
+/**
+ * Hex of an RLP-encoded transaction given as 0x-prefixed hex or as base64,
+ * or null when it is neither.
+ */
+function toHex(rawTransaction: string): string | null {
+  if (rawTransaction.startsWith('0x')) {
+    return /^0x([0-9a-fA-F]{2})+$/.test(rawTransaction) ? rawTransaction : null;
+  }
+  if (rawTransaction === '' || !BASE64_PATTERN.test(rawTransaction)) {
+    return null;
+  }
+  return '0x' + Buffer.from(rawTransaction, 'base64').toString('hex');
+}
+
+export interface DecodedRawTransaction {
+  fields: RawTransactionFields; // from is only set when signer is
+  signed: boolean;
+  // Recovered from the signature; null when unsigned or when the signature
+  // does not recover to any address
+  signer: string | null;
+}
+
+/**
+ * Decodes a signed or unsigned legacy, EIP-2930, EIP-1559 or EIP-7702
+ * transaction. Returns null when rawTransaction is not valid RLP of one of
+ * those types.
+ */
+export function decodeRawTransaction(
+  rawTransaction: string,
+): DecodedRawTransaction | null {
+  const hex = toHex(rawTransaction);
+  if (hex === null) return null;
+
+  let tx: ethers.Transaction;
+  try {
+    tx = ethers.Transaction.from(hex);
+  } catch {
+    return null;
+  }
+
+  // Type 3 blob transactions carry no calls Shield validates
+  const type = tx.type ?? 0;
+  if (type === 3 || type > 4) return null;
+
+  const fields: RawTransactionFields = {
+    type,
+    chainId: Number(tx.chainId),
+    nonce: tx.nonce,
+    to: tx.to,
+    value: tx.value.toString(),
+    data: tx.data,
+    gasLimit: tx.gasLimit.toString(),
+  };
+  const signer = tx.signature ? recoverSigner(tx) : null;
+  if (signer !== null) fields.from = signer;
+  if (type === 2 || type === 4) {
+    fields.maxFeePerGas = String(tx.maxFeePerGas ?? 0n);
+    fields.maxPriorityFeePerGas = String(tx.maxPriorityFeePerGas ?? 0n);
+  } else {
+    fields.gasPrice = String(tx.gasPrice ?? 0n);
+  }
+  if (type !== 0) {
+    fields.accessList = (tx.accessList ?? []).map(
+      ({ address, storageKeys }) => ({ address, storageKeys }),
+    );
+  }
+  if (type === 4) {
+    fields.authorizationList = (tx.authorizationList ?? []).map(
+      ({ chainId, address, nonce, signature }) => ({
+        chainId: chainId.toString(),
+        address,
+        nonce: nonce.toString(),
+        yParity: signature.yParity,
+        r: signature.r,
+        s: signature.s,
+      }),
+    );
+  }
+  return { fields, signed: tx.signature !== null, signer };
+}
+
+// ethers recovers from lazily and throws for signatures that do not recover
+function recoverSigner(tx: ethers.Transaction): string | null {
+  try {
+    return tx.from;
+  } catch {
+    return null;
+  }
+}
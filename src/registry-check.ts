@@ -0,0 +1,114 @@
+import { ethers } from 'ethers';
+import type {
+  RegistryCheckResult,
+  RegistryProblem,
+  RegistryProblemCode,
+} from './types';
+import { BaseEVMValidator, validatorRegistry } from './validators';
+import type { BaseValidator } from './validators/base.validator';
+import type { VaultRegistryOverride } from './validators/evm/erc4626';
+
+// Override fields that must hold an EVM address
+const VAULT_ADDRESS_FIELDS = [
+  'address',
+  'inputTokenAddress',
+  'vaultTokenAddress',
+] as const;
+
+/**
+ * Lints the registry validators were built from, the built-in one and
+ * override merged over it. Errors are mistakes that make a yield validate
+ * wrongly or not at all; warnings are worth a second look, such as an
+ * override replacing a built-in yield.
+ */
+export function checkRegistry(
+  validators: ReadonlyMap<string, BaseValidator>,
+  override: VaultRegistryOverride | undefined,
+): RegistryCheckResult {
+  const problems: RegistryProblem[] = [];
+  const report =
+    (severity: RegistryProblem['severity']) =>
+    (code: RegistryProblemCode, yieldId: string, message: string) =>
+      problems.push({ severity, code, yieldId, message });
+  const error = report('error');
+  const warning = report('warning');
+
+  const listed = new Set<string>();
+  for (const vault of override?.vaults ?? []) {
+    const { yieldId } = vault;
+    if (listed.has(yieldId)) {
+      error(
+        'DUPLICATE_YIELD_ID',
+        yieldId,
+        `${yieldId} is listed more than once in the registry override; the last entry wins`,
+      );
+    } else if (validatorRegistry.has(yieldId)) {
+      warning(
+        'YIELD_REPLACED',
+        yieldId,
+        `The registry override replaces the built-in yield ${yieldId}`,
+      );
+    }
+    listed.add(yieldId);
+
+    const addresses = [
+      ...VAULT_ADDRESS_FIELDS.map((field) => [field, vault[field]]),
+      ...(vault.allocatorVaults ?? []).map((a) => ['allocatorVaults', a]),
+      ...Object.keys(vault.bytecodeHashes ?? {}).map((a) => [
+        'bytecodeHashes',
+        a,
+      ]),
+      ...Object.keys(vault.privilegedFunctions ?? {}).map((a) => [
+        'privilegedFunctions',
+        a,
+      ]),
+    ];
+    for (const [field, address] of addresses) {
+      if (!ethers.isAddress(address)) {
+        error(
+          'INVALID_ADDRESS',
+          yieldId,
+          `${field} ${address} is not a valid EVM address`,
+        );
+      }
+    }
+  }
+
+  for (const [yieldId, validator] of validators) {
+    const { chainId, contracts } = validator.getCapabilities();
+    const isEvm = validator instanceof BaseEVMValidator;
+
+    if (isEvm ? !/^[1-9][0-9]*$/.test(chainId) : chainId.length === 0) {
+      error('INVALID_CHAIN_ID', yieldId, `Invalid chain ID: '${chainId}'`);
+    }
+    // Native staking on other chains calls no contract
+    if (isEvm && contracts.length === 0) {
+      error('NO_CONTRACTS', yieldId, 'The yield lists no contracts');
+    }
+    for (const contract of isEvm ? contracts : []) {
+      if (!ethers.isAddress(contract)) {
+        error(
+          'INVALID_ADDRESS',
+          yieldId,
+          `Contract ${contract} is not a valid EVM address`,
+        );
+      }
+    }
+
+    for (const fn of validator.getAbiFunctions()) {
+      if (fn.selector !== ethers.id(fn.signature).slice(0, 10)) {
+        error(
+          'INVALID_SELECTOR',
+          yieldId,
+          `Selector ${fn.selector} is not that of ${fn.signature}`,
+        );
+      }
+    }
+  }
+
+  return {
+    isValid: problems.every((problem) => problem.severity !== 'error'),
+    yieldCount: validators.size,
+    problems,
+  };
+}
@@ -0,0 +1,85 @@
+import { mkdtempSync, rmSync, writeFileSync } from 'fs';
+import { tmpdir } from 'os';
+import { join } from 'path';
+import { ValidationCache } from './json';
+import { reloadRegistryOverride } from './registry';
+import type { VaultRegistryOverride } from './validators/evm/erc4626';
+
+describe('reloadRegistryOverride', () => {
+  const vault = {
+    yieldId: 'sepolia-usdc-test-vault',
+    address: '0x1111111111111111111111111111111111111111',
+    chainId: 11155111,
+    protocol: 'morpho',
+    network: 'sepolia',
+    inputTokenAddress: '0x2222222222222222222222222222222222222222',
+    vaultTokenAddress: '0x1111111111111111111111111111111111111111',
+    isWethVault: false,
+  };
+  const staging = { ...vault, yieldId: 'sepolia-usdc-staging' };
+
+  let dir: string;
+  let path: string;
+  beforeEach(() => {
+    dir = mkdtempSync(join(tmpdir(), 'shield-registry-'));
+    path = join(dir, 'registry.json');
+  });
+  afterEach(() => rmSync(dir, { recursive: true, force: true }));
+
+  it('should load the file and report the yields added and removed', () => {
+    const options: { registryOverride?: VaultRegistryOverride } = {
+      registryOverride: { vaults: [vault] },
+    };
+    writeFileSync(path, JSON.stringify({ vaults: [staging] }));
+
+    const result = reloadRegistryOverride(path, options);
+
+    expect(options.registryOverride).toEqual({ vaults: [staging] });
+    expect(result.added).toEqual([staging.yieldId]);
+    expect(result.removed).toEqual([vault.yieldId]);
+    expect(result.registry.overrideActive).toBe(true);
+    expect(result.registry.overrideHash).toEqual(expect.any(String));
+  });
+
+  it('should change the registry hash with the file', () => {
+    const options: { registryOverride?: VaultRegistryOverride } = {};
+    writeFileSync(path, JSON.stringify({ vaults: [vault] }));
+    const first = reloadRegistryOverride(path, options);
+
+    writeFileSync(path, JSON.stringify({ vaults: [vault, staging] }));
+    const second = reloadRegistryOverride(path, options);
+
+    expect(first.added).toEqual([vault.yieldId]);
+    expect(second.added).toEqual([staging.yieldId]);
+    expect(second.removed).toEqual([]);
+    expect(second.registry.overrideHash).not.toBe(first.registry.overrideHash);
+    expect(second.registry.yieldCount).toBe(first.registry.yieldCount + 1);
+  });
+
+  it('should clear cached results', () => {
+    const validationCache = new ValidationCache({
+      maxEntries: 10,
+      ttlMs: 60_000,
+    });
+    validationCache.set('key', { isValid: true, warnings: [] });
+    writeFileSync(path, JSON.stringify({ vaults: [vault] }));
+
+    reloadRegistryOverride(path, { validationCache });
+
+    expect(validationCache.size).toBe(0);
+  });
+
+  it('should keep the previous registry when the file is bad', () => {
+    const registryOverride = { vaults: [vault] };
+    const options = { registryOverride };
+
+    writeFileSync(path, '{"vaults":');
+    expect(() => reloadRegistryOverride(path, options)).toThrow(
+      'Invalid registry override',
+    );
+    expect(() =>
+      reloadRegistryOverride(join(dir, 'missing.json'), options),
+    ).toThrow();
+    expect(options.registryOverride).toBe(registryOverride);
+  });
+});
@@ -0,0 +1,34 @@
+import { readFileSync } from 'fs';
+import { parseRegistryOverride } from './json';
+import type { JsonHandlerOptions, ReloadRegistryResult } from './json';
+import { Shield } from './shield';
+
+/**
+ * Re-reads the registry override file at path into options, so requests
+ * handled with options from then on see its vaults, and reports the yields
+ * that came and went. Results cached under the previous registry are
+ * dropped. A file that cannot be read or parsed throws and leaves options
+ * as they were, so a bad edit never unloads the registry.
+ */
+export function reloadRegistryOverride(
+  path: string,
+  options: Pick<JsonHandlerOptions, 'registryOverride' | 'validationCache'>,
+): ReloadRegistryResult {
+  const registryOverride = parseRegistryOverride(readFileSync(path, 'utf8'));
+
+  const before = new Set(
+    new Shield({
+      registryOverride: options.registryOverride,
+    }).getSupportedYieldIds(),
+  );
+  const shield = new Shield({ registryOverride });
+  const after = new Set(shield.getSupportedYieldIds());
+
+  options.registryOverride = registryOverride;
+  options.validationCache?.clear();
+  return {
+    added: [...after].filter((yieldId) => !before.has(yieldId)),
+    removed: [...before].filter((yieldId) => !after.has(yieldId)),
+    registry: shield.getVersion().registry,
+  };
+}
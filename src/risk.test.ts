@@ -0,0 +1,49 @@
+import { computeRiskScore, toRiskLevel } from './risk';
+import { RiskLevel, ValidationResult } from './types';
+
+describe('computeRiskScore', () => {
+  const txWithValue = JSON.stringify({ to: '0x1', value: '0xde0b6b3a7640000' });
+  const txWithoutValue = JSON.stringify({ to: '0x1', value: '0x0' });
+
+  it('should score a clean match as zero', () => {
+    expect(computeRiskScore({ isValid: true }, txWithValue)).toBe(0);
+  });
+
+  it('should add warning weights to a matched transaction', () => {
+    const result: ValidationResult = {
+      isValid: true,
+      warnings: [{ code: 'INFINITE_APPROVAL', message: 'Unlimited approval' }],
+    };
+    expect(computeRiskScore(result, txWithoutValue)).toBe(30);
+  });
+
+  it('should score unmatched transactions moving value as maximum risk', () => {
+    expect(computeRiskScore({ isValid: false }, txWithValue)).toBe(100);
+    expect(computeRiskScore({ isValid: false }, txWithoutValue)).toBe(70);
+  });
+
+  it('should treat non-JSON transactions as carrying no value', () => {
+    expect(computeRiskScore({ isValid: false }, 'AQABAg==')).toBe(70);
+  });
+
+  it('should cap the score at 100', () => {
+    const result: ValidationResult = {
+      isValid: false,
+      warnings: [
+        { code: 'UNKNOWN_RECIPIENT', message: 'Unknown recipient' },
+        { code: 'INFINITE_APPROVAL', message: 'Unlimited approval' },
+      ],
+    };
+    expect(computeRiskScore(result, txWithValue)).toBe(100);
+  });
+});
+
+describe('toRiskLevel', () => {
+  it('should map scores to levels', () => {
+    expect(toRiskLevel(0)).toBe(RiskLevel.LOW);
+    expect(toRiskLevel(29)).toBe(RiskLevel.LOW);
+    expect(toRiskLevel(30)).toBe(RiskLevel.MEDIUM);
+    expect(toRiskLevel(69)).toBe(RiskLevel.MEDIUM);
+    expect(toRiskLevel(70)).toBe(RiskLevel.HIGH);
+  });
+});
@@ -0,0 +1,134 @@
+import {
+  RiskLevel,
+  ValidationResult,
+  ValidationWarning,
+  WarningCode,
+  WarningSeverity,
+} from './types';
+
+// Score of a transaction that matched no known pattern
+const UNMATCHED_SCORE = 70;
+
+// Added when an unmatched transaction also moves native value
+const UNMATCHED_VALUE_SCORE = 30;
+
+// Score added for each warning of that code
+const WARNING_SCORES: Record<WarningCode, number> = {
+  INFINITE_APPROVAL: 30,
+  HIGH_GAS_LIMIT: 15,
+  UNKNOWN_RECIPIENT: 40,
+  SENDER_NOT_VERIFIED: 20,
+  LONG_DEADLINE: 20,
+  ACCESS_LIST_UNEXPECTED_ADDRESS: 20,
+  UNKNOWN_PAYMASTER: 20,
+  DELEGATECALL_USED: 50,
+  NONCE_TOO_LOW: 30,
+  NONCE_GAP: 15,
+  EIP7702_DELEGATION: 50,
+  LOW_SLIPPAGE_PROTECTION: 30,
+  UNPROTECTED_REPLAY: 40,
+  IMPLEMENTATION_CHANGE: 50,
+  UNSTAKE_NEAR_FULL: 15,
+  LOCK_MAXED: 20,
+  // Only on a result that already failed, and scored as such
+  UNKNOWN_YIELD: 0,
+  YIELD_PAUSED: 50,
+  YIELD_DEPRECATED: 30,
+  CONTRACT_PAUSED: 50,
+  RECENTLY_DEPLOYED_CONTRACT: 30,
+  UNKNOWN_CAPABILITY: 15,
+  NON_ATOMIC_BATCH: 15,
+};
+
+// Severity of each warning of that code
+const WARNING_SEVERITIES: Record<WarningCode, WarningSeverity> = {
+  INFINITE_APPROVAL: 'critical',
+  HIGH_GAS_LIMIT: 'warning',
+  UNKNOWN_RECIPIENT: 'critical',
+  SENDER_NOT_VERIFIED: 'info',
+  LONG_DEADLINE: 'warning',
+  ACCESS_LIST_UNEXPECTED_ADDRESS: 'warning',
+  UNKNOWN_PAYMASTER: 'warning',
+  DELEGATECALL_USED: 'critical',
+  NONCE_TOO_LOW: 'warning',
+  NONCE_GAP: 'warning',
+  EIP7702_DELEGATION: 'critical',
+  LOW_SLIPPAGE_PROTECTION: 'warning',
+  UNPROTECTED_REPLAY: 'critical',
+  IMPLEMENTATION_CHANGE: 'critical',
+  UNSTAKE_NEAR_FULL: 'info',
+  LOCK_MAXED: 'warning',
+  UNKNOWN_YIELD: 'warning',
+  YIELD_PAUSED: 'critical',
+  YIELD_DEPRECATED: 'warning',
+  CONTRACT_PAUSED: 'critical',
+  RECENTLY_DEPLOYED_CONTRACT: 'warning',
+  UNKNOWN_CAPABILITY: 'warning',
+  NON_ATOMIC_BATCH: 'warning',
+};
+
+const MAX_SCORE = 100;
+
+/**
+ * Scores the risk of a validated transaction from 0 (matched a known pattern
+ * with no warnings) to 100. Each warning adds to the score of a matched
+ * transaction; an unmatched transaction starts high and is maxed out when it
+ * also moves native value.
+ */
+export function computeRiskScore(
+  result: ValidationResult,
+  unsignedTransaction: string,
+): number {
+  let score = result.isValid ? 0 : UNMATCHED_SCORE;
+
+  if (!result.isValid && carriesNativeValue(unsignedTransaction)) {
+    score += UNMATCHED_VALUE_SCORE;
+  }
+
+  for (const warning of result.warnings ?? []) {
+    score += WARNING_SCORES[warning.code];
+  }
+
+  return Math.min(score, MAX_SCORE);
+}
+
+/**
+ * result with the severity of each of its warnings and those of its
+ * subResults set.
+ */
+export function withWarningSeverities<
+  T extends { warnings?: ValidationWarning[]; subResults?: ValidationResult[] },
+>(result: T): T {
+  return {
+    ...result,
+    ...(result.warnings && {
+      warnings: result.warnings.map((warning) => ({
+        ...warning,
+        severity: WARNING_SEVERITIES[warning.code],
+      })),
+    }),
+    ...(result.subResults && {
+      subResults: result.subResults.map(withWarningSeverities),
+    }),
+  };
+}
+
+export function toRiskLevel(riskScore: number): RiskLevel {
+  if (riskScore < 30) return RiskLevel.LOW;
+  if (riskScore < 70) return RiskLevel.MEDIUM;
+  return RiskLevel.HIGH;
+}
+
+/**
+ * Best-effort check for a non-zero `value` on a JSON encoded transaction.
+ * Formats without such a field are treated as carrying no value.
+ */
+function carriesNativeValue(unsignedTransaction: string): boolean {
+  try {
+    const { value } = JSON.parse(unsignedTransaction) as { value?: unknown };
+    if (typeof value !== 'string' && typeof value !== 'number') return false;
+    return BigInt(value) > 0n;
+  } catch {
+    return false;
+  }
+}
@@ -0,0 +1,40 @@
+import { Shield } from './shield';
+import { runSelfTest, SELF_TEST_CORPUS } from './selftest';
+
+describe('runSelfTest', () => {
+  it('should reach the expected verdict on every corpus case', () => {
+    const result = runSelfTest(new Shield(), 2);
+
+    expect(result.failures).toEqual([]);
+    expect(result.passed).toBe(true);
+    expect(result.count).toBe(SELF_TEST_CORPUS.length * 2);
+    expect(result.iterations).toBe(2);
+  });
+
+  it('should report latency percentiles in order', () => {
+    const { latencyMs, totalMs } = runSelfTest(new Shield(), 3);
+
+    expect(latencyMs.p50).toBeLessThanOrEqual(latencyMs.p95);
+    expect(latencyMs.p95).toBeLessThanOrEqual(latencyMs.p99);
+    expect(latencyMs.p99).toBeLessThanOrEqual(totalMs);
+  });
+
+  it('should report a case whose verdict changed', () => {
+    const [stake] = SELF_TEST_CORPUS;
+    const result = runSelfTest(new Shield(), 1, [
+      { ...stake, expected: { isValid: true, detectedType: 'UNSTAKE' } },
+    ]);
+
+    expect(result.passed).toBe(false);
+    expect(result.failures).toEqual([
+      {
+        name: 'lido-stake',
+        expected: { isValid: true, detectedType: 'UNSTAKE' },
+        actual: expect.objectContaining({
+          isValid: true,
+          detectedType: 'STAKE',
+        }),
+      },
+    ]);
+  });
+});
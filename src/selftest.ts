@@ -0,0 +1,300 @@
+import { ethers } from 'ethers';
+import { Shield, type ValidationRequest } from './shield';
+import type { ReasonCode } from './types';
+
+/**
+ * A transaction of the self-test corpus, with the verdict this build must
+ * reach on it.
+ */
+export interface SelfTestCase {
+  name: string;
+  request: ValidationRequest;
+  expected: SelfTestVerdict;
+}
+
+export interface SelfTestVerdict {
+  isValid: boolean;
+  detectedType?: string; // Of valid transactions
+  reasonCode?: ReasonCode; // Of invalid ones
+}
+
+export interface SelfTestFailure {
+  name: string;
+  expected: SelfTestVerdict;
+  actual: SelfTestVerdict;
+}
+
+export interface SelfTestResult {
+  passed: boolean; // Every case reached its expected verdict
+  count: number; // Validations run, iterations of every case
+  iterations: number;
+  totalMs: number;
+  // Of a single validation, over every run of every case
+  latencyMs: { p50: number; p95: number; p99: number };
+  failures: SelfTestFailure[];
+}
+
+const USER = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+const OTHER = '0x0000000000000000000000000000000000000bad';
+const REFERRAL = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+
+const STETH = '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84';
+const WITHDRAWAL_QUEUE = '0x889edC2eDab5f40e902b864aD4d7AdE8E412F9B1';
+const VECRV = '0x5f3b5DfEb7B28CDbD7FAba78963EE202a494e2A2';
+const CRV = '0xD533a949740bb3306d119CC777fa900bA034cd52';
+const SDAI = '0x83F20F44975D03b1b09e64809B757c47f942BEeA';
+const DAI = '0x6B175474E89094C44Da98b954EedeAC495271d0F';
+
+const APTOS_USER = `0x${'7a1b'.repeat(16)}`;
+const APTOS_POOL = `0x${'db52'.repeat(16)}`;
+
+const iface = new ethers.Interface([
+  'function submit(address _referral) payable returns (uint256)',
+  'function requestWithdrawals(uint256[] _amounts, address _owner) returns (uint256[])',
+  'function approve(address spender, uint256 amount) returns (bool)',
+  'function deposit(uint256 assets, address receiver) returns (uint256)',
+]);
+
+function evmTx(
+  to: string,
+  data: string,
+  overrides: Record<string, unknown> = {},
+): string {
+  return JSON.stringify({
+    to,
+    from: USER,
+    value: '0x0',
+    data,
+    nonce: 0,
+    gasLimit: '0x493e0',
+    gasPrice: '0x4a817c800',
+    chainId: 1,
+    type: 0,
+    ...overrides,
+  });
+}
+
+function aptosTx(fn: string): string {
+  return JSON.stringify({
+    sender: APTOS_USER,
+    chain_id: 1,
+    payload: {
+      type: 'entry_function_payload',
+      function: fn,
+      type_arguments: [],
+      arguments: [APTOS_POOL, '100000000'],
+    },
+  });
+}
+
+const ONE_ETH = ethers.parseEther('1');
+const stake = iface.encodeFunctionData('submit', [REFERRAL]);
+const withdraw = iface.encodeFunctionData('requestWithdrawals', [
+  [ONE_ETH],
+  USER,
+]);
+
+/**
+ * Representative transactions of the shipped yields: one of each kind a
+ * yield accepts, and the tampered variants it must reject.
+ */
+export const SELF_TEST_CORPUS: SelfTestCase[] = [
+  {
+    name: 'lido-stake',
+    request: {
+      yieldId: 'ethereum-eth-lido-staking',
+      unsignedTransaction: evmTx(STETH, stake, { value: '0xde0b6b3a7640000' }),
+      userAddress: USER,
+    },
+    expected: { isValid: true, detectedType: 'STAKE' },
+  },
+  {
+    name: 'lido-stake-other-sender',
+    request: {
+      yieldId: 'ethereum-eth-lido-staking',
+      unsignedTransaction: evmTx(STETH, stake, {
+        from: OTHER,
+        value: '0xde0b6b3a7640000',
+      }),
+      userAddress: USER,
+    },
+    expected: { isValid: false, reasonCode: 'SENDER_MISMATCH' },
+  },
+  {
+    name: 'lido-unstake',
+    request: {
+      yieldId: 'ethereum-eth-lido-staking',
+      unsignedTransaction: evmTx(WITHDRAWAL_QUEUE, withdraw),
+      userAddress: USER,
+    },
+    expected: { isValid: true, detectedType: 'UNSTAKE' },
+  },
+  {
+    name: 'lido-unstake-with-value',
+    request: {
+      yieldId: 'ethereum-eth-lido-staking',
+      unsignedTransaction: evmTx(WITHDRAWAL_QUEUE, withdraw, {
+        value: '0xde0b6b3a7640000',
+      }),
+      userAddress: USER,
+    },
+    expected: { isValid: false, reasonCode: 'UNEXPECTED_NATIVE_VALUE' },
+  },
+  {
+    name: 'vecrv-approve',
+    request: {
+      yieldId: 'ethereum-crv-vecrv-staking',
+      unsignedTransaction: evmTx(
+        CRV,
+        iface.encodeFunctionData('approve', [VECRV, ONE_ETH]),
+      ),
+      userAddress: USER,
+    },
+    expected: { isValid: true, detectedType: 'APPROVAL' },
+  },
+  {
+    name: 'vecrv-approve-other-spender',
+    request: {
+      yieldId: 'ethereum-crv-vecrv-staking',
+      unsignedTransaction: evmTx(
+        CRV,
+        iface.encodeFunctionData('approve', [OTHER, ONE_ETH]),
+      ),
+      userAddress: USER,
+    },
+    expected: { isValid: false, reasonCode: 'APPROVAL_SPENDER_MISMATCH' },
+  },
+  {
+    name: 'erc4626-deposit',
+    request: {
+      yieldId:
+        'ethereum-dai-sdai-0x83f20f44975d03b1b09e64809b757c47f942beea-4626-vault',
+      unsignedTransaction: evmTx(
+        SDAI,
+        iface.encodeFunctionData('deposit', [ONE_ETH, USER]),
+      ),
+      userAddress: USER,
+    },
+    expected: { isValid: true, detectedType: 'SUPPLY' },
+  },
+  {
+    name: 'erc4626-approve',
+    request: {
+      yieldId:
+        'ethereum-dai-sdai-0x83f20f44975d03b1b09e64809b757c47f942beea-4626-vault',
+      unsignedTransaction: evmTx(
+        DAI,
+        iface.encodeFunctionData('approve', [SDAI, ONE_ETH]),
+      ),
+      userAddress: USER,
+    },
+    expected: { isValid: true, detectedType: 'APPROVAL' },
+  },
+  {
+    name: 'near-stake',
+    request: {
+      yieldId: 'near-near-native-staking',
+      unsignedTransaction: JSON.stringify({
+        signerId: 'alice.near',
+        receiverId: 'figment.poolv1.near',
+        actions: [
+          {
+            functionCall: {
+              methodName: 'deposit_and_stake',
+              args: {},
+              gas: '50000000000000',
+              deposit: '1000000000000000000000000',
+            },
+          },
+        ],
+      }),
+      userAddress: 'alice.near',
+    },
+    expected: { isValid: true, detectedType: 'STAKE' },
+  },
+  {
+    name: 'aptos-stake',
+    request: {
+      yieldId: 'aptos-apt-native-staking',
+      unsignedTransaction: aptosTx('0x1::delegation_pool::add_stake'),
+      userAddress: APTOS_USER,
+    },
+    expected: { isValid: true, detectedType: 'STAKE' },
+  },
+  {
+    name: 'aptos-transfer',
+    request: {
+      yieldId: 'aptos-apt-native-staking',
+      unsignedTransaction: aptosTx('0x1::aptos_account::transfer'),
+      userAddress: APTOS_USER,
+    },
+    expected: { isValid: false, reasonCode: 'MODULE_NOT_ALLOWED' },
+  },
+];
+
+/**
+ * Validates every case of corpus iterations times with shield, timing each
+ * validation, and reports the cases whose first verdict differs from the
+ * expected one. Only the fields a case expects are compared.
+ */
+export function runSelfTest(
+  shield = new Shield(),
+  iterations = 10,
+  corpus = SELF_TEST_CORPUS,
+): SelfTestResult {
+  const latencies: number[] = [];
+  const failures: SelfTestFailure[] = [];
+  const startedAt = performance.now();
+
+  for (const { name, request, expected } of corpus) {
+    for (let i = 0; i < iterations; i++) {
+      const start = performance.now();
+      const result = shield.validate(request);
+      latencies.push(performance.now() - start);
+      if (i > 0) continue;
+
+      const actual: SelfTestVerdict = {
+        isValid: result.isValid,
+        ...(result.detectedType !== undefined && {
+          detectedType: result.detectedType,
+        }),
+        ...(result.reasonCode !== undefined && {
+          reasonCode: result.reasonCode,
+        }),
+      };
+      if (
+        actual.isValid !== expected.isValid ||
+        (expected.detectedType !== undefined &&
+          actual.detectedType !== expected.detectedType) ||
+        (expected.reasonCode !== undefined &&
+          actual.reasonCode !== expected.reasonCode)
+      ) {
+        failures.push({ name, expected, actual });
+      }
+    }
+  }
+
+  latencies.sort((a, b) => a - b);
+  return {
+    passed: failures.length === 0,
+    count: latencies.length,
+    iterations,
+    totalMs: round(performance.now() - startedAt),
+    latencyMs: {
+      p50: round(percentile(latencies, 50)),
+      p95: round(percentile(latencies, 95)),
+      p99: round(percentile(latencies, 99)),
+    },
+    failures,
+  };
+}
+
+// Nearest-rank percentile of sorted values, 0 for none
+function percentile(sorted: number[], p: number): number {
+  if (sorted.length === 0) return 0;
+  return sorted[Math.ceil((p / 100) * sorted.length) - 1];
+}
+
+function round(ms: number): number {
+  return Math.round(ms * 100) / 100;
+}
@@ -1,5 +1,6 @@
+import { ethers } from 'ethers';
 import { Shield } from './shield';
-import { TransactionType } from './types';
+import { RiskLevel, SendCalls, TransactionType, YieldStatus } from './types';
 import { validatorRegistry } from './validators';
 
 describe('Shield', () => {
@@ -16,6 +17,43 @@ describe('Shield', () => {
     });
   });
 
+  describe('getSupportedYieldIds with a chainId', () => {
+    it('should only return yields on that chain', () => {
+      const yieldIds = shield.getSupportedYieldIds('42161');
+
+      expect(yieldIds.length).toBeGreaterThan(0);
+      expect(yieldIds).not.toContain('ethereum-eth-lido-staking');
+      for (const yieldId of yieldIds) {
+        expect(shield.getYieldCapabilities(yieldId)?.chainId).toBe('42161');
+      }
+    });
+
+    it('should return nothing for an unknown chain', () => {
+      expect(shield.getSupportedYieldIds('999999')).toEqual([]);
+    });
+  });
+
+  describe('getSupportedYields', () => {
+    it('should pair every supported yield with its chainId', () => {
+      const yields = shield.getSupportedYields();
+
+      expect(yields.map(({ yieldId }) => yieldId)).toEqual(
+        shield.getSupportedYieldIds(),
+      );
+      expect(yields).toContainEqual({
+        yieldId: 'ethereum-eth-lido-staking',
+        chainId: '1',
+      });
+    });
+
+    it('should filter by chainId', () => {
+      const yields = shield.getSupportedYields('42161');
+
+      expect(yields.length).toBeGreaterThan(0);
+      expect(yields.every(({ chainId }) => chainId === '42161')).toBe(true);
+    });
+  });
+
   describe('isSupported', () => {
     it('should return true for supported yields', () => {
       expect(shield.isSupported('ethereum-eth-lido-staking')).toBe(true);
@@ -31,6 +69,278 @@ describe('Shield', () => {
     });
   });
 
+  describe('registry override', () => {
+    const vault = {
+      yieldId: 'sepolia-weth-test-vault',
+      address: '0x3333333333333333333333333333333333333333',
+      chainId: 11155111,
+      protocol: 'upcoming-protocol',
+      network: 'sepolia',
+      inputTokenAddress: '0x4444444444444444444444444444444444444444',
+      vaultTokenAddress: '0x3333333333333333333333333333333333333333',
+      isWethVault: true,
+    };
+
+    it('should register override vaults of any protocol on that Shield only', () => {
+      const overridden = new Shield({ registryOverride: { vaults: [vault] } });
+
+      expect(overridden.isSupported(vault.yieldId)).toBe(true);
+      expect(overridden.getYieldCapabilities(vault.yieldId)?.chainId).toBe(
+        '11155111',
+      );
+      expect(overridden.isSupported('ethereum-eth-lido-staking')).toBe(true);
+      expect(shield.isSupported(vault.yieldId)).toBe(false);
+    });
+
+    it('should report the override in getVersion', () => {
+      const overridden = new Shield({ registryOverride: { vaults: [vault] } });
+
+      expect(shield.getVersion().registry.overrideActive).toBe(false);
+      expect(overridden.getVersion().registry).toMatchObject({
+        overrideActive: true,
+        yieldCount: shield.getVersion().registry.yieldCount + 1,
+      });
+    });
+  });
+
+  describe('checkRegistry', () => {
+    const vault = {
+      yieldId: 'sepolia-weth-test-vault',
+      address: '0x3333333333333333333333333333333333333333',
+      chainId: 11155111,
+      protocol: 'morpho',
+      network: 'sepolia',
+      inputTokenAddress: '0x4444444444444444444444444444444444444444',
+      vaultTokenAddress: '0x3333333333333333333333333333333333333333',
+      isWethVault: true,
+    };
+
+    it('should find no problems with the built-in registry', () => {
+      const result = shield.checkRegistry();
+
+      expect(result).toEqual({
+        isValid: true,
+        yieldCount: shield.getSupportedYieldIds().length,
+        problems: [],
+      });
+    });
+
+    it('should report a yield listed twice as an error', () => {
+      const overridden = new Shield({
+        registryOverride: { vaults: [vault, vault] },
+      });
+      const result = overridden.checkRegistry();
+
+      expect(result.isValid).toBe(false);
+      expect(result.problems).toEqual([
+        expect.objectContaining({
+          severity: 'error',
+          code: 'DUPLICATE_YIELD_ID',
+          yieldId: vault.yieldId,
+        }),
+      ]);
+    });
+
+    it('should report badly checksummed addresses', () => {
+      const overridden = new Shield({
+        registryOverride: {
+          vaults: [
+            {
+              ...vault,
+              // USDC, with the case of one letter flipped
+              inputTokenAddress: '0xa0B86991c6218b36c1d19D4a2e9Eb0cE3606eB48',
+            },
+          ],
+        },
+      });
+      const result = overridden.checkRegistry();
+
+      expect(result.isValid).toBe(false);
+      expect(result.problems).toContainEqual(
+        expect.objectContaining({
+          code: 'INVALID_ADDRESS',
+          message: expect.stringContaining('inputTokenAddress'),
+        }),
+      );
+    });
+
+    it('should warn about an override replacing a built-in yield', () => {
+      const [builtIn] = shield
+        .getSupportedYieldIds()
+        .filter((yieldId) => yieldId.endsWith('-4626-vault'));
+      const overridden = new Shield({
+        registryOverride: { vaults: [{ ...vault, yieldId: builtIn }] },
+      });
+      const result = overridden.checkRegistry();
+
+      expect(result.isValid).toBe(true);
+      expect(result.problems).toEqual([
+        expect.objectContaining({
+          severity: 'warning',
+          code: 'YIELD_REPLACED',
+          yieldId: builtIn,
+        }),
+      ]);
+    });
+  });
+
+  describe('getYieldCapabilities', () => {
+    it('should describe a supported yield', () => {
+      expect(
+        shield.getYieldCapabilities('ethereum-eth-lido-staking'),
+      ).toEqual({
+        yieldId: 'ethereum-eth-lido-staking',
+        name: 'Lido',
+        protocol: 'lido',
+        network: 'ethereum',
+        supportedTypes: [
+          TransactionType.STAKE,
+          TransactionType.UNSTAKE,
+          TransactionType.CLAIM_UNSTAKED,
+        ],
+        supportsPartialAmounts: true,
+        chainId: '1',
+        contracts: [
+          '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+          '0x889edC2eDab5f40e902b864aD4d7AdE8E412F9B1',
+        ],
+        status: 'active',
+      });
+    });
+
+    it('should describe every registered yield', () => {
+      for (const yieldId of shield.getSupportedYieldIds()) {
+        const capabilities = shield.getYieldCapabilities(yieldId);
+
+        expect(capabilities?.yieldId).toBe(yieldId);
+        expect(capabilities?.supportedTypes.length).toBeGreaterThan(0);
+        expect(capabilities?.chainId).not.toBe('');
+        expect(capabilities?.protocol).toMatch(/^[a-z0-9-]+$/);
+        expect(capabilities?.network).toMatch(/^[a-z0-9-]+$/);
+      }
+    });
+
+    it('should return null for unsupported yields', () => {
+      expect(shield.getYieldCapabilities('unknown-yield')).toBeNull();
+    });
+  });
+
+  describe('getYieldAbi', () => {
+    it('should list the functions each transaction type calls', () => {
+      const functions = shield.getYieldAbi('ethereum-eth-lido-staking');
+
+      expect(
+        functions?.map(({ transactionType, signature }) => [
+          transactionType,
+          signature,
+        ]),
+      ).toEqual([
+        [TransactionType.STAKE, 'submit(address)'],
+        [TransactionType.UNSTAKE, 'requestWithdrawals(uint256[],address)'],
+        [
+          TransactionType.UNSTAKE,
+          'requestWithdrawalsWithPermit(uint256[],address,(uint256,uint256,uint8,bytes32,bytes32))',
+        ],
+        [
+          TransactionType.UNSTAKE,
+          'requestWithdrawalsWstETH(uint256[],address)',
+        ],
+        [
+          TransactionType.UNSTAKE,
+          'requestWithdrawalsWstETHWithPermit(uint256[],address,(uint256,uint256,uint8,bytes32,bytes32))',
+        ],
+        [TransactionType.CLAIM_UNSTAKED, 'claimWithdrawal(uint256)'],
+        [
+          TransactionType.CLAIM_UNSTAKED,
+          'claimWithdrawals(uint256[],uint256[])',
+        ],
+        [
+          TransactionType.CLAIM_UNSTAKED,
+          'claimWithdrawalsTo(uint256[],uint256[],address)',
+        ],
+      ]);
+      expect(functions?.[0]).toEqual({
+        transactionType: TransactionType.STAKE,
+        name: 'submit',
+        selector: '0xa1903eab',
+        signature: 'submit(address)',
+        inputs: [{ name: '_referral', type: 'address' }],
+        payable: true,
+      });
+      expect(functions?.find(({ name }) => name === 'claimWithdrawal')).toEqual(
+        expect.objectContaining({ payable: false }),
+      );
+    });
+
+    it('should narrow to one transaction type', () => {
+      expect(
+        shield
+          .getYieldAbi('ethereum-eth-lido-staking', TransactionType.UNSTAKE)
+          ?.map(({ name }) => name),
+      ).toEqual([
+        'requestWithdrawals',
+        'requestWithdrawalsWithPermit',
+        'requestWithdrawalsWstETH',
+        'requestWithdrawalsWstETHWithPermit',
+      ]);
+    });
+
+    it('should return an empty list for yields without contract calls', () => {
+      expect(shield.getYieldAbi('cosmos-atom-native-staking')).toEqual([]);
+    });
+
+    it('should return null for unsupported yields', () => {
+      expect(shield.getYieldAbi('unknown-yield')).toBeNull();
+    });
+  });
+
+  describe('detectYields', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const lidoStakeTx = JSON.stringify({
+      to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+      from: userAddress,
+      value: '0xde0b6b3a7640000',
+      data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+      chainId: 1,
+    });
+    const vault = '0x7ed866d2d66c3149fafe854c30c68a8ba7cee8b9';
+    const vaultDepositTx = JSON.stringify({
+      to: vault,
+      from: userAddress,
+      value: '0x0',
+      data: new ethers.Interface([
+        'function deposit(uint256 assets, address receiver) returns (uint256)',
+      ]).encodeFunctionData('deposit', [100n, userAddress]),
+      chainId: 42161,
+    });
+
+    it('should find the yield a transaction belongs to', () => {
+      expect(shield.detectYields(lidoStakeTx)).toEqual([
+        {
+          yieldId: 'ethereum-eth-lido-staking',
+          detectedType: TransactionType.STAKE,
+        },
+      ]);
+      expect(shield.detectYields(vaultDepositTx)).toEqual([
+        {
+          yieldId: `arbitrum-arb-earb-1-${vault}-4626-vault`,
+          detectedType: TransactionType.SUPPLY,
+        },
+      ]);
+    });
+
+    it('should only try yields on chainId', () => {
+      expect(shield.detectYields(lidoStakeTx, '42161')).toEqual([]);
+      expect(shield.detectYields(lidoStakeTx, '1')).toHaveLength(1);
+    });
+
+    it('should return nothing for transactions no yield matches', () => {
+      expect(shield.detectYields('{ invalid json }')).toEqual([]);
+      expect(shield.detectYields('')).toEqual([]);
+    });
+  });
+
   describe('validate', () => {
     const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
     const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
@@ -96,6 +406,33 @@ describe('Shield', () => {
       expect(result.isValid).toBe(false);
       expect(result.reason).toBe('Unknown yield ID');
       expect(result.detectedType).toBeUndefined();
+      expect(result.warnings).toBeUndefined();
+    });
+
+    it('should warn UNKNOWN_YIELD on an unknown yield when lenient', () => {
+      const result = shield.validate({
+        unsignedTransaction: JSON.stringify(validLidoStakeTx),
+        yieldId: 'unknown-yield',
+        userAddress,
+        lenientUnknownYield: true,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('YIELD_NOT_FOUND');
+      expect(result.warnings).toEqual([
+        expect.objectContaining({
+          code: 'UNKNOWN_YIELD',
+          severity: 'warning',
+          details: { yieldId: 'unknown-yield' },
+        }),
+      ]);
+      expect(result.riskScore).toBe(
+        shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'unknown-yield',
+          userAddress,
+        }).riskScore,
+      );
     });
 
     describe('Auto-detection for different transaction types', () => {
@@ -108,9 +445,24 @@ describe('Shield', () => {
 
         expect(result.isValid).toBe(true);
         expect(result.detectedType).toBe(TransactionType.STAKE);
+        expect(result.detectedTypes).toEqual([TransactionType.STAKE]);
         expect(result.reason).toBeUndefined();
       });
 
+      it('should report the call arguments by name', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoUnstakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.decoded?.decodedArgs).toEqual({
+          _amounts: ['1000000000000000000'],
+          _owner: ethers.getAddress(userAddress),
+        });
+      });
+
       it('should auto-detect UNSTAKE transaction', () => {
         const result = shield.validate({
           unsignedTransaction: JSON.stringify(validLidoUnstakeTx),
@@ -170,207 +522,5565 @@ describe('Shield', () => {
       });
     });
 
-    describe('Failed validations', () => {
-      it('should reject transaction that matches no patterns and not set detectedType', () => {
-        const invalidTx = {
-          to: '0x0000000000000000000000000000000000000000',
-          from: userAddress,
-          value: '0x0',
-          data: '0x',
-          chainId: 1,
-        };
-
+    describe('Risk assessment', () => {
+      it('should score a matched transaction as low risk', () => {
         const result = shield.validate({
-          unsignedTransaction: JSON.stringify(invalidTx),
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
           yieldId: 'ethereum-eth-lido-staking',
           userAddress,
         });
 
-        expect(result.isValid).toBe(false);
-        expect(result.reason).toContain('No matching operation pattern found');
-        expect(result.detectedType).toBeUndefined();
+        expect(result.riskScore).toBe(0);
+        expect(result.riskLevel).toBe(RiskLevel.LOW);
       });
 
-      it('should include attempts in details when no pattern matches', () => {
-        const invalidTx = {
-          to: '0x0000000000000000000000000000000000000000',
-          from: userAddress,
-          value: '0x0',
-          data: '0x',
-          chainId: 1,
-        };
-
+      it('should score an unmatched transaction with value as high risk', () => {
         const result = shield.validate({
-          unsignedTransaction: JSON.stringify(invalidTx),
+          unsignedTransaction: JSON.stringify({
+            ...validLidoStakeTx,
+            to: '0x0000000000000000000000000000000000000001',
+          }),
           yieldId: 'ethereum-eth-lido-staking',
           userAddress,
         });
 
         expect(result.isValid).toBe(false);
-        expect(result.detectedType).toBeUndefined();
-        expect(result.details?.attempts).toBeDefined();
-        expect(Array.isArray(result.details?.attempts)).toBe(true);
-        expect(result.details?.attempts?.length).toBe(3); // STAKE, UNSTAKE, CLAIM_UNSTAKED
-
-        result.details?.attempts?.forEach((attempt: any) => {
-          expect(attempt.type).toBeDefined();
-          expect(attempt.reason).toBeDefined();
-        });
+        expect(result.riskScore).toBe(100);
+        expect(result.riskLevel).toBe(RiskLevel.HIGH);
       });
 
-      it('should not set detectedType for transactions that fail validation', () => {
-        const invalidReferralTx = {
-          to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
-          from: userAddress,
-          value: '0xde0b6b3a7640000',
-          data:
-            '0xa1903eab' +
-            '0000000000000000000000000000000000000000000000000000000000000000', // wrong referral
-          chainId: 1,
-        };
-
+      it('should keep valid transactions below the risk threshold', () => {
         const result = shield.validate({
-          unsignedTransaction: JSON.stringify(invalidReferralTx),
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
           yieldId: 'ethereum-eth-lido-staking',
           userAddress,
+          riskThreshold: 1,
         });
 
-        expect(result.isValid).toBe(false);
-        expect(result.reason).toContain('No matching operation pattern found');
-        expect(result.detectedType).toBeUndefined();
+        expect(result.isValid).toBe(true);
       });
+    });
 
-      it('should not set detectedType for wrong user address', () => {
-        const wrongUserTx = {
-          to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
-          from: '0x0000000000000000000000000000000000000001', // wrong from address
-          value: '0xde0b6b3a7640000',
-          data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
-          chainId: 1,
-        };
+    describe('Expected amount', () => {
+      const oneEth = '1000000000000000000';
 
+      it('should report the native value as the amount', () => {
         const result = shield.validate({
-          unsignedTransaction: JSON.stringify(wrongUserTx),
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
           yieldId: 'ethereum-eth-lido-staking',
           userAddress,
         });
 
-        expect(result.isValid).toBe(false);
-        expect(result.reason).toContain('No matching operation pattern found');
-        expect(result.detectedType).toBeUndefined();
+        expect(result.isValid).toBe(true);
+        expect(result.amount).toEqual({
+          token: 'native',
+          amount: oneEth,
+          symbol: 'ETH',
+          decimals: 18,
+          normalized: '1.0',
+        });
       });
 
-      it('should not allow for two or more matches', () => {
-        // Create a mock validator that returns multiple valid matches
-        const mockValidator = {
-          getSupportedTransactionTypes: jest
-            .fn()
-            .mockReturnValue([
-              TransactionType.STAKE,
-              TransactionType.UNSTAKE,
-              TransactionType.CLAIM_UNSTAKED,
-            ]),
-          validate: jest.fn().mockImplementation((_, transactionType) => {
-            // Mock to return valid for both STAKE and UNSTAKE
-            if (
-              transactionType === TransactionType.STAKE ||
-              transactionType === TransactionType.UNSTAKE
-            ) {
-              return { isValid: true };
-            }
-            return { isValid: false, reason: 'Not supported' };
-          }),
-        };
-
-        // Store the original validator
-        const originalValidator = validatorRegistry.get(
-          'ethereum-eth-lido-staking',
-        );
+      it('should accept the expected amount and token', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+          expectedAmount: oneEth,
+          expectedAmountToken: 'native',
+        });
 
-        // Temporarily replace the validator in the registry
-        (validatorRegistry as any).set(
-          'ethereum-eth-lido-staking',
-          mockValidator,
-        );
+        expect(result.isValid).toBe(true);
+      });
 
+      it('should reject a different amount', () => {
         const result = shield.validate({
           unsignedTransaction: JSON.stringify(validLidoStakeTx),
           yieldId: 'ethereum-eth-lido-staking',
           userAddress,
+          expectedAmount: '1500000000000000000',
         });
 
         expect(result.isValid).toBe(false);
-        expect(result.reason).toContain(
-          'Ambiguous transaction pattern detected',
-        );
-        expect(result.reason).toContain(
-          'Transaction matches multiple operation types',
-        );
-        expect(result.details?.matchedTypes).toEqual([
-          TransactionType.STAKE,
-          TransactionType.UNSTAKE,
-        ]);
-        expect(result.details?.warning).toContain(
-          'A legitimate transaction must match exactly one pattern',
-        );
-        expect(result.detectedType).toBeUndefined();
+        expect(result.reason).toBe('AMOUNT_MISMATCH');
+        expect(result.details).toEqual({
+          yieldId: 'ethereum-eth-lido-staking',
+          expected: '1500000000000000000',
+          actual: oneEth,
+        });
+        expect(result.amount?.amount).toBe(oneEth);
+      });
 
-        // Restore the original validator
-        if (originalValidator) {
-          (validatorRegistry as any).set(
-            'ethereum-eth-lido-staking',
-            originalValidator,
-          );
+      it('should allow amountToleranceBps either way', () => {
+        for (const { expectedAmount, isValid } of [
+          { expectedAmount: '1010000000000000000', isValid: true },
+          { expectedAmount: '991000000000000000', isValid: true },
+          { expectedAmount: '1020000000000000000', isValid: false },
+        ]) {
+          const result = shield.validate({
+            unsignedTransaction: JSON.stringify(validLidoStakeTx),
+            yieldId: 'ethereum-eth-lido-staking',
+            userAddress,
+            expectedAmount,
+            amountToleranceBps: 100,
+          });
+
+          expect(result.isValid).toBe(isValid);
         }
       });
 
-      it('should handle validator throwing an error', () => {
-        // Create a mock validator that throws an error
-        const mockValidator = {
-          getSupportedTransactionTypes: jest
-            .fn()
-            .mockReturnValue([TransactionType.STAKE]),
-          validate: jest.fn().mockImplementation(() => {
-            throw new Error('Validator internal error');
-          }),
-        };
+      it('should allow amountTolerance base units, the larger tolerance applying', () => {
+        for (const { amountToleranceBps, isValid } of [
+          { amountToleranceBps: undefined, isValid: true },
+          { amountToleranceBps: 1, isValid: true },
+          { amountToleranceBps: 100, isValid: true },
+        ]) {
+          const result = shield.validate({
+            unsignedTransaction: JSON.stringify(validLidoStakeTx),
+            yieldId: 'ethereum-eth-lido-staking',
+            userAddress,
+            expectedAmount: '1000000000000000003',
+            amountTolerance: '3',
+            amountToleranceBps,
+          });
 
-        // Store the original validator
-        const originalValidator = validatorRegistry.get(
-          'ethereum-eth-lido-staking',
-        );
+          expect(result.isValid).toBe(isValid);
+        }
 
-        // Temporarily replace the validator in the registry
-        (validatorRegistry as any).set(
-          'ethereum-eth-lido-staking',
-          mockValidator,
-        );
+        const beyond = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+          expectedAmount: '1000000000000000004',
+          amountTolerance: '3',
+        });
+        expect(beyond.reasonCode).toBe('AMOUNT_MISMATCH');
+      });
+
+      it('should report the delta from expectedAmount, also within tolerance', () => {
+        const within = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+          expectedAmount: '1000000000000000003',
+          amountTolerance: '3',
+        });
+        const exact = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+          expectedAmount: oneEth,
+        });
+        const unchecked = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        expect(within.isValid).toBe(true);
+        expect(within.amountDelta).toBe('-3');
+        expect(exact.amountDelta).toBe('0');
+        expect(unchecked.amountDelta).toBeUndefined();
+      });
+
+      it('should reject a malformed amountTolerance', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+          expectedAmount: oneEth,
+          amountTolerance: '-1',
+        });
 
+        expect(result.reasonCode).toBe('INVALID_REQUEST');
+      });
+
+      it('should reject a different token', () => {
         const result = shield.validate({
           unsignedTransaction: JSON.stringify(validLidoStakeTx),
           yieldId: 'ethereum-eth-lido-staking',
           userAddress,
+          expectedAmount: oneEth,
+          expectedAmountToken: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
         });
 
         expect(result.isValid).toBe(false);
-        expect(result.reason).toContain('No matching operation pattern found');
-        expect(result.details?.attempts).toBeDefined();
-        expect(Array.isArray(result.details?.attempts)).toBe(true);
-        expect(result.details?.attempts?.length).toBe(1);
-        expect(result.details?.attempts?.[0].type).toBe(TransactionType.STAKE);
-        expect(result.details?.attempts?.[0].reason).toBe(
-          'Validator internal error',
+        expect(result.reason).toBe('AMOUNT_MISMATCH');
+        expect(result.details?.error).toContain('native');
+      });
+
+      it('should reject a transaction without a decodable amount', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoClaimTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+          expectedAmount: oneEth,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reason).toBe('AMOUNT_MISMATCH');
+        expect(result.details?.actual).toBeUndefined();
+      });
+
+      it('should reject a malformed expectedAmount', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+          expectedAmount: '1.5',
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reason).toBe('Invalid request parameters');
+      });
+    });
+
+    describe('Strict mode', () => {
+      it('should keep warnings informational by default', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.warnings?.map((w) => w.code)).toEqual([
+          'SENDER_NOT_VERIFIED',
+        ]);
+      });
+
+      it('should reject a transaction with warnings', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          strict: true,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reason).toBe('STRICT_MODE_WARNING');
+        expect(result.details).toEqual({
+          yieldId: 'ethereum-eth-lido-staking',
+          warningCodes: ['SENDER_NOT_VERIFIED'],
+        });
+        expect(result.warnings?.map((w) => w.code)).toEqual([
+          'SENDER_NOT_VERIFIED',
+        ]);
+      });
+
+      it('should accept a transaction without warnings', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+          strict: true,
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.reason).toBeUndefined();
+      });
+
+      it('should rate each warning by severity', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+        });
+
+        expect(result.warnings?.[0].severity).toBe('info');
+      });
+
+      it('should only reject the strictSeverities given', () => {
+        const request = {
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          strict: true,
+        };
+
+        const critical = shield.validate({
+          ...request,
+          strictSeverities: ['critical'],
+        });
+        expect(critical.isValid).toBe(true);
+        expect(critical.warnings?.map((w) => w.code)).toEqual([
+          'SENDER_NOT_VERIFIED',
+        ]);
+
+        const info = shield.validate({
+          ...request,
+          strictSeverities: ['info', 'critical'],
+        });
+        expect(info.isValid).toBe(false);
+        expect(info.reasonCode).toBe('STRICT_MODE_WARNING');
+      });
+
+      it('should apply to every step of a flow', () => {
+        const result = shield.validateFlow({
+          yieldId: 'ethereum-eth-lido-staking',
+          transactions: [JSON.stringify(validLidoStakeTx)],
+          strict: true,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reason).toBe('FLOW_STEP_INVALID');
+        expect(result.details).toEqual({
+          step: 0,
+          reason: 'STRICT_MODE_WARNING',
+        });
+      });
+    });
+
+    describe('Observe mode', () => {
+      it('should report a rejection without rejecting', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          strict: true,
+          observe: true,
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.wouldReject).toBe(true);
+        expect(result.wouldRejectReason).toBe('STRICT_MODE_WARNING');
+        expect(result.wouldRejectReasonCode).toBe('STRICT_MODE_WARNING');
+        expect(result.reason).toBeUndefined();
+        expect(result.reasonCode).toBeUndefined();
+        expect(result.warnings?.map((w) => w.code)).toEqual([
+          'SENDER_NOT_VERIFIED',
+        ]);
+      });
+
+      it('should report a valid transaction as not rejected', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+          observe: true,
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.wouldReject).toBe(false);
+        expect(result.wouldRejectReason).toBeUndefined();
+      });
+
+      it('should let even invalid requests through', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'unknown-yield',
+          observe: true,
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.wouldRejectReasonCode).toBe('YIELD_NOT_FOUND');
+      });
+
+      it('should trace the verdict it would have reached', () => {
+        const result = shield.explain({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          strict: true,
+          observe: true,
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(
+          result.trace.find((entry) => entry.check === 'strict-mode')?.status,
+        ).toBe('fail');
+      });
+
+      it('should enforce by default', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          strict: true,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.wouldReject).toBeUndefined();
+      });
+    });
+
+    describe('Reason codes', () => {
+      it('should set a reasonCode on every rejection', () => {
+        const cases = [
+          {
+            yieldId: 'unknown-yield-xyz',
+            tx: validLidoStakeTx,
+            reasonCode: 'YIELD_NOT_FOUND',
+          },
+          {
+            yieldId: 'ethereum-eth-lido-staking',
+            tx: { ...validLidoStakeTx, chainId: 10 },
+            reasonCode: 'CHAIN_ID_MISMATCH',
+          },
+          {
+            yieldId: 'ethereum-eth-lido-staking',
+            tx: {
+              ...validLidoStakeTx,
+              to: '0x0000000000000000000000000000000000000001',
+            },
+            reasonCode: 'RECIPIENT_MISMATCH',
+          },
+          {
+            yieldId: 'ethereum-eth-lido-staking',
+            tx: { ...validLidoStakeTx, data: '0xdeadbeef' },
+            reasonCode: 'SELECTOR_MISMATCH',
+          },
+          {
+            yieldId: 'ethereum-eth-lido-staking',
+            tx: { ...validLidoClaimTx, to: validLidoStakeTx.to },
+            reasonCode: 'NO_MATCHING_PATTERN',
+          },
+        ];
+
+        for (const { yieldId, tx, reasonCode } of cases) {
+          const result = shield.validate({
+            unsignedTransaction: JSON.stringify(tx),
+            yieldId,
+            userAddress,
+          });
+
+          expect(result.isValid).toBe(false);
+          expect(result.reasonCode).toBe(reasonCode);
+        }
+      });
+
+      it('should keep the display reason alongside the code', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          riskThreshold: 1,
+        });
+
+        expect(result.reasonCode).toBe('RISK_THRESHOLD_EXCEEDED');
+        expect(result.reason).toContain('reaches the configured threshold');
+      });
+
+      it('should tell an unknown yield from an action the yield lacks', () => {
+        // An ERC-4626 deposit(uint256,address), which Lido never takes
+        const deposit = {
+          ...validLidoStakeTx,
+          data: '0x6e553f65' + '00'.repeat(64),
+        };
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(deposit),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('OPERATION_NOT_SUPPORTED_FOR_YIELD');
+        expect(result.reason).toContain(
+          'Supported types: STAKE, UNSTAKE, CLAIM_UNSTAKED',
         );
-        expect(result.detectedType).toBeUndefined();
+        expect(result.details).toEqual({
+          yieldId: 'ethereum-eth-lido-staking',
+          impliedTypes: [TransactionType.SUPPLY],
+          supportedTypes: [
+            TransactionType.STAKE,
+            TransactionType.UNSTAKE,
+            TransactionType.CLAIM_UNSTAKED,
+          ],
+        });
 
-        // Restore the original validator
-        if (originalValidator) {
-          (validatorRegistry as any).set(
-            'ethereum-eth-lido-staking',
-            originalValidator,
-          );
+        expect(
+          shield.validate({
+            unsignedTransaction: JSON.stringify(deposit),
+            yieldId: 'unknown-yield-xyz',
+            userAddress,
+          }).reasonCode,
+        ).toBe('YIELD_NOT_FOUND');
+      });
+
+      it('should not set a reasonCode on valid results', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.reasonCode).toBeUndefined();
+      });
+    });
+
+    describe('Claim recipient', () => {
+      const withdrawalQueue = new ethers.Interface([
+        'function claimWithdrawalsTo(uint256[] _requestIds, uint256[] _hints, address _recipient)',
+      ]);
+      const claimTo = (recipient: string) => ({
+        ...validLidoClaimTx,
+        data: withdrawalQueue.encodeFunctionData('claimWithdrawalsTo', [
+          [123],
+          [1],
+          recipient,
+        ]),
+      });
+
+      it('should accept a claim paid to the user', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(claimTo(userAddress)),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.detectedType).toBe(TransactionType.CLAIM_UNSTAKED);
+        expect(result.decoded?.recipient).toEqual({
+          address: ethers.getAddress(userAddress),
+          implicit: false,
+          variant: 'claimWithdrawalsTo(uint256[],uint256[],address)',
+        });
+      });
+
+      it('should reject a claim paid to another address', () => {
+        const attacker = '0x000000000000000000000000000000000000bad1';
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(claimTo(attacker)),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('REWARD_RECIPIENT_MISMATCH');
+        expect(result.details).toEqual({
+          yieldId: 'ethereum-eth-lido-staking',
+          expected: userAddress,
+          actual: attacker,
+          variant: 'claimWithdrawalsTo(uint256[],uint256[],address)',
+        });
+      });
+
+      it('should mark claims that pay their sender as implicit', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoClaimTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.decoded?.recipient).toEqual({
+          address: userAddress,
+          implicit: true,
+          variant: 'claimWithdrawal(uint256)',
+        });
+      });
+
+      it('should not report a recipient for other transaction types', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.decoded?.recipient).toBeUndefined();
+      });
+
+      describe('claim variants', () => {
+        const attacker = '0x000000000000000000000000000000000000bad1';
+        const gauge = '0x5555555555555555555555555555555555555555';
+        const claims = new ethers.Interface([
+          'function claim(address to)',
+          'function getReward(address account)',
+          'function getReward(address account, bool claimExtras)',
+          'function claimRewards(address to)',
+          'function claim_rewards(address _addr)',
+          'function claim_rewards(address _addr, address _receiver)',
+          'function getReward()',
+        ]);
+        const claimTx = (signature: string, args: unknown[]) =>
+          JSON.stringify({
+            to: gauge,
+            from: userAddress,
+            value: '0x0',
+            data: claims.encodeFunctionData(signature, args),
+            chainId: 1,
+          });
+
+        it('should reject every variant that pays another address', () => {
+          for (const [signature, args] of [
+            ['claim(address)', [attacker]],
+            ['getReward(address)', [attacker]],
+            ['getReward(address,bool)', [attacker, true]],
+            ['claimRewards(address)', [attacker]],
+            ['claim_rewards(address)', [attacker]],
+            ['claim_rewards(address,address)', [userAddress, attacker]],
+          ] as const) {
+            const result = shield.validate({
+              unsignedTransaction: claimTx(signature, [...args]),
+              yieldId: 'ethereum-eth-lido-staking',
+              userAddress,
+            });
+
+            expect(result.reasonCode).toBe('REWARD_RECIPIENT_MISMATCH');
+            expect(result.details?.actual).toBe(attacker);
+            expect(result.details?.variant).toBe(signature);
+          }
+        });
+
+        it('should leave variants that pay the user to the yield', () => {
+          for (const [signature, args] of [
+            ['getReward(address)', [userAddress]],
+            ['claim_rewards(address,address)', [attacker, userAddress]],
+            ['getReward()', []],
+          ] as const) {
+            const result = shield.validate({
+              unsignedTransaction: claimTx(signature, [...args]),
+              yieldId: 'ethereum-eth-lido-staking',
+              userAddress,
+            });
+
+            // Lido's contracts have no such function
+            expect(result.reasonCode).not.toBe('REWARD_RECIPIENT_MISMATCH');
+            expect(result.isValid).toBe(false);
+          }
+        });
+      });
+    });
+
+    describe('Gas limit', () => {
+      it('should accept legacy and EIP-1559 fee fields', () => {
+        for (const fees of [
+          { gasLimit: '0x30d40', gasPrice: '0x4a817c800' },
+          {
+            gasLimit: 200000,
+            maxFeePerGas: '0x6fc23ac00',
+            maxPriorityFeePerGas: '0x3b9aca00',
+          },
+        ]) {
+          const result = shield.validate({
+            unsignedTransaction: JSON.stringify({
+              ...validLidoStakeTx,
+              ...fees,
+            }),
+            yieldId: 'ethereum-eth-lido-staking',
+            userAddress,
+          });
+
+          expect(result.isValid).toBe(true);
+          expect(result.warnings).toBeUndefined();
         }
       });
+
+      it('should warn about a gas limit far above the typical range', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify({
+            ...validLidoStakeTx,
+            gasLimit: '0x989680', // 10,000,000
+          }),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.warnings).toEqual([
+          expect.objectContaining({
+            code: 'HIGH_GAS_LIMIT',
+            details: {
+              gasLimit: '10000000',
+              expected: { min: '21000', max: '1500000' },
+            },
+          }),
+        ]);
+        expect(result.riskLevel).toBe(RiskLevel.LOW);
+      });
+    });
+
+    describe('Safe transactions', () => {
+      const safe = '0x1111111111111111111111111111111111111111';
+      const owner = '0x2222222222222222222222222222222222222222';
+      const safeIface = new ethers.Interface([
+        'function execTransaction(address to, uint256 value, bytes data, uint8 operation, uint256 safeTxGas, uint256 baseGas, uint256 gasPrice, address gasToken, address refundReceiver, bytes signatures) returns (bool)',
+      ]);
+
+      const execTransaction = (inner: typeof validLidoStakeTx, operation = 0) =>
+        JSON.stringify({
+          to: safe,
+          from: owner,
+          value: '0x0',
+          data: safeIface.encodeFunctionData('execTransaction', [
+            inner.to,
+            BigInt(inner.value),
+            inner.data,
+            operation,
+            0n,
+            0n,
+            0n,
+            ethers.ZeroAddress,
+            ethers.ZeroAddress,
+            '0x',
+          ]),
+          chainId: 1,
+        });
+
+      it('should validate the call a Safe executes', () => {
+        const result = shield.validate({
+          unsignedTransaction: execTransaction(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress: safe,
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.detectedType).toBe(TransactionType.STAKE);
+        expect(result.wrapper).toEqual({
+          detectedType: 'SAFE_EXEC_TRANSACTION',
+          address: safe,
+          operation: 'CALL',
+        });
+        expect(result.warnings).toBeUndefined();
+      });
+
+      it('should report the Safe wrapper path in timing', () => {
+        const result = shield.validate({
+          unsignedTransaction: execTransaction(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress: safe,
+          includeTiming: true,
+        });
+
+        expect(result.timing?.path).toBe('evm-safe-wrapper');
+      });
+
+      it('should treat the Safe as the sender of the inner call', () => {
+        const result = shield.validate({
+          unsignedTransaction: execTransaction(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress: owner,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reason).toBe('SENDER_MISMATCH');
+        expect(result.details?.actual).toBe(safe);
+      });
+
+      it('should reject an inner call that matches no pattern', () => {
+        const result = shield.validate({
+          unsignedTransaction: execTransaction({
+            ...validLidoStakeTx,
+            to: '0x0000000000000000000000000000000000000bad',
+          }),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress: safe,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reason).toContain('No matching operation pattern found');
+        expect(result.wrapper?.address).toBe(safe);
+      });
+
+      it('should warn when the Safe uses DelegateCall', () => {
+        const result = shield.validate({
+          unsignedTransaction: execTransaction(validLidoStakeTx, 1),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress: safe,
+        });
+
+        expect(result.wrapper?.operation).toBe('DELEGATECALL');
+        expect(result.warnings?.map((w) => w.code)).toEqual([
+          'DELEGATECALL_USED',
+        ]);
+        expect(result.warnings?.[0].details).toEqual({
+          safe,
+          target: validLidoStakeTx.to,
+        });
+        expect(result.riskLevel).toBe(RiskLevel.MEDIUM);
+      });
+
+      it('should reject DelegateCall when the policy blocks it', () => {
+        const result = shield.validate({
+          unsignedTransaction: execTransaction(validLidoStakeTx, 1),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress: safe,
+          policy: { blockDelegateCall: true },
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reason).toBe('DELEGATECALL_BLOCKED');
+        expect(result.details?.actual).toBe(validLidoStakeTx.to);
+      });
+
+      it('should not block plain calls in strict mode', () => {
+        const result = shield.validate({
+          unsignedTransaction: execTransaction(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress: safe,
+          policy: { blockDelegateCall: true },
+        });
+
+        expect(result.isValid).toBe(true);
+      });
+
+      it('should check the policy against the Safe and the inner contract', () => {
+        const result = shield.validate({
+          unsignedTransaction: execTransaction(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress: safe,
+          policy: { blockedContracts: [validLidoStakeTx.to] },
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reason).toBe('CONTRACT_BLOCKED');
+      });
+
+      describe('SafeTx typed data', () => {
+        const safeTxTypes = {
+          SafeTx: [
+            { name: 'to', type: 'address' },
+            { name: 'value', type: 'uint256' },
+            { name: 'data', type: 'bytes' },
+            { name: 'operation', type: 'uint8' },
+            { name: 'safeTxGas', type: 'uint256' },
+            { name: 'baseGas', type: 'uint256' },
+            { name: 'gasPrice', type: 'uint256' },
+            { name: 'gasToken', type: 'address' },
+            { name: 'refundReceiver', type: 'address' },
+            { name: 'nonce', type: 'uint256' },
+          ],
+        };
+
+        const safeTx = (
+          message: Record<string, unknown> = {},
+          chainId = 1,
+        ) => ({
+          domain: { chainId, verifyingContract: safe },
+          types: safeTxTypes,
+          primaryType: 'SafeTx',
+          message: {
+            to: validLidoStakeTx.to,
+            value: '1000000000000000000',
+            data: validLidoStakeTx.data,
+            operation: 0,
+            safeTxGas: '0',
+            baseGas: '0',
+            gasPrice: '0',
+            gasToken: ethers.ZeroAddress,
+            refundReceiver: ethers.ZeroAddress,
+            nonce: '7',
+            ...message,
+          },
+        });
+
+        it('should validate the call a SafeTx confirms and report its hash', () => {
+          const typedData = safeTx();
+          const result = shield.validateTypedData({
+            yieldId: 'ethereum-eth-lido-staking',
+            typedData,
+            userAddress: safe,
+          });
+
+          expect(result.isValid).toBe(true);
+          expect(result.detectedType).toBe(TransactionType.STAKE);
+          expect(result.wrapper).toEqual({
+            detectedType: 'SAFE_EXEC_TRANSACTION',
+            address: safe,
+            operation: 'CALL',
+          });
+          expect(result.safeTxHash).toBe(
+            ethers.TypedDataEncoder.hash(
+              typedData.domain,
+              safeTxTypes,
+              typedData.message,
+            ),
+          );
+        });
+
+        it('should change the hash with the Safe nonce', () => {
+          const hash = (nonce: string) =>
+            shield.validateTypedData({
+              yieldId: 'ethereum-eth-lido-staking',
+              typedData: safeTx({ nonce }),
+              userAddress: safe,
+            }).safeTxHash;
+
+          expect(hash('7')).not.toBe(hash('8'));
+        });
+
+        it('should reject an inner call that matches no pattern', () => {
+          const result = shield.validateTypedData({
+            yieldId: 'ethereum-eth-lido-staking',
+            typedData: safeTx({
+              to: '0x0000000000000000000000000000000000000bad',
+            }),
+            userAddress: safe,
+          });
+
+          expect(result.isValid).toBe(false);
+          expect(result.reason).toContain(
+            'No matching operation pattern found',
+          );
+          expect(result.safeTxHash).toBeDefined();
+        });
+
+        it('should flag and block DelegateCall', () => {
+          const request = {
+            yieldId: 'ethereum-eth-lido-staking',
+            typedData: safeTx({ operation: 1 }),
+            userAddress: safe,
+          };
+
+          expect(
+            shield.validateTypedData(request).warnings?.map((w) => w.code),
+          ).toEqual(['DELEGATECALL_USED']);
+          expect(
+            shield.validateTypedData({
+              ...request,
+              policy: { blockDelegateCall: true },
+            }).reasonCode,
+          ).toBe('DELEGATECALL_BLOCKED');
+        });
+
+        it('should treat the Safe as the sender of the inner call', () => {
+          const result = shield.validateTypedData({
+            yieldId: 'ethereum-eth-lido-staking',
+            typedData: safeTx(),
+            userAddress: owner,
+          });
+
+          expect(result.isValid).toBe(false);
+          expect(result.reasonCode).toBe('SENDER_MISMATCH');
+        });
+
+        it('should reject a SafeTx for another chain or operation', () => {
+          for (const typedData of [safeTx({}, 137), safeTx({ operation: 2 })]) {
+            const result = shield.validateTypedData({
+              yieldId: 'ethereum-eth-lido-staking',
+              typedData,
+              userAddress: safe,
+            });
+
+            expect(result.isValid).toBe(false);
+            expect(result.reasonCode).toBe('TYPED_DATA_INVALID');
+          }
+        });
+
+        it('should reject a SafeTx type that differs from the Safe one', () => {
+          const result = shield.validateTypedData({
+            yieldId: 'ethereum-eth-lido-staking',
+            typedData: {
+              ...safeTx(),
+              types: { SafeTx: safeTxTypes.SafeTx.slice(0, -1) },
+            },
+            userAddress: safe,
+          });
+
+          expect(result.isValid).toBe(false);
+          expect(result.reasonCode).toBe('TYPED_DATA_INVALID');
+        });
+      });
+    });
+
+    describe('Forwarded calls', () => {
+      const forwarder = '0x3333333333333333333333333333333333333333';
+      const relayer = '0x4444444444444444444444444444444444444444';
+      // Well-known test keys; never use them on a live network
+      const staker = new ethers.Wallet(
+        '0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318',
+      );
+      const other = new ethers.Wallet('0x' + '11'.repeat(32));
+      const forwarderIface = new ethers.Interface([
+        'function execute((address from, address to, uint256 value, uint256 gas, uint256 nonce, bytes data) req, bytes signature) payable returns (bool, bytes)',
+      ]);
+      const forwardRequestTypes = {
+        ForwardRequest: [
+          { name: 'from', type: 'address' },
+          { name: 'to', type: 'address' },
+          { name: 'value', type: 'uint256' },
+          { name: 'gas', type: 'uint256' },
+          { name: 'nonce', type: 'uint256' },
+          { name: 'data', type: 'bytes' },
+        ],
+      };
+      const trusted = { trustedForwarders: [forwarder] };
+
+      const execute = (from = staker.address, signer = staker) => {
+        const req = {
+          from,
+          to: validLidoStakeTx.to,
+          value: BigInt(validLidoStakeTx.value),
+          gas: 300000n,
+          nonce: 7n,
+          data: validLidoStakeTx.data,
+        };
+        const domain = {
+          name: 'MinimalForwarder',
+          version: '0.0.1',
+          chainId: 1,
+          verifyingContract: forwarder,
+        };
+        const signature = signer.signingKey.sign(
+          ethers.TypedDataEncoder.hash(domain, forwardRequestTypes, req),
+        ).serialized;
+        return JSON.stringify({
+          to: forwarder,
+          from: relayer,
+          value: validLidoStakeTx.value,
+          data: forwarderIface.encodeFunctionData('execute', [req, signature]),
+          chainId: 1,
+        });
+      };
+
+      it('should validate the call a trusted forwarder relays', () => {
+        const result = shield.validate({
+          unsignedTransaction: execute(),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress: staker.address,
+          policy: trusted,
+          includeTiming: true,
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.detectedType).toBe(TransactionType.STAKE);
+        expect(result.wrapper).toEqual({
+          detectedType: 'ERC2771_FORWARD',
+          address: forwarder,
+          operation: 'CALL',
+          relayer,
+          signer: staker.address,
+        });
+        expect(result.timing?.path).toBe('evm-forwarder');
+      });
+
+      it('should reject a forwarder the policy does not trust', () => {
+        const result = shield.validate({
+          unsignedTransaction: execute(),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress: staker.address,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('UNTRUSTED_FORWARDER');
+        expect(result.details).toEqual({
+          yieldId: 'ethereum-eth-lido-staking',
+          forwarder,
+        });
+      });
+
+      it('should reject a request its from did not sign', () => {
+        const result = shield.validate({
+          unsignedTransaction: execute(staker.address, other),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress: staker.address,
+          policy: trusted,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('FORWARD_SIGNATURE_MISMATCH');
+        expect(result.details).toMatchObject({
+          expected: staker.address,
+          actual: other.address,
+        });
+      });
+
+      it('should check the request from, not the relayer, is the user', () => {
+        const forwarded = execute(other.address, other);
+
+        const result = shield.validate({
+          unsignedTransaction: forwarded,
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress: staker.address,
+          policy: trusted,
+        });
+        expect(result.reasonCode).toBe('SENDER_MISMATCH');
+        expect(result.details?.actual).toBe(other.address);
+
+        expect(
+          shield.validate({
+            unsignedTransaction: forwarded,
+            yieldId: 'ethereum-eth-lido-staking',
+            userAddress: relayer,
+            policy: trusted,
+          }).reasonCode,
+        ).toBe('SENDER_MISMATCH');
+      });
+    });
+
+    describe('Multicall transactions', () => {
+      const yieldId =
+        'arbitrum-arb-earb-1-0x7ed866d2d66c3149fafe854c30c68a8ba7cee8b9-4626-vault';
+      const vault = '0x7ed866d2d66c3149fafe854c30c68a8ba7cee8b9';
+      const token = '0x912ce59144191c1204e64559fe8253a0e49e6548';
+      const multicall3 = '0xcA11bde05977b3631167028862bE2a173976CA11';
+
+      const erc20Iface = new ethers.Interface([
+        'function approve(address spender, uint256 amount) returns (bool)',
+      ]);
+      const vaultIface = new ethers.Interface([
+        'function deposit(uint256 assets, address receiver) returns (uint256)',
+        'function multicall(bytes[] data) payable returns (bytes[] results)',
+        'function multicall(uint256 deadline, bytes[] data) payable returns (bytes[] results)',
+      ]);
+      const multicall3Iface = new ethers.Interface([
+        'function aggregate3((address target, bool allowFailure, bytes callData)[] calls) payable returns ((bool success, bytes returnData)[] returnData)',
+        'function aggregate3Value((address target, bool allowFailure, uint256 value, bytes callData)[] calls) payable returns ((bool success, bytes returnData)[] returnData)',
+      ]);
+
+      const deposit = (amount: bigint, receiver = userAddress) =>
+        vaultIface.encodeFunctionData('deposit', [amount, receiver]);
+      const buildTx = (to: string, data: string, value = '0x0') =>
+        JSON.stringify({ to, from: userAddress, value, data, chainId: 42161 });
+      const multicallTx = (...calls: string[]) =>
+        buildTx(
+          vault,
+          vaultIface.encodeFunctionData('multicall(bytes[])', [calls]),
+        );
+
+      const validate = (
+        unsignedTransaction: string,
+        expectedAmount?: string,
+      ) =>
+        shield.validate({
+          unsignedTransaction,
+          yieldId,
+          userAddress,
+          expectedAmount,
+        });
+
+      it('should report the multicall path in timing', () => {
+        const result = shield.validate({
+          unsignedTransaction: multicallTx(deposit(100n)),
+          yieldId,
+          userAddress,
+          includeTiming: true,
+        });
+
+        expect(result.timing?.path).toBe('evm-multicall');
+      });
+
+      it('should validate each call of a multicall', () => {
+        const result = validate(multicallTx(deposit(100n), deposit(50n)));
+
+        expect(result.isValid).toBe(true);
+        expect(result.detectedType).toBe(TransactionType.SUPPLY);
+        expect(result.detectedTypes).toEqual([
+          TransactionType.SUPPLY,
+          TransactionType.SUPPLY,
+        ]);
+        expect(result.subResults?.map((r) => r.detectedType)).toEqual([
+          TransactionType.SUPPLY,
+          TransactionType.SUPPLY,
+        ]);
+        expect(result.multicall).toEqual({
+          detectedType: 'MULTICALL',
+          address: vault,
+          functionName: 'multicall',
+          value: '0',
+          calls: [
+            {
+              target: vault,
+              value: '0',
+              data: deposit(100n),
+              allowFailure: false,
+            },
+            {
+              target: vault,
+              value: '0',
+              data: deposit(50n),
+              allowFailure: false,
+            },
+          ],
+        });
+        expect(result.expectedRecipient).toBe(vault);
+      });
+
+      it('should accept the deadline variant of multicall', () => {
+        const result = validate(
+          buildTx(
+            vault,
+            vaultIface.encodeFunctionData('multicall(uint256,bytes[])', [
+              1900000000n,
+              [deposit(100n)],
+            ]),
+          ),
+        );
+
+        expect(result.isValid).toBe(true);
+        expect(result.amount?.amount).toBe('100');
+      });
+
+      it('should reject a multicall with an invalid call', () => {
+        const stranger = '0x0000000000000000000000000000000000000bad';
+        const result = validate(
+          multicallTx(deposit(100n), deposit(50n, stranger)),
+        );
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('MULTICALL_CALL_INVALID');
+        expect(result.details?.subCall).toBe(1);
+        expect(result.subResults?.map((r) => r.isValid)).toEqual([true, false]);
+      });
+
+      it('should reject nested multicalls', () => {
+        const result = validate(
+          multicallTx(
+            vaultIface.encodeFunctionData('multicall(bytes[])', [
+              [deposit(100n)],
+            ]),
+          ),
+        );
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('MULTICALL_CALL_INVALID');
+      });
+
+      it('should require a call beyond approvals', () => {
+        const result = validate(multicallTx());
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('MULTICALL_CALL_MISSING');
+      });
+
+      it('should check expectedAmount against the batch', () => {
+        const result = validate(multicallTx(deposit(100n)), '200');
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('AMOUNT_MISMATCH');
+        expect(result.subResults).toHaveLength(1);
+      });
+
+      it('should treat Multicall3 as the sender of its calls', () => {
+        const result = validate(
+          buildTx(
+            multicall3,
+            multicall3Iface.encodeFunctionData('aggregate3', [
+              [
+                [
+                  token,
+                  false,
+                  erc20Iface.encodeFunctionData('approve', [vault, 100n]),
+                ],
+                [vault, false, deposit(100n)],
+              ],
+            ]),
+          ),
+        );
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('MULTICALL_CALL_INVALID');
+        expect(result.multicall?.detectedType).toBe('MULTICALL3_AGGREGATE');
+        expect(result.multicall?.calls.map((c) => c.target)).toEqual([
+          ethers.getAddress(token),
+          ethers.getAddress(vault),
+        ]);
+        expect(result.subResults?.[0].reasonCode).toBe('SENDER_MISMATCH');
+        expect(result.subResults?.[0].details?.actual).toBe(multicall3);
+      });
+
+      it('should reject Multicall3 calls to contracts outside the yield', () => {
+        const stranger = '0x0000000000000000000000000000000000000bad';
+        const result = validate(
+          buildTx(
+            multicall3,
+            multicall3Iface.encodeFunctionData('aggregate3', [
+              [
+                [vault, false, deposit(100n)],
+                [stranger, true, '0x'],
+              ],
+            ]),
+          ),
+        );
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('RECIPIENT_MISMATCH');
+        expect(result.details).toEqual({
+          yieldId,
+          subCall: 1,
+          actual: ethers.getAddress(stranger),
+        });
+      });
+
+      it('should reject value Multicall3 does not pass on', () => {
+        const result = validate(
+          buildTx(
+            multicall3,
+            multicall3Iface.encodeFunctionData('aggregate3Value', [
+              [[vault, false, 1n, deposit(100n)]],
+            ]),
+            '0x2',
+          ),
+        );
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('MULTICALL_VALUE_MISMATCH');
+        expect(result.details?.expected).toBe('2');
+        expect(result.details?.actual).toBe('1');
+      });
+    });
+
+    describe('Expected recipient', () => {
+      it('should report the contract a valid transaction was matched against', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.expectedRecipient).toBe(validLidoStakeTx.to);
+        expect(result.expectedRecipients).toBeUndefined();
+      });
+
+      it('should not report a recipient for rejected transactions', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify({
+            ...validLidoStakeTx,
+            to: '0x0000000000000000000000000000000000000001',
+          }),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.expectedRecipient).toBeUndefined();
+      });
+    });
+
+    describe('Contract policy', () => {
+      const lidoStEth = validLidoStakeTx.to;
+
+      it('should reject a blocked contract', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+          policy: { blockedContracts: [lidoStEth.toLowerCase()] },
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reason).toBe('CONTRACT_BLOCKED');
+        expect(result.details?.actual).toBe(lidoStEth);
+      });
+
+      it('should reject a contract missing from the allowlist', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+          policy: {
+            allowedContracts: ['0x889edC2eDab5f40e902b864aD4d7AdE8E412F9B1'],
+          },
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reason).toBe('CONTRACT_NOT_ALLOWED');
+      });
+
+      it('should accept an allowlisted contract', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+          policy: { allowedContracts: [lidoStEth], blockedContracts: [] },
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.detectedType).toBe(TransactionType.STAKE);
+      });
+
+      it('should not let an allowlist pass a transaction Shield rejects', () => {
+        const unknownContract = '0x0000000000000000000000000000000000000001';
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify({
+            ...validLidoStakeTx,
+            to: unknownContract,
+          }),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+          policy: { allowedContracts: [unknownContract] },
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reason).toContain('No matching operation pattern found');
+      });
+    });
+
+    describe('Amount limits', () => {
+      // validLidoStakeTx stakes 1 ETH
+      const validate = (amountLimits: Record<string, object>) =>
+        shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+          policy: { amountLimits },
+        });
+
+      it('should reject an amount above the maxAmount', () => {
+        const result = validate({
+          'ethereum-eth-lido-staking': { maxAmount: '0.5' },
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('AMOUNT_ABOVE_LIMIT');
+        expect(result.details).toMatchObject({ limit: '0.5', actual: '1.0' });
+      });
+
+      it('should reject an amount below the minAmount', () => {
+        const result = validate({
+          'ethereum-eth-lido-staking': { minAmount: '1.000000000000000001' },
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('AMOUNT_BELOW_MINIMUM');
+      });
+
+      it('should accept an amount equal to either limit', () => {
+        const result = validate({
+          'ethereum-eth-lido-staking': { minAmount: '1', maxAmount: '1.00' },
+        });
+
+        expect(result.isValid).toBe(true);
+      });
+
+      it("should ignore other yields' limits", () => {
+        const result = validate({
+          'ethereum-eth-other-staking': { maxAmount: '0.5' },
+        });
+
+        expect(result.isValid).toBe(true);
+      });
+
+      it('should reject limits that are not whole units', () => {
+        const result = validate({
+          'ethereum-eth-lido-staking': { maxAmount: '1e18' },
+        });
+
+        expect(result.reasonCode).toBe('INVALID_REQUEST');
+      });
+    });
+
+    describe('Numeric fields', () => {
+      const validate = (fields: Record<string, unknown>) =>
+        shield.validate({
+          unsignedTransaction: JSON.stringify({
+            ...validLidoStakeTx,
+            ...fields,
+          }),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+      it('should accept decimal and hex quantities alike', () => {
+        const hex = validate({
+          value: '0xde0b6b3a7640000',
+          nonce: '0x7',
+          gasLimit: '0x30d40',
+          gasPrice: '0x4a817c800',
+          chainId: '0x1',
+        });
+        const decimal = validate({
+          value: '1000000000000000000',
+          nonce: '7',
+          gasLimit: '200000',
+          gasPrice: '20000000000',
+          chainId: '1',
+        });
+        const numbers = validate({
+          nonce: 7,
+          gasLimit: 200000,
+          gasPrice: 20000000000,
+          chainId: 1,
+        });
+
+        for (const result of [hex, decimal, numbers]) {
+          expect(result.isValid).toBe(true);
+          expect(result.detectedType).toBe(TransactionType.STAKE);
+          expect(result.decoded?.value).toBe('1000000000000000000');
+          expect(result.amount?.amount).toBe('1000000000000000000');
+        }
+      });
+
+      it('should reject quantities that are not integers', () => {
+        const cases: Array<[string, unknown]> = [
+          ['value', '1.5'],
+          ['value', '-1'],
+          ['value', '0xzz'],
+          ['nonce', 'seven'],
+          ['gasLimit', 'lots'],
+          ['gasPrice', ''],
+          ['maxFeePerGas', 1.5],
+          ['chainId', 'mainnet'],
+        ];
+
+        for (const [field, value] of cases) {
+          const result = validate({ [field]: value });
+
+          expect(result.isValid).toBe(false);
+          expect(result.reasonCode).toBe('MALFORMED_NUMERIC');
+          expect(result.details).toEqual({
+            yieldId: 'ethereum-eth-lido-staking',
+            field,
+            actual: value,
+          });
+        }
+      });
+    });
+
+    describe('Replay protection', () => {
+      const validate = (fields: Record<string, unknown>, strict?: boolean) =>
+        shield.validate({
+          unsignedTransaction: JSON.stringify({
+            ...validLidoStakeTx,
+            ...fields,
+          }),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+          strict,
+        });
+
+      it('should warn about a legacy transaction with chain ID 0', () => {
+        for (const fields of [
+          { chainId: 0 },
+          { chainId: '0x0', type: 0, gasPrice: '0x4a817c800' },
+        ]) {
+          const result = validate(fields);
+
+          expect(result.isValid).toBe(true);
+          expect(result.detectedType).toBe(TransactionType.STAKE);
+          expect(result.warnings).toContainEqual({
+            code: 'UNPROTECTED_REPLAY',
+            message:
+              'Transaction has chain ID 0, so it can be replayed on any chain',
+            details: { expected: '1', actual: '0' },
+            severity: 'critical',
+          });
+        }
+      });
+
+      it('should reject it in strict mode', () => {
+        const result = validate({ chainId: 0 }, true);
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('STRICT_MODE_WARNING');
+        expect(result.details?.warningCodes).toEqual(['UNPROTECTED_REPLAY']);
+      });
+
+      it('should reject a typed transaction with chain ID 0', () => {
+        const result = validate({
+          chainId: 0,
+          type: 2,
+          maxFeePerGas: '0x4a817c800',
+          maxPriorityFeePerGas: '0x3b9aca00',
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('CHAIN_ID_MISMATCH');
+        expect(result.details).toEqual({
+          yieldId: 'ethereum-eth-lido-staking',
+          expected: '1',
+          actual: '0',
+        });
+      });
+
+      it('should not warn about a transaction bound to its chain', () => {
+        const result = validate({});
+
+        expect(result.isValid).toBe(true);
+        expect(
+          result.warnings?.some((w) => w.code === 'UNPROTECTED_REPLAY'),
+        ).toBeFalsy();
+      });
+    });
+
+    describe('Calldata size', () => {
+      const padded = (bytes: number) =>
+        JSON.stringify({
+          ...validLidoStakeTx,
+          data: validLidoStakeTx.data + '00'.repeat(bytes - 36),
+        });
+
+      it('should report the calldata length', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.decoded?.calldataBytes).toBe(36);
+      });
+
+      it('should reject calldata over the default limit', () => {
+        const result = shield.validate({
+          unsignedTransaction: padded(8 * 1024 + 1),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('CALLDATA_TOO_LARGE');
+        expect(result.details).toEqual({
+          yieldId: 'ethereum-eth-lido-staking',
+          expected: 8 * 1024,
+          actual: 8 * 1024 + 1,
+        });
+      });
+
+      it('should apply the policy limit', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+          policy: { maxCalldataBytes: 35 },
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('CALLDATA_TOO_LARGE');
+        expect(result.details?.expected).toBe(35);
+      });
+    });
+
+    describe('Failed validations', () => {
+      it('should reject transaction that matches no patterns and not set detectedType', () => {
+        const invalidTx = {
+          to: '0x0000000000000000000000000000000000000000',
+          from: userAddress,
+          value: '0x0',
+          data: '0xdeadbeef',
+          chainId: 1,
+        };
+
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(invalidTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reason).toContain('No matching operation pattern found');
+        expect(result.detectedType).toBeUndefined();
+      });
+
+      it('should include attempts in details when no pattern matches', () => {
+        const invalidTx = {
+          to: '0x0000000000000000000000000000000000000000',
+          from: userAddress,
+          value: '0x0',
+          data: '0xdeadbeef',
+          chainId: 1,
+        };
+
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(invalidTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.detectedType).toBeUndefined();
+        expect(result.details?.attempts).toBeDefined();
+        expect(Array.isArray(result.details?.attempts)).toBe(true);
+        expect(result.details?.attempts?.length).toBe(3); // STAKE, UNSTAKE, CLAIM_UNSTAKED
+
+        result.details?.attempts?.forEach((attempt: any) => {
+          expect(attempt.type).toBeDefined();
+          expect(attempt.reason).toBeDefined();
+        });
+      });
+
+      it('should not set detectedType for transactions that fail validation', () => {
+        const invalidReferralTx = {
+          to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+          from: userAddress,
+          value: '0xde0b6b3a7640000',
+          data:
+            '0xa1903eab' +
+            '0000000000000000000000000000000000000000000000000000000000000000', // wrong referral
+          chainId: 1,
+        };
+
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(invalidReferralTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reason).toContain('No matching operation pattern found');
+        expect(result.detectedType).toBeUndefined();
+      });
+
+      it('should not set detectedType for wrong user address', () => {
+        const wrongUserTx = {
+          to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+          from: '0x0000000000000000000000000000000000000001', // wrong from address
+          value: '0xde0b6b3a7640000',
+          data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+          chainId: 1,
+        };
+
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(wrongUserTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reason).toBe('SENDER_MISMATCH');
+        expect(result.detectedType).toBeUndefined();
+      });
+
+      it('should compare EVM sender addresses case-insensitively', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress: userAddress.toUpperCase().replace('0X', '0x'),
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.detectedType).toBe(TransactionType.STAKE);
+      });
+
+      it('should reject a transaction for another chain', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify({
+            ...validLidoStakeTx,
+            chainId: 42161,
+          }),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reason).toBe('CHAIN_ID_MISMATCH');
+        expect(result.details?.expected).toBe('1');
+        expect(result.details?.actual).toBe('42161');
+      });
+
+      it('should accept a hex chainId', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify({
+            ...validLidoStakeTx,
+            chainId: '0x1',
+          }),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(true);
+      });
+
+      it('should reject invalid gas fields', () => {
+        const cases: Array<[Record<string, unknown>, string]> = [
+          [{ gasPrice: '0x0' }, 'Zero gas price'],
+          [{ maxFeePerGas: 0, maxPriorityFeePerGas: 0 }, 'Zero gas price'],
+          [{ gasLimit: 20000 }, 'below the 21000'],
+          [
+            { maxFeePerGas: '0x3b9aca00', maxPriorityFeePerGas: '0x6fc23ac00' },
+            'exceeds maxFeePerGas',
+          ],
+        ];
+
+        for (const [fields, error] of cases) {
+          const result = shield.validate({
+            unsignedTransaction: JSON.stringify({
+              ...validLidoStakeTx,
+              ...fields,
+            }),
+            yieldId: 'ethereum-eth-lido-staking',
+            userAddress,
+          });
+
+          expect(result.isValid).toBe(false);
+          expect(result.reason).toBe('INVALID_GAS_FIELDS');
+          expect(result.details?.error).toContain(error);
+        }
+      });
+
+      it('should warn when userAddress is omitted', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.detectedType).toBe(TransactionType.STAKE);
+        expect(result.warnings).toContainEqual({
+          code: 'SENDER_NOT_VERIFIED',
+          message:
+            'No userAddress was provided, so the transaction sender was not checked',
+          details: { sender: validLidoStakeTx.from },
+          severity: 'info',
+        });
+      });
+
+      it('should not allow for two or more matches', () => {
+        // Create a mock validator that returns multiple valid matches
+        const mockValidator = {
+          getSigner: jest.fn().mockReturnValue(undefined),
+          getChainId: jest.fn().mockReturnValue(undefined),
+          isReplayable: jest.fn().mockReturnValue(false),
+          getLookupTableAddresses: jest.fn().mockReturnValue([]),
+          getNumericError: jest.fn().mockReturnValue(undefined),
+          getMalformedError: jest.fn().mockReturnValue(undefined),
+          getStakedBalanceCall: jest.fn().mockReturnValue(undefined),
+          getClaimedPositions: jest.fn().mockReturnValue(undefined),
+          getBytecodeHash: jest.fn().mockReturnValue(undefined),
+          getImplementationChange: jest.fn().mockReturnValue(undefined),
+          getPrivilegedCall: jest.fn().mockReturnValue(undefined),
+          getGasError: jest.fn().mockReturnValue(undefined),
+          getCalldataSize: jest.fn().mockReturnValue(undefined),
+          getWrappedTransaction: jest.fn().mockReturnValue(undefined),
+          getMulticall: jest.fn().mockReturnValue(undefined),
+          getApproval: jest.fn().mockReturnValue(undefined),
+          getMismatchCode: jest.fn().mockReturnValue(undefined),
+          getSelector: jest.fn().mockReturnValue(undefined),
+          getClaimCall: jest.fn().mockReturnValue(undefined),
+          getWithdrawal: jest.fn().mockReturnValue(undefined),
+          getBeneficiary: jest.fn().mockReturnValue(undefined),
+          getRecipientName: jest.fn().mockReturnValue(undefined),
+          getMemo: jest.fn().mockReturnValue(undefined),
+          getDeadline: jest.fn().mockReturnValue(undefined),
+          getNativeValue: jest.fn().mockReturnValue(undefined),
+          getSupportedTransactionTypes: jest
+            .fn()
+            .mockReturnValue([
+              TransactionType.STAKE,
+              TransactionType.UNSTAKE,
+              TransactionType.CLAIM_UNSTAKED,
+            ]),
+          validate: jest.fn().mockImplementation((_, transactionType) => {
+            // Mock to return valid for both STAKE and UNSTAKE
+            if (
+              transactionType === TransactionType.STAKE ||
+              transactionType === TransactionType.UNSTAKE
+            ) {
+              return { isValid: true };
+            }
+            return { isValid: false, reason: 'Not supported' };
+          }),
+        };
+
+        // Store the original validator
+        const originalValidator = validatorRegistry.get(
+          'ethereum-eth-lido-staking',
+        );
+
+        // Temporarily replace the validator in the registry
+        (validatorRegistry as any).set(
+          'ethereum-eth-lido-staking',
+          mockValidator,
+        );
+
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reason).toContain(
+          'Ambiguous transaction pattern detected',
+        );
+        expect(result.reason).toContain(
+          'Transaction matches multiple operation types',
+        );
+        expect(result.details?.matchedTypes).toEqual([
+          TransactionType.STAKE,
+          TransactionType.UNSTAKE,
+        ]);
+        expect(result.details?.warning).toContain(
+          'A legitimate transaction must match exactly one pattern',
+        );
+        expect(result.detectedType).toBeUndefined();
+
+        // Restore the original validator
+        if (originalValidator) {
+          (validatorRegistry as any).set(
+            'ethereum-eth-lido-staking',
+            originalValidator,
+          );
+        }
+      });
+
+      it('should reject a match that calls a function of another type', () => {
+        const originalValidator = validatorRegistry.get(
+          'ethereum-eth-lido-staking',
+        )!;
+        // Matches the stake call as an unstake, which Lido's ABI lists
+        // other functions for
+        const mockValidator = Object.assign(Object.create(originalValidator), {
+          validate: jest.fn().mockImplementation((_, transactionType) =>
+            transactionType === TransactionType.UNSTAKE
+              ? { isValid: true }
+              : { isValid: false, reason: 'Not supported' },
+          ),
+        });
+        (validatorRegistry as any).set(
+          'ethereum-eth-lido-staking',
+          mockValidator,
+        );
+
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        (validatorRegistry as any).set(
+          'ethereum-eth-lido-staking',
+          originalValidator,
+        );
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('SELECTOR_NOT_ALLOWED');
+        expect(result.details?.detectedType).toBe(TransactionType.UNSTAKE);
+        expect(result.details?.actual).toBe('0xa1903eab');
+        expect(result.details?.expected).toEqual(
+          shield
+            .getYieldAbi('ethereum-eth-lido-staking', TransactionType.UNSTAKE)
+            ?.map((fn) => fn.selector),
+        );
+      });
+
+      it('should handle validator throwing an error', () => {
+        // Create a mock validator that throws an error
+        const mockValidator = {
+          getSigner: jest.fn().mockReturnValue(undefined),
+          getChainId: jest.fn().mockReturnValue(undefined),
+          isReplayable: jest.fn().mockReturnValue(false),
+          getLookupTableAddresses: jest.fn().mockReturnValue([]),
+          getNumericError: jest.fn().mockReturnValue(undefined),
+          getMalformedError: jest.fn().mockReturnValue(undefined),
+          getStakedBalanceCall: jest.fn().mockReturnValue(undefined),
+          getClaimedPositions: jest.fn().mockReturnValue(undefined),
+          getBytecodeHash: jest.fn().mockReturnValue(undefined),
+          getImplementationChange: jest.fn().mockReturnValue(undefined),
+          getPrivilegedCall: jest.fn().mockReturnValue(undefined),
+          getGasError: jest.fn().mockReturnValue(undefined),
+          getCalldataSize: jest.fn().mockReturnValue(undefined),
+          getWrappedTransaction: jest.fn().mockReturnValue(undefined),
+          getMulticall: jest.fn().mockReturnValue(undefined),
+          getApproval: jest.fn().mockReturnValue(undefined),
+          getMismatchCode: jest.fn().mockReturnValue(undefined),
+          getSelector: jest.fn().mockReturnValue(undefined),
+          getClaimCall: jest.fn().mockReturnValue(undefined),
+          getWithdrawal: jest.fn().mockReturnValue(undefined),
+          getBeneficiary: jest.fn().mockReturnValue(undefined),
+          getRecipientName: jest.fn().mockReturnValue(undefined),
+          getMemo: jest.fn().mockReturnValue(undefined),
+          getDeadline: jest.fn().mockReturnValue(undefined),
+          getNativeValue: jest.fn().mockReturnValue(undefined),
+          getSupportedTransactionTypes: jest
+            .fn()
+            .mockReturnValue([TransactionType.STAKE]),
+          validate: jest.fn().mockImplementation(() => {
+            throw new Error('Validator internal error');
+          }),
+        };
+
+        // Store the original validator
+        const originalValidator = validatorRegistry.get(
+          'ethereum-eth-lido-staking',
+        );
+
+        // Temporarily replace the validator in the registry
+        (validatorRegistry as any).set(
+          'ethereum-eth-lido-staking',
+          mockValidator,
+        );
+
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reason).toContain('No matching operation pattern found');
+        expect(result.reasonCode).toBe('NO_MATCHING_PATTERN');
+        expect(result.details?.attempts).toBeDefined();
+        expect(Array.isArray(result.details?.attempts)).toBe(true);
+        expect(result.details?.attempts?.length).toBe(1);
+        expect(result.details?.attempts?.[0].type).toBe(TransactionType.STAKE);
+        expect(result.details?.attempts?.[0].reason).toBe(
+          'Validator internal error',
+        );
+        expect(result.detectedType).toBeUndefined();
+
+        // Restore the original validator
+        if (originalValidator) {
+          (validatorRegistry as any).set(
+            'ethereum-eth-lido-staking',
+            originalValidator,
+          );
+        }
+      });
+    });
+
+    describe('Timing', () => {
+      it('should only report timing when includeTiming is set', () => {
+        const request = {
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+        };
+
+        expect(shield.validate(request).timing).toBeUndefined();
+
+        const result = shield.validate({ ...request, includeTiming: true });
+        expect(result.isValid).toBe(true);
+        expect(result.timing).toEqual({
+          decodeMs: expect.any(Number),
+          matchMs: expect.any(Number),
+          totalMs: result.timing?.matchMs,
+          path: 'evm-abi-match',
+        });
+        expect(result.timing?.simulateMs).toBeUndefined();
+      });
+
+      it('should report timing for rejected transactions', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify({
+            ...validLidoStakeTx,
+            chainId: 5,
+          }),
+          yieldId: 'ethereum-eth-lido-staking',
+          userAddress,
+          includeTiming: true,
+        });
+
+        expect(result.reasonCode).toBe('CHAIN_ID_MISMATCH');
+        expect(result.timing?.path).toBe('evm-abi-match');
+      });
+
+      it('should report no path for an unknown yield', () => {
+        const result = shield.validate({
+          unsignedTransaction: JSON.stringify(validLidoStakeTx),
+          yieldId: 'unknown-yield',
+          userAddress,
+          includeTiming: true,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.timing).toMatchObject({ decodeMs: 0, path: 'none' });
+      });
+
+      it('should name the path of each transaction family', () => {
+        const cases = [
+          [
+            'cosmos-atom-native-staking',
+            JSON.stringify({ messages: [] }),
+            'cosmos-message-match',
+          ],
+          ['near-near-native-staking', '{}', 'near-action-match'],
+          ['dot-dot-native-staking', '0x00', 'substrate-call-match'],
+        ];
+        for (const [yieldId, unsignedTransaction, path] of cases) {
+          const result = shield.validate({
+            yieldId,
+            unsignedTransaction,
+            includeTiming: true,
+          });
+
+          expect(result.timing?.path).toBe(path);
+        }
+      });
+    });
+  });
+
+  describe('validateFlow', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const yieldId =
+      'arbitrum-arb-earb-1-0x7ed866d2d66c3149fafe854c30c68a8ba7cee8b9-4626-vault';
+    const vault = '0x7ed866d2d66c3149fafe854c30c68a8ba7cee8b9';
+    const token = '0x912ce59144191c1204e64559fe8253a0e49e6548';
+
+    const erc20Iface = new ethers.Interface([
+      'function approve(address spender, uint256 amount) returns (bool)',
+    ]);
+    const vaultIface = new ethers.Interface([
+      'function deposit(uint256 assets, address receiver) returns (uint256)',
+    ]);
+
+    const buildTx = (to: string, data: string) =>
+      JSON.stringify({
+        to,
+        from: userAddress,
+        value: '0x0',
+        data,
+        chainId: 42161,
+      });
+    const approveTx = (amount: bigint) =>
+      buildTx(token, erc20Iface.encodeFunctionData('approve', [vault, amount]));
+    const depositTx = (amount: bigint) =>
+      buildTx(
+        vault,
+        vaultIface.encodeFunctionData('deposit', [amount, userAddress]),
+      );
+
+    it('should accept a deposit covered by an earlier approval', () => {
+      const result = shield.validateFlow({
+        yieldId,
+        userAddress,
+        transactions: [approveTx(100n), depositTx(100n)],
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.steps.map((step) => step.detectedType)).toEqual([
+        TransactionType.APPROVAL,
+        TransactionType.SUPPLY,
+      ]);
+    });
+
+    it('should reject a deposit larger than the approval', () => {
+      const result = shield.validateFlow({
+        yieldId,
+        userAddress,
+        transactions: [approveTx(100n), depositTx(101n)],
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('APPROVAL_INSUFFICIENT_FOR_DEPOSIT');
+      expect(result.details).toEqual({
+        step: 1,
+        approved: '100',
+        required: '101',
+      });
+      expect(result.steps).toHaveLength(2);
+    });
+
+    it('should count deposits against the remaining allowance', () => {
+      const result = shield.validateFlow({
+        yieldId,
+        userAddress,
+        transactions: [approveTx(100n), depositTx(60n), depositTx(60n)],
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('APPROVAL_INSUFFICIENT_FOR_DEPOSIT');
+      expect(result.details?.step).toBe(2);
+      expect(result.details?.approved).toBe('40');
+    });
+
+    it('should accept any deposit under an unlimited approval', () => {
+      const result = shield.validateFlow({
+        yieldId,
+        userAddress,
+        transactions: [approveTx(ethers.MaxUint256), depositTx(10n ** 24n)],
+      });
+
+      expect(result.isValid).toBe(true);
+    });
+
+    it('should reject an approval larger than the deposit by policy', () => {
+      const result = shield.validateFlow({
+        yieldId,
+        userAddress,
+        transactions: [approveTx(110n), depositTx(100n)],
+        policy: { maxApprovalExcessBps: 500 },
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('APPROVAL_EXCEEDS_STAKE');
+      expect(result.details).toEqual({
+        step: 0,
+        approved: '110',
+        staked: '100',
+        maxApprovalExcessBps: 500,
+      });
+    });
+
+    it('should accept approvals within maxApprovalExcessBps', () => {
+      const within = (transactions: string[], maxApprovalExcessBps: number) =>
+        shield.validateFlow({
+          yieldId,
+          userAddress,
+          transactions,
+          policy: { maxApprovalExcessBps },
+        }).isValid;
+
+      expect(within([approveTx(100n), depositTx(100n)], 0)).toBe(true);
+      expect(within([approveTx(105n), depositTx(100n)], 500)).toBe(true);
+      expect(
+        within([approveTx(100n), depositTx(60n), depositTx(40n)], 0),
+      ).toBe(true);
+      expect(within([approveTx(101n), depositTx(100n)], 0)).toBe(false);
+      expect(
+        within([approveTx(ethers.MaxUint256), depositTx(100n)], 10000),
+      ).toBe(false);
+    });
+
+    it('should check an approval the flow replaces', () => {
+      const result = shield.validateFlow({
+        yieldId,
+        userAddress,
+        transactions: [
+          approveTx(200n),
+          depositTx(100n),
+          approveTx(50n),
+          depositTx(50n),
+        ],
+        policy: { maxApprovalExcessBps: 0 },
+      });
+
+      expect(result.reason).toBe('APPROVAL_EXCEEDS_STAKE');
+      expect(result.details?.step).toBe(0);
+    });
+
+    it('should report the first step that fails on its own', () => {
+      const result = shield.validateFlow({
+        yieldId,
+        userAddress,
+        transactions: [approveTx(100n), buildTx(vault, '0xdeadbeef')],
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('FLOW_STEP_INVALID');
+      expect(result.details?.step).toBe(1);
+      expect(result.steps[0].isValid).toBe(true);
+      expect(result.steps[1].isValid).toBe(false);
+    });
+
+    it('should reject an empty flow', () => {
+      const result = shield.validateFlow({
+        yieldId,
+        userAddress,
+        transactions: [],
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('Invalid request parameters');
+      expect(result.steps).toEqual([]);
+    });
+  });
+
+  describe('validateUserOperation', () => {
+    const account = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const yieldId =
+      'arbitrum-arb-earb-1-0x7ed866d2d66c3149fafe854c30c68a8ba7cee8b9-4626-vault';
+    const vault = '0x7ed866d2d66c3149fafe854c30c68a8ba7cee8b9';
+    const token = '0x912ce59144191c1204e64559fe8253a0e49e6548';
+    const paymaster = '0x00000000000000fb866daaa79352cc568a005d96';
+
+    const erc20Iface = new ethers.Interface([
+      'function approve(address spender, uint256 amount) returns (bool)',
+    ]);
+    const vaultIface = new ethers.Interface([
+      'function deposit(uint256 assets, address receiver) returns (uint256)',
+    ]);
+    const accountIface = new ethers.Interface([
+      'function execute(address dest, uint256 value, bytes func)',
+      'function executeBatch(address[] dest, bytes[] func)',
+    ]);
+
+    const approveData = (amount: bigint) =>
+      erc20Iface.encodeFunctionData('approve', [vault, amount]);
+    const depositData = (amount: bigint) =>
+      vaultIface.encodeFunctionData('deposit', [amount, account]);
+    const userOperation = (callData: string, paymasterAndData = '0x') => ({
+      sender: account,
+      nonce: '0x0',
+      callData,
+      paymasterAndData,
+    });
+
+    it('should validate a batched approval and deposit', () => {
+      const result = shield.validateUserOperation({
+        yieldId,
+        userOperation: userOperation(
+          accountIface.encodeFunctionData('executeBatch', [
+            [token, vault],
+            [approveData(100n), depositData(100n)],
+          ]),
+        ),
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.SUPPLY);
+      expect(result.detectedTypes).toEqual([
+        TransactionType.APPROVAL,
+        TransactionType.SUPPLY,
+      ]);
+      expect(result.steps.map((step) => step.detectedType)).toEqual([
+        TransactionType.APPROVAL,
+        TransactionType.SUPPLY,
+      ]);
+      expect(result.paymaster).toBeUndefined();
+      expect(result.warnings).toBeUndefined();
+    });
+
+    it('should check batched deposits against the batched approval', () => {
+      const result = shield.validateUserOperation({
+        yieldId,
+        userOperation: userOperation(
+          accountIface.encodeFunctionData('executeBatch', [
+            [token, vault],
+            [approveData(100n), depositData(101n)],
+          ]),
+        ),
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('APPROVAL_INSUFFICIENT_FOR_DEPOSIT');
+      expect(result.detectedType).toBeUndefined();
+    });
+
+    it('should reject an inner call to another contract', () => {
+      const result = shield.validateUserOperation({
+        yieldId,
+        userOperation: userOperation(
+          accountIface.encodeFunctionData('execute', [
+            '0x0000000000000000000000000000000000000bad',
+            0n,
+            depositData(100n),
+          ]),
+        ),
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('FLOW_STEP_INVALID');
+    });
+
+    it('should reject a userAddress other than the sender', () => {
+      const result = shield.validateUserOperation({
+        yieldId,
+        userOperation: userOperation(
+          accountIface.encodeFunctionData('execute', [
+            vault,
+            0n,
+            depositData(100n),
+          ]),
+        ),
+        userAddress: '0x0000000000000000000000000000000000000001',
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('SENDER_MISMATCH');
+    });
+
+    it('should reject callData that is not an account call', () => {
+      const result = shield.validateUserOperation({
+        yieldId,
+        userOperation: userOperation(depositData(100n)),
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('UNSUPPORTED_ACCOUNT_CALL');
+      expect(result.details?.selector).toBe(depositData(100n).slice(0, 10));
+    });
+
+    it('should flag a paymaster that was not expected', () => {
+      const result = shield.validateUserOperation({
+        yieldId,
+        userOperation: userOperation(
+          accountIface.encodeFunctionData('execute', [
+            vault,
+            0n,
+            depositData(100n),
+          ]),
+          paymaster + 'ff'.repeat(16),
+        ),
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.paymaster).toBe(paymaster);
+      expect(result.warnings?.map((w) => w.code)).toEqual([
+        'UNKNOWN_PAYMASTER',
+      ]);
+    });
+
+    it('should reject an unknown paymaster in strict mode', () => {
+      const result = shield.validateUserOperation({
+        yieldId,
+        userOperation: userOperation(
+          accountIface.encodeFunctionData('execute', [
+            vault,
+            0n,
+            depositData(100n),
+          ]),
+          paymaster,
+        ),
+        strict: true,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('STRICT_MODE_WARNING');
+      expect(result.details?.warningCodes).toEqual(['UNKNOWN_PAYMASTER']);
+    });
+
+    it('should accept an expected paymaster', () => {
+      const result = shield.validateUserOperation({
+        yieldId,
+        userOperation: userOperation(
+          accountIface.encodeFunctionData('execute', [
+            vault,
+            0n,
+            depositData(100n),
+          ]),
+          paymaster,
+        ),
+        paymasters: [paymaster.toUpperCase().replace('0X', '0x')],
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.paymaster).toBe(paymaster);
+      expect(result.warnings).toBeUndefined();
+    });
+
+    it('should reject malformed paymasterAndData', () => {
+      const result = shield.validateUserOperation({
+        yieldId,
+        userOperation: userOperation('0xb61d27f6', '0x1234'),
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('Invalid request parameters');
+    });
+  });
+
+  describe('validateSendCalls', () => {
+    const account = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const yieldId =
+      'arbitrum-arb-earb-1-0x7ed866d2d66c3149fafe854c30c68a8ba7cee8b9-4626-vault';
+    const vault = '0x7ed866d2d66c3149fafe854c30c68a8ba7cee8b9';
+    const token = '0x912ce59144191c1204e64559fe8253a0e49e6548';
+
+    const erc20Iface = new ethers.Interface([
+      'function approve(address spender, uint256 amount) returns (bool)',
+    ]);
+    const vaultIface = new ethers.Interface([
+      'function deposit(uint256 assets, address receiver) returns (uint256)',
+    ]);
+
+    const approveCall = (amount: bigint) => ({
+      to: token,
+      data: erc20Iface.encodeFunctionData('approve', [vault, amount]),
+    });
+    const depositCall = (amount: bigint) => ({
+      to: vault,
+      data: vaultIface.encodeFunctionData('deposit', [amount, account]),
+    });
+    const sendCalls = (overrides: Partial<SendCalls> = {}): SendCalls => ({
+      version: '2.0.0',
+      chainId: '0xa4b1', // Arbitrum
+      from: account,
+      atomicRequired: true,
+      calls: [approveCall(100n), depositCall(100n)],
+      ...overrides,
+    });
+
+    it('should validate an atomic approval and deposit', () => {
+      const result = shield.validateSendCalls({
+        yieldId,
+        sendCalls: sendCalls(),
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.atomicRequired).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.SUPPLY);
+      expect(result.detectedTypes).toEqual([
+        TransactionType.APPROVAL,
+        TransactionType.SUPPLY,
+      ]);
+      expect(result.steps).toHaveLength(2);
+      expect(result.warnings).toBeUndefined();
+    });
+
+    it('should check the deposit against the batched approval', () => {
+      const result = shield.validateSendCalls({
+        yieldId,
+        sendCalls: sendCalls({
+          calls: [approveCall(100n), depositCall(101n)],
+        }),
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('APPROVAL_INSUFFICIENT_FOR_DEPOSIT');
+      expect(result.steps).toHaveLength(2);
+    });
+
+    it('should report the call that fails on its own', () => {
+      const result = shield.validateSendCalls({
+        yieldId,
+        sendCalls: sendCalls({
+          calls: [
+            approveCall(100n),
+            {
+              ...depositCall(100n),
+              to: '0x0000000000000000000000000000000000000bad',
+            },
+          ],
+        }),
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('FLOW_STEP_INVALID');
+      expect(result.details?.step).toBe(1);
+      expect(result.steps.map((step) => step.isValid)).toEqual([true, false]);
+    });
+
+    it('should warn that a non-atomic batch can leave an approval behind', () => {
+      const result = shield.validateSendCalls({
+        yieldId,
+        sendCalls: sendCalls({ atomicRequired: false }),
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.atomicRequired).toBe(false);
+      expect(result.warnings).toEqual([
+        expect.objectContaining({
+          code: 'NON_ATOMIC_BATCH',
+          details: { call: 0 },
+        }),
+      ]);
+    });
+
+    it('should not warn about a non-atomic batch without approvals', () => {
+      const result = shield.validateSendCalls({
+        yieldId,
+        sendCalls: sendCalls({ calls: [depositCall(100n)] }),
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.warnings).toBeUndefined();
+    });
+
+    it('should warn about capabilities it does not know', () => {
+      const result = shield.validateSendCalls({
+        yieldId,
+        sendCalls: sendCalls({
+          capabilities: {
+            paymasterService: { url: 'https://paymaster.example' },
+            permissions: { sessionKey: '0x01' },
+          },
+          calls: [
+            approveCall(100n),
+            { ...depositCall(100n), capabilities: { flowControl: {} } },
+          ],
+        }),
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.warnings?.map((warning) => warning.details)).toEqual([
+        { capability: 'permissions' },
+        { capability: 'flowControl', call: 1 },
+      ]);
+      expect(result.warnings?.[0]).toMatchObject({
+        code: 'UNKNOWN_CAPABILITY',
+        severity: 'warning',
+      });
+    });
+
+    it('should reject a batch with warnings in strict mode', () => {
+      const result = shield.validateSendCalls({
+        yieldId,
+        sendCalls: sendCalls({ atomicRequired: false }),
+        strict: true,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('STRICT_MODE_WARNING');
+      expect(result.details?.warningCodes).toEqual(['NON_ATOMIC_BATCH']);
+    });
+
+    it('should reject a batch for another chain or sender', () => {
+      const chain = shield.validateSendCalls({
+        yieldId,
+        sendCalls: sendCalls({ chainId: '0x1' }),
+      });
+      const sender = shield.validateSendCalls({
+        yieldId,
+        sendCalls: sendCalls(),
+        userAddress: '0x0000000000000000000000000000000000000bad',
+      });
+
+      expect(chain.reasonCode).toBe('CHAIN_ID_MISMATCH');
+      expect(chain.details).toMatchObject({ expected: '42161', actual: '1' });
+      expect(sender.reasonCode).toBe('SENDER_MISMATCH');
+    });
+
+    it('should take the sender from userAddress when from is unset', () => {
+      const result = shield.validateSendCalls({
+        yieldId,
+        sendCalls: sendCalls({ from: undefined }),
+        userAddress: account,
+      });
+
+      expect(result.isValid).toBe(true);
+    });
+
+    it('should reject a batch without calls or a sender', () => {
+      expect(
+        shield.validateSendCalls({
+          yieldId,
+          sendCalls: sendCalls({ calls: [] }),
+        }).reasonCode,
+      ).toBe('INVALID_REQUEST');
+      expect(
+        shield.validateSendCalls({
+          yieldId,
+          sendCalls: sendCalls({ from: undefined }),
+        }).reasonCode,
+      ).toBe('INVALID_REQUEST');
+    });
+  });
+
+  describe('compareIntent', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const yieldId = 'ethereum-eth-lido-staking';
+    const oneEth = '1000000000000000000';
+    const stakeTx = (to = '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84') =>
+      JSON.stringify({
+        to,
+        from: userAddress,
+        value: '0xde0b6b3a7640000', // 1 ETH
+        data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+        chainId: 1,
+      });
+    const intent = {
+      action: TransactionType.STAKE,
+      yieldId,
+      amount: oneEth,
+      token: 'native',
+    };
+
+    it('should match a transaction that carries out the intent', () => {
+      const result = shield.compareIntent({
+        intent,
+        unsignedTransaction: stakeTx(),
+        userAddress,
+      });
+
+      expect(result).toMatchObject({
+        match: true,
+        actionMatch: true,
+        amountMatch: true,
+        recipientMatch: true,
+      });
+      expect(result.reasonCode).toBeUndefined();
+      expect(result.validation.isValid).toBe(true);
+    });
+
+    it('should only compare the amount and token when given', () => {
+      const result = shield.compareIntent({
+        intent: { action: TransactionType.STAKE, yieldId },
+        unsignedTransaction: stakeTx(),
+        userAddress,
+      });
+
+      expect(result.match).toBe(true);
+      expect(result.amountMatch).toBe(true);
+    });
+
+    it('should list every field that diverges', () => {
+      const result = shield.compareIntent({
+        intent: {
+          ...intent,
+          action: TransactionType.UNSTAKE,
+          amount: '2000000000000000000',
+        },
+        unsignedTransaction: stakeTx(),
+        userAddress,
+      });
+
+      expect(result).toMatchObject({
+        match: false,
+        actionMatch: false,
+        amountMatch: false,
+        recipientMatch: true,
+        reason: 'INTENT_MISMATCH',
+        reasonCode: 'INTENT_MISMATCH',
+      });
+      expect(result.details).toEqual({
+        yieldId,
+        fields: ['action', 'amount'],
+        expected: {
+          action: 'UNSTAKE',
+          amount: '2000000000000000000',
+          token: 'native',
+        },
+        actual: { action: 'STAKE', amount: oneEth, token: 'native' },
+      });
+      expect(result.validation.isValid).toBe(true);
+    });
+
+    it('should compare the token', () => {
+      const result = shield.compareIntent({
+        intent: {
+          ...intent,
+          token: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+        },
+        unsignedTransaction: stakeTx(),
+        userAddress,
+      });
+
+      expect(result.amountMatch).toBe(false);
+      expect(result.details?.fields).toEqual(['amount']);
+    });
+
+    it('should not match a transaction that fails validation', () => {
+      const result = shield.compareIntent({
+        intent,
+        unsignedTransaction: stakeTx(
+          '0x1111111111111111111111111111111111111111',
+        ),
+        userAddress,
+      });
+
+      expect(result.match).toBe(false);
+      expect(result.recipientMatch).toBe(false);
+      expect(result.validation.isValid).toBe(false);
+      expect(result.reasonCode).toBe(result.validation.reasonCode);
+      expect(result.reasonCode).not.toBe('INTENT_MISMATCH');
+    });
+
+    it('should validate for the intended yield', () => {
+      const result = shield.compareIntent({
+        intent: { ...intent, yieldId: 'unknown-yield' },
+        unsignedTransaction: stakeTx(),
+        userAddress,
+      });
+
+      expect(result.match).toBe(false);
+      expect(result.reasonCode).toBe('YIELD_NOT_FOUND');
+    });
+
+    it('should reject a missing or malformed intent', () => {
+      for (const request of [
+        null,
+        { unsignedTransaction: stakeTx() },
+        { intent: { ...intent, action: 'BURN' }, unsignedTransaction: '{}' },
+        { intent: { ...intent, amount: '1.5' }, unsignedTransaction: '{}' },
+      ]) {
+        const result = shield.compareIntent(request as any);
+
+        expect(result.match).toBe(false);
+        expect(result.reasonCode).toBe('INVALID_REQUEST');
+      }
+    });
+  });
+
+  describe('preflight', () => {
+    const yieldId = 'ethereum-eth-lido-staking';
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const oneEth = '1000000000000000000';
+
+    it('should allow a stake and describe where it goes', () => {
+      const result = shield.preflight({
+        yieldId,
+        detectedType: TransactionType.STAKE,
+        userAddress,
+        amount: oneEth,
+      });
+
+      expect(result).toEqual({
+        allowed: true,
+        warnings: [],
+        chainId: '1',
+        expectedRecipient: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+        amount: expect.objectContaining({
+          token: 'native',
+          amount: oneEth,
+          symbol: 'ETH',
+        }),
+      });
+    });
+
+    it('should warn that the sender is not checked without a userAddress', () => {
+      const result = shield.preflight({
+        yieldId,
+        detectedType: TransactionType.UNSTAKE,
+      });
+
+      expect(result.allowed).toBe(true);
+      expect(result.amount).toBeUndefined();
+      expect(result.warnings.map((warning) => warning.code)).toEqual([
+        'SENDER_NOT_VERIFIED',
+      ]);
+    });
+
+    it('should refuse an unknown yield, another chain or an unsupported type', () => {
+      const unknown = shield.preflight({
+        yieldId: 'ethereum-eth-unknown-staking',
+        detectedType: TransactionType.STAKE,
+      });
+      const otherChain = shield.preflight({
+        yieldId,
+        detectedType: TransactionType.STAKE,
+        chainId: '42161',
+      });
+      const unsupported = shield.preflight({
+        yieldId,
+        detectedType: TransactionType.SUPPLY,
+      });
+
+      expect(unknown.allowed).toBe(false);
+      expect(unknown.reasonCode).toBe('YIELD_NOT_FOUND');
+      expect(otherChain.reasonCode).toBe('CHAIN_ID_MISMATCH');
+      expect(otherChain.details).toMatchObject({
+        expected: '1',
+        actual: '42161',
+      });
+      expect(otherChain.chainId).toBe('1');
+      expect(unsupported.reasonCode).toBe('OPERATION_NOT_SUPPORTED_FOR_YIELD');
+    });
+
+    it('should refuse an amount below the policy minimum', () => {
+      const result = shield.preflight({
+        yieldId,
+        detectedType: TransactionType.STAKE,
+        userAddress,
+        amount: oneEth,
+        policy: { amountLimits: { [yieldId]: { minAmount: '2' } } },
+      });
+
+      expect(result.allowed).toBe(false);
+      expect(result.reasonCode).toBe('AMOUNT_BELOW_MINIMUM');
+    });
+
+    it("should refuse a userAddress not of the yield's network", () => {
+      const result = shield.preflight({
+        yieldId,
+        detectedType: TransactionType.STAKE,
+        userAddress: 'cosmos1notanevmaddress',
+      });
+
+      expect(result.allowed).toBe(false);
+      expect(result.reasonCode).toBe('INVALID_REQUEST');
+    });
+
+    it('should refuse deposits into a vault that is closed to them', () => {
+      const vault = {
+        yieldId: 'sepolia-weth-closed-vault',
+        address: '0x3333333333333333333333333333333333333333',
+        chainId: 11155111,
+        protocol: 'upcoming-protocol',
+        network: 'sepolia',
+        inputTokenAddress: '0x4444444444444444444444444444444444444444',
+        vaultTokenAddress: '0x3333333333333333333333333333333333333333',
+        canEnter: false,
+      };
+      const closed = new Shield({ registryOverride: { vaults: [vault] } });
+      const preflight = (detectedType: TransactionType) =>
+        closed.preflight({ yieldId: vault.yieldId, detectedType, userAddress });
+
+      expect(preflight(TransactionType.SUPPLY)).toMatchObject({
+        allowed: false,
+        reasonCode: 'YIELD_PAUSED',
+        expectedRecipient: vault.address,
+      });
+      expect(preflight(TransactionType.WITHDRAW).allowed).toBe(true);
+    });
+
+    it('should reject malformed requests', () => {
+      for (const request of [
+        null,
+        { yieldId },
+        { yieldId, detectedType: 'BURN' },
+        { yieldId, detectedType: TransactionType.STAKE, amount: '1.5' },
+      ]) {
+        expect(shield.preflight(request as any).reasonCode).toBe(
+          'INVALID_REQUEST',
+        );
+      }
+    });
+  });
+
+  describe('validateCandidates', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const lidoStake = JSON.stringify({
+      to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+      from: userAddress,
+      value: '0xde0b6b3a7640000',
+      data:
+        '0xa1903eab' + referralAddress.slice(2).padStart(64, '0').toLowerCase(),
+      chainId: 1,
+    });
+
+    it('should answer with the first candidate the transaction passes for', () => {
+      const result = shield.validateCandidates({
+        yieldIds: ['ethereum-eth-reth-staking', 'ethereum-eth-lido-staking'],
+        unsignedTransaction: lidoStake,
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.yieldId).toBe('ethereum-eth-lido-staking');
+      expect(result.detectedType).toBe(TransactionType.STAKE);
+      expect(result).toEqual({
+        ...shield.validate({
+          yieldId: 'ethereum-eth-lido-staking',
+          unsignedTransaction: lidoStake,
+          userAddress,
+        }),
+        yieldId: 'ethereum-eth-lido-staking',
+      });
+    });
+
+    it('should report why each candidate failed', () => {
+      const result = shield.validateCandidates({
+        yieldIds: ['ethereum-eth-reth-staking', 'unknown-yield'],
+        unsignedTransaction: lidoStake,
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('ALL_CANDIDATES_FAILED');
+      expect(result.yieldId).toBeUndefined();
+      expect(
+        result.details?.candidates?.map(({ yieldId, reasonCode }) => ({
+          yieldId,
+          reasonCode,
+        })),
+      ).toEqual([
+        {
+          yieldId: 'ethereum-eth-reth-staking',
+          reasonCode: shield.validate({
+            yieldId: 'ethereum-eth-reth-staking',
+            unsignedTransaction: lidoStake,
+            userAddress,
+          }).reasonCode,
+        },
+        { yieldId: 'unknown-yield', reasonCode: 'YIELD_NOT_FOUND' },
+      ]);
+    });
+
+    it('should reject an empty list of candidates', () => {
+      const result = shield.validateCandidates({
+        yieldIds: [],
+        unsignedTransaction: lidoStake,
+      });
+
+      expect(result.reasonCode).toBe('INVALID_REQUEST');
+    });
+
+    it('should report the failure in observe mode', () => {
+      const result = shield.validateCandidates({
+        yieldIds: ['ethereum-eth-reth-staking'],
+        unsignedTransaction: lidoStake,
+        userAddress,
+        observe: true,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.wouldRejectReasonCode).toBe('ALL_CANDIDATES_FAILED');
+    });
+  });
+
+  describe('validateRawTransaction', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const yieldId = 'ethereum-eth-lido-staking';
+    const stETH = '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const stakeData =
+      '0xa1903eab' + referralAddress.slice(2).padStart(64, '0').toLowerCase();
+
+    const serialize = (fields: Partial<ethers.TransactionLike>) =>
+      ethers.Transaction.from({
+        type: 2,
+        chainId: 1,
+        nonce: 7,
+        to: stETH,
+        value: 10n ** 18n,
+        data: stakeData,
+        gasLimit: 150000n,
+        maxFeePerGas: 30n * 10n ** 9n,
+        maxPriorityFeePerGas: 10n ** 9n,
+        ...fields,
+      }).unsignedSerialized;
+
+    it('should validate an unsigned EIP-1559 transaction like its fields', () => {
+      const result = shield.validateRawTransaction({
+        yieldId,
+        rawTransaction: serialize({}),
+        userAddress,
+      });
+      const expected = shield.validate({
+        yieldId,
+        unsignedTransaction: JSON.stringify(result.transaction),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.STAKE);
+      expect(result).toEqual({ ...expected, transaction: result.transaction });
+      expect(result.transaction).toEqual({
+        type: 2,
+        chainId: 1,
+        nonce: 7,
+        to: stETH,
+        from: userAddress,
+        value: '1000000000000000000',
+        data: stakeData,
+        gasLimit: '150000',
+        maxFeePerGas: '30000000000',
+        maxPriorityFeePerGas: '1000000000',
+        accessList: [],
+      });
+    });
+
+    it('should report a rejection once in observe mode', () => {
+      const result = shield.validateRawTransaction({
+        yieldId,
+        rawTransaction: serialize({ chainId: 5 }),
+        userAddress,
+        observe: true,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.wouldReject).toBe(true);
+      expect(result.wouldRejectReasonCode).toBe('CHAIN_ID_MISMATCH');
+    });
+
+    it('should accept base64', () => {
+      const hex = serialize({});
+      const result = shield.validateRawTransaction({
+        yieldId,
+        rawTransaction: Buffer.from(hex.slice(2), 'hex').toString('base64'),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.transaction?.nonce).toBe(7);
+    });
+
+    it('should decode legacy transactions', () => {
+      const result = shield.validateRawTransaction({
+        yieldId,
+        rawTransaction: serialize({ type: 0, gasPrice: 10n ** 9n }),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.transaction).toMatchObject({
+        type: 0,
+        chainId: 1,
+        gasPrice: '1000000000',
+      });
+      expect(result.transaction?.maxFeePerGas).toBeUndefined();
+    });
+
+    it('should return the decoded fields of transactions that fail', () => {
+      const result = shield.validateRawTransaction({
+        yieldId,
+        rawTransaction: serialize({ chainId: 137 }),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('CHAIN_ID_MISMATCH');
+      expect(result.transaction?.chainId).toBe(137);
+    });
+
+    describe('signed', () => {
+      // Well-known test key; never use it on a live network
+      const wallet = new ethers.Wallet(
+        '0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318',
+      );
+      const sign = (
+        signature?: (tx: ethers.Transaction) => ethers.Signature,
+      ) => {
+        const tx = ethers.Transaction.from(serialize({}));
+        tx.signature = signature
+          ? signature(tx)
+          : wallet.signingKey.sign(tx.unsignedHash);
+        return tx.serialized;
+      };
+
+      it('should recover the signer and validate as it', () => {
+        const result = shield.validateRawTransaction({
+          yieldId,
+          rawTransaction: sign(),
+          userAddress: wallet.address.toLowerCase(),
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.recoveredAddress).toBe(wallet.address);
+        expect(result.signatureValid).toBe(true);
+        expect(result.transaction?.from).toBe(wallet.address);
+      });
+
+      it('should reject a signer other than userAddress', () => {
+        const result = shield.validateRawTransaction({
+          yieldId,
+          rawTransaction: sign(),
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('SIGNATURE_SENDER_MISMATCH');
+        expect(result.details).toMatchObject({
+          expected: userAddress,
+          actual: wallet.address,
+        });
+        expect(result.recoveredAddress).toBe(wallet.address);
+        expect(result.signatureValid).toBe(true);
+      });
+
+      it('should reject a signature that recovers no signer', () => {
+        const result = shield.validateRawTransaction({
+          yieldId,
+          // No curve point has x = 5
+          rawTransaction: sign(() =>
+            ethers.Signature.from({
+              r: ethers.toBeHex(5, 32),
+              s: ethers.toBeHex(1, 32),
+              yParity: 0,
+            }),
+          ),
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('SIGNATURE_INVALID');
+        expect(result.signatureValid).toBe(false);
+        expect(result.recoveredAddress).toBeUndefined();
+        expect(result.transaction?.nonce).toBe(7);
+      });
+
+      it('should validate against the signer when userAddress is omitted', () => {
+        const result = shield.validateRawTransaction({
+          yieldId,
+          rawTransaction: sign(),
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.recoveredAddress).toBe(wallet.address);
+        expect(result.warnings).toContainEqual(
+          expect.objectContaining({ code: 'SENDER_NOT_VERIFIED' }),
+        );
+      });
+    });
+
+    it('should leave the signature fields unset for unsigned input', () => {
+      const result = shield.validateRawTransaction({
+        yieldId,
+        rawTransaction: serialize({}),
+        userAddress,
+      });
+
+      expect(result.recoveredAddress).toBeUndefined();
+      expect(result.signatureValid).toBeUndefined();
+    });
+
+    it('should reject input that is not RLP', () => {
+      const cases = ['0x02ff', '0x0', 'not base64!', serialize({}) + '00'];
+      for (const rawTransaction of cases) {
+        const result = shield.validateRawTransaction({
+          yieldId,
+          rawTransaction,
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('MALFORMED_RAW_TRANSACTION');
+        expect(result.transaction).toBeUndefined();
+      }
+    });
+  });
+
+  describe('Nonce checks', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const stakeTx = (nonce?: number | string) =>
+      JSON.stringify({
+        to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+        from: userAddress,
+        value: '0xde0b6b3a7640000',
+        data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+        chainId: 1,
+        nonce,
+      });
+    const validate = (
+      unsignedTransaction: string,
+      nonces: { expectedNonce?: number; accountNonce?: number },
+    ) =>
+      shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction,
+        userAddress,
+        ...nonces,
+      });
+
+    it('should accept the expected nonce, in any format', () => {
+      for (const nonce of [5, '5', '0x5']) {
+        expect(validate(stakeTx(nonce), { expectedNonce: 5 }).isValid).toBe(
+          true,
+        );
+      }
+    });
+
+    it('should reject another nonce with NONCE_MISMATCH', () => {
+      const result = validate(stakeTx(4), { expectedNonce: 5 });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('NONCE_MISMATCH');
+      expect(result.details).toEqual({
+        yieldId: 'ethereum-eth-lido-staking',
+        expected: '5',
+        actual: '4',
+      });
+    });
+
+    it('should reject a transaction without a nonce when one is expected', () => {
+      const result = validate(stakeTx(), { expectedNonce: 0 });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('NONCE_MISMATCH');
+      expect(result.details?.actual).toBeUndefined();
+    });
+
+    it('should keep the original reason of transactions that fail', () => {
+      const result = shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: stakeTx(4),
+        userAddress: '0x0000000000000000000000000000000000000001',
+        expectedNonce: 5,
+      });
+
+      expect(result.reasonCode).toBe('SENDER_MISMATCH');
+    });
+
+    it('should warn about nonces below or above the account nonce', () => {
+      const cases = [
+        { nonce: 3, code: 'NONCE_TOO_LOW' },
+        { nonce: 9, code: 'NONCE_GAP' },
+      ];
+      for (const { nonce, code } of cases) {
+        const result = validate(stakeTx(nonce), { accountNonce: 5 });
+
+        expect(result.isValid).toBe(true);
+        expect(result.warnings).toEqual([
+          expect.objectContaining({
+            code,
+            details: { nonce: String(nonce), accountNonce: '5' },
+          }),
+        ]);
+        expect(result.riskScore).toBeGreaterThan(0);
+      }
+      expect(
+        validate(stakeTx(5), { accountNonce: 5 }).warnings,
+      ).toBeUndefined();
+    });
+
+    it('should reject nonce warnings in strict mode', () => {
+      const result = shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: stakeTx(3),
+        userAddress,
+        accountNonce: 5,
+        strict: true,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('STRICT_MODE_WARNING');
+      expect(result.details?.warningCodes).toEqual(['NONCE_TOO_LOW']);
+    });
+
+    it('should reject nonces that are not non-negative integers', () => {
+      const cases = [{ expectedNonce: -1 }, { accountNonce: 1.5 }];
+      for (const nonces of cases) {
+        expect(validate(stakeTx(0), nonces).reasonCode).toBe(
+          'INVALID_REQUEST',
+        );
+      }
+    });
+
+    it('should fetch the pending transaction count as the account nonce', async () => {
+      const originalFetch = global.fetch;
+      const fetchMock = jest.fn().mockResolvedValue({
+        ok: true,
+        status: 200,
+        json: () => Promise.resolve({ jsonrpc: '2.0', id: 1, result: '0x1a' }),
+      });
+      global.fetch = fetchMock as unknown as typeof fetch;
+      try {
+        await expect(
+          shield.fetchAccountNonce('https://eth.example.com', userAddress),
+        ).resolves.toBe(26);
+        expect(JSON.parse(fetchMock.mock.calls[0][1].body).params).toEqual([
+          userAddress,
+          'pending',
+        ]);
+      } finally {
+        global.fetch = originalFetch;
+      }
+    });
+  });
+
+  describe('ENS recipients', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const stETH = '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84';
+    const stakeTx = (to: string) =>
+      JSON.stringify({
+        to,
+        from: userAddress,
+        value: '0xde0b6b3a7640000',
+        data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+        chainId: 1,
+      });
+    const validate = (
+      unsignedTransaction: string,
+      ens: {
+        expectedRecipientEns?: string;
+        ensAddresses?: Record<string, string>;
+      },
+    ) =>
+      shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction,
+        userAddress,
+        ...ens,
+      });
+
+    it('should list the names a request needs resolved', () => {
+      expect(
+        shield.getEnsNames({
+          yieldId: 'ethereum-eth-lido-staking',
+          unsignedTransaction: stakeTx('steth.lido.eth'),
+          expectedRecipientEns: 'lido.eth',
+        }),
+      ).toEqual(['lido.eth', 'steth.lido.eth']);
+      expect(
+        shield.getEnsNames({
+          yieldId: 'ethereum-eth-lido-staking',
+          unsignedTransaction: stakeTx(stETH),
+        }),
+      ).toEqual([]);
+    });
+
+    it('should accept a name that resolves to the recipient', () => {
+      const result = validate(stakeTx(stETH), {
+        expectedRecipientEns: 'lido.eth',
+        ensAddresses: { 'lido.eth': stETH.toLowerCase() },
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.resolvedRecipient).toEqual({
+        name: 'lido.eth',
+        address: stETH.toLowerCase(),
+      });
+    });
+
+    it('should reject a name that resolves elsewhere', () => {
+      const result = validate(stakeTx(stETH), {
+        expectedRecipientEns: 'lido.eth',
+        ensAddresses: { 'lido.eth': referralAddress },
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('RECIPIENT_ENS_MISMATCH');
+      expect(result.details?.expected).toBe(stETH);
+      expect(result.details?.actual).toBe(referralAddress);
+    });
+
+    it('should reject a name that did not resolve', () => {
+      const result = validate(stakeTx(stETH), {
+        expectedRecipientEns: 'lido.eth',
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('RECIPIENT_ENS_MISMATCH');
+    });
+
+    it('should validate a transaction sent to a name by its address', () => {
+      const resolved = validate(stakeTx('lido.eth'), {
+        ensAddresses: { 'lido.eth': stETH },
+      });
+      expect(resolved.isValid).toBe(true);
+      expect(resolved.detectedType).toBe(TransactionType.STAKE);
+      expect(resolved.resolvedRecipient).toEqual({
+        name: 'lido.eth',
+        address: stETH,
+      });
+
+      const elsewhere = validate(stakeTx('lido.eth'), {
+        ensAddresses: { 'lido.eth': referralAddress },
+      });
+      expect(elsewhere.isValid).toBe(false);
+      expect(elsewhere.reasonCode).toBe('RECIPIENT_MISMATCH');
+
+      const unresolved = validate(stakeTx('lido.eth'), {});
+      expect(unresolved.isValid).toBe(false);
+      expect(unresolved.reasonCode).toBe('RECIPIENT_ENS_MISMATCH');
+    });
+  });
+
+  describe('Contract code', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const stETH = '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84';
+    const stakeTx = (to = stETH) =>
+      JSON.stringify({
+        to,
+        from: userAddress,
+        value: '0xde0b6b3a7640000',
+        data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+        chainId: 1,
+      });
+    const yieldId = 'ethereum-eth-lido-staking';
+
+    it('should list the contracts a transaction sends calldata to', () => {
+      expect(
+        shield.getCodeAddresses({ yieldId, unsignedTransaction: stakeTx() }),
+      ).toEqual([stETH]);
+    });
+
+    it('should list none for a transaction without calldata', () => {
+      const unsignedTransaction = JSON.stringify({
+        ...JSON.parse(stakeTx()),
+        data: '0x',
+      });
+
+      expect(
+        shield.getCodeAddresses({ yieldId, unsignedTransaction }),
+      ).toEqual([]);
+    });
+
+    it('should list none for an unresolved ENS recipient', () => {
+      expect(
+        shield.getCodeAddresses({
+          yieldId,
+          unsignedTransaction: stakeTx('lido.eth'),
+        }),
+      ).toEqual([]);
+      expect(
+        shield.getCodeAddresses({
+          yieldId,
+          unsignedTransaction: stakeTx('lido.eth'),
+          ensAddresses: { 'lido.eth': stETH },
+        }),
+      ).toEqual([stETH]);
+    });
+
+    it('should reject calldata sent to an account without code', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: stakeTx(),
+        userAddress,
+        contractCode: { [stETH]: false },
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('RECIPIENT_NOT_A_CONTRACT');
+      expect(result.details).toEqual({ yieldId, actual: stETH });
+    });
+
+    it('should accept calldata sent to a contract', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: stakeTx(),
+        userAddress,
+        contractCode: { [stETH]: true },
+      });
+
+      expect(result.isValid).toBe(true);
+    });
+
+    it('should warn about a contract that reports paused', () => {
+      const request = {
+        yieldId,
+        unsignedTransaction: stakeTx(),
+        userAddress,
+        contractCode: { [stETH]: true },
+        contractPaused: { [stETH]: true },
+      };
+      const result = shield.validate(request);
+
+      expect(result.isValid).toBe(true);
+      expect(result.warnings).toEqual([
+        expect.objectContaining({
+          code: 'CONTRACT_PAUSED',
+          severity: 'critical',
+          details: { contract: stETH },
+        }),
+      ]);
+      expect(shield.validate({ ...request, strict: true }).reasonCode).toBe(
+        'STRICT_MODE_WARNING',
+      );
+    });
+
+    describe('contract age', () => {
+      const now = Math.floor(Date.now() / 1000);
+      const request = {
+        yieldId,
+        unsignedTransaction: stakeTx(),
+        userAddress,
+        policy: { minContractAgeSeconds: 30 * 86400 },
+      };
+
+      it('should warn about a contract deployed recently', () => {
+        const deployment = { block: 21000000, timestamp: now - 3600 };
+        const result = shield.validate({
+          ...request,
+          contractDeployments: { [stETH]: deployment },
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.contractDeployments).toEqual({ [stETH]: deployment });
+        expect(result.warnings).toEqual([
+          expect.objectContaining({
+            code: 'RECENTLY_DEPLOYED_CONTRACT',
+            severity: 'warning',
+            details: expect.objectContaining({
+              contract: stETH,
+              block: 21000000,
+              timestamp: now - 3600,
+              minContractAgeSeconds: 30 * 86400,
+            }),
+          }),
+        ]);
+        expect(
+          shield.validate({
+            ...request,
+            contractDeployments: { [stETH]: deployment },
+            strict: true,
+          }).reasonCode,
+        ).toBe('STRICT_MODE_WARNING');
+      });
+
+      it('should accept a contract older than the minimum', () => {
+        const deployment = { block: 11473216, timestamp: 1608242396 };
+        const result = shield.validate({
+          ...request,
+          contractDeployments: { [stETH]: deployment },
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.warnings).toBeUndefined();
+        expect(result.contractDeployments).toEqual({ [stETH]: deployment });
+      });
+
+      it('should only check the age when the policy asks for it', () => {
+        const contractDeployments = {
+          [stETH]: { block: 21000000, timestamp: now - 3600 },
+        };
+
+        expect(
+          shield.validate({ ...request, policy: {}, contractDeployments })
+            .warnings,
+        ).toBeUndefined();
+        expect(
+          shield
+            .explain(request)
+            .trace.find((entry) => entry.check === 'contract-age'),
+        ).toEqual({
+          check: 'contract-age',
+          status: 'skip',
+          detail:
+            'No rpcUrl to find when the contracts called were deployed from',
+        });
+      });
+    });
+
+    it('should skip the check without contractCode', () => {
+      const result = shield.explain({
+        yieldId,
+        unsignedTransaction: stakeTx(),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(
+        result.trace.find((entry) => entry.check === 'recipient-code'),
+      ).toEqual({
+        check: 'recipient-code',
+        status: 'skip',
+        detail: 'No rpcUrl to fetch the code of the contracts called from',
+      });
+    });
+  });
+
+  describe('Yield status', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const vaultAddress = '0x3333333333333333333333333333333333333333';
+    const vault = {
+      yieldId: 'sepolia-usdc-retired-vault',
+      address: vaultAddress,
+      chainId: 11155111,
+      protocol: 'euler',
+      network: 'sepolia',
+      inputTokenAddress: '0x4444444444444444444444444444444444444444',
+      vaultTokenAddress: vaultAddress,
+      isWethVault: false,
+    };
+    const vaultIface = new ethers.Interface([
+      'function deposit(uint256 assets, address receiver) returns (uint256)',
+      'function redeem(uint256 shares, address receiver, address owner) returns (uint256)',
+    ]);
+    const tx = (data: string) =>
+      JSON.stringify({
+        to: vaultAddress,
+        from: userAddress,
+        value: '0x0',
+        data,
+        chainId: 11155111,
+      });
+    const depositTx = tx(
+      vaultIface.encodeFunctionData('deposit', [100n, userAddress]),
+    );
+    const redeemTx = tx(
+      vaultIface.encodeFunctionData('redeem', [100n, userAddress, userAddress]),
+    );
+    const withStatus = (status: YieldStatus) =>
+      new Shield({ registryOverride: { vaults: [{ ...vault, status }] } });
+
+    it('should report the status of every yield', () => {
+      expect(
+        shield.getYieldCapabilities('ethereum-eth-lido-staking')?.status,
+      ).toBe('active');
+      expect(
+        withStatus('deprecated').getYieldCapabilities(vault.yieldId)?.status,
+      ).toBe('deprecated');
+    });
+
+    it('should warn about a deposit into a paused or deprecated yield', () => {
+      const paused = withStatus('paused').validate({
+        yieldId: vault.yieldId,
+        unsignedTransaction: depositTx,
+        userAddress,
+      });
+      const deprecated = withStatus('deprecated').validate({
+        yieldId: vault.yieldId,
+        unsignedTransaction: depositTx,
+        userAddress,
+      });
+
+      expect(paused.isValid).toBe(true);
+      expect(paused.warnings?.map((warning) => warning.code)).toEqual([
+        'YIELD_PAUSED',
+      ]);
+      expect(deprecated.warnings).toEqual([
+        expect.objectContaining({
+          code: 'YIELD_DEPRECATED',
+          severity: 'warning',
+          details: { yieldId: vault.yieldId, status: 'deprecated' },
+        }),
+      ]);
+    });
+
+    it('should reject such a deposit in strict mode', () => {
+      const result = withStatus('paused').validate({
+        yieldId: vault.yieldId,
+        unsignedTransaction: depositTx,
+        userAddress,
+        strict: true,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('STRICT_MODE_WARNING');
+      expect(result.details?.warningCodes).toEqual(['YIELD_PAUSED']);
+    });
+
+    it('should let users exit a deprecated yield without a warning', () => {
+      const result = withStatus('deprecated').validate({
+        yieldId: vault.yieldId,
+        unsignedTransaction: redeemTx,
+        userAddress,
+        strict: true,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.warnings ?? []).toEqual([]);
+    });
+
+    it('should refuse a paused yield in preflight', () => {
+      const result = withStatus('paused').preflight({
+        yieldId: vault.yieldId,
+        detectedType: TransactionType.SUPPLY,
+        userAddress,
+      });
+
+      expect(result.allowed).toBe(false);
+      expect(result.reasonCode).toBe('YIELD_PAUSED');
+    });
+  });
+
+  describe('Bytecode hash', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const vaultAddress = '0x3333333333333333333333333333333333333333';
+    const pinnedHash = '0x' + '11'.repeat(32);
+    const vault = {
+      yieldId: 'sepolia-usdc-pinned-vault',
+      address: vaultAddress,
+      chainId: 11155111,
+      protocol: 'euler',
+      network: 'sepolia',
+      inputTokenAddress: '0x4444444444444444444444444444444444444444',
+      vaultTokenAddress: vaultAddress,
+      isWethVault: false,
+      bytecodeHashes: { [vaultAddress]: pinnedHash },
+    };
+    const pinned = new Shield({ registryOverride: { vaults: [vault] } });
+    const depositTx = JSON.stringify({
+      to: vaultAddress,
+      from: userAddress,
+      value: '0x0',
+      data: new ethers.Interface([
+        'function deposit(uint256 assets, address receiver) returns (uint256)',
+      ]).encodeFunctionData('deposit', [100n, userAddress]),
+      chainId: 11155111,
+    });
+    const request = {
+      yieldId: vault.yieldId,
+      unsignedTransaction: depositTx,
+      userAddress,
+    };
+
+    it('should reject a recipient whose code hash is not the pinned one', () => {
+      const actual = '0x' + '22'.repeat(32);
+      const result = pinned.validate({
+        ...request,
+        actualBytecodeHash: actual,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('BYTECODE_MISMATCH');
+      expect(result.details).toEqual({
+        yieldId: vault.yieldId,
+        expected: pinnedHash,
+        actual,
+      });
+    });
+
+    it('should accept the pinned hash in any case', () => {
+      const result = pinned.validate({
+        ...request,
+        actualBytecodeHash: pinnedHash.toUpperCase().replace('0X', '0x'),
+      });
+
+      expect(result.isValid).toBe(true);
+    });
+
+    it('should not check a recipient without a pinned hash', () => {
+      const result = shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify({
+          to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+          from: userAddress,
+          value: '0xde0b6b3a7640000',
+          data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+          chainId: 1,
+        }),
+        userAddress,
+        actualBytecodeHash: '0x' + '22'.repeat(32),
+      });
+
+      expect(result.isValid).toBe(true);
+    });
+
+    it('should reject a hash that is not 32 bytes of hex', () => {
+      const result = pinned.validate({
+        ...request,
+        actualBytecodeHash: '0x1234',
+      });
+
+      expect(result.reasonCode).toBe('INVALID_REQUEST');
+    });
+
+    it('should trace the check', () => {
+      const result = pinned.explain({
+        ...request,
+        actualBytecodeHash: pinnedHash,
+      });
+
+      expect(
+        result.trace.find((entry) => entry.check === 'bytecode-hash'),
+      ).toEqual({
+        check: 'bytecode-hash',
+        status: 'pass',
+        detail: `The recipient's code hashes to the pinned ${pinnedHash}`,
+      });
+    });
+  });
+
+  describe('Staked balance', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const stETH = '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84';
+    const yieldId = 'ethereum-eth-lido-staking';
+    const oneEth = 10n ** 18n;
+    const unstakeTx = JSON.stringify({
+      to: '0x889edC2eDab5f40e902b864aD4d7AdE8E412F9B1', // Withdrawal queue
+      from: userAddress,
+      value: '0x0',
+      data: new ethers.Interface([
+        'function requestWithdrawals(uint256[] _amounts, address _owner) returns (uint256[])',
+      ]).encodeFunctionData('requestWithdrawals', [[oneEth], userAddress]),
+      chainId: 1,
+    });
+    const unstake = (stakedBalance: bigint) =>
+      shield.validate({
+        yieldId,
+        unsignedTransaction: unstakeTx,
+        userAddress,
+        stakedBalance: stakedBalance.toString(),
+      });
+
+    it("should read the sender's stETH balance for an unstake", () => {
+      expect(
+        shield.getStakedBalanceCall({
+          yieldId,
+          unsignedTransaction: unstakeTx,
+        }),
+      ).toEqual({
+        call: {
+          to: stETH,
+          data: new ethers.Interface([
+            'function balanceOf(address owner) view returns (uint256)',
+          ]).encodeFunctionData('balanceOf', [userAddress]),
+        },
+        amount: oneEth.toString(),
+      });
+    });
+
+    it('should read no balance for a stake', () => {
+      const stakeTx = JSON.stringify({
+        to: stETH,
+        from: userAddress,
+        value: '0xde0b6b3a7640000',
+        data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+        chainId: 1,
+      });
+
+      expect(
+        shield.getStakedBalanceCall({ yieldId, unsignedTransaction: stakeTx }),
+      ).toBeUndefined();
+    });
+
+    it('should reject an unstake of more than the staked balance', () => {
+      const result = unstake(oneEth / 2n);
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('UNSTAKE_EXCEEDS_BALANCE');
+      expect(result.details).toEqual({
+        yieldId,
+        balance: (oneEth / 2n).toString(),
+        amount: oneEth.toString(),
+      });
+    });
+
+    it('should accept an unstake of the whole balance or a clear part of it', () => {
+      for (const balance of [oneEth, 2n * oneEth]) {
+        const result = unstake(balance);
+
+        expect(result.isValid).toBe(true);
+        expect(result.warnings).toBeUndefined();
+      }
+    });
+
+    it('should warn on an unstake that leaves a sliver of the balance', () => {
+      const result = unstake(oneEth + 1000n);
+
+      expect(result.isValid).toBe(true);
+      expect(result.warnings).toEqual([
+        expect.objectContaining({
+          code: 'UNSTAKE_NEAR_FULL',
+          details: {
+            balance: (oneEth + 1000n).toString(),
+            amount: oneEth.toString(),
+          },
+        }),
+      ]);
+    });
+
+    it('should skip the check without stakedBalance', () => {
+      const result = shield.explain({
+        yieldId,
+        unsignedTransaction: unstakeTx,
+        userAddress,
+      });
+
+      expect(
+        result.trace.find((entry) => entry.check === 'unstake-balance'),
+      ).toEqual({
+        check: 'unstake-balance',
+        status: 'skip',
+        detail: 'No rpcUrl to read the staked balance from',
+      });
+    });
+  });
+
+  describe('Claimed positions', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const otherAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const withdrawalQueue = '0x889edC2eDab5f40e902b864aD4d7AdE8E412F9B1';
+    const yieldId = 'ethereum-eth-lido-staking';
+    const claimTx = JSON.stringify({
+      to: withdrawalQueue,
+      from: userAddress,
+      value: '0x0',
+      data: new ethers.Interface([
+        'function claimWithdrawals(uint256[] _requestIds, uint256[] _hints)',
+      ]).encodeFunctionData('claimWithdrawals', [
+        [123, 124],
+        [1, 1],
+      ]),
+      chainId: 1,
+    });
+    const claim = (positionOwners?: Record<string, string>) =>
+      shield.validate({
+        yieldId,
+        unsignedTransaction: claimTx,
+        userAddress,
+        positionOwners,
+      });
+
+    it('should list the withdrawal requests a claim redeems', () => {
+      expect(
+        shield.getClaimedPositions({ yieldId, unsignedTransaction: claimTx }),
+      ).toEqual({ contract: withdrawalQueue, positionIds: ['123', '124'] });
+    });
+
+    it('should note that ownership was not checked without positionOwners', () => {
+      const result = claim();
+
+      expect(result.isValid).toBe(true);
+      expect(result.claimedPositions).toEqual({
+        contract: withdrawalQueue,
+        positionIds: ['123', '124'],
+      });
+      expect(result.positionOwnershipVerified).toBe(false);
+    });
+
+    it('should accept a claim of positions the user owns', () => {
+      const result = claim({ '123': userAddress, '124': userAddress });
+
+      expect(result.isValid).toBe(true);
+      expect(result.positionOwnershipVerified).toBe(true);
+    });
+
+    it('should reject a claim of a position owned by someone else', () => {
+      const result = claim({ '123': userAddress, '124': otherAddress });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('CLAIM_POSITION_NOT_OWNED');
+      expect(result.details).toEqual({
+        yieldId,
+        positionId: '124',
+        expected: userAddress,
+        actual: otherAddress,
+      });
+      expect(result.positionOwnershipVerified).toBe(false);
+    });
+
+    it('should reject a claim of a position without an owner', () => {
+      const result = claim({ '123': userAddress });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('CLAIM_POSITION_NOT_OWNED');
+      expect(result.reason).toBe(
+        'Position 124 does not exist or was already claimed',
+      );
+    });
+
+    it('should skip the check without positionOwners', () => {
+      const result = shield.explain({
+        yieldId,
+        unsignedTransaction: claimTx,
+        userAddress,
+      });
+
+      expect(
+        result.trace.find((entry) => entry.check === 'claim-position'),
+      ).toEqual({
+        check: 'claim-position',
+        status: 'skip',
+        detail: 'No rpcUrl to read the owners of the claimed positions from',
+      });
+    });
+  });
+
+  describe('Claim and restake multicalls', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const otherAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const withdrawalQueue = '0x889edC2eDab5f40e902b864aD4d7AdE8E412F9B1';
+    const stETH = '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84';
+    const yieldId = 'ethereum-eth-lido-staking';
+    const queueIface = new ethers.Interface([
+      'function claimWithdrawals(uint256[] _requestIds, uint256[] _hints)',
+      'function claimWithdrawalsTo(uint256[] _requestIds, uint256[] _hints, address _recipient)',
+      'function multicall(bytes[] data) payable returns (bytes[] results)',
+    ]);
+    const claimWithdrawals = (...ids: number[]) =>
+      queueIface.encodeFunctionData('claimWithdrawals', [
+        ids,
+        ids.map(() => 1),
+      ]);
+    const claimWithdrawalsTo = (recipient: string, ...ids: number[]) =>
+      queueIface.encodeFunctionData('claimWithdrawalsTo', [
+        ids,
+        ids.map(() => 1),
+        recipient,
+      ]);
+    const multicallTx = (...calls: string[]) =>
+      JSON.stringify({
+        to: withdrawalQueue,
+        from: userAddress,
+        value: '0x0',
+        data: queueIface.encodeFunctionData('multicall', [calls]),
+        chainId: 1,
+      });
+    const validate = (
+      unsignedTransaction: string,
+      positionOwners?: Record<string, string>,
+    ) =>
+      shield.validate({
+        yieldId,
+        unsignedTransaction,
+        userAddress,
+        positionOwners,
+      });
+
+    const claimTx = multicallTx(
+      claimWithdrawals(123),
+      claimWithdrawalsTo(userAddress, 124, 125),
+    );
+    const owners = {
+      '123': userAddress,
+      '124': userAddress,
+      '125': userAddress,
+    };
+
+    it('should list the positions every call claims', () => {
+      expect(
+        shield.getClaimedPositions({ yieldId, unsignedTransaction: claimTx }),
+      ).toEqual({
+        contract: withdrawalQueue,
+        positionIds: ['123', '124', '125'],
+      });
+    });
+
+    it('should break a verified claim down per position', () => {
+      const result = validate(claimTx, owners);
+
+      expect(result.isValid).toBe(true);
+      expect(result.positionOwnershipVerified).toBe(true);
+      expect(result.positions).toEqual([
+        { positionId: '123', subCall: 0, owner: userAddress },
+        { positionId: '124', subCall: 1, owner: userAddress },
+        { positionId: '125', subCall: 1, owner: userAddress },
+      ]);
+    });
+
+    it('should break an unverified claim down without owners', () => {
+      const result = validate(claimTx);
+
+      expect(result.isValid).toBe(true);
+      expect(result.positionOwnershipVerified).toBe(false);
+      expect(result.positions).toEqual([
+        { positionId: '123', subCall: 0 },
+        { positionId: '124', subCall: 1 },
+        { positionId: '125', subCall: 1 },
+      ]);
+    });
+
+    it('should reject a call that claims a position of someone else', () => {
+      const result = validate(claimTx, { ...owners, '125': otherAddress });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('CLAIM_POSITION_NOT_OWNED');
+      expect(result.details).toEqual({
+        yieldId,
+        positionId: '125',
+        subCall: 1,
+        expected: userAddress,
+        actual: otherAddress,
+      });
+    });
+
+    it('should reject a call that pays a claim to someone else', () => {
+      const result = validate(
+        multicallTx(
+          claimWithdrawals(123),
+          claimWithdrawalsTo(otherAddress, 124),
+        ),
+        owners,
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('MULTICALL_CALL_INVALID');
+      expect(result.details?.subCall).toBe(1);
+      expect(result.subResults?.[1].reasonCode).toBe(
+        'REWARD_RECIPIENT_MISMATCH',
+      );
+    });
+
+    describe('with restakes', () => {
+      const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+      const stake = (value: string) =>
+        JSON.stringify({
+          from: userAddress,
+          to: stETH,
+          value,
+          data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+          chainId: 1,
+        });
+      const claim = (data: string) =>
+        JSON.stringify({
+          from: userAddress,
+          to: withdrawalQueue,
+          value: '0x0',
+          data,
+          chainId: 1,
+        });
+      const call = (unsignedTransaction: string) => {
+        const { to, value, data } = JSON.parse(unsignedTransaction);
+        return {
+          target: to,
+          value: BigInt(value).toString(),
+          data,
+          allowFailure: false,
+        };
+      };
+
+      // Stands in for a batch whose calls the user's account makes itself,
+      // as a smart account's executeBatch does
+      const validateBatch = (
+        unsignedTransactions: string[],
+        positionOwners?: Record<string, string>,
+      ) => {
+        const originalValidator = validatorRegistry.get(yieldId)!;
+        const batchTx = multicallTx();
+        const mockValidator = Object.assign(Object.create(originalValidator), {
+          getMulticall: (unsignedTransaction: string) =>
+            unsignedTransaction === batchTx
+              ? {
+                  multicall: {
+                    detectedType: 'MULTICALL',
+                    address: withdrawalQueue,
+                    functionName: 'multicall',
+                    value: '0',
+                    calls: unsignedTransactions.map(call),
+                  },
+                  unsignedTransactions,
+                }
+              : undefined,
+        });
+        (validatorRegistry as any).set(yieldId, mockValidator);
+
+        const result = validate(batchTx, positionOwners);
+
+        (validatorRegistry as any).set(yieldId, originalValidator);
+        return result;
+      };
+
+      it('should sum the restakes of a claim and restake', () => {
+        const result = validateBatch(
+          [
+            claim(claimWithdrawals(123)),
+            claim(claimWithdrawals(124)),
+            stake('0xde0b6b3a7640000'),
+            stake('0x6f05b59d3b20000'),
+          ],
+          owners,
+        );
+
+        expect(result.isValid).toBe(true);
+        expect(result.amount?.amount).toBe('1500000000000000000');
+        expect(result.compound?.restaked).toEqual(result.amount);
+        expect(result.positions).toEqual([
+          { positionId: '123', subCall: 0, owner: userAddress },
+          { positionId: '124', subCall: 1, owner: userAddress },
+        ]);
+      });
+
+      it('should reject a restake made from another account', () => {
+        const result = validateBatch(
+          [
+            claim(claimWithdrawals(123)),
+            JSON.stringify({
+              ...JSON.parse(stake('0xde0b6b3a7640000')),
+              from: otherAddress,
+            }),
+          ],
+          owners,
+        );
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('MULTICALL_CALL_INVALID');
+        expect(result.details?.subCall).toBe(1);
+      });
+    });
+  });
+
+  describe('Summary', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const stakeTx = (from = userAddress) =>
+      JSON.stringify({
+        to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+        from,
+        value: '0xde0b6b3a7640000',
+        data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+        chainId: 1,
+      });
+
+    it('should summarize the action of a valid transaction', () => {
+      const result = shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: stakeTx(),
+        userAddress,
+      });
+
+      expect(result.yieldName).toBe('Lido');
+      expect(result.summary).toBe('You are staking 1 ETH with Lido');
+    });
+
+    it('should name the yield a valid transaction was validated against', () => {
+      const valid = shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: stakeTx(),
+        userAddress,
+      });
+      const invalid = shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: stakeTx(),
+        userAddress: referralAddress,
+      });
+
+      expect(valid.yield).toEqual({
+        name: 'Lido',
+        protocol: 'lido',
+        network: 'ethereum',
+      });
+      expect(invalid.isValid).toBe(false);
+      expect(invalid.yield).toBeUndefined();
+    });
+
+    it('should fall back to English for locales without messages', () => {
+      const result = shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: stakeTx(),
+        userAddress,
+        locale: 'fr-CA',
+      });
+
+      expect(result.locale).toBe('en');
+      expect(result.summary).toBe('You are staking 1 ETH with Lido');
+      expect(
+        shield.validate({
+          yieldId: 'ethereum-eth-lido-staking',
+          unsignedTransaction: stakeTx(),
+          userAddress,
+        }).locale,
+      ).toBeUndefined();
+    });
+
+    it('should format the amount for the locale, keeping normalized', () => {
+      const result = shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify({
+          ...JSON.parse(stakeTx()),
+          value: '0x' + (12345n * 10n ** 17n).toString(16),
+        }),
+        userAddress,
+        locale: 'de-DE',
+      });
+
+      expect(result.amount).toMatchObject({
+        normalized: '1234.5',
+        formatted: '1.234,5',
+      });
+      expect(result.summary).toBe('You are staking 1.234,5 ETH with Lido');
+      expect(
+        shield.validate({
+          yieldId: 'ethereum-eth-lido-staking',
+          unsignedTransaction: stakeTx(),
+          userAddress,
+        }).amount?.formatted,
+      ).toBeUndefined();
+    });
+
+    it('should not summarize an invalid transaction', () => {
+      const result = shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: stakeTx(
+          '0x0000000000000000000000000000000000000001',
+        ),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.summary).toBeUndefined();
+    });
+  });
+
+  describe('Matched rule', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const stakeTx = (from = userAddress) =>
+      JSON.stringify({
+        to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+        from,
+        value: '0xde0b6b3a7640000',
+        data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+        chainId: 1,
+      });
+
+    it('should name the function a valid transaction matched', () => {
+      const result = shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: stakeTx(),
+        userAddress,
+      });
+
+      expect(result.matchedRule).toEqual({
+        id: 'ethereum-eth-lido-staking:STAKE:submit(address)',
+        description:
+          'Selector 0xa1903eab (submit(address)) to 0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84 matched STAKE of ethereum-eth-lido-staking',
+      });
+    });
+
+    it('should keep the id when the transaction changes', () => {
+      const result = shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify({
+          ...JSON.parse(stakeTx()),
+          value: '0x1',
+          nonce: 7,
+        }),
+        userAddress,
+        locale: 'de-DE',
+      });
+
+      expect(result.matchedRule?.id).toBe(
+        'ethereum-eth-lido-staking:STAKE:submit(address)',
+      );
+    });
+
+    it('should not name a rule for an invalid transaction', () => {
+      const result = shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: stakeTx(
+          '0x0000000000000000000000000000000000000001',
+        ),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.matchedRule).toBeUndefined();
+    });
+  });
+
+  describe('Fingerprint', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const stakeTx = (fields: Record<string, unknown> = {}) =>
+      JSON.stringify({
+        to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+        from: userAddress,
+        value: '0xde0b6b3a7640000',
+        data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+        chainId: 1,
+        ...fields,
+      });
+    const fingerprint = (unsignedTransaction: string) =>
+      shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction,
+        userAddress,
+      }).fingerprint;
+
+    it('should fingerprint a valid transaction', () => {
+      expect(fingerprint(stakeTx())).toBe(
+        'db7a76636e608775fcd7e02c26b6fb8239a8874ff3ee450bb46a50878c9a1aab',
+      );
+    });
+
+    it('should share the fingerprint across encodings', () => {
+      const reference = fingerprint(stakeTx());
+
+      expect(
+        fingerprint(
+          stakeTx({
+            to: '0xAE7AB96520DE3A18E5E111B5EAAB095312D7FE84',
+            from: userAddress.toUpperCase().replace('0X', '0x'),
+            value: '1000000000000000000',
+            data: '0xA1903EAB' + referralAddress.slice(2).padStart(64, '0'),
+          }),
+        ),
+      ).toBe(reference);
+      expect(fingerprint(stakeTx({ nonce: 7, gasLimit: '0x5208' }))).toBe(
+        reference,
+      );
+    });
+
+    it('should change the fingerprint with the amount', () => {
+      expect(fingerprint(stakeTx({ value: '0x1' }))).not.toBe(
+        fingerprint(stakeTx()),
+      );
+    });
+
+    it('should not fingerprint an invalid transaction', () => {
+      expect(
+        fingerprint(
+          stakeTx({ from: '0x0000000000000000000000000000000000000001' }),
+        ),
+      ).toBeUndefined();
+    });
+  });
+
+  describe('Contract overrides', () => {
+    const yieldId = 'ethereum-eth-lido-staking';
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const stETH = '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84';
+    const moved = '0x1111111111111111111111111111111111111111';
+    const stakeTx = (to: string) =>
+      JSON.stringify({
+        to,
+        from: userAddress,
+        value: '0xde0b6b3a7640000',
+        data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+        chainId: 1,
+      });
+
+    it('should match a transaction sent to the overridden address', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: stakeTx(moved),
+        userAddress,
+        contractOverrides: { [yieldId]: moved },
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.STAKE);
+      expect(result.expectedRecipient).toBe(moved);
+      expect(result.contractOverride).toEqual({
+        registered: stETH,
+        address: moved,
+      });
+    });
+
+    it('should not persist past the request that carries it', () => {
+      shield.validate({
+        yieldId,
+        unsignedTransaction: stakeTx(moved),
+        userAddress,
+        contractOverrides: { [yieldId]: moved },
+      });
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: stakeTx(moved),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('RECIPIENT_MISMATCH');
+      expect(result.contractOverride).toBeUndefined();
+    });
+
+    it('should leave transactions to other addresses and yields alone', () => {
+      const registered = shield.validate({
+        yieldId,
+        unsignedTransaction: stakeTx(stETH),
+        userAddress,
+        contractOverrides: { [yieldId]: moved },
+      });
+      const otherYield = shield.validate({
+        yieldId,
+        unsignedTransaction: stakeTx(moved),
+        userAddress,
+        contractOverrides: { 'ethereum-eth-reth-staking': moved },
+      });
+
+      expect(registered.isValid).toBe(true);
+      expect(registered.contractOverride).toBeUndefined();
+      expect(otherYield.isValid).toBe(false);
+    });
+
+    it('should check the policy against the overridden address', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: stakeTx(moved),
+        userAddress,
+        contractOverrides: { [yieldId]: moved },
+        policy: { allowedContracts: [stETH] },
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('CONTRACT_NOT_ALLOWED');
+    });
+  });
+
+  describe('proxy upgrades', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const stETH = '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84';
+    const implementation = '0x1111111111111111111111111111111111111111';
+    const iface = new ethers.Interface([
+      'function upgradeTo(address newImplementation)',
+      'function upgradeToAndCall(address newImplementation, bytes data)',
+      'function upgrade(address proxy, address implementation)',
+    ]);
+    const buildTx = (to: string, data: string) =>
+      JSON.stringify({ to, from: userAddress, value: '0x0', data, chainId: 1 });
+
+    it('should reject upgrades the yield does not expect', () => {
+      const cases = [
+        {
+          to: stETH,
+          data: iface.encodeFunctionData('upgradeTo', [implementation]),
+          functionName: 'upgradeTo',
+        },
+        {
+          to: stETH,
+          data: iface.encodeFunctionData('upgradeToAndCall', [
+            implementation,
+            '0x',
+          ]),
+          functionName: 'upgradeToAndCall',
+        },
+        {
+          to: '0x2222222222222222222222222222222222222222', // A ProxyAdmin
+          data: iface.encodeFunctionData('upgrade', [stETH, implementation]),
+          functionName: 'upgrade',
+        },
+      ];
+      for (const { to, data, functionName } of cases) {
+        const result = shield.validate({
+          yieldId: 'ethereum-eth-lido-staking',
+          unsignedTransaction: buildTx(to, data),
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('UNEXPECTED_PROXY_UPGRADE');
+        expect(result.details).toEqual({
+          yieldId: 'ethereum-eth-lido-staking',
+          proxy: stETH,
+          implementation,
+          functionName,
+        });
+      }
+    });
+
+    it('should warn on upgrades the yield expects', () => {
+      const validator = validatorRegistry.get('ethereum-eth-lido-staking')!;
+      const functions = validator.getAbiFunctions();
+      // A yield whose ABI lists upgradeTo as its STAKE function
+      Object.assign(validator, {
+        getAbiFunctions: () => [
+          ...functions,
+          {
+            transactionType: TransactionType.STAKE,
+            name: 'upgradeTo',
+            selector: iface.getFunction('upgradeTo')!.selector,
+            signature: 'upgradeTo(address)',
+            inputs: [{ name: 'newImplementation', type: 'address' }],
+          },
+        ],
+        validate: (_: string, type: TransactionType) =>
+          type === TransactionType.STAKE
+            ? { isValid: true }
+            : { isValid: false, reason: 'Not supported' },
+      });
+
+      try {
+        const result = shield.validate({
+          yieldId: 'ethereum-eth-lido-staking',
+          unsignedTransaction: buildTx(
+            stETH,
+            iface.encodeFunctionData('upgradeTo', [implementation]),
+          ),
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.decoded?.implementationChange).toEqual({
+          proxy: stETH,
+          implementation,
+          functionName: 'upgradeTo',
+        });
+        expect(result.warnings?.map(({ code }) => code)).toContain(
+          'IMPLEMENTATION_CHANGE',
+        );
+      } finally {
+        const overridden = validator as unknown as Record<string, unknown>;
+        delete overridden.getAbiFunctions;
+        delete overridden.validate;
+      }
+    });
+  });
+
+  describe('privileged functions', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const stETH = '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84';
+    const withdrawalQueue = '0x889edC2eDab5f40e902b864aD4d7AdE8E412F9B1';
+    const iface = new ethers.Interface([
+      'function transferOwnership(address newOwner)',
+      'function pause()',
+      'function pauseStaking()',
+      'function pauseFor(uint256 duration)',
+      'function setFee(uint256 fee)',
+    ]);
+    const buildTx = (to: string, data: string, chainId = 1) =>
+      JSON.stringify({ to, from: userAddress, value: '0x0', data, chainId });
+    const validateLido = (to: string, data: string) =>
+      shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: buildTx(to, data),
+        userAddress,
+      });
+
+    it('should reject admin functions of a yield contract by name', () => {
+      const result = validateLido(
+        stETH,
+        iface.encodeFunctionData('transferOwnership', [userAddress]),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('PRIVILEGED_FUNCTION_CALL');
+      expect(result.reason).toContain('transferOwnership');
+      expect(result.details).toEqual({
+        yieldId: 'ethereum-eth-lido-staking',
+        contract: stETH,
+        functionName: 'transferOwnership',
+        signature: 'transferOwnership(address)',
+        selector: iface.getFunction('transferOwnership')!.selector,
+      });
+    });
+
+    it('should reject the functions a validator lists for its contracts', () => {
+      const staking = validateLido(
+        stETH,
+        iface.encodeFunctionData('pauseStaking'),
+      );
+      const withdrawals = validateLido(
+        withdrawalQueue,
+        iface.encodeFunctionData('pauseFor', [3600]),
+      );
+
+      expect(staking.reasonCode).toBe('PRIVILEGED_FUNCTION_CALL');
+      expect(withdrawals.reasonCode).toBe('PRIVILEGED_FUNCTION_CALL');
+      expect(withdrawals.details).toMatchObject({
+        contract: withdrawalQueue,
+        functionName: 'pauseFor',
+      });
+    });
+
+    it('should not name a function of a contract the yield does not list', () => {
+      const result = validateLido(
+        '0x2222222222222222222222222222222222222222',
+        iface.encodeFunctionData('pause'),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).not.toBe('PRIVILEGED_FUNCTION_CALL');
+    });
+
+    it('should reject the functions the registry lists for a vault', () => {
+      const vaultAddress = '0x3333333333333333333333333333333333333333';
+      const vault = {
+        yieldId: 'sepolia-usdc-admin-vault',
+        address: vaultAddress,
+        chainId: 11155111,
+        protocol: 'euler',
+        network: 'sepolia',
+        inputTokenAddress: '0x4444444444444444444444444444444444444444',
+        vaultTokenAddress: vaultAddress,
+        isWethVault: false,
+        privilegedFunctions: { [vaultAddress]: ['setFee(uint256)'] },
+      };
+      const overridden = new Shield({ registryOverride: { vaults: [vault] } });
+      const validateVault = (data: string) =>
+        overridden.validate({
+          yieldId: vault.yieldId,
+          unsignedTransaction: buildTx(vaultAddress, data, 11155111),
+          userAddress,
+        });
+
+      expect(
+        validateVault(iface.encodeFunctionData('setFee', [100])).details,
+      ).toMatchObject({ functionName: 'setFee', signature: 'setFee(uint256)' });
+      expect(validateVault(iface.encodeFunctionData('pause')).reasonCode).toBe(
+        'PRIVILEGED_FUNCTION_CALL',
+      );
+    });
+
+    it('should explain the check', () => {
+      const { trace } = shield.explain({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: buildTx(stETH, iface.encodeFunctionData('pause')),
+        userAddress,
+      });
+
+      expect(
+        trace.find((entry) => entry.check === 'privileged-function'),
+      ).toEqual(expect.objectContaining({ status: 'fail' }));
+    });
+  });
+
+  describe('explain', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const stakeTx = (from = userAddress) =>
+      JSON.stringify({
+        to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+        from,
+        value: '0xde0b6b3a7640000',
+        data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+        chainId: 1,
+      });
+    const entry = (
+      trace: { check: string; status: string }[],
+      check: string,
+    ) => trace.find((e) => e.check === check);
+
+    it('should trace every check a valid transaction passes', () => {
+      const result = shield.explain({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: stakeTx(),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.trace.map(({ check }) => check)).toEqual([
+        'yield',
+        'request',
+        'chain-id',
+        'transaction-format',
+        'safe-wrapper',
+        'sender',
+        'multicall',
+        'proxy-upgrade',
+        'privileged-function',
+        'approval-spender',
+        'reward-recipient',
+        'withdrawal-recipient',
+        'beneficiary',
+        'native-value',
+        'recipient',
+        'selector',
+        'transaction-type',
+        'stake-token',
+        'amount',
+        'delegation',
+        'ens-recipient',
+        'recipient-code',
+        'contract-paused',
+        'contract-age',
+        'yield-status',
+        'bytecode-hash',
+        'unstake-balance',
+        'claim-position',
+        'memo',
+        'deadline',
+        'lock-duration',
+        'nonce',
+        'policy',
+        'risk-threshold',
+        'strict-mode',
+      ]);
+      expect(result.trace.some(({ status }) => status === 'fail')).toBe(false);
+      for (const check of ['sender', 'recipient', 'selector']) {
+        expect(entry(result.trace, check)?.status).toBe('pass');
+      }
+      expect(entry(result.trace, 'transaction-type')).toEqual({
+        check: 'transaction-type',
+        status: 'pass',
+        detail: 'Matches STAKE and no other transaction type',
+      });
+      expect(entry(result.trace, 'amount')?.status).toBe('skip');
+    });
+
+    it('should skip the checks after the one that fails', () => {
+      const result = shield.explain({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: stakeTx(
+          '0x0000000000000000000000000000000000000001',
+        ),
+        userAddress,
+      });
+
+      expect(result.reasonCode).toBe('SENDER_MISMATCH');
+      expect(entry(result.trace, 'sender')?.status).toBe('fail');
+      const after = result.trace.slice(
+        result.trace.findIndex(({ check }) => check === 'sender') + 1,
+      );
+      expect(after.length).toBeGreaterThan(0);
+      expect(after.every(({ status }) => status === 'skip')).toBe(true);
+    });
+
+    it('should fail the amount check on an unexpected amount', () => {
+      const result = shield.explain({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: stakeTx(),
+        userAddress,
+        expectedAmount: '2000000000000000000',
+      });
+
+      expect(result.reasonCode).toBe('AMOUNT_MISMATCH');
+      expect(entry(result.trace, 'transaction-type')?.status).toBe('pass');
+      expect(entry(result.trace, 'amount')?.status).toBe('fail');
+    });
+
+    it('should list why each transaction type did not match', () => {
+      const result = shield.explain({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify({
+          to: '0x0000000000000000000000000000000000000000',
+          from: userAddress,
+          value: '0x0',
+          data: '0xdeadbeef',
+          chainId: 1,
+        }),
+        userAddress,
+      });
+
+      expect(result.reasonCode).toBe('RECIPIENT_MISMATCH');
+      expect(entry(result.trace, 'recipient')?.status).toBe('fail');
+      const attempts = result.trace.filter(({ check }) =>
+        check.startsWith('transaction-type:'),
+      );
+      expect(attempts.length).toBeGreaterThan(0);
+      expect(attempts.every(({ status }) => status === 'fail')).toBe(true);
+    });
+
+    it('should warn when the sender cannot be checked', () => {
+      const result = shield.explain({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: stakeTx(),
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(entry(result.trace, 'sender')?.status).toBe('warn');
+    });
+
+    it('should fail the yield check of an unknown yield', () => {
+      const result = shield.explain({
+        yieldId: 'unknown-yield',
+        unsignedTransaction: stakeTx(),
+        userAddress,
+      });
+
+      expect(result.trace).toEqual([
+        { check: 'yield', status: 'fail', detail: expect.any(String) },
+      ]);
+    });
+  });
+
+  describe('EIP-7702 delegation', () => {
+    const yieldId = 'ethereum-eth-lido-staking';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const delegate = '0x63c0c19a282a1B52b07dD5a65b58948A07DAE32B';
+    // Well-known test keys; never use them on a live network
+    const wallet = new ethers.Wallet(
+      '0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318',
+    );
+    const other = new ethers.Wallet('0x' + '11'.repeat(32));
+    const delegating = new Shield({
+      delegationTargets: { [yieldId]: [delegate] },
+    });
+
+    const authorize = (signer = wallet, address = delegate) => {
+      const authorization = { chainId: 1n, address, nonce: 1n };
+      const { r, s, yParity } = signer.signingKey.sign(
+        ethers.hashAuthorization(authorization),
+      );
+      return { chainId: 1, address, nonce: 1, r, s, yParity };
+    };
+    const stakeTx = (authorizationList: object[]) =>
+      JSON.stringify({
+        to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+        from: wallet.address,
+        value: '0xde0b6b3a7640000',
+        data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+        maxFeePerGas: '0x6fc23ac00',
+        maxPriorityFeePerGas: '0x3b9aca00',
+        chainId: 1,
+        type: 4,
+        authorizationList,
+      });
+
+    it('should warn about a delegation to an allowed contract', () => {
+      const result = delegating.validate({
+        yieldId,
+        unsignedTransaction: stakeTx([authorize()]),
+        userAddress: wallet.address,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.decoded?.authorizationList).toEqual([
+        {
+          chainId: '1',
+          address: delegate,
+          nonce: '1',
+          authority: wallet.address,
+        },
+      ]);
+      expect(result.warnings?.map((w) => w.code)).toContain(
+        'EIP7702_DELEGATION',
+      );
+    });
+
+    it('should reject a delegation the yield does not allow', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: stakeTx([authorize()]),
+        userAddress: wallet.address,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('DELEGATION_TARGET_NOT_ALLOWED');
+      expect(result.details).toEqual({ yieldId, index: 0, actual: delegate });
+    });
+
+    it('should accept clearing a delegation', () => {
+      const cleared = '0x' + '0'.repeat(40);
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: stakeTx([authorize(wallet, cleared)]),
+        userAddress: wallet.address,
+      });
+
+      expect(result.isValid).toBe(true);
+    });
+
+    it('should reject an authorization of another account', () => {
+      const result = delegating.validate({
+        yieldId,
+        unsignedTransaction: stakeTx([authorize(), authorize(other)]),
+        userAddress: wallet.address,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('AUTHORITY_MISMATCH');
+      expect(result.details).toEqual({
+        yieldId,
+        index: 1,
+        expected: wallet.address,
+        actual: other.address,
+      });
+    });
+
+    it('should decode the authorizations', () => {
+      const result = shield.decode({
+        yieldId,
+        unsignedTransaction: stakeTx([authorize(other)]),
+      });
+
+      expect(result.decoded?.authorizationList?.[0].authority).toBe(
+        other.address,
+      );
+    });
+  });
+
+  describe('Function signature', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const referralAddress = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+    const yieldId = 'ethereum-eth-lido-staking';
+    const tx = (data: string) =>
+      JSON.stringify({
+        to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+        from: userAddress,
+        value: '0xde0b6b3a7640000',
+        data,
+        chainId: 1,
+      });
+    const stakeTx = tx(
+      '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+    );
+
+    it('should name the canonical signature of a decoded call', () => {
+      const result = shield.decode({ yieldId, unsignedTransaction: stakeTx });
+
+      expect(result.decoded?.functionName).toBe('submit');
+      expect(result.decoded?.functionSignature).toBe('submit(address)');
+    });
+
+    it('should add the selector and signature to validate results', () => {
+      const request = { yieldId, unsignedTransaction: stakeTx, userAddress };
+
+      expect(shield.validate(request).decoded?.functionSignature).toBe(
+        undefined,
+      );
+      const result = shield.validate({ ...request, includeSignature: true });
+      expect(result.isValid).toBe(true);
+      expect(result.decoded?.selector).toBe('0xa1903eab');
+      expect(result.decoded?.functionSignature).toBe('submit(address)');
+    });
+
+    it('should mark the signature of an unknown selector as unknown', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: tx('0xdeadbeef' + '0'.repeat(64)),
+        userAddress,
+        includeSignature: true,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.decoded?.selector).toBe('0xdeadbeef');
+      expect(result.decoded?.functionSignature).toBe('unknown');
+    });
+
+    it('should leave transactions without calldata alone', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: tx('0x'),
+        userAddress,
+        includeSignature: true,
+      });
+
+      expect(result.decoded?.functionSignature).toBeUndefined();
+    });
+  });
+
+  describe('Withdrawal recipient', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const yieldId =
+      'arbitrum-arb-earb-1-0x7ed866d2d66c3149fafe854c30c68a8ba7cee8b9-4626-vault';
+    const vault = '0x7ed866d2d66c3149fafe854c30c68a8ba7cee8b9';
+    const token = '0x912ce59144191c1204e64559fe8253a0e49e6548';
+
+    const vaultIface = new ethers.Interface([
+      'function withdraw(uint256 assets, address receiver, address owner) returns (uint256)',
+      'function redeem(uint256 shares, address receiver, address owner) returns (uint256)',
+    ]);
+    const vaultTx = (
+      name: 'withdraw' | 'redeem',
+      amount: bigint,
+      receiver: string,
+    ) =>
+      JSON.stringify({
+        to: vault,
+        from: userAddress,
+        value: '0x0',
+        data: vaultIface.encodeFunctionData(name, [
+          amount,
+          receiver,
+          userAddress,
+        ]),
+        chainId: 42161,
+      });
+
+    it('should report what a withdrawal pays and to whom', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: vaultTx('withdraw', 100n, userAddress),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.WITHDRAW);
+      expect(result.decoded?.withdrawal).toEqual({
+        phase: 'WITHDRAW',
+        recipient: ethers.getAddress(userAddress),
+        token,
+        amount: '100',
+      });
+    });
+
+    it('should reject a withdrawal paid to another address', () => {
+      const attacker = '0x000000000000000000000000000000000000bad1';
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: vaultTx('redeem', 5n, attacker),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('WITHDRAWAL_RECIPIENT_MISMATCH');
+      expect(result.details?.expected).toBe(userAddress);
+      expect(result.details?.actual?.toLowerCase()).toBe(attacker);
+    });
+
+    it('should mark a Lido withdrawal request as the first phase', () => {
+      const withdrawalQueue = new ethers.Interface([
+        'function requestWithdrawals(uint256[] _amounts, address _owner) returns (uint256[])',
+      ]);
+      const result = shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify({
+          to: '0x889edC2eDab5f40e902b864aD4d7AdE8E412F9B1',
+          from: userAddress,
+          value: '0x0',
+          data: withdrawalQueue.encodeFunctionData('requestWithdrawals', [
+            [10n ** 18n, 2n * 10n ** 18n],
+            userAddress,
+          ]),
+          chainId: 1,
+        }),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.UNSTAKE);
+      expect(result.decoded?.withdrawal).toEqual({
+        phase: 'REQUEST',
+        recipient: ethers.getAddress(userAddress),
+        token: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+        amount: '3000000000000000000',
+      });
+    });
+  });
+
+  describe('Beneficiary', () => {
+    const custodian = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const customer = '0x1111111111111111111111111111111111111111';
+    const yieldId =
+      'arbitrum-arb-earb-1-0x7ed866d2d66c3149fafe854c30c68a8ba7cee8b9-4626-vault';
+
+    const vaultIface = new ethers.Interface([
+      'function deposit(uint256 assets, address receiver) returns (uint256)',
+    ]);
+    const depositTx = (receiver: string) =>
+      JSON.stringify({
+        to: '0x7ed866d2d66c3149fafe854c30c68a8ba7cee8b9',
+        from: custodian,
+        value: '0x0',
+        data: vaultIface.encodeFunctionData('deposit', [100n, receiver]),
+        chainId: 42161,
+      });
+
+    it('should accept a deposit a custodian sends for its customer', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: depositTx(customer),
+        userAddress: custodian,
+        beneficiaryAddress: customer,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.SUPPLY);
+    });
+
+    it('should reject a deposit credited to another account', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: depositTx(custodian),
+        userAddress: custodian,
+        beneficiaryAddress: customer,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('BENEFICIARY_MISMATCH');
+      expect(result.details?.expected).toBe(customer);
+      expect(result.details?.actual?.toLowerCase()).toBe(custodian);
+    });
+
+    it('should require the user to be credited without a beneficiary', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: depositTx(customer),
+        userAddress: custodian,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('BENEFICIARY_MISMATCH');
+      expect(result.details?.expected).toBe(custodian);
+    });
+
+    it('should reject a stake that can only credit its sender', () => {
+      const referral = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
+      const result = shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: JSON.stringify({
+          to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+          from: custodian,
+          value: '0xde0b6b3a7640000',
+          data: '0xa1903eab' + referral.slice(2).padStart(64, '0'),
+          chainId: 1,
+        }),
+        userAddress: custodian,
+        beneficiaryAddress: customer,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('BENEFICIARY_MISMATCH');
+      expect(result.details).toEqual({
+        yieldId: 'ethereum-eth-lido-staking',
+        expected: customer,
+        actual: custodian,
+      });
+    });
+  });
+
+  describe('Bridge then stake', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const attacker = '0x1111111111111111111111111111111111111111';
+    const yieldId =
+      'arbitrum-arb-earb-1-0x7ed866d2d66c3149fafe854c30c68a8ba7cee8b9-4626-vault';
+    const vault = '0x7ed866d2d66c3149fafe854c30c68a8ba7cee8b9';
+    const arb = '0x912ce59144191c1204e64559fe8253a0e49e6548'; // On Arbitrum
+    const handler = '0x924a9f036260DdD5808007E1AA95f08eD08aA569';
+
+    const spokePoolIface = new ethers.Interface([
+      'function depositV3(address depositor, address recipient, address inputToken, address outputToken, uint256 inputAmount, uint256 outputAmount, uint256 destinationChainId, address exclusiveRelayer, uint32 quoteTimestamp, uint32 fillDeadline, uint32 exclusivityDeadline, bytes message)',
+    ]);
+    const tokenIface = new ethers.Interface([
+      'function approve(address spender, uint256 amount) returns (bool)',
+    ]);
+    const vaultIface = new ethers.Interface([
+      'function deposit(uint256 assets, address receiver) returns (uint256)',
+    ]);
+    const approveCall = [
+      arb,
+      tokenIface.encodeFunctionData('approve', [vault, 100n]),
+      0n,
+    ];
+    const depositCall = (receiver = userAddress) => [
+      vault,
+      vaultIface.encodeFunctionData('deposit', [100n, receiver]),
+      0n,
+    ];
+    const message = (calls: unknown[][], fallbackRecipient = userAddress) =>
+      ethers.AbiCoder.defaultAbiCoder().encode(
+        [
+          'tuple(tuple(address target, bytes callData, uint256 value)[] calls, address fallbackRecipient)',
+        ],
+        [[calls, fallbackRecipient]],
+      );
+    const bridgeTx = ({
+      recipient = handler,
+      destinationChainId = 42161,
+      calls = [approveCall, depositCall()],
+      fallbackRecipient = userAddress,
+    }: {
+      recipient?: string;
+      destinationChainId?: number;
+      calls?: unknown[][];
+      fallbackRecipient?: string;
+    } = {}) =>
+      JSON.stringify({
+        to: '0x5c7BCd6E7De5423a257D81B442095A1a6ced35C5', // Ethereum
+        from: userAddress,
+        value: '0x0',
+        data: spokePoolIface.encodeFunctionData('depositV3', [
+          userAddress,
+          recipient,
+          '0xB50721BCf8d664c30412Cfbc6cf7a15145234ad1', // ARB on Ethereum
+          arb,
+          101n,
+          100n,
+          destinationChainId,
+          ethers.ZeroAddress,
+          1700000000,
+          1700003600,
+          0,
+          message(calls, fallbackRecipient),
+        ]),
+        chainId: 1,
+      });
+
+    it('should accept a bridge whose message stakes for the user', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: bridgeTx(),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.BRIDGE);
+      expect(result.detectedTypes).toEqual([
+        TransactionType.BRIDGE,
+        TransactionType.APPROVAL,
+        TransactionType.SUPPLY,
+      ]);
+      expect(result.bridgeLeg).toMatchObject({
+        protocol: 'across',
+        sourceChainId: '1',
+        destinationChainId: '42161',
+        inputAmount: '101',
+        outputAmount: '100',
+        fillDeadline: 1700003600,
+      });
+      expect(result.stakingLeg).toHaveLength(2);
+      expect(result.stakingLeg?.every((step) => step.isValid)).toBe(true);
+    });
+
+    it('should reject a bridge to another chain than the yield', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: bridgeTx({ destinationChainId: 10 }),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('BRIDGE_DESTINATION_MISMATCH');
+      expect(result.details).toMatchObject({
+        expected: '42161',
+        actual: '10',
+      });
+    });
+
+    it('should reject bridged funds that can reach another account', () => {
+      const recipient = shield.validate({
+        yieldId,
+        unsignedTransaction: bridgeTx({ recipient: attacker }),
+        userAddress,
+      });
+      const fallback = shield.validate({
+        yieldId,
+        unsignedTransaction: bridgeTx({ fallbackRecipient: attacker }),
+        userAddress,
+      });
+
+      expect(recipient.reasonCode).toBe('BRIDGE_RECIPIENT_MISMATCH');
+      expect(recipient.details?.field).toBe('recipient');
+      expect(fallback.reasonCode).toBe('BRIDGE_RECIPIENT_MISMATCH');
+      expect(fallback.details).toMatchObject({
+        field: 'fallbackRecipient',
+        expected: userAddress,
+      });
+    });
+
+    it('should reject a message without calls', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: bridgeTx({ calls: [] }),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('BRIDGE_MESSAGE_INVALID');
+    });
+
+    it('should reject a staking leg that credits another account', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: bridgeTx({
+          calls: [approveCall, depositCall(attacker)],
+        }),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('FLOW_STEP_INVALID');
+      expect(result.details?.step).toBe(1);
+      expect(result.stakingLeg?.[1].reasonCode).toBe('BENEFICIARY_MISMATCH');
+    });
+
+    it('should trace the bridge and the staking leg', () => {
+      const { trace } = shield.explain({
+        yieldId,
+        unsignedTransaction: bridgeTx({ destinationChainId: 10 }),
+        userAddress,
+      });
+
+      expect(trace.map((entry) => [entry.check, entry.status])).toEqual([
+        ['yield', 'pass'],
+        ['bridge', 'fail'],
+        ['staking-leg', 'skip'],
+      ]);
+    });
+  });
+
+  describe('Plain transfers', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const stETH = '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84';
+    const transfer = (fields: Record<string, unknown> = {}) =>
+      JSON.stringify({
+        to: stETH,
+        from: userAddress,
+        value: '0xde0b6b3a7640000',
+        chainId: 1,
+        ...fields,
+      });
+
+    it('should pass a transfer to a contract that stakes it', () => {
+      const result = shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: transfer({ data: '0x' }),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.TRANSFER);
+      expect(result.emptyCalldata).toBe(true);
+      expect(result.expectedRecipient).toBe(stETH);
+      expect(result.amount?.amount).toBe('1000000000000000000');
+      expect(result.summary).toBe('You are sending 1 ETH to Lido');
+    });
+
+    it('should reject a transfer to a contract that does not stake it', () => {
+      for (const [yieldId, to] of [
+        ['ethereum-eth-reth-staking', stETH],
+        [
+          'ethereum-eth-lido-staking',
+          '0x889edC2eDab5f40e902b864aD4d7AdE8E412F9B1', // Withdrawal Queue
+        ],
+      ]) {
+        const result = shield.validate({
+          yieldId,
+          unsignedTransaction: transfer({ to }),
+          userAddress,
+        });
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('NAKED_TRANSFER_NOT_SUPPORTED');
+        expect(result.emptyCalldata).toBe(true);
+        expect(result.details?.actual).toBe(to);
+      }
+    });
+
+    it('should reject a transfer without value', () => {
+      const result = shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: transfer({ value: '0x0' }),
+        userAddress,
+      });
+
+      expect(result.reasonCode).toBe('NAKED_TRANSFER_NOT_SUPPORTED');
+    });
+
+    it('should credit the sender', () => {
+      const result = shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: transfer(),
+        userAddress,
+        beneficiaryAddress: '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be',
+      });
+
+      expect(result.reasonCode).toBe('BENEFICIARY_MISMATCH');
+    });
+
+    it('should check the amount the user intended', () => {
+      const result = shield.validate({
+        yieldId: 'ethereum-eth-lido-staking',
+        unsignedTransaction: transfer(),
+        userAddress,
+        expectedAmount: '2000000000000000000',
+      });
+
+      expect(result.reasonCode).toBe('AMOUNT_MISMATCH');
+    });
+  });
+
+  describe('validateAndSimulate', () => {
+    const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+    const yieldId =
+      'arbitrum-arb-earb-1-0x7ed866d2d66c3149fafe854c30c68a8ba7cee8b9-4626-vault';
+    const vault = '0x7ed866d2d66c3149fafe854c30c68a8ba7cee8b9';
+    const rpcUrl = 'https://arbitrum.example.com';
+
+    const vaultIface = new ethers.Interface([
+      'function deposit(uint256 assets, address receiver) returns (uint256)',
+    ]);
+    const depositTx = JSON.stringify({
+      to: vault,
+      from: userAddress,
+      value: '0x0',
+      data: vaultIface.encodeFunctionData('deposit', [100n, userAddress]),
+      chainId: 42161,
+    });
+
+    const originalFetch = global.fetch;
+    let fetchMock: jest.Mock;
+    const respondWith = (body: object) => {
+      fetchMock = jest.fn().mockResolvedValue({
+        ok: true,
+        status: 200,
+        json: () => Promise.resolve({ jsonrpc: '2.0', id: 1, ...body }),
+      });
+      global.fetch = fetchMock as unknown as typeof fetch;
+    };
+    const shares = (amount: bigint) =>
+      ethers.AbiCoder.defaultAbiCoder().encode(['uint256'], [amount]);
+
+    afterAll(() => {
+      global.fetch = originalFetch;
+    });
+
+    it('should report the shares a deposit credits', async () => {
+      respondWith({ result: shares(95n) });
+
+      const result = await shield.validateAndSimulate({
+        yieldId,
+        unsignedTransaction: depositTx,
+        userAddress,
+        rpcUrl,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.SUPPLY);
+      expect(result.simulation?.success).toBe(true);
+      expect(result.simulation?.balanceChange).toEqual({
+        token: vault,
+        amount: '95',
+      });
+    });
+
+    it('should reject a deposit that credits no shares', async () => {
+      respondWith({ result: shares(0n) });
+
+      const result = await shield.validateAndSimulate({
+        yieldId,
+        unsignedTransaction: depositTx,
+        userAddress,
+        rpcUrl,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('SIMULATION_NO_BALANCE_CHANGE');
+    });
+
+    it('should add simulation time to the timing', async () => {
+      respondWith({ result: shares(95n) });
+
+      const result = await shield.validateAndSimulate({
+        yieldId,
+        unsignedTransaction: depositTx,
+        userAddress,
+        rpcUrl,
+        includeTiming: true,
+      });
+
+      expect(result.isValid).toBe(true);
+      const { matchMs, simulateMs, totalMs } = result.timing!;
+      expect(simulateMs).toEqual(expect.any(Number));
+      expect(totalMs).toBeCloseTo(matchMs + simulateMs!);
+    });
+
+    it('should reject a reverting transaction with its decoded reason', async () => {
+      respondWith({
+        error: {
+          code: 3,
+          message: 'execution reverted',
+          data:
+            '0x08c379a0' +
+            ethers.AbiCoder.defaultAbiCoder()
+              .encode(['string'], ['E_ZeroShares'])
+              .slice(2),
+        },
+      });
+
+      const result = await shield.validateAndSimulate({
+        yieldId,
+        unsignedTransaction: depositTx,
+        userAddress,
+        rpcUrl,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('SIMULATION_REVERTED');
+      expect(result.simulation?.revertReason).toBe('E_ZeroShares');
+      expect(result.detectedType).toBeUndefined();
+    });
+
+    it('should reject when the node cannot be reached', async () => {
+      global.fetch = jest
+        .fn()
+        .mockRejectedValue(
+          new Error('connect ECONNREFUSED'),
+        ) as unknown as typeof fetch;
+
+      const result = await shield.validateAndSimulate({
+        yieldId,
+        unsignedTransaction: depositTx,
+        userAddress,
+        rpcUrl,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('SIMULATION_FAILED');
+      expect(result.details?.error).toBe('connect ECONNREFUSED');
+    });
+
+    it('should not simulate transactions that fail validation', async () => {
+      respondWith({ result: shares(95n) });
+
+      const result = await shield.validateAndSimulate({
+        yieldId,
+        unsignedTransaction: depositTx,
+        userAddress: '0x0000000000000000000000000000000000000001',
+        rpcUrl,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('SENDER_MISMATCH');
+      expect(fetchMock).not.toHaveBeenCalled();
     });
   });
 });
@@ -1,126 +1,4621 @@
 import {
+  AbiFunction,
+  AmountLimits,
   ValidationResult,
+  DecodeResult,
   ActionArguments,
+  ExplainResult,
+  FlowValidationResult,
+  ImplementationChange,
+  IntentComparisonResult,
+  MulticallTransaction,
+  PreflightResult,
+  ReasonCode,
+  StakedBalanceCall,
+  ClaimCall,
+  ClaimedPosition,
+  ClaimedPositions,
+  ContractDeployment,
+  TokenApproval,
+  TransactionAmount,
+  TransactionLock,
+  TransactionSignatures,
+  TransactionIntent,
   TransactionType,
+  TypedData,
+  WrappedTransaction,
+  UserOperation,
+  UserOperationValidationResult,
+  SendCalls,
+  SendCallsValidationResult,
   ValidationContext,
+  ValidationPolicy,
+  ValidationTiming,
+  ValidationWarning,
+  WarningSeverity,
+  VersionInfo,
+  RegistryCheckResult,
+  YieldCapabilities,
+  SupportedYield,
+  YieldMatch,
 } from './types';
-import { validatorRegistry } from './validators';
-import { isNonEmptyString, isNullOrUndefined } from './utils/validation';
+import {
+  BaseEVMValidator,
+  validatorRegistry,
+  withBabylonStaking,
+  withRegistryOverride,
+} from './validators';
+import { BaseValidator } from './validators/base.validator';
+import {
+  isDefined,
+  isNonEmptyString,
+  isNullOrUndefined,
+} from './utils/validation';
+import {
+  computeRiskScore,
+  toRiskLevel,
+  withWarningSeverities,
+} from './risk';
+import {
+  CallOutcome,
+  callUint256,
+  getDeployment,
+  getLookupTable,
+  getOwnerOf,
+  getTransactionCount,
+  hasCode,
+  isPaused,
+  resolveEnsName,
+  simulateCall,
+} from './simulation';
+import { decodeAccountCalls, getPaymaster } from './user-operation';
+import { ACROSS_MULTICALL_HANDLER, decodeBridgeCall } from './bridge';
+import { decodeRawTransaction } from './raw-transaction';
+import { getVersionInfo } from './version';
+import { checkRegistry } from './registry-check';
+import { traceValidation } from './explain';
+import { summarize } from './summary';
+import { computeFingerprint } from './fingerprint';
+import { compareWholeUnits, toTransactionAmount } from './utils/amount';
+import { toDeadline } from './utils/deadline';
+import {
+  formatAmount,
+  getMessageCatalog,
+  localizeResult,
+  resolveLocale,
+  structureResult,
+} from './locales';
+import type { VaultRegistryOverride } from './validators/evm/erc4626';
+import type { BabylonStakingParams } from './validators/bitcoin';
+
+// Permits and deadlines further out than this add LONG_DEADLINE, unless
+// the policy sets its own maxDeadlineSeconds
+const DEFAULT_MAX_DEADLINE_SECONDS = 24 * 60 * 60;
+
+// Calldata longer than this fails CALLDATA_TOO_LARGE, unless the policy
+// sets its own maxCalldataBytes. Safe transactions and multicalls carry
+// whole calls, so they get more room
+const DEFAULT_MAX_CALLDATA_BYTES = 8 * 1024;
+const DEFAULT_MAX_BATCH_CALLDATA_BYTES = 128 * 1024;
+
+// Swaps that accept more than this below their expected output, in basis
+// points, add LOW_SLIPPAGE_PROTECTION, unless the policy sets its own
+// maxSlippageBps
+const DEFAULT_MAX_SLIPPAGE_BPS = 500;
+
+// An unstake that leaves less than this of the staked balance, in basis
+// points, adds UNSTAKE_NEAR_FULL
+const NEAR_FULL_REMAINDER_BPS = 100n;
+
+// Transaction types that credit a position to the account they name or,
+// when they name none, to their sender
+const CREDITING_TYPES = new Set([
+  TransactionType.STAKE,
+  TransactionType.SUPPLY,
+  TransactionType.DEPOSIT,
+  TransactionType.RESTAKE,
+]);
+
+// Transaction types that pay out what a position earned or unlocked, and
+// those a multicall may put it back into the yield with
+const CLAIMING_TYPES = new Set([
+  TransactionType.CLAIM_UNSTAKED,
+  TransactionType.CLAIM_REWARDS,
+]);
+const RESTAKING_TYPES = new Set([
+  ...CREDITING_TYPES,
+  TransactionType.RESTAKE_REWARDS,
+]);
+
+// Transaction types that put the yield's stake token into it
+const STAKING_TYPES = new Set([...CREDITING_TYPES, TransactionType.LOCK]);
+
+// An EIP-7702 authorization to the zero address clears the account's
+// delegation rather than making one
+const CLEARED_DELEGATION = '0x' + '0'.repeat(40);
+
+// wallet_sendCalls capabilities that change nothing the calls do: who pays
+// the gas (ERC-7677) and where the wallet finds the funds (ERC-7682)
+const KNOWN_CAPABILITIES = new Set(['paymasterService', 'auxiliaryFunds']);
+
+export interface ShieldOptions {
+  // Vaults registered over the embedded registry, e.g. testnet deployments.
+  // An entry replaces any yield of the same yieldId
+  registryOverride?: VaultRegistryOverride;
+  // Babylon's global staking parameters, from its global-params.json.
+  // bitcoin-btc-babylon-staking is only supported when they are given
+  babylonParams?: BabylonStakingParams;
+  // Contracts an EIP-7702 authorization may delegate the user's account
+  // to, by yieldId. Yields without an entry accept no delegation
+  delegationTargets?: Record<string, string[]>;
+}
 
 export interface ValidationRequest {
   yieldId: string;
   unsignedTransaction: string;
+  // When omitted, the transaction's own sender is trusted and the result
+  // carries a SENDER_NOT_VERIFIED warning
+  userAddress?: string;
+  args?: ActionArguments;
+  context?: ValidationContext;
+  // Reject otherwise valid transactions whose riskScore reaches this value
+  riskThreshold?: number;
+  policy?: ValidationPolicy;
+  // Reject otherwise valid transactions that carry any warning
+  strict?: boolean;
+  // With strict, only warnings of these severities reject, e.g. just
+  // ['critical']. Every severity does when left out
+  strictSeverities?: WarningSeverity[];
+  // Base units the user intends to move, e.g. the 1.5 ETH they asked to
+  // stake. A transaction moving another amount fails with AMOUNT_MISMATCH
+  expectedAmount?: string;
+  expectedAmountToken?: string; // Token contract or denomination, or 'native'
+  amountToleranceBps?: number; // Allowed deviation from expectedAmount
+  amountTolerance?: string; // The same in base units; the larger applies
+  // Nonce the transaction must use, or it fails with NONCE_MISMATCH
+  expectedNonce?: number;
+  // The sender's next nonce, e.g. from fetchAccountNonce. A transaction
+  // nonce below it adds NONCE_TOO_LOW, one above it NONCE_GAP
+  accountNonce?: number;
+  // The block state such as accountNonce and contractCode was read at, for
+  // reproducing a validation later. validateAndSimulate simulates at it,
+  // and the result reports it as blockNumber
+  blockNumber?: number;
+  // Report where validation spent its time as the result's timing
+  includeTiming?: boolean;
+  // Set decoded.selector and decoded.functionSignature on EVM calls
+  includeSignature?: boolean;
+  // ENS name the user was shown as the recipient. It must resolve, through
+  // ensAddresses, to the contract the transaction calls, or validation
+  // fails with RECIPIENT_ENS_MISMATCH
+  expectedRecipientEns?: string;
+  // Addresses of the names getEnsNames lists, e.g. from resolveEnsName.
+  // Names that did not resolve are left out
+  ensAddresses?: Record<string, string>;
+  // The addresses of the lookup tables a versioned Solana transaction
+  // loads accounts from, in order, by table address, e.g. from
+  // fetchLookupTable. Without each, it fails with ALT_RESOLUTION_REQUIRED
+  addressLookupTables?: Record<string, string[]>;
+  // Whether each contract getCodeAddresses lists has code, e.g. from
+  // hasContractCode. Calldata sent to one without fails with
+  // RECIPIENT_NOT_A_CONTRACT
+  contractCode?: Record<string, boolean>;
+  // Whether each contract with code reports paused(), e.g. from
+  // isContractPaused. A paused one adds CONTRACT_PAUSED
+  contractPaused?: Record<string, boolean>;
+  // When each contract with code was deployed, e.g. from
+  // fetchContractDeployment. One younger than policy.minContractAgeSeconds
+  // adds RECENTLY_DEPLOYED_CONTRACT
+  contractDeployments?: Record<string, ContractDeployment>;
+  // The balance getStakedBalanceCall reads, in base units, e.g. from
+  // fetchStakedBalance. An unstake of more fails with
+  // UNSTAKE_EXCEEDS_BALANCE
+  stakedBalance?: string;
+  // The owner of each position getClaimedPositions lists, by position ID,
+  // e.g. from fetchPositionOwners. A claim of a position owned by anyone
+  // but userAddress, or left out, fails with CLAIM_POSITION_NOT_OWNED
+  positionOwners?: Record<string, string>;
+  // BCP-47 tag, e.g. 'de-DE', of the language reasons, warnings and the
+  // summary are written in, where Shield has them in it; English otherwise
+  locale?: string;
+  // Keep reasons and warnings in English, report the reason as
+  // reasonMessage too, and put the locale's messages in messageLocalized
+  structuredMessages?: boolean;
+  // Memo the transaction must carry, e.g. an exchange deposit's tag. A
+  // transaction with none fails with MISSING_MEMO, another with
+  // MEMO_MISMATCH
+  expectedMemo?: string;
+  // Never reject: an invalid result is reported valid, with what it would
+  // have been as wouldReject, wouldRejectReason and wouldRejectReasonCode
+  observe?: boolean;
+  // Account a stake or deposit must credit when userAddress, e.g. a
+  // custodian, sends it on the account's behalf. Another fails with
+  // BENEFICIARY_MISMATCH
+  beneficiaryAddress?: string;
+  // keccak256 of the code deployed at the transaction's recipient, fetched
+  // by the caller. Fails with BYTECODE_MISMATCH when the registry pins
+  // another hash for the recipient
+  actualBytecodeHash?: string;
+  // Mark a yieldId Shield does not know with an UNKNOWN_YIELD warning, for
+  // clients that may name yields newer than this release. The result
+  // still fails with YIELD_NOT_FOUND
+  lenientUnknownYield?: boolean;
+  // Address a yield's contract moved to before the registry caught up, by
+  // yieldId. A transaction sent there is matched as if sent to the yield's
+  // registered contract, for this call only
+  contractOverrides?: Record<string, string>;
+}
+
+export interface RawTransactionValidationRequest
+  extends Omit<ValidationRequest, 'unsignedTransaction'> {
+  // RLP-encoded EVM transaction, signed or not, as 0x-prefixed hex or
+  // base64. On Cosmos, a base64 TxRaw; on Solana, a serialized transaction
+  rawTransaction: string;
+  // The signer's Cosmos account number, which a SIGN_MODE_DIRECT signature
+  // commits to. Needed to verify a signed Cosmos transaction
+  accountNumber?: string;
+}
+
+export interface CandidateValidationRequest
+  extends Omit<ValidationRequest, 'yieldId'> {
+  yieldIds: string[]; // Tried in order, so the likeliest goes first
+}
+
+export interface SimulationRequest extends ValidationRequest {
+  rpcUrl: string; // JSON-RPC endpoint of the yield's chain
+}
+
+export interface FlowValidationRequest {
+  yieldId: string;
+  transactions: string[]; // Unsigned transactions, in execution order
+  userAddress?: string;
+  beneficiaryAddress?: string; // Credited by each step in its place
+  args?: ActionArguments;
+  context?: ValidationContext;
+  riskThreshold?: number;
+  policy?: ValidationPolicy;
+  strict?: boolean;
+  strictSeverities?: WarningSeverity[];
+}
+
+export interface TypedDataValidationRequest {
+  yieldId: string;
+  typedData: TypedData; // EIP-712 payload the user is asked to sign
   userAddress: string;
+  // Only its maxDeadlineSeconds applies, or all of it to a SafeTx
+  policy?: ValidationPolicy;
+  strict?: boolean;
+  strictSeverities?: WarningSeverity[];
+}
+
+export interface UserOperationValidationRequest {
+  yieldId: string;
+  userOperation: UserOperation;
+  userAddress?: string; // Must be userOperation.sender when given
+  paymasters?: string[]; // Paymasters expected to sponsor the gas
+  args?: ActionArguments;
+  context?: ValidationContext;
+  riskThreshold?: number;
+  policy?: ValidationPolicy;
+  strict?: boolean;
+  strictSeverities?: WarningSeverity[];
+}
+
+export interface SendCallsValidationRequest {
+  yieldId: string;
+  sendCalls: SendCalls; // The params of an EIP-5792 wallet_sendCalls
+  userAddress?: string; // Must be sendCalls.from when both are given
   args?: ActionArguments;
   context?: ValidationContext;
+  riskThreshold?: number;
+  policy?: ValidationPolicy;
+  strict?: boolean;
+  strictSeverities?: WarningSeverity[];
+}
+
+export interface IntentComparisonRequest {
+  intent: TransactionIntent;
+  unsignedTransaction: string;
+  userAddress?: string;
+  args?: ActionArguments;
+  context?: ValidationContext;
+  riskThreshold?: number;
+  policy?: ValidationPolicy;
+  strict?: boolean;
+  strictSeverities?: WarningSeverity[];
+}
+
+export interface PreflightRequest {
+  yieldId: string;
+  detectedType: TransactionType; // The type of the transaction to be built
+  userAddress?: string;
+  // Base units of the token the yield stakes, as a decimal string. Only
+  // read for stakes, locks, supplies and deposits
+  amount?: string;
+  chainId?: string; // The chain the wallet means to build it for
+  policy?: ValidationPolicy;
+}
+
+export interface DecodeRequest {
+  unsignedTransaction: string;
+  // Narrows decoding to one yield's ABIs and enables detectedType
+  yieldId?: string;
+  // Describe a call no ABI matches by its selector rather than as null
+  includeSignature?: boolean;
+}
+
+function isBasisPoints(value: number): boolean {
+  return Number.isInteger(value) && value >= 0 && value <= 10000;
+}
+
+// Both limits, when set, in whole units, e.g. "32" or "0.5"
+function isAmountLimits(limits: AmountLimits): boolean {
+  return [limits.minAmount, limits.maxAmount].every(
+    (limit) => !isDefined(limit) || /^[0-9]+(\.[0-9]+)?$/.test(limit),
+  );
+}
+
+function isLookupTables(tables: Record<string, string[]>): boolean {
+  return (
+    typeof tables === 'object' &&
+    tables !== null &&
+    Object.values(tables).every(
+      (addresses) =>
+        Array.isArray(addresses) && addresses.every(isNonEmptyString),
+    )
+  );
+}
+
+function isNonce(value: number): boolean {
+  return Number.isSafeInteger(value) && value >= 0;
+}
+
+// Timings are reported to the microsecond
+function roundMs(ms: number): number {
+  return Math.round(ms * 1000) / 1000;
+}
+
+function elapsedMs(startedAt: number): number {
+  return roundMs(performance.now() - startedAt);
+}
+
+// Transaction types by function selector across every registered yield,
+// built on first use
+let typesBySelector: Map<string, TransactionType[]> | undefined;
+
+function getImpliedTypes(selector: string | undefined): TransactionType[] {
+  if (!isDefined(selector)) return [];
+
+  if (!typesBySelector) {
+    typesBySelector = new Map();
+    for (const validator of validatorRegistry.values()) {
+      for (const fn of validator.getAbiFunctions()) {
+        const types = typesBySelector.get(fn.selector) ?? [];
+        if (!types.includes(fn.transactionType)) types.push(fn.transactionType);
+        typesBySelector.set(fn.selector, types);
+      }
+    }
+  }
+  return typesBySelector.get(selector) ?? [];
 }
 
 export class Shield {
-  getSupportedYieldIds(): string[] {
-    return Array.from(validatorRegistry.keys());
+  private readonly validators: ReadonlyMap<string, BaseValidator>;
+
+  constructor(private readonly options: ShieldOptions = {}) {
+    const validators = isDefined(options.registryOverride)
+      ? withRegistryOverride(options.registryOverride)
+      : validatorRegistry;
+    this.validators = isDefined(options.babylonParams)
+      ? withBabylonStaking(validators, options.babylonParams)
+      : validators;
+  }
+
+  /**
+   * Lists every supported yield, or only those on chainId when it is given
+   * (in the format of YieldCapabilities.chainId, e.g. '42161').
+   */
+  getSupportedYieldIds(chainId?: string): string[] {
+    const yieldIds = Array.from(this.validators.keys());
+    if (!isDefined(chainId)) return yieldIds;
+
+    return this.getSupportedYields(chainId).map(({ yieldId }) => yieldId);
+  }
+
+  /**
+   * Like getSupportedYieldIds, with each yield's chainId alongside it.
+   */
+  getSupportedYields(chainId?: string): SupportedYield[] {
+    const yields = Array.from(this.validators, ([yieldId, validator]) => ({
+      yieldId,
+      chainId: validator.getCapabilities().chainId,
+    }));
+    if (!isDefined(chainId)) return yields;
+
+    return yields.filter((entry) => entry.chainId === chainId);
   }
 
   isSupported(yieldId: string): boolean {
-    return validatorRegistry.has(yieldId);
+    return this.validators.has(yieldId);
+  }
+
+  /**
+   * Identifies this build, its embedded registry snapshot and the registry
+   * override, if any.
+   */
+  getVersion(): VersionInfo {
+    return getVersionInfo(this.validators.size, this.options.registryOverride);
+  }
+
+  /**
+   * Checks that the registry this instance validates against, the
+   * registry override merged over the built-in one, is consistent: yield
+   * IDs are unique, chain IDs valid, addresses well-formed, and selectors
+   * those of their signatures. isValid is false on any error.
+   */
+  checkRegistry(): RegistryCheckResult {
+    return checkRegistry(this.validators, this.options.registryOverride);
+  }
+
+  /**
+   * Describes what a yield supports, or returns null for unknown yields.
+   */
+  getYieldCapabilities(yieldId: string): YieldCapabilities | null {
+    const validator = this.validators.get(yieldId);
+    if (!validator) return null;
+
+    return {
+      yieldId,
+      supportedTypes: validator.getSupportedTransactionTypes(),
+      ...validator.getCapabilities(),
+      status: validator.getStatus(),
+    };
+  }
+
+  /**
+   * Lists the contract functions a yield's transactions call, optionally
+   * for one transaction type, or returns null for unknown yields.
+   */
+  getYieldAbi(
+    yieldId: string,
+    transactionType?: TransactionType,
+  ): AbiFunction[] | null {
+    const validator = this.validators.get(yieldId);
+    if (!validator) return null;
+
+    const functions = validator.getAbiFunctions();
+    if (!isDefined(transactionType)) return functions;
+
+    return functions.filter((fn) => fn.transactionType === transactionType);
   }
 
   validate(request: ValidationRequest): ValidationResult {
-    if (isNullOrUndefined(request)) {
+    return this.applyObserveMode(
+      request,
+      this.withBlockNumber(
+        request,
+        this.withFunctionSignature(request, this.checkRequest(request)),
+      ),
+    );
+  }
+
+  // Reports the block the request's state was read at, so that the
+  // validation can be repeated against the same state
+  private withBlockNumber(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    return isDefined(request?.blockNumber)
+      ? { ...result, blockNumber: request.blockNumber }
+      : result;
+  }
+
+  /**
+   * With includeSignature, reports the selector an EVM call starts with and
+   * the signature of the function it names, e.g. 'submit(address)', or
+   * 'unknown' when none of the yield's ABIs has one, so logs show what was
+   * called whether or not it validated.
+   */
+  private withFunctionSignature(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    const validator = this.validators.get(request?.yieldId);
+    if (
+      !request?.includeSignature ||
+      !validator ||
+      !isNonEmptyString(request.unsignedTransaction)
+    ) {
+      return result;
+    }
+
+    const signature = validator.getFunctionSignature(
+      request.unsignedTransaction,
+    );
+    return isDefined(signature)
+      ? { ...result, decoded: { ...result.decoded, ...signature } }
+      : result;
+  }
+
+  // A bridge-then-stake transaction is validated leg by leg, any other as it is
+  private checkRequest(request: ValidationRequest): ValidationResult {
+    const bridged = this.checkBridge(request);
+    return isDefined(bridged)
+      ? this.localize(request, bridged)
+      : this.checkTransaction(request);
+  }
+
+  private checkTransaction(request: ValidationRequest): ValidationResult {
+    const resolved = this.resolveRecipient(request);
+    if (!resolved?.includeTiming) {
+      return this.localize(resolved, this.assess(resolved));
+    }
+
+    const startedAt = performance.now();
+    const result = this.assess(resolved);
+    const matchMs = elapsedMs(startedAt);
+    return this.localize(resolved, {
+      ...result,
+      timing: this.measureDecode(resolved, matchMs),
+    });
+  }
+
+  /**
+   * Validates a transaction that bridges to the yield's chain and stakes
+   * there in one call: an Across deposit whose message has Across'
+   * MulticallHandler make the staking calls with what it bridges. The
+   * bridge must go to the yield's chain, and only the user may get the
+   * funds back. The message's calls are validated as a flow the handler
+   * sends on the yield's chain, crediting the user. Returns undefined for
+   * transactions that call no bridge Shield knows, to be validated as they
+   * are.
+   */
+  private checkBridge(
+    request: ValidationRequest,
+  ): ValidationResult | undefined {
+    if (
+      isNullOrUndefined(request) ||
+      !isNonEmptyString(request.unsignedTransaction)
+    ) {
+      return undefined;
+    }
+    const validator = this.validators.get(request.yieldId);
+    let tx: { from?: unknown; to?: unknown; data?: unknown };
+    try {
+      tx = JSON.parse(request.unsignedTransaction);
+    } catch {
+      return undefined;
+    }
+    const bridge = isNullOrUndefined(tx) ? null : decodeBridgeCall(tx);
+    if (!validator || !bridge) return undefined;
+
+    const { leg, calls } = bridge;
+    const { yieldId, userAddress } = request;
+    const fail = (
+      reasonCode: ReasonCode,
+      reason: string,
+      details: Record<string, unknown>,
+    ): ValidationResult => ({
+      isValid: false,
+      reason,
+      reasonCode,
+      details: { yieldId, ...details },
+      detectedType: TransactionType.BRIDGE,
+      bridgeLeg: leg,
+    });
+
+    if (!isNonEmptyString(userAddress)) {
+      return fail(
+        'INVALID_REQUEST',
+        'userAddress is needed to check where a bridge sends the funds',
+        {},
+      );
+    }
+    const chainId = validator.getChainId(request.unsignedTransaction);
+    if (isDefined(chainId) && chainId !== leg.sourceChainId) {
+      return fail('CHAIN_ID_MISMATCH', 'CHAIN_ID_MISMATCH', {
+        expected: leg.sourceChainId,
+        actual: chainId,
+      });
+    }
+    if (
+      typeof tx.from !== 'string' ||
+      !validator.isSameAddress(tx.from, userAddress)
+    ) {
+      return fail('SENDER_MISMATCH', 'SENDER_MISMATCH', {
+        expected: userAddress,
+        actual: tx.from,
+      });
+    }
+
+    const destinationChainId = validator.getCapabilities().chainId;
+    if (leg.destinationChainId !== destinationChainId) {
+      return fail(
+        'BRIDGE_DESTINATION_MISMATCH',
+        `Bridges to chain ${leg.destinationChainId}, not the yield's chain ${destinationChainId}`,
+        { expected: destinationChainId, actual: leg.destinationChainId },
+      );
+    }
+    if (calls === null || calls.length === 0) {
+      return fail(
+        'BRIDGE_MESSAGE_INVALID',
+        "The bridge's message makes no calls Shield can decode",
+        {},
+      );
+    }
+
+    // The handler runs the calls; the user gets refunds and leftovers
+    const accounts = [
+      ['recipient', leg.recipient, ACROSS_MULTICALL_HANDLER],
+      ['depositor', leg.depositor, userAddress],
+      ['fallbackRecipient', leg.fallbackRecipient, userAddress],
+    ] as const;
+    for (const [field, actual, expected] of accounts) {
+      if (!isDefined(actual) || !validator.isSameAddress(actual, expected)) {
+        return fail(
+          'BRIDGE_RECIPIENT_MISMATCH',
+          `The bridge's ${field} is ${actual}, not ${expected}`,
+          { field, expected, actual },
+        );
+      }
+    }
+
+    const flow = this.validateFlow({
+      yieldId,
+      transactions: calls.map((call) =>
+        JSON.stringify({
+          from: ACROSS_MULTICALL_HANDLER,
+          ...call,
+          chainId: destinationChainId,
+        }),
+      ),
+      userAddress: ACROSS_MULTICALL_HANDLER,
+      beneficiaryAddress: userAddress,
+      args: request.args,
+      context: request.context,
+      riskThreshold: request.riskThreshold,
+      policy: request.policy,
+      strict: request.strict,
+      strictSeverities: request.strictSeverities,
+    });
+
+    const warnings = flow.steps.flatMap((step) => step.warnings ?? []);
+    const result: ValidationResult = {
+      isValid: flow.isValid,
+      ...(!flow.isValid && {
+        reason: flow.reason,
+        reasonCode: flow.reasonCode,
+        details: flow.details,
+      }),
+      detectedType: TransactionType.BRIDGE,
+      detectedTypes: [
+        TransactionType.BRIDGE,
+        ...flow.steps.flatMap((step) =>
+          isDefined(step.detectedType) ? [step.detectedType] : [],
+        ),
+      ],
+      expectedRecipient: leg.contract,
+      amount: { token: leg.inputToken, amount: leg.inputAmount },
+      ...(warnings.length > 0 && { warnings }),
+      bridgeLeg: leg,
+      stakingLeg: flow.steps,
+    };
+    const riskScore = computeRiskScore(result, request.unsignedTransaction);
+    return this.withSummary(
+      request,
+      this.applyStrictMode(request, {
+        ...result,
+        riskScore,
+        riskLevel: toRiskLevel(riskScore),
+      }),
+    );
+  }
+
+  private assess(request: ValidationRequest): ValidationResult {
+    const matched = this.matchOverriddenContract(request);
+    if (isNullOrUndefined(request)) return matched;
+
+    const result = this.applyPolicy(
+      request,
+      this.applyNonceChecks(
+        request,
+        this.applyLockCheck(
+          request,
+          this.applyDeadlineCheck(
+            request,
+            this.applyMemoCheck(
+              request,
+              this.applyPositionCheck(
+                request,
+                this.applyBalanceCheck(
+                  request,
+                  this.applyBytecodeCheck(
+                    request,
+                    this.applyYieldStatusCheck(
+                      request,
+                      this.applyContractAgeCheck(
+                        request,
+                        this.applyPauseCheck(
+                          request,
+                          this.applyContractCodeCheck(
+                            request,
+                            this.applyEnsCheck(
+                              request,
+                              this.applyDelegationCheck(
+                                request,
+                                this.applyReplayCheck(request, matched),
+                              ),
+                            ),
+                          ),
+                        ),
+                      ),
+                    ),
+                  ),
+                ),
+              ),
+            ),
+          ),
+        ),
+      ),
+    );
+
+    const riskScore = computeRiskScore(result, request.unsignedTransaction);
+    const assessed: ValidationResult = {
+      ...result,
+      riskScore,
+      riskLevel: toRiskLevel(riskScore),
+    };
+
+    if (
+      assessed.isValid &&
+      isDefined(request.riskThreshold) &&
+      riskScore >= request.riskThreshold
+    ) {
       return {
+        ...assessed,
         isValid: false,
-        reason: 'Missing validation request',
+        reason: `Transaction risk score ${riskScore} reaches the configured threshold of ${request.riskThreshold}`,
+        reasonCode: 'RISK_THRESHOLD_EXCEEDED',
       };
     }
 
-    const validator = validatorRegistry.get(request.yieldId);
+    const strict = this.applyStrictMode(request, assessed);
+    return this.withFingerprint(
+      request,
+      this.withMatchedRule(
+        request,
+        this.withSummary(
+        request,
+          this.withFormattedAmount(
+            request,
+            this.withAmountDelta(request, strict),
+          ),
+        ),
+      ),
+    );
+  }
 
-    if (!validator) {
+  /**
+   * Fingerprints a valid transaction by what it does on its chain, with
+   * addresses normalized as the validator compares them, so that encodings
+   * of the same transaction share it. See computeFingerprint.
+   */
+  private withFingerprint(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    const validator = this.validators.get(request.yieldId);
+    if (!result.isValid || !validator) return result;
+
+    const tx = request.unsignedTransaction;
+    const from = validator.getSigner(tx) ?? request.userAddress;
+    const { amount } = result;
+    const fingerprint = computeFingerprint({
+      chainId: validator.getCapabilities().chainId,
+      from: isDefined(from) ? validator.normalizeAddress(from) : null,
+      to: validator
+        .getContractAddresses(tx)
+        .map((address) => validator.normalizeAddress(address)),
+      selector: validator.getSelector(tx)?.toLowerCase() ?? null,
+      token: !isDefined(amount)
+        ? null
+        : amount.token === 'native'
+          ? 'native'
+          : validator.normalizeAddress(amount.token),
+      amount: isDefined(amount) ? BigInt(amount.amount).toString() : null,
+    });
+    return { ...result, fingerprint };
+  }
+
+  /**
+   * Names the rule a valid transaction matched: its yield, type and, for a
+   * contract call, the function of getYieldAbi it calls. Transactions that
+   * call none, such as Cosmos messages or multicalls, are named by type.
+   */
+  private withMatchedRule(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    const validator = this.validators.get(request.yieldId);
+    const { detectedType } = result;
+    if (!result.isValid || !isDefined(detectedType) || !validator) {
+      return result;
+    }
+
+    const selector = validator.getSelector(request.unsignedTransaction);
+    const fn = validator
+      .getAbiFunctions()
+      .find(
+        (fn) => fn.selector === selector && fn.transactionType === detectedType,
+      );
+    const id = [request.yieldId, detectedType, ...(fn ? [fn.signature] : [])];
+    const target =
+      result.expectedRecipient ?? result.expectedRecipients?.join(', ');
+    const call = [
+      fn ? `Selector ${fn.selector} (${fn.signature})` : 'The transaction',
+      ...(isDefined(target) ? [`to ${target}`] : []),
+    ].join(' ');
+    return {
+      ...result,
+      matchedRule: {
+        id: id.join(':'),
+        description: `${call} matched ${detectedType} of ${request.yieldId}`,
+      },
+    };
+  }
+
+  // How far the amount is from expectedAmount, also when within tolerance
+  private withAmountDelta(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    const { amount } = result;
+    const validator = this.validators.get(request.yieldId);
+    if (
+      !isDefined(request.expectedAmount) ||
+      !isDefined(amount) ||
+      !validator ||
+      (isDefined(request.expectedAmountToken) &&
+        !validator.isSameAddress(amount.token, request.expectedAmountToken))
+    ) {
+      return result;
+    }
+
+    const delta = BigInt(amount.amount) - BigInt(request.expectedAmount);
+    return { ...result, amountDelta: delta.toString() };
+  }
+
+  // The amount also as the request's locale writes it, for the summary
+  private withFormattedAmount(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    const { amount } = result;
+    if (!isDefined(request.locale) || !isDefined(amount?.normalized)) {
+      return result;
+    }
+
+    return {
+      ...result,
+      amount: {
+        ...amount,
+        formatted: formatAmount(amount.normalized, request.locale),
+      },
+    };
+  }
+
+  private withSummary(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    const validator = this.validators.get(request.yieldId);
+    if (!result.isValid || !isDefined(result.detectedType) || !validator) {
+      return result;
+    }
+
+    const { name, protocol, network } = validator.getCapabilities();
+    const { summarize: localized } = getMessageCatalog(request.locale);
+    return {
+      ...result,
+      yieldName: name,
+      yield: { name, protocol, network },
+      summary: (localized ?? summarize)(
+        result.detectedType,
+        name,
+        result.amount,
+      ),
+    };
+  }
+
+  // result in the request's locale, naming the one its messages are in
+  private localize(
+    request:
+      | Pick<ValidationRequest, 'locale' | 'structuredMessages'>
+      | undefined,
+    result: ValidationResult,
+  ): ValidationResult {
+    const catalog = isDefined(request?.locale)
+      ? getMessageCatalog(request.locale)
+      : undefined;
+    if (request?.structuredMessages) {
       return {
-        isValid: false,
-        reason: 'Unknown yield ID',
-        details: { yieldId: request.yieldId },
+        ...structureResult(result, catalog),
+        ...(isDefined(request.locale)
+          ? { locale: resolveLocale(request.locale) }
+          : {}),
       };
     }
+    if (!isDefined(catalog)) return result;
+    return {
+      ...localizeResult(result, catalog),
+      locale: resolveLocale(request!.locale),
+    };
+  }
+
+  /**
+   * Validates the transaction as validate does, and also reports every
+   * check that ran and its outcome as trace, for working out why a
+   * transaction was flagged. Nothing is simulated.
+   */
+  explain(request: ValidationRequest): ExplainResult {
+    const result = this.checkRequest(request);
+    const validator = isNullOrUndefined(request)
+      ? undefined
+      : this.validators.get(request.yieldId);
+    return {
+      ...this.applyObserveMode(request, result),
+      trace: traceValidation(this.resolveRecipient(request), validator, result),
+    };
+  }
+
+  /**
+   * In observe mode, reports an invalid result as valid, moving its reason
+   * to wouldRejectReason and wouldRejectReasonCode, so what enforcing would
+   * block can be measured against real traffic before turning it on.
+   */
+  private applyObserveMode(
+    request: { observe?: boolean } | undefined,
+    result: ValidationResult,
+  ): ValidationResult {
+    if (!request?.observe) return result;
+    if (result.isValid) return { ...result, wouldReject: false };
+
+    const { reason, reasonCode, ...observed } = result;
+    return {
+      ...observed,
+      isValid: true,
+      wouldReject: true,
+      wouldRejectReason: reason,
+      wouldRejectReasonCode: reasonCode,
+    };
+  }
 
+  /**
+   * Validates the transaction against each of yieldIds in turn, for callers
+   * that have a shortlist of yields but not the one it was built for. The
+   * first yield it passes for answers, with yieldId set to it. When it
+   * passes for none, the result fails with ALL_CANDIDATES_FAILED, and
+   * details.candidates gives each yield's reason.
+   */
+  validateCandidates(request: CandidateValidationRequest): ValidationResult {
+    return this.applyObserveMode(request, this.checkCandidates(request));
+  }
+
+  private checkCandidates(
+    request: CandidateValidationRequest,
+  ): ValidationResult {
     if (
-      !isNonEmptyString(request.unsignedTransaction) ||
-      !isNonEmptyString(request.userAddress)
+      isNullOrUndefined(request) ||
+      !Array.isArray(request.yieldIds) ||
+      request.yieldIds.length === 0
     ) {
       return {
         isValid: false,
         reason: 'Invalid request parameters',
+        reasonCode: 'INVALID_REQUEST',
       };
     }
 
-    const supportedTypes = validator.getSupportedTransactionTypes();
-    const attempts: Array<{
-      type: TransactionType;
-      result: ValidationResult;
-    }> = [];
-    const matches: Array<{ type: TransactionType; result: ValidationResult }> =
-      [];
+    const { yieldIds, ...shared } = request;
+    const candidates = [];
+    for (const yieldId of yieldIds) {
+      const result = this.checkTransaction({ ...shared, yieldId });
+      if (result.isValid) return { ...result, yieldId };
+      candidates.push({
+        yieldId,
+        reason: result.reason,
+        reasonCode: result.reasonCode,
+      });
+    }
+    return this.localize(request, {
+      isValid: false,
+      reason: 'ALL_CANDIDATES_FAILED',
+      reasonCode: 'ALL_CANDIDATES_FAILED',
+      details: { candidates },
+    });
+  }
 
-    for (const transactionType of supportedTypes) {
-      try {
-        const result = validator.validate(
-          request.unsignedTransaction,
-          transactionType,
-          request.userAddress,
-          request.args,
-          request.context,
-        );
+  /**
+   * Validates an RLP-encoded EVM transaction exactly as validate would
+   * validate its fields. The decoded fields are returned as transaction, so
+   * callers can confirm the parse; an unsigned transaction is taken to be
+   * sent by userAddress. A signed transaction must recover to userAddress,
+   * and also returns the recovered signer.
+   *
+   * On Cosmos and Solana, rawTransaction is the signed transaction itself,
+   * validated as its unsignedTransaction would be. Every signature must
+   * verify, and the fee payer must be userAddress.
+   */
+  validateRawTransaction(
+    request: RawTransactionValidationRequest,
+  ): ValidationResult {
+    return this.applyObserveMode(
+      request,
+      this.localize(request, this.checkRawTransaction(request)),
+    );
+  }
 
-        attempts.push({ type: transactionType, result });
+  private checkRawTransaction(
+    request: RawTransactionValidationRequest,
+  ): ValidationResult {
+    if (
+      isNullOrUndefined(request) ||
+      !isNonEmptyString(request.rawTransaction)
+    ) {
+      return {
+        isValid: false,
+        reason: 'Invalid request parameters',
+        reasonCode: 'INVALID_REQUEST',
+      };
+    }
 
-        if (result.isValid) {
-          matches.push({ type: transactionType, result });
-        }
-      } catch (error) {
-        attempts.push({
-          type: transactionType,
-          result: {
-            isValid: false,
-            reason: error instanceof Error ? error.message : String(error),
-          },
-        });
-      }
+    const signatures = this.validators
+      .get(request.yieldId)
+      ?.getSignatures(request.rawTransaction, request.accountNumber);
+    if (signatures !== undefined) {
+      return this.checkSignedTransaction(request, signatures);
     }
 
-    if (matches.length === 1) {
+    const decoded = decodeRawTransaction(request.rawTransaction);
+    if (!decoded) {
       return {
-        ...matches[0].result,
-        detectedType: matches[0].type,
+        isValid: false,
+        reason: 'MALFORMED_RAW_TRANSACTION',
+        reasonCode: 'MALFORMED_RAW_TRANSACTION',
+        details: { yieldId: request.yieldId },
       };
     }
 
-    if (matches.length > 1) {
+    const { fields, signed, signer } = decoded;
+    if (signed && signer === null) {
       return {
         isValid: false,
-        reason:
-          'Transaction validation failed: Ambiguous transaction pattern detected. Transaction matches multiple operation types, which may indicate a security risk.',
+        reason: 'SIGNATURE_INVALID',
+        reasonCode: 'SIGNATURE_INVALID',
+        details: { yieldId: request.yieldId },
+        transaction: fields,
+        signatureValid: false,
+      };
+    }
+
+    const signature =
+      signer !== null ? { recoveredAddress: signer, signatureValid: true } : {};
+    if (
+      signer !== null &&
+      isDefined(request.userAddress) &&
+      signer.toLowerCase() !== request.userAddress.toLowerCase()
+    ) {
+      return {
+        isValid: false,
+        reason: 'SIGNATURE_SENDER_MISMATCH',
+        reasonCode: 'SIGNATURE_SENDER_MISMATCH',
         details: {
           yieldId: request.yieldId,
-          matchedTypes: matches.map((m) => m.type),
-          warning:
-            'A legitimate transaction must match exactly one pattern. Multiple matches indicate potential manipulation.',
+          expected: request.userAddress,
+          actual: signer,
         },
+        transaction: fields,
+        ...signature,
       };
     }
 
-    return {
-      isValid: false,
-      reason:
-        'Transaction validation failed: No matching operation pattern found. This transaction may be malicious or corrupted.',
-      details: {
-        yieldId: request.yieldId,
-        supportedTypes,
-        warning:
-          'A legitimate transaction should match exactly one supported pattern',
-        attempts: attempts.map((a) => ({
-          type: a.type,
-          reason: a.result.reason,
+    const transaction = { ...fields, from: signer ?? request.userAddress };
+    const result = this.checkTransaction({
+      ...request,
+      unsignedTransaction: JSON.stringify(transaction),
+    });
+    return { ...result, transaction, ...signature };
+  }
+
+  // A Cosmos or Solana rawTransaction is validated as it is, as its
+  // unsignedTransaction would be. Its fee payer must be userAddress
+  private checkSignedTransaction(
+    request: RawTransactionValidationRequest,
+    signatures: TransactionSignatures | null,
+  ): ValidationResult {
+    if (signatures === null) {
+      return {
+        isValid: false,
+        reason: 'MALFORMED_RAW_TRANSACTION',
+        reasonCode: 'MALFORMED_RAW_TRANSACTION',
+        details: { yieldId: request.yieldId },
+      };
+    }
+
+    const { signed, signers, error } = signatures;
+    if (isDefined(error)) {
+      return {
+        isValid: false,
+        reason: 'SIGNATURE_INVALID',
+        reasonCode: 'SIGNATURE_INVALID',
+        details: { yieldId: request.yieldId, error },
+        signatureValid: false,
+      };
+    }
+
+    const signature = signed
+      ? {
+          recoveredAddress: signers[0],
+          recoveredAddresses: signers,
+          signatureValid: true,
+        }
+      : {};
+    if (
+      signed &&
+      isDefined(request.userAddress) &&
+      signers[0] !== request.userAddress
+    ) {
+      return {
+        isValid: false,
+        reason: 'SIGNATURE_SENDER_MISMATCH',
+        reasonCode: 'SIGNATURE_SENDER_MISMATCH',
+        details: {
+          yieldId: request.yieldId,
+          expected: request.userAddress,
+          actual: signers[0],
+        },
+        ...signature,
+      };
+    }
+
+    const result = this.checkTransaction({
+      ...request,
+      unsignedTransaction: request.rawTransaction,
+    });
+    return { ...result, ...signature };
+  }
+
+  /**
+   * Validates the transaction and, when it passes, executes it with eth_call
+   * against rpcUrl to confirm it succeeds and credits the user. This is the
+   * only method that makes network calls. Simulation failures of any kind
+   * reject the transaction.
+   */
+  async validateAndSimulate(
+    request: SimulationRequest,
+  ): Promise<ValidationResult> {
+    // Transactions that would be rejected are not simulated, observed or not
+    const result = this.checkTransaction(request);
+    if (!result.isValid) {
+      return this.applyObserveMode(
+        request,
+        this.withBlockNumber(request, result),
+      );
+    }
+
+    const startedAt = performance.now();
+    const simulated = await this.simulate(
+      this.resolveRecipient(request),
+      result,
+    );
+    if (!isDefined(result.timing)) {
+      return this.applyObserveMode(
+        request,
+        this.withBlockNumber(request, this.localize(request, simulated)),
+      );
+    }
+
+    const simulateMs = elapsedMs(startedAt);
+    return this.applyObserveMode(
+      request,
+      this.withBlockNumber(
+        request,
+        this.localize(request, {
+          ...simulated,
+          timing: {
+            ...result.timing,
+            simulateMs,
+            totalMs: roundMs(result.timing.totalMs + simulateMs),
+          },
+        }),
+      ),
+    );
+  }
+
+  private async simulate(
+    request: SimulationRequest,
+    result: ValidationResult,
+  ): Promise<ValidationResult> {
+    const validator = this.validators.get(request.yieldId)!;
+    const call = validator.getSimulationCall(request.unsignedTransaction);
+    if (!isDefined(call) || !isNonEmptyString(request.rpcUrl)) {
+      return {
+        isValid: false,
+        reason: 'Simulation is not supported for this yield',
+        reasonCode: 'SIMULATION_UNSUPPORTED',
+        details: { yieldId: request.yieldId },
+      };
+    }
+
+    let outcome: CallOutcome;
+    try {
+      outcome = await simulateCall(
+        request.rpcUrl,
+        call,
+        fetch,
+        request.blockNumber,
+      );
+    } catch (error) {
+      return {
+        isValid: false,
+        reason: 'SIMULATION_FAILED',
+        reasonCode: 'SIMULATION_FAILED',
+        details: {
+          yieldId: request.yieldId,
+          error: error instanceof Error ? error.message : String(error),
+        },
+      };
+    }
+
+    if (!outcome.success) {
+      return {
+        isValid: false,
+        reason: 'SIMULATION_REVERTED',
+        reasonCode: 'SIMULATION_REVERTED',
+        details: { yieldId: request.yieldId },
+        simulation: outcome,
+      };
+    }
+
+    const balanceChange = validator.getBalanceChange(
+      request.unsignedTransaction,
+      outcome.returnData,
+    );
+    const simulation = { ...outcome, balanceChange };
+    if (isDefined(balanceChange) && BigInt(balanceChange.amount) === 0n) {
+      return {
+        isValid: false,
+        reason: 'SIMULATION_NO_BALANCE_CHANGE',
+        reasonCode: 'SIMULATION_NO_BALANCE_CHANGE',
+        details: { yieldId: request.yieldId },
+        simulation,
+      };
+    }
+
+    return { ...result, simulation };
+  }
+
+  /**
+   * Fetches the next nonce of address from rpcUrl, counting its pending
+   * transactions, to pass as accountNonce. Besides validateAndSimulate,
+   * resolveEnsName, hasContractCode, isContractPaused,
+   * fetchContractDeployment, fetchStakedBalance, fetchPositionOwners and
+   * fetchLookupTable, this is the only method that makes network calls.
+   * Each of those that reads EVM state takes a block to read it at, as
+   * blockNumber, in place of the latest one; the nonce is then the one
+   * after the transactions included by that block.
+   */
+  fetchAccountNonce(
+    rpcUrl: string,
+    address: string,
+    block?: number,
+  ): Promise<number> {
+    return getTransactionCount(rpcUrl, address, fetch, block);
+  }
+
+  /**
+   * The ENS names validate needs resolved into ensAddresses: the request's
+   * expectedRecipientEns, and the transaction's recipient when that is a
+   * name.
+   */
+  getEnsNames(request: ValidationRequest): string[] {
+    const validator = this.validators.get(request.yieldId);
+    const recipient = isNonEmptyString(request.unsignedTransaction)
+      ? validator?.getRecipientName(request.unsignedTransaction)
+      : undefined;
+    return [request.expectedRecipientEns, recipient].filter(
+      (name, i, all): name is string =>
+        isNonEmptyString(name) && all.indexOf(name) === i,
+    );
+  }
+
+  /**
+   * Looks name up in the ENS registry of rpcUrl's chain, which must be
+   * Ethereum or one of its testnets, for ensAddresses. Resolves to null
+   * when the name has no address.
+   */
+  resolveEnsName(
+    rpcUrl: string,
+    name: string,
+    block?: number,
+  ): Promise<string | null> {
+    return resolveEnsName(rpcUrl, name, fetch, block);
+  }
+
+  /**
+   * The contracts validate needs contractCode for: those the transaction
+   * calls, when it carries calldata, which an account without code ignores.
+   * A recipient still given as an unresolved ENS name is left out.
+   */
+  getCodeAddresses(request: ValidationRequest): string[] {
+    const resolved = this.resolveRecipient(request);
+    const validator = this.validators.get(resolved?.yieldId);
+    const tx = resolved?.unsignedTransaction;
+    if (
+      !validator ||
+      !isNonEmptyString(tx) ||
+      !((validator.getCalldataSize(tx) ?? 0) > 0) ||
+      isDefined(validator.getRecipientName(tx))
+    ) {
+      return [];
+    }
+    return validator.getContractAddresses(tx);
+  }
+
+  /**
+   * Whether address has contract code on rpcUrl's chain, for contractCode.
+   */
+  hasContractCode(
+    rpcUrl: string,
+    address: string,
+    block?: number,
+  ): Promise<boolean> {
+    return hasCode(rpcUrl, address, fetch, block);
+  }
+
+  /**
+   * Whether the contract at address reports paused() on rpcUrl's chain, for
+   * contractPaused.
+   */
+  isContractPaused(
+    rpcUrl: string,
+    address: string,
+    block?: number,
+  ): Promise<boolean> {
+    return isPaused(rpcUrl, address, fetch, block);
+  }
+
+  /**
+   * When the contract at address was deployed on rpcUrl's chain, for
+   * contractDeployments, or null when it has no code. Needs an archive
+   * node for contracts older than the node keeps state.
+   */
+  fetchContractDeployment(
+    rpcUrl: string,
+    address: string,
+    block?: number,
+  ): Promise<ContractDeployment | null> {
+    return getDeployment(rpcUrl, address, fetch, block);
+  }
+
+  /**
+   * The call validate needs the result of as stakedBalance: the balance an
+   * unstake or withdrawal draws on, or undefined for other transactions.
+   */
+  getStakedBalanceCall(
+    request: ValidationRequest,
+  ): StakedBalanceCall | undefined {
+    const validator = this.validators.get(request?.yieldId);
+    return isNonEmptyString(request?.unsignedTransaction)
+      ? validator?.getStakedBalanceCall(request.unsignedTransaction)
+      : undefined;
+  }
+
+  /**
+   * Executes call, from getStakedBalanceCall, on rpcUrl's chain for
+   * stakedBalance.
+   */
+  async fetchStakedBalance(
+    rpcUrl: string,
+    call: StakedBalanceCall,
+    block?: number,
+  ): Promise<string> {
+    return (await callUint256(rpcUrl, call.call, fetch, block)).toString();
+  }
+
+  /**
+   * The positions validate needs the owners of as positionOwners: those a
+   * claim redeems, also across the calls of a multicall, or undefined for
+   * other transactions.
+   */
+  getClaimedPositions(
+    request: ValidationRequest,
+  ): ClaimedPositions | undefined {
+    const validator = this.validators.get(request?.yieldId);
+    if (!validator || !isNonEmptyString(request?.unsignedTransaction)) {
+      return undefined;
+    }
+    return this.toClaimedPositions(
+      this.getPositionClaims(validator, request.unsignedTransaction),
+    );
+  }
+
+  // Each position the transaction claims, with the call of a multicall
+  // that claims it
+  private getPositionClaims(
+    validator: BaseValidator,
+    unsignedTransaction: string,
+  ): (ClaimedPosition & { contract: string })[] {
+    const multicall = validator.getMulticall(unsignedTransaction);
+    const transactions = multicall?.unsignedTransactions ?? [
+      unsignedTransaction,
+    ];
+    return transactions.flatMap((transaction, subCall) => {
+      const positions = validator.getClaimedPositions(transaction);
+      return (positions?.positionIds ?? []).map((positionId) => ({
+        contract: positions!.contract,
+        positionId,
+        ...(isDefined(multicall) && { subCall }),
+      }));
+    });
+  }
+
+  private toClaimedPositions(
+    claims: (ClaimedPosition & { contract: string })[],
+  ): ClaimedPositions | undefined {
+    if (claims.length === 0) return undefined;
+    return {
+      contract: claims[0].contract,
+      positionIds: claims.map((claim) => claim.positionId),
+    };
+  }
+
+  /**
+   * Reads the owner of each of positions, from getClaimedPositions, on
+   * rpcUrl's chain for positionOwners. Positions without one, such as
+   * those already claimed, are left out.
+   */
+  async fetchPositionOwners(
+    rpcUrl: string,
+    positions: ClaimedPositions,
+    block?: number,
+  ): Promise<Record<string, string>> {
+    const owners: Record<string, string> = {};
+    for (const positionId of positions.positionIds) {
+      const owner = await getOwnerOf(
+        rpcUrl,
+        positions.contract,
+        positionId,
+        fetch,
+        block,
+      );
+      if (owner !== null) owners[positionId] = owner;
+    }
+    return owners;
+  }
+
+  /**
+   * The lookup tables validate needs the addresses of as
+   * addressLookupTables: those a versioned Solana transaction loads
+   * accounts from that the request does not give.
+   */
+  getLookupTableAddresses(request: ValidationRequest): string[] {
+    const validator = this.validators.get(request?.yieldId);
+    if (!validator || !isNonEmptyString(request.unsignedTransaction)) {
+      return [];
+    }
+    return validator
+      .getLookupTableAddresses(request.unsignedTransaction)
+      .filter(
+        (table) => !Object.hasOwn(request.addressLookupTables ?? {}, table),
+      );
+  }
+
+  /**
+   * The addresses the lookup table at address holds on rpcUrl's Solana
+   * cluster, in order, for addressLookupTables.
+   */
+  fetchLookupTable(rpcUrl: string, address: string): Promise<string[]> {
+    return getLookupTable(rpcUrl, address);
+  }
+
+  /**
+   * Validates an ordered sequence of transactions for one yield, such as an
+   * approval followed by a deposit. Each step must pass on its own, and
+   * every token pull must be covered by an approval earlier in the flow, to
+   * the same spender, when the flow contains one for that token.
+   */
+  validateFlow(request: FlowValidationRequest): FlowValidationResult {
+    if (isNullOrUndefined(request)) {
+      return {
+        isValid: false,
+        reason: 'Missing validation request',
+        reasonCode: 'INVALID_REQUEST',
+        steps: [],
+      };
+    }
+
+    if (
+      !Array.isArray(request.transactions) ||
+      request.transactions.length === 0
+    ) {
+      return {
+        isValid: false,
+        reason: 'Invalid request parameters',
+        reasonCode: 'INVALID_REQUEST',
+        steps: [],
+      };
+    }
+
+    const { transactions, ...shared } = request;
+    const steps = transactions.map((unsignedTransaction) =>
+      this.validate({ ...shared, unsignedTransaction }),
+    );
+
+    const failed = steps.findIndex((step) => !step.isValid);
+    if (failed !== -1) {
+      return {
+        isValid: false,
+        reason: 'FLOW_STEP_INVALID',
+        reasonCode: 'FLOW_STEP_INVALID',
+        details: { step: failed, reason: steps[failed].reason },
+        steps,
+      };
+    }
+
+    const validator = this.validators.get(request.yieldId);
+    const mismatch = validator
+      ? this.checkFlowAllowances(
+          validator,
+          transactions,
+          steps,
+          request.policy,
+        )
+      : null;
+    return mismatch ? { ...mismatch, steps } : { isValid: true, steps };
+  }
+
+  /**
+   * Validates an ERC-4337 UserOperation. Each call the smart account's
+   * execute or executeBatch callData makes is validated as one step of a
+   * flow sent by userOperation.sender, so a batched approval and deposit is
+   * checked as validateFlow checks them. A paymaster outside
+   * request.paymasters adds an UNKNOWN_PAYMASTER warning.
+   */
+  validateUserOperation(
+    request: UserOperationValidationRequest,
+  ): UserOperationValidationResult {
+    if (isNullOrUndefined(request)) {
+      return {
+        isValid: false,
+        reason: 'Missing validation request',
+        reasonCode: 'INVALID_REQUEST',
+        steps: [],
+      };
+    }
+
+    const validator = this.validators.get(request.yieldId);
+    if (!validator) {
+      return {
+        isValid: false,
+        reason: 'Unknown yield ID',
+        reasonCode: 'YIELD_NOT_FOUND',
+        details: { yieldId: request.yieldId },
+        steps: [],
+      };
+    }
+
+    const { userOperation } = request;
+    const paymaster = getPaymaster(userOperation?.paymasterAndData);
+    if (
+      isNullOrUndefined(userOperation) ||
+      !isNonEmptyString(userOperation.sender) ||
+      !isNonEmptyString(userOperation.callData) ||
+      paymaster === null ||
+      (isDefined(request.userAddress) &&
+        !isNonEmptyString(request.userAddress))
+    ) {
+      return {
+        isValid: false,
+        reason: 'Invalid request parameters',
+        reasonCode: 'INVALID_REQUEST',
+        steps: [],
+      };
+    }
+
+    const { sender } = userOperation;
+    if (
+      isDefined(request.userAddress) &&
+      !validator.isSameAddress(sender, request.userAddress)
+    ) {
+      return {
+        isValid: false,
+        reason: 'SENDER_MISMATCH',
+        reasonCode: 'SENDER_MISMATCH',
+        details: {
+          yieldId: request.yieldId,
+          expected: request.userAddress,
+          actual: sender,
+        },
+        steps: [],
+      };
+    }
+
+    const calls = decodeAccountCalls(userOperation.callData);
+    if (calls === null || calls.length === 0) {
+      return {
+        isValid: false,
+        reason: 'UNSUPPORTED_ACCOUNT_CALL',
+        reasonCode: 'UNSUPPORTED_ACCOUNT_CALL',
+        details: {
+          yieldId: request.yieldId,
+          selector: userOperation.callData.slice(0, 10),
+        },
+        steps: [],
+      };
+    }
+
+    const { chainId } = validator.getCapabilities();
+    const flow = this.validateFlow({
+      yieldId: request.yieldId,
+      transactions: calls.map((call) =>
+        JSON.stringify({ from: sender, ...call, chainId }),
+      ),
+      userAddress: sender,
+      args: request.args,
+      context: request.context,
+      riskThreshold: request.riskThreshold,
+      policy: request.policy,
+      strict: request.strict,
+      strictSeverities: request.strictSeverities,
+    });
+
+    const last = flow.steps[flow.steps.length - 1];
+    const result: UserOperationValidationResult = flow.isValid
+      ? {
+          ...flow,
+          detectedType: last.detectedType,
+          detectedTypes: flow.steps.flatMap((step) =>
+            isDefined(step.detectedType) ? [step.detectedType] : [],
+          ),
+        }
+      : flow;
+    if (!isDefined(paymaster)) return result;
+
+    const known = (request.paymasters ?? []).some((entry) =>
+      validator.isSameAddress(entry, paymaster),
+    );
+    if (known) return { ...result, paymaster };
+
+    return this.applyStrictMode(request, {
+      ...result,
+      paymaster,
+      warnings: [
+        {
+          code: 'UNKNOWN_PAYMASTER',
+          message: `Gas is sponsored by unknown paymaster ${paymaster}`,
+          details: { paymaster },
+        },
+      ],
+    });
+  }
+
+  /**
+   * Validates the calls of an EIP-5792 wallet_sendCalls batch, each as one
+   * step of a flow sent by sendCalls.from on the yield's chain. Unless
+   * atomicRequired is set the wallet may execute the calls one by one, so
+   * an approval followed by the call that spends it adds NON_ATOMIC_BATCH:
+   * a later call failing leaves the allowance in place. Capabilities Shield
+   * does not know add UNKNOWN_CAPABILITY.
+   */
+  validateSendCalls(
+    request: SendCallsValidationRequest,
+  ): SendCallsValidationResult {
+    const invalid = (
+      reasonCode: ReasonCode,
+      reason: string,
+      details?: Record<string, unknown>,
+    ): SendCallsValidationResult => ({
+      isValid: false,
+      reason,
+      reasonCode,
+      ...(details && { details }),
+      steps: [],
+      atomicRequired: request?.sendCalls?.atomicRequired === true,
+    });
+    if (isNullOrUndefined(request)) {
+      return invalid('INVALID_REQUEST', 'Missing validation request');
+    }
+
+    const validator = this.validators.get(request.yieldId);
+    if (!validator) {
+      return invalid('YIELD_NOT_FOUND', 'Unknown yield ID', {
+        yieldId: request.yieldId,
+      });
+    }
+
+    const { sendCalls } = request;
+    const from = sendCalls?.from ?? request.userAddress;
+    if (
+      isNullOrUndefined(sendCalls) ||
+      !Array.isArray(sendCalls.calls) ||
+      sendCalls.calls.length === 0 ||
+      sendCalls.calls.some((call) => !isNonEmptyString(call?.to)) ||
+      !isNonEmptyString(from) ||
+      (isDefined(sendCalls.chainId) &&
+        !/^0x[0-9a-fA-F]+$/.test(sendCalls.chainId)) ||
+      (isDefined(request.userAddress) &&
+        !isNonEmptyString(request.userAddress))
+    ) {
+      return invalid('INVALID_REQUEST', 'Invalid request parameters');
+    }
+
+    if (
+      isDefined(request.userAddress) &&
+      !validator.isSameAddress(from, request.userAddress)
+    ) {
+      return invalid('SENDER_MISMATCH', 'SENDER_MISMATCH', {
+        yieldId: request.yieldId,
+        expected: request.userAddress,
+        actual: from,
+      });
+    }
+
+    const { chainId } = validator.getCapabilities();
+    if (
+      isDefined(sendCalls.chainId) &&
+      BigInt(sendCalls.chainId).toString() !== chainId
+    ) {
+      return invalid('CHAIN_ID_MISMATCH', 'CHAIN_ID_MISMATCH', {
+        yieldId: request.yieldId,
+        expected: chainId,
+        actual: BigInt(sendCalls.chainId).toString(),
+      });
+    }
+
+    const flow = this.validateFlow({
+      yieldId: request.yieldId,
+      transactions: sendCalls.calls.map(({ to, data, value }) =>
+        JSON.stringify({
+          from,
+          to,
+          data: data ?? '0x',
+          value: value ?? '0x0',
+          chainId,
+        }),
+      ),
+      userAddress: from,
+      args: request.args,
+      context: request.context,
+      riskThreshold: request.riskThreshold,
+      policy: request.policy,
+      strict: request.strict,
+      strictSeverities: request.strictSeverities,
+    });
+
+    const atomicRequired = sendCalls.atomicRequired === true;
+    const detectedTypes = flow.steps.flatMap((step) =>
+      isDefined(step.detectedType) ? [step.detectedType] : [],
+    );
+    const warnings: ValidationWarning[] = [
+      ...this.getUnknownCapabilities(sendCalls),
+      ...(atomicRequired || !flow.isValid
+        ? []
+        : this.getNonAtomicApprovals(detectedTypes)),
+    ];
+
+    const result: SendCallsValidationResult = flow.isValid
+      ? {
+          ...flow,
+          detectedType: detectedTypes[detectedTypes.length - 1],
+          detectedTypes,
+          atomicRequired,
+        }
+      : { ...flow, atomicRequired };
+    if (warnings.length === 0) return result;
+    return this.applyStrictMode(request, { ...result, warnings });
+  }
+
+  /**
+   * Checks that a transaction carries out the intent the user declared
+   * before it was built, to catch it being changed before signing. The
+   * transaction is validated for intent.yieldId, then compared with the
+   * intent: its detected type, the exact amount and token it moves, and
+   * that every contract it calls is one of the yield's. A transaction that
+   * fails validation does not match, with its own reason.
+   */
+  compareIntent(request: IntentComparisonRequest): IntentComparisonResult {
+    const { intent, ...shared } = request ?? {};
+    if (
+      isNullOrUndefined(intent) ||
+      !isNonEmptyString(intent.yieldId) ||
+      !Object.values(TransactionType).includes(intent.action) ||
+      (isDefined(intent.amount) && !/^\d+$/.test(intent.amount)) ||
+      (isDefined(intent.token) && !isNonEmptyString(intent.token))
+    ) {
+      const validation: ValidationResult = {
+        isValid: false,
+        reason: 'Invalid request parameters',
+        reasonCode: 'INVALID_REQUEST',
+      };
+      return {
+        match: false,
+        actionMatch: false,
+        amountMatch: false,
+        recipientMatch: false,
+        reason: validation.reason,
+        reasonCode: validation.reasonCode,
+        validation,
+      };
+    }
+
+    const validation = this.validate({ ...shared, yieldId: intent.yieldId });
+    const validator = this.validators.get(intent.yieldId);
+    const tx = shared.unsignedTransaction;
+    const amount =
+      validator && isNonEmptyString(tx) ? validator.getAmount(tx) : undefined;
+
+    const actionMatch = validation.detectedType === intent.action;
+    const amountMatch =
+      (!isDefined(intent.amount) && !isDefined(intent.token)) ||
+      (isDefined(amount) &&
+        (!isDefined(intent.amount) ||
+          BigInt(amount.amount) === BigInt(intent.amount)) &&
+        (!isDefined(intent.token) ||
+          validator?.isSameAddress(amount.token, intent.token) === true));
+    const contracts = validator?.getCapabilities().contracts ?? [];
+    const recipientMatch =
+      isDefined(validator) &&
+      isNonEmptyString(tx) &&
+      validator
+        .getContractAddresses(tx)
+        .every((address) =>
+          contracts.some((contract) =>
+            validator.isSameAddress(contract, address),
+          ),
+        );
+    const comparison = { actionMatch, amountMatch, recipientMatch };
+
+    if (!validation.isValid) {
+      return {
+        match: false,
+        ...comparison,
+        reason: validation.reason,
+        reasonCode: validation.reasonCode,
+        details: validation.details,
+        validation,
+      };
+    }
+
+    const fields = [
+      ...(actionMatch ? [] : ['action']),
+      ...(amountMatch ? [] : ['amount']),
+      ...(recipientMatch ? [] : ['recipient']),
+    ];
+    if (fields.length === 0) return { match: true, ...comparison, validation };
+
+    return {
+      match: false,
+      ...comparison,
+      reason: 'INTENT_MISMATCH',
+      reasonCode: 'INTENT_MISMATCH',
+      details: {
+        yieldId: intent.yieldId,
+        fields,
+        expected: {
+          action: intent.action,
+          amount: intent.amount,
+          token: intent.token,
+        },
+        actual: {
+          action: validation.detectedType,
+          amount: amount?.amount,
+          token: amount?.token,
+        },
+      },
+      validation,
+    };
+  }
+
+  /**
+   * Checks that a transaction of request.detectedType would be allowed for
+   * the yield before it is built, so that a wallet can fail fast: the yield
+   * is known, on request.chainId when given, takes the type and has not
+   * paused it, userAddress is an account of its chain, and amount lies
+   * within the policy's limits. Reports the chain and the contract to build
+   * the transaction for. The transaction itself still needs validating.
+   */
+  preflight(request: PreflightRequest): PreflightResult {
+    const fail = (
+      reasonCode: ReasonCode,
+      reason: string,
+      details?: Record<string, unknown>,
+    ): PreflightResult => ({
+      allowed: false,
+      reason,
+      reasonCode,
+      details,
+      warnings: [],
+    });
+
+    const limits = request?.policy?.amountLimits?.[request?.yieldId];
+    if (
+      isNullOrUndefined(request) ||
+      !isNonEmptyString(request.yieldId) ||
+      !Object.values(TransactionType).includes(request.detectedType) ||
+      (isDefined(request.userAddress) &&
+        !isNonEmptyString(request.userAddress)) ||
+      (isDefined(request.amount) && !/^[0-9]+$/.test(request.amount)) ||
+      (isDefined(request.chainId) && !isNonEmptyString(request.chainId)) ||
+      (isDefined(limits) && !isAmountLimits(limits))
+    ) {
+      return fail('INVALID_REQUEST', 'Invalid request parameters');
+    }
+
+    const { yieldId, detectedType } = request;
+    const validator = this.validators.get(yieldId);
+    if (!validator) {
+      return fail('YIELD_NOT_FOUND', 'Unknown yield ID', { yieldId });
+    }
+
+    const { chainId, network } = validator.getCapabilities();
+    const amount =
+      isDefined(request.amount) && STAKING_TYPES.has(detectedType)
+        ? validator.getStakeAmount(BigInt(request.amount))
+        : undefined;
+    const preflight = (result: PreflightResult): PreflightResult => ({
+      ...result,
+      chainId,
+      expectedRecipient: validator.getTransactionRecipient(detectedType),
+      amount,
+    });
+
+    if (isDefined(request.chainId) && request.chainId !== chainId) {
+      return preflight(
+        fail('CHAIN_ID_MISMATCH', 'CHAIN_ID_MISMATCH', {
+          yieldId,
+          expected: chainId,
+          actual: request.chainId,
+        }),
+      );
+    }
+
+    const supportedTypes = validator.getSupportedTransactionTypes();
+    if (!supportedTypes.includes(detectedType)) {
+      return preflight(
+        fail(
+          'OPERATION_NOT_SUPPORTED_FOR_YIELD',
+          `Yield ${yieldId} does not support ${detectedType} transactions. Supported types: ${supportedTypes.join(', ')}`,
+          { yieldId, supportedTypes },
+        ),
+      );
+    }
+
+    const status = validator.getStatus();
+    const entering = STAKING_TYPES.has(detectedType);
+    if (
+      validator.isPaused(detectedType) ||
+      (status === 'paused' && entering)
+    ) {
+      return preflight(
+        fail('YIELD_PAUSED', `${detectedType} is paused for ${yieldId}`, {
+          yieldId,
+        }),
+      );
+    }
+
+    if (
+      isDefined(request.userAddress) &&
+      !validator.isValidAddress(request.userAddress)
+    ) {
+      return preflight(
+        fail('INVALID_REQUEST', `userAddress is not a ${network} address`, {
+          yieldId,
+          userAddress: request.userAddress,
+        }),
+      );
+    }
+
+    const limited = this.checkAmountLimits(request, { amount });
+    if (isDefined(limited)) {
+      return preflight(
+        fail(limited.reasonCode!, limited.reason!, limited.details),
+      );
+    }
+
+    const warnings: ValidationWarning[] = [];
+    if (status === 'deprecated' && entering) {
+      warnings.push({
+        code: 'YIELD_DEPRECATED',
+        message: `${yieldId} is deprecated and may stop being supported`,
+        details: { yieldId, status },
+      });
+    }
+    if (!isDefined(request.userAddress)) {
+      warnings.push({
+        code: 'SENDER_NOT_VERIFIED',
+        message: 'No userAddress given, so the account is not checked',
+      });
+    }
+    return preflight(withWarningSeverities({ allowed: true, warnings }));
+  }
+
+  /**
+   * Validates an EIP-712 signature request, such as an EIP-2612 permit,
+   * instead of a transaction. A Gnosis Safe SafeTx is validated as the
+   * execTransaction it confirms, with userAddress the Safe, and reports
+   * its safeTxHash.
+   */
+  validateTypedData(request: TypedDataValidationRequest): ValidationResult {
+    if (isNullOrUndefined(request)) {
+      return {
+        isValid: false,
+        reason: 'Missing validation request',
+        reasonCode: 'INVALID_REQUEST',
+      };
+    }
+
+    const validator = this.validators.get(request.yieldId);
+    if (!validator) {
+      return {
+        isValid: false,
+        reason: 'Unknown yield ID',
+        reasonCode: 'YIELD_NOT_FOUND',
+        details: { yieldId: request.yieldId },
+      };
+    }
+
+    if (
+      isNullOrUndefined(request.typedData) ||
+      !isNonEmptyString(request.userAddress)
+    ) {
+      return {
+        isValid: false,
+        reason: 'Invalid request parameters',
+        reasonCode: 'INVALID_REQUEST',
+      };
+    }
+
+    try {
+      const safeTransaction = validator.getSafeTransaction(request.typedData);
+      if (isDefined(safeTransaction)) {
+        return {
+          ...this.validate({
+            yieldId: request.yieldId,
+            unsignedTransaction: safeTransaction.unsignedTransaction,
+            userAddress: request.userAddress,
+            policy: request.policy,
+            strict: request.strict,
+            strictSeverities: request.strictSeverities,
+          }),
+          safeTxHash: safeTransaction.safeTxHash,
+        };
+      }
+
+      const result = validator.validateTypedData(
+        request.typedData,
+        request.userAddress,
+      );
+      return this.applyStrictMode(
+        request,
+        result.isValid
+          ? this.checkDeadline(result, request.policy)
+          : {
+              ...result,
+              reasonCode: result.reasonCode ?? 'TYPED_DATA_INVALID',
+            },
+      );
+    } catch (error) {
+      return {
+        isValid: false,
+        reason: error instanceof Error ? error.message : String(error),
+        reasonCode: 'TYPED_DATA_INVALID',
+      };
+    }
+  }
+
+  /**
+   * Lists the yields whose rules a transaction matches, each with the type
+   * it matches as, for transactions whose yield is not known yet. With
+   * chainId, only yields on that chain are tried. As in decode, the
+   * transaction's own sender stands in for the user.
+   */
+  detectYields(unsignedTransaction: string, chainId?: string): YieldMatch[] {
+    if (!isNonEmptyString(unsignedTransaction)) return [];
+
+    const matches: YieldMatch[] = [];
+    for (const { yieldId } of this.getSupportedYields(chainId)) {
+      try {
+        const result = this.matchAsSigner(
+          yieldId,
+          this.validators.get(yieldId)!,
+          unsignedTransaction,
+        );
+        if (result?.isValid && isDefined(result.detectedType)) {
+          matches.push({ yieldId, detectedType: result.detectedType });
+        }
+      } catch {
+        // A yield that cannot read the transaction does not match it
+      }
+    }
+    return matches;
+  }
+
+  /**
+   * The request as validate reads it, for debugging one that validates
+   * otherwise than expected: the transaction as its validator parsed it,
+   * with quantities as decimal strings, and the defaults of the fields
+   * left out filled in. Requests for unknown yields, and fields Shield
+   * does not read, are returned as they are.
+   */
+  normalizeRequest<T extends Partial<ValidationRequest>>(request: T): T {
+    const validator = isNonEmptyString(request.yieldId)
+      ? this.validators.get(request.yieldId)
+      : undefined;
+    const { unsignedTransaction, userAddress } = request;
+    if (!validator || !isNonEmptyString(unsignedTransaction)) return request;
+
+    const isBatch =
+      isDefined(validator.getWrappedTransaction(unsignedTransaction)) ||
+      isDefined(validator.getMulticall(unsignedTransaction));
+    const hasCalldata = isDefined(
+      validator.getCalldataSize(unsignedTransaction),
+    );
+    return {
+      ...request,
+      unsignedTransaction:
+        validator.getNormalizedTransaction(unsignedTransaction) ??
+        unsignedTransaction,
+      ...(isNonEmptyString(userAddress) && {
+        beneficiaryAddress: request.beneficiaryAddress ?? userAddress,
+      }),
+      strict: request.strict ?? false,
+      observe: request.observe ?? false,
+      ...(isDefined(request.expectedAmount) && {
+        amountToleranceBps: request.amountToleranceBps ?? 0,
+        amountTolerance: request.amountTolerance ?? '0',
+      }),
+      policy: {
+        ...(hasCalldata && {
+          maxCalldataBytes: isBatch
+            ? DEFAULT_MAX_BATCH_CALLDATA_BYTES
+            : DEFAULT_MAX_CALLDATA_BYTES,
+        }),
+        maxDeadlineSeconds: DEFAULT_MAX_DEADLINE_SECONDS,
+        maxSlippageBps: DEFAULT_MAX_SLIPPAGE_BPS,
+        ...request.policy,
+      },
+    };
+  }
+
+  /**
+   * Describes what a transaction does without validating it. Never throws:
+   * when nothing can be decoded, decoded is null and reason explains why.
+   */
+  decode(request: DecodeRequest): DecodeResult {
+    if (
+      isNullOrUndefined(request) ||
+      !isNonEmptyString(request.unsignedTransaction)
+    ) {
+      return { decoded: null, reason: 'Invalid request parameters' };
+    }
+
+    try {
+      if (isDefined(request.yieldId)) {
+        const result = this.decodeForYield(
+          request.yieldId,
+          request.unsignedTransaction,
+        );
+        const validator = this.validators.get(request.yieldId);
+        return request.includeSignature && validator
+          ? this.withUnknownSignature(validator, request, result)
+          : result;
+      }
+
+      // Validators of the same class share their ABIs, so try each once
+      const tried = new Set<unknown>();
+      for (const validator of this.validators.values()) {
+        if (tried.has(validator.constructor)) continue;
+        tried.add(validator.constructor);
+
+        const result = validator.decode(request.unsignedTransaction);
+        if (result.decoded) return result;
+      }
+
+      const result: DecodeResult = {
+        decoded: null,
+        reason: 'No known ABI matches the transaction data',
+      };
+      const evm = [...this.validators.values()].find(
+        (validator) => validator instanceof BaseEVMValidator,
+      );
+      return request.includeSignature && evm
+        ? this.withUnknownSignature(evm, request, result)
+        : result;
+    } catch (error) {
+      return {
+        decoded: null,
+        reason: error instanceof Error ? error.message : String(error),
+      };
+    }
+  }
+
+  // A call no ABI decodes, described by its selector alone. The reason is
+  // kept, as the call is still not decoded
+  private withUnknownSignature(
+    validator: BaseValidator,
+    request: DecodeRequest,
+    result: DecodeResult,
+  ): DecodeResult {
+    const signature = validator.getFunctionSignature(
+      request.unsignedTransaction,
+    );
+    return result.decoded === null && isDefined(signature)
+      ? { ...result, decoded: signature }
+      : result;
+  }
+
+  private decodeForYield(
+    yieldId: string,
+    unsignedTransaction: string,
+  ): DecodeResult {
+    const validator = this.validators.get(yieldId);
+    if (!validator) {
+      return { decoded: null, reason: 'Unknown yield ID' };
+    }
+
+    const result = validator.decode(unsignedTransaction);
+    if (!result.decoded) return result;
+
+    const detectedType = this.matchAsSigner(
+      yieldId,
+      validator,
+      unsignedTransaction,
+    )?.detectedType;
+    return isDefined(detectedType)
+      ? { decoded: { ...result.decoded, detectedType } }
+      : result;
+  }
+
+  // The transaction's own signer stands in for the user, so the detected
+  // type reflects the transaction rather than who is asking. A Safe, not
+  // the owner executing it, sends the call it wraps, and a forward
+  // request's from, not the relayer, the call it relays
+  private matchAsSigner(
+    yieldId: string,
+    validator: BaseValidator,
+    unsignedTransaction: string,
+  ): ValidationResult | undefined {
+    const wrapped = validator.getWrappedTransaction(unsignedTransaction);
+    const signer = validator.getSigner(
+      wrapped?.unsignedTransaction ?? unsignedTransaction,
+    );
+    if (!isNonEmptyString(signer)) return undefined;
+
+    return this.matchTransaction({
+      yieldId,
+      unsignedTransaction,
+      userAddress: signer,
+    });
+  }
+
+  /**
+   * Matches a transaction sent to the address contractOverrides names for
+   * its yield as if it were sent to the contract the registry lists first,
+   * the yield's own, and reports the override as its expectedRecipient.
+   * Only the match sees the registered contract: the policy, bytecode and
+   * code checks after it see the address the transaction is sent to.
+   */
+  private matchOverriddenContract(
+    request: ValidationRequest,
+  ): ValidationResult {
+    const address = request?.contractOverrides?.[request.yieldId];
+    const validator = this.validators.get(request?.yieldId);
+    if (
+      !isNonEmptyString(address) ||
+      !validator ||
+      !isNonEmptyString(request.unsignedTransaction)
+    ) {
+      return this.matchTransaction(request);
+    }
+
+    const [recipient] = validator.getContractAddresses(
+      request.unsignedTransaction,
+    );
+    const [registered] = validator.getCapabilities().contracts;
+    if (
+      !isDefined(recipient) ||
+      !isDefined(registered) ||
+      !validator.isSameAddress(recipient, address)
+    ) {
+      return this.matchTransaction(request);
+    }
+
+    const result = this.matchTransaction({
+      ...request,
+      unsignedTransaction: validator.withRecipientAddress(
+        request.unsignedTransaction,
+        registered,
+      ),
+    });
+    return {
+      ...result,
+      ...(isDefined(result.expectedRecipient) && {
+        expectedRecipient: recipient,
+      }),
+      contractOverride: { registered, address: recipient },
+    };
+  }
+
+  private matchTransaction(request: ValidationRequest): ValidationResult {
+    if (isNullOrUndefined(request)) {
+      return {
+        isValid: false,
+        reason: 'Missing validation request',
+        reasonCode: 'INVALID_REQUEST',
+      };
+    }
+
+    const validator = this.validators.get(request.yieldId);
+
+    if (!validator) {
+      return {
+        isValid: false,
+        reason: 'Unknown yield ID',
+        reasonCode: 'YIELD_NOT_FOUND',
+        details: { yieldId: request.yieldId },
+        ...(request.lenientUnknownYield && {
+          warnings: [
+            {
+              code: 'UNKNOWN_YIELD',
+              message: `${request.yieldId} is not a yield this version of Shield knows`,
+              details: { yieldId: request.yieldId },
+            },
+          ],
+        }),
+      };
+    }
+
+    const limits = request.policy?.amountLimits?.[request.yieldId];
+    if (
+      !isNonEmptyString(request.unsignedTransaction) ||
+      (isDefined(limits) && !isAmountLimits(limits)) ||
+      (isDefined(request.userAddress) &&
+        !isNonEmptyString(request.userAddress)) ||
+      (isDefined(request.expectedAmount) &&
+        !/^[0-9]+$/.test(request.expectedAmount)) ||
+      (isDefined(request.amountToleranceBps) &&
+        !isBasisPoints(request.amountToleranceBps)) ||
+      (isDefined(request.amountTolerance) &&
+        !/^[0-9]+$/.test(request.amountTolerance)) ||
+      (isDefined(request.expectedNonce) && !isNonce(request.expectedNonce)) ||
+      (isDefined(request.beneficiaryAddress) &&
+        !isNonEmptyString(request.beneficiaryAddress)) ||
+      (isDefined(request.stakedBalance) &&
+        !/^[0-9]+$/.test(request.stakedBalance)) ||
+      (isDefined(request.actualBytecodeHash) &&
+        !/^0x[0-9a-fA-F]{64}$/.test(request.actualBytecodeHash)) ||
+      (isDefined(request.accountNonce) && !isNonce(request.accountNonce)) ||
+      (isDefined(request.blockNumber) && !isNonce(request.blockNumber)) ||
+      (isDefined(request.addressLookupTables) &&
+        !isLookupTables(request.addressLookupTables))
+    ) {
+      return {
+        isValid: false,
+        reason: 'Invalid request parameters',
+        reasonCode: 'INVALID_REQUEST',
+      };
+    }
+
+    // A transaction for another network must never match this yield, even
+    // if the same contract is deployed there
+    const chainId = validator.getChainId(request.unsignedTransaction);
+    if (
+      isDefined(chainId) &&
+      chainId !== validator.getCapabilities().chainId
+    ) {
+      return {
+        isValid: false,
+        reason: 'CHAIN_ID_MISMATCH',
+        reasonCode: 'CHAIN_ID_MISMATCH',
+        details: {
+          yieldId: request.yieldId,
+          expected: validator.getCapabilities().chainId,
+          actual: chainId,
+        },
+      };
+    }
+
+    // Accounts loaded from a lookup table are only indexes into it, so a
+    // transaction is never validated without the accounts they stand for
+    const lookupTables = validator.getLookupTableAddresses(
+      request.unsignedTransaction,
+    );
+    if (lookupTables.length > 0) {
+      return {
+        isValid: false,
+        reason: 'ALT_RESOLUTION_REQUIRED',
+        reasonCode: 'ALT_RESOLUTION_REQUIRED',
+        details: {
+          yieldId: request.yieldId,
+          lookupTables,
+          missing: lookupTables.filter(
+            (table) => !Object.hasOwn(request.addressLookupTables ?? {}, table),
+          ),
+        },
+      };
+    }
+
+    const numericError = validator.getNumericError(
+      request.unsignedTransaction,
+    );
+    if (isDefined(numericError)) {
+      return {
+        isValid: false,
+        reason: 'MALFORMED_NUMERIC',
+        reasonCode: 'MALFORMED_NUMERIC',
+        details: {
+          yieldId: request.yieldId,
+          field: numericError.field,
+          actual: numericError.value,
+        },
+      };
+    }
+
+    const malformed = validator.getMalformedError(request.unsignedTransaction);
+    if (isDefined(malformed)) {
+      return {
+        isValid: false,
+        reason: 'MALFORMED_TRANSACTION',
+        reasonCode: 'MALFORMED_TRANSACTION',
+        details: { yieldId: request.yieldId, error: malformed },
+      };
+    }
+
+    const gasError = validator.getGasError(request.unsignedTransaction);
+    if (isDefined(gasError)) {
+      return {
+        isValid: false,
+        reason: 'INVALID_GAS_FIELDS',
+        reasonCode: 'INVALID_GAS_FIELDS',
+        details: { yieldId: request.yieldId, error: gasError },
+      };
+    }
+
+    const calldataError = this.checkCalldataSize(request, validator);
+    if (isDefined(calldataError)) return calldataError;
+
+    // A Safe transaction is validated by the call it executes, which the
+    // Safe itself sends, and a forwarded call by the call it relays
+    const wrapped = validator.getWrappedTransaction(
+      request.unsignedTransaction,
+    );
+    if (isDefined(wrapped)) {
+      return this.matchWrappedTransaction(request, validator, wrapped);
+    }
+
+    const sender = validator.getSigner(request.unsignedTransaction);
+    const verified = isNonEmptyString(request.userAddress);
+
+    if (
+      verified &&
+      isNonEmptyString(sender) &&
+      !validator.isSameAddress(sender, request.userAddress)
+    ) {
+      return {
+        isValid: false,
+        reason: 'SENDER_MISMATCH',
+        reasonCode: 'SENDER_MISMATCH',
+        details: {
+          yieldId: request.yieldId,
+          expected: request.userAddress,
+          actual: sender,
+        },
+      };
+    }
+
+    const userAddress = verified ? request.userAddress : sender;
+    if (!isNonEmptyString(userAddress)) {
+      return {
+        isValid: false,
+        reason: 'Invalid request parameters',
+        reasonCode: 'INVALID_REQUEST',
+      };
+    }
+
+    // A multicall is validated by the calls it batches, each as if it were
+    // a transaction of its own
+    const multicall = validator.getMulticall(request.unsignedTransaction);
+    if (isDefined(multicall)) {
+      const matched = this.matchMulticall(
+        { ...request, userAddress },
+        validator,
+        multicall,
+      );
+      return verified || !matched.isValid
+        ? matched
+        : this.withSenderNotVerified(matched, sender);
+    }
+
+    // New code for a proxy changes what every later call to it does, so
+    // only a yield whose ABI has the upgrade function may make one
+    const implementationChange = validator.getImplementationChange(
+      request.unsignedTransaction,
+    );
+    if (
+      isDefined(implementationChange) &&
+      !this.isExpectedFunction(validator, request.unsignedTransaction)
+    ) {
+      return {
+        isValid: false,
+        reason: 'UNEXPECTED_PROXY_UPGRADE',
+        reasonCode: 'UNEXPECTED_PROXY_UPGRADE',
+        details: { yieldId: request.yieldId, ...implementationChange },
+      };
+    }
+
+    // Pausing a contract or handing over its ownership is its owner's
+    // business, not a staker's
+    const privilegedCall = validator.getPrivilegedCall(
+      request.unsignedTransaction,
+    );
+    if (
+      isDefined(privilegedCall) &&
+      !this.isExpectedFunction(validator, request.unsignedTransaction)
+    ) {
+      return {
+        isValid: false,
+        reason: `Calls ${privilegedCall.functionName}, which only an owner or admin of ${privilegedCall.contract} may call`,
+        reasonCode: 'PRIVILEGED_FUNCTION_CALL',
+        details: { yieldId: request.yieldId, ...privilegedCall },
+      };
+    }
+
+    const supportedTypes = validator.getSupportedTransactionTypes();
+    const approval = validator.getApproval(request.unsignedTransaction);
+
+    // An allowance for the yield's own contracts must be of a token it
+    // takes, not a lookalike of one
+    if (
+      isDefined(approval) &&
+      supportedTypes.includes(TransactionType.APPROVAL) &&
+      [
+        ...validator.getCapabilities().contracts,
+        ...validator.getExpectedSpenders(request.unsignedTransaction),
+      ].some((spender) => validator.isSameAddress(spender, approval.spender)) &&
+      !this.isYieldToken(validator, approval.token)
+    ) {
+      return {
+        isValid: false,
+        reason: 'TOKEN_MISMATCH',
+        reasonCode: 'TOKEN_MISMATCH',
+        details: {
+          yieldId: request.yieldId,
+          expected: validator.getStakeTokens(),
+          actual: approval.token,
+        },
+      };
+    }
+
+    if (
+      isDefined(approval) &&
+      supportedTypes.includes(TransactionType.APPROVAL) &&
+      !validator
+        .getExpectedSpenders(request.unsignedTransaction)
+        .some((spender) => validator.isSameAddress(spender, approval.spender))
+    ) {
+      return {
+        isValid: false,
+        reason: 'APPROVAL_SPENDER_MISMATCH',
+        reasonCode: 'APPROVAL_SPENDER_MISMATCH',
+        details: { yieldId: request.yieldId, actual: approval.spender },
+      };
+    }
+
+    // A claim must pay the user, whichever type it goes on to match. One
+    // that names no recipient pays its sender, already checked to be the
+    // user
+    const claim = validator.getClaimCall(request.unsignedTransaction);
+    if (
+      isNonEmptyString(claim?.recipient) &&
+      !validator.isSameAddress(claim.recipient, userAddress)
+    ) {
+      return {
+        isValid: false,
+        reason: 'REWARD_RECIPIENT_MISMATCH',
+        reasonCode: 'REWARD_RECIPIENT_MISMATCH',
+        details: {
+          yieldId: request.yieldId,
+          expected: userAddress,
+          actual: claim.recipient,
+          variant: claim.variant,
+        },
+      };
+    }
+
+    // An unstake or withdrawal must pay the user as well
+    const withdrawal = validator.getWithdrawal(request.unsignedTransaction);
+    if (
+      isDefined(withdrawal) &&
+      !validator.isSameAddress(withdrawal.recipient, userAddress)
+    ) {
+      return {
+        isValid: false,
+        reason: 'WITHDRAWAL_RECIPIENT_MISMATCH',
+        reasonCode: 'WITHDRAWAL_RECIPIENT_MISMATCH',
+        details: {
+          yieldId: request.yieldId,
+          expected: userAddress,
+          actual: withdrawal.recipient,
+        },
+      };
+    }
+
+    // A deposit on behalf of another account must credit that account, and
+    // any other deposit the user
+    const beneficiary = validator.getBeneficiary(request.unsignedTransaction);
+    const expectedBeneficiary = request.beneficiaryAddress ?? userAddress;
+    if (
+      isDefined(beneficiary) &&
+      !validator.isSameAddress(beneficiary, expectedBeneficiary)
+    ) {
+      return {
+        isValid: false,
+        reason: 'BENEFICIARY_MISMATCH',
+        reasonCode: 'BENEFICIARY_MISMATCH',
+        details: {
+          yieldId: request.yieldId,
+          expected: expectedBeneficiary,
+          actual: beneficiary,
+        },
+      };
+    }
+
+    // Value sent with a token call is lost, whichever type it matches. A
+    // function's payability comes from its ABI, so this needs no node
+    const native = validator.getNativeValue(request.unsignedTransaction);
+    if (isDefined(native) && !native.payable && native.value > 0n) {
+      return {
+        isValid: false,
+        reason: 'UNEXPECTED_NATIVE_VALUE',
+        reasonCode: 'UNEXPECTED_NATIVE_VALUE',
+        details: {
+          yieldId: request.yieldId,
+          value: native.value.toString(),
+          functionSignature: native.signature,
+        },
+      };
+    }
+
+    // Without calldata a transaction calls no function, so only a contract
+    // whose receive function stakes what it is sent can take it
+    if (validator.getCalldataSize(request.unsignedTransaction) === 0) {
+      const transfer = this.matchTransfer(
+        request,
+        validator,
+        userAddress,
+        expectedBeneficiary,
+      );
+      return verified || !transfer.isValid
+        ? transfer
+        : this.withSenderNotVerified(transfer, sender);
+    }
+
+    const attempts: Array<{
+      type: TransactionType;
+      result: ValidationResult;
+    }> = [];
+    const matches: Array<{ type: TransactionType; result: ValidationResult }> =
+      [];
+
+    for (const transactionType of supportedTypes) {
+      try {
+        const result = validator.validate(
+          request.unsignedTransaction,
+          transactionType,
+          userAddress,
+          request.args,
+          request.context,
+        );
+
+        attempts.push({ type: transactionType, result });
+
+        if (result.isValid) {
+          matches.push({ type: transactionType, result });
+        }
+      } catch (error) {
+        attempts.push({
+          type: transactionType,
+          result: {
+            isValid: false,
+            reason: error instanceof Error ? error.message : String(error),
+          },
+        });
+      }
+    }
+
+    if (matches.length === 1) {
+      const selectorError = this.checkSelector(
+        request,
+        validator,
+        matches[0].type,
+      );
+      if (isDefined(selectorError)) return selectorError;
+
+      // A call that names no beneficiary credits its sender
+      if (
+        !isDefined(beneficiary) &&
+        CREDITING_TYPES.has(matches[0].type) &&
+        !validator.isSameAddress(expectedBeneficiary, userAddress)
+      ) {
+        return {
+          isValid: false,
+          reason: 'BENEFICIARY_MISMATCH',
+          reasonCode: 'BENEFICIARY_MISMATCH',
+          details: {
+            yieldId: request.yieldId,
+            expected: expectedBeneficiary,
+            actual: userAddress,
+          },
+        };
+      }
+
+      let matched: ValidationResult = this.withExpectedRecipients(
+        {
+          ...matches[0].result,
+          detectedType: matches[0].type,
+          detectedTypes: [matches[0].type],
+        },
+        validator.getContractAddresses(request.unsignedTransaction),
+      );
+      if (isDefined(approval)) {
+        matched = this.withApproval(matched, approval);
+      }
+      if (isDefined(implementationChange)) {
+        matched = this.withImplementationChange(matched, implementationChange);
+      }
+      matched = this.withAccessListCheck(
+        matched,
+        validator,
+        request.unsignedTransaction,
+      );
+      matched = this.withGasLimitCheck(
+        matched,
+        validator,
+        request.unsignedTransaction,
+        matches[0].type,
+      );
+      matched = this.withSlippageCheck(matched, validator, request);
+
+      if (isDefined(claim)) {
+        matched = this.withClaimRecipient(matched, claim, userAddress);
+      }
+      if (isDefined(withdrawal)) {
+        matched = { ...matched, decoded: { ...matched.decoded, withdrawal } };
+      }
+      if (isDefined(native)) {
+        matched = {
+          ...matched,
+          decoded: { ...matched.decoded, value: native.value.toString() },
+        };
+      }
+      const calldataBytes = validator.getCalldataSize(
+        request.unsignedTransaction,
+      );
+      if (isDefined(calldataBytes)) {
+        matched = {
+          ...matched,
+          decoded: { ...matched.decoded, calldataBytes },
+        };
+      }
+      const decodedArgs = validator.getDecodedArgs(request.unsignedTransaction);
+      if (isDefined(decodedArgs)) {
+        matched = {
+          ...matched,
+          decoded: { ...matched.decoded, decodedArgs },
+        };
+      }
+
+      const amount = validator.getAmount(request.unsignedTransaction);
+      const stakeTokens = validator.getStakeTokens();
+      if (
+        isDefined(amount) &&
+        STAKING_TYPES.has(matches[0].type) &&
+        stakeTokens.length > 0 &&
+        !stakeTokens.some((token) =>
+          validator.isSameAddress(token, amount.token),
+        )
+      ) {
+        return {
+          isValid: false,
+          reason: 'TOKEN_MISMATCH',
+          reasonCode: 'TOKEN_MISMATCH',
+          details: {
+            yieldId: request.yieldId,
+            expected: stakeTokens,
+            actual: amount.token,
+            symbol: amount.symbol,
+          },
+          amount,
+        };
+      }
+      const amountMismatch = this.checkAmount(request, validator, amount);
+      if (isDefined(amountMismatch)) return amountMismatch;
+      if (isDefined(amount)) matched = { ...matched, amount };
+
+      return verified ? matched : this.withSenderNotVerified(matched, sender);
+    }
+
+    if (matches.length > 1) {
+      return {
+        isValid: false,
+        reason:
+          'Transaction validation failed: Ambiguous transaction pattern detected. Transaction matches multiple operation types, which may indicate a security risk.',
+        reasonCode: 'AMBIGUOUS_PATTERN',
+        details: {
+          yieldId: request.yieldId,
+          matchedTypes: matches.map((m) => m.type),
+          warning:
+            'A legitimate transaction must match exactly one pattern. Multiple matches indicate potential manipulation.',
+        },
+      };
+    }
+
+    // A call that only other yields make is an action this yield cannot
+    // take, rather than a corrupted transaction
+    const impliedTypes = getImpliedTypes(
+      validator.getSelector(request.unsignedTransaction),
+    );
+    if (
+      impliedTypes.length > 0 &&
+      !impliedTypes.some((type) => supportedTypes.includes(type))
+    ) {
+      return {
+        isValid: false,
+        reason: `Yield ${request.yieldId} does not support ${impliedTypes.join(' or ')} transactions. Supported types: ${supportedTypes.join(', ')}`,
+        reasonCode: 'OPERATION_NOT_SUPPORTED_FOR_YIELD',
+        details: { yieldId: request.yieldId, impliedTypes, supportedTypes },
+      };
+    }
+
+    return {
+      isValid: false,
+      reason:
+        'Transaction validation failed: No matching operation pattern found. This transaction may be malicious or corrupted.',
+      reasonCode:
+        validator.getMismatchCode(request.unsignedTransaction, request.args) ??
+        'NO_MATCHING_PATTERN',
+      details: {
+        yieldId: request.yieldId,
+        supportedTypes,
+        warning:
+          'A legitimate transaction should match exactly one supported pattern',
+        attempts: attempts.map((a) => ({
+          type: a.type,
+          reason: a.result.reason,
         })),
       },
     };
   }
+
+  // A token the yield stakes or one of its own contracts, such as a vault's
+  // shares. Any token is, for yields that do not list what they stake
+  private isYieldToken(validator: BaseValidator, token: string): boolean {
+    const stakeTokens = validator.getStakeTokens();
+    return (
+      stakeTokens.length === 0 ||
+      [...stakeTokens, ...validator.getCapabilities().contracts].some(
+        (other) => validator.isSameAddress(other, token),
+      )
+    );
+  }
+
+  // A plain transfer credits its sender with what it sends
+  private matchTransfer(
+    request: ValidationRequest,
+    validator: BaseValidator,
+    userAddress: string,
+    expectedBeneficiary: string,
+  ): ValidationResult {
+    const [to] = validator.getContractAddresses(request.unsignedTransaction);
+    const amount = validator.getAmount(request.unsignedTransaction);
+    if (
+      !isDefined(to) ||
+      !isDefined(amount) ||
+      !validator
+        .getTransferRecipients()
+        .some((recipient) => validator.isSameAddress(recipient, to))
+    ) {
+      return {
+        isValid: false,
+        reason: 'NAKED_TRANSFER_NOT_SUPPORTED',
+        reasonCode: 'NAKED_TRANSFER_NOT_SUPPORTED',
+        details: { yieldId: request.yieldId, actual: to },
+        emptyCalldata: true,
+      };
+    }
+
+    if (!validator.isSameAddress(expectedBeneficiary, userAddress)) {
+      return {
+        isValid: false,
+        reason: 'BENEFICIARY_MISMATCH',
+        reasonCode: 'BENEFICIARY_MISMATCH',
+        details: {
+          yieldId: request.yieldId,
+          expected: expectedBeneficiary,
+          actual: userAddress,
+        },
+      };
+    }
+
+    const amountMismatch = this.checkAmount(request, validator, amount);
+    if (isDefined(amountMismatch)) return amountMismatch;
+
+    return this.withExpectedRecipients(
+      {
+        isValid: true,
+        detectedType: TransactionType.TRANSFER,
+        detectedTypes: [TransactionType.TRANSFER],
+        decoded: { value: amount.amount, calldataBytes: 0 },
+        amount,
+        emptyCalldata: true,
+      },
+      [to],
+    );
+  }
+
+  private matchWrappedTransaction(
+    request: ValidationRequest,
+    validator: BaseValidator,
+    wrapped: WrappedTransaction,
+  ): ValidationResult {
+    const nested = validator.getWrappedTransaction(wrapped.unsignedTransaction);
+    if (isDefined(nested)) {
+      return {
+        isValid: false,
+        reason: 'Nested multisig transactions are not supported',
+        reasonCode: 'NESTED_MULTISIG',
+        details: { yieldId: request.yieldId },
+      };
+    }
+
+    // A contract only takes the appended sender from a forwarder it trusts,
+    // and would otherwise credit the forwarder itself
+    const { wrapper } = wrapped;
+    if (
+      wrapper.detectedType === 'ERC2771_FORWARD' &&
+      !(request.policy?.trustedForwarders ?? []).some((forwarder) =>
+        validator.isSameAddress(forwarder, wrapper.address),
+      )
+    ) {
+      return {
+        isValid: false,
+        reason: `Forwarder ${wrapper.address} is not in policy.trustedForwarders`,
+        reasonCode: 'UNTRUSTED_FORWARDER',
+        details: { yieldId: request.yieldId, forwarder: wrapper.address },
+      };
+    }
+    // The forwarder reverts on a request its from did not sign, and the
+    // relayed call's sender is only checked to be the user after this
+    const from = validator.getSigner(wrapped.unsignedTransaction);
+    if (
+      wrapper.detectedType === 'ERC2771_FORWARD' &&
+      !(
+        isNonEmptyString(wrapper.signer) &&
+        isNonEmptyString(from) &&
+        validator.isSameAddress(wrapper.signer, from)
+      )
+    ) {
+      return {
+        isValid: false,
+        reason: 'FORWARD_SIGNATURE_MISMATCH',
+        reasonCode: 'FORWARD_SIGNATURE_MISMATCH',
+        details: {
+          yieldId: request.yieldId,
+          forwarder: wrapper.address,
+          expected: from,
+          actual: wrapper.signer,
+        },
+      };
+    }
+
+    const inner = this.matchTransaction({
+      ...request,
+      unsignedTransaction: wrapped.unsignedTransaction,
+    });
+    if (wrapper.operation !== 'DELEGATECALL') return { ...inner, wrapper };
+
+    const [target] = validator.getContractAddresses(
+      wrapped.unsignedTransaction,
+    );
+    // The inner contract's code would run against the Safe's own storage
+    // and funds, so a matching pattern says little about its effect
+    return {
+      ...inner,
+      wrapper,
+      warnings: [
+        ...(inner.warnings ?? []),
+        {
+          code: 'DELEGATECALL_USED',
+          message: `Safe ${wrapper.address} executes the inner call as a DelegateCall`,
+          details: { safe: wrapper.address, target },
+        },
+      ],
+    };
+  }
+
+  /**
+   * Compares the amount the transaction moves with request.expectedAmount,
+   * allowing amountToleranceBps of it or amountTolerance base units either
+   * way, whichever is larger. A transaction whose amount cannot be decoded
+   * never matches.
+   */
+  private checkAmount(
+    request: ValidationRequest,
+    validator: BaseValidator,
+    amount: TransactionAmount | undefined,
+  ): ValidationResult | undefined {
+    if (!isDefined(request.expectedAmount)) return undefined;
+
+    const expected = BigInt(request.expectedAmount);
+    const bpsTolerance =
+      (expected * BigInt(request.amountToleranceBps ?? 0)) / 10000n;
+    const absoluteTolerance = BigInt(request.amountTolerance ?? 0);
+    const tolerance =
+      bpsTolerance > absoluteTolerance ? bpsTolerance : absoluteTolerance;
+    const difference = isDefined(amount)
+      ? BigInt(amount.amount) - expected
+      : undefined;
+    const tokenMatches =
+      !isDefined(amount) ||
+      !isDefined(request.expectedAmountToken) ||
+      validator.isSameAddress(amount.token, request.expectedAmountToken);
+
+    if (
+      isDefined(difference) &&
+      tokenMatches &&
+      difference <= tolerance &&
+      -difference <= tolerance
+    ) {
+      return undefined;
+    }
+
+    return {
+      isValid: false,
+      reason: 'AMOUNT_MISMATCH',
+      reasonCode: 'AMOUNT_MISMATCH',
+      details: {
+        yieldId: request.yieldId,
+        expected: request.expectedAmount,
+        actual: amount?.amount,
+        error: tokenMatches
+          ? undefined
+          : `Transaction moves ${amount?.token}, not ${request.expectedAmountToken}`,
+      },
+      amount,
+    };
+  }
+
+  /**
+   * Rates the warnings of result by severity, and rejects a valid result
+   * that carries any of the severities strict mode escalates when the
+   * caller asked for it, for integrations with no user to confirm a
+   * warning.
+   */
+  private applyStrictMode<T extends ValidationResult | FlowValidationResult>(
+    request: {
+      yieldId: string;
+      strict?: boolean;
+      strictSeverities?: WarningSeverity[];
+    },
+    unrated: T & { warnings?: ValidationWarning[] },
+  ): T {
+    const result = withWarningSeverities(unrated);
+    const escalated = (result.warnings ?? []).filter(
+      ({ severity }) =>
+        !isDefined(request.strictSeverities) ||
+        request.strictSeverities.includes(severity!),
+    );
+    if (!request.strict || !result.isValid || escalated.length === 0) {
+      return result;
+    }
+
+    const details = {
+      yieldId: request.yieldId,
+      warningCodes: escalated.map((warning) => warning.code),
+    };
+    return {
+      ...result,
+      isValid: false,
+      reason: 'STRICT_MODE_WARNING',
+      reasonCode: 'STRICT_MODE_WARNING',
+      details,
+    };
+  }
+
+  /**
+   * The request with a recipient given as an ENS name replaced by the
+   * address it resolved to, which then also has to match
+   * expectedRecipientEns, if any, and with the accounts a versioned Solana
+   * transaction loads from addressLookupTables in place. An unresolved name
+   * or table is left in place.
+   */
+  private resolveRecipient<T extends ValidationRequest>(request: T): T {
+    if (
+      isNullOrUndefined(request) ||
+      !isNonEmptyString(request.unsignedTransaction)
+    ) {
+      return request;
+    }
+    const validator = this.validators.get(request.yieldId);
+    if (!validator) return request;
+    const unsignedTransaction = isDefined(request.addressLookupTables)
+      ? validator.withLookupTables(
+          request.unsignedTransaction,
+          request.addressLookupTables,
+        )
+      : request.unsignedTransaction;
+    const name = validator.getRecipientName(unsignedTransaction);
+    if (!isDefined(name)) return { ...request, unsignedTransaction };
+
+    const address = request.ensAddresses?.[name];
+    return {
+      ...request,
+      unsignedTransaction: isDefined(address)
+        ? validator.withRecipientAddress(unsignedTransaction, address)
+        : unsignedTransaction,
+      expectedRecipientEns: request.expectedRecipientEns ?? name,
+    };
+  }
+
+  /**
+   * Checks that expectedRecipientEns resolved to the contract the
+   * transaction calls. A name that did not resolve fails whatever the
+   * transaction; otherwise, like the policy, this only ever rejects
+   * transactions that passed.
+   */
+  private applyMemoCheck(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    const validator = this.validators.get(request.yieldId);
+    if (!validator || result.reasonCode === 'INVALID_REQUEST') return result;
+
+    const memo = validator.getMemo(request.unsignedTransaction);
+    const withMemo = isDefined(memo) ? { ...result, memo } : result;
+    const expected = request.expectedMemo;
+    if (!isDefined(expected) || !result.isValid) return withMemo;
+
+    if (!isDefined(memo)) {
+      return {
+        isValid: false,
+        reason: `Transaction carries no memo, expected ${expected}`,
+        reasonCode: 'MISSING_MEMO',
+        details: { yieldId: request.yieldId, expected },
+      };
+    }
+    if (memo !== expected) {
+      return {
+        isValid: false,
+        reason: `Transaction memo ${memo} is not the expected ${expected}`,
+        reasonCode: 'MEMO_MISMATCH',
+        details: { yieldId: request.yieldId, expected, actual: memo },
+        memo,
+      };
+    }
+
+    return withMemo;
+  }
+
+  /**
+   * Warns that a valid transaction binds to no chain: signed once, it can
+   * be replayed on every chain where the nonce lines up, such as a chain
+   * where the user holds the same assets under the same address.
+   */
+  private applyReplayCheck(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    const validator = this.validators.get(request.yieldId);
+    if (
+      !result.isValid ||
+      !validator?.isReplayable(request.unsignedTransaction)
+    ) {
+      return result;
+    }
+
+    return {
+      ...result,
+      warnings: [
+        ...(result.warnings ?? []),
+        {
+          code: 'UNPROTECTED_REPLAY',
+          message:
+            'Transaction has chain ID 0, so it can be replayed on any chain',
+          details: {
+            expected: validator.getCapabilities().chainId,
+            actual: '0',
+          },
+        },
+      ],
+    };
+  }
+
+  /**
+   * Fails a valid EIP-7702 transaction whose authorizations another account
+   * signed with AUTHORITY_MISMATCH, and one delegating to a contract outside
+   * the yield's delegationTargets with DELEGATION_TARGET_NOT_ALLOWED. Any
+   * other delegation is reported with an EIP7702_DELEGATION warning, since
+   * the contract's code then controls the user's account and funds.
+   */
+  private applyDelegationCheck(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    const validator = this.validators.get(request.yieldId);
+    if (!result.isValid || !validator) return result;
+
+    const delegations = validator.getDelegations(request.unsignedTransaction);
+    if (!isDefined(delegations)) return result;
+
+    const userAddress =
+      request.userAddress ?? validator.getSigner(request.unsignedTransaction);
+    const allowed = this.options.delegationTargets?.[request.yieldId] ?? [];
+    for (const [index, { address, authority }] of delegations.entries()) {
+      if (
+        !isNonEmptyString(userAddress) ||
+        authority === null ||
+        !validator.isSameAddress(authority, userAddress)
+      ) {
+        return {
+          isValid: false,
+          reason: 'AUTHORITY_MISMATCH',
+          reasonCode: 'AUTHORITY_MISMATCH',
+          details: {
+            yieldId: request.yieldId,
+            index,
+            expected: userAddress,
+            actual: authority,
+          },
+        };
+      }
+      if (
+        !validator.isSameAddress(address, CLEARED_DELEGATION) &&
+        !allowed.some((target) => validator.isSameAddress(target, address))
+      ) {
+        return {
+          isValid: false,
+          reason: 'DELEGATION_TARGET_NOT_ALLOWED',
+          reasonCode: 'DELEGATION_TARGET_NOT_ALLOWED',
+          details: { yieldId: request.yieldId, index, actual: address },
+        };
+      }
+    }
+
+    const targets = delegations.map(({ address }) => address);
+    return {
+      ...result,
+      decoded: { ...result.decoded, authorizationList: delegations },
+      warnings: [
+        ...(result.warnings ?? []),
+        {
+          code: 'EIP7702_DELEGATION',
+          message: `Delegates the account of ${userAddress} to the code of ${targets.join(', ')}`,
+          details: { targets },
+        },
+      ],
+    };
+  }
+
+  private applyDeadlineCheck(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    const validator = this.validators.get(request.yieldId);
+    if (!validator || result.reasonCode === 'INVALID_REQUEST') return result;
+
+    const timestamp = validator.getDeadline(request.unsignedTransaction);
+    if (!isDefined(timestamp)) return result;
+
+    return this.checkDeadline(
+      { ...result, deadline: toDeadline(BigInt(timestamp)) },
+      request.policy,
+    );
+  }
+
+  /**
+   * Reports the unlock time of a vote-escrow lock as lock, and fails a
+   * valid one that ends sooner or later than the yield allows with
+   * LOCK_DURATION_OUT_OF_RANGE. A lock as long as the escrow allows, which
+   * keeps the tokens for years, warns LOCK_MAXED.
+   */
+  private applyLockCheck(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    const validator = this.validators.get(request.yieldId);
+    if (!result.isValid || !validator) return result;
+
+    const terms = validator.getLock(request.unsignedTransaction);
+    if (!isDefined(terms)) return result;
+
+    const { minLockSeconds, maxLockSeconds, lockPeriodSeconds } = terms;
+    const unlockTime = BigInt(terms.unlockTime);
+    const duration = unlockTime - BigInt(Math.floor(Date.now() / 1000));
+    const { timestamp, iso } = toDeadline(unlockTime);
+    const lock: TransactionLock = {
+      unlockTime: timestamp,
+      ...(isDefined(iso) && { unlockTimeIso: iso }),
+      durationSeconds: Number(duration),
+      minLockSeconds,
+      maxLockSeconds,
+    };
+    if (
+      duration <= 0n ||
+      duration < BigInt(minLockSeconds) ||
+      duration > BigInt(maxLockSeconds)
+    ) {
+      return {
+        isValid: false,
+        reason: `Unlocks ${duration} seconds from now, outside the ${minLockSeconds} to ${maxLockSeconds} seconds the yield allows`,
+        reasonCode: 'LOCK_DURATION_OUT_OF_RANGE',
+        details: {
+          yieldId: request.yieldId,
+          unlockTime: timestamp,
+          durationSeconds: lock.durationSeconds,
+          minLockSeconds,
+          maxLockSeconds,
+        },
+        lock,
+      };
+    }
+
+    // No later unlock time rounds to one the escrow takes
+    if (duration + BigInt(lockPeriodSeconds) <= BigInt(maxLockSeconds)) {
+      return { ...result, lock };
+    }
+    return {
+      ...result,
+      lock,
+      warnings: [
+        ...(result.warnings ?? []),
+        {
+          code: 'LOCK_MAXED',
+          message: `Locks until ${iso ?? timestamp}, as long as the yield allows; the tokens cannot be withdrawn before then`,
+          details: { unlockTime: timestamp, maxLockSeconds },
+        },
+      ],
+    };
+  }
+
+  // Whether getYieldAbi lists the function the transaction calls
+  private isExpectedFunction(
+    validator: BaseValidator,
+    unsignedTransaction: string,
+  ): boolean {
+    const selector = validator.getSelector(unsignedTransaction);
+    return validator.getAbiFunctions().some((fn) => fn.selector === selector);
+  }
+
+  /**
+   * Fails a match whose function is not one getYieldAbi lists for its
+   * transaction type with SELECTOR_NOT_ALLOWED. Validators check the
+   * function they expect themselves; this holds them to the ABI they
+   * publish, whatever contract the transaction calls.
+   */
+  private checkSelector(
+    request: ValidationRequest,
+    validator: BaseValidator,
+    transactionType: TransactionType,
+  ): ValidationResult | undefined {
+    const selector = validator.getSelector(request.unsignedTransaction);
+    const functions = validator.getAbiFunctions();
+    if (!isDefined(selector) || functions.length === 0) return undefined;
+
+    const allowed = functions
+      .filter((fn) => fn.transactionType === transactionType)
+      .map((fn) => fn.selector);
+    if (allowed.includes(selector)) return undefined;
+
+    return {
+      isValid: false,
+      reason: 'SELECTOR_NOT_ALLOWED',
+      reasonCode: 'SELECTOR_NOT_ALLOWED',
+      details: {
+        yieldId: request.yieldId,
+        detectedType: transactionType,
+        expected: [...new Set(allowed)],
+        actual: selector,
+      },
+    };
+  }
+
+  /**
+   * Fails a transaction whose calldata is longer than the policy allows
+   * with CALLDATA_TOO_LARGE. Junk appended to calldata is ignored by most
+   * contracts, so it would otherwise only obscure the call.
+   */
+  private checkCalldataSize(
+    request: ValidationRequest,
+    validator: BaseValidator,
+  ): ValidationResult | undefined {
+    const size = validator.getCalldataSize(request.unsignedTransaction);
+    if (!isDefined(size)) return undefined;
+
+    const { unsignedTransaction } = request;
+    const isBatch =
+      isDefined(validator.getWrappedTransaction(unsignedTransaction)) ||
+      isDefined(validator.getMulticall(unsignedTransaction));
+    const maxCalldataBytes =
+      request.policy?.maxCalldataBytes ??
+      (isBatch ? DEFAULT_MAX_BATCH_CALLDATA_BYTES : DEFAULT_MAX_CALLDATA_BYTES);
+    if (size <= maxCalldataBytes) return undefined;
+
+    return {
+      isValid: false,
+      reason: 'CALLDATA_TOO_LARGE',
+      reasonCode: 'CALLDATA_TOO_LARGE',
+      details: {
+        yieldId: request.yieldId,
+        expected: maxCalldataBytes,
+        actual: size,
+      },
+    };
+  }
+
+  /**
+   * Fails a valid result whose deadline has passed with DEADLINE_IN_PAST,
+   * and warns LONG_DEADLINE when it lies further out than the policy's
+   * maxDeadlineSeconds.
+   */
+  private checkDeadline(
+    result: ValidationResult,
+    policy?: ValidationPolicy,
+  ): ValidationResult {
+    if (!result.isValid || !isDefined(result.deadline)) return result;
+
+    const { timestamp, iso } = result.deadline;
+    const deadline = BigInt(timestamp);
+    const now = BigInt(Math.floor(Date.now() / 1000));
+    if (deadline <= now) {
+      return {
+        isValid: false,
+        reason: `Deadline ${iso ?? timestamp} has already passed`,
+        reasonCode: 'DEADLINE_IN_PAST',
+        details: { deadline: timestamp },
+        deadline: result.deadline,
+      };
+    }
+
+    const maxDeadlineSeconds =
+      policy?.maxDeadlineSeconds ?? DEFAULT_MAX_DEADLINE_SECONDS;
+    if (deadline - now <= BigInt(Math.floor(maxDeadlineSeconds))) {
+      return result;
+    }
+
+    return {
+      ...result,
+      warnings: [
+        ...(result.warnings ?? []),
+        {
+          code: 'LONG_DEADLINE',
+          message: `Stays valid until ${iso ?? 'the end of time'}, more than ${maxDeadlineSeconds} seconds from now`,
+          details: { deadline: timestamp, maxDeadlineSeconds },
+        },
+      ],
+    };
+  }
+
+  private applyEnsCheck(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    const name = request.expectedRecipientEns;
+    if (!isNonEmptyString(name)) return result;
+
+    const validator = this.validators.get(request.yieldId);
+    if (!validator || result.reasonCode === 'INVALID_REQUEST') return result;
+
+    const address = request.ensAddresses?.[name];
+    if (isDefined(address) && !result.isValid) return result;
+
+    const [recipient] = validator.getContractAddresses(
+      request.unsignedTransaction,
+    );
+    if (
+      !isDefined(address) ||
+      !isDefined(recipient) ||
+      !validator.isSameAddress(address, recipient)
+    ) {
+      return {
+        isValid: false,
+        reason: isDefined(address)
+          ? `ENS name ${name} resolves to ${address}, not to the transaction's recipient`
+          : `ENS name ${name} did not resolve to an address`,
+        reasonCode: 'RECIPIENT_ENS_MISMATCH',
+        details: {
+          yieldId: request.yieldId,
+          expected: recipient,
+          actual: address,
+        },
+      };
+    }
+
+    return { ...result, resolvedRecipient: { name, address } };
+  }
+
+  /**
+   * Checks that every contract the transaction sends calldata to has code,
+   * per contractCode, since the data does nothing at an account without
+   * it. Contracts missing from contractCode are not checked. Like the
+   * policy, this only ever rejects transactions that passed.
+   */
+  private applyContractCodeCheck(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    const { contractCode } = request;
+    if (!result.isValid || !isDefined(contractCode)) return result;
+
+    const address = this.getCodeAddresses(request).find(
+      (address) => contractCode[address] === false,
+    );
+    if (!isDefined(address)) return result;
+
+    return {
+      isValid: false,
+      reason: `${address} has no contract code, so the call would do nothing with its data`,
+      reasonCode: 'RECIPIENT_NOT_A_CONTRACT',
+      details: { yieldId: request.yieldId, actual: address },
+    };
+  }
+
+  /**
+   * Warns CONTRACT_PAUSED when a contract the transaction calls reports
+   * paused(), per contractPaused: the call would most likely revert, or
+   * leave the funds stuck until it is unpaused.
+   */
+  private applyPauseCheck(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    const { contractPaused } = request;
+    if (!result.isValid || !isDefined(contractPaused)) return result;
+
+    const address = this.getCodeAddresses(request).find(
+      (address) => contractPaused[address] === true,
+    );
+    if (!isDefined(address)) return result;
+
+    return {
+      ...result,
+      warnings: [
+        ...(result.warnings ?? []),
+        {
+          code: 'CONTRACT_PAUSED',
+          message: `${address} is paused, so the transaction is likely to revert`,
+          details: { contract: address },
+        },
+      ],
+    };
+  }
+
+  /**
+   * Warns RECENTLY_DEPLOYED_CONTRACT for each contract the transaction
+   * calls that was deployed less than policy.minContractAgeSeconds ago, per
+   * contractDeployments: staking contracts are usually long established,
+   * so a fresh one is more likely a lookalike. The deployments checked are
+   * reported either way.
+   */
+  private applyContractAgeCheck(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    const minAge = request.policy?.minContractAgeSeconds;
+    const { contractDeployments } = request;
+    if (
+      !result.isValid ||
+      !isDefined(minAge) ||
+      !isDefined(contractDeployments)
+    ) {
+      return result;
+    }
+
+    const deployments: Record<string, ContractDeployment> = {};
+    for (const address of this.getCodeAddresses(request)) {
+      if (isDefined(contractDeployments[address])) {
+        deployments[address] = contractDeployments[address];
+      }
+    }
+    const now = Math.floor(Date.now() / 1000);
+    const warnings: ValidationWarning[] = Object.entries(deployments)
+      .filter(([, { timestamp }]) => now - timestamp < minAge)
+      .map(([address, { block, timestamp }]) => ({
+        code: 'RECENTLY_DEPLOYED_CONTRACT',
+        message: `${address} was deployed ${now - timestamp} seconds ago, in block ${block}`,
+        details: {
+          contract: address,
+          block,
+          timestamp,
+          ageSeconds: now - timestamp,
+          minContractAgeSeconds: minAge,
+        },
+      }));
+
+    return {
+      ...result,
+      contractDeployments: deployments,
+      ...(warnings.length > 0 && {
+        warnings: [...(result.warnings ?? []), ...warnings],
+      }),
+    };
+  }
+
+  /**
+   * Warns YIELD_PAUSED or YIELD_DEPRECATED on a transaction that puts funds
+   * into a yield the registry marks as such. Exits are left alone, since
+   * leaving such a yield is what users should do.
+   */
+  private applyYieldStatusCheck(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    const validator = this.validators.get(request.yieldId);
+    if (!result.isValid || !validator) return result;
+
+    const status = validator.getStatus();
+    const types = result.detectedTypes ?? [result.detectedType];
+    if (
+      status === 'active' ||
+      !types.some((type) => isDefined(type) && STAKING_TYPES.has(type))
+    ) {
+      return result;
+    }
+
+    return {
+      ...result,
+      warnings: [
+        ...(result.warnings ?? []),
+        status === 'paused'
+          ? {
+              code: 'YIELD_PAUSED',
+              message: `${request.yieldId} is paused, so the stake is likely to revert or be stuck`,
+              details: { yieldId: request.yieldId, status },
+            }
+          : {
+              code: 'YIELD_DEPRECATED',
+              message: `${request.yieldId} is deprecated and may stop being supported`,
+              details: { yieldId: request.yieldId, status },
+            },
+      ],
+    };
+  }
+
+  /**
+   * Compares actualBytecodeHash with the hash the registry pins for the
+   * transaction's recipient, so that code replaced behind a known address
+   * is caught offline. A recipient without a pinned hash is not checked.
+   * Like the policy, this only ever rejects transactions that passed.
+   */
+  private applyBytecodeCheck(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    const { actualBytecodeHash: actual } = request;
+    const validator = this.validators.get(request.yieldId);
+    if (!result.isValid || !isDefined(actual) || !validator) return result;
+
+    const [recipient] = validator.getContractAddresses(
+      request.unsignedTransaction,
+    );
+    const expected = isDefined(recipient)
+      ? validator.getBytecodeHash(recipient)
+      : undefined;
+    if (
+      !isDefined(expected) ||
+      expected.toLowerCase() === actual.toLowerCase()
+    ) {
+      return result;
+    }
+
+    return {
+      isValid: false,
+      reason: `The code at ${recipient} hashes to ${actual}, not to the pinned ${expected}`,
+      reasonCode: 'BYTECODE_MISMATCH',
+      details: { yieldId: request.yieldId, expected, actual },
+    };
+  }
+
+  /**
+   * Compares what an unstake draws with stakedBalance: more would revert,
+   * and an amount far beyond the balance can be a tampered one. An unstake
+   * that leaves a sliver of the balance staked adds UNSTAKE_NEAR_FULL, as
+   * it was most likely meant to take everything, and its amount rounded or
+   * mistyped on the way. Like the policy, this only ever rejects
+   * transactions that passed.
+   */
+  private applyBalanceCheck(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    const { stakedBalance } = request;
+    if (!result.isValid || !isDefined(stakedBalance)) return result;
+
+    const call = this.getStakedBalanceCall(request);
+    if (!isDefined(call)) return result;
+
+    const balance = BigInt(stakedBalance);
+    const amount = BigInt(call.amount);
+    const details = { balance: stakedBalance, amount: call.amount };
+    if (amount > balance) {
+      return {
+        isValid: false,
+        reason: `Unstakes ${amount}, more than the staked balance of ${balance}`,
+        reasonCode: 'UNSTAKE_EXCEEDS_BALANCE',
+        details: { yieldId: request.yieldId, ...details },
+      };
+    }
+
+    const remainder = balance - amount;
+    if (
+      remainder === 0n ||
+      remainder * 10000n >= balance * NEAR_FULL_REMAINDER_BPS
+    ) {
+      return result;
+    }
+    return {
+      ...result,
+      warnings: [
+        ...(result.warnings ?? []),
+        {
+          code: 'UNSTAKE_NEAR_FULL',
+          message: `Unstakes all but ${remainder} of the staked balance of ${balance}, which stays staked`,
+          details,
+        },
+      ],
+    };
+  }
+
+  /**
+   * Checks that userAddress owns every position a claim redeems, by
+   * positionOwners, also across the calls of a multicall, which report each
+   * position as positions. Without them, or without a userAddress, the
+   * claim is reported with positionOwnershipVerified false.
+   */
+  private applyPositionCheck(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    const validator = this.validators.get(request.yieldId);
+    if (!result.isValid || !validator) return result;
+
+    const claims = this.getPositionClaims(
+      validator,
+      request.unsignedTransaction,
+    );
+    const claimedPositions = this.toClaimedPositions(claims);
+    if (!isDefined(claimedPositions)) return result;
+
+    // Multicalls report which of their calls claims each position
+    const positions = (
+      owners?: Record<string, string>,
+    ): Partial<ValidationResult> =>
+      isDefined(result.multicall)
+        ? {
+            positions: claims.map(({ positionId, subCall }) => ({
+              positionId,
+              subCall,
+              ...(isDefined(owners) && { owner: owners[positionId] }),
+            })),
+          }
+        : {};
+
+    const { positionOwners, userAddress } = request;
+    if (!isDefined(positionOwners) || !isNonEmptyString(userAddress)) {
+      return {
+        ...result,
+        claimedPositions,
+        positionOwnershipVerified: false,
+        ...positions(),
+      };
+    }
+
+    for (const { positionId, subCall } of claims) {
+      const owner = Object.hasOwn(positionOwners, positionId)
+        ? positionOwners[positionId]
+        : undefined;
+      if (!isDefined(owner) || !validator.isSameAddress(owner, userAddress)) {
+        return {
+          isValid: false,
+          reason: isDefined(owner)
+            ? `Position ${positionId} is owned by ${owner}, not ${userAddress}`
+            : `Position ${positionId} does not exist or was already claimed`,
+          reasonCode: 'CLAIM_POSITION_NOT_OWNED',
+          details: {
+            yieldId: request.yieldId,
+            positionId,
+            ...(isDefined(subCall) && { subCall }),
+            expected: userAddress,
+            actual: owner,
+          },
+          claimedPositions,
+          positionOwnershipVerified: false,
+        };
+      }
+    }
+    return {
+      ...result,
+      claimedPositions,
+      positionOwnershipVerified: true,
+      ...positions(positionOwners),
+    };
+  }
+
+  /**
+   * Compares the transaction's nonce with the caller's expectations. Like
+   * the policy, this only ever rejects transactions that passed.
+   */
+  private applyNonceChecks(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    const validator = this.validators.get(request.yieldId);
+    if (!result.isValid || !validator) return result;
+
+    const nonce = validator.getNonce(request.unsignedTransaction);
+    if (
+      isDefined(request.expectedNonce) &&
+      nonce !== BigInt(request.expectedNonce)
+    ) {
+      return {
+        isValid: false,
+        reason: 'NONCE_MISMATCH',
+        reasonCode: 'NONCE_MISMATCH',
+        details: {
+          yieldId: request.yieldId,
+          expected: request.expectedNonce.toString(),
+          actual: nonce?.toString(),
+        },
+      };
+    }
+
+    if (!isDefined(request.accountNonce) || !isDefined(nonce)) return result;
+    const accountNonce = BigInt(request.accountNonce);
+    if (nonce === accountNonce) return result;
+
+    const details = {
+      nonce: nonce.toString(),
+      accountNonce: accountNonce.toString(),
+    };
+    const warning: ValidationWarning =
+      nonce < accountNonce
+        ? {
+            code: 'NONCE_TOO_LOW',
+            message: `Nonce ${nonce} is below the sender's next nonce ${accountNonce}, so the transaction would fail or replace a pending one`,
+            details,
+          }
+        : {
+            code: 'NONCE_GAP',
+            message: `Nonce ${nonce} skips ahead of the sender's next nonce ${accountNonce}, so the transaction would wait for the nonces in between`,
+            details,
+          };
+    return { ...result, warnings: [...(result.warnings ?? []), warning] };
+  }
+
+  /**
+   * Layers the caller's contract policy on top of built-in validation. A
+   * transaction that already failed keeps its original reason.
+   */
+  private applyPolicy(
+    request: ValidationRequest,
+    result: ValidationResult,
+  ): ValidationResult {
+    const validator = this.validators.get(request.yieldId);
+    if (!result.isValid || !validator || !isDefined(request.policy)) {
+      return result;
+    }
+
+    const {
+      allowedContracts = [],
+      blockedContracts = [],
+      blockDelegateCall = false,
+    } = request.policy;
+    const isListed = (contract: string, list: string[]) =>
+      list.some((entry) => validator.isSameAddress(entry, contract));
+
+    for (const contract of validator.getContractAddresses(
+      request.unsignedTransaction,
+    )) {
+      if (isListed(contract, blockedContracts)) {
+        return {
+          isValid: false,
+          reason: 'CONTRACT_BLOCKED',
+          reasonCode: 'CONTRACT_BLOCKED',
+          details: { yieldId: request.yieldId, actual: contract },
+        };
+      }
+
+      if (
+        allowedContracts.length > 0 &&
+        !isListed(contract, allowedContracts)
+      ) {
+        return {
+          isValid: false,
+          reason: 'CONTRACT_NOT_ALLOWED',
+          reasonCode: 'CONTRACT_NOT_ALLOWED',
+          details: { yieldId: request.yieldId, actual: contract },
+        };
+      }
+    }
+
+    const delegateCall = result.warnings?.find(
+      (warning) => warning.code === 'DELEGATECALL_USED',
+    );
+    if (blockDelegateCall && isDefined(delegateCall)) {
+      return {
+        isValid: false,
+        reason: 'DELEGATECALL_BLOCKED',
+        reasonCode: 'DELEGATECALL_BLOCKED',
+        details: {
+          yieldId: request.yieldId,
+          actual: delegateCall.details?.target as string | undefined,
+        },
+      };
+    }
+
+    return this.checkAmountLimits(request, result) ?? result;
+  }
+
+  /**
+   * Fails a transaction whose amount lies outside the policy's limits for
+   * its yield. An amount in a token whose decimals Shield does not know
+   * cannot be compared with whole units, and fails both limits.
+   */
+  private checkAmountLimits(
+    request: Pick<ValidationRequest, 'yieldId' | 'policy'>,
+    result: Pick<ValidationResult, 'amount'>,
+  ): ValidationResult | undefined {
+    const limits = request.policy?.amountLimits?.[request.yieldId];
+    const { amount } = result;
+    if (!isDefined(limits) || !isDefined(amount)) return undefined;
+
+    const { decimals } = amount;
+    // Whether the amount is past limit, more than it for sign 1 and less
+    // for -1
+    const isPast = (limit: string | undefined, sign: number) => {
+      if (!isDefined(limit)) return false;
+      if (!isDefined(decimals)) return true;
+      const comparison = compareWholeUnits(
+        BigInt(amount.amount),
+        decimals,
+        limit,
+      );
+      return comparison * sign > 0;
+    };
+
+    let code: ReasonCode;
+    let limit: string | undefined;
+    if (isPast(limits.maxAmount, 1)) {
+      code = 'AMOUNT_ABOVE_LIMIT';
+      limit = limits.maxAmount;
+    } else if (isPast(limits.minAmount, -1)) {
+      code = 'AMOUNT_BELOW_MINIMUM';
+      limit = limits.minAmount;
+    } else {
+      return undefined;
+    }
+
+    return {
+      isValid: false,
+      reason: code,
+      reasonCode: code,
+      details: {
+        yieldId: request.yieldId,
+        limit,
+        actual: amount.normalized,
+        error: isDefined(decimals)
+          ? undefined
+          : `Decimals of ${amount.token} are unknown`,
+      },
+      amount,
+    };
+  }
+
+  // An UNKNOWN_CAPABILITY warning per capability of the batch, or of one of
+  // its calls, outside KNOWN_CAPABILITIES
+  private getUnknownCapabilities(sendCalls: SendCalls): ValidationWarning[] {
+    const unknown = (
+      capabilities: Record<string, unknown> | undefined,
+      call?: number,
+    ) =>
+      Object.keys(capabilities ?? {})
+        .filter((capability) => !KNOWN_CAPABILITIES.has(capability))
+        .map((capability) => ({
+          code: 'UNKNOWN_CAPABILITY' as const,
+          message: isDefined(call)
+            ? `Call ${call} requests wallet capability ${capability}, which Shield does not check`
+            : `The batch requests wallet capability ${capability}, which Shield does not check`,
+          details: { capability, ...(isDefined(call) && { call }) },
+        }));
+
+    return [
+      ...unknown(sendCalls.capabilities),
+      ...sendCalls.calls.flatMap((call, i) => unknown(call.capabilities, i)),
+    ];
+  }
+
+  // A NON_ATOMIC_BATCH warning per approval a later call of the batch may
+  // spend, as the wallet may execute the approval but not that call
+  private getNonAtomicApprovals(
+    detectedTypes: TransactionType[],
+  ): ValidationWarning[] {
+    return detectedTypes.flatMap((type, call) =>
+      type === TransactionType.APPROVAL &&
+      detectedTypes
+        .slice(call + 1)
+        .some((later) => later !== TransactionType.APPROVAL)
+        ? [
+            {
+              code: 'NON_ATOMIC_BATCH' as const,
+              message: `The wallet may execute the batch call by call, leaving the approval of call ${call} in place if a later call fails`,
+              details: { call },
+            },
+          ]
+        : [],
+    );
+  }
+
+  /**
+   * Walks the flow in order, tracking what each approval leaves to spend.
+   * Pulls of tokens the flow never approves rely on an allowance granted
+   * earlier and are not checked. With policy.maxApprovalExcessBps, an
+   * approval the flow pulls from must not leave more than that share of
+   * what it pulled unspent; approvals it never pulls from are not checked.
+   */
+  private checkFlowAllowances(
+    validator: BaseValidator,
+    transactions: string[],
+    steps: ValidationResult[],
+    policy: ValidationPolicy | undefined,
+  ): Omit<FlowValidationResult, 'steps'> | null {
+    const allowances: Array<{
+      approval: TokenApproval;
+      step: number;
+      remaining: bigint;
+      spent: bigint;
+    }> = [];
+
+    const checkExcess = (
+      allowance: (typeof allowances)[number],
+    ): Omit<FlowValidationResult, 'steps'> | null => {
+      const maxExcessBps = policy?.maxApprovalExcessBps;
+      if (!isDefined(maxExcessBps) || allowance.spent === 0n) return null;
+
+      const approved = BigInt(allowance.approval.amount);
+      const excess = approved - allowance.spent;
+      if (excess * 10000n <= allowance.spent * BigInt(maxExcessBps)) {
+        return null;
+      }
+      return {
+        isValid: false,
+        reason: 'APPROVAL_EXCEEDS_STAKE',
+        reasonCode: 'APPROVAL_EXCEEDS_STAKE',
+        details: {
+          step: allowance.step,
+          approved: allowance.approval.amount,
+          staked: allowance.spent.toString(),
+          maxApprovalExcessBps: maxExcessBps,
+        },
+      };
+    };
+
+    for (const [step, unsignedTransaction] of transactions.entries()) {
+      const approval = steps[step].decoded?.approval;
+      if (isDefined(approval)) {
+        const existing = allowances.findIndex((a) =>
+          validator.isSameAddress(a.approval.token, approval.token),
+        );
+        // approve() replaces the allowance rather than adding to it
+        if (existing !== -1) {
+          const excess = checkExcess(allowances[existing]);
+          if (excess) return excess;
+          allowances.splice(existing, 1);
+        }
+        allowances.push({
+          approval,
+          step,
+          remaining: BigInt(approval.amount),
+          spent: 0n,
+        });
+        continue;
+      }
+
+      const spend = validator.getTokenSpend(unsignedTransaction);
+      if (!isDefined(spend)) continue;
+
+      const allowance = allowances.find((a) =>
+        validator.isSameAddress(a.approval.token, spend.token),
+      );
+      if (!allowance) continue;
+
+      if (!validator.isSameAddress(allowance.approval.spender, spend.spender)) {
+        return {
+          isValid: false,
+          reason: 'APPROVAL_SPENDER_MISMATCH',
+          reasonCode: 'APPROVAL_SPENDER_MISMATCH',
+          details: {
+            step,
+            expected: spend.spender,
+            actual: allowance.approval.spender,
+          },
+        };
+      }
+
+      const amount = BigInt(spend.amount);
+      allowance.spent += amount;
+      if (allowance.approval.isUnlimited) continue;
+      if (amount > allowance.remaining) {
+        return {
+          isValid: false,
+          reason: 'APPROVAL_INSUFFICIENT_FOR_DEPOSIT',
+          reasonCode: 'APPROVAL_INSUFFICIENT_FOR_DEPOSIT',
+          details: {
+            step,
+            approved: allowance.remaining.toString(),
+            required: spend.amount,
+          },
+        };
+      }
+      allowance.remaining -= amount;
+    }
+
+    for (const allowance of allowances) {
+      const excess = checkExcess(allowance);
+      if (excess) return excess;
+    }
+    return null;
+  }
+
+  /**
+   * Validates each call of a multicall, then the batch as a whole: it must
+   * call only the yield's contracts, make a call beyond approvals, pull no
+   * more than it approves and, through Multicall3, pass on all its value.
+   * expectedAmount applies to the batch, whose amount is the sum of its
+   * calls' in a single token. A batch that claims and puts the claim back
+   * into the yield reports both as compound.
+   */
+  private matchMulticall(
+    request: ValidationRequest & { userAddress: string },
+    validator: BaseValidator,
+    { multicall, unsignedTransactions }: MulticallTransaction,
+  ): ValidationResult {
+    const invalid = (
+      reasonCode: ReasonCode,
+      details: ValidationResult['details'],
+      subResults?: ValidationResult[],
+    ): ValidationResult => ({
+      isValid: false,
+      reason: reasonCode,
+      reasonCode,
+      details: { yieldId: request.yieldId, ...details },
+      multicall,
+      subResults,
+    });
+
+    const { contracts } = validator.getCapabilities();
+    const unexpected = multicall.calls.findIndex(
+      (call) =>
+        !contracts.some((contract) =>
+          validator.isSameAddress(contract, call.target),
+        ),
+    );
+    if (unexpected !== -1) {
+      return invalid('RECIPIENT_MISMATCH', {
+        subCall: unexpected,
+        actual: multicall.calls[unexpected].target,
+      });
+    }
+
+    // Multicall3 keeps whatever value its calls are not sent with
+    const forwarded = multicall.calls.reduce(
+      (total, call) => total + BigInt(call.value),
+      0n,
+    );
+    if (
+      multicall.detectedType === 'MULTICALL3_AGGREGATE' &&
+      forwarded !== BigInt(multicall.value)
+    ) {
+      return invalid('MULTICALL_VALUE_MISMATCH', {
+        expected: multicall.value,
+        actual: forwarded.toString(),
+      });
+    }
+
+    const subResults = unsignedTransactions.map((unsignedTransaction) =>
+      isDefined(validator.getMulticall(unsignedTransaction)) ||
+      isDefined(validator.getWrappedTransaction(unsignedTransaction))
+        ? invalid('MULTICALL_CALL_INVALID', {
+            error: 'Nested multicall and Safe transactions are not supported',
+          })
+        : this.matchTransaction({
+            ...request,
+            unsignedTransaction,
+            expectedAmount: undefined,
+          }),
+    );
+
+    const failed = subResults.findIndex((result) => !result.isValid);
+    if (failed !== -1) {
+      return invalid(
+        'MULTICALL_CALL_INVALID',
+        { subCall: failed, error: subResults[failed].reason },
+        subResults,
+      );
+    }
+
+    const actions = subResults.filter(
+      (result) => result.detectedType !== TransactionType.APPROVAL,
+    );
+    if (actions.length === 0) {
+      return invalid('MULTICALL_CALL_MISSING', {}, subResults);
+    }
+
+    const allowanceMismatch = this.checkFlowAllowances(
+      validator,
+      unsignedTransactions,
+      subResults,
+      request.policy,
+    );
+    if (allowanceMismatch) {
+      return invalid(
+        allowanceMismatch.reasonCode!,
+        { subCall: allowanceMismatch.details?.step as number },
+        subResults,
+      );
+    }
+
+    // positionOwners name positions by ID alone, so they must all be of one
+    // contract
+    const claims = this.getPositionClaims(
+      validator,
+      request.unsignedTransaction,
+    );
+    const otherContract = claims.find(
+      (claim) => !validator.isSameAddress(claim.contract, claims[0].contract),
+    );
+    if (isDefined(otherContract)) {
+      return invalid(
+        'MULTICALL_CALL_INVALID',
+        {
+          subCall: otherContract.subCall,
+          error: `Claims positions of ${otherContract.contract} as well as ${claims[0].contract}`,
+        },
+        subResults,
+      );
+    }
+
+    // Claims pay out to the user rather than take from them
+    const claimResults = subResults.filter(
+      (result) =>
+        isDefined(result.detectedType) &&
+        CLAIMING_TYPES.has(result.detectedType),
+    );
+    const amount = this.sumAmounts(
+      validator,
+      subResults.filter((result) => !claimResults.includes(result)),
+    );
+    const amountMismatch = this.checkAmount(request, validator, amount);
+    if (isDefined(amountMismatch)) {
+      return { ...amountMismatch, multicall, subResults };
+    }
+
+    const warnings = subResults.flatMap((result) => result.warnings ?? []);
+    let matched: ValidationResult = this.withExpectedRecipients(
+      {
+        isValid: true,
+        detectedType: actions[actions.length - 1].detectedType,
+        detectedTypes: subResults.flatMap((result) =>
+          isDefined(result.detectedType) ? [result.detectedType] : [],
+        ),
+        multicall,
+        subResults,
+      },
+      validator.getContractAddresses(request.unsignedTransaction),
+    );
+    if (warnings.length > 0) matched = { ...matched, warnings };
+    if (isDefined(amount)) matched = { ...matched, amount };
+
+    const restaked = this.sumAmounts(
+      validator,
+      subResults.filter(
+        (result) =>
+          isDefined(result.detectedType) &&
+          RESTAKING_TYPES.has(result.detectedType),
+      ),
+    );
+    if (claimResults.length > 0 && isDefined(restaked)) {
+      const claimed = this.sumAmounts(validator, claimResults);
+      matched = {
+        ...matched,
+        compound: {
+          ...(isDefined(claimed) && { claimed }),
+          restaked,
+        },
+      };
+    }
+    return this.withAccessListCheck(
+      matched,
+      validator,
+      request.unsignedTransaction,
+    );
+  }
+
+  // The sum of the results' amounts, or undefined when none has one or they
+  // are in more than one token
+  private sumAmounts(
+    validator: BaseValidator,
+    results: ValidationResult[],
+  ): TransactionAmount | undefined {
+    const amounts = results.flatMap((result) =>
+      isDefined(result.amount) ? [result.amount] : [],
+    );
+    if (amounts.length <= 1) return amounts[0];
+
+    const [{ token, symbol, decimals }] = amounts;
+    const sameToken = amounts.every(
+      (amount) =>
+        amount.token === token || validator.isSameAddress(amount.token, token),
+    );
+    if (!sameToken) return undefined;
+
+    const total = amounts.reduce(
+      (sum, amount) => sum + BigInt(amount.amount),
+      0n,
+    );
+    return toTransactionAmount(
+      token,
+      total,
+      isDefined(symbol) && isDefined(decimals)
+        ? { symbol, decimals }
+        : undefined,
+    );
+  }
+
+  private withExpectedRecipients(
+    result: ValidationResult,
+    contracts: string[],
+  ): ValidationResult {
+    if (contracts.length === 0) return result;
+    return contracts.length === 1
+      ? { ...result, expectedRecipient: contracts[0] }
+      : { ...result, expectedRecipients: contracts };
+  }
+
+  /**
+   * Reports who a matched claim pays and the claim it calls, marking claims
+   * that name no recipient and so pay their sender.
+   */
+  private withClaimRecipient(
+    result: ValidationResult,
+    claim: ClaimCall,
+    userAddress: string,
+  ): ValidationResult {
+    return {
+      ...result,
+      decoded: {
+        ...result.decoded,
+        recipient: {
+          address: claim.recipient ?? userAddress,
+          implicit: claim.recipient === null,
+          variant: claim.variant,
+        },
+      },
+    };
+  }
+
+  /**
+   * Reports the decoded allowance, flagging unlimited ones so a UI can ask
+   * the user to confirm.
+   */
+  private withApproval(
+    result: ValidationResult,
+    approval: TokenApproval,
+  ): ValidationResult {
+    const warnings = [...(result.warnings ?? [])];
+    if (approval.isUnlimited) {
+      warnings.push({
+        code: 'INFINITE_APPROVAL',
+        message: `Transaction grants ${approval.spender} an unlimited allowance`,
+        details: {
+          token: approval.token,
+          spender: approval.spender,
+          amount: approval.amount,
+        },
+      });
+    }
+
+    return {
+      ...result,
+      warnings,
+      decoded: { ...result.decoded, approval },
+    };
+  }
+
+  private withImplementationChange(
+    result: ValidationResult,
+    implementationChange: ImplementationChange,
+  ): ValidationResult {
+    return {
+      ...result,
+      warnings: [
+        ...(result.warnings ?? []),
+        {
+          code: 'IMPLEMENTATION_CHANGE',
+          message: `Transaction upgrades ${implementationChange.proxy} to the implementation at ${implementationChange.implementation}`,
+          details: { ...implementationChange },
+        },
+      ],
+      decoded: { ...result.decoded, implementationChange },
+    };
+  }
+
+  /**
+   * Flags access list entries outside the yield's contracts. They cost the
+   * user gas for nothing, and an attacker could pad the list with them.
+   */
+  private withAccessListCheck(
+    result: ValidationResult,
+    validator: BaseValidator,
+    unsignedTransaction: string,
+  ): ValidationResult {
+    const accessList = validator.getAccessList(unsignedTransaction) ?? [];
+    const { contracts } = validator.getCapabilities();
+    const unexpected = accessList
+      .map(({ address }) => address)
+      .filter(
+        (address) =>
+          !contracts.some((contract) =>
+            validator.isSameAddress(contract, address),
+          ),
+      );
+    if (unexpected.length === 0) return result;
+
+    return {
+      ...result,
+      warnings: [
+        ...(result.warnings ?? []),
+        {
+          code: 'ACCESS_LIST_UNEXPECTED_ADDRESS',
+          message: 'Access list references addresses outside this yield',
+          details: { addresses: unexpected },
+        },
+      ],
+    };
+  }
+
+  /**
+   * Flags a gas limit far above what the detected operation needs, which a
+   * malicious relayer could use to drain the user through fees.
+   */
+  private withGasLimitCheck(
+    result: ValidationResult,
+    validator: BaseValidator,
+    unsignedTransaction: string,
+    transactionType: TransactionType,
+  ): ValidationResult {
+    const gasLimit = validator.getGasLimit(unsignedTransaction);
+    const range = validator.getGasLimitRange(transactionType);
+    if (!isDefined(gasLimit) || !isDefined(range) || gasLimit <= range.max) {
+      return result;
+    }
+
+    return {
+      ...result,
+      warnings: [
+        ...(result.warnings ?? []),
+        {
+          code: 'HIGH_GAS_LIMIT',
+          message: `Gas limit ${gasLimit} is far above the ${range.max} that ${transactionType} transactions typically need`,
+          details: {
+            gasLimit: gasLimit.toString(),
+            expected: { min: range.min.toString(), max: range.max.toString() },
+          },
+        },
+      ],
+    };
+  }
+
+  /**
+   * Reports what a swap accepts back, and flags a minimum output of zero,
+   * or one further below the output the call expects than the policy
+   * allows. A searcher can sandwich the swap for the difference. Calls
+   * that name no expected output, as LI.FI's do not, are only flagged at
+   * zero, since their tokens cannot be compared.
+   */
+  private withSlippageCheck(
+    result: ValidationResult,
+    validator: BaseValidator,
+    request: ValidationRequest,
+  ): ValidationResult {
+    const slippage = validator.getSlippage(request.unsignedTransaction);
+    if (!isDefined(slippage)) return result;
+
+    const withSlippage = {
+      ...result,
+      decoded: { ...result.decoded, slippage },
+    };
+    const maxSlippageBps =
+      request.policy?.maxSlippageBps ?? DEFAULT_MAX_SLIPPAGE_BPS;
+    const minAmountOut = BigInt(slippage.minAmountOut);
+    const expected = isDefined(slippage.expectedAmountOut)
+      ? BigInt(slippage.expectedAmountOut)
+      : undefined;
+    const tooLow =
+      minAmountOut === 0n ||
+      (isDefined(expected) &&
+        minAmountOut * 10000n < expected * BigInt(10000 - maxSlippageBps));
+    if (!tooLow) return withSlippage;
+
+    return {
+      ...withSlippage,
+      warnings: [
+        ...(result.warnings ?? []),
+        {
+          code: 'LOW_SLIPPAGE_PROTECTION',
+          message:
+            minAmountOut === 0n
+              ? 'Swap accepts any output, however small'
+              : `Swap accepts ${slippage.minAmountOut}, more than ${maxSlippageBps} basis points below the ${slippage.expectedAmountOut} it expects`,
+          details: {
+            inputAmount: slippage.inputAmount,
+            minAmountOut: slippage.minAmountOut,
+            expectedAmountOut: slippage.expectedAmountOut,
+            maxSlippageBps,
+          },
+        },
+      ],
+    };
+  }
+
+  // Timing of a validate call that took matchMs. Matching decodes the
+  // transaction at every check, so decodeMs, one decode on its own, is
+  // included in matchMs rather than added to it
+  private measureDecode(
+    request: ValidationRequest,
+    matchMs: number,
+  ): ValidationTiming {
+    // No validator looks at requests rejected up front, such as those for
+    // an unknown yield
+    const validator = this.validators.get(request.yieldId);
+    if (!validator || !isNonEmptyString(request.unsignedTransaction)) {
+      return { decodeMs: 0, matchMs, totalMs: matchMs, path: 'none' };
+    }
+
+    const startedAt = performance.now();
+    validator.decode(request.unsignedTransaction);
+    return {
+      decodeMs: elapsedMs(startedAt),
+      matchMs,
+      totalMs: matchMs,
+      path: validator.getMatchPath(request.unsignedTransaction),
+    };
+  }
+
+  private withSenderNotVerified(
+    result: ValidationResult,
+    sender: string | undefined,
+  ): ValidationResult {
+    return {
+      ...result,
+      warnings: [
+        ...(result.warnings ?? []),
+        {
+          code: 'SENDER_NOT_VERIFIED',
+          message:
+            'No userAddress was provided, so the transaction sender was not checked',
+          details: { sender },
+        },
+      ],
+    };
+  }
 }
@@ -0,0 +1,442 @@
+import { ethers } from 'ethers';
+import {
+  callUint256,
+  decodeRevertReason,
+  getDeployment,
+  getTransactionCount,
+  hasCode,
+  isPaused,
+  resolveEnsName,
+  simulateCall,
+} from './simulation';
+
+describe('simulateCall', () => {
+  const rpcUrl = 'https://rpc.example.com';
+  const call = {
+    from: '0x742d35cc6634c0532925a3b844bc9e7595f0beb8',
+    to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+    data: '0x',
+    value: '0x0',
+  };
+
+  const respondWith = (body: unknown, status = 200) =>
+    jest.fn().mockResolvedValue({
+      ok: status === 200,
+      status,
+      json: () => Promise.resolve(body),
+    }) as unknown as typeof fetch;
+
+  const errorData = (message: string) =>
+    '0x08c379a0' +
+    ethers.AbiCoder.defaultAbiCoder().encode(['string'], [message]).slice(2);
+
+  it('should send eth_call against the latest block', async () => {
+    const fetchImpl = respondWith({ jsonrpc: '2.0', id: 1, result: '0x' });
+
+    await simulateCall(rpcUrl, call, fetchImpl);
+
+    const [url, init] = (fetchImpl as unknown as jest.Mock).mock.calls[0];
+    expect(url).toBe(rpcUrl);
+    expect(JSON.parse(init.body)).toEqual({
+      jsonrpc: '2.0',
+      id: 1,
+      method: 'eth_call',
+      params: [call, 'latest'],
+    });
+  });
+
+  it('should send eth_call against a given block', async () => {
+    const fetchImpl = respondWith({ jsonrpc: '2.0', id: 1, result: '0x' });
+
+    await simulateCall(rpcUrl, call, fetchImpl, 20000000);
+
+    const [, init] = (fetchImpl as unknown as jest.Mock).mock.calls[0];
+    expect(JSON.parse(init.body).params).toEqual([call, '0x1312d00']);
+  });
+
+  it('should return the data of a successful call', async () => {
+    const result = await simulateCall(
+      rpcUrl,
+      call,
+      respondWith({ jsonrpc: '2.0', id: 1, result: '0x01' }),
+    );
+
+    expect(result).toEqual({ success: true, returnData: '0x01' });
+  });
+
+  it('should decode the reason of a reverted call', async () => {
+    const data = errorData('BAL#001');
+    const result = await simulateCall(
+      rpcUrl,
+      call,
+      respondWith({
+        jsonrpc: '2.0',
+        id: 1,
+        error: { code: 3, message: 'execution reverted: BAL#001', data },
+      }),
+    );
+
+    expect(result.success).toBe(false);
+    expect(result.returnData).toBe(data);
+    expect(result.revertReason).toBe('BAL#001');
+  });
+
+  it('should accept revert data nested under error.data', async () => {
+    const data = errorData('paused');
+    const result = await simulateCall(
+      rpcUrl,
+      call,
+      respondWith({
+        jsonrpc: '2.0',
+        id: 1,
+        error: { code: -32000, message: 'execution reverted', data: { data } },
+      }),
+    );
+
+    expect(result.success).toBe(false);
+    expect(result.revertReason).toBe('paused');
+  });
+
+  it('should throw on node errors other than reverts', async () => {
+    await expect(
+      simulateCall(
+        rpcUrl,
+        call,
+        respondWith({
+          jsonrpc: '2.0',
+          id: 1,
+          error: { code: -32601, message: 'method not found' },
+        }),
+      ),
+    ).rejects.toThrow('method not found');
+  });
+
+  it('should throw on HTTP errors', async () => {
+    await expect(
+      simulateCall(rpcUrl, call, respondWith({}, 503)),
+    ).rejects.toThrow('HTTP 503');
+  });
+});
+
+describe('getTransactionCount', () => {
+  const rpcUrl = 'https://rpc.example.com';
+  const address = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+
+  const respondWith = (body: unknown) =>
+    jest.fn().mockResolvedValue({
+      ok: true,
+      status: 200,
+      json: () => Promise.resolve(body),
+    }) as unknown as typeof fetch;
+
+  it('should count pending transactions', async () => {
+    const fetchImpl = respondWith({ jsonrpc: '2.0', id: 1, result: '0x2a' });
+
+    await expect(
+      getTransactionCount(rpcUrl, address, fetchImpl),
+    ).resolves.toBe(42);
+    const [, init] = (fetchImpl as unknown as jest.Mock).mock.calls[0];
+    expect(JSON.parse(init.body)).toEqual({
+      jsonrpc: '2.0',
+      id: 1,
+      method: 'eth_getTransactionCount',
+      params: [address, 'pending'],
+    });
+  });
+
+  it('should count the transactions included by a given block', async () => {
+    const fetchImpl = respondWith({ jsonrpc: '2.0', id: 1, result: '0x29' });
+
+    await expect(
+      getTransactionCount(rpcUrl, address, fetchImpl, 20000000),
+    ).resolves.toBe(41);
+    const [, init] = (fetchImpl as unknown as jest.Mock).mock.calls[0];
+    expect(JSON.parse(init.body).params).toEqual([address, '0x1312d00']);
+  });
+
+  it('should throw node errors', async () => {
+    const fetchImpl = respondWith({
+      jsonrpc: '2.0',
+      id: 1,
+      error: { code: -32602, message: 'invalid address' },
+    });
+
+    await expect(
+      getTransactionCount(rpcUrl, address, fetchImpl),
+    ).rejects.toThrow('invalid address');
+  });
+});
+
+describe('hasCode', () => {
+  const rpcUrl = 'https://rpc.example.com';
+  const address = '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84';
+
+  const respondWith = (body: unknown) =>
+    jest.fn().mockResolvedValue({
+      ok: true,
+      status: 200,
+      json: () => Promise.resolve(body),
+    }) as unknown as typeof fetch;
+
+  it('should fetch the code at the latest block', async () => {
+    const fetchImpl = respondWith({ jsonrpc: '2.0', id: 1, result: '0x6080' });
+
+    await expect(hasCode(rpcUrl, address, fetchImpl)).resolves.toBe(true);
+    const [, init] = (fetchImpl as unknown as jest.Mock).mock.calls[0];
+    expect(JSON.parse(init.body)).toEqual({
+      jsonrpc: '2.0',
+      id: 1,
+      method: 'eth_getCode',
+      params: [address, 'latest'],
+    });
+  });
+
+  it('should fetch the code at a given block', async () => {
+    const fetchImpl = respondWith({ jsonrpc: '2.0', id: 1, result: '0x6080' });
+
+    await hasCode(rpcUrl, address, fetchImpl, 20000000);
+    const [, init] = (fetchImpl as unknown as jest.Mock).mock.calls[0];
+    expect(JSON.parse(init.body).params).toEqual([address, '0x1312d00']);
+  });
+
+  it('should report an account without code', async () => {
+    const fetchImpl = respondWith({ jsonrpc: '2.0', id: 1, result: '0x' });
+
+    await expect(hasCode(rpcUrl, address, fetchImpl)).resolves.toBe(false);
+  });
+
+  it('should throw node errors', async () => {
+    const fetchImpl = respondWith({
+      jsonrpc: '2.0',
+      id: 1,
+      error: { code: -32602, message: 'invalid address' },
+    });
+
+    await expect(hasCode(rpcUrl, address, fetchImpl)).rejects.toThrow(
+      'invalid address',
+    );
+  });
+});
+
+describe('getDeployment', () => {
+  const rpcUrl = 'https://rpc.example.com';
+  const address = '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84';
+
+  // A chain of 1000 blocks, block n minted at 1700000000 + 12n, whose
+  // contract has code from block deployedAt
+  const chain = (deployedAt: number) =>
+    jest.fn(async (_url: string, init: RequestInit) => {
+      const { method, params } = JSON.parse(init.body as string);
+      const block =
+        params[0] === 'latest' || params[1] === 'latest'
+          ? 1000
+          : Number(method === 'eth_getCode' ? params[1] : params[0]);
+      const result =
+        method === 'eth_blockNumber'
+          ? '0x3e8'
+          : method === 'eth_getCode'
+            ? block >= deployedAt
+              ? '0x6080'
+              : '0x'
+            : { timestamp: ethers.toQuantity(1700000000 + 12 * block) };
+      return {
+        ok: true,
+        status: 200,
+        json: () => Promise.resolve({ jsonrpc: '2.0', id: 1, result }),
+      };
+    }) as unknown as typeof fetch;
+
+  it('should find the first block with code and its timestamp', async () => {
+    for (const deployedAt of [0, 1, 437, 999, 1000]) {
+      await expect(
+        getDeployment(rpcUrl, address, chain(deployedAt)),
+      ).resolves.toEqual({
+        block: deployedAt,
+        timestamp: 1700000000 + 12 * deployedAt,
+      });
+    }
+  });
+
+  it('should bisect rather than scan the chain', async () => {
+    const fetchImpl = chain(437);
+    await getDeployment(rpcUrl, address, fetchImpl);
+
+    expect(
+      (fetchImpl as unknown as jest.Mock).mock.calls.length,
+    ).toBeLessThanOrEqual(14);
+  });
+
+  it('should report an account without code', async () => {
+    await expect(getDeployment(rpcUrl, address, chain(1001))).resolves.toBe(
+      null,
+    );
+  });
+
+  it('should search the chain up to a given block', async () => {
+    const fetchImpl = chain(437);
+
+    await expect(getDeployment(rpcUrl, address, fetchImpl, 400)).resolves.toBe(
+      null,
+    );
+    await expect(
+      getDeployment(rpcUrl, address, fetchImpl, 500),
+    ).resolves.toEqual({ block: 437, timestamp: 1700000000 + 12 * 437 });
+    const methods = (fetchImpl as unknown as jest.Mock).mock.calls.map(
+      ([, init]) => JSON.parse(init.body).method,
+    );
+    expect(methods).not.toContain('eth_blockNumber');
+  });
+});
+
+describe('isPaused', () => {
+  const rpcUrl = 'https://rpc.example.com';
+  const address = '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84';
+
+  const respondWith = (body: unknown) =>
+    jest.fn().mockResolvedValue({
+      ok: true,
+      status: 200,
+      json: () => Promise.resolve(body),
+    }) as unknown as typeof fetch;
+  const word = (value: number) =>
+    '0x' + value.toString(16).padStart(64, '0');
+
+  it('should call paused() at the latest block', async () => {
+    const fetchImpl = respondWith({ jsonrpc: '2.0', id: 1, result: word(1) });
+
+    await expect(isPaused(rpcUrl, address, fetchImpl)).resolves.toBe(true);
+    const [, init] = (fetchImpl as unknown as jest.Mock).mock.calls[0];
+    expect(JSON.parse(init.body).params).toEqual([
+      { to: address, data: '0x5c975abb' },
+      'latest',
+    ]);
+  });
+
+  it('should report contracts without paused() as not paused', async () => {
+    for (const body of [
+      { jsonrpc: '2.0', id: 1, result: word(0) },
+      { jsonrpc: '2.0', id: 1, result: '0x' },
+      {
+        jsonrpc: '2.0',
+        id: 1,
+        error: { code: 3, message: 'execution reverted', data: '0x' },
+      },
+    ]) {
+      await expect(
+        isPaused(rpcUrl, address, respondWith(body)),
+      ).resolves.toBe(false);
+    }
+  });
+});
+
+describe('callUint256', () => {
+  const rpcUrl = 'https://rpc.example.com';
+  const call = { to: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84', data: '0x' };
+
+  const respondWith = (body: unknown) =>
+    jest.fn().mockResolvedValue({
+      ok: true,
+      status: 200,
+      json: () => Promise.resolve(body),
+    }) as unknown as typeof fetch;
+
+  it('should return the uint256 the call returns', async () => {
+    const fetchImpl = respondWith({
+      jsonrpc: '2.0',
+      id: 1,
+      result: '0x' + (10n ** 18n).toString(16).padStart(64, '0'),
+    });
+
+    await expect(callUint256(rpcUrl, call, fetchImpl)).resolves.toBe(
+      10n ** 18n,
+    );
+  });
+
+  it('should throw on reverts and return data of another shape', async () => {
+    const reverted = respondWith({
+      jsonrpc: '2.0',
+      id: 1,
+      error: { code: 3, message: 'execution reverted', data: '0x' },
+    });
+    const empty = respondWith({ jsonrpc: '2.0', id: 1, result: '0x' });
+
+    await expect(callUint256(rpcUrl, call, reverted)).rejects.toThrow(
+      'Call reverted',
+    );
+    await expect(callUint256(rpcUrl, call, empty)).rejects.toThrow(
+      'Call did not return a uint256',
+    );
+  });
+});
+
+describe('resolveEnsName', () => {
+  const rpcUrl = 'https://rpc.example.com';
+  const resolver = '0x231b0Ee14048e9dCcD1d247744d114a4EB5E8E63';
+  const stETH = '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84';
+
+  const word = (address: string) =>
+    '0x' + address.slice(2).toLowerCase().padStart(64, '0');
+  const respondWith = (...results: string[]) => {
+    const fetchImpl = jest.fn();
+    for (const result of results) {
+      fetchImpl.mockResolvedValueOnce({
+        ok: true,
+        status: 200,
+        json: () => Promise.resolve({ jsonrpc: '2.0', id: 1, result }),
+      });
+    }
+    return fetchImpl as unknown as typeof fetch;
+  };
+
+  it("should ask the registry for the name's resolver, then its address", async () => {
+    const fetchImpl = respondWith(word(resolver), word(stETH));
+
+    await expect(resolveEnsName(rpcUrl, 'lido.eth', fetchImpl)).resolves.toBe(
+      ethers.getAddress(stETH),
+    );
+
+    const calls = (fetchImpl as unknown as jest.Mock).mock.calls.map(
+      ([, init]) => JSON.parse(init.body).params[0],
+    );
+    const node = ethers.namehash('lido.eth').slice(2);
+    expect(calls).toEqual([
+      {
+        to: '0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e',
+        data: '0x0178b8bf' + node,
+      },
+      { to: ethers.getAddress(resolver), data: '0x3b3b57de' + node },
+    ]);
+  });
+
+  it('should resolve names without a resolver or address to null', async () => {
+    const zero = word(ethers.ZeroAddress);
+
+    await expect(
+      resolveEnsName(rpcUrl, 'unknown.eth', respondWith(zero)),
+    ).resolves.toBeNull();
+    await expect(
+      resolveEnsName(rpcUrl, 'lido.eth', respondWith(word(resolver), zero)),
+    ).resolves.toBeNull();
+    // A chain without the registry
+    await expect(
+      resolveEnsName(rpcUrl, 'lido.eth', respondWith('0x')),
+    ).resolves.toBeNull();
+  });
+});
+
+describe('decodeRevertReason', () => {
+  it('should decode Panic(uint256)', () => {
+    const data =
+      '0x4e487b71' +
+      ethers.AbiCoder.defaultAbiCoder().encode(['uint256'], [0x11]).slice(2);
+    expect(decodeRevertReason(data)).toBe('Panic(0x11)');
+  });
+
+  it('should leave custom errors undecoded', () => {
+    expect(decodeRevertReason('0x1425ea42')).toBeUndefined();
+  });
+
+  it('should leave malformed payloads undecoded', () => {
+    expect(decodeRevertReason('0x08c379a0ff')).toBeUndefined();
+  });
+});
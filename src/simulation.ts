@@ -0,0 +1,401 @@
+import { AddressLookupTableAccount } from '@solana/web3.js';
+import { ethers } from 'ethers';
+import { ContractDeployment, SimulationCall } from './types';
+
+// Upper bound on how long a node may take to answer a request
+const RPC_TIMEOUT_MS = 10_000;
+
+export interface CallOutcome {
+  success: boolean;
+  returnData: string;
+  revertReason?: string;
+}
+
+interface JsonRpcResponse {
+  result?: unknown;
+  error?: { code?: number; message?: string; data?: unknown };
+}
+
+const ERROR_SELECTOR = '0x08c379a0'; // Error(string)
+const PANIC_SELECTOR = '0x4e487b71'; // Panic(uint256)
+
+// A block number as JSON-RPC takes it, or otherwise when none is given
+function toBlockTag(block: number | undefined, otherwise = 'latest'): string {
+  return block === undefined ? otherwise : ethers.toQuantity(block);
+}
+
+/**
+ * Executes call against rpcUrl at block, or the latest block. A revert is a
+ * normal outcome with success: false; transport and node errors throw.
+ */
+export async function simulateCall(
+  rpcUrl: string,
+  call: SimulationCall,
+  fetchImpl: typeof fetch = fetch,
+  block?: number,
+): Promise<CallOutcome> {
+  const body = await postJsonRpc(
+    rpcUrl,
+    'eth_call',
+    [call, toBlockTag(block)],
+    fetchImpl,
+  );
+  if (typeof body.result === 'string') {
+    return { success: true, returnData: body.result };
+  }
+
+  // Nodes report reverts as an error whose data is the revert payload;
+  // some wrap it as { data: '0x...' }
+  const data =
+    typeof body.error?.data === 'string'
+      ? body.error.data
+      : (body.error?.data as { data?: unknown } | undefined)?.data;
+  if (isRevert(body.error?.message) || typeof data === 'string') {
+    const returnData = typeof data === 'string' ? data : '0x';
+    return {
+      success: false,
+      returnData,
+      revertReason: decodeRevertReason(returnData),
+    };
+  }
+
+  throw new Error(body.error?.message ?? 'RPC endpoint returned no result');
+}
+
+/**
+ * The next nonce of address, counting its pending transactions, as
+ * eth_getTransactionCount reports it. At a block, only the transactions
+ * included by then count. Transport and node errors throw.
+ */
+export async function getTransactionCount(
+  rpcUrl: string,
+  address: string,
+  fetchImpl: typeof fetch = fetch,
+  block?: number,
+): Promise<number> {
+  const body = await postJsonRpc(
+    rpcUrl,
+    'eth_getTransactionCount',
+    [address, toBlockTag(block, 'pending')],
+    fetchImpl,
+  );
+  if (
+    typeof body.result !== 'string' ||
+    !/^0x[0-9a-fA-F]{1,16}$/.test(body.result)
+  ) {
+    throw new Error(body.error?.message ?? 'RPC endpoint returned no result');
+  }
+  return Number(BigInt(body.result));
+}
+
+/**
+ * Whether address has code at block, or the latest block, as eth_getCode
+ * reports it: false for an externally owned account. Transport and node
+ * errors throw.
+ */
+export async function hasCode(
+  rpcUrl: string,
+  address: string,
+  fetchImpl: typeof fetch = fetch,
+  block?: number,
+): Promise<boolean> {
+  return hasCodeAt(rpcUrl, address, toBlockTag(block), fetchImpl);
+}
+
+async function hasCodeAt(
+  rpcUrl: string,
+  address: string,
+  block: string,
+  fetchImpl: typeof fetch,
+): Promise<boolean> {
+  const body = await postJsonRpc(
+    rpcUrl,
+    'eth_getCode',
+    [address, block],
+    fetchImpl,
+  );
+  if (
+    typeof body.result !== 'string' ||
+    !/^0x([0-9a-fA-F]{2})*$/.test(body.result)
+  ) {
+    throw new Error(body.error?.message ?? 'RPC endpoint returned no result');
+  }
+  return body.result !== '0x';
+}
+
+/**
+ * The block contract was deployed in, with its timestamp: the first block
+ * at which it has code, found by bisecting eth_getCode over the chain's
+ * history, so about 25 requests on Ethereum. null when it has no code at
+ * block, or the latest block. Older blocks need an archive node: a pruned
+ * node's errors throw, as transport and node errors do.
+ */
+export async function getDeployment(
+  rpcUrl: string,
+  contract: string,
+  fetchImpl: typeof fetch = fetch,
+  block?: number,
+): Promise<ContractDeployment | null> {
+  let last = block;
+  if (last === undefined) {
+    const latest = await postJsonRpc(rpcUrl, 'eth_blockNumber', [], fetchImpl);
+    if (!isQuantity(latest.result)) {
+      throw new Error(
+        latest.error?.message ?? 'RPC endpoint returned no result',
+      );
+    }
+    last = Number(BigInt(latest.result));
+  }
+
+  if (!(await hasCodeAt(rpcUrl, contract, toBlockTag(last), fetchImpl))) {
+    return null;
+  }
+
+  let low = 0;
+  let high = last;
+  while (low < high) {
+    const middle = Math.floor((low + high) / 2);
+    if (
+      await hasCodeAt(rpcUrl, contract, ethers.toQuantity(middle), fetchImpl)
+    ) {
+      high = middle;
+    } else {
+      low = middle + 1;
+    }
+  }
+
+  const header = await postJsonRpc(
+    rpcUrl,
+    'eth_getBlockByNumber',
+    [ethers.toQuantity(high), false],
+    fetchImpl,
+  );
+  const timestamp = (header.result as { timestamp?: unknown } | null)
+    ?.timestamp;
+  if (!isQuantity(timestamp)) {
+    throw new Error(header.error?.message ?? 'RPC endpoint returned no block');
+  }
+  return { block: high, timestamp: Number(BigInt(timestamp)) };
+}
+
+function isQuantity(value: unknown): value is string {
+  return typeof value === 'string' && /^0x[0-9a-fA-F]{1,16}$/.test(value);
+}
+
+/**
+ * The uint256 call returns at block, or the latest block, e.g. a
+ * balanceOf. A revert, or return data that is not a single uint256, throws
+ * as transport and node errors do.
+ */
+export async function callUint256(
+  rpcUrl: string,
+  call: SimulationCall,
+  fetchImpl: typeof fetch = fetch,
+  block?: number,
+): Promise<bigint> {
+  const outcome = await simulateCall(rpcUrl, call, fetchImpl, block);
+  if (!outcome.success) {
+    throw new Error(outcome.revertReason ?? 'Call reverted');
+  }
+  if (!/^0x[0-9a-fA-F]{64}$/.test(outcome.returnData)) {
+    throw new Error('Call did not return a uint256');
+  }
+  return BigInt(outcome.returnData);
+}
+
+const pausableInterface = new ethers.Interface([
+  'function paused() view returns (bool)',
+]);
+
+/**
+ * Whether contract reports paused() at block, or the latest block, as
+ * OpenZeppelin's Pausable and most vaults do. A contract without paused(),
+ * whose call reverts or returns something other than a bool, is not
+ * paused. Transport and node errors throw.
+ */
+export async function isPaused(
+  rpcUrl: string,
+  contract: string,
+  fetchImpl: typeof fetch = fetch,
+  block?: number,
+): Promise<boolean> {
+  const outcome = await simulateCall(
+    rpcUrl,
+    { to: contract, data: pausableInterface.encodeFunctionData('paused') },
+    fetchImpl,
+    block,
+  );
+  return outcome.success && /^0x0{63}1$/.test(outcome.returnData);
+}
+
+const erc721Interface = new ethers.Interface([
+  'function ownerOf(uint256 tokenId) view returns (address)',
+]);
+
+/**
+ * The owner of token tokenId of the ERC-721 contract on rpcUrl's chain, at
+ * block or the latest block, or null when ownerOf reverts, as it does for
+ * tokens that do not exist or were burned. Transport and node errors throw.
+ */
+export async function getOwnerOf(
+  rpcUrl: string,
+  contract: string,
+  tokenId: string,
+  fetchImpl: typeof fetch = fetch,
+  block?: number,
+): Promise<string | null> {
+  const outcome = await simulateCall(
+    rpcUrl,
+    {
+      to: contract,
+      data: erc721Interface.encodeFunctionData('ownerOf', [tokenId]),
+    },
+    fetchImpl,
+    block,
+  );
+  if (!outcome.success) return null;
+  if (outcome.returnData.length !== 66) {
+    throw new Error('ownerOf did not return an address');
+  }
+
+  const [owner] = erc721Interface.decodeFunctionResult(
+    'ownerOf',
+    outcome.returnData,
+  );
+  return owner as string;
+}
+
+const LOOKUP_TABLE_PROGRAM = 'AddressLookupTab1e1111111111111111111111111';
+
+/**
+ * The addresses the Solana address lookup table at address holds, in
+ * order, as getAccountInfo reports its account. An account that does not
+ * exist or is not a lookup table throws, as transport and node errors do.
+ */
+export async function getLookupTable(
+  rpcUrl: string,
+  address: string,
+  fetchImpl: typeof fetch = fetch,
+): Promise<string[]> {
+  const body = await postJsonRpc(
+    rpcUrl,
+    'getAccountInfo',
+    [address, { encoding: 'base64' }],
+    fetchImpl,
+  );
+  // The account's data is [base64, 'base64']
+  const account = (
+    body.result as { value?: { owner?: unknown; data?: unknown } } | null
+  )?.value;
+  const data =
+    account && Array.isArray(account.data) ? account.data[0] : undefined;
+  if (typeof data !== 'string') {
+    throw new Error(body.error?.message ?? `No account at ${address}`);
+  }
+  if (account!.owner !== LOOKUP_TABLE_PROGRAM) {
+    throw new Error(`${address} is not an address lookup table`);
+  }
+
+  const state = AddressLookupTableAccount.deserialize(
+    Buffer.from(data, 'base64'),
+  );
+  return state.addresses.map((key) => key.toBase58());
+}
+
+// The ENS registry, at the same address on Ethereum and its testnets
+const ENS_REGISTRY = '0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e';
+const ensInterface = new ethers.Interface([
+  'function resolver(bytes32 node) view returns (address)',
+  'function addr(bytes32 node) view returns (address)',
+]);
+
+/**
+ * The address name resolves to through the ENS registry of rpcUrl's chain,
+ * at block or the latest block, or null when the name has no resolver or
+ * no address there. Transport and node errors throw.
+ */
+export async function resolveEnsName(
+  rpcUrl: string,
+  name: string,
+  fetchImpl: typeof fetch = fetch,
+  block?: number,
+): Promise<string | null> {
+  const node = ethers.namehash(name);
+  const resolver = await callForAddress(
+    rpcUrl,
+    ENS_REGISTRY,
+    'resolver',
+    node,
+    fetchImpl,
+    block,
+  );
+  if (resolver === null) return null;
+  return callForAddress(rpcUrl, resolver, 'addr', node, fetchImpl, block);
+}
+
+// A chain without the registry answers with empty return data
+async function callForAddress(
+  rpcUrl: string,
+  to: string,
+  functionName: 'resolver' | 'addr',
+  node: string,
+  fetchImpl: typeof fetch,
+  block: number | undefined,
+): Promise<string | null> {
+  const outcome = await simulateCall(
+    rpcUrl,
+    { to, data: ensInterface.encodeFunctionData(functionName, [node]) },
+    fetchImpl,
+    block,
+  );
+  if (!outcome.success || outcome.returnData.length !== 66) return null;
+
+  const [address] = ensInterface.decodeFunctionResult(
+    functionName,
+    outcome.returnData,
+  );
+  return address === ethers.ZeroAddress ? null : (address as string);
+}
+
+async function postJsonRpc(
+  rpcUrl: string,
+  method: string,
+  params: unknown[],
+  fetchImpl: typeof fetch,
+): Promise<JsonRpcResponse> {
+  const response = await fetchImpl(rpcUrl, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ jsonrpc: '2.0', id: 1, method, params }),
+    signal: AbortSignal.timeout(RPC_TIMEOUT_MS),
+  });
+  if (!response.ok) {
+    throw new Error(`RPC endpoint responded with HTTP ${response.status}`);
+  }
+  return (await response.json()) as JsonRpcResponse;
+}
+
+function isRevert(message: string | undefined): boolean {
+  return typeof message === 'string' && /revert/i.test(message);
+}
+
+/**
+ * Decodes Solidity's Error(string) and Panic(uint256) revert payloads.
+ * Custom errors are left to the caller, as returnData.
+ */
+export function decodeRevertReason(data: string): string | undefined {
+  const coder = ethers.AbiCoder.defaultAbiCoder();
+  try {
+    if (data.startsWith(ERROR_SELECTOR)) {
+      const [message] = coder.decode(['string'], '0x' + data.slice(10));
+      return message as string;
+    }
+    if (data.startsWith(PANIC_SELECTOR)) {
+      const [code] = coder.decode(['uint256'], '0x' + data.slice(10));
+      return `Panic(0x${(code as bigint).toString(16)})`;
+    }
+  } catch {
+    // Malformed payloads are reported as raw returnData only
+  }
+  return undefined;
+}
@@ -0,0 +1,55 @@
+import { summarize } from './summary';
+import { TransactionType } from './types';
+
+describe('summarize', () => {
+  const twoEth = {
+    token: 'native',
+    amount: '2000000000000000000',
+    symbol: 'ETH',
+    decimals: 18,
+    normalized: '2.0',
+  };
+
+  it('should name the amount in whole units', () => {
+    expect(summarize(TransactionType.STAKE, 'Lido', twoEth)).toBe(
+      'You are staking 2 ETH with Lido',
+    );
+    expect(
+      summarize(TransactionType.UNSTAKE, 'Lido', {
+        ...twoEth,
+        normalized: '1.5',
+      }),
+    ).toBe('You are unstaking 1.5 ETH from Lido');
+  });
+
+  it('should name the amount as formatted for the locale', () => {
+    expect(
+      summarize(TransactionType.STAKE, 'Lido', {
+        ...twoEth,
+        normalized: '1234.5',
+        formatted: '1.234,5',
+      }),
+    ).toBe('You are staking 1.234,5 ETH with Lido');
+  });
+
+  it('should leave out amounts whose decimals are unknown', () => {
+    expect(
+      summarize(TransactionType.SUPPLY, 'Morpho vault', {
+        token: '0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48',
+        amount: '1000000',
+      }),
+    ).toBe('You are depositing into Morpho vault');
+    expect(summarize(TransactionType.CLAIM_REWARDS, 'Cosmos Hub')).toBe(
+      'You are claiming rewards from Cosmos Hub',
+    );
+  });
+
+  it('should fall back to the transaction type', () => {
+    expect(summarize(TransactionType.SPLIT, 'Solana native staking')).toBe(
+      'You are signing a split transaction for Solana native staking',
+    );
+    expect(summarize(TransactionType.UNLOCK, 'Jito')).toBe(
+      'You are signing an unlock transaction for Jito',
+    );
+  });
+});
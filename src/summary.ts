@@ -0,0 +1,92 @@
+import { TransactionAmount, TransactionType } from './types';
+import { isDefined } from './utils/validation';
+
+interface Action {
+  verb: string;
+  what?: string; // Stands in for the amount when it is unknown
+  preposition: string;
+}
+
+const ACTIONS: Partial<Record<TransactionType, Action>> = {
+  [TransactionType.STAKE]: { verb: 'staking', preposition: 'with' },
+  [TransactionType.UNSTAKE]: { verb: 'unstaking', preposition: 'from' },
+  [TransactionType.CLAIM_UNSTAKED]: {
+    verb: 'claiming',
+    what: 'unstaked funds',
+    preposition: 'from',
+  },
+  [TransactionType.CLAIM_REWARDS]: {
+    verb: 'claiming',
+    what: 'rewards',
+    preposition: 'from',
+  },
+  [TransactionType.RESTAKE_REWARDS]: {
+    verb: 'restaking',
+    what: 'rewards',
+    preposition: 'with',
+  },
+  [TransactionType.APPROVAL]: {
+    verb: 'approving',
+    what: 'tokens',
+    preposition: 'for',
+  },
+  [TransactionType.SUPPLY]: { verb: 'depositing', preposition: 'into' },
+  [TransactionType.DEPOSIT]: { verb: 'depositing', preposition: 'into' },
+  [TransactionType.WITHDRAW]: { verb: 'withdrawing', preposition: 'from' },
+  [TransactionType.WITHDRAW_ALL]: {
+    verb: 'withdrawing',
+    what: 'everything',
+    preposition: 'from',
+  },
+  [TransactionType.WRAP]: { verb: 'wrapping', preposition: 'for' },
+  [TransactionType.UNWRAP]: { verb: 'unwrapping', preposition: 'from' },
+  [TransactionType.SWAP]: { verb: 'swapping', preposition: 'through' },
+  [TransactionType.BRIDGE]: {
+    verb: 'bridging',
+    what: 'tokens',
+    preposition: 'to',
+  },
+  [TransactionType.LOCK]: { verb: 'locking', preposition: 'in' },
+  [TransactionType.REBOND]: { verb: 'restaking', preposition: 'with' },
+  [TransactionType.RESTAKE]: { verb: 'restaking', preposition: 'with' },
+  [TransactionType.TRANSFER]: { verb: 'sending', preposition: 'to' },
+  [TransactionType.VOTE]: {
+    verb: 'choosing',
+    what: 'validators',
+    preposition: 'for',
+  },
+};
+
+/**
+ * A sentence describing what a transaction of type does, e.g. "You are
+ * staking 2 ETH with Lido". The amount is only named when its symbol and
+ * decimals are known, as formatted for the request's locale when it is.
+ * Summaries are for display: their wording may change between releases,
+ * so logic should rely on the structured fields.
+ */
+export function summarize(
+  type: TransactionType,
+  yieldName: string,
+  amount?: TransactionAmount,
+): string {
+  const action = ACTIONS[type];
+  if (!isDefined(action)) {
+    const words = type.toLowerCase().replace(/_/g, ' ');
+    const article = /^[aeiou]/.test(words) ? 'an' : 'a';
+    return `You are signing ${article} ${words} transaction for ${yieldName}`;
+  }
+
+  const number =
+    amount?.formatted ?? amount?.normalized?.replace(/\.0$/, ''); // 2.0 as 2
+  const what =
+    isDefined(number) && isDefined(amount?.symbol)
+      ? `${number} ${amount.symbol}`
+      : action.what;
+  return [
+    'You are',
+    action.verb,
+    ...(isDefined(what) ? [what] : []),
+    action.preposition,
+    yieldName,
+  ].join(' ');
+}
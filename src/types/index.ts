@@ -1,17 +1,1058 @@
 export interface ValidationResult {
   isValid: boolean;
-  reason?: string;
+  reason?: string; // Display string; its wording may change between releases
+  reasonCode?: ReasonCode; // Set whenever isValid is false
+  // reason and reasonCode as one message, with structuredMessages
+  reasonMessage?: LocalizedMessage;
   details?: {
     yieldId?: string;
     matchedTypes?: TransactionType[];
     supportedTypes?: TransactionType[];
     warning?: string;
+    expected?: string;
+    actual?: string;
+    error?: string;
+    subCall?: number; // Index of the offending multicall sub-call
+    positionId?: string; // The position of CLAIM_POSITION_NOT_OWNED
+    warningCodes?: WarningCode[];
     attempts?: {
       type?: TransactionType;
       reason?: string;
     }[];
+    // Set with ALL_CANDIDATES_FAILED: why each candidate yield failed
+    candidates?: {
+      yieldId: string;
+      reason?: string;
+      reasonCode?: ReasonCode;
+    }[];
   };
   detectedType?: TransactionType;
+  // Set when validated against candidate yields: the one that passed
+  yieldId?: string;
+  // Set with detectedType: every action the transaction takes, in order.
+  // A multicall lists each of its calls, approvals included, and its
+  // detectedType is the last action
+  detectedTypes?: TransactionType[];
+  // Contract the transaction was matched against, when it calls exactly one
+  expectedRecipient?: string;
+  // Set instead of expectedRecipient when several contracts are called
+  expectedRecipients?: string[];
+  // Set when contractOverrides moved the yield's contract to the address
+  // the transaction is sent to
+  contractOverride?: ContractOverride;
+  // Set when the contracts' age was checked: the deployment of each
+  contractDeployments?: Record<string, ContractDeployment>;
+  warnings?: ValidationWarning[];
+  riskScore?: number;
+  riskLevel?: RiskLevel;
+  decoded?: DecodedTransaction;
+  // Only set when simulation was requested
+  simulation?: SimulationResult;
+  // Set when the validated call was executed through a multisig wallet or
+  // relayed through an ERC-2771 forwarder
+  wrapper?: TransactionWrapper;
+  // Set when SafeTx typed data was validated: the hash its owners sign
+  safeTxHash?: string;
+  // Set when the transaction is a multicall: the calls it batches, and
+  // each call's own result, aligned by index
+  multicall?: Multicall;
+  subResults?: ValidationResult[];
+  // Set for matched transactions whose amount Shield can decode
+  amount?: TransactionAmount;
+  // amount less expectedAmount, in base units, when both are known and in
+  // the same token, e.g. '-3' for 3 fewer. Also set within tolerance
+  amountDelta?: string;
+  // Set on claims of positions. positionOwnershipVerified tells whether
+  // userAddress was checked to own each, which takes positionOwners
+  claimedPositions?: ClaimedPositions;
+  positionOwnershipVerified?: boolean;
+  // Set on multicalls that claim positions: each position, the call that
+  // claims it and, once verified, its owner
+  positions?: ClaimedPosition[];
+  // Set on a transaction that bridges to the yield's chain to stake there:
+  // the bridge call, and the result of each call its message makes there
+  bridgeLeg?: BridgeLeg;
+  stakingLeg?: ValidationResult[];
+  // Set when an EVM transaction carries no calldata: a plain transfer,
+  // detected as TRANSFER
+  emptyCalldata?: boolean;
+  // Set when an RLP-encoded transaction was validated: what it decoded to
+  transaction?: RawTransactionFields;
+  // Set when that transaction was signed. recoveredAddress is its signer,
+  // unset when signatureValid is false. A signed Cosmos or Solana
+  // transaction also lists every signer in recoveredAddresses, its fee
+  // payer first
+  recoveredAddress?: string;
+  recoveredAddresses?: string[];
+  signatureValid?: boolean;
+  // Only set when includeTiming was requested
+  timing?: ValidationTiming;
+  // Set when expectedRecipientEns, or an ENS name given as the transaction's
+  // to, resolved to the contract the transaction calls
+  resolvedRecipient?: ResolvedRecipient;
+  // Set with detectedType: the yield's display name, and a sentence for
+  // users that is not meant to be parsed, e.g. "You are staking 2 ETH with
+  // Lido"
+  yieldName?: string;
+  summary?: string;
+  // Set with detectedType: the yield validated against, e.g. Lido's name,
+  // protocol 'lido' and network 'ethereum'
+  yield?: YieldInfo;
+  // Set on valid results with detectedType: the rule the transaction
+  // matched, for logging why it was trusted
+  matchedRule?: MatchedRule;
+  // Set when the request pinned its state to a block: that block's number
+  blockNumber?: number;
+  // Set on valid results: what identifies the transaction across encodings,
+  // as computeFingerprint describes, e.g. for deduplicating submissions
+  fingerprint?: string;
+  memo?: string; // Set when the transaction carries one, e.g. on Cosmos
+  // Set for permits, and transactions that carry a deadline, e.g. a Permit2
+  // Proxy call: when the signature or allowance stops being usable
+  deadline?: Deadline;
+  // Set for vote-escrow locks that set an unlock time
+  lock?: TransactionLock;
+  // Set on compounds: the rewards claimed, when args.amount names them, and
+  // what is restaked. Also on multicalls that claim and restake, summed
+  // over their calls
+  compound?: CompoundAmounts;
+  // Only set in observe mode, where isValid is always true: whether the
+  // transaction would have been rejected, and with what reason
+  wouldReject?: boolean;
+  wouldRejectReason?: string;
+  wouldRejectReasonCode?: ReasonCode;
+  // The locale messages are written in, when the request named one
+  locale?: string;
+}
+
+// One check of an explained validation, in the order Shield ran it
+export interface ExplainEntry {
+  check: string; // e.g. 'sender', or 'transaction-type:STAKE' per attempt
+  status: 'pass' | 'fail' | 'warn' | 'skip';
+  detail: string;
+}
+
+export interface ExplainResult extends ValidationResult {
+  trace: ExplainEntry[];
+}
+
+/**
+ * The rule a valid transaction matched. id is stable across releases, to
+ * log and grep, e.g. 'ethereum-eth-lido-staking:STAKE:submit(address)';
+ * description is a sentence for people and may change.
+ */
+export interface MatchedRule {
+  id: string;
+  description: string;
+}
+
+// A contract of contractOverrides: the one the registry lists, and the
+// address it was overridden with
+export interface ContractOverride {
+  registered: string;
+  address: string;
+}
+
+export interface Deadline {
+  timestamp: string; // Unix seconds
+  // Unset for deadlines past the year 275760, e.g. max uint256 for "never"
+  iso?: string;
+}
+
+// The unlock time of a vote-escrow lock a transaction creates or extends,
+// as a validator reads it, with the lock durations its yield allows
+export interface LockTerms {
+  unlockTime: string; // Unix seconds, rounded as the escrow rounds it
+  minLockSeconds: number;
+  maxLockSeconds: number;
+  lockPeriodSeconds: number; // What unlock times are rounded down to
+}
+
+export interface TransactionLock {
+  unlockTime: string; // Unix seconds
+  // Unset for unlock times past the year 275760
+  unlockTimeIso?: string;
+  durationSeconds: number; // From when the transaction was validated
+  minLockSeconds: number;
+  maxLockSeconds: number;
+}
+
+export interface ResolvedRecipient {
+  name: string; // e.g. 'lido.eth'
+  address: string; // What name resolves to
+}
+
+/**
+ * Where a validation spent its time, in milliseconds, and the code path
+ * that matched the transaction, e.g. evm-abi-match or evm-safe-wrapper.
+ */
+export interface ValidationTiming {
+  decodeMs: number;
+  matchMs: number;
+  simulateMs?: number; // Only set when the transaction was simulated
+  totalMs: number;
+  path: string;
+}
+
+/**
+ * The fields of an RLP-encoded EVM transaction, in the shape of an
+ * unsignedTransaction. Quantities are decimal strings.
+ */
+export interface RawTransactionFields {
+  type: number; // 0 legacy, 1 EIP-2930, 2 EIP-1559, 4 EIP-7702
+  chainId: number;
+  nonce: number;
+  to: string | null; // null for contract creation
+  // Recovered from the signature, or the request's userAddress if unsigned
+  from?: string;
+  value: string;
+  data: string;
+  gasLimit: string;
+  gasPrice?: string;
+  maxFeePerGas?: string;
+  maxPriorityFeePerGas?: string;
+  accessList?: AccessListEntry[];
+  authorizationList?: SignedAuthorization[];
+}
+
+/**
+ * The signatures a Cosmos or Solana transaction carries. signers are the
+ * accounts whose signatures verify, the fee payer first; error says why
+ * one does not, or could not be verified.
+ */
+export interface TransactionSignatures {
+  signed: boolean;
+  signers: string[];
+  error?: string;
+}
+
+/**
+ * The multisig wallet or ERC-2771 forwarder call a transaction's validated
+ * inner call is made through. detectedType of the result is the inner
+ * call's.
+ */
+export interface TransactionWrapper {
+  detectedType: 'SAFE_EXEC_TRANSACTION' | 'ERC2771_FORWARD';
+  // The wallet, which is also the inner call's sender, or the forwarder
+  address: string;
+  operation: 'CALL' | 'DELEGATECALL';
+  // Of forwarded calls: who sends the transaction and pays for its gas, if
+  // it names them, and whom the forward request's signature recovers to,
+  // null when it does not recover
+  relayer?: string;
+  signer?: string | null;
+}
+
+export interface WrappedTransaction {
+  // The inner call, sent by the wallet, or by the forward request's from
+  // as the contract called sees it
+  unsignedTransaction: string;
+  wrapper: TransactionWrapper;
+}
+
+/**
+ * The transaction an EIP-712 SafeTx lets a Safe execute once its owners
+ * confirm it.
+ */
+export interface SafeTransaction {
+  unsignedTransaction: string; // The Safe's execTransaction, unsigned
+  safeTxHash: string; // The EIP-712 hash the owners sign
+}
+
+/**
+ * A batch of calls made through Multicall3's aggregate functions, which
+ * send each call from the Multicall3 contract, or a contract's own
+ * multicall(bytes[]), which calls itself on behalf of the sender.
+ */
+export interface Multicall {
+  detectedType: 'MULTICALL3_AGGREGATE' | 'MULTICALL';
+  address: string; // The contract the transaction calls
+  functionName: string; // e.g. aggregate3
+  value: string; // Base units the transaction sends along
+  calls: MulticallCall[];
+}
+
+export interface MulticallCall {
+  target: string;
+  value: string; // Base units the call is made with
+  data: string;
+  allowFailure: boolean; // Whether the batch goes on if the call reverts
+}
+
+export interface MulticallTransaction {
+  multicall: Multicall;
+  // Each call as an unsignedTransaction, sent by whoever makes it
+  unsignedTransactions: string[];
+}
+
+export enum RiskLevel {
+  LOW = 'LOW',
+  MEDIUM = 'MEDIUM',
+  HIGH = 'HIGH',
+}
+
+/**
+ * A non-blocking concern about a transaction. A transaction can be valid
+ * and still carry warnings.
+ */
+export interface ValidationWarning {
+  code: WarningCode;
+  message: string;
+  // message in the requested locale, with structuredMessages and a locale
+  messageLocalized?: string;
+  details?: Record<string, unknown>;
+  // How much the warning matters, by its code. Set on every warning Shield
+  // returns
+  severity?: WarningSeverity;
+}
+
+/**
+ * A reason or error as structuredMessages reports it: its stable code, its
+ * English message, and that message in the requested locale, when one was
+ * requested.
+ */
+export interface LocalizedMessage {
+  code: string;
+  message: string;
+  messageLocalized?: string;
+}
+
+// info is worth knowing, warning worth a second look, and critical worth
+// stopping for, e.g. an INFINITE_APPROVAL
+export type WarningSeverity = 'info' | 'warning' | 'critical';
+
+export type WarningCode =
+  | 'INFINITE_APPROVAL'
+  | 'HIGH_GAS_LIMIT'
+  | 'UNKNOWN_RECIPIENT'
+  | 'SENDER_NOT_VERIFIED'
+  | 'LONG_DEADLINE'
+  | 'ACCESS_LIST_UNEXPECTED_ADDRESS'
+  | 'UNKNOWN_PAYMASTER'
+  | 'DELEGATECALL_USED'
+  | 'NONCE_TOO_LOW' // Below the sender's next nonce: replaces or fails
+  | 'NONCE_GAP' // Above it: stuck until the nonces in between are used
+  | 'EIP7702_DELEGATION' // Hands the user's account to a contract's code
+  | 'LOW_SLIPPAGE_PROTECTION' // Its minimum output invites sandwiching
+  | 'UNPROTECTED_REPLAY' // Valid on every chain, as it binds to none
+  | 'IMPLEMENTATION_CHANGE' // Upgrades a proxy the yield expects to upgrade
+  | 'UNSTAKE_NEAR_FULL' // Unstakes all but a sliver of the balance
+  | 'LOCK_MAXED' // Locks for as long as the escrow allows
+  | 'UNKNOWN_YIELD' // With lenientUnknownYield, on a yieldId not known
+  // Stakes into a yield the registry marks as paused or deprecated
+  | 'YIELD_PAUSED'
+  | 'YIELD_DEPRECATED'
+  | 'CONTRACT_PAUSED' // A contract called reports paused() on-chain
+  // A contract called is younger than policy.minContractAgeSeconds
+  | 'RECENTLY_DEPLOYED_CONTRACT'
+  // A wallet_sendCalls capability Shield does not know the effect of
+  | 'UNKNOWN_CAPABILITY'
+  // Approves in a batch the wallet may execute call by call
+  | 'NON_ATOMIC_BATCH';
+
+/**
+ * Why a result is invalid, as a stable code to switch on. reason carries
+ * the matching display string.
+ */
+export type ReasonCode =
+  | 'INVALID_REQUEST'
+  | 'YIELD_NOT_FOUND'
+  | 'YIELD_PAUSED' // The yield currently refuses the transaction type
+  // The transaction calls a function of another yield's transaction type,
+  // e.g. an ERC-4626 deposit sent for a Lido yield
+  | 'OPERATION_NOT_SUPPORTED_FOR_YIELD'
+  | 'CHAIN_ID_MISMATCH'
+  | 'MALFORMED_TRANSACTION'
+  | 'MALFORMED_NUMERIC' // A quantity is neither a decimal nor a 0x integer
+  | 'MALFORMED_RAW_TRANSACTION' // rawTransaction is not RLP Shield can decode
+  | 'INVALID_GAS_FIELDS'
+  | 'SENDER_MISMATCH'
+  | 'SIGNATURE_INVALID' // Signed rawTransaction with no recoverable signer
+  | 'SIGNATURE_SENDER_MISMATCH' // Its recovered signer is not userAddress
+  | 'APPROVAL_SPENDER_MISMATCH'
+  | 'APPROVAL_INSUFFICIENT_FOR_DEPOSIT'
+  | 'APPROVAL_EXCEEDS_STAKE' // A flow approves more than it then pulls
+  | 'UNEXPECTED_PROXY_UPGRADE' // Changes a proxy's implementation
+  | 'PRIVILEGED_FUNCTION_CALL' // Calls an owner- or admin-only function
+  | 'REWARD_RECIPIENT_MISMATCH'
+  | 'WITHDRAWAL_RECIPIENT_MISMATCH'
+  | 'AMOUNT_MISMATCH'
+  | 'INTENT_MISMATCH' // The transaction does not carry out the intent
+  | 'NONCE_MISMATCH'
+  | 'NONCE_CHECK_FAILED' // The sender's nonce could not be fetched
+  | 'RECIPIENT_ENS_MISMATCH' // An ENS name resolves to another recipient
+  | 'ENS_RESOLUTION_FAILED' // An ENS name could not be looked up
+  | 'RECIPIENT_NOT_A_CONTRACT' // Calldata sent to an address without code
+  | 'CODE_CHECK_FAILED' // A called contract's code could not be fetched
+  | 'BYTECODE_MISMATCH' // The recipient's code hash is not the pinned one
+  | 'UNSTAKE_EXCEEDS_BALANCE' // Unstakes more than the user has staked
+  | 'BALANCE_CHECK_FAILED' // The staked balance could not be fetched
+  | 'CLAIM_POSITION_NOT_OWNED' // Claims a position userAddress does not own
+  | 'POSITION_CHECK_FAILED' // A claimed position's owner could not be read
+  // A versioned Solana transaction loads accounts from lookup tables that
+  // were not given
+  | 'ALT_RESOLUTION_REQUIRED'
+  | 'ALT_RESOLUTION_FAILED' // A lookup table could not be fetched
+  | 'MISSING_MEMO' // An expectedMemo was given, the transaction has none
+  | 'MEMO_MISMATCH'
+  | 'UNEXPECTED_NATIVE_VALUE' // Value sent to a function that is not payable
+  | 'CALLDATA_TOO_LARGE'
+  | 'SELECTOR_NOT_ALLOWED' // Matched, but not with a function of its type
+  | 'BRIDGE_DESTINATION_MISMATCH' // Bridges to another chain than the yield's
+  | 'BRIDGE_RECIPIENT_MISMATCH' // Bridged funds can reach another account
+  | 'BRIDGE_MESSAGE_INVALID' // Carries no staking calls Shield can decode
+  // An EIP-7702 authorization delegates to a contract outside the yield's
+  // delegationTargets, or is signed by another account than the user
+  | 'DELEGATION_TARGET_NOT_ALLOWED'
+  | 'AUTHORITY_MISMATCH'
+  // A stake or deposit credits another account than beneficiaryAddress, or
+  // the user when none was given
+  | 'BENEFICIARY_MISMATCH'
+  | 'DEADLINE_IN_PAST' // A permit or transaction deadline has passed
+  | 'LOCK_DURATION_OUT_OF_RANGE' // A lock the yield's bounds do not allow
+  // No transaction type matched because the transaction calls a contract
+  // the yield does not use, or a function none of its ABIs declare
+  | 'RECIPIENT_MISMATCH'
+  | 'SELECTOR_MISMATCH'
+  // A stake, lock or approval of a token the yield does not take, such as a
+  // lookalike of its own, or of an ERC-20 for a native-token yield
+  | 'TOKEN_MISMATCH'
+  // A transfer without calldata to a contract that does not stake it
+  | 'NAKED_TRANSFER_NOT_SUPPORTED'
+  | 'CONTRACT_TYPE_NOT_SUPPORTED' // A Tron contract type no yield type uses
+  | 'PALLET_NOT_ALLOWED' // A Substrate call outside staking and utility
+  | 'MODULE_NOT_ALLOWED' // An Aptos entry function outside delegation_pool
+  // A Cosmos message targets a validator args.validatorAddresses leaves out
+  | 'UNEXPECTED_VALIDATOR'
+  // A compound restakes with a validator it does not claim from, or more
+  // than the rewards it claims
+  | 'COMPOUND_DESTINATION_MISMATCH'
+  | 'NO_MATCHING_PATTERN' // No type matched for another reason
+  | 'AMBIGUOUS_PATTERN'
+  | 'NESTED_MULTISIG'
+  // A forwarded call through a forwarder policy.trustedForwarders leaves
+  // out, or whose forward request its from did not sign
+  | 'UNTRUSTED_FORWARDER'
+  | 'FORWARD_SIGNATURE_MISMATCH'
+  | 'MULTICALL_CALL_MISSING' // A multicall without a call beyond approvals
+  | 'MULTICALL_CALL_INVALID' // One of its calls fails validation
+  | 'MULTICALL_VALUE_MISMATCH' // Multicall3 keeps value no call is sent
+  | 'CONTRACT_BLOCKED'
+  | 'CONTRACT_NOT_ALLOWED'
+  | 'DELEGATECALL_BLOCKED'
+  | 'AMOUNT_ABOVE_LIMIT' // Moves more than the policy's maxAmount
+  | 'AMOUNT_BELOW_MINIMUM' // Moves less than the policy's minAmount
+  | 'RISK_THRESHOLD_EXCEEDED'
+  | 'STRICT_MODE_WARNING'
+  | 'FLOW_STEP_INVALID'
+  | 'ALL_CANDIDATES_FAILED' // No candidate yield validates the transaction
+  | 'UNSUPPORTED_ACCOUNT_CALL'
+  | 'TYPED_DATA_INVALID'
+  | 'SIMULATION_UNSUPPORTED'
+  | 'SIMULATION_FAILED'
+  | 'SIMULATION_REVERTED'
+  | 'SIMULATION_NO_BALANCE_CHANGE'
+  | 'INTERNAL_ERROR';
+
+/**
+ * What Shield understands a transaction to be, independent of whether it
+ * passes validation. Purely informational.
+ */
+export interface DecodedTransaction {
+  // EVM contract calls
+  functionName?: string;
+  selector?: string;
+  // Canonical, e.g. 'submit(address)', or 'unknown' when no ABI Shield
+  // knows has a function for selector
+  functionSignature?: string;
+  args?: DecodedArgument[];
+  // The same by name, or by position where the ABI names none
+  decodedArgs?: Record<string, unknown>;
+  // Solana transactions, in execution order
+  instructions?: DecodedInstruction[];
+  // Cosmos SDK transactions, in execution order
+  messages?: DecodedMessage[];
+  // Matched Cosmos stakes: what each MsgDelegate delegates, and to whom
+  delegations?: ValidatorDelegation[];
+  // NEAR transactions, in execution order
+  actions?: DecodedAction[];
+  // Substrate extrinsics, batches flattened, in execution order
+  calls?: DecodedCall[];
+  // Aptos transactions, which make a single entry function call
+  entryFunction?: DecodedEntryFunction;
+  // Bitcoin transactions, in output order
+  outputs?: DecodedOutput[];
+  // ERC-20 approve(spender, amount) calls
+  approval?: TokenApproval;
+  // Calls that change the implementation of a proxy
+  implementationChange?: ImplementationChange;
+  // Matched CLAIM_REWARDS and CLAIM_UNSTAKED transactions
+  recipient?: ClaimRecipient;
+  // Matched unstake and withdraw calls that name who they pay
+  withdrawal?: Withdrawal;
+  // Matched claims of withdrawal NFTs, e.g. Lido withdrawal request IDs
+  requestIds?: string[];
+  // Permit2 PermitSingle and PermitBatch typed data
+  permit2?: Permit2Permit;
+  // EIP-2930 access list of type 1, 2 and 4 EVM transactions
+  accessList?: AccessListEntry[];
+  // EIP-7702 authorizations of type 4 EVM transactions
+  authorizationList?: Delegation[];
+  // Native value EVM contract calls send, in base units
+  value?: string;
+  calldataBytes?: number; // Length of EVM calldata
+  // Swaps, and zaps that swap before staking, that bound their output
+  slippage?: SwapSlippage;
+  detectedType?: TransactionType;
+}
+
+/**
+ * What a swap puts in and the least it accepts back, in base units. A call
+ * quoted at an output, such as Rocket Pool's swapTo, names it as
+ * expectedAmountOut. Tokens are addresses, or 'native'.
+ */
+export interface SwapSlippage {
+  inputToken: string;
+  inputAmount: string;
+  outputToken: string;
+  minAmountOut: string;
+  expectedAmountOut?: string;
+}
+
+/**
+ * A Permit2 allowance signature: spender may pull each of details' tokens
+ * until its expiration, if the signature is used by sigDeadline. Amounts
+ * and times are decimal strings, times in Unix seconds.
+ */
+export interface Permit2Permit {
+  spender: string;
+  sigDeadline: string;
+  details: Permit2Details[]; // One for PermitSingle
+}
+
+export interface Permit2Details {
+  token: string;
+  amount: string; // Base units
+  expiration: string; // 0 for the block the permit is used in
+  nonce: string;
+  isUnlimited: boolean; // The maximum uint160 allowance
+}
+
+/**
+ * Who a claim pays out to. implicit is set when the call takes no recipient
+ * and the protocol pays its sender, so the recipient check holds trivially.
+ */
+export interface ClaimRecipient {
+  address: string;
+  implicit: boolean;
+  variant: string; // The claim called, e.g. getReward(address)
+}
+
+/**
+ * The claim a transaction makes: the function or message it calls, e.g.
+ * getReward(address), and the address it names to pay, null when it names
+ * none and the protocol pays its sender.
+ */
+export interface ClaimCall {
+  variant: string;
+  recipient: string | null;
+}
+
+/**
+ * What an unstake or withdraw call pays out, and to whom. phase is REQUEST
+ * for the first step of a delayed withdrawal, which a CLAIM_UNSTAKED
+ * transaction completes, and WITHDRAW for calls that pay out at once.
+ */
+export interface Withdrawal {
+  phase: 'REQUEST' | 'WITHDRAW';
+  recipient: string;
+  token: string; // The token given up, e.g. stETH or vault shares
+  amount: string; // Base units of token
+}
+
+export interface AccessListEntry {
+  address: string;
+  storageKeys: string[]; // 32-byte hex slots
+}
+
+/**
+ * An EIP-7702 authorization as a type 4 transaction carries it. Whoever
+ * signed it, its authority, has their account run address's code from then
+ * on, until another authorization replaces it.
+ */
+export interface SignedAuthorization {
+  chainId: string | number; // 0 for any chain
+  address: string;
+  nonce: string | number;
+  yParity: number;
+  r: string;
+  s: string;
+}
+
+/**
+ * A decoded EIP-7702 authorization. Quantities are decimal strings.
+ */
+export interface Delegation {
+  chainId: string;
+  address: string; // The contract the authority delegates to
+  nonce: string;
+  authority: string | null; // null when the signature recovers no address
+}
+
+export interface DecodedInstruction {
+  programId: string;
+  program?: string; // Set for well-known programs, e.g. 'Stake'
+  instructionType: string;
+  accounts: string[];
+}
+
+export interface DecodedArgument {
+  name: string;
+  type: string;
+  value: unknown; // JSON-safe: integers are decimal strings
+}
+
+export interface DecodedMessage {
+  typeUrl: string; // e.g. '/cosmos.staking.v1beta1.MsgDelegate'
+  delegatorAddress?: string;
+  validatorAddress?: string; // Source validator for MsgBeginRedelegate
+  validatorDstAddress?: string;
+  amount?: { denom: string; amount: string };
+}
+
+export interface CompoundAmounts {
+  claimed?: TransactionAmount;
+  restaked: TransactionAmount;
+}
+
+export interface ValidatorDelegation {
+  messageIndex: number;
+  validatorAddress: string;
+  amount: TransactionAmount;
+}
+
+export interface DecodedAction {
+  type: string; // e.g. 'FunctionCall' or 'Transfer'
+  methodName?: string; // FunctionCall only, as are args and gas
+  args?: Record<string, unknown>;
+  gas?: string;
+  deposit?: string; // yoctoNEAR, as a decimal string
+}
+
+export interface DecodedOutput {
+  value: string; // Satoshis, as a decimal string
+  script: string; // The scriptPubKey, as hex
+}
+
+export interface DecodedCall {
+  pallet: string; // e.g. 'staking'
+  method: string; // e.g. 'bond'
+  args: Record<string, unknown>; // Amounts as decimal strings, accounts SS58
+}
+
+export interface DecodedEntryFunction {
+  module: string; // e.g. '0x…01::delegation_pool', its address in long form
+  functionName: string; // e.g. 'add_stake'
+  typeArguments: string[];
+  arguments: unknown[]; // As given, u64 amounts as decimal strings
+}
+
+export interface TokenApproval {
+  token: string; // Contract whose allowance is set
+  spender: string;
+  amount: string; // Base units, as a decimal string
+  isUnlimited: boolean; // At or near 2^256-1
+}
+
+/**
+ * A call that points a proxy at new code: an EIP-1967 or UUPS upgradeTo or
+ * upgradeToAndCall on the proxy itself, or a ProxyAdmin upgrade or
+ * upgradeAndCall for a TransparentUpgradeableProxy.
+ */
+export interface ImplementationChange {
+  proxy: string; // Contract whose code changes
+  implementation: string; // What it runs from then on
+  functionName: string; // e.g. 'upgradeToAndCall'
+}
+
+/**
+ * A call to a function only a contract's owner or an admin role may make,
+ * such as pausing it or handing over its ownership.
+ */
+export interface PrivilegedCall {
+  contract: string; // Contract the function belongs to
+  functionName: string; // e.g. 'transferOwnership'
+  signature: string; // e.g. 'transferOwnership(address)'
+  selector: string;
+}
+
+/**
+ * Tokens a transaction pulls from the user under an existing allowance,
+ * e.g. an ERC-4626 deposit.
+ */
+export interface TokenSpend {
+  token: string;
+  spender: string; // Contract that calls transferFrom
+  amount: string; // Base units, as a decimal string
+}
+
+/**
+ * eth_call parameters for a transaction. Quantities are hex strings.
+ */
+export interface SimulationCall {
+  from?: string;
+  to: string;
+  data?: string;
+  value?: string;
+  gas?: string;
+}
+
+/**
+ * A bridge call that carries a message for the destination chain, e.g. an
+ * Across depositV3 whose MulticallHandler stakes what it bridges. Chain IDs
+ * and amounts are decimal strings; fillDeadline is in unix seconds.
+ */
+export interface BridgeLeg {
+  protocol: 'across';
+  contract: string; // The bridge contract called
+  sourceChainId: string;
+  destinationChainId: string;
+  depositor: string; // Refunded when the deposit is not filled in time
+  recipient: string; // Receives outputToken, and runs the message
+  inputToken: string;
+  inputAmount: string;
+  outputToken: string;
+  outputAmount: string;
+  fillDeadline: number;
+  fallbackRecipient?: string; // Receives what the message's calls leave
+}
+
+/**
+ * The positions a claim redeems, e.g. Lido withdrawal NFTs: token IDs of
+ * the ERC-721 contract that tracks them, as decimal strings.
+ */
+export interface ClaimedPositions {
+  contract: string;
+  positionIds: string[];
+}
+
+export interface ClaimedPosition {
+  positionId: string;
+  subCall?: number; // Index of the multicall's call that claims it
+  owner?: string; // Set when positionOwners verified it
+}
+
+/**
+ * When a contract was deployed: the first block at which it has code, and
+ * that block's timestamp in unix seconds.
+ */
+export interface ContractDeployment {
+  block: number;
+  timestamp: number;
+}
+
+/**
+ * The eth_call that reads the balance an unstake draws on, which returns
+ * it as a uint256 in the units of amount, and the amount drawn.
+ */
+export interface StakedBalanceCall {
+  call: SimulationCall;
+  amount: string; // Base units, as a decimal string
+}
+
+/**
+ * The outcome of executing a transaction with eth_call against the latest
+ * block. Nothing is broadcast.
+ */
+export interface SimulationResult {
+  success: boolean;
+  // Return data of a successful call, or revert data of a reverted one
+  returnData: string;
+  // Decoded Error(string) or Panic(uint256), when the call reverted with one
+  revertReason?: string;
+  // Tokens the call credits to the user, when they can be read from
+  // returnData
+  balanceChange?: BalanceChange;
+}
+
+/**
+ * A contract function a yield's transactions of transactionType may call.
+ */
+export interface AbiFunction {
+  transactionType: TransactionType;
+  name: string;
+  selector: string; // First 4 bytes of calldata, e.g. '0xa1903eab'
+  signature: string; // Canonical, e.g. 'submit(address)'
+  inputs: { name: string; type: string }[];
+  payable: boolean; // Whether it accepts native value; others revert on any
+}
+
+/**
+ * What a transaction stakes, deposits or otherwise moves from the user.
+ */
+export interface TransactionAmount {
+  token: string; // Token contract or denomination, or 'native'
+  amount: string; // Base units, as a decimal string
+  // Set when Shield knows the token's decimals
+  symbol?: string;
+  decimals?: number;
+  normalized?: string; // amount in whole units, e.g. "1.5"
+  // normalized as the request's locale writes numbers, e.g. "1.234,5" for
+  // 'de-DE'. For display only, and only set when a locale is given
+  formatted?: string;
+}
+
+export interface BalanceChange {
+  token: string; // Contract of the credited token, e.g. the vault share
+  amount: string; // Base units, as a decimal string
+}
+
+/**
+ * The outcome of validating an ordered sequence of transactions for one
+ * yield. steps holds each transaction's own result, in order.
+ */
+export interface FlowValidationResult {
+  isValid: boolean;
+  reason?: string;
+  reasonCode?: ReasonCode;
+  details?: Record<string, unknown>;
+  steps: ValidationResult[];
+}
+
+/**
+ * An ERC-4337 UserOperation for EntryPoint v0.6, as passed to
+ * eth_sendUserOperation. Integers are hex quantities.
+ */
+export interface UserOperation {
+  sender: string; // The smart account
+  nonce: string;
+  initCode?: string;
+  callData: string; // The account's execute or executeBatch call
+  callGasLimit?: string;
+  verificationGasLimit?: string;
+  preVerificationGas?: string;
+  maxFeePerGas?: string;
+  maxPriorityFeePerGas?: string;
+  paymasterAndData?: string;
+  signature?: string;
+}
+
+/**
+ * The outcome of validating a UserOperation. steps holds the result of
+ * each call the account makes, in order; detectedType is the last call's,
+ * e.g. SUPPLY for an approval batched with a deposit, and detectedTypes
+ * lists them all.
+ */
+export interface UserOperationValidationResult extends FlowValidationResult {
+  detectedType?: TransactionType;
+  detectedTypes?: TransactionType[]; // Each step's, in order
+  paymaster?: string; // Set when a paymaster sponsors the gas
+  warnings?: ValidationWarning[];
+}
+
+/**
+ * The params of an EIP-5792 wallet_sendCalls request. Integers are hex
+ * quantities.
+ */
+export interface SendCalls {
+  version?: string; // e.g. '2.0.0'
+  chainId?: string; // e.g. '0x1'
+  from?: string;
+  atomicRequired?: boolean; // All calls land or none do; false when unset
+  calls: SendCallsCall[];
+  capabilities?: Record<string, unknown>;
+}
+
+export interface SendCallsCall {
+  to: string;
+  data?: string;
+  value?: string;
+  capabilities?: Record<string, unknown>;
+}
+
+/**
+ * The outcome of validating a wallet_sendCalls batch. steps holds the
+ * result of each call, in order, and isValid is the batch's verdict;
+ * detectedType and detectedTypes are as for a UserOperation.
+ */
+export interface SendCallsValidationResult extends FlowValidationResult {
+  detectedType?: TransactionType;
+  detectedTypes?: TransactionType[]; // Each step's, in order
+  atomicRequired: boolean;
+  warnings?: ValidationWarning[];
+}
+
+/**
+ * What the user asked for before the transaction was built, e.g. staking
+ * 1 ETH with Lido, for compareIntent.
+ */
+export interface TransactionIntent {
+  action: TransactionType;
+  yieldId: string;
+  amount?: string; // Base units, as a decimal string
+  token?: string; // The token amount is in, e.g. 'native'
+}
+
+/**
+ * How a transaction compares field by field with a TransactionIntent.
+ * match is true when the transaction validates for the intended yield and
+ * every comparison holds; validation is the transaction's own result.
+ */
+export interface IntentComparisonResult {
+  match: boolean;
+  actionMatch: boolean; // Its detected type is intent.action
+  amountMatch: boolean; // It moves exactly intent.amount of intent.token
+  recipientMatch: boolean; // Every contract it calls belongs to the yield
+  reason?: string;
+  reasonCode?: ReasonCode;
+  details?: Record<string, unknown>;
+  validation: ValidationResult;
+}
+
+/**
+ * Whether a transaction of a type would be allowed for a yield, before it
+ * is built. chainId, expectedRecipient and amount are set once the yield is
+ * known, for the builder to use.
+ */
+export interface PreflightResult {
+  allowed: boolean;
+  reason?: string;
+  reasonCode?: ReasonCode;
+  details?: Record<string, unknown>;
+  warnings: ValidationWarning[]; // Always present, empty when none apply
+  chainId?: string; // The yield's, as getYieldCapabilities reports it
+  // The contract to send the transaction to, when the yield sends every
+  // transaction of the type to the same one
+  expectedRecipient?: string;
+  amount?: TransactionAmount; // The amount, in the token the yield stakes
+}
+
+export interface DecodeResult {
+  decoded: DecodedTransaction | null;
+  // Why decoded is null, or only holds the selector with includeSignature
+  reason?: string;
+}
+
+/**
+ * An EIP-712 typed-data payload, as passed to eth_signTypedData_v4.
+ */
+export interface TypedData {
+  domain: TypedDataDomain;
+  types: Record<string, TypedDataField[]>;
+  primaryType: string;
+  message: Record<string, unknown>;
+}
+
+export interface TypedDataDomain {
+  name?: string;
+  version?: string;
+  chainId?: number | string;
+  verifyingContract?: string;
+  salt?: string;
+}
+
+export interface TypedDataField {
+  name: string;
+  type: string;
+}
+
+/**
+ * What a yield accepts, so integrations can build their flows without
+ * hardcoding it.
+ */
+/**
+ * Gas a transaction of one operation typically needs, in gas units.
+ */
+export interface GasLimitRange {
+  min: bigint;
+  max: bigint;
+}
+
+export interface YieldCapabilities {
+  yieldId: string;
+  name: string; // Display name, e.g. 'Lido' or 'Morpho USDC vault'
+  // Lowercase identifiers, as the registry writes them: 'lido', 'morpho',
+  // or 'native' for a network's own staking; 'ethereum', 'arbitrum'
+  protocol: string;
+  network: string;
+  supportedTypes: TransactionType[];
+  // Whether exits may cover part of a position rather than all of it
+  supportsPartialAmounts: boolean;
+  // Decimal EVM chain ID, Cosmos chain ID, or e.g. 'solana-mainnet'
+  chainId: string;
+  contracts: string[]; // Contracts or programs transactions may call
+  status: YieldStatus;
+}
+
+// Whether a yield takes new stakes: a paused one's contracts refuse them
+// for now, a deprecated one's integration is being retired
+export type YieldStatus = 'active' | 'paused' | 'deprecated';
+
+// What validate results name of the yield
+export type YieldInfo = Pick<
+  YieldCapabilities,
+  'name' | 'protocol' | 'network'
+>;
+
+// One entry of getSupportedYields, enough to group yields without a
+// getYieldCapabilities call per yield
+export type SupportedYield = Pick<YieldCapabilities, 'yieldId' | 'chainId'>;
+
+// A yield whose rules a transaction matches, and the type it matches as
+export interface YieldMatch {
+  yieldId: string;
+  detectedType: TransactionType;
+}
+
+export type ValidatorCapabilities = Omit<
+  YieldCapabilities,
+  'yieldId' | 'supportedTypes' | 'status'
+>;
+
+// What checkRegistry finds wrong with a registry
+export type RegistryProblemCode =
+  | 'DUPLICATE_YIELD_ID' // Listed twice in the override
+  | 'YIELD_REPLACED' // An override vault replaces a built-in yield
+  | 'INVALID_CHAIN_ID'
+  | 'NO_CONTRACTS' // An EVM yield with no contract to call
+  | 'INVALID_ADDRESS'
+  | 'INVALID_SELECTOR'; // Not the selector of its function's signature
+
+export interface RegistryProblem {
+  // Errors make a yield validate wrongly or not at all
+  severity: 'error' | 'warning';
+  code: RegistryProblemCode;
+  yieldId: string;
+  message: string;
+}
+
+export interface RegistryCheckResult {
+  isValid: boolean; // Whether no problem is an error
+  yieldCount: number;
+  problems: RegistryProblem[];
+}
+
+/**
+ * Identifies the build that produced a result, for bug reports.
+ */
+export interface VersionInfo {
+  version: string; // Package semver
+  gitCommit: string; // Full commit hash, or 'unknown'
+  buildDate: string; // ISO 8601, or 'unknown'
+  // Request apiVersion values this build accepts, oldest first
+  supportedApiVersions: string[];
+  // Accepted for now, but with an API_VERSION_DEPRECATED warning
+  deprecatedApiVersions: string[];
+  registry: {
+    version: number; // Schema version of the embedded vault registry
+    generatedAt: string; // When the registry snapshot was taken
+    hash: string; // SHA-256 of the registry snapshot
+    yieldCount: number; // Yields this build validates, vaults included
+    // Whether vaults were registered over the embedded registry, and the
+    // SHA-256 of the override when they were
+    overrideActive: boolean;
+    overrideHash?: string;
+  };
 }
 
 export type ActionArguments = {
@@ -40,10 +1081,52 @@ export interface ValidationContext {
   feeConfiguration?: FeeConfiguration[];
 }
 
+/**
+ * Caller-defined contract rules and limits, applied on top of the built-in
+ * validation. Only transactions that already pass validation are checked
+ * against them.
+ */
+export interface ValidationPolicy {
+  allowedContracts?: string[]; // When non-empty, every contract must be listed
+  blockedContracts?: string[]; // No contract may be listed
+  // Reject, rather than warn about, calls made with DELEGATECALL
+  blockDelegateCall?: boolean;
+  // ERC-2771 forwarders the yield's contracts trust, whose forwarded calls
+  // are validated by the call they relay. Left out, none is trusted
+  trustedForwarders?: string[];
+  // Deadlines further out than this add LONG_DEADLINE. Defaults to a day
+  maxDeadlineSeconds?: number;
+  // Contracts called that were deployed more recently than this add
+  // RECENTLY_DEPLOYED_CONTRACT, given contractDeployments. Left out, their
+  // age is not checked
+  minContractAgeSeconds?: number;
+  // Longer calldata fails CALLDATA_TOO_LARGE, valid or not. Defaults to
+  // 8 KiB, or 128 KiB for Safe transactions and multicalls
+  maxCalldataBytes?: number;
+  // Swaps that accept more than this below the output they expect, in
+  // basis points, add LOW_SLIPPAGE_PROTECTION. Defaults to 500, 5%
+  maxSlippageBps?: number;
+  // Flows and multicalls that approve more than they then pull, beyond
+  // this share of the pull in basis points, fail APPROVAL_EXCEEDS_STAKE.
+  // Left out, any excess is allowed
+  maxApprovalExcessBps?: number;
+  // Limits on the amount a transaction moves, by yieldId. Transactions
+  // that move no amount are not checked
+  amountLimits?: Record<string, AmountLimits>;
+}
+
+// In whole units of the token moved, e.g. "32" or "0.5"
+export interface AmountLimits {
+  minAmount?: string; // Less fails AMOUNT_BELOW_MINIMUM
+  maxAmount?: string; // More fails AMOUNT_ABOVE_LIMIT
+}
+
 export enum TransactionType {
   SWAP = 'SWAP',
   DEPOSIT = 'DEPOSIT',
   APPROVAL = 'APPROVAL',
+  PERMIT = 'PERMIT',
+  PERMIT2 = 'PERMIT2',
   STAKE = 'STAKE',
   CLAIM_UNSTAKED = 'CLAIM_UNSTAKED',
   CLAIM_REWARDS = 'CLAIM_REWARDS',
@@ -77,6 +1160,8 @@ export enum TransactionType {
   UNFREEZE_ENERGY = 'UNFREEZE_ENERGY',
   FREEZE_BANDWIDTH = 'FREEZE_BANDWIDTH',
   FREEZE_ENERGY = 'FREEZE_ENERGY',
+  DELEGATE_BANDWIDTH = 'DELEGATE_BANDWIDTH',
+  DELEGATE_ENERGY = 'DELEGATE_ENERGY',
   UNDELEGATE_BANDWIDTH = 'UNDELEGATE_BANDWIDTH',
   UNDELEGATE_ENERGY = 'UNDELEGATE_ENERGY',
   P2P_NODE_REQUEST = 'P2P_NODE_REQUEST',
@@ -91,6 +1176,7 @@ export enum TransactionType {
   INFSTONES_PROVISION = 'INFSTONES_PROVISION',
   INFSTONES_EXIT_REQUEST = 'INFSTONES_EXIT_REQUEST',
   INFSTONES_CLAIM_REQUEST = 'INFSTONES_CLAIM_REQUEST',
+  TRANSFER = 'TRANSFER',
 }
 
 export enum TronResourceType {
@@ -0,0 +1,89 @@
+import { ethers } from 'ethers';
+import { decodeAccountCalls, getPaymaster } from './user-operation';
+
+describe('decodeAccountCalls', () => {
+  const to = '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84';
+  const other = '0x889edC2eDab5f40e902b864aD4d7AdE8E412F9B1';
+  const iface = new ethers.Interface([
+    'function execute(address dest, uint256 value, bytes func)',
+    'function executeBatch(address[] dest, bytes[] func)',
+    'function executeBatch(address[] dest, uint256[] value, bytes[] func)',
+    'function executeBatch((address target, uint256 value, bytes data)[] calls)',
+  ]);
+
+  it('should decode a single execute call', () => {
+    const callData = iface.encodeFunctionData('execute', [to, 10n, '0x1234']);
+
+    expect(decodeAccountCalls(callData)).toEqual([
+      { to, value: '0xa', data: '0x1234' },
+    ]);
+  });
+
+  it('should decode SimpleAccount v0.6 batches', () => {
+    const callData = iface.encodeFunctionData(
+      'executeBatch(address[],bytes[])',
+      [
+        [to, other],
+        ['0x12', '0x34'],
+      ],
+    );
+
+    expect(decodeAccountCalls(callData)).toEqual([
+      { to, value: '0x0', data: '0x12' },
+      { to: other, value: '0x0', data: '0x34' },
+    ]);
+  });
+
+  it('should decode SimpleAccount v0.7 batches without values', () => {
+    const callData = iface.encodeFunctionData(
+      'executeBatch(address[],uint256[],bytes[])',
+      [[to], [], ['0x12']],
+    );
+
+    expect(decodeAccountCalls(callData)).toEqual([
+      { to, value: '0x0', data: '0x12' },
+    ]);
+  });
+
+  it('should decode batches of call structs', () => {
+    const callData = iface.encodeFunctionData(
+      'executeBatch((address,uint256,bytes)[])',
+      [[[to, 1n, '0x12']]],
+    );
+
+    expect(decodeAccountCalls(callData)).toEqual([
+      { to, value: '0x1', data: '0x12' },
+    ]);
+  });
+
+  it('should reject batches of mismatched lengths', () => {
+    const callData = iface.encodeFunctionData(
+      'executeBatch(address[],bytes[])',
+      [[to, other], ['0x12']],
+    );
+
+    expect(decodeAccountCalls(callData)).toBeNull();
+  });
+
+  it('should reject unknown callData', () => {
+    expect(decodeAccountCalls('0xdeadbeef')).toBeNull();
+  });
+});
+
+describe('getPaymaster', () => {
+  const paymaster = '0x00000000000000fb866daaa79352cc568a005d96';
+
+  it('should read the paymaster from the first 20 bytes', () => {
+    expect(getPaymaster(paymaster + 'abcd')).toBe(paymaster);
+  });
+
+  it('should return undefined for self-paid operations', () => {
+    expect(getPaymaster(undefined)).toBeUndefined();
+    expect(getPaymaster('0x')).toBeUndefined();
+  });
+
+  it('should return null for malformed paymasterAndData', () => {
+    expect(getPaymaster('0x1234')).toBeNull();
+    expect(getPaymaster(paymaster + 'a')).toBeNull();
+  });
+});
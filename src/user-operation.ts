@@ -0,0 +1,86 @@
+import { ethers } from 'ethers';
+
+// Call dispatchers of common smart accounts: eth-infinitism's SimpleAccount
+// (EntryPoint v0.6 and v0.7) and Coinbase Smart Wallet
+const accountInterface = new ethers.Interface([
+  'function execute(address dest, uint256 value, bytes func)',
+  'function executeBatch(address[] dest, bytes[] func)',
+  'function executeBatch(address[] dest, uint256[] value, bytes[] func)',
+  'function executeBatch((address target, uint256 value, bytes data)[] calls)',
+]);
+
+export interface AccountCall {
+  to: string;
+  value: string; // Hex quantity
+  data: string;
+}
+
+/**
+ * The calls a smart account makes when it executes callData, in order, or
+ * null when callData is not an execute or executeBatch call Shield knows.
+ */
+export function decodeAccountCalls(callData: string): AccountCall[] | null {
+  let parsed: ethers.TransactionDescription | null;
+  try {
+    parsed = accountInterface.parseTransaction({ data: callData });
+  } catch {
+    return null;
+  }
+  if (!parsed) return null;
+
+  const call = (to: string, value: bigint, data: string): AccountCall => ({
+    to,
+    value: ethers.toQuantity(value),
+    data,
+  });
+
+  switch (parsed.signature) {
+    case 'execute(address,uint256,bytes)': {
+      const [to, value, data] = parsed.args;
+      return [call(to, BigInt(value), data)];
+    }
+    case 'executeBatch(address[],bytes[])': {
+      const [targets, datas] = parsed.args;
+      if (targets.length !== datas.length) return null;
+      return Array.from(targets, (to: string, i) => call(to, 0n, datas[i]));
+    }
+    case 'executeBatch(address[],uint256[],bytes[])': {
+      // SimpleAccount v0.7 accepts an empty value array for value-less calls
+      const [targets, values, datas] = parsed.args;
+      if (
+        targets.length !== datas.length ||
+        (values.length !== 0 && values.length !== targets.length)
+      ) {
+        return null;
+      }
+      return Array.from(targets, (to: string, i) =>
+        call(to, values.length === 0 ? 0n : BigInt(values[i]), datas[i]),
+      );
+    }
+    case 'executeBatch((address,uint256,bytes)[])': {
+      const [calls] = parsed.args;
+      return Array.from(calls, ([to, value, data]: [string, bigint, string]) =>
+        call(to, BigInt(value), data),
+      );
+    }
+    default:
+      return null;
+  }
+}
+
+/**
+ * The paymaster named by the first 20 bytes of paymasterAndData. Returns
+ * undefined when the account pays its own gas, and null when
+ * paymasterAndData is malformed.
+ */
+export function getPaymaster(
+  paymasterAndData: string | undefined,
+): string | undefined | null {
+  if (paymasterAndData === undefined || paymasterAndData === '0x') {
+    return undefined;
+  }
+  if (!/^0x[0-9a-fA-F]{40}([0-9a-fA-F]{2})*$/.test(paymasterAndData)) {
+    return null;
+  }
+  return paymasterAndData.slice(0, 42);
+}
@@ -0,0 +1,46 @@
+import { ethers } from 'ethers';
+import { TransactionAmount } from '../types';
+import { isDefined } from './validation';
+
+export interface AssetInfo {
+  symbol: string;
+  decimals: number;
+}
+
+/**
+ * Compares amount, in base units of a token with decimals, with limit, a
+ * decimal in whole units such as "1.5": negative when amount is less,
+ * positive when it is more, and 0 when they are equal.
+ */
+export function compareWholeUnits(
+  amount: bigint,
+  decimals: number,
+  limit: string,
+): number {
+  const [whole, fraction = ''] = limit.split('.');
+  const scale = Math.max(decimals, fraction.length);
+  const scaledLimit = BigInt(whole + fraction.padEnd(scale, '0'));
+  const scaledAmount = amount * 10n ** BigInt(scale - decimals);
+  if (scaledAmount === scaledLimit) return 0;
+  return scaledAmount < scaledLimit ? -1 : 1;
+}
+
+/**
+ * An amount of token in base units, also in whole units when the asset's
+ * decimals are known, as ethers.formatUnits writes them: "1.5", "100.0".
+ */
+export function toTransactionAmount(
+  token: string,
+  amount: bigint,
+  asset?: AssetInfo,
+): TransactionAmount {
+  if (!isDefined(asset)) return { token, amount: amount.toString() };
+
+  return {
+    token,
+    amount: amount.toString(),
+    symbol: asset.symbol,
+    decimals: asset.decimals,
+    normalized: ethers.formatUnits(amount, asset.decimals),
+  };
+}
@@ -0,0 +1,17 @@
+import { Deadline } from '../types';
+
+// The latest instant a JavaScript Date can hold, in seconds
+const MAX_DATE_SECONDS = 8_640_000_000_000n;
+
+/**
+ * A deadline in unix seconds, also as an ISO 8601 string when a Date can
+ * hold it: "never" deadlines such as 2^256-1 have no ISO form.
+ */
+export function toDeadline(timestamp: bigint): Deadline {
+  if (timestamp > MAX_DATE_SECONDS) return { timestamp: timestamp.toString() };
+
+  return {
+    timestamp: timestamp.toString(),
+    iso: new Date(Number(timestamp) * 1000).toISOString(),
+  };
+}
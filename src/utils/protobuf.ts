@@ -0,0 +1,78 @@
+export type ProtobufField = {
+  field: number;
+  wireType: number;
+  value: Buffer | bigint;
+};
+
+// Minimal protobuf wire format reader, enough for the Cosmos and Tron
+// messages Shield decodes
+export function readFields(bytes: Buffer): ProtobufField[] {
+  const fields: ProtobufField[] = [];
+  let offset = 0;
+
+  const readVarint = (): bigint => {
+    let result = 0n;
+    for (let shift = 0n; shift < 70n; shift += 7n) {
+      if (offset >= bytes.length) throw new Error('Truncated varint');
+      const byte = bytes[offset++];
+      result |= BigInt(byte & 0x7f) << shift;
+      if ((byte & 0x80) === 0) return result;
+    }
+    throw new Error('Varint too long');
+  };
+
+  const take = (length: number): Buffer => {
+    if (offset + length > bytes.length) throw new Error('Truncated field');
+    const value = bytes.subarray(offset, offset + length);
+    offset += length;
+    return value;
+  };
+
+  while (offset < bytes.length) {
+    const key = Number(readVarint());
+    const field = key >>> 3;
+    const wireType = key & 0x7;
+
+    switch (wireType) {
+      case 0:
+        fields.push({ field, wireType, value: readVarint() });
+        break;
+      case 1:
+        fields.push({ field, wireType, value: take(8) });
+        break;
+      case 2:
+        fields.push({ field, wireType, value: take(Number(readVarint())) });
+        break;
+      case 5:
+        fields.push({ field, wireType, value: take(4) });
+        break;
+      default:
+        throw new Error(`Unsupported protobuf wire type ${wireType}`);
+    }
+  }
+
+  return fields;
+}
+
+export function bytesField(
+  fields: ProtobufField[],
+  field: number,
+): Buffer | null {
+  const found = fields.find((f) => f.field === field && f.wireType === 2);
+  return found ? asBytes(found) : null;
+}
+
+export function varintField(
+  fields: ProtobufField[],
+  field: number,
+): bigint | null {
+  const found = fields.find((f) => f.field === field && f.wireType === 0);
+  return found ? (found.value as bigint) : null;
+}
+
+export function asBytes(field: ProtobufField): Buffer {
+  if (!Buffer.isBuffer(field.value)) {
+    throw new Error(`Expected length-delimited protobuf field ${field.field}`);
+  }
+  return field.value;
+}
@@ -11,3 +11,12 @@ export const isNullOrUndefined = (
 export const isDefined = <T>(value: T | null | undefined): value is T => {
   return value !== null && value !== undefined;
 };
+
+// A dotted name such as lido.eth, as opposed to a 0x address
+export const isEnsName = (value: unknown): value is string => {
+  return (
+    typeof value === 'string' &&
+    !value.startsWith('0x') &&
+    /^[^\s.]+(\.[^\s.]+)+$/.test(value)
+  );
+};
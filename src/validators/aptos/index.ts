@@ -0,0 +1 @@
+export { AptosStakingValidator } from './native-staking/native-staking.validator';
@@ -0,0 +1,186 @@
+import { Shield } from '../../../shield';
+import { TransactionType } from '../../../types';
+
+describe('AptosStakingValidator via Shield', () => {
+  const shield = new Shield();
+  const yieldId = 'aptos-apt-native-staking';
+  const userAddress =
+    '0x7a1b3c5d7e9f0a2b4c6d8e0f1a3b5c7d9e1f3a5b7c9d1e3f5a7b9c1d3e5f7a9b';
+  const pool =
+    '0xdb5247f859ce63dbe8940cf8773be722a60dcc594a8be9aca4b76abceb251b8e';
+  const longFramework = `0x${'0'.repeat(63)}1`;
+
+  const restTx = (
+    fn: string,
+    args: unknown[] = [pool, '100000000'],
+    overrides: Record<string, unknown> = {},
+  ) =>
+    JSON.stringify({
+      sender: userAddress,
+      sequence_number: '12',
+      max_gas_amount: '2000',
+      gas_unit_price: '100',
+      expiration_timestamp_secs: '1767225600',
+      chain_id: 1,
+      payload: {
+        type: 'entry_function_payload',
+        function: fn,
+        type_arguments: [],
+        arguments: args,
+      },
+      ...overrides,
+    });
+
+  const validate = (
+    unsignedTransaction: string,
+    args?: { validatorAddress?: string; validatorAddresses?: string[] },
+  ) => shield.validate({ yieldId, unsignedTransaction, userAddress, args });
+
+  it('should support the Aptos staking yield', () => {
+    expect(shield.isSupported(yieldId)).toBe(true);
+    expect(shield.getYieldCapabilities(yieldId)?.supportedTypes).toEqual([
+      TransactionType.STAKE,
+      TransactionType.UNSTAKE,
+      TransactionType.WITHDRAW,
+    ]);
+  });
+
+  describe('transaction encodings', () => {
+    it('should accept REST API JSON and report the amount', () => {
+      const result = validate(restTx('0x1::delegation_pool::add_stake'));
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.STAKE);
+      expect(result.amount).toEqual({
+        token: 'native',
+        amount: '100000000',
+        symbol: 'APT',
+        decimals: 8,
+        normalized: '1.0',
+      });
+      expect(result.decoded?.entryFunction).toEqual({
+        module: `${longFramework}::delegation_pool`,
+        functionName: 'add_stake',
+        typeArguments: [],
+        arguments: [pool, '100000000'],
+      });
+    });
+
+    it('should accept TypeScript SDK JSON', () => {
+      const result = validate(
+        JSON.stringify({
+          sender: userAddress,
+          chainId: 1,
+          data: {
+            function: `${longFramework}::delegation_pool::unlock`,
+            typeArguments: [],
+            functionArguments: [pool, 250000000],
+          },
+        }),
+      );
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.UNSTAKE);
+    });
+
+    it('should reject script payloads', () => {
+      const result = validate(
+        JSON.stringify({
+          sender: userAddress,
+          payload: { type: 'script_payload', code: { bytecode: '0xa11ceb0b' } },
+        }),
+      );
+
+      expect(result.isValid).toBe(false);
+    });
+  });
+
+  describe('entry functions', () => {
+    it('should detect each transaction type', () => {
+      expect(
+        validate(restTx('0x1::delegation_pool::withdraw')).detectedType,
+      ).toBe(TransactionType.WITHDRAW);
+      expect(
+        validate(restTx('0x1::delegation_pool::unlock')).detectedType,
+      ).toBe(TransactionType.UNSTAKE);
+    });
+
+    it('should reject functions outside the staking module', () => {
+      const result = validate(
+        restTx('0x1::aptos_account::transfer', [pool, '100000000']),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('MODULE_NOT_ALLOWED');
+    });
+
+    it('should reject a lookalike delegation_pool module', () => {
+      const result = validate(restTx(`${pool}::delegation_pool::add_stake`));
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('MODULE_NOT_ALLOWED');
+    });
+
+    it('should reject other functions of the staking module', () => {
+      const result = validate(
+        restTx('0x1::delegation_pool::reactivate_stake'),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('NO_MATCHING_PATTERN');
+    });
+
+    it('should reject type arguments and extra arguments', () => {
+      const typed = JSON.parse(restTx('0x1::delegation_pool::add_stake'));
+      typed.payload.type_arguments = ['0x1::aptos_coin::AptosCoin'];
+
+      expect(validate(JSON.stringify(typed)).isValid).toBe(false);
+      expect(
+        validate(
+          restTx('0x1::delegation_pool::add_stake', [pool, '1', userAddress]),
+        ).isValid,
+      ).toBe(false);
+    });
+
+    it('should reject a zero or malformed amount', () => {
+      for (const amount of ['0', '-1', '1.5', '18446744073709551616']) {
+        expect(
+          validate(restTx('0x1::delegation_pool::add_stake', [pool, amount]))
+            .isValid,
+        ).toBe(false);
+      }
+    });
+  });
+
+  describe('sender and pool', () => {
+    it('should reject a transaction sent by another account', () => {
+      const result = validate(
+        restTx('0x1::delegation_pool::add_stake', undefined, {
+          sender: pool,
+        }),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('SENDER_MISMATCH');
+    });
+
+    it('should compare addresses in their long form', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: restTx('0x1::delegation_pool::add_stake'),
+        userAddress: userAddress.toUpperCase().replace('0X', '0x'),
+      });
+
+      expect(result.isValid).toBe(true);
+    });
+
+    it('should check the pool against args.validatorAddress', () => {
+      const tx = restTx('0x1::delegation_pool::add_stake');
+
+      expect(validate(tx, { validatorAddress: pool }).isValid).toBe(true);
+      expect(
+        validate(tx, { validatorAddresses: [longFramework] }).isValid,
+      ).toBe(false);
+    });
+  });
+});
@@ -0,0 +1,266 @@
+import {
+  ActionArguments,
+  DecodeResult,
+  ReasonCode,
+  TransactionAmount,
+  TransactionType,
+  ValidationContext,
+  ValidationResult,
+  ValidatorCapabilities,
+} from '../../../types';
+import {
+  isDefined,
+  isNonEmptyString,
+  isNullOrUndefined,
+} from '../../../utils/validation';
+import { toTransactionAmount } from '../../../utils/amount';
+import { BaseValidator } from '../../base.validator';
+import {
+  AptosTransaction,
+  decodeAptosTransaction,
+  isAptosAddress,
+  normalizeAptosAddress,
+} from '../tx-decoder';
+
+// The framework module delegation pools are run by, at 0x1
+const STAKING_MODULE = `${normalizeAptosAddress('0x1')}::delegation_pool`;
+
+// The entry function of each transaction type. Each takes the pool's
+// address and an amount in octas, the signer being implicit
+const EXPECTED_FUNCTIONS: Partial<Record<TransactionType, string>> = {
+  [TransactionType.STAKE]: 'add_stake',
+  [TransactionType.UNSTAKE]: 'unlock',
+  [TransactionType.WITHDRAW]: 'withdraw',
+};
+
+// Transactions name their network by number
+const CHAIN_IDS: Record<number, string> = {
+  1: 'aptos-mainnet',
+  2: 'aptos-testnet',
+};
+
+const APT_ASSET = { symbol: 'APT', decimals: 8 };
+
+/**
+ * Native APT staking through delegation pools
+ *
+ * Transaction Types Validated:
+ * - STAKE: delegation_pool::add_stake
+ * - UNSTAKE: delegation_pool::unlock, which starts the lockup's unbonding
+ * - WITHDRAW: delegation_pool::withdraw, once the unlocked APT is released
+ *
+ * A transaction is a single entry function call, so anything outside the
+ * delegation_pool module of 0x1 is rejected.
+ */
+export class AptosStakingValidator extends BaseValidator {
+  getSupportedTransactionTypes(): TransactionType[] {
+    return [
+      TransactionType.STAKE,
+      TransactionType.UNSTAKE,
+      TransactionType.WITHDRAW,
+    ];
+  }
+
+  // Every validator runs its own pool, so there is no fixed contract list
+  getCapabilities(): ValidatorCapabilities {
+    return {
+      name: 'Aptos native staking',
+      protocol: 'native',
+      network: 'aptos',
+      supportsPartialAmounts: true,
+      chainId: CHAIN_IDS[1],
+      contracts: [],
+    };
+  }
+
+  decode(unsignedTransaction: string): DecodeResult {
+    const decoded = this.decodeTransaction(unsignedTransaction);
+    if (!decoded.transaction) {
+      return {
+        decoded: null,
+        reason: `Failed to decode Aptos transaction: ${decoded.error}`,
+      };
+    }
+    return { decoded: { entryFunction: decoded.transaction.entryFunction } };
+  }
+
+  getMatchPath(_unsignedTransaction: string): string {
+    return 'aptos-entry-function-match';
+  }
+
+  getSigner(unsignedTransaction: string): string | undefined {
+    const { transaction } = this.decodeTransaction(unsignedTransaction);
+    return transaction?.sender;
+  }
+
+  getChainId(unsignedTransaction: string): string | undefined {
+    const { transaction } = this.decodeTransaction(unsignedTransaction);
+    const chainId = transaction?.chainId;
+    if (!isDefined(chainId)) return undefined;
+    return CHAIN_IDS[chainId] ?? String(chainId);
+  }
+
+  // The pool a delegation goes to plays the part of the contract called
+  getContractAddresses(unsignedTransaction: string): string[] {
+    const { transaction } = this.decodeTransaction(unsignedTransaction);
+    const pool = transaction?.entryFunction.arguments[0];
+    return typeof pool === 'string' && isAptosAddress(pool)
+      ? [normalizeAptosAddress(pool)]
+      : [];
+  }
+
+  getMismatchCode(unsignedTransaction: string): ReasonCode | undefined {
+    const { transaction } = this.decodeTransaction(unsignedTransaction);
+    return transaction && transaction.entryFunction.module !== STAKING_MODULE
+      ? 'MODULE_NOT_ALLOWED'
+      : undefined;
+  }
+
+  getAmount(unsignedTransaction: string): TransactionAmount | undefined {
+    const { transaction } = this.decodeTransaction(unsignedTransaction);
+    if (transaction?.entryFunction.module !== STAKING_MODULE) return undefined;
+    const amount = toU64(transaction.entryFunction.arguments[1]);
+    return isDefined(amount)
+      ? toTransactionAmount('native', amount, APT_ASSET)
+      : undefined;
+  }
+
+  getStakeAmount(amount: bigint): TransactionAmount | undefined {
+    return toTransactionAmount('native', amount, APT_ASSET);
+  }
+
+  isSameAddress(a: string, b: string): boolean {
+    if (!isAptosAddress(a) || !isAptosAddress(b)) return a === b;
+    return normalizeAptosAddress(a) === normalizeAptosAddress(b);
+  }
+
+  normalizeAddress(address: string): string {
+    return isAptosAddress(address) ? normalizeAptosAddress(address) : address;
+  }
+
+  isValidAddress(address: string): boolean {
+    return isAptosAddress(address);
+  }
+
+  validate(
+    unsignedTransaction: string,
+    transactionType: TransactionType,
+    userAddress: string,
+    args?: ActionArguments,
+    _context?: ValidationContext,
+  ): ValidationResult {
+    const decoded = this.decodeTransaction(unsignedTransaction);
+    if (!decoded.transaction) {
+      return this.blocked('Failed to decode Aptos transaction', {
+        error: decoded.error,
+      });
+    }
+
+    const { sender, entryFunction } = decoded.transaction;
+
+    if (!this.isSameAddress(sender, userAddress)) {
+      return this.blocked('Sender is not user address', {
+        expected: userAddress,
+        actual: sender,
+      });
+    }
+
+    if (entryFunction.module !== STAKING_MODULE) {
+      return this.blocked('Entry function is outside the staking module', {
+        expected: STAKING_MODULE,
+        actual: entryFunction.module,
+      });
+    }
+
+    const expectedFunction = EXPECTED_FUNCTIONS[transactionType];
+    if (!isDefined(expectedFunction)) {
+      return this.blocked('Unsupported transaction type', {
+        transactionType,
+      });
+    }
+    if (entryFunction.functionName !== expectedFunction) {
+      return this.blocked('Unexpected entry function', {
+        expected: expectedFunction,
+        actual: entryFunction.functionName,
+      });
+    }
+
+    // None of the functions is generic over a coin type
+    if (entryFunction.typeArguments.length > 0) {
+      return this.blocked('Unexpected type arguments', {
+        actual: entryFunction.typeArguments,
+      });
+    }
+    if (entryFunction.arguments.length !== 2) {
+      return this.blocked('Unexpected number of arguments', {
+        expected: 2,
+        actual: entryFunction.arguments.length,
+      });
+    }
+
+    const [pool, amount] = entryFunction.arguments;
+    if (typeof pool !== 'string' || !isAptosAddress(pool)) {
+      return this.blocked('Invalid pool address', { actual: pool });
+    }
+    const expectedPools = this.getExpectedPools(args);
+    if (
+      expectedPools !== null &&
+      !expectedPools.some((expected) => this.isSameAddress(expected, pool))
+    ) {
+      return this.blocked('Transaction targets an unexpected delegation pool', {
+        expected: expectedPools,
+        actual: pool,
+      });
+    }
+
+    const octas = toU64(amount);
+    if (!isDefined(octas) || octas === 0n) {
+      return this.blocked('Invalid amount', { actual: amount });
+    }
+
+    return {
+      ...this.safe(),
+      decoded: { entryFunction },
+    };
+  }
+
+  private getExpectedPools(args?: ActionArguments): string[] | null {
+    if (isNullOrUndefined(args)) return null;
+    if (
+      isDefined(args.validatorAddresses) &&
+      args.validatorAddresses.length > 0
+    ) {
+      return args.validatorAddresses;
+    }
+    if (isNonEmptyString(args.validatorAddress)) {
+      return [args.validatorAddress];
+    }
+    return null;
+  }
+
+  private decodeTransaction(unsignedTransaction: string): {
+    transaction?: AptosTransaction;
+    error?: string;
+  } {
+    try {
+      return { transaction: decodeAptosTransaction(unsignedTransaction) };
+    } catch (error) {
+      return {
+        error: error instanceof Error ? error.message : String(error),
+      };
+    }
+  }
+}
+
+// u64 arguments are decimal strings in JSON, or numbers from the SDK
+function toU64(value: unknown): bigint | undefined {
+  let amount: bigint;
+  if (typeof value === 'number' && Number.isSafeInteger(value)) {
+    amount = BigInt(value);
+  } else if (typeof value === 'string' && /^[0-9]{1,20}$/.test(value)) {
+    amount = BigInt(value);
+  } else {
+    return undefined;
+  }
+  return amount >= 0n && amount < 1n << 64n ? amount : undefined;
+}
@@ -0,0 +1,98 @@
+import { DecodedEntryFunction } from '../../types';
+import { isNonEmptyString } from '../../utils/validation';
+
+export interface AptosTransaction {
+  sender: string; // In long form
+  chainId?: number;
+  entryFunction: DecodedEntryFunction;
+}
+
+/**
+ * Decodes an Aptos transaction given as JSON, either as the REST API
+ * reports it ({ sender, chain_id, payload: { type: 'entry_function_payload',
+ * function, type_arguments, arguments } }) or as the TypeScript SDK takes
+ * it ({ sender, chainId, data: { function, typeArguments,
+ * functionArguments } }). Only entry function payloads are accepted.
+ */
+export function decodeAptosTransaction(encoded: string): AptosTransaction {
+  const json: unknown = JSON.parse(encoded);
+  if (!isRecord(json)) {
+    throw new Error('Transaction JSON must be an object');
+  }
+
+  if (!isNonEmptyString(json.sender) || !isAptosAddress(json.sender)) {
+    throw new Error('Transaction has no valid sender');
+  }
+  const chainId = json.chain_id ?? json.chainId;
+  if (
+    chainId !== undefined &&
+    !(typeof chainId === 'number' && Number.isInteger(chainId) && chainId > 0)
+  ) {
+    throw new Error(`Invalid chain ID ${String(chainId)}`);
+  }
+
+  const payload = json.payload ?? json.data;
+  if (!isRecord(payload)) {
+    throw new Error('Transaction JSON has no payload');
+  }
+  // Scripts and multisig payloads run code other than a module's
+  if (payload.type !== undefined && payload.type !== 'entry_function_payload') {
+    throw new Error(`Unsupported payload type ${String(payload.type)}`);
+  }
+
+  return {
+    sender: normalizeAptosAddress(json.sender),
+    chainId,
+    entryFunction: decodeEntryFunction(payload),
+  };
+}
+
+function decodeEntryFunction(
+  payload: Record<string, unknown>,
+): DecodedEntryFunction {
+  // <address>::<module>::<function>
+  const id = payload.function;
+  const parts = isNonEmptyString(id) ? id.split('::') : [];
+  if (
+    parts.length !== 3 ||
+    !isAptosAddress(parts[0]) ||
+    !parts.slice(1).every((part) => /^[A-Za-z_][A-Za-z0-9_]*$/.test(part))
+  ) {
+    throw new Error(`Invalid entry function ${String(id)}`);
+  }
+
+  const typeArguments = payload.type_arguments ?? payload.typeArguments ?? [];
+  const args = payload.arguments ?? payload.functionArguments ?? [];
+  if (
+    !Array.isArray(typeArguments) ||
+    !typeArguments.every((type) => isNonEmptyString(type))
+  ) {
+    throw new Error('Type arguments must be a list of type tags');
+  }
+  if (!Array.isArray(args)) {
+    throw new Error('Arguments must be a list');
+  }
+
+  return {
+    module: `${normalizeAptosAddress(parts[0])}::${parts[1]}`,
+    functionName: parts[2],
+    typeArguments,
+    arguments: args,
+  };
+}
+
+export function isAptosAddress(address: string): boolean {
+  return /^0x[0-9a-fA-F]{1,64}$/.test(address);
+}
+
+/**
+ * The long form of an Aptos address: 0x and 64 lower-case hex digits, so
+ * that 0x1 and 0x0...01 compare equal.
+ */
+export function normalizeAptosAddress(address: string): string {
+  return `0x${address.slice(2).toLowerCase().padStart(64, '0')}`;
+}
+
+function isRecord(value: unknown): value is Record<string, unknown> {
+  return typeof value === 'object' && value !== null && !Array.isArray(value);
+}
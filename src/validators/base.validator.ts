@@ -1,13 +1,52 @@
 import {
+  AbiFunction,
+  AccessListEntry,
+  Delegation,
   ActionArguments,
+  BalanceChange,
+  DecodeResult,
+  DecodedTransaction,
+  GasLimitRange,
+  ImplementationChange,
+  PrivilegedCall,
+  LockTerms,
+  MulticallTransaction,
+  ReasonCode,
+  SafeTransaction,
+  SimulationCall,
+  StakedBalanceCall,
+  ClaimCall,
+  ClaimedPositions,
+  SwapSlippage,
+  TokenApproval,
+  TokenSpend,
+  TransactionSignatures,
+  TransactionAmount,
   ValidationResult,
   TransactionType,
+  TypedData,
   ValidationContext,
+  ValidationWarning,
+  ValidatorCapabilities,
+  WarningCode,
+  Withdrawal,
+  WrappedTransaction,
+  YieldStatus,
 } from '../types';
 
 export abstract class BaseValidator {
-  protected safe(): ValidationResult {
-    return { isValid: true };
+  protected safe(warnings: ValidationWarning[] = []): ValidationResult {
+    return warnings.length > 0
+      ? { isValid: true, warnings }
+      : { isValid: true };
+  }
+
+  protected warning(
+    code: WarningCode,
+    message: string,
+    details?: Record<string, unknown>,
+  ): ValidationWarning {
+    return { code, message, details };
   }
 
   protected blocked(
@@ -21,8 +60,515 @@ export abstract class BaseValidator {
     };
   }
 
+  /**
+   * Describes the transaction without validating it. Validators that have
+   * no ABI to decode against report decoded: null.
+   */
+  decode(_unsignedTransaction: string): DecodeResult {
+    return {
+      decoded: null,
+      reason: 'Decoding is not supported for this yield',
+    };
+  }
+
+  /**
+   * The address the transaction is to be signed by, if it can be read from
+   * the transaction itself.
+   */
+  getSigner(_unsignedTransaction: string): string | undefined {
+    return undefined;
+  }
+
+  /**
+   * The signatures of a rawTransaction on chains that are not EVM, e.g. a
+   * Cosmos TxRaw, or null when it cannot be read. undefined on EVM chains,
+   * whose rawTransaction is RLP. accountNumber is the Cosmos signer's.
+   */
+  getSignatures(
+    _rawTransaction: string,
+    _accountNumber?: string,
+  ): TransactionSignatures | null | undefined {
+    return undefined;
+  }
+
+  /**
+   * The memo the transaction carries, on chains whose transactions have
+   * one, e.g. a Cosmos transaction's body memo.
+   */
+  getMemo(_unsignedTransaction: string): string | undefined {
+    return undefined;
+  }
+
+  /**
+   * The deadline, in unix seconds, after which the transaction reverts,
+   * for calls that carry one, e.g. a permit-based swap.
+   */
+  getDeadline(_unsignedTransaction: string): string | undefined {
+    return undefined;
+  }
+
+  /**
+   * The chain the transaction is bound to, in the format of
+   * getCapabilities().chainId, if the transaction names one.
+   */
+  getChainId(_unsignedTransaction: string): string | undefined {
+    return undefined;
+  }
+
+  /**
+   * Whether the transaction binds to no chain, so that anyone can replay it
+   * on every chain once signed. getChainId then names the yield's own.
+   */
+  isReplayable(_unsignedTransaction: string): boolean {
+    return false;
+  }
+
+  /**
+   * The call the transaction makes through a multisig wallet, such as a
+   * Gnosis Safe execTransaction, or relays through an ERC-2771 forwarder,
+   * if it is such a transaction.
+   */
+  getWrappedTransaction(
+    _unsignedTransaction: string,
+  ): WrappedTransaction | undefined {
+    return undefined;
+  }
+
+  /**
+   * The execTransaction an EIP-712 SafeTx confirms, and its hash, if the
+   * typed data is a Gnosis Safe SafeTx. Throws on a SafeTx that is not
+   * well formed or names another chain.
+   */
+  getSafeTransaction(_typedData: TypedData): SafeTransaction | undefined {
+    return undefined;
+  }
+
+  /**
+   * The calls the transaction batches through a multicall, such as a
+   * Multicall3 aggregate3, if it is such a transaction.
+   */
+  getMulticall(_unsignedTransaction: string): MulticallTransaction | undefined {
+    return undefined;
+  }
+
+  /**
+   * The code path that matches the transaction, reported in the timing of
+   * a validation, e.g. evm-abi-match.
+   */
+  getMatchPath(_unsignedTransaction: string): string {
+    return 'pattern-match';
+  }
+
+  /**
+   * Why no transaction type matched, when the transaction shows it plainly:
+   * RECIPIENT_MISMATCH, SELECTOR_MISMATCH, TOKEN_MISMATCH or
+   * CONTRACT_TYPE_NOT_SUPPORTED, or UNEXPECTED_VALIDATOR against the
+   * validators args allows.
+   */
+  getMismatchCode(
+    _unsignedTransaction: string,
+    _args?: ActionArguments,
+  ): ReasonCode | undefined {
+    return undefined;
+  }
+
+  /**
+   * The claim the transaction makes, with the address it pays out to, or
+   * undefined when the transaction is no claim.
+   */
+  getClaimCall(_unsignedTransaction: string): ClaimCall | undefined {
+    return undefined;
+  }
+
+  /**
+   * The unlock time of a vote-escrow lock the transaction creates or
+   * extends, with the lock durations the yield allows.
+   */
+  getLock(_unsignedTransaction: string): LockTerms | undefined {
+    return undefined;
+  }
+
+  /**
+   * The account a stake or deposit credits, for calls that name one, e.g.
+   * an ERC-4626 deposit's receiver.
+   */
+  getBeneficiary(_unsignedTransaction: string): string | undefined {
+    return undefined;
+  }
+
+  /**
+   * The payout of an unstake or withdraw call that names its recipient.
+   */
+  getWithdrawal(_unsignedTransaction: string): Withdrawal | undefined {
+    return undefined;
+  }
+
+  /**
+   * The native value the transaction sends, and whether the function it
+   * calls accepts any, with the function's canonical signature, for
+   * contract calls of a known function.
+   */
+  getNativeValue(
+    _unsignedTransaction: string,
+  ): { value: bigint; payable: boolean; signature: string } | undefined {
+    return undefined;
+  }
+
+  /**
+   * The contracts whose receive function stakes a plain transfer of native
+   * value, e.g. Lido's stETH. A transfer without calldata to any other
+   * address fails with NAKED_TRANSFER_NOT_SUPPORTED.
+   */
+  getTransferRecipients(): string[] {
+    return [];
+  }
+
+  /**
+   * The tokens the yield stakes, with 'native' for the chain's currency. A
+   * stake, lock, supply or deposit of any other token fails with
+   * TOKEN_MISMATCH. Empty for yields that leave it to their validate.
+   */
+  getStakeTokens(): string[] {
+    return [];
+  }
+
+  /**
+   * amount, in base units of the token the yield stakes, as a stake of it
+   * would report it. Undefined when the yield stakes several tokens, or
+   * Shield cannot tell which.
+   */
+  getStakeAmount(_amount: bigint): TransactionAmount | undefined {
+    return undefined;
+  }
+
+  /**
+   * The contract a transaction of transactionType is sent to, for yields
+   * that send every such transaction to the same one.
+   */
+  getTransactionRecipient(
+    _transactionType: TransactionType,
+  ): string | undefined {
+    return undefined;
+  }
+
+  /**
+   * Whether the yield currently refuses transactions of transactionType,
+   * e.g. a vault whose deposits the registry marks as paused.
+   */
+  isPaused(_transactionType: TransactionType): boolean {
+    return false;
+  }
+
+  /**
+   * Whether the yield takes new stakes, as the registry marks it. Built-in
+   * yields are active.
+   */
+  getStatus(): YieldStatus {
+    return 'active';
+  }
+
+  /**
+   * The 4-byte function selector the transaction calls, for matching it
+   * against getAbiFunctions.
+   */
+  getSelector(_unsignedTransaction: string): string | undefined {
+    return undefined;
+  }
+
+  /**
+   * The selector the transaction calls and the canonical signature of its
+   * function, or 'unknown' when no ABI this validator decodes has one for
+   * it.
+   */
+  getFunctionSignature(
+    _unsignedTransaction: string,
+  ): Pick<DecodedTransaction, 'selector' | 'functionSignature'> | undefined {
+    return undefined;
+  }
+
+  /**
+   * The contract functions the yield's transactions may call. Yields whose
+   * transactions are not contract calls, such as Cosmos messages, list none.
+   */
+  getAbiFunctions(): AbiFunction[] {
+    return [];
+  }
+
+  /**
+   * The contracts (or programs) the transaction calls, for policy checks.
+   */
+  getContractAddresses(_unsignedTransaction: string): string[] {
+    return [];
+  }
+
+  /**
+   * The keccak256 hash of the runtime code the yield's registry entry pins
+   * for the contract at address, if it pins one.
+   */
+  getBytecodeHash(_address: string): string | undefined {
+    return undefined;
+  }
+
+  /**
+   * The EIP-2930 access list the transaction declares, if any.
+   */
+  getAccessList(_unsignedTransaction: string): AccessListEntry[] | undefined {
+    return undefined;
+  }
+
+  /**
+   * The EIP-7702 authorizations the transaction carries, if any, each with
+   * the authority its signature recovers.
+   */
+  getDelegations(_unsignedTransaction: string): Delegation[] | undefined {
+    return undefined;
+  }
+
+  /**
+   * The token allowance the transaction grants, if it is an approval.
+   */
+  getApproval(_unsignedTransaction: string): TokenApproval | undefined {
+    return undefined;
+  }
+
+  /**
+   * The proxy upgrade the transaction makes, if it changes the code a
+   * proxy runs.
+   */
+  getImplementationChange(
+    _unsignedTransaction: string,
+  ): ImplementationChange | undefined {
+    return undefined;
+  }
+
+  /**
+   * The owner- or admin-only function the transaction calls, if it calls
+   * one of a contract this yield knows the privileged functions of.
+   */
+  getPrivilegedCall(_unsignedTransaction: string): PrivilegedCall | undefined {
+    return undefined;
+  }
+
+  /**
+   * The tokens the transaction pulls from the user under an allowance, if
+   * the amount is known before execution.
+   */
+  getTokenSpend(_unsignedTransaction: string): TokenSpend | undefined {
+    return undefined;
+  }
+
+  /**
+   * What the transaction swaps and the least output it accepts, if it is a
+   * swap, or a zap that swaps before staking.
+   */
+  getSlippage(_unsignedTransaction: string): SwapSlippage | undefined {
+    return undefined;
+  }
+
+  /**
+   * The amount the transaction moves from the user, for checking against
+   * the amount the user intended.
+   */
+  getAmount(_unsignedTransaction: string): TransactionAmount | undefined {
+    return undefined;
+  }
+
+  /**
+   * The ENS name the transaction is addressed to, when its recipient is
+   * given as a name rather than an address on a chain that has ENS.
+   */
+  getRecipientName(_unsignedTransaction: string): string | undefined {
+    return undefined;
+  }
+
+  /**
+   * The transaction addressed to address in place of its recipient name.
+   */
+  withRecipientAddress(unsignedTransaction: string, _address: string): string {
+    return unsignedTransaction;
+  }
+
+  /**
+   * The address lookup tables a versioned Solana transaction loads accounts
+   * from and which are not resolved yet.
+   */
+  getLookupTableAddresses(_unsignedTransaction: string): string[] {
+    return [];
+  }
+
+  /**
+   * The transaction with the accounts it loads from lookupTables, each
+   * table's addresses in order by its address, in place of their indexes.
+   * A transaction some table is missing for is left as it is.
+   */
+  withLookupTables(
+    unsignedTransaction: string,
+    _lookupTables: Record<string, string[]>,
+  ): string {
+    return unsignedTransaction;
+  }
+
+  /**
+   * The eth_call parameters that execute the transaction, or undefined when
+   * this validator's chain cannot be simulated.
+   */
+  getSimulationCall(_unsignedTransaction: string): SimulationCall | undefined {
+    return undefined;
+  }
+
+  /**
+   * The call that reads the balance the transaction unstakes from, with
+   * the amount it unstakes, for checking that the user has that much.
+   */
+  getStakedBalanceCall(
+    _unsignedTransaction: string,
+  ): StakedBalanceCall | undefined {
+    return undefined;
+  }
+
+  /**
+   * The positions the transaction claims, for checking that the user owns
+   * them, on yields whose claims redeem positions by ID.
+   */
+  getClaimedPositions(
+    _unsignedTransaction: string,
+  ): ClaimedPositions | undefined {
+    return undefined;
+  }
+
+  /**
+   * The tokens the transaction credits to the user, read from the return
+   * data of a successful simulation.
+   */
+  getBalanceChange(
+    _unsignedTransaction: string,
+    _returnData: string,
+  ): BalanceChange | undefined {
+    return undefined;
+  }
+
+  /**
+   * The first numeric field of the transaction that cannot be read as an
+   * integer, such as a value of "1.5", or undefined when all of them can.
+   */
+  getNumericError(
+    _unsignedTransaction: string,
+  ): { field: string; value: unknown } | undefined {
+    return undefined;
+  }
+
+  /**
+   * Why the transaction's fields contradict each other, e.g. fee fields of
+   * another transaction type, or undefined when they are consistent.
+   */
+  getMalformedError(_unsignedTransaction: string): string | undefined {
+    return undefined;
+  }
+
+  /**
+   * Why the transaction's gas fields could never be accepted or mined, or
+   * undefined when they are valid or absent.
+   */
+  getGasError(_unsignedTransaction: string): string | undefined {
+    return undefined;
+  }
+
+  /**
+   * The arguments of the contract call the transaction makes, by name, on
+   * chains that decode calls against an ABI.
+   */
+  getDecodedArgs(
+    _unsignedTransaction: string,
+  ): Record<string, unknown> | undefined {
+    return undefined;
+  }
+
+  /**
+   * The transaction as this validator reads it, serialized again, on
+   * chains whose transactions give the same field in several forms.
+   */
+  getNormalizedTransaction(_unsignedTransaction: string): string | undefined {
+    return undefined;
+  }
+
+  /**
+   * The length in bytes of the data the transaction calls a contract with,
+   * on chains where it is free-form.
+   */
+  getCalldataSize(_unsignedTransaction: string): number | undefined {
+    return undefined;
+  }
+
+  /**
+   * The gas limit the transaction is submitted with, if it sets one.
+   */
+  getGasLimit(_unsignedTransaction: string): bigint | undefined {
+    return undefined;
+  }
+
+  /**
+   * The nonce the transaction is submitted with, if it sets one.
+   */
+  getNonce(_unsignedTransaction: string): bigint | undefined {
+    return undefined;
+  }
+
+  /**
+   * The gas a transaction of type typically needs, or undefined when this
+   * validator's chain has no gas limit to check.
+   */
+  getGasLimitRange(
+    _transactionType: TransactionType,
+  ): GasLimitRange | undefined {
+    return undefined;
+  }
+
+  /**
+   * The spenders this yield may be granted an allowance for by the
+   * transaction. Only consulted for yields that support APPROVAL.
+   */
+  getExpectedSpenders(_unsignedTransaction: string): string[] {
+    return [];
+  }
+
+  /**
+   * Validates an off-chain signature request, such as an EIP-2612 permit,
+   * that userAddress is asked to sign for this yield.
+   */
+  validateTypedData(
+    _typedData: TypedData,
+    _userAddress: string,
+  ): ValidationResult {
+    return this.blocked('Typed data is not supported for this yield');
+  }
+
+  /**
+   * Whether two addresses of this validator's chain are the same account.
+   */
+  isSameAddress(a: string, b: string): boolean {
+    return a === b;
+  }
+
+  /**
+   * address in the one form isSameAddress compares addresses in, so that
+   * the same account is always written alike.
+   */
+  normalizeAddress(address: string): string {
+    return address;
+  }
+
+  /**
+   * Whether address is an account of this validator's chain. Validators
+   * that cannot tell accept any.
+   */
+  isValidAddress(_address: string): boolean {
+    return true;
+  }
+
   abstract getSupportedTransactionTypes(): TransactionType[];
 
+  abstract getCapabilities(): ValidatorCapabilities;
+
   abstract validate(
     unsignedTransaction: string,
     transactionType: TransactionType,
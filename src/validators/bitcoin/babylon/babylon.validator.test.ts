@@ -0,0 +1,263 @@
+import { ethers } from 'ethers';
+import { Shield } from '../../../shield';
+import { TransactionType } from '../../../types';
+import {
+  buildKeyPathOutputScript,
+  buildStakingOutputScript,
+} from './staking-script';
+
+describe('BabylonStakingValidator via Shield', () => {
+  const yieldId = 'bitcoin-btc-babylon-staking';
+
+  // x-only public keys of throwaway private keys
+  const xOnly = (privateKey: number) =>
+    ethers.SigningKey.computePublicKey(
+      ethers.toBeHex(privateKey, 32),
+      true,
+    ).slice(4);
+
+  const stakerPk = xOnly(1);
+  const finalityProviderPk = xOnly(2);
+  const otherPk = xOnly(3);
+  const covenantPks = [xOnly(4), xOnly(5), xOnly(6)];
+
+  const params = {
+    tag: '62626e31',
+    covenantPks,
+    covenantQuorum: 2,
+    minStakingAmount: 500_000,
+    maxStakingAmount: 5_000_000,
+    minStakingTime: 64_000,
+    maxStakingTime: 64_000,
+  };
+  const shield = new Shield({ babylonParams: params });
+
+  const stakingScript = (
+    stakingTime = 64_000,
+    fp = finalityProviderPk,
+    staker = stakerPk,
+  ) =>
+    buildStakingOutputScript({
+      stakerPk: Buffer.from(staker, 'hex'),
+      finalityProviderPks: [Buffer.from(fp, 'hex')],
+      covenantPks: covenantPks.map((pk) => Buffer.from(pk, 'hex')),
+      covenantQuorum: 2,
+      stakingTime,
+    });
+
+  const opReturn = (
+    stakingTime = 64_000,
+    fp = finalityProviderPk,
+    staker = stakerPk,
+  ) => {
+    const time = Buffer.alloc(2);
+    time.writeUInt16BE(stakingTime);
+    return Buffer.concat([
+      Buffer.from('6a4762626e3100', 'hex'),
+      Buffer.from(staker, 'hex'),
+      Buffer.from(fp, 'hex'),
+      time,
+    ]);
+  };
+
+  // A legacy-serialized transaction spending one input to outputs
+  const serialize = (outputs: { value: bigint; script: Buffer }[]) => {
+    const uint32 = (n: number) => {
+      const b = Buffer.alloc(4);
+      b.writeUInt32LE(n);
+      return b;
+    };
+    const uint64 = (n: bigint) => {
+      const b = Buffer.alloc(8);
+      b.writeBigUInt64LE(n);
+      return b;
+    };
+    return Buffer.concat([
+      uint32(2),
+      Buffer.from([1]),
+      Buffer.alloc(32, 0xab),
+      uint32(0),
+      Buffer.from([0]),
+      uint32(0xfffffffd),
+      Buffer.from([outputs.length]),
+      ...outputs.flatMap(({ value, script }) => [
+        uint64(value),
+        Buffer.from([script.length]),
+        script,
+      ]),
+      uint32(0),
+    ]).toString('hex');
+  };
+
+  const change = {
+    value: 90_000n,
+    script: buildKeyPathOutputScript(Buffer.from(stakerPk, 'hex')),
+  };
+  const stakingTx = (
+    outputs = [
+      { value: 1_000_000n, script: stakingScript() },
+      { value: 0n, script: opReturn() },
+      change,
+    ],
+  ) => serialize(outputs);
+
+  const validate = (
+    unsignedTransaction: string,
+    args?: { validatorAddress?: string; duration?: number },
+    userAddress = stakerPk,
+  ) => shield.validate({ yieldId, unsignedTransaction, userAddress, args });
+
+  // Why the transaction did not match STAKE
+  const stakeReason = (result: ReturnType<typeof validate>) =>
+    result.details?.attempts?.find(
+      (attempt: { type: TransactionType }) =>
+        attempt.type === TransactionType.STAKE,
+    )?.reason;
+
+  it('should only support the yield when params are given', () => {
+    expect(new Shield().isSupported(yieldId)).toBe(false);
+    expect(shield.isSupported(yieldId)).toBe(true);
+    expect(shield.getYieldCapabilities(yieldId)?.supportedTypes).toEqual([
+      TransactionType.STAKE,
+    ]);
+  });
+
+  it('should validate a staking transaction', () => {
+    const result = validate(stakingTx(), {
+      validatorAddress: finalityProviderPk,
+      duration: 64_000,
+    });
+
+    expect(result.isValid).toBe(true);
+    expect(result.detectedType).toBe(TransactionType.STAKE);
+    expect(result.warnings).toBeUndefined();
+    expect(result.amount).toEqual({
+      token: 'native',
+      amount: '1000000',
+      symbol: 'BTC',
+      decimals: 8,
+      normalized: '0.01',
+    });
+    expect(result.summary).toBe('You are staking 0.01 BTC with Babylon');
+  });
+
+  it('should validate a segwit-serialized transaction', () => {
+    const legacy = stakingTx();
+    const segwit =
+      legacy.slice(0, 8) +
+      '0001' +
+      legacy.slice(8, -8) +
+      '0140' +
+      'cd'.repeat(64) +
+      legacy.slice(-8);
+
+    expect(validate(segwit).isValid).toBe(true);
+  });
+
+  it('should decode the outputs', () => {
+    const result = shield.decode({ yieldId, unsignedTransaction: stakingTx() });
+
+    expect(result.decoded?.outputs).toEqual([
+      { value: '1000000', script: stakingScript().toString('hex') },
+      { value: '0', script: opReturn().toString('hex') },
+      { value: '90000', script: change.script.toString('hex') },
+    ]);
+  });
+
+  it('should reject staking data that names another staker', () => {
+    const result = validate(stakingTx(), undefined, otherPk);
+
+    expect(result.isValid).toBe(false);
+    expect(result.reasonCode).toBe('SENDER_MISMATCH');
+  });
+
+  it('should reject a staking output to another script', () => {
+    const result = validate(
+      stakingTx([
+        { value: 1_000_000n, script: stakingScript(64_000, otherPk) },
+        { value: 0n, script: opReturn() },
+      ]),
+    );
+
+    expect(result.isValid).toBe(false);
+    expect(stakeReason(result)).toBe(
+      'Expected exactly one output to the staking script',
+    );
+  });
+
+  it('should reject an unexpected finality provider', () => {
+    const result = validate(stakingTx(), { validatorAddress: otherPk });
+
+    expect(result.isValid).toBe(false);
+    expect(stakeReason(result)).toBe(
+      'Stakes with an unexpected finality provider',
+    );
+  });
+
+  it('should reject a staking time outside the params', () => {
+    const result = validate(
+      stakingTx([
+        { value: 1_000_000n, script: stakingScript(1_000) },
+        { value: 0n, script: opReturn(1_000) },
+      ]),
+    );
+
+    expect(result.isValid).toBe(false);
+    expect(stakeReason(result)).toBe('Staking time is not allowed');
+  });
+
+  it('should reject a staking time other than the requested duration', () => {
+    const result = validate(stakingTx(), { duration: 1_000 });
+
+    expect(result.isValid).toBe(false);
+    expect(stakeReason(result)).toBe('Staking time is not allowed');
+  });
+
+  it('should reject amounts outside the params', () => {
+    for (const value of [499_999n, 5_000_001n]) {
+      const result = validate(
+        stakingTx([
+          { value, script: stakingScript() },
+          { value: 0n, script: opReturn() },
+        ]),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(stakeReason(result)).toBe('Staking amount is out of bounds');
+    }
+  });
+
+  it('should reject a transaction without staking data', () => {
+    const result = validate(
+      stakingTx([{ value: 1_000_000n, script: stakingScript() }]),
+    );
+
+    expect(result.isValid).toBe(false);
+    expect(stakeReason(result)).toBe(
+      'Expected exactly one Babylon OP_RETURN output',
+    );
+  });
+
+  it('should warn about outputs that do not pay the staker', () => {
+    const result = validate(
+      stakingTx([
+        { value: 1_000_000n, script: stakingScript() },
+        { value: 0n, script: opReturn() },
+        {
+          value: 90_000n,
+          script: buildKeyPathOutputScript(Buffer.from(otherPk, 'hex')),
+        },
+      ]),
+    );
+
+    expect(result.isValid).toBe(true);
+    expect(result.warnings?.map((w) => w.code)).toEqual(['UNKNOWN_RECIPIENT']);
+    expect(result.warnings?.[0].details?.index).toBe(2);
+  });
+
+  it('should reject a transaction that is not hex', () => {
+    const result = validate('not a transaction');
+
+    expect(result.isValid).toBe(false);
+  });
+});
@@ -0,0 +1,319 @@
+import {
+  ActionArguments,
+  DecodeResult,
+  TransactionAmount,
+  TransactionType,
+  ValidationContext,
+  ValidationResult,
+  ValidationWarning,
+  ValidatorCapabilities,
+} from '../../../types';
+import {
+  isDefined,
+  isNonEmptyString,
+  isNullOrUndefined,
+} from '../../../utils/validation';
+import { toTransactionAmount } from '../../../utils/amount';
+import { BaseValidator } from '../../base.validator';
+import { BitcoinTransaction, decodeBitcoinTransaction } from '../tx-decoder';
+import {
+  buildKeyPathOutputScript,
+  buildStakingOutputScript,
+  parseStakingData,
+  StakingData,
+} from './staking-script';
+
+/**
+ * Babylon's global staking parameters, as its global-params.json lists
+ * them for the version in effect. Keys are x-only, as hex.
+ */
+export interface BabylonStakingParams {
+  tag: string; // The OP_RETURN tag, e.g. '62626e31' ("bbn1") on mainnet
+  covenantPks: string[];
+  covenantQuorum: number;
+  minStakingAmount: number; // In satoshis, as is maxStakingAmount
+  maxStakingAmount: number;
+  minStakingTime: number; // In blocks, as is maxStakingTime
+  maxStakingTime: number;
+}
+
+const BTC_ASSET = { symbol: 'BTC', decimals: 8 };
+
+const X_ONLY_KEY = /^(0x)?[0-9a-fA-F]{64}$/;
+
+/**
+ * Babylon Bitcoin staking
+ *
+ * Transaction Types Validated:
+ * - STAKE: a transaction paying the staking output, with an OP_RETURN
+ *   output naming the staker, finality provider and staking time
+ *
+ * The staking output must pay the taproot script those and the covenant
+ * of params build, so that only the timelock, unbonding and slashing
+ * paths Babylon defines can spend it. userAddress is the staker's x-only
+ * public key, as hex.
+ */
+export class BabylonStakingValidator extends BaseValidator {
+  private readonly tag: Buffer;
+  private readonly covenantPks: Buffer[];
+
+  constructor(private readonly params: BabylonStakingParams) {
+    super();
+    this.tag = Buffer.from(params.tag, 'hex');
+    this.covenantPks = params.covenantPks.map(toKey);
+  }
+
+  getSupportedTransactionTypes(): TransactionType[] {
+    return [TransactionType.STAKE];
+  }
+
+  // Bitcoin has no contracts: the staking script is what is validated
+  getCapabilities(): ValidatorCapabilities {
+    return {
+      name: 'Babylon',
+      protocol: 'babylon',
+      network: 'bitcoin',
+      supportsPartialAmounts: true,
+      chainId: 'bitcoin-mainnet',
+      contracts: [],
+    };
+  }
+
+  decode(unsignedTransaction: string): DecodeResult {
+    const decoded = this.decodeTransaction(unsignedTransaction);
+    if (!decoded.transaction) {
+      return {
+        decoded: null,
+        reason: `Failed to decode Bitcoin transaction: ${decoded.error}`,
+      };
+    }
+    return {
+      decoded: {
+        outputs: decoded.transaction.outputs.map((output) => ({
+          value: output.value.toString(),
+          script: output.script.toString('hex'),
+        })),
+      },
+    };
+  }
+
+  getMatchPath(_unsignedTransaction: string): string {
+    return 'bitcoin-output-match';
+  }
+
+  // The staker the OP_RETURN output names, who funds the transaction
+  getSigner(unsignedTransaction: string): string | undefined {
+    const { transaction } = this.decodeTransaction(unsignedTransaction);
+    const data = transaction ? this.findStakingData(transaction) : undefined;
+    return data?.stakerPk.toString('hex');
+  }
+
+  isSameAddress(a: string, b: string): boolean {
+    return normalizeKey(a) === normalizeKey(b);
+  }
+
+  normalizeAddress(address: string): string {
+    return normalizeKey(address);
+  }
+
+  getAmount(unsignedTransaction: string): TransactionAmount | undefined {
+    const { transaction } = this.decodeTransaction(unsignedTransaction);
+    const data = transaction ? this.findStakingData(transaction) : undefined;
+    if (!transaction || !data) return undefined;
+
+    const script = this.getStakingScript(data);
+    const output = transaction.outputs.find((o) => o.script.equals(script));
+    return output
+      ? toTransactionAmount('native', output.value, BTC_ASSET)
+      : undefined;
+  }
+
+  getStakeAmount(amount: bigint): TransactionAmount | undefined {
+    return toTransactionAmount('native', amount, BTC_ASSET);
+  }
+
+  validate(
+    unsignedTransaction: string,
+    transactionType: TransactionType,
+    userAddress: string,
+    args?: ActionArguments,
+    _context?: ValidationContext,
+  ): ValidationResult {
+    const decoded = this.decodeTransaction(unsignedTransaction);
+    if (!decoded.transaction) {
+      return this.blocked('Failed to decode Bitcoin transaction', {
+        error: decoded.error,
+      });
+    }
+
+    if (transactionType !== TransactionType.STAKE) {
+      return this.blocked('Unsupported transaction type', {
+        transactionType,
+      });
+    }
+
+    if (!X_ONLY_KEY.test(userAddress)) {
+      return this.blocked('User address is not an x-only public key', {
+        actual: userAddress,
+      });
+    }
+
+    const { outputs } = decoded.transaction;
+    const tagged = outputs.filter((output) =>
+      isDefined(parseStakingData(output.script, this.tag)),
+    );
+    if (tagged.length !== 1) {
+      return this.blocked('Expected exactly one Babylon OP_RETURN output', {
+        actual: tagged.length,
+      });
+    }
+
+    const data = parseStakingData(tagged[0].script, this.tag)!;
+    if (data.version !== 0) {
+      return this.blocked('Unsupported staking data version', {
+        expected: 0,
+        actual: data.version,
+      });
+    }
+
+    const stakerPk = data.stakerPk.toString('hex');
+    if (!this.isSameAddress(stakerPk, userAddress)) {
+      return this.blocked('Staker key is not user address', {
+        expected: userAddress,
+        actual: stakerPk,
+      });
+    }
+
+    const finalityProviderPk = data.finalityProviderPk.toString('hex');
+    const expectedProviders = this.getExpectedProviders(args);
+    if (
+      expectedProviders !== null &&
+      !expectedProviders.some((pk) =>
+        this.isSameAddress(pk, finalityProviderPk),
+      )
+    ) {
+      return this.blocked('Stakes with an unexpected finality provider', {
+        expected: expectedProviders,
+        actual: finalityProviderPk,
+      });
+    }
+
+    const { minStakingTime, maxStakingTime } = this.params;
+    if (
+      data.stakingTime < minStakingTime ||
+      data.stakingTime > maxStakingTime ||
+      (isDefined(args?.duration) && data.stakingTime !== args.duration)
+    ) {
+      return this.blocked('Staking time is not allowed', {
+        expected: args?.duration ?? {
+          min: minStakingTime,
+          max: maxStakingTime,
+        },
+        actual: data.stakingTime,
+      });
+    }
+
+    const script = this.getStakingScript(data);
+    const staking = outputs.filter((output) => output.script.equals(script));
+    if (staking.length !== 1) {
+      return this.blocked(
+        'Expected exactly one output to the staking script',
+        { expected: script.toString('hex'), actual: staking.length },
+      );
+    }
+
+    const amount = staking[0].value;
+    const { minStakingAmount, maxStakingAmount } = this.params;
+    if (
+      amount < BigInt(minStakingAmount) ||
+      amount > BigInt(maxStakingAmount)
+    ) {
+      return this.blocked('Staking amount is out of bounds', {
+        min: minStakingAmount.toString(),
+        max: maxStakingAmount.toString(),
+        actual: amount.toString(),
+      });
+    }
+
+    // Change should go back to the staker's own taproot key
+    const change = buildKeyPathOutputScript(data.stakerPk);
+    const warnings: ValidationWarning[] = outputs.flatMap((output, index) =>
+      output === staking[0] ||
+      output === tagged[0] ||
+      output.script.equals(change)
+        ? []
+        : [
+            this.warning(
+              'UNKNOWN_RECIPIENT',
+              "Output pays a script other than the staker's taproot key",
+              {
+                index,
+                script: output.script.toString('hex'),
+                value: output.value.toString(),
+              },
+            ),
+          ],
+    );
+
+    return this.safe(warnings);
+  }
+
+  private findStakingData(
+    transaction: BitcoinTransaction,
+  ): StakingData | undefined {
+    for (const output of transaction.outputs) {
+      const data = parseStakingData(output.script, this.tag);
+      if (data) return data;
+    }
+    return undefined;
+  }
+
+  private getStakingScript(data: StakingData): Buffer {
+    return buildStakingOutputScript({
+      stakerPk: data.stakerPk,
+      finalityProviderPks: [data.finalityProviderPk],
+      covenantPks: this.covenantPks,
+      covenantQuorum: this.params.covenantQuorum,
+      stakingTime: data.stakingTime,
+    });
+  }
+
+  // Finality providers are named as validators are on other chains
+  private getExpectedProviders(args?: ActionArguments): string[] | null {
+    if (isNullOrUndefined(args)) return null;
+    if (
+      isDefined(args.validatorAddresses) &&
+      args.validatorAddresses.length > 0
+    ) {
+      return args.validatorAddresses;
+    }
+    if (isNonEmptyString(args.validatorAddress)) {
+      return [args.validatorAddress];
+    }
+    return null;
+  }
+
+  private decodeTransaction(unsignedTransaction: string): {
+    transaction?: BitcoinTransaction;
+    error?: string;
+  } {
+    try {
+      return { transaction: decodeBitcoinTransaction(unsignedTransaction) };
+    } catch (error) {
+      return {
+        error: error instanceof Error ? error.message : String(error),
+      };
+    }
+  }
+}
+
+function normalizeKey(key: string): string {
+  return key.toLowerCase().replace(/^0x/, '');
+}
+
+function toKey(hex: string): Buffer {
+  if (!X_ONLY_KEY.test(hex)) {
+    throw new Error(`Not an x-only public key: ${hex}`);
+  }
+  return Buffer.from(normalizeKey(hex), 'hex');
+}
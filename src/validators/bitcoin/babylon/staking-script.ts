@@ -0,0 +1,207 @@
+import { createHash } from 'node:crypto';
+import { ethers } from 'ethers';
+
+const OP_RETURN = 0x6a;
+const OP_NUMEQUAL = 0x9c;
+const OP_NUMEQUALVERIFY = 0x9d;
+const OP_CHECKSIG = 0xac;
+const OP_CHECKSIGVERIFY = 0xad;
+const OP_CHECKSEQUENCEVERIFY = 0xb2;
+const OP_CHECKSIGADD = 0xba;
+
+const TAPSCRIPT_LEAF_VERSION = 0xc0;
+
+// BIP-341's provably unspendable internal key, which leaves only the
+// script paths to spend a staking output by
+const UNSPENDABLE_KEY =
+  '0x0250929b74c1a04954b78b4b6035e97a5e078a5a0f28ec96d547bfee9ace803ac0';
+
+// tag, version, staker key, finality provider key and staking time
+const OP_RETURN_DATA_SIZE = 4 + 1 + 32 + 32 + 2;
+
+export interface StakingScriptParams {
+  stakerPk: Buffer; // x-only, as are the other keys
+  finalityProviderPks: Buffer[];
+  covenantPks: Buffer[];
+  covenantQuorum: number;
+  stakingTime: number; // In blocks
+}
+
+export interface StakingData {
+  version: number;
+  stakerPk: Buffer;
+  finalityProviderPk: Buffer;
+  stakingTime: number;
+}
+
+/**
+ * The P2TR output script a Babylon staking output pays to: a taproot
+ * output of the unspendable key, spendable by the staker once stakingTime
+ * blocks pass, by the staker and the covenant on unbonding, or by the
+ * staker, a finality provider and the covenant on slashing.
+ */
+export function buildStakingOutputScript(params: StakingScriptParams): Buffer {
+  const { stakerPk, finalityProviderPks, covenantPks, covenantQuorum } =
+    params;
+
+  const stakerSig = singleKeySigScript(stakerPk, true);
+  const covenantMultisig = multiSigScript(covenantPks, covenantQuorum, false);
+  const timelock = Buffer.concat([
+    stakerSig,
+    pushInt(params.stakingTime),
+    Buffer.from([OP_CHECKSEQUENCEVERIFY]),
+  ]);
+  const unbonding = Buffer.concat([stakerSig, covenantMultisig]);
+  const slashing = Buffer.concat([
+    stakerSig,
+    multiSigScript(finalityProviderPks, 1, true),
+    covenantMultisig,
+  ]);
+
+  // Assembled as btcd does: the first two leaves pair up, and the third
+  // joins them at the root
+  const root = tapBranch(
+    tapBranch(tapLeaf(timelock), tapLeaf(unbonding)),
+    tapLeaf(slashing),
+  );
+  return taprootOutputScript(UNSPENDABLE_KEY, root);
+}
+
+/**
+ * The BIP-86 key path only output script of key, which is how taproot
+ * wallets receive to their own key.
+ */
+export function buildKeyPathOutputScript(key: Buffer): Buffer {
+  return taprootOutputScript(`0x02${key.toString('hex')}`);
+}
+
+/**
+ * Reads the staking data of an OP_RETURN output carrying tag, or returns
+ * undefined for scripts that are not one.
+ */
+export function parseStakingData(
+  script: Buffer,
+  tag: Buffer,
+): StakingData | undefined {
+  if (
+    script.length !== OP_RETURN_DATA_SIZE + 2 ||
+    script[0] !== OP_RETURN ||
+    script[1] !== OP_RETURN_DATA_SIZE ||
+    !script.subarray(2, 6).equals(tag)
+  ) {
+    return undefined;
+  }
+
+  return {
+    version: script[6],
+    stakerPk: script.subarray(7, 39),
+    finalityProviderPk: script.subarray(39, 71),
+    stakingTime: script.readUInt16BE(71),
+  };
+}
+
+function singleKeySigScript(key: Buffer, verify: boolean): Buffer {
+  return Buffer.concat([
+    pushData(key),
+    Buffer.from([verify ? OP_CHECKSIGVERIFY : OP_CHECKSIG]),
+  ]);
+}
+
+// A threshold of keys, sorted so that the script does not depend on the
+// order they are configured in
+function multiSigScript(
+  keys: Buffer[],
+  threshold: number,
+  verify: boolean,
+): Buffer {
+  if (keys.length === 0) throw new Error('Multisig script has no keys');
+  if (threshold > keys.length) {
+    throw new Error('Multisig threshold exceeds the number of keys');
+  }
+  if (keys.length === 1) return singleKeySigScript(keys[0], verify);
+
+  const sorted = [...keys].sort(Buffer.compare);
+  if (sorted.some((key, i) => i > 0 && key.equals(sorted[i - 1]))) {
+    throw new Error('Multisig script has duplicate keys');
+  }
+
+  return Buffer.concat([
+    ...sorted.map((key, i) =>
+      Buffer.concat([
+        pushData(key),
+        Buffer.from([i === 0 ? OP_CHECKSIG : OP_CHECKSIGADD]),
+      ]),
+    ),
+    pushInt(threshold),
+    Buffer.from([verify ? OP_NUMEQUALVERIFY : OP_NUMEQUAL]),
+  ]);
+}
+
+// Keys and other pushes here are all shorter than OP_PUSHDATA1 needs
+function pushData(data: Buffer): Buffer {
+  return Buffer.concat([Buffer.from([data.length]), data]);
+}
+
+// The minimal push of a non-negative number: OP_0 to OP_16, or a
+// little-endian script number
+function pushInt(value: number): Buffer {
+  if (value === 0) return Buffer.from([0x00]);
+  if (value <= 16) return Buffer.from([0x50 + value]);
+
+  const bytes: number[] = [];
+  for (let rest = value; rest > 0; rest = Math.floor(rest / 256)) {
+    bytes.push(rest % 256);
+  }
+  // The top bit is the sign, so a set one needs a byte of its own
+  if (bytes[bytes.length - 1] & 0x80) bytes.push(0x00);
+  return pushData(Buffer.from(bytes));
+}
+
+function tapLeaf(script: Buffer): Buffer {
+  return taggedHash(
+    'TapLeaf',
+    Buffer.from([TAPSCRIPT_LEAF_VERSION]),
+    compactSize(script.length),
+    script,
+  );
+}
+
+function tapBranch(a: Buffer, b: Buffer): Buffer {
+  return Buffer.compare(a, b) <= 0
+    ? taggedHash('TapBranch', a, b)
+    : taggedHash('TapBranch', b, a);
+}
+
+// OP_1 and the x-only key of internalKey tweaked by merkleRoot
+function taprootOutputScript(internalKey: string, merkleRoot?: Buffer): Buffer {
+  const internal = Buffer.from(internalKey.slice(4), 'hex');
+  const tweak = taggedHash(
+    'TapTweak',
+    internal,
+    ...(merkleRoot ? [merkleRoot] : []),
+  );
+  const output = ethers.SigningKey.addPoints(
+    internalKey,
+    ethers.SigningKey.computePublicKey(tweak, true),
+    true,
+  );
+  return Buffer.concat([
+    Buffer.from([0x51, 0x20]),
+    Buffer.from(output.slice(4), 'hex'),
+  ]);
+}
+
+function taggedHash(tag: string, ...data: Buffer[]): Buffer {
+  const tagHash = createHash('sha256').update(tag).digest();
+  const hash = createHash('sha256').update(tagHash).update(tagHash);
+  for (const chunk of data) hash.update(chunk);
+  return hash.digest();
+}
+
+function compactSize(length: number): Buffer {
+  if (length < 0xfd) return Buffer.from([length]);
+  const size = Buffer.alloc(3);
+  size[0] = 0xfd;
+  size.writeUInt16LE(length, 1);
+  return size;
+}
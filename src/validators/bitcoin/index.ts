@@ -0,0 +1,2 @@
+export { BabylonStakingValidator } from './babylon/babylon.validator';
+export type { BabylonStakingParams } from './babylon/babylon.validator';
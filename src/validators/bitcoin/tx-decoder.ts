@@ -0,0 +1,115 @@
+export interface BitcoinInput {
+  txid: string; // Of the transaction spent, in the order explorers show it
+  vout: number;
+  sequence: number;
+}
+
+export interface BitcoinOutput {
+  value: bigint; // In satoshis
+  script: Buffer;
+}
+
+export interface BitcoinTransaction {
+  version: number;
+  inputs: BitcoinInput[];
+  outputs: BitcoinOutput[];
+  locktime: number;
+}
+
+/**
+ * Decodes a raw Bitcoin transaction given as hex, with or without the
+ * segwit marker. Witness data is skipped, since an unsigned transaction
+ * carries none worth validating.
+ */
+export function decodeBitcoinTransaction(hex: string): BitcoinTransaction {
+  const digits = hex.startsWith('0x') ? hex.slice(2) : hex;
+  if (digits.length === 0 || !/^([0-9a-fA-F]{2})+$/.test(digits)) {
+    throw new Error('Transaction must be a hex string');
+  }
+
+  const reader = new Reader(Buffer.from(digits, 'hex'));
+  const version = reader.uint32();
+
+  // A zero input count is the segwit marker, followed by a flag of 1
+  let inputCount = reader.varInt();
+  const segwit = inputCount === 0;
+  if (segwit) {
+    if (reader.uint8() !== 1) throw new Error('Invalid segwit flag');
+    inputCount = reader.varInt();
+  }
+  if (inputCount === 0) throw new Error('Transaction has no inputs');
+
+  const inputs: BitcoinInput[] = [];
+  for (let i = 0; i < inputCount; i++) {
+    const txid = Buffer.from(reader.bytes(32)).reverse().toString('hex');
+    const vout = reader.uint32();
+    reader.bytes(reader.varInt()); // scriptSig
+    inputs.push({ txid, vout, sequence: reader.uint32() });
+  }
+
+  const outputCount = reader.varInt();
+  if (outputCount === 0) throw new Error('Transaction has no outputs');
+
+  const outputs: BitcoinOutput[] = [];
+  for (let i = 0; i < outputCount; i++) {
+    const value = reader.uint64();
+    outputs.push({ value, script: reader.bytes(reader.varInt()) });
+  }
+
+  if (segwit) {
+    for (let i = 0; i < inputCount; i++) {
+      const items = reader.varInt();
+      for (let j = 0; j < items; j++) reader.bytes(reader.varInt());
+    }
+  }
+
+  const locktime = reader.uint32();
+  if (!reader.done()) throw new Error('Unexpected trailing bytes');
+
+  return { version, inputs, outputs, locktime };
+}
+
+class Reader {
+  private offset = 0;
+
+  constructor(private readonly data: Buffer) {}
+
+  bytes(length: number): Buffer {
+    if (this.offset + length > this.data.length) {
+      throw new Error('Unexpected end of transaction');
+    }
+    const bytes = this.data.subarray(this.offset, this.offset + length);
+    this.offset += length;
+    return bytes;
+  }
+
+  uint8(): number {
+    return this.bytes(1)[0];
+  }
+
+  uint32(): number {
+    return this.bytes(4).readUInt32LE(0);
+  }
+
+  uint64(): bigint {
+    return this.bytes(8).readBigUInt64LE(0);
+  }
+
+  // Bitcoin's CompactSize: one byte, or a marker byte and 2, 4 or 8 more
+  varInt(): number {
+    const first = this.uint8();
+    if (first < 0xfd) return first;
+    if (first === 0xfd) return this.bytes(2).readUInt16LE(0);
+    if (first === 0xfe) return this.uint32();
+
+    const value = this.uint64();
+    if (value > BigInt(this.data.length)) {
+      throw new Error('Length exceeds the transaction size');
+    }
+    return Number(value);
+  }
+
+  done(): boolean {
+    return this.offset === this.data.length;
+  }
+}
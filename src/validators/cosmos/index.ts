@@ -0,0 +1,2 @@
+export { CosmosStakingValidator } from './native-staking/native-staking.validator';
+export type { CosmosChainConfig } from './native-staking/native-staking.validator';
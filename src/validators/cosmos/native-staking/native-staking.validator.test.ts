@@ -0,0 +1,632 @@
+import { createECDH, createPrivateKey, sign } from 'crypto';
+import { Shield } from '../../../shield';
+import { TransactionType } from '../../../types';
+
+// Minimal protobuf encoder for building TxRaw / SignDoc fixtures
+const varint = (value: number): Buffer => {
+  const bytes: number[] = [];
+  while (value > 0x7f) {
+    bytes.push((value & 0x7f) | 0x80);
+    value >>>= 7;
+  }
+  bytes.push(value);
+  return Buffer.from(bytes);
+};
+const field = (number: number, value: Buffer | string): Buffer => {
+  const bytes = typeof value === 'string' ? Buffer.from(value) : value;
+  return Buffer.concat([varint((number << 3) | 2), varint(bytes.length), bytes]);
+};
+
+describe('CosmosStakingValidator via Shield', () => {
+  const shield = new Shield();
+  const yieldId = 'cosmos-atom-native-staking';
+  const userAddress = 'cosmos1xy5mkg6dd0pqg9eq9wajsazm40fjekd3fgemzu';
+  const validatorAddress =
+    'cosmosvaloper1clpqr4nrk4khgkxj78fcwwh6dl3uw4epsluffn';
+  const otherValidator = 'cosmosvaloper1sjllsnramtg3ewxqwwrwjxfgc4n4ef9u2lcnj0';
+
+  const delegate = (overrides: Record<string, unknown> = {}) => ({
+    '@type': '/cosmos.staking.v1beta1.MsgDelegate',
+    delegator_address: userAddress,
+    validator_address: validatorAddress,
+    amount: { denom: 'uatom', amount: '1000000' },
+    ...overrides,
+  });
+
+  const protoJsonTx = (...messages: object[]) =>
+    JSON.stringify({ body: { messages, memo: '' }, auth_info: {} });
+
+  const validate = (
+    unsignedTransaction: string,
+    args?: {
+      validatorAddress?: string;
+      validatorAddresses?: string[];
+      amount?: string;
+    },
+  ) => shield.validate({ yieldId, unsignedTransaction, userAddress, args });
+
+  const attemptReasons = (result: ReturnType<typeof validate>) =>
+    result.details?.attempts?.map((attempt) => attempt.reason) ?? [];
+
+  describe('isSupported', () => {
+    it('should support cosmos-atom-native-staking yield', () => {
+      expect(shield.isSupported(yieldId)).toBe(true);
+    });
+  });
+
+  describe('transaction encodings', () => {
+    it('should accept protobuf JSON', () => {
+      const result = validate(protoJsonTx(delegate()));
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.STAKE);
+    });
+
+    it('should accept an amino sign doc', () => {
+      const result = validate(
+        JSON.stringify({
+          chain_id: 'cosmoshub-4',
+          memo: '',
+          msgs: [
+            {
+              type: 'cosmos-sdk/MsgDelegate',
+              value: {
+                delegator_address: userAddress,
+                validator_address: validatorAddress,
+                amount: { denom: 'uatom', amount: '1000000' },
+              },
+            },
+          ],
+        }),
+      );
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.STAKE);
+    });
+
+    it('should accept a base64 protobuf TxRaw', () => {
+      const msgDelegate = Buffer.concat([
+        field(1, userAddress),
+        field(2, validatorAddress),
+        field(3, Buffer.concat([field(1, 'uatom'), field(2, '1000000')])),
+      ]);
+      const any = Buffer.concat([
+        field(1, '/cosmos.staking.v1beta1.MsgDelegate'),
+        field(2, msgDelegate),
+      ]);
+      const txRaw = Buffer.concat([
+        field(1, field(1, any)), // body_bytes
+        field(2, Buffer.alloc(0)), // auth_info_bytes
+      ]);
+
+      const result = validate(txRaw.toString('base64'));
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.STAKE);
+      expect(result.decoded?.messages?.[0].validatorAddress).toBe(
+        validatorAddress,
+      );
+    });
+
+    it('should reject a SignDoc for another chain', () => {
+      const any = Buffer.concat([
+        field(1, '/cosmos.staking.v1beta1.MsgDelegate'),
+        field(
+          2,
+          Buffer.concat([
+            field(1, userAddress),
+            field(2, validatorAddress),
+            field(3, Buffer.concat([field(1, 'uatom'), field(2, '1')])),
+          ]),
+        ),
+      ]);
+      const signDoc = Buffer.concat([
+        field(1, field(1, any)),
+        field(2, Buffer.alloc(0)),
+        field(3, 'theta-testnet-001'),
+      ]);
+
+      const result = validate(signDoc.toString('base64'));
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('CHAIN_ID_MISMATCH');
+      expect(result.details?.actual).toBe('theta-testnet-001');
+    });
+
+    it('should reject undecodable input', () => {
+      const result = validate('not a transaction!');
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain(
+        'Failed to decode Cosmos transaction',
+      );
+    });
+  });
+
+  describe('message validation', () => {
+    it('should map MsgUndelegate to UNSTAKE', () => {
+      const result = validate(
+        protoJsonTx(
+          delegate({ '@type': '/cosmos.staking.v1beta1.MsgUndelegate' }),
+        ),
+      );
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.UNSTAKE);
+    });
+
+    it('should map MsgWithdrawDelegatorReward to CLAIM_REWARDS', () => {
+      const claim = (validator: string) => ({
+        '@type': '/cosmos.distribution.v1beta1.MsgWithdrawDelegatorReward',
+        delegator_address: userAddress,
+        validator_address: validator,
+      });
+
+      const result = validate(
+        protoJsonTx(claim(validatorAddress), claim(otherValidator)),
+      );
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.CLAIM_REWARDS);
+      expect(result.decoded?.messages).toHaveLength(2);
+      // Rewards go to the delegator's withdraw address
+      expect(result.decoded?.recipient).toEqual({
+        address: userAddress,
+        implicit: true,
+        variant: '/cosmos.distribution.v1beta1.MsgWithdrawDelegatorReward',
+      });
+    });
+
+    it('should reject a delegator other than the user', () => {
+      const result = validate(
+        protoJsonTx(
+          delegate({
+            delegator_address: 'cosmos1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqnrql8a',
+          }),
+        ),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('SENDER_MISMATCH');
+    });
+
+    it('should reject a later message from another delegator', () => {
+      const result = validate(
+        protoJsonTx(
+          delegate(),
+          delegate({
+            delegator_address: 'cosmos1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqnrql8a',
+          }),
+        ),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain(
+        'Delegator address is not user address',
+      );
+    });
+
+    it('should reject an unexpected denomination', () => {
+      const result = validate(
+        protoJsonTx(delegate({ amount: { denom: 'uosmo', amount: '1' } })),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain('Unexpected denomination');
+    });
+
+    it('should reject mixed message types', () => {
+      const result = validate(
+        protoJsonTx(delegate(), {
+          '@type': '/cosmos.bank.v1beta1.MsgSend',
+          from_address: userAddress,
+        }),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain('Unexpected message type');
+    });
+  });
+
+  describe('amount', () => {
+    it('should report the total delegated amount', () => {
+      const result = validate(
+        protoJsonTx(
+          delegate(),
+          delegate({
+            validator_address: otherValidator,
+            amount: { denom: 'uatom', amount: '500000' },
+          }),
+        ),
+      );
+
+      expect(result.isValid).toBe(true);
+      expect(result.amount).toEqual({
+        token: 'uatom',
+        amount: '1500000',
+        symbol: 'ATOM',
+        decimals: 6,
+        normalized: '1.5',
+      });
+    });
+
+    it('should break a stake down by validator', () => {
+      const result = validate(
+        protoJsonTx(
+          delegate(),
+          delegate({
+            validator_address: otherValidator,
+            amount: { denom: 'uatom', amount: '500000' },
+          }),
+        ),
+      );
+
+      expect(result.decoded?.delegations).toEqual([
+        {
+          messageIndex: 0,
+          validatorAddress,
+          amount: {
+            token: 'uatom',
+            amount: '1000000',
+            symbol: 'ATOM',
+            decimals: 6,
+            normalized: '1.0',
+          },
+        },
+        {
+          messageIndex: 1,
+          validatorAddress: otherValidator,
+          amount: {
+            token: 'uatom',
+            amount: '500000',
+            symbol: 'ATOM',
+            decimals: 6,
+            normalized: '0.5',
+          },
+        },
+      ]);
+    });
+
+    it('should not break down other transaction types', () => {
+      const result = validate(
+        protoJsonTx(
+          delegate({ '@type': '/cosmos.staking.v1beta1.MsgUndelegate' }),
+        ),
+      );
+
+      expect(result.detectedType).toBe(TransactionType.UNSTAKE);
+      expect(result.decoded?.delegations).toBeUndefined();
+    });
+  });
+
+  describe('expected validators', () => {
+    it('should accept messages to the expected validator', () => {
+      const result = validate(protoJsonTx(delegate()), { validatorAddress });
+
+      expect(result.isValid).toBe(true);
+    });
+
+    it('should flag messages targeting an unexpected validator', () => {
+      const result = validate(
+        protoJsonTx(
+          delegate(),
+          delegate({ validator_address: otherValidator }),
+        ),
+        { validatorAddress },
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('UNEXPECTED_VALIDATOR');
+      const attempt = result.details?.attempts?.find(
+        (a) => a.type === TransactionType.STAKE,
+      );
+      expect(attempt?.reason).toBe('Message targets an unexpected validator');
+    });
+
+    it('should keep other reason codes without expected validators', () => {
+      const result = validate(
+        protoJsonTx(delegate({ amount: { denom: 'uosmo', amount: '1' } })),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('NO_MATCHING_PATTERN');
+    });
+
+    it('should accept any of validatorAddresses', () => {
+      const result = validate(
+        protoJsonTx(
+          delegate(),
+          delegate({ validator_address: otherValidator }),
+        ),
+        { validatorAddresses: [validatorAddress, otherValidator] },
+      );
+
+      expect(result.isValid).toBe(true);
+    });
+  });
+
+  describe('compound', () => {
+    const claim = (validator = validatorAddress) => ({
+      '@type': '/cosmos.distribution.v1beta1.MsgWithdrawDelegatorReward',
+      delegator_address: userAddress,
+      validator_address: validator,
+    });
+
+    it('should validate restaking rewards with the validator paying them', () => {
+      const result = validate(protoJsonTx(claim(), delegate()), {
+        amount: '1200000',
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.RESTAKE_REWARDS);
+      expect(result.compound).toEqual({
+        claimed: {
+          token: 'uatom',
+          amount: '1200000',
+          symbol: 'ATOM',
+          decimals: 6,
+          normalized: '1.2',
+        },
+        restaked: {
+          token: 'uatom',
+          amount: '1000000',
+          symbol: 'ATOM',
+          decimals: 6,
+          normalized: '1.0',
+        },
+      });
+    });
+
+    it('should only report what is restaked without args.amount', () => {
+      const result = validate(
+        protoJsonTx(
+          claim(),
+          claim(otherValidator),
+          delegate({ validator_address: otherValidator }),
+        ),
+      );
+
+      expect(result.isValid).toBe(true);
+      expect(result.compound?.claimed).toBeUndefined();
+      expect(result.compound?.restaked.amount).toBe('1000000');
+    });
+
+    it('should reject restaking with another validator', () => {
+      const result = validate(
+        protoJsonTx(claim(), delegate({ validator_address: otherValidator })),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('COMPOUND_DESTINATION_MISMATCH');
+      expect(attemptReasons(result)).toContain(
+        'Restakes with a validator the rewards are not claimed from',
+      );
+    });
+
+    it('should reject restaking more than the rewards claimed', () => {
+      const result = validate(protoJsonTx(claim(), delegate()), {
+        amount: '999999',
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('COMPOUND_DESTINATION_MISMATCH');
+      expect(attemptReasons(result)).toContain(
+        'Restakes more than the rewards claimed',
+      );
+    });
+
+    it('should reject delegating before claiming', () => {
+      const result = validate(protoJsonTx(delegate(), claim()));
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('NO_MATCHING_PATTERN');
+    });
+  });
+
+  describe('memo', () => {
+    const memoTx = (memo: string) =>
+      JSON.stringify({ body: { messages: [delegate()], memo }, auth_info: {} });
+    const validateMemo = (unsignedTransaction: string, expectedMemo: string) =>
+      shield.validate({
+        yieldId,
+        unsignedTransaction,
+        userAddress,
+        expectedMemo,
+      });
+
+    it('should report the memo the transaction carries', () => {
+      expect(validate(memoTx('104455')).memo).toBe('104455');
+      expect(validate(memoTx('')).memo).toBeUndefined();
+    });
+
+    it('should accept the expected memo', () => {
+      expect(validateMemo(memoTx('104455'), '104455').isValid).toBe(true);
+    });
+
+    it('should reject a missing memo with MISSING_MEMO', () => {
+      const result = validateMemo(memoTx(''), '104455');
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('MISSING_MEMO');
+      expect(result.details?.expected).toBe('104455');
+    });
+
+    it('should reject another memo with MEMO_MISMATCH', () => {
+      const result = validateMemo(memoTx('104456'), '104455');
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('MEMO_MISMATCH');
+      expect(result.details?.actual).toBe('104456');
+      expect(result.memo).toBe('104456');
+    });
+
+    it('should read the memo of protobuf transactions', () => {
+      const txRaw = field(1, field(2, '104455')).toString('base64');
+
+      expect(validate(txRaw).memo).toBe('104455');
+    });
+  });
+
+  describe('signed transactions', () => {
+    // Private key 0x0101...01, and the address it derives
+    const ecdh = createECDH('secp256k1');
+    ecdh.setPrivateKey(Buffer.alloc(32, 1));
+    const publicKey = Buffer.from(ecdh.getPublicKey(null, 'compressed'));
+    const uncompressed = ecdh.getPublicKey();
+    const privateKey = createPrivateKey({
+      key: {
+        kty: 'EC',
+        crv: 'secp256k1',
+        d: ecdh.getPrivateKey().toString('base64url'),
+        x: uncompressed.subarray(1, 33).toString('base64url'),
+        y: uncompressed.subarray(33).toString('base64url'),
+      },
+      format: 'jwk',
+    });
+    const signer = 'cosmos10xcqpzrky6eff2g52qdye53xkk9jxkvrpq6uqr';
+    const order =
+      0xfffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141n;
+
+    const body = field(
+      1,
+      Buffer.concat([
+        field(1, '/cosmos.staking.v1beta1.MsgDelegate'),
+        field(
+          2,
+          Buffer.concat([
+            field(1, signer),
+            field(2, validatorAddress),
+            field(3, Buffer.concat([field(1, 'uatom'), field(2, '1000000')])),
+          ]),
+        ),
+      ]),
+    );
+    const authInfo = field(
+      1,
+      Buffer.concat([
+        field(
+          1,
+          Buffer.concat([
+            field(1, '/cosmos.crypto.secp256k1.PubKey'),
+            field(2, field(1, publicKey)),
+          ]),
+        ),
+        field(2, field(1, Buffer.from([0x08, 0x01]))), // SIGN_MODE_DIRECT
+      ]),
+    );
+
+    // A low-s signature over the SignDoc for accountNumber, which proto3
+    // leaves out when zero
+    const signedTx = (accountNumber: number, chainId = 'cosmoshub-4') => {
+      const signDoc = Buffer.concat([
+        field(1, body),
+        field(2, authInfo),
+        field(3, chainId),
+        accountNumber > 0
+          ? Buffer.concat([varint(4 << 3), varint(accountNumber)])
+          : Buffer.alloc(0),
+      ]);
+      const signature = sign('sha256', signDoc, {
+        key: privateKey,
+        dsaEncoding: 'ieee-p1363',
+      });
+      const s = BigInt('0x' + signature.subarray(32).toString('hex'));
+      if (s > order / 2n) {
+        Buffer.from((order - s).toString(16).padStart(64, '0'), 'hex').copy(
+          signature,
+          32,
+        );
+      }
+      return Buffer.concat([
+        field(1, body),
+        field(2, authInfo),
+        field(3, signature),
+      ]).toString('base64');
+    };
+
+    const validateRaw = (
+      rawTransaction: string,
+      accountNumber?: string,
+      user = signer,
+    ) =>
+      shield.validateRawTransaction({
+        yieldId,
+        rawTransaction,
+        accountNumber,
+        userAddress: user,
+      });
+
+    it('should verify the signer and validate the transaction', () => {
+      const result = validateRaw(signedTx(42), '42');
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.STAKE);
+      expect(result.signatureValid).toBe(true);
+      expect(result.recoveredAddress).toBe(signer);
+      expect(result.recoveredAddresses).toEqual([signer]);
+    });
+
+    it('should reject a signer other than userAddress', () => {
+      const result = validateRaw(signedTx(42), '42', userAddress);
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('SIGNATURE_SENDER_MISMATCH');
+      expect(result.details?.expected).toBe(userAddress);
+      expect(result.details?.actual).toBe(signer);
+    });
+
+    it('should reject a signature for another account or chain', () => {
+      for (const result of [
+        validateRaw(signedTx(42), '43'),
+        validateRaw(signedTx(42, 'theta-testnet-001'), '42'),
+      ]) {
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('SIGNATURE_INVALID');
+        expect(result.signatureValid).toBe(false);
+        expect(result.recoveredAddress).toBeUndefined();
+      }
+    });
+
+    it('should take a zero account number', () => {
+      expect(validateRaw(signedTx(0), '0').signatureValid).toBe(true);
+    });
+
+    it('should need accountNumber to verify a signature', () => {
+      const result = validateRaw(signedTx(42));
+
+      expect(result.reasonCode).toBe('SIGNATURE_INVALID');
+      expect(result.details?.error).toMatch(/accountNumber/);
+    });
+
+    it('should validate an unsigned TxRaw as validate would', () => {
+      const unsigned = Buffer.concat([
+        field(1, body),
+        field(2, authInfo),
+      ]).toString('base64');
+      const result = validateRaw(unsigned);
+
+      expect(result.isValid).toBe(true);
+      expect(result.signatureValid).toBeUndefined();
+    });
+
+    it('should reject input that is no transaction', () => {
+      expect(validateRaw('not a transaction').reasonCode).toBe(
+        'MALFORMED_RAW_TRANSACTION',
+      );
+    });
+  });
+
+  describe('decode', () => {
+    it('should decode messages and the detected type', () => {
+      const result = shield.decode({
+        yieldId,
+        unsignedTransaction: protoJsonTx(delegate()),
+      });
+
+      expect(result.decoded?.messages?.[0].typeUrl).toBe(
+        '/cosmos.staking.v1beta1.MsgDelegate',
+      );
+      expect(result.decoded?.detectedType).toBe(TransactionType.STAKE);
+    });
+  });
+});
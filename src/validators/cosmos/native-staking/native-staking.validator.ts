@@ -0,0 +1,480 @@
+import {
+  ActionArguments,
+  DecodedMessage,
+  DecodeResult,
+  ReasonCode,
+  TransactionAmount,
+  TransactionSignatures,
+  TransactionType,
+  ValidationContext,
+  ValidationResult,
+  ValidatorCapabilities,
+  ValidatorDelegation,
+} from '../../../types';
+import {
+  isDefined,
+  isNonEmptyString,
+  isNullOrUndefined,
+} from '../../../utils/validation';
+import { toTransactionAmount } from '../../../utils/amount';
+import { BaseValidator } from '../../base.validator';
+import {
+  COSMOS_MESSAGE_TYPES,
+  CosmosTransaction,
+  decodeCosmosTransaction,
+} from '../tx-decoder';
+import { readCosmosSignatures } from '../signatures';
+
+export interface CosmosChainConfig {
+  chainId: string;
+  name: string; // Network display name, e.g. 'Cosmos Hub'
+  network: string; // As in yield IDs, e.g. 'cosmos'
+  denom: string; // Staking denomination, e.g. 'uatom'
+  symbol: string; // Display denomination, e.g. 'ATOM'
+  decimals: number; // Exponent of symbol over denom, e.g. 6
+  bech32Prefix: string; // Account prefix, e.g. 'cosmos'
+}
+
+// The one message type each transaction type is made of
+const EXPECTED_MESSAGE_TYPES: Partial<Record<TransactionType, string>> = {
+  [TransactionType.STAKE]: COSMOS_MESSAGE_TYPES.delegate,
+  [TransactionType.UNSTAKE]: COSMOS_MESSAGE_TYPES.undelegate,
+  [TransactionType.CLAIM_REWARDS]: COSMOS_MESSAGE_TYPES.withdrawDelegatorReward,
+};
+
+const BECH32_DATA = /^[02-9ac-hj-np-z]+$/;
+
+/**
+ * Native Cosmos SDK staking
+ *
+ * Transaction Types Validated:
+ * - STAKE: MsgDelegate
+ * - UNSTAKE: MsgUndelegate
+ * - CLAIM_REWARDS: MsgWithdrawDelegatorReward
+ * - RESTAKE_REWARDS: MsgWithdrawDelegatorReward, then MsgDelegate
+ *
+ * A transaction may carry several messages (e.g. delegating to or claiming
+ * from each of several validators), but all of them must be of the
+ * expected type, and target a validator args allows when it names any. A
+ * compound may only restake with the validators it claims from, and no
+ * more than args.amount, the rewards claimed, when given.
+ */
+export class CosmosStakingValidator extends BaseValidator {
+  constructor(private readonly config: CosmosChainConfig) {
+    super();
+  }
+
+  getSupportedTransactionTypes(): TransactionType[] {
+    return [
+      TransactionType.STAKE,
+      TransactionType.UNSTAKE,
+      TransactionType.CLAIM_REWARDS,
+      TransactionType.RESTAKE_REWARDS,
+    ];
+  }
+
+  getCapabilities(): ValidatorCapabilities {
+    return {
+      name: `${this.config.name} native staking`,
+      protocol: 'native',
+      network: this.config.network,
+      supportsPartialAmounts: true,
+      chainId: this.config.chainId,
+      contracts: [],
+    };
+  }
+
+  decode(unsignedTransaction: string): DecodeResult {
+    const decoded = this.decodeTransaction(unsignedTransaction);
+    if (!decoded.transaction) {
+      return {
+        decoded: null,
+        reason: `Failed to decode Cosmos transaction: ${decoded.error}`,
+      };
+    }
+    return { decoded: { messages: decoded.transaction.messages } };
+  }
+
+  getMatchPath(_unsignedTransaction: string): string {
+    return 'cosmos-message-match';
+  }
+
+  getSigner(unsignedTransaction: string): string | undefined {
+    const { transaction } = this.decodeTransaction(unsignedTransaction);
+    return transaction?.messages.at(0)?.delegatorAddress;
+  }
+
+  getSignatures(
+    rawTransaction: string,
+    accountNumber?: string,
+  ): TransactionSignatures | null {
+    return readCosmosSignatures(
+      rawTransaction,
+      this.config.chainId,
+      this.config.bech32Prefix,
+      accountNumber,
+    );
+  }
+
+  getChainId(unsignedTransaction: string): string | undefined {
+    const { transaction } = this.decodeTransaction(unsignedTransaction);
+    return transaction?.chainId;
+  }
+
+  // The bech32 address of a 20- or 32-byte account, not a validator's.
+  // The checksum is not verified
+  isValidAddress(address: string): boolean {
+    const data = address.startsWith(`${this.config.bech32Prefix}1`)
+      ? address.slice(this.config.bech32Prefix.length + 1)
+      : '';
+    return (
+      (data.length === 38 || data.length === 58) &&
+      /^[02-9ac-hj-np-z]+$/.test(data)
+    );
+  }
+
+  getMemo(unsignedTransaction: string): string | undefined {
+    const { transaction } = this.decodeTransaction(unsignedTransaction);
+    return isNonEmptyString(transaction?.memo) ? transaction.memo : undefined;
+  }
+
+  // MsgWithdrawDelegatorReward names no recipient: rewards go to the
+  // delegator's withdraw address, the delegator itself unless changed
+  getClaimCall(unsignedTransaction: string): ClaimCall | undefined {
+    const { transaction } = this.decodeTransaction(unsignedTransaction);
+    const claims = transaction?.messages.some(
+      ({ typeUrl }) => typeUrl === COSMOS_MESSAGE_TYPES.withdrawDelegatorReward,
+    );
+    if (!claims) return undefined;
+    return {
+      variant: COSMOS_MESSAGE_TYPES.withdrawDelegatorReward,
+      recipient: null,
+    };
+  }
+
+  // A message to a validator outside those args names, or a compound that
+  // diverts its rewards, matches no type
+  getMismatchCode(
+    unsignedTransaction: string,
+    args?: ActionArguments,
+  ): ReasonCode | undefined {
+    const { transaction } = this.decodeTransaction(unsignedTransaction);
+    if (!transaction) return undefined;
+
+    const expectedValidators = this.getExpectedValidators(args);
+    if (
+      expectedValidators !== null &&
+      transaction.messages.some(
+        ({ validatorAddress }) =>
+          isDefined(validatorAddress) &&
+          !expectedValidators.includes(validatorAddress),
+      )
+    ) {
+      return 'UNEXPECTED_VALIDATOR';
+    }
+    return isDefined(this.checkCompound(transaction.messages, args))
+      ? 'COMPOUND_DESTINATION_MISMATCH'
+      : undefined;
+  }
+
+  // Delegating to several validators at once stakes their total
+  getAmount(unsignedTransaction: string): TransactionAmount | undefined {
+    const { transaction } = this.decodeTransaction(unsignedTransaction);
+    const amounts = (transaction?.messages ?? []).flatMap(({ amount }) =>
+      amount?.denom === this.config.denom && /^[0-9]+$/.test(amount.amount)
+        ? [BigInt(amount.amount)]
+        : [],
+    );
+    if (amounts.length === 0) return undefined;
+
+    return toTransactionAmount(
+      this.config.denom,
+      amounts.reduce((total, amount) => total + amount),
+      this.config,
+    );
+  }
+
+  getStakeAmount(amount: bigint): TransactionAmount | undefined {
+    return toTransactionAmount(this.config.denom, amount, this.config);
+  }
+
+  validate(
+    unsignedTransaction: string,
+    transactionType: TransactionType,
+    userAddress: string,
+    args?: ActionArguments,
+    _context?: ValidationContext,
+  ): ValidationResult {
+    const decoded = this.decodeTransaction(unsignedTransaction);
+    if (!decoded.transaction) {
+      return this.blocked('Failed to decode Cosmos transaction', {
+        error: decoded.error,
+      });
+    }
+
+    const { chainId, messages } = decoded.transaction;
+
+    if (isDefined(chainId) && chainId !== this.config.chainId) {
+      return this.blocked('Transaction chain ID does not match', {
+        expected: this.config.chainId,
+        actual: chainId,
+      });
+    }
+
+    if (messages.length === 0) {
+      return this.blocked('Transaction contains no messages');
+    }
+
+    const expectedValidators = this.getExpectedValidators(args);
+    if (transactionType === TransactionType.RESTAKE_REWARDS) {
+      return this.validateCompound(
+        messages,
+        userAddress,
+        expectedValidators,
+        args,
+      );
+    }
+
+    const expectedType = EXPECTED_MESSAGE_TYPES[transactionType];
+    if (!isDefined(expectedType)) {
+      return this.blocked('Unsupported transaction type', {
+        transactionType,
+      });
+    }
+
+    for (const [messageIndex, message] of messages.entries()) {
+      const messageErr = this.validateMessage(
+        message,
+        messageIndex,
+        expectedType,
+        userAddress,
+        expectedValidators,
+      );
+      if (messageErr) return messageErr;
+    }
+
+    if (transactionType !== TransactionType.STAKE) {
+      return { ...this.safe(), decoded: { messages } };
+    }
+
+    // Messages have been checked to delegate a positive amount of denom
+    const delegations = messages.map(
+      ({ validatorAddress, amount }, messageIndex): ValidatorDelegation => ({
+        messageIndex,
+        validatorAddress: validatorAddress!,
+        amount: toTransactionAmount(
+          this.config.denom,
+          BigInt(amount!.amount),
+          this.config,
+        ),
+      }),
+    );
+    return { ...this.safe(), decoded: { messages, delegations } };
+  }
+
+  // Claims from validators, then restakes what they paid with them
+  private validateCompound(
+    messages: DecodedMessage[],
+    userAddress: string,
+    expectedValidators: string[] | null,
+    args?: ActionArguments,
+  ): ValidationResult {
+    const claims = messages.findIndex(
+      ({ typeUrl }) => typeUrl !== COSMOS_MESSAGE_TYPES.withdrawDelegatorReward,
+    );
+    if (claims <= 0) {
+      return this.blocked('A compound must claim rewards, then restake them');
+    }
+
+    for (const [messageIndex, message] of messages.entries()) {
+      const messageErr = this.validateMessage(
+        message,
+        messageIndex,
+        messageIndex < claims
+          ? COSMOS_MESSAGE_TYPES.withdrawDelegatorReward
+          : COSMOS_MESSAGE_TYPES.delegate,
+        userAddress,
+        expectedValidators,
+      );
+      if (messageErr) return messageErr;
+    }
+
+    const compoundErr = this.checkCompound(messages, args);
+    if (compoundErr) {
+      return this.blocked(compoundErr.reason, compoundErr.details);
+    }
+
+    const { denom } = this.config;
+    const restaked = messages
+      .slice(claims)
+      .reduce((total, { amount }) => total + BigInt(amount!.amount), 0n);
+    return {
+      ...this.safe(),
+      decoded: { messages },
+      compound: {
+        ...(isDefined(args?.amount) && {
+          claimed: toTransactionAmount(denom, BigInt(args.amount), this.config),
+        }),
+        restaked: toTransactionAmount(denom, restaked, this.config),
+      },
+    };
+  }
+
+  /**
+   * Why a transaction shaped as a compound, claims followed by delegations,
+   * diverts its rewards: a delegation to a validator it does not claim from,
+   * or more restaked than args.amount. Null when it does not, or is shaped
+   * otherwise.
+   */
+  private checkCompound(
+    messages: DecodedMessage[],
+    args?: ActionArguments,
+  ): { reason: string; details: Record<string, unknown> } | null {
+    const claims = messages.findIndex(
+      ({ typeUrl }) => typeUrl !== COSMOS_MESSAGE_TYPES.withdrawDelegatorReward,
+    );
+    const delegations = messages.slice(claims);
+    if (
+      claims <= 0 ||
+      delegations.some(
+        ({ typeUrl }) => typeUrl !== COSMOS_MESSAGE_TYPES.delegate,
+      )
+    ) {
+      return null;
+    }
+
+    const claimed = messages
+      .slice(0, claims)
+      .map(({ validatorAddress }) => validatorAddress);
+    for (const [index, { validatorAddress }] of delegations.entries()) {
+      if (!claimed.includes(validatorAddress)) {
+        return {
+          reason: 'Restakes with a validator the rewards are not claimed from',
+          details: {
+            messageIndex: claims + index,
+            expected: claimed,
+            actual: validatorAddress,
+          },
+        };
+      }
+    }
+
+    if (!isDefined(args?.amount)) return null;
+    const restaked = delegations.reduce(
+      (total, { amount }) =>
+        amount?.denom === this.config.denom && /^[0-9]+$/.test(amount.amount)
+          ? total + BigInt(amount.amount)
+          : total,
+      0n,
+    );
+    if (!/^[0-9]+$/.test(args.amount) || restaked > BigInt(args.amount)) {
+      return {
+        reason: 'Restakes more than the rewards claimed',
+        details: { expected: args.amount, actual: restaked.toString() },
+      };
+    }
+    return null;
+  }
+
+  private validateMessage(
+    message: DecodedMessage,
+    messageIndex: number,
+    expectedType: string,
+    userAddress: string,
+    expectedValidators: string[] | null,
+  ): ValidationResult | null {
+    if (message.typeUrl !== expectedType) {
+      return this.blocked('Unexpected message type', {
+        messageIndex,
+        expected: expectedType,
+        actual: message.typeUrl,
+      });
+    }
+
+    if (message.delegatorAddress !== userAddress) {
+      return this.blocked('Delegator address is not user address', {
+        messageIndex,
+        expected: userAddress,
+        actual: message.delegatorAddress,
+      });
+    }
+
+    if (!this.isValidatorOperatorAddress(message.validatorAddress)) {
+      return this.blocked('Invalid validator operator address', {
+        messageIndex,
+        actual: message.validatorAddress,
+      });
+    }
+
+    if (
+      expectedValidators !== null &&
+      !expectedValidators.includes(message.validatorAddress)
+    ) {
+      return this.blocked('Message targets an unexpected validator', {
+        messageIndex,
+        expected: expectedValidators,
+        actual: message.validatorAddress,
+      });
+    }
+
+    if (expectedType === COSMOS_MESSAGE_TYPES.withdrawDelegatorReward) {
+      return null;
+    }
+
+    const { amount } = message;
+    if (!isDefined(amount) || amount.denom !== this.config.denom) {
+      return this.blocked('Unexpected denomination', {
+        messageIndex,
+        expected: this.config.denom,
+        actual: amount?.denom,
+      });
+    }
+
+    if (!/^[1-9][0-9]*$/.test(amount.amount)) {
+      return this.blocked('Invalid amount', {
+        messageIndex,
+        actual: amount.amount,
+      });
+    }
+
+    return null;
+  }
+
+  private getExpectedValidators(args?: ActionArguments): string[] | null {
+    if (isNullOrUndefined(args)) return null;
+    if (
+      isDefined(args.validatorAddresses) &&
+      args.validatorAddresses.length > 0
+    ) {
+      return args.validatorAddresses;
+    }
+    if (isNonEmptyString(args.validatorAddress)) {
+      return [args.validatorAddress];
+    }
+    return null;
+  }
+
+  private isValidatorOperatorAddress(
+    address: string | undefined,
+  ): address is string {
+    const prefix = `${this.config.bech32Prefix}valoper1`;
+    return (
+      isNonEmptyString(address) &&
+      address.startsWith(prefix) &&
+      BECH32_DATA.test(address.slice(prefix.length))
+    );
+  }
+
+  private decodeTransaction(unsignedTransaction: string): {
+    transaction?: CosmosTransaction;
+    error?: string;
+  } {
+    try {
+      return { transaction: decodeCosmosTransaction(unsignedTransaction) };
+    } catch (error) {
+      return {
+        error: error instanceof Error ? error.message : String(error),
+      };
+    }
+  }
+}
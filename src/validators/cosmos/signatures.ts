@@ -0,0 +1,235 @@
+import { createHash, createPublicKey, verify } from 'crypto';
+import type { TransactionSignatures } from '../../types';
+import {
+  bytesField,
+  readFields,
+  varintField,
+  type ProtobufField,
+} from '../../utils/protobuf';
+
+const SECP256K1_PUBKEY = '/cosmos.crypto.secp256k1.PubKey';
+const SIGN_MODE_DIRECT = 1n;
+
+// DER SubjectPublicKeyInfo header of a compressed secp256k1 key, for
+// node:crypto to take the 33 bytes Cosmos carries
+const SECP256K1_SPKI_PREFIX = Buffer.from(
+  '3036301006072a8648ce3d020106052b8104000a032200',
+  'hex',
+);
+
+// The SDK only accepts signatures with s in the lower half of the order
+const SECP256K1_HALF_ORDER =
+  0x7fffffffffffffffffffffffffffffff5d576e7357a4501ddfe92f46681b20a0n;
+
+const BECH32_CHARSET = 'qpzry9x8gf2tvdw0s3jn54khce6mua7l';
+
+/**
+ * The signatures of a base64 TxRaw. A SIGN_MODE_DIRECT signature commits
+ * to the signer's account number, which the TxRaw does not carry, so it is
+ * only verified when accountNumber is given. JSON sign docs, SignDocs and
+ * TxRaws without signatures are unsigned. Returns null when encoded is
+ * none of these.
+ */
+export function readCosmosSignatures(
+  encoded: string,
+  chainId: string,
+  bech32Prefix: string,
+  accountNumber: string | undefined,
+): TransactionSignatures | null {
+  const trimmed = encoded.trim();
+  if (trimmed.startsWith('{')) return { signed: false, signers: [] };
+  if (!/^[A-Za-z0-9+/]+={0,2}$/.test(trimmed)) return null;
+
+  let fields: ProtobufField[];
+  try {
+    fields = readFields(Buffer.from(trimmed, 'base64'));
+  } catch {
+    return null;
+  }
+  const bodyBytes = bytesField(fields, 1);
+  if (!bodyBytes) return null;
+
+  // As decodeCosmosTransaction tells them apart: field 3 of a SignDoc is
+  // its chain ID
+  const signatures = fields
+    .filter((field) => field.field === 3 && field.wireType === 2)
+    .map((field) => field.value as Buffer);
+  const isSignDoc =
+    signatures.length === 1 &&
+    /^[A-Za-z0-9._-]{1,64}$/.test(signatures[0].toString('utf8'));
+  if (isSignDoc || signatures.every((signature) => signature.length === 0)) {
+    return { signed: false, signers: [] };
+  }
+
+  const authInfoBytes = bytesField(fields, 2) ?? Buffer.alloc(0);
+  let signer: { address: string; key: Buffer } | { error: string };
+  try {
+    signer = readSigner(authInfoBytes, bech32Prefix);
+  } catch {
+    return null;
+  }
+  const fail = (error: string) => ({ signed: true, signers: [], error });
+  if ('error' in signer) return fail(signer.error);
+  if (signatures.length !== 1) {
+    return fail('The transaction must carry one signature per signer');
+  }
+  if (accountNumber === undefined) {
+    return fail('accountNumber is needed to verify a Cosmos signature');
+  }
+
+  const signDoc = encodeSignDoc(
+    bodyBytes,
+    authInfoBytes,
+    chainId,
+    BigInt(accountNumber),
+  );
+  return verifySecp256k1(signDoc, signer.key, signatures[0])
+    ? { signed: true, signers: [signer.address] }
+    : fail(`The signature does not verify for ${signer.address}`);
+}
+
+// The one signer of AuthInfo, with the address its public key derives
+function readSigner(
+  authInfoBytes: Buffer,
+  bech32Prefix: string,
+): { address: string; key: Buffer } | { error: string } {
+  const signerInfos = readFields(authInfoBytes).filter(
+    (field) => field.field === 1 && field.wireType === 2,
+  );
+  if (signerInfos.length !== 1) {
+    return { error: 'Only transactions with a single signer are verified' };
+  }
+
+  const signerInfo = readFields(signerInfos[0].value as Buffer);
+  const publicKey = readFields(bytesField(signerInfo, 1) ?? Buffer.alloc(0));
+  const typeUrl = bytesField(publicKey, 1)?.toString('utf8');
+  const key = bytesField(
+    readFields(bytesField(publicKey, 2) ?? Buffer.alloc(0)),
+    1,
+  );
+  if (typeUrl !== SECP256K1_PUBKEY || key?.length !== 33) {
+    return { error: 'Only secp256k1 signers are verified' };
+  }
+
+  const modeInfo = readFields(bytesField(signerInfo, 2) ?? Buffer.alloc(0));
+  const single = readFields(bytesField(modeInfo, 1) ?? Buffer.alloc(0));
+  if (varintField(single, 1) !== SIGN_MODE_DIRECT) {
+    return { error: 'Only SIGN_MODE_DIRECT signatures are verified' };
+  }
+
+  const hash = createHash('ripemd160')
+    .update(createHash('sha256').update(key).digest())
+    .digest();
+  return { address: encodeBech32(bech32Prefix, hash), key };
+}
+
+// SignDoc { body_bytes = 1, auth_info_bytes = 2, chain_id = 3,
+// account_number = 4 }, as proto3 encodes it: a zero account number is
+// left out
+function encodeSignDoc(
+  bodyBytes: Buffer,
+  authInfoBytes: Buffer,
+  chainId: string,
+  accountNumber: bigint,
+): Buffer {
+  const varint = (value: bigint) => {
+    const bytes: number[] = [];
+    while (value > 0x7fn) {
+      bytes.push(Number(value & 0x7fn) | 0x80);
+      value >>= 7n;
+    }
+    bytes.push(Number(value));
+    return Buffer.from(bytes);
+  };
+  const bytes = (field: number, value: Buffer) =>
+    Buffer.concat([
+      varint(BigInt((field << 3) | 2)),
+      varint(BigInt(value.length)),
+      value,
+    ]);
+
+  return Buffer.concat([
+    bytes(1, bodyBytes),
+    authInfoBytes.length > 0 ? bytes(2, authInfoBytes) : Buffer.alloc(0),
+    bytes(3, Buffer.from(chainId, 'utf8')),
+    accountNumber > 0n
+      ? Buffer.concat([varint(BigInt(4 << 3)), varint(accountNumber)])
+      : Buffer.alloc(0),
+  ]);
+}
+
+// A 64-byte r || s signature over the SHA-256 of data
+function verifySecp256k1(
+  data: Buffer,
+  key: Buffer,
+  signature: Buffer,
+): boolean {
+  if (signature.length !== 64) return false;
+  const s = BigInt('0x' + signature.subarray(32).toString('hex'));
+  if (s > SECP256K1_HALF_ORDER) return false;
+
+  try {
+    const publicKey = createPublicKey({
+      key: Buffer.concat([SECP256K1_SPKI_PREFIX, key]),
+      format: 'der',
+      type: 'spki',
+    });
+    return verify(
+      'sha256',
+      data,
+      { key: publicKey, dsaEncoding: 'ieee-p1363' },
+      signature,
+    );
+  } catch {
+    return false; // Not a point on the curve
+  }
+}
+
+// BIP 173
+function encodeBech32(prefix: string, data: Buffer): string {
+  const words: number[] = [];
+  let accumulator = 0;
+  let bits = 0;
+  for (const byte of data) {
+    accumulator = (accumulator << 8) | byte;
+    bits += 8;
+    while (bits >= 5) {
+      bits -= 5;
+      words.push((accumulator >> bits) & 31);
+    }
+  }
+  if (bits > 0) words.push((accumulator << (5 - bits)) & 31);
+
+  const values = [
+    ...[...prefix].map((c) => c.charCodeAt(0) >> 5),
+    0,
+    ...[...prefix].map((c) => c.charCodeAt(0) & 31),
+    ...words,
+    0,
+    0,
+    0,
+    0,
+    0,
+    0,
+  ];
+  const checksum = polymod(values) ^ 1;
+  for (let i = 0; i < 6; i++) {
+    words.push((checksum >> (5 * (5 - i))) & 31);
+  }
+  return `${prefix}1${words.map((word) => BECH32_CHARSET[word]).join('')}`;
+}
+
+function polymod(values: number[]): number {
+  const generator = [
+    0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3,
+  ];
+  let checksum = 1;
+  for (const value of values) {
+    const top = checksum >> 25;
+    checksum = ((checksum & 0x1ffffff) << 5) ^ value;
+    for (let i = 0; i < 5; i++) {
+      if ((top >> i) & 1) checksum ^= generator[i];
+    }
+  }
+  return checksum;
+}
@@ -0,0 +1,183 @@
+import { DecodedMessage } from '../../types';
+import { asBytes, bytesField, readFields } from '../../utils/protobuf';
+import { isNonEmptyString } from '../../utils/validation';
+
+export const COSMOS_MESSAGE_TYPES = {
+  delegate: '/cosmos.staking.v1beta1.MsgDelegate',
+  undelegate: '/cosmos.staking.v1beta1.MsgUndelegate',
+  beginRedelegate: '/cosmos.staking.v1beta1.MsgBeginRedelegate',
+  withdrawDelegatorReward:
+    '/cosmos.distribution.v1beta1.MsgWithdrawDelegatorReward',
+};
+
+// Amino JSON names of the messages above
+const AMINO_TYPES: Record<string, string> = {
+  'cosmos-sdk/MsgDelegate': COSMOS_MESSAGE_TYPES.delegate,
+  'cosmos-sdk/MsgUndelegate': COSMOS_MESSAGE_TYPES.undelegate,
+  'cosmos-sdk/MsgBeginRedelegate': COSMOS_MESSAGE_TYPES.beginRedelegate,
+  'cosmos-sdk/MsgWithdrawDelegationReward':
+    COSMOS_MESSAGE_TYPES.withdrawDelegatorReward,
+};
+
+export interface CosmosTransaction {
+  chainId?: string; // Only present in sign docs
+  memo?: string;
+  messages: DecodedMessage[];
+}
+
+/**
+ * Decodes a Cosmos SDK transaction given as JSON (amino StdSignDoc or
+ * protobuf JSON) or as base64-encoded protobuf (TxRaw or SignDoc).
+ */
+export function decodeCosmosTransaction(encoded: string): CosmosTransaction {
+  const trimmed = encoded.trim();
+  if (trimmed.startsWith('{')) {
+    return decodeJsonTransaction(JSON.parse(trimmed));
+  }
+
+  if (!/^[A-Za-z0-9+/]+={0,2}$/.test(trimmed)) {
+    throw new Error('Transaction is neither JSON nor base64 encoded');
+  }
+  return decodeProtobufTransaction(Buffer.from(trimmed, 'base64'));
+}
+
+function decodeJsonTransaction(json: unknown): CosmosTransaction {
+  if (!isRecord(json)) {
+    throw new Error('Transaction JSON must be an object');
+  }
+
+  // Amino: { chain_id, memo, msgs: [{ type, value }] }
+  if (Array.isArray(json.msgs)) {
+    return {
+      chainId: optionalString(json.chain_id),
+      memo: optionalString(json.memo),
+      messages: json.msgs.map((msg) => {
+        if (!isRecord(msg) || !isNonEmptyString(msg.type)) {
+          throw new Error('Invalid amino message');
+        }
+        return toDecodedMessage(AMINO_TYPES[msg.type] ?? msg.type, msg.value);
+      }),
+    };
+  }
+
+  // Protobuf JSON: { body: { messages: [{ '@type', ... }], memo } }
+  const body = isRecord(json.body) ? json.body : json;
+  if (!Array.isArray(body.messages)) {
+    throw new Error('Transaction JSON has no messages');
+  }
+  return {
+    chainId: optionalString(json.chain_id ?? json.chainId),
+    memo: optionalString(body.memo),
+    messages: body.messages.map((msg) => {
+      if (!isRecord(msg) || !isNonEmptyString(msg['@type'])) {
+        throw new Error('Invalid protobuf JSON message');
+      }
+      return toDecodedMessage(msg['@type'], msg);
+    }),
+  };
+}
+
+function toDecodedMessage(typeUrl: string, value: unknown): DecodedMessage {
+  const fields = isRecord(value) ? value : {};
+  const amount = fields.amount;
+
+  return {
+    typeUrl,
+    delegatorAddress: optionalString(
+      fields.delegator_address ?? fields.delegatorAddress,
+    ),
+    validatorAddress: optionalString(
+      fields.validator_address ??
+        fields.validatorAddress ??
+        fields.validator_src_address ??
+        fields.validatorSrcAddress,
+    ),
+    validatorDstAddress: optionalString(
+      fields.validator_dst_address ?? fields.validatorDstAddress,
+    ),
+    amount: isRecord(amount)
+      ? {
+          denom: optionalString(amount.denom) ?? '',
+          amount: optionalString(amount.amount) ?? '',
+        }
+      : undefined,
+  };
+}
+
+function decodeProtobufTransaction(bytes: Buffer): CosmosTransaction {
+  // TxRaw and SignDoc both carry the body bytes in field 1. Field 3 is the
+  // signature list of a TxRaw, but the chain ID of a SignDoc.
+  const fields = readFields(bytes);
+  const bodyBytes = bytesField(fields, 1);
+  if (!bodyBytes) {
+    throw new Error('Transaction has no body');
+  }
+
+  const field3 = bytesField(fields, 3);
+  const chainId =
+    field3 && /^[A-Za-z0-9._-]{1,64}$/.test(field3.toString('utf8'))
+      ? field3.toString('utf8')
+      : undefined;
+
+  const body = readFields(bodyBytes);
+  const memo = bytesField(body, 2);
+
+  return {
+    chainId,
+    memo: memo?.toString('utf8'),
+    messages: body
+      .filter((field) => field.field === 1)
+      .map((field) => decodeProtobufAny(asBytes(field))),
+  };
+}
+
+function decodeProtobufAny(bytes: Buffer): DecodedMessage {
+  const any = readFields(bytes);
+  const typeUrl = bytesField(any, 1)?.toString('utf8') ?? '';
+  const value = readFields(bytesField(any, 2) ?? Buffer.alloc(0));
+  const text = (field: number) => bytesField(value, field)?.toString('utf8');
+  const coin = (field: number) => {
+    const coinBytes = bytesField(value, field);
+    if (!coinBytes) return undefined;
+    const coinFields = readFields(coinBytes);
+    return {
+      denom: bytesField(coinFields, 1)?.toString('utf8') ?? '',
+      amount: bytesField(coinFields, 2)?.toString('utf8') ?? '',
+    };
+  };
+
+  switch (typeUrl) {
+    case COSMOS_MESSAGE_TYPES.delegate:
+    case COSMOS_MESSAGE_TYPES.undelegate:
+      return {
+        typeUrl,
+        delegatorAddress: text(1),
+        validatorAddress: text(2),
+        amount: coin(3),
+      };
+    case COSMOS_MESSAGE_TYPES.beginRedelegate:
+      return {
+        typeUrl,
+        delegatorAddress: text(1),
+        validatorAddress: text(2),
+        validatorDstAddress: text(3),
+        amount: coin(4),
+      };
+    case COSMOS_MESSAGE_TYPES.withdrawDelegatorReward:
+      return {
+        typeUrl,
+        delegatorAddress: text(1),
+        validatorAddress: text(2),
+      };
+    default:
+      return { typeUrl };
+  }
+}
+
+function isRecord(value: unknown): value is Record<string, unknown> {
+  return typeof value === 'object' && value !== null && !Array.isArray(value);
+}
+
+function optionalString(value: unknown): string | undefined {
+  return typeof value === 'string' ? value : undefined;
+}
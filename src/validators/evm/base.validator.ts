@@ -1,6 +1,37 @@
 import { BaseValidator } from '../base.validator';
-import { ValidationResult } from '../../types';
-import { isDefined, isNonEmptyString } from '../../utils/validation';
+import {
+  AbiFunction,
+  AccessListEntry,
+  ClaimCall,
+  DecodeResult,
+  DecodedTransaction,
+  Delegation,
+  GasLimitRange,
+  ImplementationChange,
+  MulticallCall,
+  MulticallTransaction,
+  PrivilegedCall,
+  ReasonCode,
+  SafeTransaction,
+  SimulationCall,
+  TokenApproval,
+  TransactionAmount,
+  TransactionType,
+  TypedData,
+  TypedDataField,
+  Permit2Details,
+  SignedAuthorization,
+  ValidationResult,
+  ValidationWarning,
+  WrappedTransaction,
+} from '../../types';
+import {
+  isDefined,
+  isEnsName,
+  isNonEmptyString,
+} from '../../utils/validation';
+import { AssetInfo, toTransactionAmount } from '../../utils/amount';
+import { toDeadline } from '../../utils/deadline';
 import { ethers } from 'ethers';
 
 export interface EVMTransaction {
@@ -15,9 +46,1399 @@ export interface EVMTransaction {
   maxPriorityFeePerGas?: string | number;
   chainId?: string | number;
   type?: string | number;
+  accessList?: AccessListEntry[];
+  authorizationList?: SignedAuthorization[];
+}
+
+// ethers returns bigints and array-like Results, neither of which
+// JSON.stringify can represent as-is
+function toJsonValue(value: unknown): unknown {
+  if (typeof value === 'bigint') return value.toString();
+  if (Array.isArray(value)) return Array.from(value, toJsonValue);
+  return value;
+}
+
+// The arguments of a call by name, or by position where the ABI names none
+function toDecodedArgs(
+  parsed: ethers.TransactionDescription,
+): Record<string, unknown> {
+  return Object.fromEntries(
+    parsed.fragment.inputs.map((input, i) => [
+      input.name || String(i),
+      toJsonValue(parsed.args[i]),
+    ]),
+  );
+}
+
+const erc20ApproveInterface = new ethers.Interface([
+  'function approve(address spender, uint256 amount) returns (bool)',
+]);
+
+// Gnosis Safe's entry point for executing a transaction its owners signed
+const safeInterface = new ethers.Interface([
+  'function execTransaction(address to, uint256 value, bytes data, uint8 operation, uint256 safeTxGas, uint256 baseGas, uint256 gasPrice, address gasToken, address refundReceiver, bytes signatures) returns (bool)',
+]);
+const SAFE_OPERATIONS = ['CALL', 'DELEGATECALL'] as const;
+
+// OpenZeppelin's MinimalForwarder, an ERC-2771 forwarder: it calls req.to
+// with req.from appended to req.data, which contracts that trust it take
+// as the call's sender. The request is signed in full, nonce included
+const forwarderInterface = new ethers.Interface([
+  'function execute((address from, address to, uint256 value, uint256 gas, uint256 nonce, bytes data) req, bytes signature) payable returns (bool, bytes)',
+]);
+const FORWARDER_DOMAIN = { name: 'MinimalForwarder', version: '0.0.1' };
+const FORWARD_REQUEST_FIELDS = [
+  { name: 'from', type: 'address' },
+  { name: 'to', type: 'address' },
+  { name: 'value', type: 'uint256' },
+  { name: 'gas', type: 'uint256' },
+  { name: 'nonce', type: 'uint256' },
+  { name: 'data', type: 'bytes' },
+];
+
+// The struct Safe owners sign to confirm a transaction, as execTransaction
+// takes it less the signatures
+const SAFE_TX_FIELDS = [
+  { name: 'to', type: 'address' },
+  { name: 'value', type: 'uint256' },
+  { name: 'data', type: 'bytes' },
+  { name: 'operation', type: 'uint8' },
+  { name: 'safeTxGas', type: 'uint256' },
+  { name: 'baseGas', type: 'uint256' },
+  { name: 'gasPrice', type: 'uint256' },
+  { name: 'gasToken', type: 'address' },
+  { name: 'refundReceiver', type: 'address' },
+  { name: 'nonce', type: 'uint256' },
+];
+
+// Reward claims as staking contracts and gauges name them, each with the
+// position of the argument naming who is paid, or null for those that pay
+// msg.sender. Synthetix-style getReward(address) and Curve's
+// claim_rewards(address) claim for, and pay, the account they name
+const CLAIM_VARIANTS: Record<string, number | null> = {
+  'claim()': null,
+  'claim(address)': 0,
+  'claimRewards()': null,
+  'claimRewards(address)': 0,
+  'getReward()': null,
+  'getReward(address)': 0,
+  'getReward(address,bool)': 0,
+  'claim_rewards()': null,
+  'claim_rewards(address)': 0,
+  'claim_rewards(address,address)': 1,
+};
+const claimInterface = new ethers.Interface(
+  Object.keys(CLAIM_VARIANTS).map((signature) => `function ${signature}`),
+);
+
+// Multicall3 is deployed at this address on every chain it supports, and
+// makes each call itself
+const MULTICALL3_ADDRESS = '0xcA11bde05977b3631167028862bE2a173976CA11';
+const multicall3Interface = new ethers.Interface([
+  'function aggregate((address target, bytes callData)[] calls) payable returns (uint256 blockNumber, bytes[] returnData)',
+  'function blockAndAggregate((address target, bytes callData)[] calls) payable returns (uint256 blockNumber, bytes32 blockHash, (bool success, bytes returnData)[] returnData)',
+  'function tryAggregate(bool requireSuccess, (address target, bytes callData)[] calls) payable returns ((bool success, bytes returnData)[] returnData)',
+  'function tryBlockAndAggregate(bool requireSuccess, (address target, bytes callData)[] calls) payable returns (uint256 blockNumber, bytes32 blockHash, (bool success, bytes returnData)[] returnData)',
+  'function aggregate3((address target, bool allowFailure, bytes callData)[] calls) payable returns ((bool success, bytes returnData)[] returnData)',
+  'function aggregate3Value((address target, bool allowFailure, uint256 value, bytes callData)[] calls) payable returns ((bool success, bytes returnData)[] returnData)',
+]);
+
+// OpenZeppelin's Multicall and Uniswap's deadline variant delegatecall the
+// contract itself, so each call keeps the sender and sees the whole value
+const selfMulticallInterface = new ethers.Interface([
+  'function multicall(bytes[] data) payable returns (bytes[] results)',
+  'function multicall(uint256 deadline, bytes[] data) payable returns (bytes[] results)',
+]);
+
+// EIP-1967 and UUPS proxies upgrade themselves; a TransparentUpgradeableProxy
+// is upgraded through its ProxyAdmin, which names the proxy
+const proxyUpgradeInterface = new ethers.Interface([
+  'function upgradeTo(address newImplementation)',
+  'function upgradeToAndCall(address newImplementation, bytes data) payable',
+]);
+const proxyAdminInterface = new ethers.Interface([
+  'function upgrade(address proxy, address implementation)',
+  'function upgradeAndCall(address proxy, address implementation, bytes data) payable',
+]);
+
+// Ownable, Pausable and AccessControl functions only a contract's owner or
+// an admin role may call, on every contract a yield lists
+const PRIVILEGED_FUNCTIONS = [
+  'transferOwnership(address)',
+  'renounceOwnership()',
+  'acceptOwnership()',
+  'pause()',
+  'unpause()',
+  'grantRole(bytes32,address)',
+  'revokeRole(bytes32,address)',
+];
+
+// Allowances this large are never meant to be spent down; wallets and dapps
+// commonly use 2^256-1 or values just below it
+const UNLIMITED_APPROVAL_THRESHOLD = 1n << 255n;
+
+// EIP-2612 Permit(owner, spender, value, nonce, deadline), in this order
+const PERMIT_FIELDS = [
+  { name: 'owner', type: 'address' },
+  { name: 'spender', type: 'address' },
+  { name: 'value', type: 'uint256' },
+  { name: 'nonce', type: 'uint256' },
+  { name: 'deadline', type: 'uint256' },
+];
+
+// Uniswap's Permit2, deployed at the same address on every chain
+const PERMIT2_ADDRESS = '0x000000000022D473030F116dDEE9F6B43aC78BA3';
+
+const PERMIT2_DETAILS_FIELDS = [
+  { name: 'token', type: 'address' },
+  { name: 'amount', type: 'uint160' },
+  { name: 'expiration', type: 'uint48' },
+  { name: 'nonce', type: 'uint48' },
+];
+
+// PermitSingle takes one PermitDetails, PermitBatch an array of them
+const PERMIT2_FIELDS = (detailsType: string) => [
+  { name: 'details', type: detailsType },
+  { name: 'spender', type: 'address' },
+  { name: 'sigDeadline', type: 'uint256' },
+];
+
+const MAX_UINT160 = (1n << 160n) - 1n;
+const MAX_UINT48 = (1n << 48n) - 1n;
+
+// Approvals and WETH wrapping touch a single contract; staking and vault
+// operations may route through several
+const SINGLE_CONTRACT_GAS_RANGE: GasLimitRange = {
+  min: 21_000n,
+  max: 150_000n,
+};
+const MULTI_CONTRACT_GAS_RANGE: GasLimitRange = {
+  min: 21_000n,
+  max: 1_500_000n,
+};
+const SINGLE_CONTRACT_TYPES = new Set([
+  TransactionType.APPROVAL,
+  TransactionType.WRAP,
+  TransactionType.UNWRAP,
+]);
+
+// Native currencies of the chains Shield's yields are deployed on
+const NATIVE_CURRENCIES: Record<number, AssetInfo> = {
+  1: { symbol: 'ETH', decimals: 18 },
+  10: { symbol: 'ETH', decimals: 18 },
+  56: { symbol: 'BNB', decimals: 18 },
+  100: { symbol: 'XDAI', decimals: 18 },
+  130: { symbol: 'ETH', decimals: 18 },
+  137: { symbol: 'POL', decimals: 18 },
+  143: { symbol: 'MON', decimals: 18 },
+  146: { symbol: 'S', decimals: 18 },
+  999: { symbol: 'HYPE', decimals: 18 },
+  8453: { symbol: 'ETH', decimals: 18 },
+  9745: { symbol: 'XPL', decimals: 18 },
+  42161: { symbol: 'ETH', decimals: 18 },
+  43114: { symbol: 'AVAX', decimals: 18 },
+  59144: { symbol: 'ETH', decimals: 18 },
+  747474: { symbol: 'ETH', decimals: 18 },
+};
+
+// Every transaction pays at least this much gas before executing anything
+const INTRINSIC_GAS = 21_000n;
+
+// Wallets send chain IDs as numbers, decimal strings or hex strings
+function parseChainId(value: string | number): number {
+  if (typeof value === 'number') return value;
+  return /^(\d+|0x[0-9a-fA-F]+)$/.test(value) ? Number(value) : NaN;
+}
+
+// Whether an EIP-712 struct declares exactly expected, in order
+function hasFields(
+  fields: TypedDataField[] | undefined,
+  expected: TypedDataField[],
+): boolean {
+  return (
+    isDefined(fields) &&
+    fields.length === expected.length &&
+    fields.every(
+      (f, i) => f.name === expected[i].name && f.type === expected[i].type,
+    )
+  );
+}
+
+// The quantities of a transaction, which upstreams give as JSON numbers,
+// decimal strings or hex strings alike
+const NUMERIC_FIELDS = [
+  'value',
+  'nonce',
+  'gasLimit',
+  'gasPrice',
+  'maxFeePerGas',
+  'maxPriorityFeePerGas',
+  'chainId',
+] as const;
+
+// Typed-data integers arrive as JSON numbers, decimal strings or hex strings
+function toUint256(value: unknown): bigint | null {
+  if (typeof value === 'number') {
+    return Number.isSafeInteger(value) && value >= 0 ? BigInt(value) : null;
+  }
+  if (typeof value !== 'string' || !/^(\d+|0x[0-9a-fA-F]+)$/.test(value)) {
+    return null;
+  }
+  const n = BigInt(value);
+  return n < 1n << 256n ? n : null;
+}
+
+function isAccessList(value: unknown): boolean {
+  return (
+    Array.isArray(value) &&
+    value.every(
+      (entry) =>
+        ethers.isAddress(entry?.address) &&
+        Array.isArray(entry.storageKeys) &&
+        entry.storageKeys.every((key: unknown) => ethers.isHexString(key, 32)),
+    )
+  );
+}
+
+// Authorizations are signed tuples; an unsigned one could not be included
+function isAuthorizationList(value: unknown): boolean {
+  return (
+    Array.isArray(value) &&
+    value.length > 0 &&
+    value.every(
+      (entry) =>
+        toUint256(entry?.chainId) !== null &&
+        ethers.isAddress(entry.address) &&
+        toUint256(entry.nonce) !== null &&
+        (entry.yParity === 0 || entry.yParity === 1) &&
+        ethers.isHexString(entry.r, 32) &&
+        ethers.isHexString(entry.s, 32),
+    )
+  );
+}
+
+/**
+ * Whether tx is a legacy transaction with chain ID 0, which is signed
+ * without EIP-155 replay protection. Typed transactions always sign their
+ * chain ID, so one of 0 is valid on no chain rather than every chain.
+ */
+function isUnprotected(tx: EVMTransaction): boolean {
+  if (!isDefined(tx.chainId) || parseChainId(tx.chainId) !== 0) return false;
+  if (isDefined(tx.type)) return toUint256(tx.type) === 0n;
+  return (
+    !isDefined(tx.maxFeePerGas) &&
+    !isDefined(tx.maxPriorityFeePerGas) &&
+    !isDefined(tx.accessList) &&
+    !isDefined(tx.authorizationList)
+  );
+}
+
+/**
+ * Rejects fields that contradict the transaction's type: legacy (0),
+ * EIP-2930 access list (1), EIP-1559 (2) or EIP-7702 set code (4). Without
+ * a type, the fee fields must still all belong to one of them.
+ */
+function checkTransactionType(tx: EVMTransaction): string | null {
+  const hasGasPrice = isDefined(tx.gasPrice);
+  const hasDynamicFees =
+    isDefined(tx.maxFeePerGas) || isDefined(tx.maxPriorityFeePerGas);
+  const hasAuthorizations = isDefined(tx.authorizationList);
+  if (hasGasPrice && hasDynamicFees) {
+    return 'Transaction mixes legacy gasPrice with EIP-1559 fee fields';
+  }
+  if (isDefined(tx.accessList) && !isAccessList(tx.accessList)) {
+    return 'accessList must be a list of { address, storageKeys }';
+  }
+  if (hasAuthorizations && !isAuthorizationList(tx.authorizationList)) {
+    return 'authorizationList must be a non-empty list of { chainId, address, nonce, yParity, r, s }';
+  }
+  if (!isDefined(tx.type)) {
+    return hasAuthorizations && hasGasPrice
+      ? 'Transactions with an authorizationList cannot carry gasPrice'
+      : null;
+  }
+
+  const type = toUint256(tx.type);
+  if (hasAuthorizations && type !== 4n) {
+    return `Type ${tx.type} transactions cannot carry an authorizationList`;
+  }
+  switch (type) {
+    case 0n:
+      if (hasDynamicFees) {
+        return 'Type 0 transactions cannot carry EIP-1559 fee fields';
+      }
+      if (isDefined(tx.accessList)) {
+        return 'Type 0 transactions cannot carry an accessList';
+      }
+      return null;
+    case 1n:
+      return hasDynamicFees
+        ? 'Type 1 transactions cannot carry EIP-1559 fee fields'
+        : null;
+    case 2n:
+      return hasGasPrice ? 'Type 2 transactions cannot carry gasPrice' : null;
+    case 4n:
+      if (hasGasPrice) return 'Type 4 transactions cannot carry gasPrice';
+      if (!hasAuthorizations) {
+        return 'Type 4 transactions must carry an authorizationList';
+      }
+      return isNonEmptyString(tx.to)
+        ? null
+        : 'Type 4 transactions cannot create contracts';
+    default:
+      return `Unsupported transaction type: ${tx.type}`;
+  }
+}
+
+/**
+ * Rejects gas fields no node would accept, or that would never be mined.
+ * All fields are optional, as wallets usually fill them in.
+ */
+function checkGasFields(tx: EVMTransaction): string | null {
+  const fields = [
+    'gasLimit',
+    'gasPrice',
+    'maxFeePerGas',
+    'maxPriorityFeePerGas',
+  ] as const;
+  const values: Partial<Record<(typeof fields)[number], bigint>> = {};
+  for (const field of fields) {
+    if (!isDefined(tx[field])) continue;
+    const value = toUint256(tx[field]);
+    if (value === null) return `Invalid ${field} format: ${tx[field]}`;
+    values[field] = value;
+  }
+
+  const { gasLimit, gasPrice, maxFeePerGas, maxPriorityFeePerGas } = values;
+  if (isDefined(gasLimit) && gasLimit < INTRINSIC_GAS) {
+    return `Gas limit ${gasLimit} is below the ${INTRINSIC_GAS} every transaction needs`;
+  }
+  if (gasPrice === 0n || maxFeePerGas === 0n) {
+    return 'Zero gas price; the transaction would never be mined';
+  }
+  if (
+    isDefined(maxFeePerGas) &&
+    isDefined(maxPriorityFeePerGas) &&
+    maxPriorityFeePerGas > maxFeePerGas
+  ) {
+    return 'maxPriorityFeePerGas exceeds maxFeePerGas';
+  }
+  return null;
 }
 
 export abstract class BaseEVMValidator extends BaseValidator {
+  /**
+   * Interfaces tried, in order, when decoding a transaction.
+   */
+  protected getDecodeInterfaces(): ethers.Interface[] {
+    return [];
+  }
+
+  /**
+   * The functions each transaction type may call, for getAbiFunctions.
+   */
+  protected getTransactionFunctions(): Partial<
+    Record<TransactionType, ethers.FunctionFragment[]>
+  > {
+    return {};
+  }
+
+  getAbiFunctions(): AbiFunction[] {
+    return Object.entries(this.getTransactionFunctions()).flatMap(
+      ([transactionType, fragments]) =>
+        (fragments ?? []).map((fragment) => ({
+          transactionType: transactionType as TransactionType,
+          name: fragment.name,
+          selector: fragment.selector,
+          signature: fragment.format(),
+          inputs: fragment.inputs.map((input) => ({
+            name: input.name,
+            type: input.format(),
+          })),
+          payable: fragment.payable,
+        })),
+    );
+  }
+
+  decode(unsignedTransaction: string): DecodeResult {
+    const decoded = this.decodeEVMTransaction(unsignedTransaction);
+    if (!decoded.isValid || !decoded.transaction) {
+      return {
+        decoded: null,
+        reason: `Failed to decode EVM transaction: ${decoded.error}`,
+      };
+    }
+
+    const tx = decoded.transaction;
+    const parsed = this.parseDecodableCall(tx);
+    if (!isDefined(parsed)) {
+      return {
+        decoded: null,
+        reason: 'No known ABI matches the transaction data',
+      };
+    }
+
+    return {
+      decoded: {
+        functionName: parsed.name,
+        selector: parsed.selector,
+        functionSignature: parsed.fragment.format(),
+        args: parsed.fragment.inputs.map((input, i) => ({
+          name: input.name,
+          type: input.type,
+          value: toJsonValue(parsed.args[i]),
+        })),
+        decodedArgs: toDecodedArgs(parsed),
+        approval: this.getApproval(unsignedTransaction),
+        accessList: tx.accessList,
+        authorizationList: this.getDelegations(unsignedTransaction),
+        value: (toUint256(tx.value ?? 0) ?? 0n).toString(),
+        calldataBytes: this.getCalldataSize(unsignedTransaction),
+      },
+    };
+  }
+
+  getDecodedArgs(
+    unsignedTransaction: string,
+  ): Record<string, unknown> | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    const parsed = tx ? this.parseDecodableCall(tx) : undefined;
+    return isDefined(parsed) ? toDecodedArgs(parsed) : undefined;
+  }
+
+  // Safe transactions, forwarded calls and multicalls decode as their
+  // outer call, inner calls included
+  private parseDecodableCall(
+    tx: EVMTransaction,
+  ): ethers.TransactionDescription | undefined {
+    for (const iface of [
+      ...this.getDecodeInterfaces(),
+      safeInterface,
+      forwarderInterface,
+      selfMulticallInterface,
+      multicall3Interface,
+    ]) {
+      const parsed = this.tryParseTransaction(tx, iface);
+      if (isDefined(parsed)) return parsed;
+    }
+    return undefined;
+  }
+
+  // Quantities as decimal strings, however the transaction gave them
+  getNormalizedTransaction(unsignedTransaction: string): string | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    return tx && JSON.stringify(tx);
+  }
+
+  getSigner(unsignedTransaction: string): string | undefined {
+    const decoded = this.decodeEVMTransaction(unsignedTransaction);
+    const from = decoded.transaction?.from;
+    return isNonEmptyString(from) ? from : undefined;
+  }
+
+  getChainId(unsignedTransaction: string): string | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    const chainId = tx ? this.getNumericChainId(tx) : null;
+    return chainId === null ? undefined : String(chainId);
+  }
+
+  isReplayable(unsignedTransaction: string): boolean {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    return isDefined(tx) && isUnprotected(tx);
+  }
+
+  // Functions not declared payable revert on value, or, behind a contract
+  // that forwards it, lose it
+  getNativeValue(
+    unsignedTransaction: string,
+  ): { value: bigint; payable: boolean; signature: string } | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    if (!tx) return undefined;
+
+    for (const iface of [
+      ...this.getDecodeInterfaces(),
+      safeInterface,
+      forwarderInterface,
+      selfMulticallInterface,
+      multicall3Interface,
+      erc20ApproveInterface,
+    ]) {
+      const parsed = this.tryParseTransaction(tx, iface);
+      if (!isDefined(parsed)) continue;
+      return {
+        value: toUint256(tx.value ?? 0) ?? 0n,
+        payable: parsed.fragment.payable,
+        signature: parsed.fragment.format(),
+      };
+    }
+    return undefined;
+  }
+
+  getSelector(unsignedTransaction: string): string | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    const data = tx?.data;
+    if (!isNonEmptyString(data) || data.length < 10) return undefined;
+    return data.slice(0, 10).toLowerCase();
+  }
+
+  getFunctionSignature(
+    unsignedTransaction: string,
+  ): Pick<DecodedTransaction, 'selector' | 'functionSignature'> | undefined {
+    const selector = this.getSelector(unsignedTransaction);
+    if (!isDefined(selector)) return undefined;
+
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    const parsed = tx ? this.parseDecodableCall(tx) : undefined;
+    return {
+      selector,
+      functionSignature: parsed?.fragment.format() ?? 'unknown',
+    };
+  }
+
+  getContractAddresses(unsignedTransaction: string): string[] {
+    const decoded = this.decodeEVMTransaction(unsignedTransaction);
+    const to = decoded.transaction?.to;
+    if (!isNonEmptyString(to)) return [];
+
+    // A Safe calls the inner contract on the user's behalf, and a
+    // multicall each of the targets it batches
+    const wrapped = this.getWrappedTransaction(unsignedTransaction);
+    if (wrapped) {
+      return [to, ...this.getContractAddresses(wrapped.unsignedTransaction)];
+    }
+    const calls = this.getMulticall(unsignedTransaction)?.multicall.calls;
+    const targets = (calls ?? [])
+      .map((call) => call.target)
+      .filter(
+        (target, i, all) =>
+          !this.isSameAddress(target, to) &&
+          all.findIndex((other) => this.isSameAddress(other, target)) === i,
+      );
+    return [to, ...targets];
+  }
+
+  getMismatchCode(unsignedTransaction: string): ReasonCode | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    if (!tx) return undefined;
+
+    const { contracts } = this.getCapabilities();
+    if (
+      !isNonEmptyString(tx.to) ||
+      !contracts.some((contract) => this.isSameAddress(contract, tx.to!))
+    ) {
+      return 'RECIPIENT_MISMATCH';
+    }
+    // A call that pulls a token the yield has nothing to do with, such as a
+    // lookalike of its own
+    const spend = this.getTokenSpend(unsignedTransaction);
+    const stakeTokens = this.getStakeTokens();
+    if (
+      isDefined(spend) &&
+      stakeTokens.length > 0 &&
+      ![...stakeTokens, ...contracts].some((token) =>
+        this.isSameAddress(token, spend.token),
+      )
+    ) {
+      return 'TOKEN_MISMATCH';
+    }
+    if (
+      this.getDecodeInterfaces().length > 0 &&
+      this.decode(unsignedTransaction).decoded === null
+    ) {
+      return 'SELECTOR_MISMATCH';
+    }
+    return undefined;
+  }
+
+  getMatchPath(unsignedTransaction: string): string {
+    const wrapped = this.getWrappedTransaction(unsignedTransaction);
+    if (wrapped?.wrapper.detectedType === 'ERC2771_FORWARD') {
+      return 'evm-forwarder';
+    }
+    if (wrapped) return 'evm-safe-wrapper';
+    if (this.getMulticall(unsignedTransaction)) return 'evm-multicall';
+    return 'evm-abi-match';
+  }
+
+  getWrappedTransaction(
+    unsignedTransaction: string,
+  ): WrappedTransaction | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    if (!tx || !isNonEmptyString(tx.to)) return undefined;
+
+    const forwarded = this.tryParseTransaction(tx, forwarderInterface);
+    if (forwarded) return this.getForwardedCall(tx, tx.to, forwarded);
+
+    const parsed = this.tryParseTransaction(tx, safeInterface);
+    const operation = parsed && SAFE_OPERATIONS[Number(parsed.args[3])];
+    if (!parsed || !isDefined(operation)) return undefined;
+
+    const [to, value, data] = parsed.args;
+    return {
+      unsignedTransaction: JSON.stringify({
+        from: tx.to,
+        to,
+        value: ethers.toQuantity(value),
+        data,
+        chainId: tx.chainId,
+      }),
+      wrapper: {
+        detectedType: 'SAFE_EXEC_TRANSACTION',
+        address: tx.to,
+        operation,
+      },
+    };
+  }
+
+  // The call a forwarder relays, sent by the request's from as the contract
+  // called sees it, and whom the request's signature recovers to
+  private getForwardedCall(
+    tx: EVMTransaction,
+    forwarder: string,
+    parsed: ethers.TransactionDescription,
+  ): WrappedTransaction {
+    const [req, signature] = parsed.args;
+    const request = {
+      from: req.from,
+      to: req.to,
+      value: req.value,
+      gas: req.gas,
+      nonce: req.nonce,
+      data: req.data,
+    };
+    let signer: string | null;
+    try {
+      signer = ethers.verifyTypedData(
+        {
+          ...FORWARDER_DOMAIN,
+          chainId: tx.chainId ?? this.getCapabilities().chainId,
+          verifyingContract: forwarder,
+        },
+        { ForwardRequest: FORWARD_REQUEST_FIELDS },
+        request,
+        signature,
+      );
+    } catch {
+      signer = null; // Not 65 bytes, not a point on the curve, or a high s
+    }
+
+    return {
+      unsignedTransaction: JSON.stringify({
+        from: request.from,
+        to: request.to,
+        value: ethers.toQuantity(request.value),
+        data: request.data,
+        chainId: tx.chainId,
+      }),
+      wrapper: {
+        detectedType: 'ERC2771_FORWARD',
+        address: forwarder,
+        operation: 'CALL',
+        ...(isNonEmptyString(tx.from) && { relayer: tx.from }),
+        signer,
+      },
+    };
+  }
+
+  getSafeTransaction(typedData: TypedData): SafeTransaction | undefined {
+    const { domain, types, primaryType, message } = typedData;
+    if (primaryType !== 'SafeTx') return undefined;
+
+    if (!hasFields(types?.SafeTx, SAFE_TX_FIELDS)) {
+      throw new Error('SafeTx type does not match the Safe contracts');
+    }
+    const { chainId } = this.getCapabilities();
+    if (String(domain?.chainId) !== chainId) {
+      throw new Error(
+        `Typed data chain ID ${domain?.chainId} does not match the yield's ${chainId}`,
+      );
+    }
+    const safe = domain.verifyingContract;
+    if (!isNonEmptyString(safe) || !ethers.isAddress(safe)) {
+      throw new Error('Typed data verifyingContract is not a Safe address');
+    }
+
+    const { to, data, gasToken, refundReceiver } = message ?? {};
+    const [value, operation, safeTxGas, baseGas, gasPrice] = [
+      message?.value,
+      message?.operation,
+      message?.safeTxGas,
+      message?.baseGas,
+      message?.gasPrice,
+    ].map(toUint256);
+    if (
+      ![to, gasToken, refundReceiver].every(
+        (address) => typeof address === 'string' && ethers.isAddress(address),
+      ) ||
+      typeof data !== 'string' ||
+      !ethers.isHexString(data) ||
+      [value, safeTxGas, baseGas, gasPrice].includes(null) ||
+      toUint256(message.nonce) === null
+    ) {
+      throw new Error('SafeTx message is not well formed');
+    }
+    if (operation === null || !isDefined(SAFE_OPERATIONS[Number(operation)])) {
+      throw new Error(`Unknown SafeTx operation ${String(message.operation)}`);
+    }
+
+    return {
+      unsignedTransaction: JSON.stringify({
+        to: safe,
+        value: '0x0',
+        data: safeInterface.encodeFunctionData('execTransaction', [
+          to,
+          value,
+          data,
+          operation,
+          safeTxGas,
+          baseGas,
+          gasPrice,
+          gasToken,
+          refundReceiver,
+          '0x',
+        ]),
+        chainId: domain.chainId,
+      }),
+      safeTxHash: ethers.TypedDataEncoder.hash(
+        domain,
+        { SafeTx: SAFE_TX_FIELDS },
+        message,
+      ),
+    };
+  }
+
+  getMulticall(unsignedTransaction: string): MulticallTransaction | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    const value = toUint256(tx?.value ?? 0);
+    if (!tx || !isNonEmptyString(tx.to) || value === null) return undefined;
+
+    let parsed = this.tryParseTransaction(tx, selfMulticallInterface);
+    let calls: MulticallCall[];
+    if (parsed) {
+      const datas: string[] = Array.from(parsed.args[parsed.args.length - 1]);
+      calls = datas.map((data) => ({
+        target: tx.to!,
+        value: value.toString(),
+        data,
+        allowFailure: false,
+      }));
+    } else {
+      if (!this.isSameAddress(tx.to, MULTICALL3_ADDRESS)) return undefined;
+      parsed = this.tryParseTransaction(tx, multicall3Interface);
+      if (!parsed) return undefined;
+      calls = this.getMulticall3Calls(parsed);
+    }
+
+    const self = parsed.name === 'multicall';
+    return {
+      multicall: {
+        detectedType: self ? 'MULTICALL' : 'MULTICALL3_AGGREGATE',
+        address: tx.to,
+        functionName: parsed.name,
+        value: value.toString(),
+        calls,
+      },
+      unsignedTransactions: calls.map((call) =>
+        JSON.stringify({
+          from: self ? tx.from : tx.to,
+          to: call.target,
+          value: ethers.toQuantity(BigInt(call.value)),
+          data: call.data,
+          chainId: tx.chainId,
+        }),
+      ),
+    };
+  }
+
+  // Uniswap's multicall(deadline, data) reverts once the deadline passes
+  getDeadline(unsignedTransaction: string): string | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    if (!tx) return undefined;
+
+    const parsed = this.tryParseTransaction(tx, selfMulticallInterface);
+    if (!parsed || parsed.args.length !== 2) return undefined;
+    return BigInt(parsed.args[0]).toString();
+  }
+
+  private getMulticall3Calls(
+    parsed: ethers.TransactionDescription,
+  ): MulticallCall[] {
+    const call = (
+      target: string,
+      data: string,
+      allowFailure: boolean,
+      value: bigint = 0n,
+    ): MulticallCall => ({
+      target,
+      value: value.toString(),
+      data,
+      allowFailure,
+    });
+
+    switch (parsed.name) {
+      case 'aggregate':
+      case 'blockAndAggregate':
+        return Array.from(parsed.args[0], ([target, data]: [string, string]) =>
+          call(target, data, false),
+        );
+      case 'tryAggregate':
+      case 'tryBlockAndAggregate': {
+        const [requireSuccess, calls] = parsed.args;
+        return Array.from(calls, ([target, data]: [string, string]) =>
+          call(target, data, !requireSuccess),
+        );
+      }
+      case 'aggregate3':
+        return Array.from(
+          parsed.args[0],
+          ([target, allowFailure, data]: [string, boolean, string]) =>
+            call(target, data, allowFailure),
+        );
+      default:
+        return Array.from(
+          parsed.args[0],
+          ([target, allowFailure, value, data]: [
+            string,
+            boolean,
+            bigint,
+            string,
+          ]) => call(target, data, allowFailure, BigInt(value)),
+        );
+    }
+  }
+
+  getAccessList(unsignedTransaction: string): AccessListEntry[] | undefined {
+    return this.decodeEVMTransaction(unsignedTransaction).transaction
+      ?.accessList;
+  }
+
+  getDelegations(unsignedTransaction: string): Delegation[] | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    if (!tx?.authorizationList || !isAuthorizationList(tx.authorizationList)) {
+      return undefined;
+    }
+
+    return tx.authorizationList.map(
+      ({ chainId, address, nonce, yParity, r, s }) => {
+        const authorization = {
+          chainId: toUint256(chainId)!,
+          address,
+          nonce: toUint256(nonce)!,
+        };
+        let authority: string | null;
+        try {
+          authority = ethers.verifyAuthorization(authorization, {
+            r,
+            s,
+            yParity: yParity as 0 | 1,
+          });
+        } catch {
+          authority = null; // Not a point on the curve, or a high s
+        }
+        return {
+          chainId: authorization.chainId.toString(),
+          address,
+          nonce: authorization.nonce.toString(),
+          authority,
+        };
+      },
+    );
+  }
+
+  // ERC-20 deposits move the tokens they pull, native stakes their value
+  getAmount(unsignedTransaction: string): TransactionAmount | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    if (!tx) return undefined;
+    const chainId = this.getNumericChainId(tx);
+
+    const spend = this.getTokenSpend(unsignedTransaction);
+    if (isDefined(spend)) {
+      return toTransactionAmount(
+        spend.token,
+        BigInt(spend.amount),
+        chainId === null ? undefined : this.getTokenInfo(chainId, spend.token),
+      );
+    }
+
+    const value = toUint256(tx.value ?? 0);
+    if (value === null || value === 0n) return undefined;
+    return toTransactionAmount(
+      'native',
+      value,
+      chainId === null ? undefined : NATIVE_CURRENCIES[chainId],
+    );
+  }
+
+  getStakeAmount(amount: bigint): TransactionAmount | undefined {
+    const tokens = this.getStakeTokens();
+    if (tokens.length !== 1) return undefined;
+
+    const [token] = tokens;
+    const chainId = Number(this.getCapabilities().chainId);
+    return toTransactionAmount(
+      token,
+      amount,
+      token === 'native'
+        ? NATIVE_CURRENCIES[chainId]
+        : this.getTokenInfo(chainId, token),
+    );
+  }
+
+  /**
+   * Symbol and decimals of an ERC-20 token on chainId, when known.
+   */
+  protected getTokenInfo(
+    _chainId: number,
+    _token: string,
+  ): AssetInfo | undefined {
+    return undefined;
+  }
+
+  // Some wallets show, and pass on, the recipient as an ENS name
+  getRecipientName(unsignedTransaction: string): string | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    return isEnsName(tx?.to) ? tx.to : undefined;
+  }
+
+  withRecipientAddress(unsignedTransaction: string, address: string): string {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    if (!tx) return unsignedTransaction;
+    return JSON.stringify({ ...tx, to: address });
+  }
+
+  getSimulationCall(unsignedTransaction: string): SimulationCall | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    if (!tx || !isNonEmptyString(tx.to)) return undefined;
+
+    return {
+      from: tx.from,
+      to: tx.to,
+      data: tx.data ?? '0x',
+      value: ethers.toQuantity(tx.value ?? 0),
+      gas: isDefined(tx.gasLimit) ? ethers.toQuantity(tx.gasLimit) : undefined,
+    };
+  }
+
+  getNumericError(
+    unsignedTransaction: string,
+  ): { field: string; value: unknown } | undefined {
+    try {
+      const tx = JSON.parse(unsignedTransaction) as EVMTransaction;
+      const field = NUMERIC_FIELDS.find(
+        (field) => isDefined(tx[field]) && toUint256(tx[field]) === null,
+      );
+      return isDefined(field) ? { field, value: tx[field] } : undefined;
+    } catch {
+      return undefined; // validate reports it as a decoding failure
+    }
+  }
+
+  getMalformedError(unsignedTransaction: string): string | undefined {
+    try {
+      const tx = JSON.parse(unsignedTransaction) as EVMTransaction;
+      return checkTransactionType(tx) ?? undefined;
+    } catch {
+      return undefined; // validate reports it as a decoding failure
+    }
+  }
+
+  getGasError(unsignedTransaction: string): string | undefined {
+    try {
+      const tx = JSON.parse(unsignedTransaction) as EVMTransaction;
+      return checkGasFields(tx) ?? undefined;
+    } catch {
+      return undefined; // validate reports it as a decoding failure
+    }
+  }
+
+  getCalldataSize(unsignedTransaction: string): number | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    if (!tx) return undefined;
+    try {
+      return ethers.dataLength(tx.data ?? '0x');
+    } catch {
+      return undefined; // validate reports it as a decoding failure
+    }
+  }
+
+  getGasLimit(unsignedTransaction: string): bigint | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    if (!tx || !isDefined(tx.gasLimit)) return undefined;
+    return toUint256(tx.gasLimit) ?? undefined;
+  }
+
+  getNonce(unsignedTransaction: string): bigint | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    if (!tx || !isDefined(tx.nonce)) return undefined;
+    return toUint256(tx.nonce) ?? undefined;
+  }
+
+  getGasLimitRange(transactionType: TransactionType): GasLimitRange {
+    return SINGLE_CONTRACT_TYPES.has(transactionType)
+      ? SINGLE_CONTRACT_GAS_RANGE
+      : MULTI_CONTRACT_GAS_RANGE;
+  }
+
+  getApproval(unsignedTransaction: string): TokenApproval | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    if (!tx || !isNonEmptyString(tx.to)) return undefined;
+
+    const parsed = this.tryParseTransaction(tx, erc20ApproveInterface);
+    if (!isDefined(parsed)) return undefined;
+
+    const [spender, amount] = parsed.args;
+    const allowance = BigInt(amount);
+    return {
+      token: tx.to,
+      spender,
+      amount: allowance.toString(),
+      isUnlimited: allowance >= UNLIMITED_APPROVAL_THRESHOLD,
+    };
+  }
+
+  // Whichever contract it goes to, so that no claim pays elsewhere
+  // unnoticed, even one the yield's ABIs cannot match
+  getClaimCall(unsignedTransaction: string): ClaimCall | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    if (!tx || !isNonEmptyString(tx.to)) return undefined;
+
+    const parsed = this.tryParseTransaction(tx, claimInterface);
+    if (!isDefined(parsed)) return undefined;
+
+    const position = CLAIM_VARIANTS[parsed.signature];
+    return {
+      variant: parsed.signature,
+      recipient: position === null ? null : parsed.args[position],
+    };
+  }
+
+  getImplementationChange(
+    unsignedTransaction: string,
+  ): ImplementationChange | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    if (!tx || !isNonEmptyString(tx.to)) return undefined;
+
+    const upgrade = this.tryParseTransaction(tx, proxyUpgradeInterface);
+    if (isDefined(upgrade)) {
+      return {
+        proxy: tx.to,
+        implementation: upgrade.args[0],
+        functionName: upgrade.name,
+      };
+    }
+    const adminUpgrade = this.tryParseTransaction(tx, proxyAdminInterface);
+    if (isDefined(adminUpgrade)) {
+      return {
+        proxy: adminUpgrade.args[0],
+        implementation: adminUpgrade.args[1],
+        functionName: adminUpgrade.name,
+      };
+    }
+    return undefined;
+  }
+
+  getPrivilegedCall(unsignedTransaction: string): PrivilegedCall | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    const selector = this.getSelector(unsignedTransaction);
+    if (!tx || !isNonEmptyString(tx.to) || !isDefined(selector)) {
+      return undefined;
+    }
+
+    const signature = this.getPrivilegedFunctions(tx.to).find(
+      (candidate) => ethers.id(candidate).slice(0, 10) === selector,
+    );
+    if (!isDefined(signature)) return undefined;
+    return {
+      contract: tx.to,
+      functionName: signature.slice(0, signature.indexOf('(')),
+      signature,
+      selector,
+    };
+  }
+
+  /**
+   * The canonical signatures of the functions of contract only its owner
+   * or an admin may call. Every contract the yield lists has the common
+   * Ownable, Pausable and AccessControl ones; validators add those of their
+   * own contracts.
+   */
+  protected getPrivilegedFunctions(contract: string): string[] {
+    const { contracts } = this.getCapabilities();
+    return contracts.some((listed) => this.isSameAddress(listed, contract))
+      ? PRIVILEGED_FUNCTIONS
+      : [];
+  }
+
+  /**
+   * The spenders token may be granted a permit for, or none if this yield
+   * accepts no permits for it.
+   */
+  protected getPermitSpenders(_token: string): string[] {
+    return [];
+  }
+
+  /**
+   * The spenders token may be granted a Permit2 allowance for. These are
+   * the spenders it may be permitted to unless the yield says otherwise.
+   */
+  protected getPermit2Spenders(token: string): string[] {
+    return this.getPermitSpenders(token);
+  }
+
+  validateTypedData(
+    typedData: TypedData,
+    userAddress: string,
+  ): ValidationResult {
+    const { domain, types, primaryType, message } = typedData;
+    if (primaryType === 'PermitSingle' || primaryType === 'PermitBatch') {
+      return this.validatePermit2(typedData);
+    }
+    if (primaryType !== 'Permit') {
+      return this.blocked('Unsupported typed data primaryType', {
+        actual: primaryType,
+      });
+    }
+
+    if (!hasFields(types?.Permit, PERMIT_FIELDS)) {
+      return this.blocked('Permit type does not match EIP-2612');
+    }
+
+    const { chainId } = this.getCapabilities();
+    if (String(domain?.chainId) !== chainId) {
+      return this.blocked('Typed data chain ID does not match the yield', {
+        expected: chainId,
+        actual: domain?.chainId,
+      });
+    }
+
+    const token = domain.verifyingContract;
+    const spenders = isNonEmptyString(token)
+      ? this.getPermitSpenders(token)
+      : [];
+    if (!isNonEmptyString(token) || spenders.length === 0) {
+      return this.blocked('Typed data verifyingContract is not a known token', {
+        actual: token,
+      });
+    }
+
+    const { owner, spender } = message ?? {};
+    if (!isNonEmptyString(owner) || !this.isSameAddress(owner, userAddress)) {
+      return this.blocked('SENDER_MISMATCH', {
+        expected: userAddress,
+        actual: owner,
+      });
+    }
+
+    if (
+      !isNonEmptyString(spender) ||
+      !spenders.some((s) => this.isSameAddress(s, spender))
+    ) {
+      return this.blocked('APPROVAL_SPENDER_MISMATCH', { actual: spender });
+    }
+
+    const amount = toUint256(message.value);
+    const nonce = toUint256(message.nonce);
+    const deadline = toUint256(message.deadline);
+    if (amount === null || nonce === null || deadline === null) {
+      return this.blocked('Permit value, nonce or deadline is not a uint256');
+    }
+
+    const now = BigInt(Math.floor(Date.now() / 1000));
+    if (deadline <= now) {
+      return {
+        ...this.blocked('Permit deadline has passed', {
+          deadline: deadline.toString(),
+        }),
+        reasonCode: 'DEADLINE_IN_PAST',
+      };
+    }
+
+    const approval: TokenApproval = {
+      token,
+      spender,
+      amount: amount.toString(),
+      isUnlimited: amount >= UNLIMITED_APPROVAL_THRESHOLD,
+    };
+    const warnings: ValidationWarning[] = [];
+    if (approval.isUnlimited) {
+      warnings.push(
+        this.warning(
+          'INFINITE_APPROVAL',
+          `Permit grants ${spender} an unlimited allowance`,
+          { token, spender, amount: approval.amount },
+        ),
+      );
+    }
+
+    // Shield warns about deadlines further out than the caller allows
+    return {
+      ...this.safe(warnings),
+      detectedType: TransactionType.PERMIT,
+      decoded: { approval },
+      deadline: toDeadline(deadline),
+    };
+  }
+
+  // Permit2 messages name no owner: the allowance is the signer's own
+  private validatePermit2(typedData: TypedData): ValidationResult {
+    const { domain, types, primaryType, message } = typedData;
+    const isBatch = primaryType === 'PermitBatch';
+    const detailsType = isBatch ? 'PermitDetails[]' : 'PermitDetails';
+    if (
+      !hasFields(types?.[primaryType], PERMIT2_FIELDS(detailsType)) ||
+      !hasFields(types?.PermitDetails, PERMIT2_DETAILS_FIELDS)
+    ) {
+      return this.blocked(`${primaryType} type does not match Permit2`);
+    }
+
+    const { chainId } = this.getCapabilities();
+    if (String(domain?.chainId) !== chainId) {
+      return this.blocked('Typed data chain ID does not match the yield', {
+        expected: chainId,
+        actual: domain?.chainId,
+      });
+    }
+
+    if (
+      !isNonEmptyString(domain.verifyingContract) ||
+      !this.isSameAddress(domain.verifyingContract, PERMIT2_ADDRESS)
+    ) {
+      return this.blocked('Typed data verifyingContract is not Permit2', {
+        expected: PERMIT2_ADDRESS,
+        actual: domain.verifyingContract,
+      });
+    }
+
+    const { spender } = message ?? {};
+    const sigDeadline = toUint256(message?.sigDeadline);
+    const details = isBatch ? message?.details : [message?.details];
+    if (
+      !isNonEmptyString(spender) ||
+      sigDeadline === null ||
+      !Array.isArray(details) ||
+      details.length === 0
+    ) {
+      return this.blocked(
+        'Permit2 spender, sigDeadline or details are missing or malformed',
+      );
+    }
+
+    const now = BigInt(Math.floor(Date.now() / 1000));
+    if (sigDeadline <= now) {
+      return {
+        ...this.blocked('Permit2 signature deadline has passed', {
+          sigDeadline: sigDeadline.toString(),
+        }),
+        reasonCode: 'DEADLINE_IN_PAST',
+      };
+    }
+
+    const permits: Permit2Details[] = [];
+    const warnings: ValidationWarning[] = [];
+    let longestDeadline = sigDeadline;
+    for (const [index, entry] of details.entries()) {
+      const fields = (entry ?? {}) as Record<string, unknown>;
+      const { token } = fields;
+      const amount = toUint256(fields.amount);
+      const expiration = toUint256(fields.expiration);
+      const nonce = toUint256(fields.nonce);
+      if (
+        !isNonEmptyString(token) ||
+        amount === null ||
+        amount > MAX_UINT160 ||
+        expiration === null ||
+        expiration > MAX_UINT48 ||
+        nonce === null ||
+        nonce > MAX_UINT48
+      ) {
+        return this.blocked('Permit2 details are malformed', { index });
+      }
+
+      const spenders = this.getPermit2Spenders(token);
+      if (spenders.length === 0) {
+        return this.blocked('Permit2 token is not a token of the yield', {
+          index,
+          actual: token,
+        });
+      }
+      if (!spenders.some((s) => this.isSameAddress(s, spender))) {
+        return this.blocked('APPROVAL_SPENDER_MISMATCH', {
+          index,
+          expected: spenders,
+          actual: spender,
+        });
+      }
+
+      // An expiration of 0 lasts until the end of the block it is used in
+      if (expiration !== 0n && expiration <= now) {
+        return {
+          ...this.blocked('Permit2 allowance has already expired', {
+            index,
+            expiration: expiration.toString(),
+          }),
+          reasonCode: 'DEADLINE_IN_PAST',
+        };
+      }
+      if (expiration > longestDeadline) longestDeadline = expiration;
+
+      const permit: Permit2Details = {
+        token,
+        amount: amount.toString(),
+        expiration: expiration.toString(),
+        nonce: nonce.toString(),
+        isUnlimited: amount === MAX_UINT160,
+      };
+      permits.push(permit);
+      if (permit.isUnlimited) {
+        warnings.push(
+          this.warning(
+            'INFINITE_APPROVAL',
+            `Permit2 grants ${spender} an unlimited allowance`,
+            { token, spender, amount: permit.amount },
+          ),
+        );
+      }
+    }
+
+    return {
+      ...this.safe(warnings),
+      detectedType: TransactionType.PERMIT2,
+      decoded: {
+        permit2: {
+          spender,
+          sigDeadline: sigDeadline.toString(),
+          details: permits,
+        },
+      },
+      // The signature or an allowance, whichever stays usable longest
+      deadline: toDeadline(longestDeadline),
+    };
+  }
+
+  // EVM addresses are case-insensitive; mixed case is only a checksum
+  isSameAddress(a: string, b: string): boolean {
+    return a.toLowerCase() === b.toLowerCase();
+  }
+
+  normalizeAddress(address: string): string {
+    return address.toLowerCase();
+  }
+
+  isValidAddress(address: string): boolean {
+    return ethers.isAddress(address);
+  }
+
+  protected tryParseTransaction(
+    tx: EVMTransaction,
+    iface: ethers.Interface,
+  ): ethers.TransactionDescription | null {
+    try {
+      return iface.parseTransaction({ data: tx.data ?? '0x', value: tx.value });
+    } catch {
+      return null;
+    }
+  }
+
   protected decodeEVMTransaction(transactionString: string): {
     isValid: boolean;
     transaction?: EVMTransaction;
@@ -33,18 +1454,35 @@ export abstract class BaseEVMValidator extends BaseValidator {
         };
       }
 
-      const chainId =
-        typeof transaction.chainId === 'string'
-          ? parseInt(transaction.chainId, 10)
-          : transaction.chainId;
+      const chainId = parseChainId(transaction.chainId);
 
-      if (Number.isNaN(chainId)) {
+      if (!Number.isSafeInteger(chainId)) {
         return {
           isValid: false,
           error: `Invalid chain ID format: ${transaction.chainId}`,
         };
       }
 
+      const fieldError =
+        checkTransactionType(transaction) ?? checkGasFields(transaction);
+      if (fieldError) {
+        return { isValid: false, error: fieldError };
+      }
+
+      // Validators read quantities as decimal strings, whichever form the
+      // transaction gives them in
+      for (const field of NUMERIC_FIELDS) {
+        if (!isDefined(transaction[field])) continue;
+        const value = toUint256(transaction[field]);
+        if (value === null) {
+          return {
+            isValid: false,
+            error: `Invalid ${field} format: ${transaction[field]}`,
+          };
+        }
+        transaction[field] = value.toString();
+      }
+
       return {
         isValid: true,
         transaction,
@@ -77,15 +1515,16 @@ export abstract class BaseEVMValidator extends BaseValidator {
     return null;
   }
 
+  // A replayable transaction runs on the yield's chain like on any other
   protected getNumericChainId(transaction: EVMTransaction): number | null {
     if (!isDefined(transaction.chainId)) {
       return null;
     }
-    const chainId =
-      typeof transaction.chainId === 'string'
-        ? parseInt(transaction.chainId, 10)
-        : transaction.chainId;
-    return Number.isNaN(chainId) ? null : chainId;
+    if (isUnprotected(transaction)) {
+      return Number(this.getCapabilities().chainId);
+    }
+    const chainId = parseChainId(transaction.chainId);
+    return Number.isSafeInteger(chainId) ? chainId : null;
   }
 
   protected ensureChainIdEquals(
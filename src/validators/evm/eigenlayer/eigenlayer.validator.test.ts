@@ -0,0 +1,296 @@
+import { ethers } from 'ethers';
+import { Shield } from '../../../shield';
+import { TransactionType } from '../../../types';
+import { EigenLayerValidator } from './eigenlayer.validator';
+
+describe('EigenLayerValidator via Shield', () => {
+  const shield = new Shield();
+  const yieldId = 'ethereum-steth-eigenlayer-restaking';
+  const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+  const otherAddress = '0x0000000000000000000000000000000000000bad';
+  const operator = '0x5accc90436492f24e6af278569691e2c942a676d';
+
+  const strategyManager = '0x858646372CC42E1A627fcE94aa7A7033e7CF075A';
+  const delegationManager = '0x39053D51B77DC0d36036Fc1fCc8Cb819df8Ef37A';
+  const stEthStrategy = '0x93c4b944D05dfe6df7645A86cd2206016c51564D';
+  const stEth = '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84';
+
+  const iface = new ethers.Interface([
+    'function approve(address spender, uint256 amount) returns (bool)',
+    'function depositIntoStrategy(address strategy, address token, uint256 amount) returns (uint256 shares)',
+    'function delegateTo(address operator, (bytes signature, uint256 expiry) approverSignatureAndExpiry, bytes32 approverSalt)',
+    'function undelegate(address staker) returns (bytes32[] withdrawalRoots)',
+    'function queueWithdrawals((address[] strategies, uint256[] shares, address withdrawer)[] queuedWithdrawalParams) returns (bytes32[])',
+  ]);
+
+  const tx = (to: string, data: string) =>
+    JSON.stringify({
+      to,
+      from: userAddress,
+      value: '0x0',
+      data,
+      nonce: 0,
+      gasLimit: '0x493e0',
+      gasPrice: '0x4a817c800',
+      chainId: 1,
+      type: 0,
+    });
+
+  const deposit = (strategy = stEthStrategy, token = stEth) =>
+    tx(
+      strategyManager,
+      iface.encodeFunctionData('depositIntoStrategy', [
+        strategy,
+        token,
+        ethers.parseEther('1'),
+      ]),
+    );
+
+  const delegate = (to = operator) =>
+    tx(
+      delegationManager,
+      iface.encodeFunctionData('delegateTo', [
+        to,
+        ['0x', 0n],
+        ethers.ZeroHash,
+      ]),
+    );
+
+  const queueWithdrawals = (
+    withdrawer = userAddress,
+    strategy = stEthStrategy,
+  ) =>
+    tx(
+      delegationManager,
+      iface.encodeFunctionData('queueWithdrawals', [
+        [[[strategy], [ethers.parseEther('1')], withdrawer]],
+      ]),
+    );
+
+  const validate = (
+    unsignedTransaction: string,
+    args?: { validatorAddress?: string; validatorAddresses?: string[] },
+  ) => shield.validate({ yieldId, unsignedTransaction, userAddress, args });
+
+  // Why the transaction did not match as type, when nothing matched
+  const attemptReason = (
+    result: ReturnType<typeof validate>,
+    type: TransactionType,
+  ) =>
+    result.details?.attempts?.find(
+      (attempt: { type: TransactionType }) => attempt.type === type,
+    )?.reason;
+
+  it('should support the stETH restaking yield', () => {
+    expect(shield.isSupported(yieldId)).toBe(true);
+    expect(shield.getYieldCapabilities(yieldId)?.supportedTypes).toEqual([
+      TransactionType.APPROVAL,
+      TransactionType.RESTAKE,
+      TransactionType.DELEGATE,
+      TransactionType.UNDELEGATE,
+      TransactionType.UNSTAKE,
+    ]);
+  });
+
+  describe('APPROVAL', () => {
+    it('should validate approving stETH to the StrategyManager', () => {
+      const result = validate(
+        tx(
+          stEth,
+          iface.encodeFunctionData('approve', [
+            strategyManager,
+            ethers.parseEther('1'),
+          ]),
+        ),
+      );
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.APPROVAL);
+    });
+
+    it('should reject approving another spender', () => {
+      const result = validate(
+        tx(
+          stEth,
+          iface.encodeFunctionData('approve', [
+            otherAddress,
+            ethers.parseEther('1'),
+          ]),
+        ),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('APPROVAL_SPENDER_MISMATCH');
+    });
+
+    it('should reject approving a lookalike token to the StrategyManager', () => {
+      const result = validate(
+        tx(
+          otherAddress,
+          iface.encodeFunctionData('approve', [
+            strategyManager,
+            ethers.parseEther('1'),
+          ]),
+        ),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('TOKEN_MISMATCH');
+      expect(result.details).toMatchObject({ actual: otherAddress });
+    });
+  });
+
+  describe('RESTAKE', () => {
+    it('should validate a deposit into the stETH strategy', () => {
+      const result = validate(deposit());
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.RESTAKE);
+      expect(result.amount).toEqual({
+        token: stEth,
+        amount: ethers.parseEther('1').toString(),
+        symbol: 'stETH',
+        decimals: 18,
+        normalized: '1.0',
+      });
+      expect(result.summary).toBe(
+        'You are restaking 1 stETH with EigenLayer',
+      );
+    });
+
+    it('should reject a strategy the yield does not use', () => {
+      const result = validate(deposit(otherAddress));
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReason(result, TransactionType.RESTAKE)).toBe(
+        'Strategy is not a strategy of the yield',
+      );
+    });
+
+    it('should reject a token other than the strategy takes', () => {
+      const result = validate(deposit(stEthStrategy, otherAddress));
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('TOKEN_MISMATCH');
+      expect(attemptReason(result, TransactionType.RESTAKE)).toBe(
+        'Token is not the token of the strategy',
+      );
+    });
+
+    it('should reject a deposit sent by someone else', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: deposit(),
+        userAddress: otherAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('SENDER_MISMATCH');
+    });
+  });
+
+  describe('DELEGATE', () => {
+    it('should validate delegating to the requested operator', () => {
+      const result = validate(delegate(), { validatorAddress: operator });
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.DELEGATE);
+    });
+
+    it('should reject delegating to another operator', () => {
+      const result = validate(delegate(otherAddress), {
+        validatorAddresses: [operator],
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReason(result, TransactionType.DELEGATE)).toBe(
+        'Delegates to an operator the yield does not allow',
+      );
+    });
+
+    it('should reject delegating when no operator is allowed', () => {
+      const result = validate(delegate());
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReason(result, TransactionType.DELEGATE)).toBe(
+        'Delegates to an operator the yield does not allow',
+      );
+    });
+
+    it("should only allow requested operators among the yield's", () => {
+      const validator = new EigenLayerValidator({
+        strategies: [],
+        operators: [operator],
+      });
+
+      expect(
+        validator.validate(delegate(), TransactionType.DELEGATE, userAddress)
+          .isValid,
+      ).toBe(true);
+      expect(
+        validator.validate(delegate(), TransactionType.DELEGATE, userAddress, {
+          validatorAddress: otherAddress,
+        }).details,
+      ).toEqual({ expected: [], actual: ethers.getAddress(operator) });
+    });
+  });
+
+  describe('UNDELEGATE', () => {
+    it('should validate undelegating the user', () => {
+      const result = validate(
+        tx(
+          delegationManager,
+          iface.encodeFunctionData('undelegate', [userAddress]),
+        ),
+      );
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.UNDELEGATE);
+    });
+
+    it('should reject undelegating another staker', () => {
+      const result = validate(
+        tx(
+          delegationManager,
+          iface.encodeFunctionData('undelegate', [otherAddress]),
+        ),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReason(result, TransactionType.UNDELEGATE)).toBe(
+        'Undelegated staker is not user address',
+      );
+    });
+  });
+
+  describe('UNSTAKE', () => {
+    it('should validate queueing a withdrawal to the user', () => {
+      const result = validate(queueWithdrawals());
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.UNSTAKE);
+      expect(result.decoded?.withdrawal).toEqual({
+        phase: 'REQUEST',
+        recipient: ethers.getAddress(userAddress),
+        token: stEthStrategy,
+        amount: ethers.parseEther('1').toString(),
+      });
+    });
+
+    it('should reject a withdrawal to someone else', () => {
+      const result = validate(queueWithdrawals(otherAddress));
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('WITHDRAWAL_RECIPIENT_MISMATCH');
+    });
+
+    it('should reject withdrawing from a strategy the yield does not use', () => {
+      const result = validate(queueWithdrawals(userAddress, otherAddress));
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReason(result, TransactionType.UNSTAKE)).toBe(
+        'Strategy is not a strategy of the yield',
+      );
+    });
+  });
+});
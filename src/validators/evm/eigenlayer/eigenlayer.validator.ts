@@ -0,0 +1,499 @@
+import { ethers } from 'ethers';
+import {
+  ActionArguments,
+  TokenSpend,
+  TransactionType,
+  ValidationContext,
+  ValidationResult,
+  ValidatorCapabilities,
+  Withdrawal,
+} from '../../../types';
+import { isDefined, isNonEmptyString } from '../../../utils/validation';
+import { AssetInfo } from '../../../utils/amount';
+import { BaseEVMValidator, EVMTransaction } from '../base.validator';
+
+const EIGENLAYER_CONTRACTS = {
+  strategyManager: '0x858646372CC42E1A627fcE94aa7A7033e7CF075A',
+  delegationManager: '0x39053D51B77DC0d36036Fc1fCc8Cb819df8Ef37A',
+};
+
+const STRATEGY_MANAGER_ABI = [
+  'function depositIntoStrategy(address strategy, address token, uint256 amount) returns (uint256 shares)',
+];
+
+const TOKEN_ABI = [
+  'function approve(address spender, uint256 amount) returns (bool)',
+];
+
+const DELEGATION_MANAGER_ABI = [
+  'function delegateTo(address operator, (bytes signature, uint256 expiry) approverSignatureAndExpiry, bytes32 approverSalt)',
+  'function undelegate(address staker) returns (bytes32[] withdrawalRoots)',
+  'function queueWithdrawals((address[] strategies, uint256[] shares, address withdrawer)[] queuedWithdrawalParams) returns (bytes32[])',
+];
+
+export interface EigenLayerStrategy extends AssetInfo {
+  address: string; // The strategy contract shares are minted by
+  token: string; // The token it takes, e.g. stETH
+}
+
+export interface EigenLayerConfig {
+  strategies: EigenLayerStrategy[];
+  // Operators stakers may delegate to. Empty to take them from the
+  // validatorAddress or validatorAddresses of each request's args
+  operators: string[];
+}
+
+/**
+ * EigenLayer restaking through the StrategyManager and DelegationManager
+ *
+ * Transaction Types Validated:
+ * - APPROVAL: approve a strategy's token to the StrategyManager
+ * - RESTAKE: depositIntoStrategy into one of the yield's strategies
+ * - DELEGATE: delegateTo an operator of the yield
+ * - UNDELEGATE: undelegate the user
+ * - UNSTAKE: queueWithdrawals of the yield's strategies, to the user
+ *
+ * The staker is always the transaction's sender, who must be the user.
+ */
+export class EigenLayerValidator extends BaseEVMValidator {
+  private readonly strategyManagerInterface: ethers.Interface;
+  private readonly delegationManagerInterface: ethers.Interface;
+  private readonly tokenInterface: ethers.Interface;
+
+  constructor(private readonly config: EigenLayerConfig) {
+    super();
+    this.strategyManagerInterface = new ethers.Interface(STRATEGY_MANAGER_ABI);
+    this.delegationManagerInterface = new ethers.Interface(
+      DELEGATION_MANAGER_ABI,
+    );
+    this.tokenInterface = new ethers.Interface(TOKEN_ABI);
+  }
+
+  getSupportedTransactionTypes(): TransactionType[] {
+    return [
+      TransactionType.APPROVAL,
+      TransactionType.RESTAKE,
+      TransactionType.DELEGATE,
+      TransactionType.UNDELEGATE,
+      TransactionType.UNSTAKE,
+    ];
+  }
+
+  getCapabilities(): ValidatorCapabilities {
+    return {
+      name: 'EigenLayer',
+      protocol: 'eigenlayer',
+      network: 'ethereum',
+      supportsPartialAmounts: true,
+      chainId: '1',
+      contracts: [
+        EIGENLAYER_CONTRACTS.strategyManager,
+        EIGENLAYER_CONTRACTS.delegationManager,
+        ...this.config.strategies.map(({ token }) => token),
+      ],
+    };
+  }
+
+  getExpectedSpenders(_unsignedTransaction: string): string[] {
+    return [EIGENLAYER_CONTRACTS.strategyManager];
+  }
+
+  // The underlying tokens of the strategies
+  getStakeTokens(): string[] {
+    return this.config.strategies.map(({ token }) => token);
+  }
+
+  // Approvals go to whichever strategy token is deposited
+  getTransactionRecipient(
+    transactionType: TransactionType,
+  ): string | undefined {
+    switch (transactionType) {
+      case TransactionType.APPROVAL:
+        return this.config.strategies.length === 1
+          ? this.config.strategies[0].token
+          : undefined;
+      case TransactionType.RESTAKE:
+        return EIGENLAYER_CONTRACTS.strategyManager;
+      default:
+        return EIGENLAYER_CONTRACTS.delegationManager;
+    }
+  }
+
+  protected getDecodeInterfaces(): ethers.Interface[] {
+    return [
+      this.strategyManagerInterface,
+      this.delegationManagerInterface,
+      this.tokenInterface,
+    ];
+  }
+
+  protected getTransactionFunctions(): Partial<
+    Record<TransactionType, ethers.FunctionFragment[]>
+  > {
+    const strategyManager = (name: string) =>
+      this.strategyManagerInterface.getFunction(name)!;
+    const delegationManager = (name: string) =>
+      this.delegationManagerInterface.getFunction(name)!;
+    return {
+      [TransactionType.APPROVAL]: [
+        this.tokenInterface.getFunction('approve')!,
+      ],
+      [TransactionType.RESTAKE]: [strategyManager('depositIntoStrategy')],
+      [TransactionType.DELEGATE]: [delegationManager('delegateTo')],
+      [TransactionType.UNDELEGATE]: [delegationManager('undelegate')],
+      [TransactionType.UNSTAKE]: [delegationManager('queueWithdrawals')],
+    };
+  }
+
+  protected getTokenInfo(
+    chainId: number,
+    token: string,
+  ): AssetInfo | undefined {
+    if (chainId !== 1) return undefined;
+    return this.config.strategies.find((strategy) =>
+      this.isSameAddress(strategy.token, token),
+    );
+  }
+
+  // The StrategyManager pulls the deposit under the user's allowance
+  getTokenSpend(unsignedTransaction: string): TokenSpend | undefined {
+    const parsed = this.parseCall(
+      unsignedTransaction,
+      EIGENLAYER_CONTRACTS.strategyManager,
+      this.strategyManagerInterface,
+    );
+    if (parsed?.name !== 'depositIntoStrategy') return undefined;
+
+    const [, token, amount] = parsed.args;
+    return {
+      token,
+      spender: EIGENLAYER_CONTRACTS.strategyManager,
+      amount: BigInt(amount).toString(),
+    };
+  }
+
+  // Queued withdrawals are completed by their withdrawer. Reported for
+  // withdrawals of a single strategy, whose shares add up
+  getWithdrawal(unsignedTransaction: string): Withdrawal | undefined {
+    const parsed = this.parseCall(
+      unsignedTransaction,
+      EIGENLAYER_CONTRACTS.delegationManager,
+      this.delegationManagerInterface,
+    );
+    if (parsed?.name !== 'queueWithdrawals') return undefined;
+
+    const params: [string[], bigint[], string][] = Array.from(parsed.args[0]);
+    const strategies = new Set(
+      params.flatMap(([strategies]) => strategies.map((s) => s.toLowerCase())),
+    );
+    const withdrawers = new Set(params.map(([, , w]) => w.toLowerCase()));
+    if (strategies.size !== 1 || withdrawers.size !== 1) return undefined;
+
+    return {
+      phase: 'REQUEST',
+      recipient: params[0][2],
+      token: params[0][0][0],
+      amount: params
+        .flatMap(([, shares]) => Array.from(shares))
+        .reduce((total, shares) => total + BigInt(shares), 0n)
+        .toString(),
+    };
+  }
+
+  validate(
+    unsignedTransaction: string,
+    transactionType: TransactionType,
+    userAddress: string,
+    args?: ActionArguments,
+    _context?: ValidationContext,
+  ): ValidationResult {
+    const decoded = this.decodeEVMTransaction(unsignedTransaction);
+    if (!decoded.isValid || !decoded.transaction) {
+      return this.blocked('Failed to decode EVM transaction', {
+        error: decoded.error,
+      });
+    }
+
+    const tx = decoded.transaction;
+
+    const fromErr = this.ensureTransactionFromIsUser(tx, userAddress);
+    if (fromErr) return fromErr;
+
+    const chainErr = this.ensureChainIdEquals(
+      tx,
+      1,
+      'EigenLayer only supported on Ethereum mainnet',
+    );
+    if (chainErr) return chainErr;
+
+    const value = BigInt(tx.value ?? '0');
+    if (value > 0n) {
+      return this.blocked('EigenLayer transactions should not send ETH value', {
+        value: value.toString(),
+      });
+    }
+
+    switch (transactionType) {
+      case TransactionType.APPROVAL:
+        return this.validateApproval(tx);
+      case TransactionType.RESTAKE:
+        return this.validateDeposit(tx);
+      case TransactionType.DELEGATE:
+        return this.validateDelegate(tx, args);
+      case TransactionType.UNDELEGATE:
+        return this.validateUndelegate(tx, userAddress);
+      case TransactionType.UNSTAKE:
+        return this.validateQueueWithdrawals(tx, userAddress);
+      default:
+        return this.blocked('Unsupported transaction type', {
+          transactionType,
+        });
+    }
+  }
+
+  private validateApproval(tx: EVMTransaction): ValidationResult {
+    const tokens = this.config.strategies.map(({ token }) => token);
+    if (
+      !isNonEmptyString(tx.to) ||
+      !tokens.some((token) => this.isSameAddress(token, tx.to!))
+    ) {
+      return this.blocked('Approval is not for a token of the yield', {
+        expected: tokens,
+        actual: tx.to,
+      });
+    }
+
+    const result = this.parseAndValidateCalldata(tx, this.tokenInterface);
+    if ('error' in result) return result.error;
+
+    const { parsed } = result;
+    if (parsed.name !== 'approve') {
+      return this.blocked('Invalid method for approval', {
+        expected: 'approve',
+        actual: parsed.name,
+      });
+    }
+
+    const [, amount] = parsed.args;
+    if (BigInt(amount) <= 0n) {
+      return this.blocked('Approval amount must be greater than zero');
+    }
+
+    return this.safe();
+  }
+
+  private validateDeposit(tx: EVMTransaction): ValidationResult {
+    const toErr = this.ensureTo(tx, EIGENLAYER_CONTRACTS.strategyManager);
+    if (toErr) return toErr;
+
+    const result = this.parseAndValidateCalldata(
+      tx,
+      this.strategyManagerInterface,
+    );
+    if ('error' in result) return result.error;
+
+    const { parsed } = result;
+    if (parsed.name !== 'depositIntoStrategy') {
+      return this.blocked('Invalid method for restaking', {
+        expected: 'depositIntoStrategy',
+        actual: parsed.name,
+      });
+    }
+
+    const [strategyAddress, token, amount] = parsed.args;
+    const strategy = this.findStrategy(strategyAddress);
+    if (!strategy) {
+      return this.blocked('Strategy is not a strategy of the yield', {
+        expected: this.config.strategies.map(({ address }) => address),
+        actual: strategyAddress,
+      });
+    }
+
+    if (!this.isSameAddress(token, strategy.token)) {
+      return this.blocked('Token is not the token of the strategy', {
+        expected: strategy.token,
+        actual: token,
+      });
+    }
+
+    if (BigInt(amount) <= 0n) {
+      return this.blocked('Deposit amount must be greater than zero');
+    }
+
+    return this.safe();
+  }
+
+  private validateDelegate(
+    tx: EVMTransaction,
+    args?: ActionArguments,
+  ): ValidationResult {
+    const toErr = this.ensureTo(tx, EIGENLAYER_CONTRACTS.delegationManager);
+    if (toErr) return toErr;
+
+    const result = this.parseAndValidateCalldata(
+      tx,
+      this.delegationManagerInterface,
+    );
+    if ('error' in result) return result.error;
+
+    const { parsed } = result;
+    if (parsed.name !== 'delegateTo') {
+      return this.blocked('Invalid method for delegation', {
+        expected: 'delegateTo',
+        actual: parsed.name,
+      });
+    }
+
+    const [operator] = parsed.args;
+    const operators = this.getAllowedOperators(args);
+    if (!operators.some((allowed) => this.isSameAddress(allowed, operator))) {
+      return this.blocked('Delegates to an operator the yield does not allow', {
+        expected: operators,
+        actual: operator,
+      });
+    }
+
+    return this.safe();
+  }
+
+  private validateUndelegate(
+    tx: EVMTransaction,
+    userAddress: string,
+  ): ValidationResult {
+    const toErr = this.ensureTo(tx, EIGENLAYER_CONTRACTS.delegationManager);
+    if (toErr) return toErr;
+
+    const result = this.parseAndValidateCalldata(
+      tx,
+      this.delegationManagerInterface,
+    );
+    if ('error' in result) return result.error;
+
+    const { parsed } = result;
+    if (parsed.name !== 'undelegate') {
+      return this.blocked('Invalid method for undelegation', {
+        expected: 'undelegate',
+        actual: parsed.name,
+      });
+    }
+
+    // An operator may undelegate its stakers; the user only themselves
+    const [staker] = parsed.args;
+    if (!this.isSameAddress(staker, userAddress)) {
+      return this.blocked('Undelegated staker is not user address', {
+        expected: userAddress,
+        actual: staker,
+      });
+    }
+
+    return this.safe();
+  }
+
+  private validateQueueWithdrawals(
+    tx: EVMTransaction,
+    userAddress: string,
+  ): ValidationResult {
+    const toErr = this.ensureTo(tx, EIGENLAYER_CONTRACTS.delegationManager);
+    if (toErr) return toErr;
+
+    const result = this.parseAndValidateCalldata(
+      tx,
+      this.delegationManagerInterface,
+    );
+    if ('error' in result) return result.error;
+
+    const { parsed } = result;
+    if (parsed.name !== 'queueWithdrawals') {
+      return this.blocked('Invalid method for unstaking', {
+        expected: 'queueWithdrawals',
+        actual: parsed.name,
+      });
+    }
+
+    const params: [string[], bigint[], string][] = Array.from(parsed.args[0]);
+    if (params.length === 0) {
+      return this.blocked('No withdrawals are queued');
+    }
+
+    for (const [index, [strategies, shares, withdrawer]] of params.entries()) {
+      if (strategies.length === 0 || strategies.length !== shares.length) {
+        return this.blocked('Withdrawal strategies and shares do not match', {
+          index,
+          strategiesLength: strategies.length,
+          sharesLength: shares.length,
+        });
+      }
+
+      const unknown = strategies.find((s) => !isDefined(this.findStrategy(s)));
+      if (isDefined(unknown)) {
+        return this.blocked('Strategy is not a strategy of the yield', {
+          index,
+          expected: this.config.strategies.map(({ address }) => address),
+          actual: unknown,
+        });
+      }
+
+      if (shares.some((amount) => BigInt(amount) <= 0n)) {
+        return this.blocked('Withdrawn shares must be greater than zero', {
+          index,
+        });
+      }
+
+      if (!this.isSameAddress(withdrawer, userAddress)) {
+        return this.blocked('Withdrawer is not user address', {
+          index,
+          expected: userAddress,
+          actual: withdrawer,
+        });
+      }
+    }
+
+    return this.safe();
+  }
+
+  // The request's validators narrow the yield's operators, when it has any
+  private getAllowedOperators(args?: ActionArguments): string[] {
+    const { operators } = this.config;
+    let requested: string[] = [];
+    if (isDefined(args?.validatorAddresses)) {
+      requested = args.validatorAddresses;
+    } else if (isNonEmptyString(args?.validatorAddress)) {
+      requested = [args.validatorAddress];
+    }
+
+    if (requested.length === 0) return operators;
+    if (operators.length === 0) return requested;
+    return requested.filter((operator) =>
+      operators.some((allowed) => this.isSameAddress(allowed, operator)),
+    );
+  }
+
+  private findStrategy(address: string): EigenLayerStrategy | undefined {
+    return this.config.strategies.find((strategy) =>
+      this.isSameAddress(strategy.address, address),
+    );
+  }
+
+  private ensureTo(
+    tx: EVMTransaction,
+    contract: string,
+  ): ValidationResult | null {
+    if (isNonEmptyString(tx.to) && this.isSameAddress(tx.to, contract)) {
+      return null;
+    }
+    return this.blocked('Transaction not to the EigenLayer contract', {
+      expected: contract,
+      actual: tx.to,
+    });
+  }
+
+  private parseCall(
+    unsignedTransaction: string,
+    contract: string,
+    iface: ethers.Interface,
+  ): ethers.TransactionDescription | null {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    if (!tx?.to || !this.isSameAddress(tx.to, contract)) return null;
+    return this.tryParseTransaction(tx, iface);
+  }
+}
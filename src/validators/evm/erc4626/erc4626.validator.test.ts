@@ -345,6 +345,41 @@ describe('ERC4626Validator', () => {
       expect(result.isValid).toBe(true);
     });
 
+    it('should report the assets a deposit pulls as its amount', () => {
+      const data = erc4626Iface.encodeFunctionData('deposit', [
+        ethers.parseUnits('1000', 6),
+        USER_ADDRESS,
+      ]);
+      const tx = buildTx({ to: VAULT_ADDRESS, data, value: '0x0' });
+
+      expect(validator.getAmount(tx)).toEqual({
+        token: INPUT_TOKEN.toLowerCase(),
+        amount: '1000000000',
+      });
+    });
+
+    it('should normalize the amount with decimals from the registry', () => {
+      const withDecimals = new ERC4626Validator({
+        ...mockConfig,
+        vaults: mockConfig.vaults.map((vault) => ({
+          ...vault,
+          inputTokenSymbol: 'USDC',
+          inputTokenDecimals: 6,
+        })),
+      });
+      const data = erc4626Iface.encodeFunctionData('deposit', [
+        ethers.parseUnits('100', 6),
+        USER_ADDRESS,
+      ]);
+      const tx = buildTx({ to: VAULT_ADDRESS, data, value: '0x0' });
+
+      expect(withDecimals.getAmount(tx)).toMatchObject({
+        symbol: 'USDC',
+        decimals: 6,
+        normalized: '100.0',
+      });
+    });
+
     it('should reject vault not whitelisted', () => {
       const data = erc4626Iface.encodeFunctionData('deposit', [
         ethers.parseUnits('1000', 6),
@@ -360,19 +395,14 @@ describe('ERC4626Validator', () => {
       expect(result.reason).toContain('not whitelisted');
     });
 
-    it('should reject when receiver != user address', () => {
+    // Shield rejects a receiver other than the beneficiary
+    it('should report the receiver as the beneficiary', () => {
       const data = erc4626Iface.encodeFunctionData('deposit', [
         ethers.parseUnits('1000', 6),
         OTHER_ADDRESS, // receiver is someone else
       ]);
       const tx = buildTx({ to: VAULT_ADDRESS, data, value: '0x0' });
-      const result = validator.validate(
-        tx,
-        TransactionType.SUPPLY,
-        USER_ADDRESS,
-      );
-      expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('Receiver address does not match');
+      expect(validator.getBeneficiary(tx)).toBe(OTHER_ADDRESS);
     });
 
     it('should reject ETH value on supply', () => {
@@ -726,6 +756,286 @@ describe('ERC4626Validator', () => {
   // =========================================================================
   // canEnter / canExit
   // =========================================================================
+  describe('Permit2 typed data', () => {
+    const PERMIT2 = '0x000000000022D473030F116dDEE9F6B43aC78BA3';
+    const inOneHour = Math.floor(Date.now() / 1000) + 3600;
+    const permitDetails = [
+      { name: 'token', type: 'address' },
+      { name: 'amount', type: 'uint160' },
+      { name: 'expiration', type: 'uint48' },
+      { name: 'nonce', type: 'uint48' },
+    ];
+    const details = (overrides: Record<string, unknown> = {}) => ({
+      token: INPUT_TOKEN,
+      amount: '1000000000',
+      expiration: String(inOneHour),
+      nonce: '0',
+      ...overrides,
+    });
+    const permitSingle = (message: Record<string, unknown> = {}) => ({
+      domain: {
+        name: 'Permit2',
+        chainId: CHAIN_ID,
+        verifyingContract: PERMIT2,
+      },
+      types: {
+        PermitSingle: [
+          { name: 'details', type: 'PermitDetails' },
+          { name: 'spender', type: 'address' },
+          { name: 'sigDeadline', type: 'uint256' },
+        ],
+        PermitDetails: permitDetails,
+      },
+      primaryType: 'PermitSingle',
+      message: {
+        details: details(),
+        spender: VAULT_ADDRESS,
+        sigDeadline: String(inOneHour),
+        ...message,
+      },
+    });
+
+    it('should validate a PermitSingle to the vault of its token', () => {
+      const result = validator.validateTypedData(permitSingle(), USER_ADDRESS);
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.PERMIT2);
+      expect(result.warnings).toBeUndefined();
+      expect(result.decoded?.permit2).toEqual({
+        spender: VAULT_ADDRESS,
+        sigDeadline: String(inOneHour),
+        details: [
+          {
+            token: INPUT_TOKEN,
+            amount: '1000000000',
+            expiration: String(inOneHour),
+            nonce: '0',
+            isUnlimited: false,
+          },
+        ],
+      });
+    });
+
+    it('should validate every token of a PermitBatch', () => {
+      const typedData = {
+        ...permitSingle(),
+        types: {
+          PermitBatch: [
+            { name: 'details', type: 'PermitDetails[]' },
+            { name: 'spender', type: 'address' },
+            { name: 'sigDeadline', type: 'uint256' },
+          ],
+          PermitDetails: permitDetails,
+        },
+        primaryType: 'PermitBatch',
+        message: {
+          details: [details(), details({ token: MALICIOUS_ADDRESS })],
+          spender: VAULT_ADDRESS,
+          sigDeadline: String(inOneHour),
+        },
+      };
+
+      const result = validator.validateTypedData(typedData, USER_ADDRESS);
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('Permit2 token is not a token of the yield');
+      expect(result.details).toEqual({ index: 1, actual: MALICIOUS_ADDRESS });
+    });
+
+    it('should reject another spender', () => {
+      const result = validator.validateTypedData(
+        permitSingle({ spender: OTHER_ADDRESS }),
+        USER_ADDRESS,
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('APPROVAL_SPENDER_MISMATCH');
+    });
+
+    it('should reject a verifyingContract other than Permit2', () => {
+      const typedData = permitSingle();
+      typedData.domain.verifyingContract = INPUT_TOKEN;
+
+      const result = validator.validateTypedData(typedData, USER_ADDRESS);
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('Typed data verifyingContract is not Permit2');
+    });
+
+    it('should reject a passed deadline and an expired allowance', () => {
+      const past = String(Math.floor(Date.now() / 1000) - 60);
+
+      expect(
+        validator.validateTypedData(
+          permitSingle({ sigDeadline: past }),
+          USER_ADDRESS,
+        ).reason,
+      ).toBe('Permit2 signature deadline has passed');
+      expect(
+        validator.validateTypedData(
+          permitSingle({ details: details({ expiration: past }) }),
+          USER_ADDRESS,
+        ).reason,
+      ).toBe('Permit2 allowance has already expired');
+      expect(
+        validator.validateTypedData(
+          permitSingle({ sigDeadline: past }),
+          USER_ADDRESS,
+        ).reasonCode,
+      ).toBe('DEADLINE_IN_PAST');
+    });
+
+    it('should reject amounts beyond uint160', () => {
+      const result = validator.validateTypedData(
+        permitSingle({ details: details({ amount: (1n << 160n).toString() }) }),
+        USER_ADDRESS,
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('Permit2 details are malformed');
+    });
+
+    it('should warn about unlimited and long-lived allowances', () => {
+      const inOneYear = String(Math.floor(Date.now() / 1000) + 365 * 86400);
+      const result = validator.validateTypedData(
+        permitSingle({
+          details: details({
+            amount: ((1n << 160n) - 1n).toString(),
+            expiration: inOneYear,
+          }),
+        }),
+        USER_ADDRESS,
+      );
+
+      // Shield, not the validator, weighs the deadline against the policy
+      expect(result.isValid).toBe(true);
+      expect(result.warnings?.map(({ code }) => code)).toEqual([
+        'INFINITE_APPROVAL',
+      ]);
+      expect(result.deadline?.timestamp).toBe(inOneYear);
+    });
+  });
+
+  describe('getAbiFunctions', () => {
+    it('should tell the WETH and vault overloads apart by signature', () => {
+      expect(
+        validator
+          .getAbiFunctions()
+          .map(({ transactionType, signature, selector }) => [
+            transactionType,
+            signature,
+            selector,
+          ]),
+      ).toEqual([
+        [
+          TransactionType.APPROVAL,
+          'approve(address,uint256)',
+          erc20Iface.getFunction('approve')!.selector,
+        ],
+        [
+          TransactionType.WRAP,
+          'deposit()',
+          wethIface.getFunction('deposit')!.selector,
+        ],
+        [
+          TransactionType.SUPPLY,
+          'deposit(uint256,address)',
+          erc4626Iface.getFunction('deposit')!.selector,
+        ],
+        [
+          TransactionType.SUPPLY,
+          'mint(uint256,address)',
+          erc4626Iface.getFunction('mint')!.selector,
+        ],
+        [
+          TransactionType.WITHDRAW,
+          'withdraw(uint256,address,address)',
+          erc4626Iface.getFunction('withdraw')!.selector,
+        ],
+        [
+          TransactionType.WITHDRAW,
+          'redeem(uint256,address,address)',
+          erc4626Iface.getFunction('redeem')!.selector,
+        ],
+        [
+          TransactionType.UNWRAP,
+          'withdraw(uint256)',
+          wethIface.getFunction('withdraw')!.selector,
+        ],
+      ]);
+    });
+  });
+
+  describe('getStakedBalanceCall', () => {
+    const balanceIface = new ethers.Interface([
+      'function maxWithdraw(address owner) view returns (uint256)',
+      'function balanceOf(address owner) view returns (uint256)',
+    ]);
+
+    it('should read maxWithdraw for a withdraw and balanceOf for a redeem', () => {
+      const withdraw = erc4626Iface.encodeFunctionData(
+        'withdraw(uint256,address,address)',
+        [1000n, USER_ADDRESS, USER_ADDRESS],
+      );
+      const redeem = erc4626Iface.encodeFunctionData(
+        'redeem(uint256,address,address)',
+        [500n, USER_ADDRESS, USER_ADDRESS],
+      );
+
+      expect(
+        validator.getStakedBalanceCall(buildTx({ data: withdraw })),
+      ).toEqual({
+        call: {
+          to: VAULT_ADDRESS.toLowerCase(),
+          data: balanceIface.encodeFunctionData('maxWithdraw', [USER_ADDRESS]),
+        },
+        amount: '1000',
+      });
+      expect(validator.getStakedBalanceCall(buildTx({ data: redeem }))).toEqual(
+        {
+          call: {
+            to: VAULT_ADDRESS.toLowerCase(),
+            data: balanceIface.encodeFunctionData('balanceOf', [USER_ADDRESS]),
+          },
+          amount: '500',
+        },
+      );
+    });
+
+    it('should read no balance for a deposit', () => {
+      const data = erc4626Iface.encodeFunctionData('deposit', [
+        1000n,
+        USER_ADDRESS,
+      ]);
+
+      expect(validator.getStakedBalanceCall(buildTx({ data }))).toBeUndefined();
+    });
+  });
+
+  describe('getBytecodeHash', () => {
+    const hash = '0x' + 'AB'.repeat(32);
+    const pinned = new ERC4626Validator({
+      ...mockConfig,
+      vaults: [
+        { ...mockConfig.vaults[0], bytecodeHashes: { [VAULT_ADDRESS]: hash } },
+      ],
+    });
+
+    it('should return the hash the registry pins, by any address case', () => {
+      expect(pinned.getBytecodeHash(VAULT_ADDRESS.toLowerCase())).toBe(
+        hash.toLowerCase(),
+      );
+      expect(pinned.getBytecodeHash(VAULT_ADDRESS.toUpperCase())).toBe(
+        hash.toLowerCase(),
+      );
+    });
+
+    it('should return undefined for contracts without a pinned hash', () => {
+      expect(pinned.getBytecodeHash(INPUT_TOKEN)).toBeUndefined();
+      expect(validator.getBytecodeHash(VAULT_ADDRESS)).toBeUndefined();
+    });
+  });
+
   describe('canEnter / canExit flag checks', () => {
     it('should reject SUPPLY to vault with canEnter: false', () => {
       const data = erc4626Iface.encodeFunctionData('deposit', [
@@ -1,13 +1,21 @@
 import { ethers } from 'ethers';
 import {
   ActionArguments,
+  BalanceChange,
+  StakedBalanceCall,
+  TokenSpend,
   TransactionType,
   ValidationContext,
   ValidationResult,
+  ValidatorCapabilities,
+  Withdrawal,
+  YieldStatus,
 } from '../../../types';
 import { BaseEVMValidator, EVMTransaction } from '../base.validator';
 import { VaultInfo, VaultConfiguration } from './types';
 import { WETH_ADDRESSES } from './constants';
+import { AssetInfo } from '../../../utils/amount';
+import { isDefined } from '../../../utils/validation';
 
 /**
  * Standard ERC4626 ABI - only the functions we need to validate
@@ -26,6 +34,14 @@ const ERC20_ABI = [
   'function approve(address spender, uint256 amount) returns (bool)',
 ];
 
+/**
+ * What an owner can take out of a vault, for balance checks
+ */
+const BALANCE_ABI = [
+  'function maxWithdraw(address owner) view returns (uint256)',
+  'function balanceOf(address owner) view returns (uint256)',
+];
+
 /**
  * WETH ABI - for wrap/unwrap validation
  */
@@ -34,6 +50,29 @@ const WETH_ABI = [
   'function withdraw(uint256 wad)',
 ];
 
+// e.g. 'Morpho USDC vault', from the protocol slug 'morpho'
+function getVaultName(vault: VaultInfo): string {
+  const protocol = vault.protocol
+    .split('-')
+    .map((word) => word.charAt(0).toUpperCase() + word.slice(1))
+    .join(' ');
+  return vault.inputTokenSymbol
+    ? `${protocol} ${vault.inputTokenSymbol} vault`
+    : `${protocol} vault`;
+}
+
+// Addresses and hashes lowercased, so that they compare as strings
+function normalizeHashes(
+  hashes: Record<string, string>,
+): Record<string, string> {
+  return Object.fromEntries(
+    Object.entries(hashes).map(([address, hash]) => [
+      address.toLowerCase(),
+      hash.toLowerCase(),
+    ]),
+  );
+}
+
 /**
  * Generic ERC4626 Validator
  *
@@ -49,6 +88,7 @@ export class ERC4626Validator extends BaseEVMValidator {
   private static readonly erc4626Interface = new ethers.Interface(ERC4626_ABI);
   private static readonly erc20Interface = new ethers.Interface(ERC20_ABI);
   private static readonly wethInterface = new ethers.Interface(WETH_ABI);
+  private static readonly balanceInterface = new ethers.Interface(BALANCE_ABI);
   private vaultInfoMap: Map<string, VaultInfo>; // "chainId:address" -> VaultInfo
 
   constructor(vaultConfig: VaultConfiguration) {
@@ -70,6 +110,15 @@ export class ERC4626Validator extends BaseEVMValidator {
         address,
         inputTokenAddress: vault.inputTokenAddress.toLowerCase(),
         vaultTokenAddress: vault.vaultTokenAddress.toLowerCase(),
+        bytecodeHashes:
+          vault.bytecodeHashes && normalizeHashes(vault.bytecodeHashes),
+        privilegedFunctions:
+          vault.privilegedFunctions &&
+          Object.fromEntries(
+            Object.entries(vault.privilegedFunctions).map(
+              ([address, signatures]) => [address.toLowerCase(), signatures],
+            ),
+          ),
       };
       this.vaultInfoMap.set(`${chainId}:${address}`, normalizedVault);
       if (vault.allocatorVaults) {
@@ -94,6 +143,285 @@ export class ERC4626Validator extends BaseEVMValidator {
     ];
   }
 
+  // Registered yields have a single vault, so they span a single chain
+  getCapabilities(): ValidatorCapabilities {
+    const vaults = Array.from(this.vaultInfoMap.values());
+    const contracts = new Set<string>();
+    for (const vault of vaults) {
+      contracts.add(vault.address);
+      contracts.add(vault.inputTokenAddress);
+      const wethAddress = vault.isWethVault
+        ? this.getWethAddress(vault.chainId)
+        : null;
+      if (wethAddress) contracts.add(wethAddress.toLowerCase());
+    }
+
+    return {
+      name: vaults.length > 0 ? getVaultName(vaults[0]) : 'ERC4626 vault',
+      protocol: vaults[0]?.protocol ?? 'erc4626',
+      network: vaults[0]?.network ?? 'unknown',
+      supportsPartialAmounts: true,
+      chainId: vaults.length > 0 ? String(vaults[0].chainId) : '',
+      contracts: [...contracts],
+    };
+  }
+
+  // As the registry pins it for the vault
+  getBytecodeHash(address: string): string | undefined {
+    const normalized = address.toLowerCase();
+    for (const vault of this.vaultInfoMap.values()) {
+      const hash = vault.bytecodeHashes?.[normalized];
+      if (isDefined(hash)) return hash;
+    }
+    return undefined;
+  }
+
+  // The common ones, and those the registry lists for the contract
+  protected getPrivilegedFunctions(contract: string): string[] {
+    const normalized = contract.toLowerCase();
+    const listed = new Set<string>();
+    for (const vault of this.vaultInfoMap.values()) {
+      for (const signature of vault.privilegedFunctions?.[normalized] ?? []) {
+        listed.add(signature);
+      }
+    }
+    return [...super.getPrivilegedFunctions(contract), ...listed];
+  }
+
+  protected getDecodeInterfaces(): ethers.Interface[] {
+    return [
+      ERC4626Validator.erc4626Interface,
+      ERC4626Validator.erc20Interface,
+      ERC4626Validator.wethInterface,
+    ];
+  }
+
+  protected getTransactionFunctions(): Partial<
+    Record<TransactionType, ethers.FunctionFragment[]>
+  > {
+    const vault = (signature: string) =>
+      ERC4626Validator.erc4626Interface.getFunction(signature)!;
+    const weth = (signature: string) =>
+      ERC4626Validator.wethInterface.getFunction(signature)!;
+    return {
+      [TransactionType.APPROVAL]: [
+        ERC4626Validator.erc20Interface.getFunction('approve')!,
+      ],
+      [TransactionType.WRAP]: [weth('deposit()')],
+      [TransactionType.SUPPLY]: [vault('deposit'), vault('mint')],
+      [TransactionType.WITHDRAW]: [vault('withdraw'), vault('redeem')],
+      [TransactionType.UNWRAP]: [weth('withdraw(uint256)')],
+    };
+  }
+
+  // Vaults on the transaction's chain that take the approved token
+  getExpectedSpenders(unsignedTransaction: string): string[] {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    const chainId = tx ? this.getNumericChainId(tx) : null;
+    if (!tx?.to || chainId === null) return [];
+
+    return this.getVaultsForInputToken(chainId, tx.to);
+  }
+
+  // The input tokens of the vaults. Wrapping ETH into WETH stakes nothing
+  getStakeTokens(): string[] {
+    const tokens = new Set<string>();
+    for (const vault of this.vaultInfoMap.values()) {
+      tokens.add(vault.inputTokenAddress);
+    }
+    return [...tokens];
+  }
+
+  // Those of the registered vault, the first loaded
+  getTransactionRecipient(
+    transactionType: TransactionType,
+  ): string | undefined {
+    const [vault] = this.vaultInfoMap.values();
+    if (!vault) return undefined;
+
+    switch (transactionType) {
+      case TransactionType.APPROVAL:
+        return vault.inputTokenAddress;
+      case TransactionType.WRAP:
+      case TransactionType.UNWRAP:
+        return this.getWethAddress(vault.chainId) ?? undefined;
+      default:
+        return vault.address;
+    }
+  }
+
+  // The registry marks deposits or withdrawals of a vault as paused
+  isPaused(transactionType: TransactionType): boolean {
+    const [vault] = this.vaultInfoMap.values();
+    return (
+      (transactionType === TransactionType.SUPPLY &&
+        vault?.canEnter === false) ||
+      (transactionType === TransactionType.WITHDRAW && vault?.canExit === false)
+    );
+  }
+
+  getStatus(): YieldStatus {
+    const [vault] = this.vaultInfoMap.values();
+    return vault?.status ?? 'active';
+  }
+
+  // deposit(assets) pulls exactly assets of the input token. What mint costs
+  // is only known on-chain, so it is not reported
+  getTokenSpend(unsignedTransaction: string): TokenSpend | undefined {
+    const call = this.parseVaultCall(unsignedTransaction);
+    if (call?.parsed.name !== 'deposit') return undefined;
+
+    return {
+      token: call.vaultInfo.inputTokenAddress,
+      spender: call.vaultInfo.address,
+      amount: BigInt(call.parsed.args[0]).toString(),
+    };
+  }
+
+  // deposit and mint credit the shares to their receiver
+  getBeneficiary(unsignedTransaction: string): string | undefined {
+    const parsed = this.parseVaultCall(unsignedTransaction)?.parsed;
+    if (parsed?.name !== 'deposit' && parsed?.name !== 'mint') {
+      return undefined;
+    }
+    return parsed.args[1];
+  }
+
+  // withdraw names the assets paid out, redeem the shares burned for them
+  getWithdrawal(unsignedTransaction: string): Withdrawal | undefined {
+    const call = this.parseVaultCall(unsignedTransaction);
+    if (!call) return undefined;
+
+    const { vaultInfo, parsed } = call;
+    if (parsed.name !== 'withdraw' && parsed.name !== 'redeem') {
+      return undefined;
+    }
+
+    const [amount, receiver] = parsed.args;
+    return {
+      phase: 'WITHDRAW',
+      recipient: receiver,
+      token:
+        parsed.name === 'withdraw'
+          ? vaultInfo.inputTokenAddress
+          : vaultInfo.vaultTokenAddress,
+      amount: BigInt(amount).toString(),
+    };
+  }
+
+  // withdraw names assets, which the owner can take out up to maxWithdraw,
+  // and redeem shares, of which the owner holds balanceOf
+  getStakedBalanceCall(
+    unsignedTransaction: string,
+  ): StakedBalanceCall | undefined {
+    const call = this.parseVaultCall(unsignedTransaction);
+    if (!call) return undefined;
+
+    const { vaultInfo, parsed } = call;
+    if (parsed.name !== 'withdraw' && parsed.name !== 'redeem') {
+      return undefined;
+    }
+
+    const [amount, , owner] = parsed.args;
+    return {
+      call: {
+        to: vaultInfo.address,
+        data: ERC4626Validator.balanceInterface.encodeFunctionData(
+          parsed.name === 'withdraw' ? 'maxWithdraw' : 'balanceOf',
+          [owner],
+        ),
+      },
+      amount: BigInt(amount).toString(),
+    };
+  }
+
+  // Vault shares are credited to the receiver: deposit returns how many were
+  // minted, while mint names them up front and returns the assets it took
+  getBalanceChange(
+    unsignedTransaction: string,
+    returnData: string,
+  ): BalanceChange | undefined {
+    const call = this.parseVaultCall(unsignedTransaction);
+    if (!call) return undefined;
+
+    const { vaultInfo, parsed } = call;
+    if (parsed.name === 'mint') {
+      return {
+        token: vaultInfo.vaultTokenAddress,
+        amount: BigInt(parsed.args[0]).toString(),
+      };
+    }
+    if (parsed.name !== 'deposit') return undefined;
+
+    try {
+      const [shares] = ERC4626Validator.erc4626Interface.decodeFunctionResult(
+        'deposit',
+        returnData,
+      );
+      return {
+        token: vaultInfo.vaultTokenAddress,
+        amount: BigInt(shares).toString(),
+      };
+    } catch {
+      return undefined;
+    }
+  }
+
+  // A call of one of the ERC4626 functions on a vault this validator knows
+  private parseVaultCall(
+    unsignedTransaction: string,
+  ): { vaultInfo: VaultInfo; parsed: ethers.TransactionDescription } | null {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    const chainId = tx ? this.getNumericChainId(tx) : null;
+    if (!tx?.to || chainId === null) return null;
+
+    const vaultInfo = this.vaultInfoMap.get(
+      `${chainId}:${tx.to.toLowerCase()}`,
+    );
+    if (!vaultInfo) return null;
+
+    const parsed = this.tryParseTransaction(
+      tx,
+      ERC4626Validator.erc4626Interface,
+    );
+    return parsed ? { vaultInfo, parsed } : null;
+  }
+
+  // Input tokens that implement EIP-2612 can be permitted to their vaults
+  protected getPermitSpenders(token: string): string[] {
+    const { chainId } = this.getCapabilities();
+    return this.getVaultsForInputToken(Number(chainId), token);
+  }
+
+  // The registry names the input token's decimals for vaults it has them for
+  protected getTokenInfo(
+    chainId: number,
+    token: string,
+  ): AssetInfo | undefined {
+    const inputToken = token.toLowerCase();
+    const vault = Array.from(this.vaultInfoMap.values()).find(
+      (v) =>
+        v.chainId === chainId &&
+        v.inputTokenAddress === inputToken &&
+        isDefined(v.inputTokenDecimals),
+    );
+    if (!vault) return undefined;
+
+    return {
+      symbol: vault.inputTokenSymbol ?? '',
+      decimals: vault.inputTokenDecimals!,
+    };
+  }
+
+  private getVaultsForInputToken(chainId: number, token: string): string[] {
+    const inputToken = token.toLowerCase();
+    return Array.from(this.vaultInfoMap.values())
+      .filter(
+        (v) => v.chainId === chainId && v.inputTokenAddress === inputToken,
+      )
+      .map((v) => v.address);
+  }
+
   validate(
     unsignedTransaction: string,
     transactionType: TransactionType,
@@ -132,7 +460,7 @@ export class ERC4626Validator extends BaseEVMValidator {
       case TransactionType.WRAP:
         return this.validateWrap(tx, chainId);
       case TransactionType.SUPPLY:
-        return this.validateSupply(tx, userAddress, chainId);
+        return this.validateSupply(tx, chainId);
       case TransactionType.WITHDRAW:
         return this.validateWithdraw(tx, userAddress, chainId);
       case TransactionType.UNWRAP:
@@ -263,7 +591,6 @@ export class ERC4626Validator extends BaseEVMValidator {
    */
   private validateSupply(
     tx: EVMTransaction,
-    userAddress: string,
     chainId: number,
   ): ValidationResult {
     const resolved = this.resolveVault(tx, chainId);
@@ -303,21 +630,16 @@ export class ERC4626Validator extends BaseEVMValidator {
       });
     }
 
-    // Both deposit and mint have receiver as second parameter
-    const [amount, receiver] = parsed.args;
+    // Both deposit and mint take the amount first and the receiver second
+    const [amount] = parsed.args;
     const amountBigInt = BigInt(amount);
     if (amountBigInt === 0n) {
       return this.blocked('Supply amount is zero');
     }
 
-    // Validate receiver is the user
-    if (receiver.toLowerCase() !== userAddress.toLowerCase()) {
-      return this.blocked('Receiver address does not match user address', {
-        expected: userAddress,
-        actual: receiver,
-      });
-    }
-
+    // The receiver is checked by Shield against the beneficiary, which is
+    // the user unless they deposit on another account's behalf; see
+    // getBeneficiary
     return this.safe();
   }
 
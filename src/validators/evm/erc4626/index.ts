@@ -2,6 +2,16 @@
 export { ERC4626Validator } from './erc4626.validator';
 
 // Types
-export type { VaultInfo, VaultConfiguration } from './types';
+export type {
+  VaultInfo,
+  VaultConfiguration,
+  VaultRegistryEntry,
+  VaultRegistryOverride,
+} from './types';
 
-export { loadEmbeddedRegistry } from './vault-config';
+export {
+  loadEmbeddedRegistry,
+  loadRegistryOverride,
+  getEmbeddedRegistryInfo,
+  getRegistryOverrideHash,
+} from './vault-config';
@@ -1,3 +1,5 @@
+import type { YieldStatus } from '../../../types';
+
 /**
  * Vault information from API
  */
@@ -7,12 +9,17 @@ export interface VaultInfo {
   protocol: string; // e.g., 'morpho', 'angle', 'euler'
   yieldId: string; // StakeKit yield ID
   inputTokenAddress: string; // Token being deposited
+  inputTokenSymbol?: string; // e.g. 'USDC'
+  inputTokenDecimals?: number;
   vaultTokenAddress: string; // Vault share token
   network: string; // e.g., 'ethereum', 'arbitrum'
   isWethVault?: boolean; // Supports native ETH deposits
   canEnter?: boolean; // Whether deposits are enabled
   canExit?: boolean; // Whether withdrawals are enabled
+  status?: YieldStatus; // 'active' when not given
   allocatorVaults?: string[]; // Allocator vault addresses (ERC4626-compatible)
+  bytecodeHashes?: Record<string, string>; // Contract address -> code hash
+  privilegedFunctions?: Record<string, string[]>; // Address -> signatures
 }
 
 /**
@@ -22,3 +29,40 @@ export interface VaultConfiguration {
   vaults: VaultInfo[];
   lastUpdated: number; // Timestamp of last fetch
 }
+
+/**
+ * A vault as the registry lists it
+ */
+export interface VaultRegistryEntry {
+  yieldId: string;
+  address: string;
+  chainId: number;
+  protocol: string;
+  network: string;
+  inputTokenAddress: string;
+  inputTokenSymbol?: string;
+  inputTokenDecimals?: number;
+  vaultTokenAddress: string;
+  isWethVault: boolean;
+  canEnter?: boolean;
+  canExit?: boolean;
+  status?: YieldStatus;
+  allocatorVaults?: string[];
+  // keccak256 of the runtime code of each contract the vault's
+  // transactions call, keyed by address
+  bytecodeHashes?: Record<string, string>;
+  // Canonical signatures of the owner- or admin-only functions of the
+  // vault's contracts, beyond the common ones, keyed by address
+  privilegedFunctions?: Record<string, string[]>;
+}
+
+/**
+ * Vaults registered over the embedded registry, in its format, e.g. to
+ * validate testnet deployments. An entry replaces any yield of the same
+ * yieldId.
+ */
+export interface VaultRegistryOverride {
+  version?: number;
+  generatedAt?: string;
+  vaults: VaultRegistryEntry[];
+}
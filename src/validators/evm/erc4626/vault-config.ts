@@ -1,45 +1,86 @@
+import { createHash } from 'crypto';
+import { isDefined } from '../../../utils/validation';
 import registryData from './vault-registry.json';
-import { VaultConfiguration, VaultInfo } from './types';
-
-interface RegistryEntry {
-  yieldId: string;
-  address: string;
-  chainId: number;
-  protocol: string;
-  network: string;
-  inputTokenAddress: string;
-  vaultTokenAddress: string;
-  isWethVault: boolean;
-  canEnter?: boolean;
-  canExit?: boolean;
-  allocatorVaults?: string[];
-}
+import {
+  VaultConfiguration,
+  VaultInfo,
+  VaultRegistryEntry,
+  VaultRegistryOverride,
+} from './types';
 
 interface VaultRegistry {
   version: number;
   generatedAt: string;
-  vaults: RegistryEntry[];
+  vaults: VaultRegistryEntry[];
 }
 
-export function loadEmbeddedRegistry(): VaultConfiguration {
-  const registry = registryData as VaultRegistry;
-
-  const vaults: VaultInfo[] = registry.vaults.map((entry) => ({
+function toVaultInfo(entry: VaultRegistryEntry): VaultInfo {
+  return {
     address: entry.address.toLowerCase(),
     chainId: entry.chainId,
     protocol: entry.protocol,
     yieldId: entry.yieldId,
     inputTokenAddress: entry.inputTokenAddress.toLowerCase(),
+    inputTokenSymbol: entry.inputTokenSymbol,
+    inputTokenDecimals: entry.inputTokenDecimals,
     vaultTokenAddress: entry.vaultTokenAddress.toLowerCase(),
     network: entry.network,
     isWethVault: entry.isWethVault,
     canEnter: entry.canEnter,
     canExit: entry.canExit,
+    status: entry.status,
     allocatorVaults: entry.allocatorVaults?.map((a) => a.toLowerCase()),
-  }));
+    bytecodeHashes: entry.bytecodeHashes,
+    privilegedFunctions: entry.privilegedFunctions,
+  };
+}
+
+export function loadEmbeddedRegistry(): VaultConfiguration {
+  const registry = registryData as VaultRegistry;
 
   return {
-    vaults,
+    vaults: registry.vaults.map(toVaultInfo),
     lastUpdated: new Date(registry.generatedAt).getTime(),
   };
 }
+
+/**
+ * The vaults of a registry override. An override without generatedAt is
+ * taken to be current.
+ */
+export function loadRegistryOverride(
+  override: VaultRegistryOverride,
+): VaultConfiguration {
+  const generatedAt = isDefined(override.generatedAt)
+    ? new Date(override.generatedAt).getTime()
+    : NaN;
+  return {
+    vaults: override.vaults.map(toVaultInfo),
+    lastUpdated: Number.isNaN(generatedAt) ? Date.now() : generatedAt,
+  };
+}
+
+/**
+ * Identifies the embedded registry snapshot. hash is the SHA-256 of the
+ * registry as loaded, so two builds with the same hash validate the same
+ * vaults.
+ */
+export function getEmbeddedRegistryInfo(): {
+  version: number;
+  generatedAt: string;
+  hash: string;
+} {
+  const registry = registryData as VaultRegistry;
+  return {
+    version: registry.version,
+    generatedAt: registry.generatedAt,
+    hash: createHash('sha256').update(JSON.stringify(registry)).digest('hex'),
+  };
+}
+
+// SHA-256 of a registry override as given, reported by getVersion
+export function getRegistryOverrideHash(
+  override: VaultRegistryOverride,
+): string {
+  return createHash('sha256').update(JSON.stringify(override)).digest('hex');
+}
@@ -2,3 +2,10 @@ export { BaseEVMValidator } from './base.validator';
 export type { EVMTransaction } from './base.validator';
 export { LidoValidator } from './lido/lido.validator';
 export { RocketPoolValidator } from './rocketpool/rocketpool.validator';
+export { EigenLayerValidator } from './eigenlayer/eigenlayer.validator';
+export { VoteEscrowValidator } from './vote-escrow/vote-escrow.validator';
+export type { VoteEscrowConfig } from './vote-escrow/vote-escrow.validator';
+export type {
+  EigenLayerConfig,
+  EigenLayerStrategy,
+} from './eigenlayer/eigenlayer.validator';
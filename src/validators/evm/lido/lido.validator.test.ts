@@ -139,7 +139,56 @@ describe('LidoValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found'); // Previously: toContain('Invalid method for staking');
+      expect(result.reasonCode).toBe('OPERATION_NOT_SUPPORTED_FOR_YIELD');
+    });
+
+    it('should report the value sent in the decoded result', () => {
+      const tx = {
+        to: lidoStEthAddress,
+        from: userAddress,
+        value: '0xde0b6b3a7640000',
+        data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+        nonce: 0,
+        gasLimit: '0x30d40',
+        gasPrice: '0x4a817c800',
+        chainId: 1,
+        type: 0,
+      };
+
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: JSON.stringify(tx),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.decoded?.value).toBe('1000000000000000000');
+    });
+
+    it('should reject stake without ETH value', () => {
+      const tx = {
+        to: lidoStEthAddress,
+        from: userAddress,
+        value: '0x0',
+        data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+        nonce: 0,
+        gasLimit: '0x30d40',
+        gasPrice: '0x4a817c800',
+        chainId: 1,
+        type: 0,
+      };
+
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: JSON.stringify(tx),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      const stakeAttempt = result.details?.attempts?.find(
+        (a: any) => a.type === TransactionType.STAKE,
+      );
+      expect(stakeAttempt?.reason).toBe('Stake must send ETH value');
     });
 
     it('should reject stake with wrong referral address', () => {
@@ -281,7 +330,8 @@ describe('LidoValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found'); // Previously: toContain('owner is not user address');
+      expect(result.reasonCode).toBe('WITHDRAWAL_RECIPIENT_MISMATCH');
+      expect(result.details?.actual?.toLowerCase()).toBe(wrongOwner);
     });
 
     it('should reject unstake to wrong contract', () => {
@@ -341,7 +391,11 @@ describe('LidoValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found'); // Previously: toContain('Unstake should not send ETH value');
+      expect(result.reasonCode).toBe('UNEXPECTED_NATIVE_VALUE');
+      expect(result.details).toMatchObject({
+        value: '1000000000000000000',
+        functionSignature: 'requestWithdrawals(uint256[],address)',
+      });
     });
 
     it('should reject unstake with empty amounts array', () => {
@@ -492,7 +546,123 @@ describe('LidoValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found'); // Previously: toContain('not to Lido Withdrawal Queue contract');
+      expect(result.reasonCode).toBe('OPERATION_NOT_SUPPORTED_FOR_YIELD');
+    });
+  });
+
+  describe('Withdrawal request variants', () => {
+    const wstEthAddress = '0x7f39C581F595B53c5cb19bD0b3f8dA6c935E2Ca0';
+    const permitDeadline = Math.floor(Date.now() / 1000) + 3600;
+    const queue = new ethers.Interface([
+      'function requestWithdrawals(uint256[] _amounts, address _owner)',
+      'function requestWithdrawalsWstETH(uint256[] _amounts, address _owner)',
+      'function requestWithdrawalsWithPermit(uint256[] _amounts, address _owner, (uint256 value, uint256 deadline, uint8 v, bytes32 r, bytes32 s) _permit)',
+      'function requestWithdrawalsWstETHWithPermit(uint256[] _amounts, address _owner, (uint256 value, uint256 deadline, uint8 v, bytes32 r, bytes32 s) _permit)',
+    ]);
+
+    const request = (name: string, args: unknown[]) =>
+      shield.validate({
+        yieldId,
+        unsignedTransaction: JSON.stringify({
+          to: lidoWithdrawalQueueAddress,
+          from: userAddress,
+          value: '0x0',
+          data: queue.encodeFunctionData(name, args),
+          nonce: 0,
+          gasLimit: '0x493e0',
+          gasPrice: '0x4a817c800',
+          chainId: 1,
+          type: 0,
+        }),
+        userAddress,
+      });
+
+    // Why the request did not match UNSTAKE
+    const unstakeReason = (result: ReturnType<typeof request>) =>
+      result.details?.attempts?.find(
+        (attempt: { type: TransactionType }) =>
+          attempt.type === TransactionType.UNSTAKE,
+      )?.reason;
+
+    const permitFor = (value: bigint) => [
+      value,
+      permitDeadline,
+      27,
+      ethers.ZeroHash,
+      ethers.ZeroHash,
+    ];
+
+    it('should validate a wstETH withdrawal request', () => {
+      const result = request('requestWithdrawalsWstETH', [
+        [ethers.parseEther('1')],
+        userAddress,
+      ]);
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.UNSTAKE);
+      expect(result.decoded?.withdrawal).toEqual({
+        phase: 'REQUEST',
+        recipient: ethers.getAddress(userAddress),
+        token: wstEthAddress,
+        amount: ethers.parseEther('1').toString(),
+      });
+    });
+
+    it('should validate a permit request and report its deadline', () => {
+      const result = request('requestWithdrawalsWithPermit', [
+        [ethers.parseEther('1'), ethers.parseEther('2')],
+        userAddress,
+        permitFor(ethers.parseEther('3')),
+      ]);
+
+      expect(result.isValid).toBe(true);
+      expect(result.decoded?.withdrawal?.amount).toBe(
+        ethers.parseEther('3').toString(),
+      );
+      expect(result.deadline?.timestamp).toBe(permitDeadline.toString());
+    });
+
+    it('should reject a permit for less than the amount withdrawn', () => {
+      const result = request('requestWithdrawalsWstETHWithPermit', [
+        [ethers.parseEther('2')],
+        userAddress,
+        permitFor(ethers.parseEther('1')),
+      ]);
+
+      expect(result.isValid).toBe(false);
+      expect(unstakeReason(result)).toBe(
+        'Permit value is less than the amount withdrawn',
+      );
+    });
+
+    it('should reject stETH amounts outside the queue bounds', () => {
+      for (const amount of [99n, ethers.parseEther('1001')]) {
+        const result = request('requestWithdrawals', [[amount], userAddress]);
+
+        expect(result.isValid).toBe(false);
+        expect(unstakeReason(result)).toBe(
+          'Withdrawal amount is out of bounds',
+        );
+      }
+    });
+
+    it('should reject a zero wstETH amount', () => {
+      const result = request('requestWithdrawalsWstETH', [[0n], userAddress]);
+
+      expect(result.isValid).toBe(false);
+      expect(unstakeReason(result)).toBe(
+        'Withdrawal amount is out of bounds',
+      );
+    });
+
+    it('should reject a wstETH request owned by someone else', () => {
+      const result = request('requestWithdrawalsWstETH', [
+        [ethers.parseEther('1')],
+        '0x0000000000000000000000000000000000000001',
+      ]);
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('WITHDRAWAL_RECIPIENT_MISMATCH');
     });
   });
 
@@ -521,6 +691,7 @@ describe('LidoValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(true);
+      expect(result.decoded?.requestIds).toEqual(['123']);
     });
 
     it('should validate a valid batch claim transaction', () => {
@@ -553,6 +724,7 @@ describe('LidoValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(true);
+      expect(result.decoded?.requestIds).toEqual(['123', '124']);
     });
 
     it('should reject claim with wrong method', () => {
@@ -576,7 +748,7 @@ describe('LidoValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found'); // Previously: toContain('Invalid transaction data for claiming');
+      expect(result.reasonCode).toBe('OPERATION_NOT_SUPPORTED_FOR_YIELD');
     });
 
     it('should reject claim with ETH value', () => {
@@ -602,7 +774,7 @@ describe('LidoValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found'); // Previously: toContain('Claim should not send ETH value');
+      expect(result.reasonCode).toBe('UNEXPECTED_NATIVE_VALUE');
     });
 
     it('should reject batch claim with empty requestIds array', () => {
@@ -762,6 +934,211 @@ describe('LidoValidator via Shield', () => {
     });
   });
 
+  describe('Permit typed data', () => {
+    const inOneHour = Math.floor(Date.now() / 1000) + 3600;
+    const permit = (message: Record<string, unknown> = {}) => ({
+      domain: {
+        name: 'Liquid staked Ether 2.0',
+        version: '2',
+        chainId: 1,
+        verifyingContract: lidoStEthAddress,
+      },
+      types: {
+        Permit: [
+          { name: 'owner', type: 'address' },
+          { name: 'spender', type: 'address' },
+          { name: 'value', type: 'uint256' },
+          { name: 'nonce', type: 'uint256' },
+          { name: 'deadline', type: 'uint256' },
+        ],
+      },
+      primaryType: 'Permit',
+      message: {
+        owner: userAddress,
+        spender: lidoWithdrawalQueueAddress,
+        value: ethers.parseEther('1').toString(),
+        nonce: 0,
+        deadline: inOneHour,
+        ...message,
+      },
+    });
+
+    it('should validate a stETH permit to the withdrawal queue', () => {
+      const result = shield.validateTypedData({
+        yieldId,
+        typedData: permit(),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.PERMIT);
+      expect(result.warnings).toBeUndefined();
+      expect(result.decoded?.approval).toEqual({
+        token: lidoStEthAddress,
+        spender: lidoWithdrawalQueueAddress,
+        amount: '1000000000000000000',
+        isUnlimited: false,
+      });
+    });
+
+    it('should reject a permit on another chain', () => {
+      const typedData = permit();
+      typedData.domain.chainId = 5;
+
+      const result = shield.validateTypedData({
+        yieldId,
+        typedData,
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe(
+        'Typed data chain ID does not match the yield',
+      );
+    });
+
+    it('should reject a permit for another token', () => {
+      const typedData = permit();
+      typedData.domain.verifyingContract =
+        '0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48';
+
+      const result = shield.validateTypedData({
+        yieldId,
+        typedData,
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe(
+        'Typed data verifyingContract is not a known token',
+      );
+    });
+
+    it('should reject a permit to an unknown spender', () => {
+      const result = shield.validateTypedData({
+        yieldId,
+        typedData: permit({
+          spender: '0x0000000000000000000000000000000000000001',
+        }),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('APPROVAL_SPENDER_MISMATCH');
+    });
+
+    it('should reject a permit signed for another owner', () => {
+      const result = shield.validateTypedData({
+        yieldId,
+        typedData: permit({
+          owner: '0x0000000000000000000000000000000000000001',
+        }),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('SENDER_MISMATCH');
+      expect(result.details?.expected).toBe(userAddress);
+    });
+
+    it('should reject an expired permit', () => {
+      const result = shield.validateTypedData({
+        yieldId,
+        typedData: permit({ deadline: inOneHour - 7200 }),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('Permit deadline has passed');
+      expect(result.reasonCode).toBe('DEADLINE_IN_PAST');
+    });
+
+    it('should reject a Permit type that is not EIP-2612', () => {
+      const typedData = permit();
+      typedData.types.Permit = typedData.types.Permit.slice(0, 4);
+
+      const result = shield.validateTypedData({
+        yieldId,
+        typedData,
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('Permit type does not match EIP-2612');
+    });
+
+    it('should reject other primary types', () => {
+      const result = shield.validateTypedData({
+        yieldId,
+        typedData: { ...permit(), primaryType: 'Order' },
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reason).toBe('Unsupported typed data primaryType');
+    });
+
+    it('should warn about unlimited and long-lived permits', () => {
+      const result = shield.validateTypedData({
+        yieldId,
+        typedData: permit({
+          value: ethers.MaxUint256.toString(),
+          deadline: ethers.MaxUint256.toString(),
+        }),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.decoded?.approval?.isUnlimited).toBe(true);
+      expect(result.warnings?.map((w) => w.code)).toEqual([
+        'INFINITE_APPROVAL',
+        'LONG_DEADLINE',
+      ]);
+      expect(result.deadline).toEqual({
+        timestamp: ethers.MaxUint256.toString(),
+      });
+    });
+
+    it('should report the deadline of a permit', () => {
+      const result = shield.validateTypedData({
+        yieldId,
+        typedData: permit(),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.warnings).toBeUndefined();
+      expect(result.deadline).toEqual({
+        timestamp: String(inOneHour),
+        iso: new Date(inOneHour * 1000).toISOString(),
+      });
+    });
+
+    it('should warn about deadlines more than a day away by default', () => {
+      const inTwoDays = inOneHour + 47 * 3600;
+
+      const warned = shield.validateTypedData({
+        yieldId,
+        typedData: permit({ deadline: inTwoDays }),
+        userAddress,
+      });
+      const allowed = shield.validateTypedData({
+        yieldId,
+        typedData: permit({ deadline: inTwoDays }),
+        userAddress,
+        policy: { maxDeadlineSeconds: 7 * 86400 },
+      });
+
+      expect(warned.warnings?.map((w) => w.code)).toEqual(['LONG_DEADLINE']);
+      expect(warned.warnings?.[0].details).toEqual({
+        deadline: String(inTwoDays),
+        maxDeadlineSeconds: 86400,
+      });
+      expect(allowed.isValid).toBe(true);
+      expect(allowed.warnings).toBeUndefined();
+    });
+  });
+
   describe('General validation', () => {
     it('should reject transaction from wrong user', () => {
       const wrongUser = '0x0000000000000000000000000000000000000001';
@@ -785,7 +1162,157 @@ describe('LidoValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found'); // Previously: toContain('not from user address');
+      expect(result.reason).toBe('SENDER_MISMATCH');
+    });
+
+    describe('Transaction types', () => {
+      const stake = {
+        to: lidoStEthAddress,
+        from: userAddress,
+        value: '0xde0b6b3a7640000',
+        data: '0xa1903eab' + referralAddress.slice(2).padStart(64, '0'),
+        nonce: 0,
+        gasLimit: '0x30d40',
+        chainId: 1,
+      };
+      const accessList = [
+        {
+          address: lidoStEthAddress,
+          storageKeys: [
+            '0x0000000000000000000000000000000000000000000000000000000000000000',
+          ],
+        },
+      ];
+
+      const validateTx = (tx: object) =>
+        shield.validate({
+          yieldId,
+          unsignedTransaction: JSON.stringify(tx),
+          userAddress,
+        });
+
+      it('should accept a legacy type 0 transaction', () => {
+        const result = validateTx({
+          ...stake,
+          gasPrice: '0x4a817c800',
+          type: 0,
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.detectedType).toBe(TransactionType.STAKE);
+      });
+
+      it('should accept an EIP-2930 type 1 transaction', () => {
+        const result = validateTx({
+          ...stake,
+          gasPrice: '0x4a817c800',
+          accessList,
+          type: '0x1',
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.detectedType).toBe(TransactionType.STAKE);
+      });
+
+      it('should accept an EIP-1559 type 2 transaction', () => {
+        const result = validateTx({
+          ...stake,
+          maxFeePerGas: '0x6fc23ac00',
+          maxPriorityFeePerGas: '0x3b9aca00',
+          accessList,
+          type: 2,
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.detectedType).toBe(TransactionType.STAKE);
+        expect(result.warnings).toBeUndefined();
+      });
+
+      it('should warn about access list addresses outside the yield', () => {
+        const padding = '0x0000000000000000000000000000000000000bad';
+        const result = validateTx({
+          ...stake,
+          maxFeePerGas: '0x6fc23ac00',
+          maxPriorityFeePerGas: '0x3b9aca00',
+          accessList: [...accessList, { address: padding, storageKeys: [] }],
+          type: 2,
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.warnings).toEqual([
+          expect.objectContaining({
+            code: 'ACCESS_LIST_UNEXPECTED_ADDRESS',
+            details: { addresses: [padding] },
+          }),
+        ]);
+      });
+
+      it('should accept fee fields without a type', () => {
+        const result = validateTx({
+          ...stake,
+          maxFeePerGas: '0x6fc23ac00',
+          maxPriorityFeePerGas: '0x3b9aca00',
+        });
+
+        expect(result.isValid).toBe(true);
+      });
+
+      it('should reject fields that contradict the type', () => {
+        const cases: Array<[object, string]> = [
+          [
+            { gasPrice: '0x4a817c800', type: 2 },
+            'Type 2 transactions cannot carry gasPrice',
+          ],
+          [
+            { maxFeePerGas: '0x6fc23ac00', type: 0 },
+            'Type 0 transactions cannot carry EIP-1559 fee fields',
+          ],
+          [
+            { maxPriorityFeePerGas: '0x3b9aca00', type: 1 },
+            'Type 1 transactions cannot carry EIP-1559 fee fields',
+          ],
+          [
+            { gasPrice: '0x4a817c800', accessList, type: 0 },
+            'Type 0 transactions cannot carry an accessList',
+          ],
+          [
+            { gasPrice: '0x4a817c800', maxFeePerGas: '0x6fc23ac00' },
+            'Transaction mixes legacy gasPrice with EIP-1559 fee fields',
+          ],
+          [
+            { gasPrice: '0x4a817c800', accessList: [{ address: '0x1' }] },
+            'accessList must be a list of { address, storageKeys }',
+          ],
+          [
+            { maxFeePerGas: '0x6fc23ac00', type: 3 },
+            'Unsupported transaction type: 3',
+          ],
+          [
+            { maxFeePerGas: '0x6fc23ac00', type: 4 },
+            'Type 4 transactions must carry an authorizationList',
+          ],
+          [
+            { maxFeePerGas: '0x6fc23ac00', authorizationList: [], type: 4 },
+            'authorizationList must be a non-empty list of { chainId, address, nonce, yParity, r, s }',
+          ],
+          [
+            {
+              maxFeePerGas: '0x6fc23ac00',
+              authorizationList: [{ chainId: 1, address: lidoStEthAddress }],
+              type: 4,
+            },
+            'authorizationList must be a non-empty list of { chainId, address, nonce, yParity, r, s }',
+          ],
+        ];
+
+        for (const [fields, error] of cases) {
+          const result = validateTx({ ...stake, ...fields });
+
+          expect(result.isValid).toBe(false);
+          expect(result.reason).toBe('MALFORMED_TRANSACTION');
+          expect(result.details?.error).toBe(error);
+        }
+      });
     });
 
     it('should reject unsupported transaction type', () => {
@@ -835,7 +1362,7 @@ describe('LidoValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
+      expect(result.reason).toBe('CHAIN_ID_MISMATCH');
       // Previously: toContain('Lido only supported on Ethereum mainnet');
     });
 
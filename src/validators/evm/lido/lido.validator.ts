@@ -1,32 +1,82 @@
 import { ethers } from 'ethers';
 import {
   ActionArguments,
+  ClaimCall,
+  StakedBalanceCall,
+  ClaimedPositions,
   TransactionType,
   ValidationContext,
   ValidationResult,
+  ValidatorCapabilities,
+  Withdrawal,
 } from '../../../types';
+import { isNonEmptyString } from '../../../utils/validation';
 import { BaseEVMValidator, EVMTransaction } from '../base.validator';
 
 const LIDO_CONTRACTS = {
   stETH: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84',
+  wstETH: '0x7f39C581F595B53c5cb19bD0b3f8dA6c935E2Ca0',
   withdrawalQueue: '0x889edC2eDab5f40e902b864aD4d7AdE8E412F9B1',
 };
 
+// The Withdrawal Queue's per-request bounds, in stETH
+const MIN_STETH_WITHDRAWAL_AMOUNT = 100n;
+const MAX_STETH_WITHDRAWAL_AMOUNT = 1000n * 10n ** 18n;
+
+// Each request mints an NFT the withdrawal is later claimed with
+const WITHDRAWAL_REQUESTS: Record<string, { token: string; permit: boolean }> =
+  {
+    requestWithdrawals: { token: LIDO_CONTRACTS.stETH, permit: false },
+    requestWithdrawalsWithPermit: { token: LIDO_CONTRACTS.stETH, permit: true },
+    requestWithdrawalsWstETH: { token: LIDO_CONTRACTS.wstETH, permit: false },
+    requestWithdrawalsWstETHWithPermit: {
+      token: LIDO_CONTRACTS.wstETH,
+      permit: true,
+    },
+  };
+
+// Lido's Aragon roles guard these; the DAO pauses staking and withdrawals
+// through them, never a staker
+const LIDO_PRIVILEGED_FUNCTIONS: Record<string, string[]> = {
+  [LIDO_CONTRACTS.stETH.toLowerCase()]: [
+    'stop()',
+    'resume()',
+    'pauseStaking()',
+    'resumeStaking()',
+    'setStakingLimit(uint256,uint256)',
+    'removeStakingLimit()',
+  ],
+  [LIDO_CONTRACTS.withdrawalQueue.toLowerCase()]: [
+    'pauseFor(uint256)',
+    'pauseUntil(uint256)',
+    'resume()',
+  ],
+};
+
 const LIDO_REFERRAL = '0x371240E80Bf84eC2bA8b55aE2fD0B467b16Db2be';
 
 const LIDO_ABI = [
   'function submit(address _referral) payable returns (uint256)',
   'function requestWithdrawals(uint256[] _amounts, address _owner) returns (uint256[])',
+  'function requestWithdrawalsWithPermit(uint256[] _amounts, address _owner, (uint256 value, uint256 deadline, uint8 v, bytes32 r, bytes32 s) _permit) returns (uint256[])',
+  'function requestWithdrawalsWstETH(uint256[] _amounts, address _owner) returns (uint256[])',
+  'function requestWithdrawalsWstETHWithPermit(uint256[] _amounts, address _owner, (uint256 value, uint256 deadline, uint8 v, bytes32 r, bytes32 s) _permit) returns (uint256[])',
   'function claimWithdrawal(uint256 _requestId)',
   'function claimWithdrawals(uint256[] _requestIds, uint256[] _hints)',
+  'function claimWithdrawalsTo(uint256[] _requestIds, uint256[] _hints, address _recipient)',
 ];
 
+// stETH and wstETH, for balance checks
+const TOKEN_ABI = ['function balanceOf(address owner) view returns (uint256)'];
+
 export class LidoValidator extends BaseEVMValidator {
   private readonly lidoInterface: ethers.Interface;
+  private readonly tokenInterface: ethers.Interface;
 
   constructor() {
     super();
     this.lidoInterface = new ethers.Interface(LIDO_ABI);
+    this.tokenInterface = new ethers.Interface(TOKEN_ABI);
   }
 
   getSupportedTransactionTypes(): TransactionType[] {
@@ -37,6 +87,179 @@ export class LidoValidator extends BaseEVMValidator {
     ];
   }
 
+  getCapabilities(): ValidatorCapabilities {
+    return {
+      name: 'Lido',
+      protocol: 'lido',
+      network: 'ethereum',
+      supportsPartialAmounts: true,
+      chainId: '1',
+      contracts: [LIDO_CONTRACTS.stETH, LIDO_CONTRACTS.withdrawalQueue],
+    };
+  }
+
+  protected getDecodeInterfaces(): ethers.Interface[] {
+    return [this.lidoInterface];
+  }
+
+  protected getTransactionFunctions(): Partial<
+    Record<TransactionType, ethers.FunctionFragment[]>
+  > {
+    const fn = (name: string) => this.lidoInterface.getFunction(name)!;
+    return {
+      [TransactionType.STAKE]: [fn('submit')],
+      [TransactionType.UNSTAKE]: Object.keys(WITHDRAWAL_REQUESTS).map(fn),
+      [TransactionType.CLAIM_UNSTAKED]: [
+        fn('claimWithdrawal'),
+        fn('claimWithdrawals'),
+        fn('claimWithdrawalsTo'),
+      ],
+    };
+  }
+
+  // stETH's receive function submits what it is sent, without a referral
+  getStakeTokens(): string[] {
+    return ['native'];
+  }
+
+  getTransactionRecipient(
+    transactionType: TransactionType,
+  ): string | undefined {
+    return transactionType === TransactionType.STAKE
+      ? LIDO_CONTRACTS.stETH
+      : LIDO_CONTRACTS.withdrawalQueue;
+  }
+
+  getTransferRecipients(): string[] {
+    return [LIDO_CONTRACTS.stETH];
+  }
+
+  // The owner of a withdrawal request is who can later claim its ETH
+  getWithdrawal(unsignedTransaction: string): Withdrawal | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    if (
+      !tx ||
+      !isNonEmptyString(tx.to) ||
+      !this.isSameAddress(tx.to, LIDO_CONTRACTS.withdrawalQueue)
+    ) {
+      return undefined;
+    }
+
+    const parsed = this.tryParseTransaction(tx, this.lidoInterface);
+    const request = parsed ? WITHDRAWAL_REQUESTS[parsed.name] : undefined;
+    if (!parsed || !request) return undefined;
+
+    const [amounts, owner] = parsed.args;
+    return {
+      phase: 'REQUEST',
+      recipient: owner,
+      token: request.token,
+      amount: amounts
+        .reduce((total: bigint, amount: bigint) => total + amount, 0n)
+        .toString(),
+    };
+  }
+
+  // The queue pulls the stETH or wstETH of a request from its sender
+  getStakedBalanceCall(
+    unsignedTransaction: string,
+  ): StakedBalanceCall | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    const withdrawal = this.getWithdrawal(unsignedTransaction);
+    if (!withdrawal || !isNonEmptyString(tx?.from)) return undefined;
+
+    return {
+      call: {
+        to: withdrawal.token,
+        data: this.tokenInterface.encodeFunctionData('balanceOf', [tx.from]),
+      },
+      amount: withdrawal.amount,
+    };
+  }
+
+  // The queue is the ERC-721 of the withdrawal NFTs a claim redeems
+  getClaimedPositions(
+    unsignedTransaction: string,
+  ): ClaimedPositions | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    if (
+      !tx ||
+      !isNonEmptyString(tx.to) ||
+      !this.isSameAddress(tx.to, LIDO_CONTRACTS.withdrawalQueue)
+    ) {
+      return undefined;
+    }
+
+    const parsed = this.tryParseTransaction(tx, this.lidoInterface);
+    let requestIds: bigint[];
+    switch (parsed?.name) {
+      case 'claimWithdrawal':
+        requestIds = [parsed.args[0]];
+        break;
+      case 'claimWithdrawals':
+      case 'claimWithdrawalsTo':
+        requestIds = Array.from(parsed.args[0]);
+        break;
+      default:
+        return undefined;
+    }
+    return {
+      contract: LIDO_CONTRACTS.withdrawalQueue,
+      positionIds: requestIds.map((id) => id.toString()),
+    };
+  }
+
+  // claimWithdrawal and claimWithdrawals pay the ETH to msg.sender
+  getClaimCall(unsignedTransaction: string): ClaimCall | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    if (
+      !tx ||
+      !isNonEmptyString(tx.to) ||
+      !this.isSameAddress(tx.to, LIDO_CONTRACTS.withdrawalQueue)
+    ) {
+      return super.getClaimCall(unsignedTransaction);
+    }
+
+    const parsed = this.tryParseTransaction(tx, this.lidoInterface);
+    switch (parsed?.name) {
+      case 'claimWithdrawal':
+      case 'claimWithdrawals':
+        return { variant: parsed.signature, recipient: null };
+      case 'claimWithdrawalsTo':
+        return { variant: parsed.signature, recipient: parsed.args[2] };
+      default:
+        return super.getClaimCall(unsignedTransaction);
+    }
+  }
+
+  // Requests with a permit revert once the permit's deadline passes
+  getDeadline(unsignedTransaction: string): string | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    const parsed = tx ? this.tryParseTransaction(tx, this.lidoInterface) : null;
+    if (!parsed || !WITHDRAWAL_REQUESTS[parsed.name]?.permit) {
+      return super.getDeadline(unsignedTransaction);
+    }
+
+    const [, , permit] = parsed.args;
+    return BigInt(permit.deadline).toString();
+  }
+
+  // stETH and wstETH implement EIP-2612, letting withdrawals skip the
+  // approve step
+  protected getPermitSpenders(token: string): string[] {
+    return this.isSameAddress(token, LIDO_CONTRACTS.stETH) ||
+      this.isSameAddress(token, LIDO_CONTRACTS.wstETH)
+      ? [LIDO_CONTRACTS.withdrawalQueue]
+      : [];
+  }
+
+  protected getPrivilegedFunctions(contract: string): string[] {
+    return [
+      ...super.getPrivilegedFunctions(contract),
+      ...(LIDO_PRIVILEGED_FUNCTIONS[contract.toLowerCase()] ?? []),
+    ];
+  }
+
   validate(
     unsignedTransaction: string,
     transactionType: TransactionType,
@@ -69,7 +292,7 @@ export class LidoValidator extends BaseEVMValidator {
       case TransactionType.UNSTAKE:
         return this.validateUnstake(tx, userAddress);
       case TransactionType.CLAIM_UNSTAKED:
-        return this.validateClaim(tx);
+        return this.validateClaim(tx, userAddress);
       default:
         return this.blocked('Unsupported transaction type', {
           transactionType,
@@ -97,6 +320,14 @@ export class LidoValidator extends BaseEVMValidator {
       });
     }
 
+    // submit stakes the ETH sent with it
+    const value = BigInt(tx.value ?? '0');
+    if (value <= 0n) {
+      return this.blocked('Stake must send ETH value', {
+        value: value.toString(),
+      });
+    }
+
     const [referral] = parsed.args;
     if (referral.toLowerCase() !== LIDO_REFERRAL.toLowerCase()) {
       return this.blocked('Invalid referral address', {
@@ -131,14 +362,15 @@ export class LidoValidator extends BaseEVMValidator {
 
     const { parsed } = result;
 
-    if (parsed.name !== 'requestWithdrawals') {
+    const request = WITHDRAWAL_REQUESTS[parsed.name];
+    if (!request) {
       return this.blocked('Invalid method for unstaking', {
-        expected: 'requestWithdrawals',
+        expected: Object.keys(WITHDRAWAL_REQUESTS),
         actual: parsed.name,
       });
     }
 
-    const [amounts, owner] = parsed.args;
+    const [amounts, owner, permit] = parsed.args;
 
     if (owner.toLowerCase() !== userAddress.toLowerCase()) {
       return this.blocked('Withdrawal request owner is not user address', {
@@ -151,10 +383,46 @@ export class LidoValidator extends BaseEVMValidator {
       return this.blocked('Withdrawal amounts array is empty');
     }
 
+    // wstETH amounts are only bounded once converted to stETH, on-chain
+    const isStEth = request.token === LIDO_CONTRACTS.stETH;
+    for (const [index, value] of amounts.entries()) {
+      const amount = BigInt(value);
+      if (
+        amount <= 0n ||
+        (isStEth &&
+          (amount < MIN_STETH_WITHDRAWAL_AMOUNT ||
+            amount > MAX_STETH_WITHDRAWAL_AMOUNT))
+      ) {
+        return this.blocked('Withdrawal amount is out of bounds', {
+          index,
+          min: isStEth ? MIN_STETH_WITHDRAWAL_AMOUNT.toString() : '1',
+          max: isStEth ? MAX_STETH_WITHDRAWAL_AMOUNT.toString() : undefined,
+          actual: amount.toString(),
+        });
+      }
+    }
+
+    // The permit must cover what the request pulls
+    if (request.permit) {
+      const total = amounts.reduce(
+        (sum: bigint, amount: bigint) => sum + BigInt(amount),
+        0n,
+      );
+      if (BigInt(permit.value) < total) {
+        return this.blocked('Permit value is less than the amount withdrawn', {
+          expected: total.toString(),
+          actual: BigInt(permit.value).toString(),
+        });
+      }
+    }
+
     return this.safe();
   }
 
-  private validateClaim(tx: EVMTransaction): ValidationResult {
+  private validateClaim(
+    tx: EVMTransaction,
+    userAddress: string,
+  ): ValidationResult {
     if (tx.to?.toLowerCase() !== LIDO_CONTRACTS.withdrawalQueue.toLowerCase()) {
       return this.blocked('Transaction not to Lido Withdrawal Queue contract', {
         expected: LIDO_CONTRACTS.withdrawalQueue,
@@ -175,9 +443,12 @@ export class LidoValidator extends BaseEVMValidator {
     const { parsed } = result;
 
     if (parsed.name === 'claimWithdrawal') {
-      return this.safe();
-    } else if (parsed.name === 'claimWithdrawals') {
-      const [requestIds, hints] = parsed.args;
+      return this.claimed([parsed.args[0]]);
+    } else if (
+      parsed.name === 'claimWithdrawals' ||
+      parsed.name === 'claimWithdrawalsTo'
+    ) {
+      const [requestIds, hints, recipient] = parsed.args;
 
       if (requestIds.length === 0) {
         return this.blocked('Request IDs array is empty');
@@ -190,12 +461,30 @@ export class LidoValidator extends BaseEVMValidator {
         });
       }
 
-      return this.safe();
+      if (
+        parsed.name === 'claimWithdrawalsTo' &&
+        !this.isSameAddress(recipient, userAddress)
+      ) {
+        return this.blocked('Claim recipient is not user address', {
+          expected: userAddress,
+          actual: recipient,
+        });
+      }
+
+      return this.claimed(requestIds);
     } else {
       return this.blocked('Invalid method for claiming', {
-        expected: 'claimWithdrawal or claimWithdrawals',
+        expected: 'claimWithdrawal, claimWithdrawals or claimWithdrawalsTo',
         actual: parsed.name,
       });
     }
   }
+
+  // The withdrawal NFTs a valid claim redeems
+  private claimed(requestIds: bigint[]): ValidationResult {
+    return {
+      ...this.safe(),
+      decoded: { requestIds: requestIds.map((id) => id.toString()) },
+    };
+  }
 }
@@ -247,7 +247,7 @@ describe('RocketPoolValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
+      expect(result.reasonCode).toBe('UNEXPECTED_NATIVE_VALUE');
     });
 
     it('should reject stake with zero ETH value', () => {
@@ -324,7 +324,7 @@ describe('RocketPoolValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
+      expect(result.reason).toBe('SENDER_MISMATCH');
     });
 
     it('should reject stake on wrong network', () => {
@@ -347,7 +347,7 @@ describe('RocketPoolValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
+      expect(result.reason).toBe('CHAIN_ID_MISMATCH');
     });
 
     it('should reject stake with appended bytes', () => {
@@ -519,7 +519,7 @@ describe('RocketPoolValidator via Shield', () => {
       expect(result.isValid).toBe(true);
     });
 
-    it('should reject approval to wrong contract', () => {
+    it('should reject approving another token to LI.FI', () => {
       const tx = {
         to: '0x0000000000000000000000000000000000000001',
         from: userAddress,
@@ -539,13 +539,11 @@ describe('RocketPoolValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
-      const approvalAttempt = result.details?.attempts?.find(
-        (a: any) => a.type === TransactionType.APPROVAL,
-      );
-      expect(approvalAttempt?.reason).toContain(
-        'not to RocketPool rETH contract',
-      );
+      expect(result.reasonCode).toBe('TOKEN_MISMATCH');
+      expect(result.details).toMatchObject({
+        expected: ['native'],
+        actual: '0x0000000000000000000000000000000000000001',
+      });
     });
 
     it('should reject approval with wrong method', () => {
@@ -591,11 +589,7 @@ describe('RocketPoolValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
-      const approvalAttempt = result.details?.attempts?.find(
-        (a: any) => a.type === TransactionType.APPROVAL,
-      );
-      expect(approvalAttempt?.reason).toContain('should not send ETH value');
+      expect(result.reasonCode).toBe('UNEXPECTED_NATIVE_VALUE');
     });
 
     it('should reject approval with zero amount', () => {
@@ -653,7 +647,7 @@ describe('RocketPoolValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
+      expect(result.reason).toBe('SENDER_MISMATCH');
     });
 
     it('should reject approval on wrong network', () => {
@@ -676,7 +670,7 @@ describe('RocketPoolValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
+      expect(result.reason).toBe('CHAIN_ID_MISMATCH');
     });
 
     it('should reject approval with appended bytes', () => {
@@ -732,6 +726,42 @@ describe('RocketPoolValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.APPROVAL);
+      expect(result.decoded?.approval).toEqual({
+        token: rETHAddress,
+        spender: lifiSpender,
+        amount: maxUint256.toString(),
+        isUnlimited: true,
+      });
+      expect(result.warnings).toContainEqual(
+        expect.objectContaining({
+          code: 'INFINITE_APPROVAL',
+          details: expect.objectContaining({ spender: lifiSpender }),
+        }),
+      );
+    });
+
+    it('should not warn on a bounded approval amount', () => {
+      const boundedApproveCalldata = iface.encodeFunctionData('approve', [
+        lifiSpender,
+        1000000000000000000n,
+      ]);
+
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: JSON.stringify({
+          to: rETHAddress,
+          from: userAddress,
+          value: '0x0',
+          data: boundedApproveCalldata,
+          chainId: 1,
+        }),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.decoded?.approval?.isUnlimited).toBe(false);
+      expect(result.warnings).toEqual([]);
     });
 
     it('should reject approval with unknown spender', () => {
@@ -760,13 +790,8 @@ describe('RocketPoolValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
-      const approvalAttempt = result.details?.attempts?.find(
-        (a: any) => a.type === TransactionType.APPROVAL,
-      );
-      expect(approvalAttempt?.reason).toContain(
-        'Approval spender is not a known LI.FI contract',
-      );
+      expect(result.reason).toBe('APPROVAL_SPENDER_MISMATCH');
+      expect(result.details?.actual).toBe(randomSpender);
     });
 
     it('should accept approval with Permit2 Proxy as spender', () => {
@@ -944,6 +969,61 @@ describe('RocketPoolValidator via Shield', () => {
       expect(result.detectedType).toBe(TransactionType.SWAP);
     });
 
+    it('should report the deadline of a Permit2 Proxy call', () => {
+      const tx = {
+        to: LIFI_PERMIT2_PROXY,
+        from: userAddress,
+        value: '0x0',
+        data: permit2WrappedSwapCalldata,
+        nonce: 0,
+        gasLimit: '0x30d40',
+        gasPrice: '0x4a817c800',
+        chainId: 1,
+        type: 0,
+      };
+
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: JSON.stringify(tx),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.deadline?.timestamp).toBe('9999999999');
+      expect(result.warnings?.map((w) => w.code)).toContain('LONG_DEADLINE');
+    });
+
+    it('should reject a Permit2 Proxy call whose deadline has passed', () => {
+      const tx = {
+        to: LIFI_PERMIT2_PROXY,
+        from: userAddress,
+        value: '0x0',
+        data: permit2ProxyIface.encodeFunctionData('callDiamondWithPermit2', [
+          diamondSwapCalldata,
+          [[rETHAddress, 1000000000000000000n], 0n, 1000000000n],
+          dummySignature,
+        ]),
+        nonce: 0,
+        gasLimit: '0x30d40',
+        gasPrice: '0x4a817c800',
+        chainId: 1,
+        type: 0,
+      };
+
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: JSON.stringify(tx),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('DEADLINE_IN_PAST');
+      expect(result.deadline).toEqual({
+        timestamp: '1000000000',
+        iso: '2001-09-09T01:46:40.000Z',
+      });
+    });
+
     // --- Rejections ---
 
     it('should reject SWAP to unknown contract', () => {
@@ -995,11 +1075,8 @@ describe('RocketPoolValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
-      const swapAttempt = result.details?.attempts?.find(
-        (a: any) => a.type === TransactionType.SWAP,
-      );
-      expect(swapAttempt?.reason).toContain('SWAP transaction has no calldata');
+      expect(result.reasonCode).toBe('NAKED_TRANSFER_NOT_SUPPORTED');
+      expect(result.emptyCalldata).toBe(true);
     });
 
     it('should reject SWAP with unknown Diamond function selector', () => {
@@ -1108,7 +1185,7 @@ describe('RocketPoolValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
+      expect(result.reason).toBe('SENDER_MISMATCH');
     });
 
     it('should reject SWAP on wrong network', () => {
@@ -1131,7 +1208,7 @@ describe('RocketPoolValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
+      expect(result.reason).toBe('CHAIN_ID_MISMATCH');
     });
 
     // --- Permit2 Proxy-specific rejections ---
@@ -1390,6 +1467,157 @@ describe('RocketPoolValidator via Shield', () => {
     });
   });
 
+  describe('Slippage protection', () => {
+    const stakeTx = (data = stakeCalldata) =>
+      JSON.stringify({
+        to: rocketSwapRouterAddress,
+        from: userAddress,
+        value: '0xde0b6b3a7640000',
+        data,
+        nonce: 0,
+        gasLimit: '0x30d40',
+        gasPrice: '0x4a817c800',
+        chainId: 1,
+        type: 0,
+      });
+
+    const swapTx = (to: string, data: string) =>
+      JSON.stringify({
+        to,
+        from: userAddress,
+        value: '0x0',
+        data,
+        nonce: 0,
+        gasLimit: '0x30d40',
+        gasPrice: '0x4a817c800',
+        chainId: 1,
+        type: 0,
+      });
+
+    const slippageWarning = (result: ReturnType<typeof shield.validate>) =>
+      result.warnings?.find((w) => w.code === 'LOW_SLIPPAGE_PROTECTION');
+
+    it('should report what swapTo sends and accepts', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: stakeTx(),
+        userAddress,
+      });
+
+      expect(result.decoded?.slippage).toEqual({
+        inputToken: 'native',
+        inputAmount: '1000000000000000000',
+        outputToken: rETHAddress,
+        minAmountOut: '900000000000000000',
+        expectedAmountOut: '950000000000000000',
+      });
+    });
+
+    it('should warn when swapTo accepts more than 5% below its ideal output', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: stakeTx(),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(slippageWarning(result)?.details).toEqual({
+        inputAmount: '1000000000000000000',
+        minAmountOut: '900000000000000000',
+        expectedAmountOut: '950000000000000000',
+        maxSlippageBps: 500,
+      });
+    });
+
+    it('should not warn within the policy maxSlippageBps', () => {
+      const tight = iface.encodeFunctionData('swapTo', [
+        5000n,
+        5000n,
+        945000000000000000n,
+        950000000000000000n,
+      ]);
+
+      expect(
+        slippageWarning(
+          shield.validate({
+            yieldId,
+            unsignedTransaction: stakeTx(tight),
+            userAddress,
+          }),
+        ),
+      ).toBeUndefined();
+      expect(
+        slippageWarning(
+          shield.validate({
+            yieldId,
+            unsignedTransaction: stakeTx(),
+            userAddress,
+            policy: { maxSlippageBps: 600 },
+          }),
+        ),
+      ).toBeUndefined();
+    });
+
+    it('should reject low slippage protection in strict mode', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: stakeTx(),
+        userAddress,
+        strict: true,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('STRICT_MODE_WARNING');
+      expect(result.details?.warningCodes).toEqual(['LOW_SLIPPAGE_PROTECTION']);
+    });
+
+    it('should report the minimum output of a LI.FI swap', () => {
+      for (const [to, data] of [
+        [LIFI_DIAMOND, diamondSwapCalldata],
+        [LIFI_PERMIT2_PROXY, permit2WrappedSwapCalldata],
+      ]) {
+        const result = shield.validate({
+          yieldId,
+          unsignedTransaction: swapTx(to, data),
+          userAddress,
+        });
+
+        expect(result.decoded?.slippage).toEqual({
+          inputToken: rETHAddress,
+          inputAmount: '1000000000000000000',
+          outputToken: '0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2',
+          minAmountOut: '900000000000000000',
+        });
+        expect(slippageWarning(result)).toBeUndefined();
+      }
+    });
+
+    it('should warn when a LI.FI swap accepts any output', () => {
+      const anyOutput = lifiSwapIface.encodeFunctionData(
+        'swapTokensSingleV3ERC20ToNative',
+        [
+          ethers.zeroPadValue('0x01', 32),
+          'stakekit',
+          '',
+          userAddress,
+          0n,
+          sampleSwapDataTuple,
+        ],
+      );
+
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: swapTx(LIFI_DIAMOND, anyOutput),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(slippageWarning(result)?.message).toBe(
+        'Swap accepts any output, however small',
+      );
+    });
+  });
+
   describe('General validation', () => {
     it('should reject transaction from wrong user', () => {
       const wrongUser = '0x0000000000000000000000000000000000000001';
@@ -1412,7 +1640,7 @@ describe('RocketPoolValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
+      expect(result.reason).toBe('SENDER_MISMATCH');
     });
 
     it('should reject transaction on wrong network', () => {
@@ -1435,7 +1663,7 @@ describe('RocketPoolValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
+      expect(result.reason).toBe('CHAIN_ID_MISMATCH');
     });
 
     it('should reject malformed transaction data', () => {
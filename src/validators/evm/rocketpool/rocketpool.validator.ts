@@ -1,9 +1,12 @@
 import { ethers } from 'ethers';
 import {
   ActionArguments,
+  SwapSlippage,
+  TokenSpend,
   TransactionType,
   ValidationContext,
   ValidationResult,
+  ValidatorCapabilities,
 } from '../../../types';
 import { BaseEVMValidator, EVMTransaction } from '../base.validator';
 
@@ -57,6 +60,152 @@ export class RocketPoolValidator extends BaseEVMValidator {
     ];
   }
 
+  getCapabilities(): ValidatorCapabilities {
+    return {
+      name: 'Rocket Pool',
+      protocol: 'rocketpool',
+      network: 'ethereum',
+      supportsPartialAmounts: true,
+      chainId: '1',
+      contracts: [
+        ROCKETPOOL_CONTRACTS.rETH,
+        ROCKETPOOL_CONTRACTS.rocketSwapRouter,
+        ...LIFI_CONTRACTS,
+      ],
+    };
+  }
+
+  // swapTo stakes the ETH it is sent
+  getStakeTokens(): string[] {
+    return ['native'];
+  }
+
+  // Swaps go to either LI.FI contract
+  getTransactionRecipient(
+    transactionType: TransactionType,
+  ): string | undefined {
+    switch (transactionType) {
+      case TransactionType.STAKE:
+        return ROCKETPOOL_CONTRACTS.rocketSwapRouter;
+      case TransactionType.APPROVAL:
+        return ROCKETPOOL_CONTRACTS.rETH;
+      default:
+        return undefined;
+    }
+  }
+
+  // rETH is only ever approved to LI.FI, for swaps
+  getExpectedSpenders(_unsignedTransaction: string): string[] {
+    return Array.from(LIFI_CONTRACTS);
+  }
+
+  protected getDecodeInterfaces(): ethers.Interface[] {
+    return [this.rocketPoolInterface, this.permit2ProxyInterface];
+  }
+
+  // Swaps go to the LI.FI Diamond directly or through the Permit2 Proxy
+  protected getTransactionFunctions(): Partial<
+    Record<TransactionType, ethers.FunctionFragment[]>
+  > {
+    const functions = (iface: ethers.Interface) =>
+      iface.fragments.filter(
+        (fragment): fragment is ethers.FunctionFragment =>
+          fragment.type === 'function',
+      );
+    return {
+      [TransactionType.STAKE]: [
+        this.rocketPoolInterface.getFunction('swapTo')!,
+      ],
+      [TransactionType.APPROVAL]: [
+        this.rocketPoolInterface.getFunction('approve')!,
+      ],
+      [TransactionType.SWAP]: [
+        ...functions(this.lifiSwapInterface),
+        ...functions(this.permit2ProxyInterface),
+      ],
+    };
+  }
+
+  // The Diamond pulls the first hop's fromAmount under the user's allowance.
+  // Permit2 Proxy calls carry their own signature, so they are not reported
+  getTokenSpend(unsignedTransaction: string): TokenSpend | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    if (!tx?.to || tx.to.toLowerCase() !== LIFI_DIAMOND) return undefined;
+
+    const parsed = this.tryParseTransaction(tx, this.lifiSwapInterface);
+    if (!parsed) return undefined;
+
+    const swapData = parsed.args[5];
+    const firstHop = parsed.name.startsWith('swapTokensMultiple')
+      ? swapData[0]
+      : swapData;
+    if (!firstHop) return undefined;
+
+    return {
+      token: firstHop.sendingAssetId,
+      spender: tx.to,
+      amount: BigInt(firstHop.fromAmount).toString(),
+    };
+  }
+
+  // swapTo is quoted at _idealTokensOut of rETH for the ETH it sends. LI.FI
+  // swaps name only their minimum, of the last hop's token
+  getSlippage(unsignedTransaction: string): SwapSlippage | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    if (!tx?.to || !tx.data) return undefined;
+
+    const swapTo = this.tryParseTransaction(tx, this.rocketPoolInterface);
+    if (swapTo?.name === 'swapTo') {
+      return {
+        inputToken: 'native',
+        inputAmount: BigInt(tx.value ?? '0').toString(),
+        outputToken: ROCKETPOOL_CONTRACTS.rETH,
+        minAmountOut: BigInt(swapTo.args[2]).toString(),
+        expectedAmountOut: BigInt(swapTo.args[3]).toString(),
+      };
+    }
+
+    if (!LIFI_CONTRACTS.has(tx.to.toLowerCase())) return undefined;
+    const calldata = this.extractDiamondCalldata(tx);
+    const parsed = calldata
+      ? this.tryParseTransaction(
+          { ...tx, data: calldata },
+          this.lifiSwapInterface,
+        )
+      : null;
+    if (!parsed) return undefined;
+
+    const swapData = parsed.args[5];
+    const hops = parsed.name.startsWith('swapTokensMultiple')
+      ? Array.from(swapData)
+      : [swapData];
+    if (hops.length === 0) return undefined;
+
+    const [firstHop, lastHop] = [hops[0], hops[hops.length - 1]];
+    return {
+      inputToken: firstHop.sendingAssetId,
+      inputAmount: BigInt(firstHop.fromAmount).toString(),
+      outputToken: lastHop.receivingAssetId,
+      minAmountOut: BigInt(parsed.args[4]).toString(),
+    };
+  }
+
+  // Permit2 Proxy calls revert once their permit's deadline passes
+  getDeadline(unsignedTransaction: string): string | undefined {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    const parsed = tx
+      ? this.tryParseTransaction(tx, this.permit2ProxyInterface)
+      : null;
+    if (!parsed) return super.getDeadline(unsignedTransaction);
+
+    // The permit is (permitted, nonce, deadline), just before the signature
+    const deadline =
+      parsed.name === 'callDiamondWithEIP2612Signature'
+        ? parsed.args[2]
+        : parsed.args[parsed.args.length - 2][2];
+    return BigInt(deadline).toString();
+  }
+
   validate(
     unsignedTransaction: string,
     transactionType: TransactionType,
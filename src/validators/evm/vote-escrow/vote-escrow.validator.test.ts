@@ -0,0 +1,223 @@
+import { ethers } from 'ethers';
+import { Shield } from '../../../shield';
+import { TransactionType } from '../../../types';
+
+describe('VoteEscrowValidator via Shield', () => {
+  const shield = new Shield();
+  const yieldId = 'ethereum-crv-vecrv-staking';
+  const userAddress = '0x742d35cc6634c0532925a3b844bc9e7595f0beb8';
+  const otherAddress = '0x0000000000000000000000000000000000000bad';
+
+  const escrow = '0x5f3b5DfEb7B28CDbD7FAba78963EE202a494e2A2';
+  const crv = '0xD533a949740bb3306d119CC777fa900bA034cd52';
+
+  const day = 24 * 60 * 60;
+  const week = 7 * day;
+  const maxLock = 4 * 365 * day;
+  const now = () => Math.floor(Date.now() / 1000);
+
+  const iface = new ethers.Interface([
+    'function approve(address spender, uint256 amount) returns (bool)',
+    'function create_lock(uint256 _value, uint256 _unlock_time)',
+    'function increase_amount(uint256 _value)',
+    'function increase_unlock_time(uint256 _unlock_time)',
+    'function withdraw()',
+  ]);
+
+  const tx = (to: string, data: string, value = '0x0') =>
+    JSON.stringify({
+      to,
+      from: userAddress,
+      value,
+      data,
+      nonce: 0,
+      gasLimit: '0x493e0',
+      gasPrice: '0x4a817c800',
+      chainId: 1,
+      type: 0,
+    });
+
+  const createLock = (unlockTime: number, amount = ethers.parseEther('100')) =>
+    tx(escrow, iface.encodeFunctionData('create_lock', [amount, unlockTime]));
+
+  const validate = (unsignedTransaction: string) =>
+    shield.validate({ yieldId, unsignedTransaction, userAddress });
+
+  it('should support the veCRV yield', () => {
+    expect(shield.isSupported(yieldId)).toBe(true);
+    expect(shield.getYieldCapabilities(yieldId)?.supportedTypes).toEqual([
+      TransactionType.APPROVAL,
+      TransactionType.LOCK,
+      TransactionType.UNLOCK,
+    ]);
+  });
+
+  describe('APPROVAL', () => {
+    it('should validate approving CRV to the VotingEscrow', () => {
+      const result = validate(
+        tx(
+          crv,
+          iface.encodeFunctionData('approve', [
+            escrow,
+            ethers.parseEther('100'),
+          ]),
+        ),
+      );
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.APPROVAL);
+    });
+
+    it('should reject approving another spender', () => {
+      const result = validate(
+        tx(
+          crv,
+          iface.encodeFunctionData('approve', [
+            otherAddress,
+            ethers.parseEther('100'),
+          ]),
+        ),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('APPROVAL_SPENDER_MISMATCH');
+    });
+
+    it('should reject approving a token other than CRV to the escrow', () => {
+      const result = validate(
+        tx(
+          otherAddress,
+          iface.encodeFunctionData('approve', [
+            escrow,
+            ethers.parseEther('100'),
+          ]),
+        ),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('TOKEN_MISMATCH');
+      expect(result.details).toMatchObject({
+        expected: [crv],
+        actual: otherAddress,
+      });
+    });
+  });
+
+  describe('LOCK', () => {
+    it('should validate a year-long lock and report its unlock time', () => {
+      const unlockTime = now() + 365 * day;
+      const rounded = Math.floor(unlockTime / week) * week;
+
+      const result = validate(createLock(unlockTime));
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.LOCK);
+      expect(result.amount).toEqual({
+        token: crv,
+        amount: ethers.parseEther('100').toString(),
+        symbol: 'CRV',
+        decimals: 18,
+        normalized: '100.0',
+      });
+      expect(result.lock).toEqual({
+        unlockTime: String(rounded),
+        unlockTimeIso: new Date(rounded * 1000).toISOString(),
+        durationSeconds: expect.any(Number),
+        minLockSeconds: 1,
+        maxLockSeconds: maxLock,
+      });
+      expect(result.lock!.durationSeconds).toBeGreaterThan(358 * day);
+      expect(result.warnings ?? []).toEqual([]);
+    });
+
+    it('should reject an unlock time that has passed', () => {
+      const result = validate(createLock(now() - 2 * week));
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('LOCK_DURATION_OUT_OF_RANGE');
+      expect(result.details).toMatchObject({
+        yieldId,
+        minLockSeconds: 1,
+        maxLockSeconds: maxLock,
+      });
+      expect(result.lock!.durationSeconds).toBeLessThan(0);
+    });
+
+    it('should reject an unlock time beyond four years', () => {
+      const result = validate(
+        tx(
+          escrow,
+          iface.encodeFunctionData('increase_unlock_time', [
+            now() + maxLock + 2 * week,
+          ]),
+        ),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('LOCK_DURATION_OUT_OF_RANGE');
+    });
+
+    it('should warn about a lock as long as the escrow allows', () => {
+      const result = validate(createLock(now() + maxLock));
+
+      expect(result.isValid).toBe(true);
+      expect(result.warnings?.map((warning) => warning.code)).toContain(
+        'LOCK_MAXED',
+      );
+    });
+
+    it('should validate adding to a lock, which sets no unlock time', () => {
+      const result = validate(
+        tx(
+          escrow,
+          iface.encodeFunctionData('increase_amount', [
+            ethers.parseEther('10'),
+          ]),
+        ),
+      );
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.LOCK);
+      expect(result.lock).toBeUndefined();
+    });
+
+    it('should reject a lock of nothing', () => {
+      const result = validate(createLock(now() + 365 * day, 0n));
+
+      expect(result.isValid).toBe(false);
+    });
+
+    it('should reject locks sent to another contract or with ETH', () => {
+      const data = iface.encodeFunctionData('create_lock', [
+        ethers.parseEther('100'),
+        now() + 365 * day,
+      ]);
+
+      expect(validate(tx(otherAddress, data)).isValid).toBe(false);
+      expect(validate(tx(escrow, data, '0x1')).isValid).toBe(false);
+    });
+  });
+
+  describe('UNLOCK', () => {
+    it('should validate withdrawing an expired lock', () => {
+      const result = validate(
+        tx(escrow, iface.encodeFunctionData('withdraw')),
+      );
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.UNLOCK);
+    });
+  });
+
+  it('should explain the lock-duration check', () => {
+    const { trace } = shield.explain({
+      yieldId,
+      unsignedTransaction: createLock(now() - 2 * week),
+      userAddress,
+    });
+
+    expect(trace.find((entry) => entry.check === 'lock-duration')).toEqual(
+      expect.objectContaining({ status: 'fail' }),
+    );
+  });
+});
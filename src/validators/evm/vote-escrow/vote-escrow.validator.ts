@@ -0,0 +1,302 @@
+import { ethers } from 'ethers';
+import {
+  ActionArguments,
+  LockTerms,
+  TokenSpend,
+  TransactionType,
+  ValidationContext,
+  ValidationResult,
+  ValidatorCapabilities,
+} from '../../../types';
+import { isNonEmptyString } from '../../../utils/validation';
+import { AssetInfo } from '../../../utils/amount';
+import { BaseEVMValidator, EVMTransaction } from '../base.validator';
+
+// Curve's VotingEscrow, which the ve-token forks of other protocols share
+const VOTING_ESCROW_ABI = [
+  'function create_lock(uint256 _value, uint256 _unlock_time)',
+  'function increase_amount(uint256 _value)',
+  'function increase_unlock_time(uint256 _unlock_time)',
+  'function withdraw()',
+];
+
+const TOKEN_ABI = [
+  'function approve(address spender, uint256 amount) returns (bool)',
+];
+
+export interface VoteEscrowConfig extends AssetInfo {
+  name: string;
+  protocol: string;
+  escrow: string; // The VotingEscrow contract
+  token: string; // The token it locks, e.g. CRV
+  // Lock durations the yield allows, from when the transaction is
+  // validated to its unlock time
+  minLockSeconds: number;
+  maxLockSeconds: number;
+  // The escrow rounds unlock times down to a multiple of this, e.g. a week
+  lockPeriodSeconds: number;
+}
+
+/**
+ * Vote-escrow locks through a Curve-style VotingEscrow on Ethereum
+ *
+ * Transaction Types Validated:
+ * - APPROVAL: approve the yield's token to the escrow
+ * - LOCK: create_lock, increase_amount or increase_unlock_time
+ * - UNLOCK: withdraw an expired lock
+ *
+ * A lock always belongs to the transaction's sender, who must be the user.
+ * Unlock times are checked against the yield's lock durations by Shield,
+ * through getLock.
+ */
+export class VoteEscrowValidator extends BaseEVMValidator {
+  private readonly escrowInterface: ethers.Interface;
+  private readonly tokenInterface: ethers.Interface;
+
+  constructor(private readonly config: VoteEscrowConfig) {
+    super();
+    this.escrowInterface = new ethers.Interface(VOTING_ESCROW_ABI);
+    this.tokenInterface = new ethers.Interface(TOKEN_ABI);
+  }
+
+  getSupportedTransactionTypes(): TransactionType[] {
+    return [
+      TransactionType.APPROVAL,
+      TransactionType.LOCK,
+      TransactionType.UNLOCK,
+    ];
+  }
+
+  getCapabilities(): ValidatorCapabilities {
+    return {
+      name: this.config.name,
+      protocol: this.config.protocol,
+      network: 'ethereum',
+      supportsPartialAmounts: true,
+      chainId: '1',
+      contracts: [this.config.escrow, this.config.token],
+    };
+  }
+
+  getExpectedSpenders(_unsignedTransaction: string): string[] {
+    return [this.config.escrow];
+  }
+
+  getStakeTokens(): string[] {
+    return [this.config.token];
+  }
+
+  getTransactionRecipient(
+    transactionType: TransactionType,
+  ): string | undefined {
+    return transactionType === TransactionType.APPROVAL
+      ? this.config.token
+      : this.config.escrow;
+  }
+
+  protected getDecodeInterfaces(): ethers.Interface[] {
+    return [this.escrowInterface, this.tokenInterface];
+  }
+
+  protected getTransactionFunctions(): Partial<
+    Record<TransactionType, ethers.FunctionFragment[]>
+  > {
+    const escrow = (name: string) => this.escrowInterface.getFunction(name)!;
+    return {
+      [TransactionType.APPROVAL]: [
+        this.tokenInterface.getFunction('approve')!,
+      ],
+      [TransactionType.LOCK]: [
+        escrow('create_lock'),
+        escrow('increase_amount'),
+        escrow('increase_unlock_time'),
+      ],
+      [TransactionType.UNLOCK]: [escrow('withdraw')],
+    };
+  }
+
+  protected getTokenInfo(
+    chainId: number,
+    token: string,
+  ): AssetInfo | undefined {
+    if (chainId !== 1 || !this.isSameAddress(token, this.config.token)) {
+      return undefined;
+    }
+    return { symbol: this.config.symbol, decimals: this.config.decimals };
+  }
+
+  // The escrow pulls what it locks under the user's allowance
+  getTokenSpend(unsignedTransaction: string): TokenSpend | undefined {
+    const parsed = this.parseEscrowCall(unsignedTransaction);
+    if (parsed?.name !== 'create_lock' && parsed?.name !== 'increase_amount') {
+      return undefined;
+    }
+
+    return {
+      token: this.config.token,
+      spender: this.config.escrow,
+      amount: BigInt(parsed.args[0]).toString(),
+    };
+  }
+
+  // increase_amount adds to a lock without moving its unlock time
+  getLock(unsignedTransaction: string): LockTerms | undefined {
+    const parsed = this.parseEscrowCall(unsignedTransaction);
+    const unlockTime =
+      parsed?.name === 'create_lock'
+        ? parsed.args[1]
+        : parsed?.name === 'increase_unlock_time'
+          ? parsed.args[0]
+          : undefined;
+    if (unlockTime === undefined) return undefined;
+
+    const { minLockSeconds, maxLockSeconds, lockPeriodSeconds } = this.config;
+    const period = BigInt(lockPeriodSeconds);
+    return {
+      unlockTime: ((BigInt(unlockTime) / period) * period).toString(),
+      minLockSeconds,
+      maxLockSeconds,
+      lockPeriodSeconds,
+    };
+  }
+
+  validate(
+    unsignedTransaction: string,
+    transactionType: TransactionType,
+    userAddress: string,
+    _args?: ActionArguments,
+    _context?: ValidationContext,
+  ): ValidationResult {
+    const decoded = this.decodeEVMTransaction(unsignedTransaction);
+    if (!decoded.isValid || !decoded.transaction) {
+      return this.blocked('Failed to decode EVM transaction', {
+        error: decoded.error,
+      });
+    }
+
+    const tx = decoded.transaction;
+
+    const fromErr = this.ensureTransactionFromIsUser(tx, userAddress);
+    if (fromErr) return fromErr;
+
+    const chainErr = this.ensureChainIdEquals(
+      tx,
+      1,
+      `${this.config.name} only supported on Ethereum mainnet`,
+    );
+    if (chainErr) return chainErr;
+
+    const value = BigInt(tx.value ?? '0');
+    if (value > 0n) {
+      return this.blocked('Vote-escrow transactions should not send ETH', {
+        value: value.toString(),
+      });
+    }
+
+    switch (transactionType) {
+      case TransactionType.APPROVAL:
+        return this.validateApproval(tx);
+      case TransactionType.LOCK:
+        return this.validateLock(tx);
+      case TransactionType.UNLOCK:
+        return this.validateUnlock(tx);
+      default:
+        return this.blocked('Unsupported transaction type', {
+          transactionType,
+        });
+    }
+  }
+
+  private validateApproval(tx: EVMTransaction): ValidationResult {
+    if (
+      !isNonEmptyString(tx.to) ||
+      !this.isSameAddress(tx.to, this.config.token)
+    ) {
+      return this.blocked('Approval is not for the token of the yield', {
+        expected: this.config.token,
+        actual: tx.to,
+      });
+    }
+
+    const result = this.parseAndValidateCalldata(tx, this.tokenInterface);
+    if ('error' in result) return result.error;
+
+    const { parsed } = result;
+    if (parsed.name !== 'approve') {
+      return this.blocked('Invalid method for approval', {
+        expected: 'approve',
+        actual: parsed.name,
+      });
+    }
+
+    const [, amount] = parsed.args;
+    if (BigInt(amount) <= 0n) {
+      return this.blocked('Approval amount must be greater than zero');
+    }
+
+    return this.safe();
+  }
+
+  private validateLock(tx: EVMTransaction): ValidationResult {
+    const toErr = this.ensureToEscrow(tx);
+    if (toErr) return toErr;
+
+    const result = this.parseAndValidateCalldata(tx, this.escrowInterface);
+    if ('error' in result) return result.error;
+
+    const { parsed } = result;
+    const methods = ['create_lock', 'increase_amount', 'increase_unlock_time'];
+    if (!methods.includes(parsed.name)) {
+      return this.blocked('Invalid method for locking', {
+        expected: methods,
+        actual: parsed.name,
+      });
+    }
+
+    if (
+      parsed.name !== 'increase_unlock_time' &&
+      BigInt(parsed.args[0]) <= 0n
+    ) {
+      return this.blocked('Lock amount must be greater than zero');
+    }
+
+    return this.safe();
+  }
+
+  private validateUnlock(tx: EVMTransaction): ValidationResult {
+    const toErr = this.ensureToEscrow(tx);
+    if (toErr) return toErr;
+
+    const result = this.parseAndValidateCalldata(tx, this.escrowInterface);
+    if ('error' in result) return result.error;
+
+    const { parsed } = result;
+    if (parsed.name !== 'withdraw') {
+      return this.blocked('Invalid method for unlocking', {
+        expected: 'withdraw',
+        actual: parsed.name,
+      });
+    }
+
+    return this.safe();
+  }
+
+  private ensureToEscrow(tx: EVMTransaction): ValidationResult | null {
+    const { escrow } = this.config;
+    if (isNonEmptyString(tx.to) && this.isSameAddress(tx.to, escrow)) {
+      return null;
+    }
+    return this.blocked('Transaction not to the VotingEscrow contract', {
+      expected: escrow,
+      actual: tx.to,
+    });
+  }
+
+  private parseEscrowCall(
+    unsignedTransaction: string,
+  ): ethers.TransactionDescription | null {
+    const { transaction: tx } = this.decodeEVMTransaction(unsignedTransaction);
+    if (!tx?.to || !this.isSameAddress(tx.to, this.config.escrow)) return null;
+    return this.tryParseTransaction(tx, this.escrowInterface);
+  }
+}
@@ -1,8 +1,27 @@
 import { BaseValidator } from './base.validator';
-import { SolanaNativeStakingValidator } from './solana';
-import { LidoValidator, RocketPoolValidator } from './evm';
+import {
+  JitoValidator,
+  MarinadeValidator,
+  SolanaNativeStakingValidator,
+} from './solana';
+import {
+  EigenLayerValidator,
+  LidoValidator,
+  RocketPoolValidator,
+  VoteEscrowValidator,
+} from './evm';
 import { TronValidator } from './tron';
-import { ERC4626Validator, loadEmbeddedRegistry } from './evm/erc4626';
+import { CosmosStakingValidator } from './cosmos';
+import { NearStakingValidator } from './near';
+import { SubstrateStakingValidator } from './substrate';
+import { AptosStakingValidator } from './aptos';
+import { BabylonStakingParams, BabylonStakingValidator } from './bitcoin';
+import {
+  ERC4626Validator,
+  loadEmbeddedRegistry,
+  loadRegistryOverride,
+  VaultRegistryOverride,
+} from './evm/erc4626';
 
 export { BaseEVMValidator, type EVMTransaction } from './evm';
 
@@ -11,9 +30,73 @@ const registry = new Map<string, BaseValidator>([
     'solana-sol-native-multivalidator-staking',
     new SolanaNativeStakingValidator(),
   ],
+  ['solana-sol-marinade-liquid-staking', new MarinadeValidator()],
+  ['solana-sol-jito-liquid-staking', new JitoValidator()],
   ['ethereum-eth-lido-staking', new LidoValidator()],
   ['tron-trx-native-staking', new TronValidator()],
   ['ethereum-eth-reth-staking', new RocketPoolValidator()],
+  [
+    'ethereum-steth-eigenlayer-restaking',
+    new EigenLayerValidator({
+      strategies: [
+        {
+          address: '0x93c4b944D05dfe6df7645A86cd2206016c51564D',
+          token: '0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84', // stETH
+          symbol: 'stETH',
+          decimals: 18,
+        },
+      ],
+      operators: [],
+    }),
+  ],
+  [
+    'cosmos-atom-native-staking',
+    new CosmosStakingValidator({
+      chainId: 'cosmoshub-4',
+      name: 'Cosmos Hub',
+      network: 'cosmos',
+      denom: 'uatom',
+      symbol: 'ATOM',
+      decimals: 6,
+      bech32Prefix: 'cosmos',
+    }),
+  ],
+  [
+    'ethereum-crv-vecrv-staking',
+    new VoteEscrowValidator({
+      name: 'Curve veCRV',
+      protocol: 'curve',
+      escrow: '0x5f3b5DfEb7B28CDbD7FAba78963EE202a494e2A2',
+      token: '0xD533a949740bb3306d119CC777fa900bA034cd52', // CRV
+      symbol: 'CRV',
+      decimals: 18,
+      // VotingEscrow takes any unlock time from the next week to 4 years
+      minLockSeconds: 1,
+      maxLockSeconds: 4 * 365 * 86400,
+      lockPeriodSeconds: 7 * 86400,
+    }),
+  ],
+  ['near-near-native-staking', new NearStakingValidator()],
+  [
+    'dot-dot-native-staking',
+    new SubstrateStakingValidator({
+      chainId: 'polkadot',
+      name: 'Polkadot',
+      network: 'polkadot',
+      genesisHash:
+        '0x91b171bb158e2d3848fa23a9f1c25182fb8e20313b2c1eb49219da7a70ce90c3',
+      ss58Prefix: 0,
+      symbol: 'DOT',
+      decimals: 10,
+      maxNominations: 16,
+      runtime: {
+        stakingPallet: 7,
+        utilityPallet: 26,
+        signedExtra: ['era', 'nonce', 'tip', 'metadataHashMode'],
+      },
+    }),
+  ],
+  ['aptos-apt-native-staking', new AptosStakingValidator()],
 ]);
 
 export const GENERIC_ERC4626_PROTOCOLS = new Set([
@@ -43,3 +126,38 @@ for (const vault of erc4626Config.vaults) {
 }
 
 export const validatorRegistry: ReadonlyMap<string, BaseValidator> = registry;
+
+/**
+ * The registry with the vaults of override registered over it. Every vault
+ * is validated as a generic ERC4626 vault, whatever its protocol, and
+ * replaces any yield of the same yieldId; later vaults win.
+ */
+export function withRegistryOverride(
+  override: VaultRegistryOverride,
+): ReadonlyMap<string, BaseValidator> {
+  const merged = new Map(registry);
+  const { vaults, lastUpdated } = loadRegistryOverride(override);
+  for (const vault of vaults) {
+    merged.set(
+      vault.yieldId,
+      new ERC4626Validator({ vaults: [vault], lastUpdated }),
+    );
+  }
+  return merged;
+}
+
+export const BABYLON_STAKING_YIELD_ID = 'bitcoin-btc-babylon-staking';
+
+/**
+ * validators with the Babylon staking yield registered for params. Babylon
+ * versions its covenant and limits by Bitcoin height, so the yield is only
+ * supported once the caller provides the version in effect.
+ */
+export function withBabylonStaking(
+  validators: ReadonlyMap<string, BaseValidator>,
+  params: BabylonStakingParams,
+): ReadonlyMap<string, BaseValidator> {
+  const merged = new Map(validators);
+  merged.set(BABYLON_STAKING_YIELD_ID, new BabylonStakingValidator(params));
+  return merged;
+}
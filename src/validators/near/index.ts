@@ -0,0 +1 @@
+export { NearStakingValidator } from './native-staking/native-staking.validator';
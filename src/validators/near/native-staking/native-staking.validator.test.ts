@@ -0,0 +1,283 @@
+import { Shield } from '../../../shield';
+import { TransactionType } from '../../../types';
+
+describe('NearStakingValidator via Shield', () => {
+  const shield = new Shield();
+  const yieldId = 'near-near-native-staking';
+  const userAddress = 'alice.near';
+  const pool = 'figment.poolv1.near';
+
+  const functionCall = (
+    methodName: string,
+    args: Record<string, unknown> = {},
+    deposit = '0',
+  ) => ({
+    functionCall: { methodName, args, gas: '50000000000000', deposit },
+  });
+
+  const nearTx = (
+    actions: unknown[],
+    overrides: Record<string, unknown> = {},
+  ) =>
+    JSON.stringify({
+      signerId: userAddress,
+      publicKey: 'ed25519:6E8sCci9badyRkXb3JoRpBj5p8C6Tw41ELDZoiihKEtp',
+      nonce: '71231100000001',
+      receiverId: pool,
+      blockHash: 'CSJZb3pNEiGHXgkXtR7wWBZ4q6dFLs64dgbBgbtiBnmm',
+      actions,
+      ...overrides,
+    });
+
+  const stakeTx = (deposit = '1000000000000000000000000') =>
+    nearTx([functionCall('deposit_and_stake', {}, deposit)]);
+
+  const validate = (
+    unsignedTransaction: string,
+    args?: { validatorAddress?: string; validatorAddresses?: string[] },
+  ) => shield.validate({ yieldId, unsignedTransaction, userAddress, args });
+
+  const attemptReasons = (result: ReturnType<typeof validate>) =>
+    result.details?.attempts?.map((attempt) => attempt.reason) ?? [];
+
+  describe('isSupported', () => {
+    it('should support near-near-native-staking yield', () => {
+      expect(shield.isSupported(yieldId)).toBe(true);
+    });
+  });
+
+  describe('transaction encodings', () => {
+    it('should accept near-api-js JSON', () => {
+      const result = validate(stakeTx());
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.STAKE);
+      expect(result.amount).toEqual({
+        token: 'native',
+        amount: '1000000000000000000000000',
+        symbol: 'NEAR',
+        decimals: 24,
+        normalized: '1.0',
+      });
+    });
+
+    it('should accept RPC JSON with base64 arguments', () => {
+      const args = Buffer.from(
+        JSON.stringify({ amount: '500000000000000000000000' }),
+      ).toString('base64');
+      const result = validate(
+        JSON.stringify({
+          signer_id: userAddress,
+          public_key: 'ed25519:6E8sCci9badyRkXb3JoRpBj5p8C6Tw41ELDZoiihKEtp',
+          nonce: 71231100000001,
+          receiver_id: pool,
+          actions: [
+            {
+              FunctionCall: {
+                method_name: 'unstake',
+                args,
+                gas: 50000000000000,
+                deposit: '0',
+              },
+            },
+          ],
+        }),
+      );
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.UNSTAKE);
+      expect(result.amount?.amount).toBe('500000000000000000000000');
+    });
+
+    it('should reject input that is not JSON', () => {
+      const result = validate('not a transaction');
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain(
+        'Failed to decode NEAR transaction',
+      );
+    });
+  });
+
+  describe('transaction types', () => {
+    it('should detect each staking pool method', () => {
+      const amount = { amount: '1000000000000000000000000' };
+      const cases = [
+        [functionCall('unstake', amount), TransactionType.UNSTAKE],
+        [functionCall('unstake_all'), TransactionType.UNSTAKE],
+        [functionCall('withdraw', amount), TransactionType.WITHDRAW],
+        [functionCall('withdraw_all'), TransactionType.WITHDRAW],
+      ] as const;
+      for (const [action, detectedType] of cases) {
+        const result = validate(nearTx([action]));
+
+        expect(result.isValid).toBe(true);
+        expect(result.detectedType).toBe(detectedType);
+      }
+    });
+
+    it('should validate every action of a transaction', () => {
+      const result = validate(
+        nearTx([
+          functionCall('withdraw_all'),
+          functionCall('withdraw', { amount: '1' }),
+        ]),
+      );
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.WITHDRAW);
+      expect(result.decoded?.actions).toHaveLength(2);
+    });
+
+    it('should reject a transaction mixing in other actions', () => {
+      const cases = [
+        [functionCall('unstake_all'), functionCall('withdraw_all')],
+        [
+          functionCall('deposit_and_stake', {}, '1'),
+          { transfer: { deposit: '1000000000000000000000000' } },
+        ],
+        [functionCall('unstake_all'), 'CreateAccount'],
+      ];
+      for (const actions of cases) {
+        const result = validate(nearTx(actions));
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('NO_MATCHING_PATTERN');
+      }
+    });
+
+    it('should reject methods staking never calls', () => {
+      const result = validate(
+        nearTx([functionCall('ping'), functionCall('unstake_all')]),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain('Unexpected method');
+    });
+
+    it('should reject a transaction without actions', () => {
+      const result = validate(nearTx([]));
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain(
+        'Transaction contains no actions',
+      );
+    });
+  });
+
+  describe('arguments and deposits', () => {
+    it('should require a deposit to stake', () => {
+      const result = validate(stakeTx('0'));
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain('Invalid deposit');
+    });
+
+    it('should reject a deposit on unstake and withdraw', () => {
+      const cases = [
+        functionCall('unstake_all', {}, '1'),
+        functionCall('withdraw', { amount: '1' }, '1'),
+      ];
+      for (const action of cases) {
+        const result = validate(nearTx([action]));
+
+        expect(result.isValid).toBe(false);
+        expect(attemptReasons(result)).toContain('Invalid deposit');
+      }
+    });
+
+    it('should reject unexpected or invalid arguments', () => {
+      const cases = [
+        [functionCall('deposit_and_stake', { account_id: 'x.near' }, '1')],
+        [functionCall('unstake', {})],
+        [functionCall('unstake', { amount: '0' })],
+        [functionCall('withdraw', { amount: 1000 })],
+      ];
+      for (const actions of cases) {
+        const result = validate(nearTx(actions));
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('NO_MATCHING_PATTERN');
+      }
+    });
+
+    it('should check the amount against expectedAmount', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: stakeTx(),
+        userAddress,
+        expectedAmount: '2000000000000000000000000',
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('AMOUNT_MISMATCH');
+    });
+  });
+
+  describe('accounts', () => {
+    it('should reject a signer other than the user', () => {
+      const result = validate(nearTx([], { signerId: 'mallory.near' }));
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('SENDER_MISMATCH');
+    });
+
+    it('should reject function calls to contracts other than staking pools', () => {
+      const cases = ['wrap.near', 'poolv1.near', 'evil.near'];
+      for (const receiverId of cases) {
+        const result = validate(
+          nearTx([functionCall('deposit_and_stake', {}, '1')], { receiverId }),
+        );
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('RECIPIENT_MISMATCH');
+      }
+    });
+
+    it('should accept pools of either staking pool factory', () => {
+      const result = validate(stakeTx(), {
+        validatorAddresses: ['astro-stakers.poolv1.near', pool],
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.expectedRecipient).toBe(pool);
+
+      const legacy = validate(
+        nearTx([functionCall('unstake_all')], {
+          receiverId: 'legends.pool.near',
+        }),
+      );
+      expect(legacy.isValid).toBe(true);
+    });
+
+    it('should reject a pool other than the expected validator', () => {
+      const result = validate(stakeTx(), {
+        validatorAddress: 'astro-stakers.poolv1.near',
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain(
+        'Transaction targets an unexpected staking pool',
+      );
+    });
+  });
+
+  describe('decode', () => {
+    it('should decode the actions without validating them', () => {
+      const result = shield.decode({
+        yieldId,
+        unsignedTransaction: nearTx([functionCall('ping')]),
+      });
+
+      expect(result.decoded?.actions).toEqual([
+        {
+          type: 'FunctionCall',
+          methodName: 'ping',
+          args: {},
+          gas: '50000000000000',
+          deposit: '0',
+        },
+      ]);
+    });
+  });
+});
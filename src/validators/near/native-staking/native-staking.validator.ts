@@ -0,0 +1,284 @@
+import {
+  ActionArguments,
+  DecodedAction,
+  DecodeResult,
+  ReasonCode,
+  TransactionAmount,
+  TransactionType,
+  ValidationContext,
+  ValidationResult,
+  ValidatorCapabilities,
+} from '../../../types';
+import {
+  isDefined,
+  isNonEmptyString,
+  isNullOrUndefined,
+} from '../../../utils/validation';
+import { toTransactionAmount } from '../../../utils/amount';
+import { BaseValidator } from '../../base.validator';
+import { NearTransaction, decodeNearTransaction } from '../tx-decoder';
+
+// Staking pool methods each transaction type may call, and the argument
+// each takes: the amount in yoctoNEAR, or nothing
+const EXPECTED_METHODS: Partial<
+  Record<TransactionType, Record<string, 'amount' | 'none'>>
+> = {
+  [TransactionType.STAKE]: { deposit_and_stake: 'none' },
+  [TransactionType.UNSTAKE]: { unstake: 'amount', unstake_all: 'none' },
+  [TransactionType.WITHDRAW]: { withdraw: 'amount', withdraw_all: 'none' },
+};
+
+// Accounts of the staking pool factories, whose pools are named
+// <validator>.poolv1.near and <validator>.pool.near
+const POOL_FACTORIES = ['poolv1.near', 'pool.near'];
+
+const NEAR_ASSET = { symbol: 'NEAR', decimals: 24 };
+
+/**
+ * Native NEAR staking through validator staking pools
+ *
+ * Transaction Types Validated:
+ * - STAKE: deposit_and_stake, attaching the amount to stake
+ * - UNSTAKE: unstake or unstake_all
+ * - WITHDRAW: withdraw or withdraw_all, once the unstaked NEAR is unlocked
+ *
+ * A transaction may carry several actions, but each must be a FunctionCall
+ * of the expected type on the staking pool the transaction is sent to.
+ */
+export class NearStakingValidator extends BaseValidator {
+  getSupportedTransactionTypes(): TransactionType[] {
+    return [
+      TransactionType.STAKE,
+      TransactionType.UNSTAKE,
+      TransactionType.WITHDRAW,
+    ];
+  }
+
+  // Every validator runs its own pool, so there is no fixed contract list
+  getCapabilities(): ValidatorCapabilities {
+    return {
+      name: 'NEAR native staking',
+      protocol: 'native',
+      network: 'near',
+      supportsPartialAmounts: true,
+      chainId: 'near-mainnet',
+      contracts: [],
+    };
+  }
+
+  decode(unsignedTransaction: string): DecodeResult {
+    const decoded = this.decodeTransaction(unsignedTransaction);
+    if (!decoded.transaction) {
+      return {
+        decoded: null,
+        reason: `Failed to decode NEAR transaction: ${decoded.error}`,
+      };
+    }
+    return { decoded: { actions: decoded.transaction.actions } };
+  }
+
+  getMatchPath(_unsignedTransaction: string): string {
+    return 'near-action-match';
+  }
+
+  getSigner(unsignedTransaction: string): string | undefined {
+    const { transaction } = this.decodeTransaction(unsignedTransaction);
+    return transaction?.signerId;
+  }
+
+  getContractAddresses(unsignedTransaction: string): string[] {
+    const { transaction } = this.decodeTransaction(unsignedTransaction);
+    return transaction ? [transaction.receiverId] : [];
+  }
+
+  getMismatchCode(unsignedTransaction: string): ReasonCode | undefined {
+    const { transaction } = this.decodeTransaction(unsignedTransaction);
+    return transaction && !this.isStakingPool(transaction.receiverId)
+      ? 'RECIPIENT_MISMATCH'
+      : undefined;
+  }
+
+  // Staking attaches its amount, unstaking and withdrawing name it; the
+  // *_all methods move an amount only known on chain
+  getAmount(unsignedTransaction: string): TransactionAmount | undefined {
+    const { transaction } = this.decodeTransaction(unsignedTransaction);
+    if (!transaction || transaction.actions.length === 0) return undefined;
+
+    let total = 0n;
+    for (const action of transaction.actions) {
+      const amount =
+        action.methodName === 'deposit_and_stake'
+          ? action.deposit
+          : action.args?.amount;
+      if (typeof amount !== 'string' || !/^[0-9]+$/.test(amount)) {
+        return undefined;
+      }
+      total += BigInt(amount);
+    }
+    return toTransactionAmount('native', total, NEAR_ASSET);
+  }
+
+  getStakeAmount(amount: bigint): TransactionAmount | undefined {
+    return toTransactionAmount('native', amount, NEAR_ASSET);
+  }
+
+  validate(
+    unsignedTransaction: string,
+    transactionType: TransactionType,
+    userAddress: string,
+    args?: ActionArguments,
+    _context?: ValidationContext,
+  ): ValidationResult {
+    const decoded = this.decodeTransaction(unsignedTransaction);
+    if (!decoded.transaction) {
+      return this.blocked('Failed to decode NEAR transaction', {
+        error: decoded.error,
+      });
+    }
+
+    const { signerId, receiverId, actions } = decoded.transaction;
+
+    if (signerId !== userAddress) {
+      return this.blocked('Signer is not user address', {
+        expected: userAddress,
+        actual: signerId,
+      });
+    }
+
+    if (!this.isStakingPool(receiverId)) {
+      return this.blocked('Receiver is not a staking pool', {
+        expected: POOL_FACTORIES.map((factory) => `*.${factory}`),
+        actual: receiverId,
+      });
+    }
+
+    const expectedPools = this.getExpectedPools(args);
+    if (expectedPools !== null && !expectedPools.includes(receiverId)) {
+      return this.blocked('Transaction targets an unexpected staking pool', {
+        expected: expectedPools,
+        actual: receiverId,
+      });
+    }
+
+    if (actions.length === 0) {
+      return this.blocked('Transaction contains no actions');
+    }
+
+    const expectedMethods = EXPECTED_METHODS[transactionType];
+    if (!isDefined(expectedMethods)) {
+      return this.blocked('Unsupported transaction type', {
+        transactionType,
+      });
+    }
+
+    for (const [actionIndex, action] of actions.entries()) {
+      const actionErr = this.validateAction(
+        action,
+        actionIndex,
+        transactionType,
+        expectedMethods,
+      );
+      if (actionErr) return actionErr;
+    }
+
+    return {
+      ...this.safe(),
+      decoded: { actions },
+    };
+  }
+
+  private validateAction(
+    action: DecodedAction,
+    actionIndex: number,
+    transactionType: TransactionType,
+    expectedMethods: Record<string, 'amount' | 'none'>,
+  ): ValidationResult | null {
+    if (action.type !== 'FunctionCall') {
+      return this.blocked('Unexpected action type', {
+        actionIndex,
+        expected: 'FunctionCall',
+        actual: action.type,
+      });
+    }
+
+    const methodName = action.methodName ?? '';
+    const argument = expectedMethods[methodName];
+    if (!isDefined(argument)) {
+      return this.blocked('Unexpected method', {
+        actionIndex,
+        expected: Object.keys(expectedMethods),
+        actual: methodName,
+      });
+    }
+
+    const argNames = Object.keys(action.args ?? {});
+    const expectedArgs = argument === 'amount' ? ['amount'] : [];
+    if (
+      argNames.length !== expectedArgs.length ||
+      !expectedArgs.every((name) => argNames.includes(name))
+    ) {
+      return this.blocked('Unexpected method arguments', {
+        actionIndex,
+        expected: expectedArgs,
+        actual: argNames,
+      });
+    }
+
+    const amount = action.args?.amount;
+    if (
+      argument === 'amount' &&
+      (typeof amount !== 'string' || !/^[1-9][0-9]*$/.test(amount))
+    ) {
+      return this.blocked('Invalid amount', { actionIndex, actual: amount });
+    }
+
+    // Only deposit_and_stake is payable; the pool rejects a deposit on the
+    // others, and staking nothing is never intended
+    const deposit = action.deposit ?? '0';
+    const staking = transactionType === TransactionType.STAKE;
+    if (staking ? deposit === '0' : deposit !== '0') {
+      return this.blocked('Invalid deposit', {
+        actionIndex,
+        expected: staking ? 'positive' : '0',
+        actual: deposit,
+      });
+    }
+
+    return null;
+  }
+
+  private isStakingPool(accountId: string): boolean {
+    return POOL_FACTORIES.some(
+      (factory) =>
+        accountId.endsWith(`.${factory}`) &&
+        accountId.length > factory.length + 1,
+    );
+  }
+
+  private getExpectedPools(args?: ActionArguments): string[] | null {
+    if (isNullOrUndefined(args)) return null;
+    if (
+      isDefined(args.validatorAddresses) &&
+      args.validatorAddresses.length > 0
+    ) {
+      return args.validatorAddresses;
+    }
+    if (isNonEmptyString(args.validatorAddress)) {
+      return [args.validatorAddress];
+    }
+    return null;
+  }
+
+  private decodeTransaction(unsignedTransaction: string): {
+    transaction?: NearTransaction;
+    error?: string;
+  } {
+    try {
+      return { transaction: decodeNearTransaction(unsignedTransaction) };
+    } catch (error) {
+      return {
+        error: error instanceof Error ? error.message : String(error),
+      };
+    }
+  }
+}
@@ -0,0 +1,110 @@
+import { DecodedAction } from '../../types';
+import { isNonEmptyString } from '../../utils/validation';
+
+export interface NearTransaction {
+  signerId: string;
+  receiverId: string;
+  actions: DecodedAction[];
+}
+
+/**
+ * Decodes a NEAR transaction given as JSON, either as near-api-js writes it
+ * ({ signerId, receiverId, actions: [{ functionCall: { methodName } }] }) or
+ * as the RPC reports it ({ signer_id, receiver_id, actions: [{ FunctionCall:
+ * { method_name } }] }).
+ */
+export function decodeNearTransaction(encoded: string): NearTransaction {
+  const json: unknown = JSON.parse(encoded);
+  if (!isRecord(json)) {
+    throw new Error('Transaction JSON must be an object');
+  }
+
+  const signerId = json.signerId ?? json.signer_id;
+  const receiverId = json.receiverId ?? json.receiver_id;
+  if (!isNonEmptyString(signerId) || !isNonEmptyString(receiverId)) {
+    throw new Error('Transaction has no signer or receiver');
+  }
+  if (!Array.isArray(json.actions)) {
+    throw new Error('Transaction JSON has no actions');
+  }
+
+  return {
+    signerId,
+    receiverId,
+    actions: json.actions.map(decodeAction),
+  };
+}
+
+function decodeAction(action: unknown): DecodedAction {
+  // Actions without fields, such as CreateAccount, are bare strings
+  if (isNonEmptyString(action)) return { type: toTypeName(action) };
+  if (!isRecord(action)) {
+    throw new Error('Invalid action');
+  }
+
+  // near-api-js also writes which variant is set as enum
+  const keys = Object.keys(action).filter((key) => key !== 'enum');
+  if (keys.length !== 1) {
+    throw new Error('Action must have exactly one variant');
+  }
+  const type = toTypeName(keys[0]);
+  const fields = isRecord(action[keys[0]]) ? action[keys[0]] : {};
+
+  if (type !== 'FunctionCall') {
+    return { type, deposit: optionalAmount(fields.deposit) };
+  }
+
+  const methodName = fields.methodName ?? fields.method_name;
+  if (!isNonEmptyString(methodName)) {
+    throw new Error('FunctionCall action has no method name');
+  }
+  return {
+    type,
+    methodName,
+    args: decodeArgs(fields.args),
+    gas: optionalAmount(fields.gas),
+    deposit: optionalAmount(fields.deposit) ?? '0',
+  };
+}
+
+// Arguments are JSON, given as an object, as base64 (RPC) or as bytes
+function decodeArgs(args: unknown): Record<string, unknown> {
+  if (args === undefined || args === '') return {};
+  if (isRecord(args)) return args;
+
+  let bytes: Buffer;
+  if (typeof args === 'string') {
+    bytes = Buffer.from(args, 'base64');
+  } else if (Array.isArray(args)) {
+    bytes = Buffer.from(args);
+  } else {
+    throw new Error('Invalid FunctionCall arguments');
+  }
+
+  const parsed: unknown = JSON.parse(bytes.toString('utf8'));
+  if (!isRecord(parsed)) {
+    throw new Error('FunctionCall arguments must be a JSON object');
+  }
+  return parsed;
+}
+
+// gas and deposit are u64 and u128, written as numbers or decimal strings
+function optionalAmount(value: unknown): string | undefined {
+  if (typeof value === 'number' && Number.isSafeInteger(value) && value >= 0) {
+    return String(value);
+  }
+  if (typeof value === 'string' && /^[0-9]+$/.test(value)) {
+    return BigInt(value).toString();
+  }
+  if (value === undefined) return undefined;
+  throw new Error(`Invalid amount ${String(value)}`);
+}
+
+// functionCall and FunctionCall both name the FunctionCall action
+function toTypeName(key: string): string {
+  return key.charAt(0).toUpperCase() + key.slice(1);
+}
+
+function isRecord(value: unknown): value is Record<string, unknown> {
+  return typeof value === 'object' && value !== null && !Array.isArray(value);
+}
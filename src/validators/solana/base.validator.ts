@@ -0,0 +1,520 @@
+import {
+  AccountMeta,
+  AddressLookupTableAccount,
+  Message,
+  PublicKey,
+  Transaction,
+  TransactionInstruction,
+  TransactionMessage,
+  VersionedMessage,
+  VersionedTransaction,
+} from '@solana/web3.js';
+import {
+  DecodedInstruction,
+  DecodeResult,
+  TransactionSignatures,
+  ValidationResult,
+  ValidationWarning,
+} from '../../types';
+import { isDefined } from '../../utils/validation';
+import { BaseValidator } from '../base.validator';
+
+export const SOLANA_CHAIN_ID = 'solana-mainnet';
+
+export const SOLANA_PROGRAMS = {
+  system: '11111111111111111111111111111111',
+  stake: 'Stake11111111111111111111111111111111111111',
+  computeBudget: 'ComputeBudget111111111111111111111111111111',
+  token: 'TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA',
+  associatedToken: 'ATokenGPvbdGVxr1b2hvZbsiqW5xWH25efTNsLJA8knL',
+  marinade: 'MarBmsSgKXdrN1egZf5sqe1TMai9K1rChYNDJgjq7aD',
+  stakePool: 'SPoo1Ku8WFXoNDMHPsrGSTSG1Y47rzgn41SLUNakuHy',
+};
+
+const PROGRAM_NAMES: Record<string, string> = {
+  [SOLANA_PROGRAMS.system]: 'System',
+  [SOLANA_PROGRAMS.stake]: 'Stake',
+  [SOLANA_PROGRAMS.computeBudget]: 'ComputeBudget',
+  [SOLANA_PROGRAMS.token]: 'Token',
+  [SOLANA_PROGRAMS.associatedToken]: 'AssociatedToken',
+  [SOLANA_PROGRAMS.marinade]: 'Marinade',
+  [SOLANA_PROGRAMS.stakePool]: 'StakePool',
+};
+
+// Anchor instruction discriminators: sha256('global:<name>')[0..8]
+const MARINADE_DISCRIMINATORS: Record<string, string> = {
+  f223c68952e1f2b6: 'Deposit',
+  '1e1e77f0bfe30c10': 'LiquidUnstake',
+  '61a7906b75be8024': 'OrderUnstake',
+  '3ec6d6c1d59f6cd2': 'Claim',
+};
+
+export type SolanaInstruction = {
+  programId: string;
+  instructionType: string; // Decoded from the instruction data
+  data: Buffer;
+  accounts: Array<AccountMeta>;
+};
+
+export abstract class BaseSolanaValidator extends BaseValidator {
+  decode(unsignedTransaction: string): DecodeResult {
+    const decoded = this.decodeSolanaTransaction(unsignedTransaction);
+    if (!decoded.isValid) {
+      return {
+        decoded: null,
+        reason: `Failed to decode Solana transaction: ${decoded.error}`,
+      };
+    }
+
+    return {
+      decoded: {
+        instructions: this.toDecodedInstructions(decoded.instructions!),
+      },
+    };
+  }
+
+  getMatchPath(_unsignedTransaction: string): string {
+    return 'solana-instruction-match';
+  }
+
+  // A versioned transaction's fee payer is always in its own accounts
+  getSigner(unsignedTransaction: string): string | undefined {
+    try {
+      const message = this.parseVersionedMessage(unsignedTransaction);
+      if (isDefined(message)) return message.staticAccountKeys[0]?.toBase58();
+      const tx = this.parseSolanaTransaction(unsignedTransaction);
+      return tx.feePayer?.toBase58();
+    } catch {
+      return undefined;
+    }
+  }
+
+  // The wire format leaves an unsigned slot as zeros, which web3.js reads
+  // as a null signature. Those of a versioned transaction sign its v0
+  // message, which web3.js cannot verify, so it is not read
+  getSignatures(rawTransaction: string): TransactionSignatures | null {
+    let tx: Transaction;
+    try {
+      if (isDefined(this.parseVersionedMessage(rawTransaction))) return null;
+      tx = this.parseSolanaTransaction(rawTransaction);
+    } catch {
+      return null;
+    }
+
+    const signers = tx.signatures
+      .filter(({ signature }) => signature !== null)
+      .map(({ publicKey }) => publicKey.toBase58());
+    if (signers.length === 0) return { signed: false, signers };
+    if (!tx.verifySignatures(false)) {
+      return {
+        signed: true,
+        signers: [],
+        error: 'A signature does not verify',
+      };
+    }
+    return { signed: true, signers };
+  }
+
+  getContractAddresses(unsignedTransaction: string): string[] {
+    const decoded = this.decodeSolanaTransaction(unsignedTransaction);
+    if (!decoded.isValid) return [];
+    return [...new Set(decoded.instructions!.map((i) => i.programId))];
+  }
+
+  getLookupTableAddresses(unsignedTransaction: string): string[] {
+    try {
+      const message = this.parseVersionedMessage(unsignedTransaction);
+      return (message?.addressTableLookups ?? []).map(({ accountKey }) =>
+        accountKey.toBase58(),
+      );
+    } catch {
+      return [];
+    }
+  }
+
+  // The legacy message of the same instructions, accounts and flags. It is
+  // only ever validated, never signed, so the signatures are dropped
+  withLookupTables(
+    unsignedTransaction: string,
+    lookupTables: Record<string, string[]>,
+  ): string {
+    try {
+      const message = this.parseVersionedMessage(unsignedTransaction);
+      if (!isDefined(message) || message.addressTableLookups.length === 0) {
+        return unsignedTransaction;
+      }
+
+      const accounts = message.addressTableLookups.map((lookup) => {
+        const key = lookup.accountKey.toBase58();
+        const addresses = Object.hasOwn(lookupTables, key)
+          ? lookupTables[key]
+          : [];
+        if (
+          [...lookup.writableIndexes, ...lookup.readonlyIndexes].some(
+            (index) => index >= addresses.length,
+          )
+        ) {
+          throw new Error(`Lookup table ${key} has no address at an index`);
+        }
+        return new AddressLookupTableAccount({
+          key: lookup.accountKey,
+          state: {
+            deactivationSlot: BigInt('0xffffffffffffffff'),
+            lastExtendedSlot: 0,
+            lastExtendedSlotStartIndex: 0,
+            addresses: addresses.map((address) => new PublicKey(address)),
+          },
+        });
+      });
+      return this.toLegacyMessage(message, accounts)
+        .serialize()
+        .toString('base64');
+    } catch {
+      // A table left out, or too short for an index, leaves it unresolved
+      return unsignedTransaction;
+    }
+  }
+
+  /**
+   * safe() that also reports the instructions that were validated.
+   */
+  protected safeWithInstructions(
+    instructions: SolanaInstruction[],
+    warnings: ValidationWarning[] = [],
+  ): ValidationResult {
+    return {
+      ...this.safe(warnings),
+      decoded: { instructions: this.toDecodedInstructions(instructions) },
+    };
+  }
+
+  protected ensureOnlyPrograms(
+    instructions: SolanaInstruction[],
+    allowedPrograms: string[],
+  ): ValidationResult | null {
+    const unexpectedPrograms = [
+      ...new Set(
+        instructions
+          .map((instruction) => instruction.programId)
+          .filter((programId) => !allowedPrograms.includes(programId)),
+      ),
+    ];
+
+    if (unexpectedPrograms.length > 0) {
+      return this.blocked(
+        'Transaction contains instructions for unexpected programs',
+        { unexpectedPrograms, allowedPrograms },
+      );
+    }
+    return null;
+  }
+
+  protected isComputeBudgetInstruction(
+    instruction: SolanaInstruction,
+    expectedType?: string,
+  ): boolean {
+    return this.isProgramInstruction(
+      instruction,
+      SOLANA_PROGRAMS.computeBudget,
+      expectedType,
+    );
+  }
+
+  protected isSystemInstruction(
+    instruction: SolanaInstruction,
+    expectedType: string,
+  ): boolean {
+    return this.isProgramInstruction(
+      instruction,
+      SOLANA_PROGRAMS.system,
+      expectedType,
+    );
+  }
+
+  protected isStakeInstruction(
+    instruction: SolanaInstruction,
+    expectedType: string,
+  ): boolean {
+    return this.isProgramInstruction(
+      instruction,
+      SOLANA_PROGRAMS.stake,
+      expectedType,
+    );
+  }
+
+  protected isProgramInstruction(
+    instruction: SolanaInstruction,
+    programId: string,
+    expectedType?: string,
+  ): boolean {
+    return (
+      instruction.programId === programId &&
+      (expectedType === undefined ||
+        instruction.instructionType === expectedType)
+    );
+  }
+
+  protected accountAt(
+    instruction: SolanaInstruction,
+    index: number,
+  ): string | undefined {
+    return instruction.accounts.at(index)?.pubkey.toBase58();
+  }
+
+  protected getAssociatedTokenAddress(owner: string, mint: string): string {
+    const [address] = PublicKey.findProgramAddressSync(
+      [
+        new PublicKey(owner).toBuffer(),
+        new PublicKey(SOLANA_PROGRAMS.token).toBuffer(),
+        new PublicKey(mint).toBuffer(),
+      ],
+      new PublicKey(SOLANA_PROGRAMS.associatedToken),
+    );
+    return address.toBase58();
+  }
+
+  /**
+   * Checks an associated token account creation is paid by, and creates the
+   * account for, the user.
+   */
+  protected ensureUserTokenAccountCreation(
+    instruction: SolanaInstruction,
+    userAddress: string,
+    mint: string,
+  ): ValidationResult | null {
+    if (this.accountAt(instruction, 0) !== userAddress) {
+      return this.blocked('Token account creation not paid by user address');
+    }
+    if (
+      this.accountAt(instruction, 2) !== userAddress ||
+      this.accountAt(instruction, 3) !== mint
+    ) {
+      return this.blocked('Token account is not the user token account', {
+        expectedMint: mint,
+        actualMint: this.accountAt(instruction, 3),
+      });
+    }
+    return null;
+  }
+
+  protected getSolanaInstructionType(
+    instruction: TransactionInstruction,
+  ): string {
+    const programId = instruction.programId.toBase58();
+    const data = instruction.data;
+
+    if (programId === SOLANA_PROGRAMS.associatedToken) {
+      // An empty payload is the original Create instruction
+      if (data.length === 0) return 'Create';
+      return data[0] === 1 ? 'CreateIdempotent' : 'Unknown';
+    }
+
+    if (data.length === 0) return 'Unknown';
+
+    switch (programId) {
+      case SOLANA_PROGRAMS.stake:
+        switch (data[0]) {
+          case 0:
+            return 'Initialize';
+          case 1:
+            return 'Authorize';
+          case 2:
+            return 'Delegate';
+          case 3:
+            return 'Split';
+          case 4:
+            return 'Withdraw';
+          case 5:
+            return 'Deactivate';
+          case 10:
+            return 'CreateAccountWithSeed';
+          default:
+            return 'Unknown';
+        }
+      case SOLANA_PROGRAMS.system:
+        switch (data[0]) {
+          case 0:
+            return 'CreateAccount';
+          case 1:
+            return 'Assign';
+          case 2:
+            return 'Transfer';
+          case 3:
+            return 'CreateAccountWithSeed';
+          case 8:
+            return 'Allocate';
+          case 9:
+            return 'AllocateWithSeed';
+          default:
+            return 'Unknown';
+        }
+      case SOLANA_PROGRAMS.computeBudget:
+        switch (data[0]) {
+          case 0:
+            return 'RequestUnitsDeprecated';
+          case 1:
+            return 'RequestHeapFrame';
+          case 2:
+            return 'SetComputeUnitLimit';
+          case 3:
+            return 'SetComputeUnitPrice';
+          default:
+            return 'Unknown';
+        }
+      case SOLANA_PROGRAMS.stakePool:
+        switch (data[0]) {
+          case 9:
+            return 'DepositStake';
+          case 10:
+            return 'WithdrawStake';
+          case 14:
+            return 'DepositSol';
+          case 16:
+            return 'WithdrawSol';
+          default:
+            return 'Unknown';
+        }
+      case SOLANA_PROGRAMS.marinade:
+        return (
+          MARINADE_DISCRIMINATORS[data.subarray(0, 8).toString('hex')] ??
+          'Unknown'
+        );
+      default:
+        return 'Unknown';
+    }
+  }
+
+  /**
+   * Accepts a hex-encoded wire transaction, or a base64-encoded wire
+   * transaction or bare transaction message, legacy or v0. A v0 one that
+   * loads accounts from lookup tables must have them resolved first, with
+   * withLookupTables.
+   */
+  protected decodeSolanaTransaction(encoded: string): {
+    isValid: boolean;
+    instructions?: SolanaInstruction[];
+    error?: string;
+  } {
+    try {
+      const tx = this.parseSolanaTransaction(encoded);
+
+      const instructions = tx.instructions.map((instruction) => {
+        const programId = instruction.programId.toBase58();
+        const instructionType = this.getSolanaInstructionType(instruction);
+
+        return {
+          programId,
+          instructionType,
+          data: instruction.data,
+          accounts: instruction.keys.map((key) => ({
+            pubkey: key.pubkey,
+            isSigner: key.isSigner,
+            isWritable: key.isWritable,
+          })),
+        };
+      });
+
+      return {
+        isValid: true,
+        instructions,
+      };
+    } catch (error) {
+      return {
+        isValid: false,
+        error: error instanceof Error ? error.message : String(error),
+      };
+    }
+  }
+
+  private parseSolanaTransaction(encoded: string): Transaction {
+    const versioned = this.parseVersionedMessage(encoded);
+    if (isDefined(versioned)) {
+      return Transaction.populate(this.toLegacyMessage(versioned));
+    }
+
+    if (/^([0-9a-fA-F]{2})+$/.test(encoded)) {
+      return Transaction.from(Buffer.from(encoded, 'hex'));
+    }
+
+    // A bare message can also parse as a (corrupt) wire transaction, so only
+    // accept a reading that serializes back to exactly the input bytes
+    const buffer = this.toBytes(encoded);
+    try {
+      const tx = Transaction.from(buffer);
+      const serialized = tx.serialize({
+        requireAllSignatures: false,
+        verifySignatures: false,
+      });
+      if (serialized.equals(buffer)) return tx;
+    } catch {
+      // Not a wire transaction, try a bare message below
+    }
+
+    const message = Message.from(buffer);
+    if (!message.serialize().equals(buffer)) {
+      throw new Error('Invalid Solana transaction message');
+    }
+    return Transaction.populate(message);
+  }
+
+  /**
+   * The message of a versioned wire transaction or bare message, read the
+   * same way as legacy ones, or undefined for a legacy transaction.
+   */
+  private parseVersionedMessage(encoded: string): VersionedMessage | undefined {
+    const buffer = this.toBytes(encoded);
+    try {
+      const tx = VersionedTransaction.deserialize(buffer);
+      if (
+        tx.version !== 'legacy' &&
+        Buffer.from(tx.serialize()).equals(buffer)
+      ) {
+        return tx.message;
+      }
+    } catch {
+      // Not a versioned wire transaction, try a bare message below
+    }
+
+    if (VersionedMessage.deserializeMessageVersion(buffer) === 'legacy') {
+      return undefined;
+    }
+    const message = VersionedMessage.deserialize(buffer);
+    if (!Buffer.from(message.serialize()).equals(buffer)) {
+      throw new Error('Invalid Solana transaction message');
+    }
+    return message;
+  }
+
+  // Throws for a message whose lookup tables are not all in lookupTables
+  private toLegacyMessage(
+    message: VersionedMessage,
+    lookupTables: AddressLookupTableAccount[] = [],
+  ): Message {
+    return TransactionMessage.decompile(message, {
+      addressLookupTableAccounts: lookupTables,
+    }).compileToLegacyMessage();
+  }
+
+  private toBytes(encoded: string): Buffer {
+    if (/^([0-9a-fA-F]{2})+$/.test(encoded)) {
+      return Buffer.from(encoded, 'hex');
+    }
+    if (!/^[A-Za-z0-9+/]+={0,2}$/.test(encoded)) {
+      throw new Error('Transaction is neither hex nor base64 encoded');
+    }
+    return Buffer.from(encoded, 'base64');
+  }
+
+  private toDecodedInstructions(
+    instructions: SolanaInstruction[],
+  ): DecodedInstruction[] {
+    return instructions.map((instruction) => ({
+      programId: instruction.programId,
+      program: PROGRAM_NAMES[instruction.programId],
+      instructionType: instruction.instructionType,
+      accounts: instruction.accounts.map((account) =>
+        account.pubkey.toBase58(),
+      ),
+    }));
+  }
+}
@@ -1 +1,4 @@
+export { BaseSolanaValidator } from './base.validator';
 export { SolanaNativeStakingValidator } from './native-staking/native-staking.validator';
+export { MarinadeValidator } from './marinade/marinade.validator';
+export { JitoValidator } from './jito/jito.validator';
@@ -0,0 +1,178 @@
+import {
+  ComputeBudgetProgram,
+  PublicKey,
+  SystemProgram,
+  Transaction,
+  TransactionInstruction,
+} from '@solana/web3.js';
+import { Shield } from '../../../shield';
+import { TransactionType } from '../../../types';
+
+const STAKE_POOL_PROGRAM = new PublicKey(
+  'SPoo1Ku8WFXoNDMHPsrGSTSG1Y47rzgn41SLUNakuHy',
+);
+const JITO_STAKE_POOL = new PublicKey(
+  'Jito4APyf642JPZPx3hGc6WWJ8zPKtRbRs4P815Awbb',
+);
+const JITOSOL_MINT = new PublicKey(
+  'J1toso1uCk3RLmjorhTtrVwY9HJ7X8V9yYac6Y7kGCPn',
+);
+const TOKEN_PROGRAM = new PublicKey(
+  'TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA',
+);
+const ATA_PROGRAM = new PublicKey(
+  'ATokenGPvbdGVxr1b2hvZbsiqW5xWH25efTNsLJA8knL',
+);
+
+describe('JitoValidator via Shield', () => {
+  const shield = new Shield();
+  const yieldId = 'solana-sol-jito-liquid-staking';
+  const userAddress = '29LDedMM8bYERotXSvUhBaXeWWdgi5kqwu4YBxAPLamy';
+  const userPubkey = new PublicKey(userAddress);
+  const [userJitoSolAccount] = PublicKey.findProgramAddressSync(
+    [userPubkey.toBuffer(), TOKEN_PROGRAM.toBuffer(), JITOSOL_MINT.toBuffer()],
+    ATA_PROGRAM,
+  );
+
+  const account = (pubkey: PublicKey, isSigner = false) => ({
+    pubkey,
+    isSigner,
+    isWritable: true,
+  });
+
+  const amountData = (instruction: number, amount: bigint) => {
+    const data = Buffer.alloc(9);
+    data.writeUInt8(instruction, 0);
+    data.writeBigUInt64LE(amount, 1);
+    return data;
+  };
+
+  const depositSol = (
+    poolTokensTo = userJitoSolAccount,
+    stakePool = JITO_STAKE_POOL,
+  ) =>
+    new TransactionInstruction({
+      programId: STAKE_POOL_PROGRAM,
+      keys: [
+        account(stakePool),
+        account(PublicKey.unique()), // withdraw authority
+        account(PublicKey.unique()), // reserve stake
+        account(userPubkey, true), // lamports from
+        account(poolTokensTo), // pool tokens to
+        account(PublicKey.unique()), // manager fee account
+        account(PublicKey.unique()), // referrer pool tokens
+        account(JITOSOL_MINT),
+        account(SystemProgram.programId),
+        account(TOKEN_PROGRAM),
+      ],
+      data: amountData(14, 1_000_000_000n),
+    });
+
+  const withdrawSol = (lamportsTo = userPubkey) =>
+    new TransactionInstruction({
+      programId: STAKE_POOL_PROGRAM,
+      keys: [
+        account(JITO_STAKE_POOL),
+        account(PublicKey.unique()), // withdraw authority
+        account(userPubkey, true), // user transfer authority
+        account(userJitoSolAccount), // pool tokens from
+        account(PublicKey.unique()), // reserve stake
+        account(lamportsTo), // lamports to
+        account(PublicKey.unique()), // manager fee account
+        account(JITOSOL_MINT),
+        account(PublicKey.unique()), // clock
+        account(PublicKey.unique()), // stake history
+        account(PublicKey.unique()), // stake program
+        account(TOKEN_PROGRAM),
+      ],
+      data: amountData(16, 1_000_000_000n),
+    });
+
+  const buildMessage = (...instructions: TransactionInstruction[]) => {
+    const transaction = new Transaction();
+    transaction.add(
+      ComputeBudgetProgram.setComputeUnitLimit({ units: 200000 }),
+    );
+    transaction.add(...instructions);
+    transaction.recentBlockhash = '11111111111111111111111111111111';
+    transaction.feePayer = userPubkey;
+    return transaction.serializeMessage().toString('base64');
+  };
+
+  const validate = (unsignedTransaction: string) =>
+    shield.validate({ yieldId, unsignedTransaction, userAddress });
+
+  const attemptReasons = (result: ReturnType<typeof validate>) =>
+    result.details?.attempts?.map((attempt) => attempt.reason) ?? [];
+
+  describe('isSupported', () => {
+    it('should support solana-sol-jito-liquid-staking yield', () => {
+      expect(shield.isSupported(yieldId)).toBe(true);
+    });
+  });
+
+  describe('STAKE validation', () => {
+    it('should validate a DepositSol into the Jito pool', () => {
+      const result = validate(buildMessage(depositSol()));
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.STAKE);
+      expect(
+        result.decoded?.instructions?.map((i) => i.instructionType),
+      ).toEqual(['SetComputeUnitLimit', 'DepositSol']);
+    });
+
+    it('should reject deposits into another stake pool', () => {
+      const result = validate(
+        buildMessage(depositSol(userJitoSolAccount, PublicKey.unique())),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain(
+        'Stake pool is not the Jito stake pool',
+      );
+    });
+
+    it('should reject JitoSOL minted to another account', () => {
+      const result = validate(buildMessage(depositSol(PublicKey.unique())));
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain(
+        'JitoSOL recipient is not the user token account',
+      );
+    });
+
+    it('should reject instructions to unexpected programs', () => {
+      const transfer = SystemProgram.transfer({
+        fromPubkey: userPubkey,
+        toPubkey: PublicKey.unique(),
+        lamports: 1_000_000_000,
+      });
+
+      const result = validate(buildMessage(depositSol(), transfer));
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain(
+        'Transaction contains instructions for unexpected programs',
+      );
+    });
+  });
+
+  describe('UNSTAKE validation', () => {
+    it('should validate a WithdrawSol to the user', () => {
+      const result = validate(buildMessage(withdrawSol()));
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.UNSTAKE);
+    });
+
+    it('should reject withdrawals to another address', () => {
+      const result = validate(buildMessage(withdrawSol(PublicKey.unique())));
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain(
+        'Withdraw recipient is not user address',
+      );
+    });
+  });
+});
@@ -0,0 +1,155 @@
+import {
+  ActionArguments,
+  TransactionType,
+  ValidationContext,
+  ValidationResult,
+  ValidatorCapabilities,
+} from '../../../types';
+import {
+  BaseSolanaValidator,
+  SOLANA_CHAIN_ID,
+  SOLANA_PROGRAMS,
+  SolanaInstruction,
+} from '../base.validator';
+
+const JITO_STAKE_POOL = 'Jito4APyf642JPZPx3hGc6WWJ8zPKtRbRs4P815Awbb';
+const JITOSOL_MINT = 'J1toso1uCk3RLmjorhTtrVwY9HJ7X8V9yYac6Y7kGCPn';
+
+const ALLOWED_PROGRAMS = [
+  SOLANA_PROGRAMS.computeBudget,
+  SOLANA_PROGRAMS.associatedToken,
+  SOLANA_PROGRAMS.stakePool,
+];
+
+/**
+ * Jito liquid staking (SOL <-> JitoSOL) through the SPL stake pool program
+ *
+ * Transaction Types Validated:
+ * - STAKE: DepositSol, minting JitoSOL to the user's token account
+ * - UNSTAKE: WithdrawSol, burning JitoSOL for SOL sent to the user
+ */
+export class JitoValidator extends BaseSolanaValidator {
+  getSupportedTransactionTypes(): TransactionType[] {
+    return [TransactionType.STAKE, TransactionType.UNSTAKE];
+  }
+
+  getCapabilities(): ValidatorCapabilities {
+    return {
+      name: 'Jito',
+      protocol: 'jito',
+      network: 'solana',
+      supportsPartialAmounts: true,
+      chainId: SOLANA_CHAIN_ID,
+      contracts: [SOLANA_PROGRAMS.stakePool, JITO_STAKE_POOL],
+    };
+  }
+
+  validate(
+    unsignedTransaction: string,
+    transactionType: TransactionType,
+    userAddress: string,
+    _args?: ActionArguments,
+    _context?: ValidationContext,
+  ): ValidationResult {
+    const decoded = this.decodeSolanaTransaction(unsignedTransaction);
+    if (!decoded.isValid) {
+      return this.blocked('Failed to decode Solana transaction', {
+        error: decoded.error,
+      });
+    }
+
+    const instructions = decoded.instructions!;
+
+    const programErr = this.ensureOnlyPrograms(instructions, ALLOWED_PROGRAMS);
+    if (programErr) return programErr;
+
+    const poolInstructions = instructions.filter(
+      (instruction) => instruction.programId === SOLANA_PROGRAMS.stakePool,
+    );
+    if (poolInstructions.length !== 1) {
+      return this.blocked('Expected exactly one stake pool instruction', {
+        actual: poolInstructions.length,
+      });
+    }
+
+    const pool = poolInstructions[0];
+    if (this.accountAt(pool, 0) !== JITO_STAKE_POOL) {
+      return this.blocked('Stake pool is not the Jito stake pool', {
+        expected: JITO_STAKE_POOL,
+        actual: this.accountAt(pool, 0),
+      });
+    }
+
+    // Only the user's own JitoSOL account may be created alongside
+    for (const instruction of instructions) {
+      if (instruction.programId !== SOLANA_PROGRAMS.associatedToken) continue;
+      const tokenErr = this.ensureUserTokenAccountCreation(
+        instruction,
+        userAddress,
+        JITOSOL_MINT,
+      );
+      if (tokenErr) return tokenErr;
+    }
+
+    switch (transactionType) {
+      case TransactionType.STAKE:
+        return this.validateStake(instructions, pool, userAddress);
+      case TransactionType.UNSTAKE:
+        return this.validateUnstake(instructions, pool, userAddress);
+      default:
+        return this.blocked('Unsupported transaction type', {
+          transactionType,
+        });
+    }
+  }
+
+  private validateStake(
+    instructions: SolanaInstruction[],
+    deposit: SolanaInstruction,
+    userAddress: string,
+  ): ValidationResult {
+    if (deposit.instructionType !== 'DepositSol') {
+      return this.blocked('Missing or invalid DepositSol instruction');
+    }
+    if (this.accountAt(deposit, 3) !== userAddress) {
+      return this.blocked('Deposit source is not user address');
+    }
+    if (this.accountAt(deposit, 7) !== JITOSOL_MINT) {
+      return this.blocked('Deposit pool mint is not JitoSOL');
+    }
+
+    const userTokenAccount = this.getAssociatedTokenAddress(
+      userAddress,
+      JITOSOL_MINT,
+    );
+    if (this.accountAt(deposit, 4) !== userTokenAccount) {
+      return this.blocked('JitoSOL recipient is not the user token account', {
+        expected: userTokenAccount,
+        actual: this.accountAt(deposit, 4),
+      });
+    }
+
+    return this.safeWithInstructions(instructions);
+  }
+
+  private validateUnstake(
+    instructions: SolanaInstruction[],
+    withdraw: SolanaInstruction,
+    userAddress: string,
+  ): ValidationResult {
+    if (withdraw.instructionType !== 'WithdrawSol') {
+      return this.blocked('Missing or invalid WithdrawSol instruction');
+    }
+    if (this.accountAt(withdraw, 2) !== userAddress) {
+      return this.blocked('Withdraw authority is not user address');
+    }
+    if (this.accountAt(withdraw, 5) !== userAddress) {
+      return this.blocked('Withdraw recipient is not user address');
+    }
+    if (this.accountAt(withdraw, 7) !== JITOSOL_MINT) {
+      return this.blocked('Withdraw pool mint is not JitoSOL');
+    }
+
+    return this.safeWithInstructions(instructions);
+  }
+}
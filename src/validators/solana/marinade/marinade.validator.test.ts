@@ -0,0 +1,393 @@
+import {
+  ComputeBudgetProgram,
+  PublicKey,
+  SystemProgram,
+  Transaction,
+  TransactionInstruction,
+} from '@solana/web3.js';
+import { Shield } from '../../../shield';
+import { TransactionType } from '../../../types';
+
+const MARINADE_PROGRAM = new PublicKey(
+  'MarBmsSgKXdrN1egZf5sqe1TMai9K1rChYNDJgjq7aD',
+);
+const MARINADE_STATE = new PublicKey(
+  '8szGkuLTAux9XMgZ2vtY39jVSowEcpBfFfD8hXSEqdGC',
+);
+const MSOL_MINT = new PublicKey('mSoLzYCxHdYgdzU16g5QSh3i5K3z3KZK7ytfqcJm7So');
+const TOKEN_PROGRAM = new PublicKey(
+  'TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA',
+);
+const ATA_PROGRAM = new PublicKey(
+  'ATokenGPvbdGVxr1b2hvZbsiqW5xWH25efTNsLJA8knL',
+);
+
+const DISCRIMINATORS = {
+  deposit: 'f223c68952e1f2b6',
+  liquidUnstake: '1e1e77f0bfe30c10',
+  orderUnstake: '61a7906b75be8024',
+  claim: '3ec6d6c1d59f6cd2',
+};
+
+describe('MarinadeValidator via Shield', () => {
+  const shield = new Shield();
+  const yieldId = 'solana-sol-marinade-liquid-staking';
+  const userAddress = '29LDedMM8bYERotXSvUhBaXeWWdgi5kqwu4YBxAPLamy';
+  const userPubkey = new PublicKey(userAddress);
+  const [userMsolAccount] = PublicKey.findProgramAddressSync(
+    [userPubkey.toBuffer(), TOKEN_PROGRAM.toBuffer(), MSOL_MINT.toBuffer()],
+    ATA_PROGRAM,
+  );
+
+  const account = (pubkey: PublicKey, isSigner = false) => ({
+    pubkey,
+    isSigner,
+    isWritable: true,
+  });
+
+  const amountData = (discriminator: string, amount: bigint) => {
+    const data = Buffer.alloc(16);
+    Buffer.from(discriminator, 'hex').copy(data);
+    data.writeBigUInt64LE(amount, 8);
+    return data;
+  };
+
+  const deposit = (mintTo = userMsolAccount, state = MARINADE_STATE) =>
+    new TransactionInstruction({
+      programId: MARINADE_PROGRAM,
+      keys: [
+        account(state),
+        account(MSOL_MINT),
+        account(PublicKey.unique()), // liq_pool_sol_leg_pda
+        account(PublicKey.unique()), // liq_pool_msol_leg
+        account(PublicKey.unique()), // liq_pool_msol_leg_authority
+        account(PublicKey.unique()), // reserve_pda
+        account(userPubkey, true), // transfer_from
+        account(mintTo), // mint_to
+        account(PublicKey.unique()), // msol_mint_authority
+        account(SystemProgram.programId),
+        account(TOKEN_PROGRAM),
+      ],
+      data: amountData(DISCRIMINATORS.deposit, 1_000_000_000n),
+    });
+
+  const liquidUnstake = (transferSolTo = userPubkey) =>
+    new TransactionInstruction({
+      programId: MARINADE_PROGRAM,
+      keys: [
+        account(MARINADE_STATE),
+        account(MSOL_MINT),
+        account(PublicKey.unique()), // liq_pool_sol_leg_pda
+        account(PublicKey.unique()), // liq_pool_msol_leg
+        account(PublicKey.unique()), // treasury_msol_account
+        account(userMsolAccount), // get_msol_from
+        account(userPubkey, true), // get_msol_from_authority
+        account(transferSolTo), // transfer_sol_to
+        account(SystemProgram.programId),
+        account(TOKEN_PROGRAM),
+      ],
+      data: amountData(DISCRIMINATORS.liquidUnstake, 1_000_000_000n),
+    });
+
+  const orderUnstake = (ticket: PublicKey) =>
+    new TransactionInstruction({
+      programId: MARINADE_PROGRAM,
+      keys: [
+        account(MARINADE_STATE),
+        account(MSOL_MINT),
+        account(userMsolAccount), // burn_msol_from
+        account(userPubkey, true), // burn_msol_authority
+        account(ticket), // new_ticket_account
+        account(PublicKey.unique()), // clock
+        account(PublicKey.unique()), // rent
+        account(TOKEN_PROGRAM),
+      ],
+      data: amountData(DISCRIMINATORS.orderUnstake, 1_000_000_000n),
+    });
+
+  const claim = (transferSolTo = userPubkey) =>
+    new TransactionInstruction({
+      programId: MARINADE_PROGRAM,
+      keys: [
+        account(MARINADE_STATE),
+        account(PublicKey.unique()), // reserve_pda
+        account(PublicKey.unique()), // ticket_account
+        account(transferSolTo), // transfer_sol_to
+        account(PublicKey.unique()), // clock
+        account(SystemProgram.programId),
+      ],
+      data: Buffer.from(DISCRIMINATORS.claim, 'hex'),
+    });
+
+  const createUserMsolAccount = (payer = userPubkey) =>
+    new TransactionInstruction({
+      programId: ATA_PROGRAM,
+      keys: [
+        account(payer, true),
+        account(userMsolAccount),
+        account(userPubkey),
+        account(MSOL_MINT),
+        account(SystemProgram.programId),
+        account(TOKEN_PROGRAM),
+      ],
+      data: Buffer.from([1]), // CreateIdempotent
+    });
+
+  const buildTransaction = (...instructions: TransactionInstruction[]) => {
+    const transaction = new Transaction();
+    transaction.add(
+      ComputeBudgetProgram.setComputeUnitLimit({ units: 200000 }),
+    );
+    transaction.add(
+      ComputeBudgetProgram.setComputeUnitPrice({ microLamports: 1 }),
+    );
+    transaction.add(...instructions);
+    transaction.recentBlockhash = '11111111111111111111111111111111';
+    transaction.feePayer = userPubkey;
+    return transaction;
+  };
+
+  const toBase64Message = (transaction: Transaction) =>
+    transaction.serializeMessage().toString('base64');
+
+  const validate = (unsignedTransaction: string) =>
+    shield.validate({ yieldId, unsignedTransaction, userAddress });
+
+  const attemptReasons = (result: ReturnType<typeof validate>) =>
+    result.details?.attempts?.map((attempt) => attempt.reason) ?? [];
+
+  describe('isSupported', () => {
+    it('should support solana-sol-marinade-liquid-staking yield', () => {
+      expect(shield.isSupported(yieldId)).toBe(true);
+      expect(shield.getSupportedYieldIds()).toContain(yieldId);
+    });
+  });
+
+  describe('transaction encodings', () => {
+    it('should accept a base64 transaction message', () => {
+      const result = validate(toBase64Message(buildTransaction(deposit())));
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.STAKE);
+    });
+
+    it('should accept a base64 wire transaction', () => {
+      const serialized = buildTransaction(deposit()).serialize({
+        requireAllSignatures: false,
+        verifySignatures: false,
+      });
+
+      const result = validate(serialized.toString('base64'));
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.STAKE);
+    });
+
+    it('should accept a hex wire transaction', () => {
+      const serialized = buildTransaction(deposit()).serialize({
+        requireAllSignatures: false,
+        verifySignatures: false,
+      });
+
+      const result = validate(serialized.toString('hex'));
+
+      expect(result.isValid).toBe(true);
+    });
+
+    it('should reject input that is not a Solana transaction', () => {
+      const result = validate('not a transaction!');
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain(
+        'Failed to decode Solana transaction',
+      );
+    });
+  });
+
+  describe('STAKE validation', () => {
+    it('should surface the decoded instruction list', () => {
+      const result = validate(toBase64Message(buildTransaction(deposit())));
+
+      expect(result.isValid).toBe(true);
+      const instructions = result.decoded?.instructions ?? [];
+      expect(instructions.map((i) => i.instructionType)).toEqual([
+        'SetComputeUnitLimit',
+        'SetComputeUnitPrice',
+        'Deposit',
+      ]);
+      expect(instructions[2].program).toBe('Marinade');
+      expect(instructions[2].accounts[6]).toBe(userAddress);
+    });
+
+    it('should allow creating the user mSOL account', () => {
+      const result = validate(
+        toBase64Message(buildTransaction(createUserMsolAccount(), deposit())),
+      );
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.STAKE);
+    });
+
+    it('should reject mSOL minted to another account', () => {
+      const result = validate(
+        toBase64Message(buildTransaction(deposit(PublicKey.unique()))),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain(
+        'mSOL recipient is not the user token account',
+      );
+    });
+
+    it('should reject a token account creation paid by someone else', () => {
+      const result = validate(
+        toBase64Message(
+          buildTransaction(
+            createUserMsolAccount(PublicKey.unique()),
+            deposit(),
+          ),
+        ),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain(
+        'Token account creation not paid by user address',
+      );
+    });
+
+    it('should reject a different Marinade state account', () => {
+      const result = validate(
+        toBase64Message(
+          buildTransaction(deposit(userMsolAccount, PublicKey.unique())),
+        ),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain(
+        'Marinade state account does not match',
+      );
+    });
+  });
+
+  describe('unexpected instructions', () => {
+    it('should reject instructions to unexpected programs', () => {
+      const foreign = new TransactionInstruction({
+        programId: PublicKey.unique(),
+        keys: [account(userPubkey, true)],
+        data: Buffer.from([0]),
+      });
+
+      const result = validate(
+        toBase64Message(buildTransaction(deposit(), foreign)),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain(
+        'Transaction contains instructions for unexpected programs',
+      );
+    });
+
+    it('should reject a hidden SOL transfer', () => {
+      const transfer = SystemProgram.transfer({
+        fromPubkey: userPubkey,
+        toPubkey: PublicKey.unique(),
+        lamports: 1_000_000_000,
+      });
+
+      const result = validate(
+        toBase64Message(buildTransaction(deposit(), transfer)),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain(
+        'Unexpected instruction in Marinade transaction',
+      );
+    });
+
+    it('should reject more than one Marinade instruction', () => {
+      const result = validate(
+        toBase64Message(buildTransaction(deposit(), deposit())),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain(
+        'Expected exactly one Marinade instruction',
+      );
+    });
+  });
+
+  describe('UNSTAKE validation', () => {
+    it('should validate a liquid unstake', () => {
+      const result = validate(
+        toBase64Message(buildTransaction(liquidUnstake())),
+      );
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.UNSTAKE);
+    });
+
+    it('should reject a liquid unstake paying out to another address', () => {
+      const result = validate(
+        toBase64Message(buildTransaction(liquidUnstake(PublicKey.unique()))),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain(
+        'Unstake recipient is not user address',
+      );
+    });
+
+    it('should validate a delayed unstake with its ticket account', () => {
+      const ticket = PublicKey.unique();
+      const createTicket = SystemProgram.createAccount({
+        fromPubkey: userPubkey,
+        newAccountPubkey: ticket,
+        lamports: 1_503_360,
+        space: 88,
+        programId: MARINADE_PROGRAM,
+      });
+
+      const result = validate(
+        toBase64Message(buildTransaction(createTicket, orderUnstake(ticket))),
+      );
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.UNSTAKE);
+    });
+  });
+
+  describe('CLAIM_UNSTAKED validation', () => {
+    it('should validate a ticket claim', () => {
+      const result = validate(toBase64Message(buildTransaction(claim())));
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.CLAIM_UNSTAKED);
+    });
+
+    it('should reject a claim paying out to another address', () => {
+      const result = validate(
+        toBase64Message(buildTransaction(claim(PublicKey.unique()))),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain(
+        'Claim recipient is not user address',
+      );
+    });
+  });
+
+  describe('decode', () => {
+    it('should decode the instruction list and detected type', () => {
+      const result = shield.decode({
+        yieldId,
+        unsignedTransaction: toBase64Message(buildTransaction(deposit())),
+      });
+
+      expect(result.decoded?.instructions).toHaveLength(3);
+      expect(result.decoded?.instructions?.[2].instructionType).toBe(
+        'Deposit',
+      );
+      expect(result.decoded?.detectedType).toBe(TransactionType.STAKE);
+    });
+  });
+});
@@ -0,0 +1,237 @@
+import {
+  ActionArguments,
+  TransactionType,
+  ValidationContext,
+  ValidationResult,
+  ValidatorCapabilities,
+} from '../../../types';
+import {
+  BaseSolanaValidator,
+  SOLANA_CHAIN_ID,
+  SOLANA_PROGRAMS,
+  SolanaInstruction,
+} from '../base.validator';
+
+const MARINADE_STATE = '8szGkuLTAux9XMgZ2vtY39jVSowEcpBfFfD8hXSEqdGC';
+const MSOL_MINT = 'mSoLzYCxHdYgdzU16g5QSh3i5K3z3KZK7ytfqcJm7So';
+
+const ALLOWED_PROGRAMS = [
+  SOLANA_PROGRAMS.computeBudget,
+  SOLANA_PROGRAMS.associatedToken,
+  SOLANA_PROGRAMS.system,
+  SOLANA_PROGRAMS.marinade,
+];
+
+/**
+ * Marinade liquid staking (SOL <-> mSOL)
+ *
+ * Transaction Types Validated:
+ * - STAKE: deposit SOL, minting mSOL to the user's token account
+ * - UNSTAKE: liquid_unstake through the pool, or order_unstake (delayed)
+ * - CLAIM_UNSTAKED: claim the SOL of a matured order_unstake ticket
+ */
+export class MarinadeValidator extends BaseSolanaValidator {
+  getSupportedTransactionTypes(): TransactionType[] {
+    return [
+      TransactionType.STAKE,
+      TransactionType.UNSTAKE,
+      TransactionType.CLAIM_UNSTAKED,
+    ];
+  }
+
+  getCapabilities(): ValidatorCapabilities {
+    return {
+      name: 'Marinade',
+      protocol: 'marinade',
+      network: 'solana',
+      supportsPartialAmounts: true,
+      chainId: SOLANA_CHAIN_ID,
+      contracts: [SOLANA_PROGRAMS.marinade, MARINADE_STATE],
+    };
+  }
+
+  // The Claim instruction pays the ticket's SOL to its fourth account
+  getClaimCall(unsignedTransaction: string): ClaimCall | undefined {
+    const decoded = this.decodeSolanaTransaction(unsignedTransaction);
+    const claim = decoded.instructions?.find(
+      (instruction) =>
+        instruction.programId === SOLANA_PROGRAMS.marinade &&
+        instruction.instructionType === 'Claim',
+    );
+    return claim
+      ? { variant: 'Claim', recipient: this.accountAt(claim, 3) }
+      : undefined;
+  }
+
+  validate(
+    unsignedTransaction: string,
+    transactionType: TransactionType,
+    userAddress: string,
+    _args?: ActionArguments,
+    _context?: ValidationContext,
+  ): ValidationResult {
+    const decoded = this.decodeSolanaTransaction(unsignedTransaction);
+    if (!decoded.isValid) {
+      return this.blocked('Failed to decode Solana transaction', {
+        error: decoded.error,
+      });
+    }
+
+    const instructions = decoded.instructions!;
+
+    const programErr = this.ensureOnlyPrograms(instructions, ALLOWED_PROGRAMS);
+    if (programErr) return programErr;
+
+    const marinadeInstructions = instructions.filter(
+      (instruction) => instruction.programId === SOLANA_PROGRAMS.marinade,
+    );
+    if (marinadeInstructions.length !== 1) {
+      return this.blocked('Expected exactly one Marinade instruction', {
+        actual: marinadeInstructions.length,
+      });
+    }
+
+    const marinade = marinadeInstructions[0];
+    if (this.accountAt(marinade, 0) !== MARINADE_STATE) {
+      return this.blocked('Marinade state account does not match', {
+        expected: MARINADE_STATE,
+        actual: this.accountAt(marinade, 0),
+      });
+    }
+
+    const helperErr = this.validateHelperInstructions(
+      instructions,
+      marinade,
+      userAddress,
+    );
+    if (helperErr) return helperErr;
+
+    switch (transactionType) {
+      case TransactionType.STAKE:
+        return this.validateStake(instructions, marinade, userAddress);
+      case TransactionType.UNSTAKE:
+        return this.validateUnstake(instructions, marinade, userAddress);
+      case TransactionType.CLAIM_UNSTAKED:
+        return this.validateClaim(instructions, marinade, userAddress);
+      default:
+        return this.blocked('Unsupported transaction type', {
+          transactionType,
+        });
+    }
+  }
+
+  private validateStake(
+    instructions: SolanaInstruction[],
+    deposit: SolanaInstruction,
+    userAddress: string,
+  ): ValidationResult {
+    if (deposit.instructionType !== 'Deposit') {
+      return this.blocked('Missing or invalid Deposit instruction');
+    }
+    if (this.accountAt(deposit, 1) !== MSOL_MINT) {
+      return this.blocked('Deposit mint is not mSOL');
+    }
+    if (this.accountAt(deposit, 6) !== userAddress) {
+      return this.blocked('Deposit source is not user address');
+    }
+
+    const userTokenAccount = this.getAssociatedTokenAddress(
+      userAddress,
+      MSOL_MINT,
+    );
+    if (this.accountAt(deposit, 7) !== userTokenAccount) {
+      return this.blocked('mSOL recipient is not the user token account', {
+        expected: userTokenAccount,
+        actual: this.accountAt(deposit, 7),
+      });
+    }
+
+    return this.safeWithInstructions(instructions);
+  }
+
+  private validateUnstake(
+    instructions: SolanaInstruction[],
+    unstake: SolanaInstruction,
+    userAddress: string,
+  ): ValidationResult {
+    if (this.accountAt(unstake, 1) !== MSOL_MINT) {
+      return this.blocked('Unstake mint is not mSOL');
+    }
+
+    switch (unstake.instructionType) {
+      case 'LiquidUnstake':
+        if (this.accountAt(unstake, 6) !== userAddress) {
+          return this.blocked('Unstake authority is not user address');
+        }
+        if (this.accountAt(unstake, 7) !== userAddress) {
+          return this.blocked('Unstake recipient is not user address');
+        }
+        return this.safeWithInstructions(instructions);
+      case 'OrderUnstake':
+        if (this.accountAt(unstake, 3) !== userAddress) {
+          return this.blocked('Unstake authority is not user address');
+        }
+        return this.safeWithInstructions(instructions);
+      default:
+        return this.blocked('Missing or invalid unstake instruction', {
+          actual: unstake.instructionType,
+        });
+    }
+  }
+
+  private validateClaim(
+    instructions: SolanaInstruction[],
+    claim: SolanaInstruction,
+    userAddress: string,
+  ): ValidationResult {
+    if (claim.instructionType !== 'Claim') {
+      return this.blocked('Missing or invalid Claim instruction');
+    }
+    if (this.accountAt(claim, 3) !== userAddress) {
+      return this.blocked('Claim recipient is not user address');
+    }
+
+    return this.safeWithInstructions(instructions);
+  }
+
+  /**
+   * Everything around the Marinade instruction must be compute budget
+   * settings, the user's own mSOL account creation, or the ticket account
+   * creation of an order_unstake.
+   */
+  private validateHelperInstructions(
+    instructions: SolanaInstruction[],
+    marinade: SolanaInstruction,
+    userAddress: string,
+  ): ValidationResult | null {
+    for (const instruction of instructions) {
+      if (instruction === marinade) continue;
+      if (this.isComputeBudgetInstruction(instruction)) continue;
+
+      if (instruction.programId === SOLANA_PROGRAMS.associatedToken) {
+        const tokenErr = this.ensureUserTokenAccountCreation(
+          instruction,
+          userAddress,
+          MSOL_MINT,
+        );
+        if (tokenErr) return tokenErr;
+        continue;
+      }
+
+      if (
+        marinade.instructionType === 'OrderUnstake' &&
+        this.isSystemInstruction(instruction, 'CreateAccount') &&
+        this.accountAt(instruction, 0) === userAddress &&
+        this.accountAt(instruction, 1) === this.accountAt(marinade, 4)
+      ) {
+        continue;
+      }
+
+      return this.blocked('Unexpected instruction in Marinade transaction', {
+        programId: instruction.programId,
+        instructionType: instruction.instructionType,
+      });
+    }
+    return null;
+  }
+}
@@ -1,9 +1,14 @@
 import {
+  AddressLookupTableAccount,
   ComputeBudgetProgram,
+  Keypair,
   PublicKey,
   StakeProgram,
+  SYSVAR_CLOCK_PUBKEY,
   SystemProgram,
   Transaction,
+  TransactionMessage,
+  VersionedTransaction,
 } from '@solana/web3.js';
 import { Shield } from '../../../shield';
 import { TransactionType } from '../../../types';
@@ -3015,4 +3020,144 @@ describe('SolanaNativeStakingValidator via Shield', () => {
       expect(result.reason).toContain('No matching operation pattern found');
     });
   });
+
+  describe('signed transactions', () => {
+    const payer = Keypair.generate();
+
+    const signedTx = (signer: Keypair) => {
+      const transaction = new Transaction().add(
+        ComputeBudgetProgram.setComputeUnitLimit({ units: 350000 }),
+      );
+      transaction.recentBlockhash = '11111111111111111111111111111111';
+      transaction.feePayer = signer.publicKey;
+      transaction.sign(signer);
+      return transaction.serialize();
+    };
+
+    const validateRaw = (rawTransaction: Buffer) =>
+      shield.validateRawTransaction({
+        yieldId,
+        rawTransaction: rawTransaction.toString('base64'),
+        userAddress: payer.publicKey.toBase58(),
+      });
+
+    it('should verify the fee payer and validate the transaction', () => {
+      const result = validateRaw(signedTx(payer));
+
+      expect(result.signatureValid).toBe(true);
+      expect(result.recoveredAddresses).toEqual([payer.publicKey.toBase58()]);
+      expect(result.reason).toContain('No matching operation pattern found');
+    });
+
+    it('should reject a fee payer other than userAddress', () => {
+      const other = Keypair.generate();
+      const result = validateRaw(signedTx(other));
+
+      expect(result.reasonCode).toBe('SIGNATURE_SENDER_MISMATCH');
+      expect(result.details?.actual).toBe(other.publicKey.toBase58());
+    });
+
+    it('should reject a signature that does not verify', () => {
+      const transaction = signedTx(payer);
+      transaction[1] ^= 0xff; // First byte of the fee payer's signature
+
+      const result = validateRaw(transaction);
+
+      expect(result.reasonCode).toBe('SIGNATURE_INVALID');
+      expect(result.signatureValid).toBe(false);
+    });
+  });
+
+  describe('versioned transactions', () => {
+    const userPubkey = new PublicKey(userAddress);
+    const stakeAccount = new PublicKey(
+      'HzcH95P8DJnmjWfNLKeWYrNSYuMrbAGcp6MhXwWfeezk',
+    );
+    const table = new AddressLookupTableAccount({
+      key: Keypair.generate().publicKey,
+      state: {
+        deactivationSlot: BigInt('0xffffffffffffffff'),
+        lastExtendedSlot: 0,
+        lastExtendedSlotStartIndex: 0,
+        addresses: [stakeAccount, SYSVAR_CLOCK_PUBKEY],
+      },
+    });
+    const tableAddress = table.key.toBase58();
+
+    // An UNSTAKE, loading its stake account from table when given
+    const unstakeTx = (tables: AddressLookupTableAccount[] = []) => {
+      const message = new TransactionMessage({
+        payerKey: userPubkey,
+        recentBlockhash: '11111111111111111111111111111111',
+        instructions: [
+          ComputeBudgetProgram.setComputeUnitLimit({ units: 350000 }),
+          ComputeBudgetProgram.setComputeUnitPrice({ microLamports: 1 }),
+          StakeProgram.deactivate({
+            stakePubkey: stakeAccount,
+            authorizedPubkey: userPubkey,
+          }).instructions[0],
+        ],
+      }).compileToV0Message(tables);
+      const serialized = new VersionedTransaction(message).serialize();
+      return Buffer.from(serialized).toString('base64');
+    };
+
+    it('should validate a v0 transaction without lookup tables', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: unstakeTx(),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.UNSTAKE);
+    });
+
+    it('should require the lookup tables a transaction loads from', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: unstakeTx([table]),
+        userAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('ALT_RESOLUTION_REQUIRED');
+      expect(result.details).toMatchObject({
+        lookupTables: [tableAddress],
+        missing: [tableAddress],
+      });
+    });
+
+    it('should validate the accounts loaded from given tables', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: unstakeTx([table]),
+        userAddress,
+        addressLookupTables: {
+          [tableAddress]: [
+            stakeAccount.toBase58(),
+            SYSVAR_CLOCK_PUBKEY.toBase58(),
+          ],
+        },
+      });
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.UNSTAKE);
+      expect(result.decoded?.instructions?.[2].accounts).toContain(
+        stakeAccount.toBase58(),
+      );
+    });
+
+    it('should not validate with a table too short for its indexes', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: unstakeTx([table]),
+        userAddress,
+        addressLookupTables: { [tableAddress]: [stakeAccount.toBase58()] },
+      });
+
+      expect(result.reasonCode).toBe('ALT_RESOLUTION_REQUIRED');
+      expect(result.details).toMatchObject({ missing: [] });
+    });
+  });
 });
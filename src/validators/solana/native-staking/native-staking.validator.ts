@@ -1,27 +1,20 @@
-import {
-  AccountMeta,
-  PublicKey,
-  StakeInstruction,
-  Transaction,
-  TransactionInstruction,
-} from '@solana/web3.js';
+import { PublicKey, StakeInstruction } from '@solana/web3.js';
 import {
   ActionArguments,
   TransactionType,
   ValidationContext,
   ValidationResult,
+  ValidatorCapabilities,
 } from '../../../types';
 import { isNonEmptyString, isNullOrUndefined } from '../../../utils/validation';
-import { BaseValidator } from '../../base.validator';
-
-type DecodedInstruction = {
-  programId: string;
-  instructionType: string; // Decoded from data[0]
-  data: Buffer;
-  accounts: Array<AccountMeta>;
-};
+import {
+  BaseSolanaValidator,
+  SOLANA_CHAIN_ID,
+  SOLANA_PROGRAMS,
+  SolanaInstruction,
+} from '../base.validator';
 
-export class SolanaNativeStakingValidator extends BaseValidator {
+export class SolanaNativeStakingValidator extends BaseSolanaValidator {
   getSupportedTransactionTypes(): TransactionType[] {
     return [
       TransactionType.STAKE,
@@ -31,6 +24,18 @@ export class SolanaNativeStakingValidator extends BaseValidator {
       TransactionType.SPLIT,
     ];
   }
+
+  // Partial exits split the stake account before deactivating it
+  getCapabilities(): ValidatorCapabilities {
+    return {
+      name: 'Solana native staking',
+      protocol: 'native',
+      network: 'solana',
+      supportsPartialAmounts: true,
+      chainId: SOLANA_CHAIN_ID,
+      contracts: [SOLANA_PROGRAMS.stake, SOLANA_PROGRAMS.system],
+    };
+  }
   validate(
     unsignedTransaction: string,
     transactionType: TransactionType,
@@ -66,7 +71,7 @@ export class SolanaNativeStakingValidator extends BaseValidator {
   }
 
   private validateStake(
-    instructions: DecodedInstruction[],
+    instructions: SolanaInstruction[],
     userAddress: string,
     args?: ActionArguments,
   ): ValidationResult {
@@ -158,11 +163,11 @@ export class SolanaNativeStakingValidator extends BaseValidator {
       }
     }
 
-    return this.safe();
+    return this.safeWithInstructions(instructions);
   }
 
   private validateUnstake(
-    instructions: DecodedInstruction[],
+    instructions: SolanaInstruction[],
     userAddress: string,
   ): ValidationResult {
     const minInstructions = 3;
@@ -206,11 +211,11 @@ export class SolanaNativeStakingValidator extends BaseValidator {
       }
     }
 
-    return this.safe();
+    return this.safeWithInstructions(instructions);
   }
 
   private validateWithdraw(
-    instructions: DecodedInstruction[],
+    instructions: SolanaInstruction[],
     userAddress: string,
   ): ValidationResult {
     if (instructions.length !== 3) {
@@ -242,11 +247,11 @@ export class SolanaNativeStakingValidator extends BaseValidator {
       return this.blocked('Withdraw authority is not user address');
     }
 
-    return this.safe();
+    return this.safeWithInstructions(instructions);
   }
 
   private validateWithdrawAll(
-    instructions: DecodedInstruction[],
+    instructions: SolanaInstruction[],
     userAddress: string,
   ): ValidationResult {
     if (instructions.length < 4) {
@@ -280,11 +285,11 @@ export class SolanaNativeStakingValidator extends BaseValidator {
       }
     }
 
-    return this.safe();
+    return this.safeWithInstructions(instructions);
   }
 
   private validateSplit(
-    instructions: DecodedInstruction[],
+    instructions: SolanaInstruction[],
     userAddress: string,
   ): ValidationResult {
     if (instructions.length !== 6) {
@@ -372,41 +377,11 @@ export class SolanaNativeStakingValidator extends BaseValidator {
       return this.blocked('Deactivate authority is not user address');
     }
 
-    return this.safe();
-  }
-
-  private isComputeBudgetInstruction(
-    instruction: DecodedInstruction,
-    expectedType: string,
-  ): boolean {
-    return (
-      instruction.programId === 'ComputeBudget111111111111111111111111111111' &&
-      instruction.instructionType === expectedType
-    );
-  }
-
-  private isSystemInstruction(
-    instruction: DecodedInstruction,
-    expectedType: string,
-  ): boolean {
-    return (
-      instruction.programId === '11111111111111111111111111111111' &&
-      instruction.instructionType === expectedType
-    );
-  }
-
-  private isStakeInstruction(
-    instruction: DecodedInstruction,
-    expectedType: string,
-  ): boolean {
-    return (
-      instruction.programId === 'Stake11111111111111111111111111111111111111' &&
-      instruction.instructionType === expectedType
-    );
+    return this.safeWithInstructions(instructions);
   }
 
   private isStakeAccountAuthorizationInstruction(
-    instruction: DecodedInstruction,
+    instruction: SolanaInstruction,
     expectedWithdrawer: string,
   ): boolean {
     const initializeInstruction = StakeInstruction.decodeInitialize({
@@ -422,109 +397,4 @@ export class SolanaNativeStakingValidator extends BaseValidator {
         expectedWithdrawer
     );
   }
-
-  private getSolanaInstructionType(
-    instruction: TransactionInstruction,
-  ): string {
-    const programId = instruction.programId.toBase58();
-    const data = instruction.data;
-    if (
-      programId === 'Stake11111111111111111111111111111111111111' &&
-      data.length > 0
-    ) {
-      switch (data[0]) {
-        case 0:
-          return 'Initialize';
-        case 1:
-          return 'Authorize';
-        case 2:
-          return 'Delegate';
-        case 3:
-          return 'Split';
-        case 4:
-          return 'Withdraw';
-        case 5:
-          return 'Deactivate';
-        case 10:
-          return 'CreateAccountWithSeed';
-        default:
-          return 'Unknown';
-      }
-    } else if (
-      programId === '11111111111111111111111111111111' &&
-      data.length > 0
-    ) {
-      switch (data[0]) {
-        case 0:
-          return 'CreateAccount';
-        case 1:
-          return 'Assign';
-        case 2:
-          return 'Transfer';
-        case 3:
-          return 'CreateAccountWithSeed';
-        case 8:
-          return 'Allocate';
-        case 9:
-          return 'AllocateWithSeed';
-        default:
-          return 'Unknown';
-      }
-    } else if (
-      programId === 'ComputeBudget111111111111111111111111111111' &&
-      data.length > 0
-    ) {
-      switch (data[0]) {
-        case 0:
-          return 'RequestUnitsDeprecated';
-        case 1:
-          return 'RequestHeapFrame';
-        case 2:
-          return 'SetComputeUnitLimit';
-        case 3:
-          return 'SetComputeUnitPrice';
-        default:
-          return 'Unknown';
-      }
-    }
-
-    return 'Unknown';
-  }
-
-  private decodeSolanaTransaction(hexString: string): {
-    isValid: boolean;
-    instructions?: DecodedInstruction[];
-    error?: string;
-  } {
-    try {
-      const buffer = Buffer.from(hexString, 'hex');
-      const tx = Transaction.from(buffer);
-
-      const instructions = tx.instructions.map((instruction) => {
-        const programId = instruction.programId.toBase58();
-        const instructionType = this.getSolanaInstructionType(instruction);
-
-        return {
-          programId,
-          instructionType,
-          data: instruction.data,
-          accounts: instruction.keys.map((key) => ({
-            pubkey: key.pubkey,
-            isSigner: key.isSigner,
-            isWritable: key.isWritable,
-          })),
-        };
-      });
-
-      return {
-        isValid: true,
-        instructions,
-      };
-    } catch (error) {
-      return {
-        isValid: false,
-        error: error instanceof Error ? error.message : String(error),
-      };
-    }
-  }
 }
@@ -0,0 +1,2 @@
+export { SubstrateStakingValidator } from './native-staking/native-staking.validator';
+export type { SubstrateChainConfig } from './native-staking/native-staking.validator';
@@ -0,0 +1,297 @@
+import { Shield } from '../../../shield';
+import { TransactionType } from '../../../types';
+
+// Minimal SCALE encoder for building Polkadot extrinsic fixtures
+const compact = (value: bigint | number): Buffer => {
+  const n = BigInt(value);
+  if (n < 1n << 6n) return Buffer.from([Number(n) << 2]);
+  if (n < 1n << 14n) {
+    const bytes = Buffer.alloc(2);
+    bytes.writeUInt16LE((Number(n) << 2) | 1);
+    return bytes;
+  }
+  if (n < 1n << 30n) {
+    const bytes = Buffer.alloc(4);
+    bytes.writeUInt32LE(((Number(n) << 2) | 2) >>> 0);
+    return bytes;
+  }
+  let hex = n.toString(16);
+  if (hex.length % 2) hex = '0' + hex;
+  const bytes = Buffer.from(hex, 'hex').reverse();
+  return Buffer.concat([Buffer.from([((bytes.length - 4) << 2) | 3]), bytes]);
+};
+const call = (pallet: number, index: number, ...args: Buffer[]) =>
+  Buffer.concat([Buffer.from([pallet, index]), ...args]);
+const id = (accountId: string) =>
+  Buffer.concat([Buffer.from([0]), Buffer.from(accountId, 'hex')]);
+const vec = (items: Buffer[]) =>
+  Buffer.concat([compact(items.length), ...items]);
+
+const bond = (value: bigint, payee = Buffer.from([0])) =>
+  call(7, 0, compact(value), payee);
+const bondExtra = (value: bigint) => call(7, 1, compact(value));
+const unbond = (value: bigint) => call(7, 2, compact(value));
+const withdrawUnbonded = () => call(7, 3, Buffer.alloc(4));
+const nominate = (...targets: string[]) => call(7, 5, vec(targets.map(id)));
+const chill = () => call(7, 6);
+const rebond = (value: bigint) => call(7, 19, compact(value));
+const batchAll = (...calls: Buffer[]) => call(26, 2, vec(calls));
+const transferKeepAlive = (to: string, value: bigint) =>
+  call(5, 3, id(to), compact(value));
+
+const unsignedExtrinsic = (method: Buffer) => {
+  const body = Buffer.concat([Buffer.from([0x04]), method]);
+  return '0x' + Buffer.concat([compact(body.length), body]).toString('hex');
+};
+
+describe('SubstrateStakingValidator via Shield', () => {
+  const shield = new Shield();
+  const yieldId = 'dot-dot-native-staking';
+  const genesisHash =
+    '0x91b171bb158e2d3848fa23a9f1c25182fb8e20313b2c1eb49219da7a70ce90c3';
+
+  // Alice, Bob, Charlie and Dave of the Substrate dev accounts
+  const userAccountId =
+    'd43593c715fdd31c61141abd04a99fd6822c8558854ccde39a5684e7a56da27d';
+  const userAddress = '15oF4uVJwmo4TdGW7VfQxNLavjCXviqxT9S1MgbjMNHr6Sp5';
+  const validatorId =
+    '8eaf04151687736326c9fea17e25fc5287613693c912909cb226aa4794f26a48';
+  const validatorAddress = '14E5nqKAp3oAJcmzgZhUD2RcptBeUBScxKHgJKU4HPNcKVf3';
+  const otherValidatorId =
+    '90b5ab205c6974c9ea841be688864633dc9ca8a357843eeacf2314649965fe22';
+  const otherValidator = '14Gjs1TD93gnwEBfDMHoCgsuf1s2TVKUP6Z1qKmAZnZ8cW5q';
+  const strangerId =
+    '306721211d5404bd9da88e0204360a1a9ab8b87c66c1bc2fcdd37f3c2222cc20';
+
+  const tenDot = 100000000000n;
+
+  const signerPayload = (
+    method: Buffer,
+    overrides: Record<string, unknown> = {},
+  ) =>
+    JSON.stringify({
+      address: userAddress,
+      blockHash:
+        '0x1b8a2e2a4e8f0b3c3d2f7b6a0d6c3f27ed1b4f0ac8e4e0e7c5a3d3b1b2a1c0d9',
+      blockNumber: '0x015a8f3e',
+      era: '0xe503',
+      genesisHash,
+      method: '0x' + method.toString('hex'),
+      nonce: '0x00000004',
+      specVersion: '0x000f4628',
+      tip: '0x00000000000000000000000000000000',
+      transactionVersion: '0x0000001a',
+      signedExtensions: ['CheckNonZeroSender', 'CheckMortality'],
+      version: 4,
+      ...overrides,
+    });
+
+  const validate = (
+    unsignedTransaction: string,
+    args?: { validatorAddress?: string; validatorAddresses?: string[] },
+  ) => shield.validate({ yieldId, unsignedTransaction, userAddress, args });
+
+  const attemptReasons = (result: ReturnType<typeof validate>) =>
+    result.details?.attempts?.map((attempt) => attempt.reason) ?? [];
+
+  describe('isSupported', () => {
+    it('should support dot-dot-native-staking yield', () => {
+      expect(shield.isSupported(yieldId)).toBe(true);
+      expect(shield.getYieldCapabilities(yieldId)?.chainId).toBe('polkadot');
+    });
+  });
+
+  describe('transaction encodings', () => {
+    it('should accept a polkadot.js signer payload', () => {
+      const result = validate(
+        signerPayload(batchAll(bond(tenDot), nominate(validatorId))),
+      );
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.STAKE);
+      expect(result.amount).toEqual({
+        token: 'native',
+        amount: '100000000000',
+        symbol: 'DOT',
+        decimals: 10,
+        normalized: '10.0',
+      });
+      expect(result.decoded?.calls).toEqual([
+        {
+          pallet: 'staking',
+          method: 'bond',
+          args: { value: '100000000000', payee: 'Staked' },
+        },
+        {
+          pallet: 'staking',
+          method: 'nominate',
+          args: { targets: [validatorAddress] },
+        },
+      ]);
+    });
+
+    it('should accept an unsigned extrinsic', () => {
+      const result = validate(unsignedExtrinsic(unbond(tenDot)));
+
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.UNSTAKE);
+    });
+
+    it('should read the signer of a signed extrinsic', () => {
+      const body = Buffer.concat([
+        Buffer.from([0x84]),
+        id(userAccountId),
+        Buffer.from([0x01]),
+        Buffer.alloc(64, 0xab), // Sr25519 signature
+        Buffer.from([0xe5, 0x03]), // Mortal era
+        compact(4), // Nonce
+        compact(0), // Tip
+        Buffer.from([0x00]), // Metadata hash disabled
+        withdrawUnbonded(),
+      ]);
+      const extrinsic =
+        '0x' + Buffer.concat([compact(body.length), body]).toString('hex');
+
+      const result = validate(extrinsic);
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.WITHDRAW);
+
+      const other = shield.validate({
+        yieldId,
+        unsignedTransaction: extrinsic,
+        userAddress: otherValidator,
+      });
+      expect(other.isValid).toBe(false);
+      expect(other.reasonCode).toBe('SENDER_MISMATCH');
+    });
+
+    it('should reject extrinsics whose length prefix is wrong', () => {
+      const extrinsic = unsignedExtrinsic(unbond(tenDot));
+
+      const result = validate(extrinsic + '00');
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('NO_MATCHING_PATTERN');
+    });
+
+    it('should reject signer payloads for another chain', () => {
+      const result = validate(
+        signerPayload(bond(tenDot), {
+          genesisHash:
+            '0xb0a8d493285c2df73290dfb7e61f870f17b41801197a149ca93654499ea3dafe',
+        }),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('CHAIN_ID_MISMATCH');
+    });
+  });
+
+  describe('transaction types', () => {
+    it('should detect each staking call', () => {
+      const cases = [
+        [bondExtra(tenDot), TransactionType.STAKE],
+        [nominate(validatorId, otherValidatorId), TransactionType.VOTE],
+        [batchAll(chill(), unbond(tenDot)), TransactionType.UNSTAKE],
+        [withdrawUnbonded(), TransactionType.WITHDRAW],
+        [rebond(tenDot), TransactionType.REBOND],
+      ] as const;
+      for (const [method, detectedType] of cases) {
+        const result = validate(signerPayload(method));
+
+        expect(result.isValid).toBe(true);
+        expect(result.detectedType).toBe(detectedType);
+      }
+    });
+
+    it('should reject calls outside the staking and utility pallets', () => {
+      const cases = [
+        transferKeepAlive(strangerId, tenDot),
+        batchAll(bond(tenDot), transferKeepAlive(strangerId, tenDot)),
+      ];
+      for (const method of cases) {
+        const result = validate(signerPayload(method));
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('PALLET_NOT_ALLOWED');
+      }
+    });
+
+    it('should reject staking calls of another transaction type', () => {
+      const result = validate(
+        signerPayload(batchAll(bond(tenDot), unbond(tenDot))),
+      );
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain('Unexpected call');
+    });
+  });
+
+  describe('amounts and rewards', () => {
+    it('should reject bonding nothing', () => {
+      const result = validate(signerPayload(bond(0n)));
+
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain('Invalid amount');
+    });
+
+    it('should check the bonded amount against expectedAmount', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: signerPayload(bond(tenDot)),
+        userAddress,
+        expectedAmount: '200000000000',
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('AMOUNT_MISMATCH');
+    });
+
+    it('should only pay rewards to the user', () => {
+      const account = (accountId: string) =>
+        Buffer.concat([Buffer.from([3]), Buffer.from(accountId, 'hex')]);
+      const cases = [
+        [Buffer.from([1]), true], // Stash
+        [account(userAccountId), true],
+        [account(strangerId), false],
+        [Buffer.from([4]), false], // None
+      ] as const;
+      for (const [payee, isValid] of cases) {
+        const result = validate(signerPayload(bond(tenDot, payee)));
+
+        expect(result.isValid).toBe(isValid);
+      }
+    });
+  });
+
+  describe('nominations', () => {
+    it('should restrict nominations to the expected validators', () => {
+      const method = batchAll(bond(tenDot), nominate(validatorId));
+
+      const expected = validate(signerPayload(method), { validatorAddress });
+      expect(expected.isValid).toBe(true);
+
+      const result = validate(signerPayload(method), {
+        validatorAddress: otherValidator,
+      });
+      expect(result.isValid).toBe(false);
+      expect(attemptReasons(result)).toContain(
+        'Nominates an unexpected validator',
+      );
+    });
+
+    it('should reject too many or duplicate nominations', () => {
+      const cases = [
+        nominate(...Array(17).fill(validatorId)),
+        nominate(validatorId, validatorId),
+        nominate(),
+      ];
+      for (const method of cases) {
+        const result = validate(signerPayload(method));
+
+        expect(result.isValid).toBe(false);
+        expect(result.reasonCode).toBe('NO_MATCHING_PATTERN');
+      }
+    });
+  });
+});
@@ -0,0 +1,326 @@
+import {
+  ActionArguments,
+  DecodedCall,
+  DecodeResult,
+  ReasonCode,
+  TransactionAmount,
+  TransactionType,
+  ValidationContext,
+  ValidationResult,
+  ValidatorCapabilities,
+} from '../../../types';
+import {
+  isDefined,
+  isNonEmptyString,
+  isNullOrUndefined,
+} from '../../../utils/validation';
+import { toTransactionAmount } from '../../../utils/amount';
+import { BaseValidator } from '../../base.validator';
+import {
+  PalletNotAllowedError,
+  SubstrateRuntime,
+  SubstrateTransaction,
+  decodeSs58,
+  decodeSubstrateTransaction,
+} from '../tx-decoder';
+
+export interface SubstrateChainConfig {
+  chainId: string; // e.g. 'polkadot'
+  name: string; // Network display name, e.g. 'Polkadot'
+  network: string; // e.g. 'polkadot'
+  genesisHash: string; // Lowercase hex, as in signer payloads
+  ss58Prefix: number; // e.g. 0 for Polkadot
+  symbol: string; // e.g. 'DOT'
+  decimals: number; // Exponent of symbol over planck, e.g. 10
+  maxNominations: number; // The runtime's MaxNominations
+  runtime: SubstrateRuntime;
+}
+
+// The staking calls each transaction type is made of: at least one of
+// required, and nothing outside allowed
+const TRANSACTION_CALLS: Partial<
+  Record<TransactionType, { required: string[]; allowed: string[] }>
+> = {
+  [TransactionType.STAKE]: {
+    required: ['bond', 'bond_extra'],
+    allowed: ['bond', 'bond_extra', 'nominate', 'set_payee'],
+  },
+  [TransactionType.VOTE]: { required: ['nominate'], allowed: ['nominate'] },
+  [TransactionType.UNSTAKE]: {
+    required: ['unbond'],
+    allowed: ['chill', 'unbond'],
+  },
+  [TransactionType.WITHDRAW]: {
+    required: ['withdraw_unbonded'],
+    allowed: ['withdraw_unbonded'],
+  },
+  [TransactionType.REBOND]: { required: ['rebond'], allowed: ['rebond'] },
+};
+
+const AMOUNT_CALLS = ['bond', 'bond_extra', 'unbond', 'rebond'];
+
+/**
+ * Native Substrate staking (pallet_staking), e.g. Polkadot nomination
+ *
+ * Transaction Types Validated:
+ * - STAKE: bond or bond_extra, optionally batched with nominate
+ * - VOTE: nominate
+ * - UNSTAKE: unbond, optionally batched with chill
+ * - WITHDRAW: withdraw_unbonded
+ * - REBOND: rebond
+ *
+ * Calls may be wrapped in a utility batch, batch_all or force_batch; any
+ * call to another pallet is rejected.
+ */
+export class SubstrateStakingValidator extends BaseValidator {
+  constructor(private readonly config: SubstrateChainConfig) {
+    super();
+  }
+
+  getSupportedTransactionTypes(): TransactionType[] {
+    return [
+      TransactionType.STAKE,
+      TransactionType.VOTE,
+      TransactionType.UNSTAKE,
+      TransactionType.WITHDRAW,
+      TransactionType.REBOND,
+    ];
+  }
+
+  getCapabilities(): ValidatorCapabilities {
+    return {
+      name: `${this.config.name} native staking`,
+      protocol: 'native',
+      network: this.config.network,
+      supportsPartialAmounts: true,
+      chainId: this.config.chainId,
+      contracts: [],
+    };
+  }
+
+  decode(unsignedTransaction: string): DecodeResult {
+    const decoded = this.decodeTransaction(unsignedTransaction);
+    if (!decoded.transaction) {
+      return {
+        decoded: null,
+        reason: `Failed to decode Substrate transaction: ${decoded.error}`,
+      };
+    }
+    return { decoded: { calls: decoded.transaction.calls } };
+  }
+
+  getMatchPath(_unsignedTransaction: string): string {
+    return 'substrate-call-match';
+  }
+
+  getSigner(unsignedTransaction: string): string | undefined {
+    const { transaction } = this.decodeTransaction(unsignedTransaction);
+    return transaction?.signer;
+  }
+
+  // Signer payloads name the chain by its genesis hash
+  getChainId(unsignedTransaction: string): string | undefined {
+    const { transaction } = this.decodeTransaction(unsignedTransaction);
+    const genesisHash = transaction?.genesisHash;
+    if (!isDefined(genesisHash)) return undefined;
+    return genesisHash === this.config.genesisHash
+      ? this.config.chainId
+      : genesisHash;
+  }
+
+  getMismatchCode(unsignedTransaction: string): ReasonCode | undefined {
+    const { notAllowed } = this.decodeTransaction(unsignedTransaction);
+    return notAllowed ? 'PALLET_NOT_ALLOWED' : undefined;
+  }
+
+  // The same account can be written with any network's SS58 prefix
+  isSameAddress(a: string, b: string): boolean {
+    const accountA = decodeSs58(a);
+    const accountB = decodeSs58(b);
+    if (!accountA || !accountB) return a === b;
+    return accountA.accountId.equals(accountB.accountId);
+  }
+
+  isValidAddress(address: string): boolean {
+    return decodeSs58(address) !== null;
+  }
+
+  getAmount(unsignedTransaction: string): TransactionAmount | undefined {
+    const { transaction } = this.decodeTransaction(unsignedTransaction);
+    const amounts = (transaction?.calls ?? []).flatMap(({ method, args }) =>
+      AMOUNT_CALLS.includes(method) && typeof args.value === 'string'
+        ? [BigInt(args.value)]
+        : [],
+    );
+    if (amounts.length === 0) return undefined;
+
+    return toTransactionAmount(
+      'native',
+      amounts.reduce((total, amount) => total + amount),
+      this.config,
+    );
+  }
+
+  getStakeAmount(amount: bigint): TransactionAmount | undefined {
+    return toTransactionAmount('native', amount, this.config);
+  }
+
+  validate(
+    unsignedTransaction: string,
+    transactionType: TransactionType,
+    userAddress: string,
+    args?: ActionArguments,
+    _context?: ValidationContext,
+  ): ValidationResult {
+    const decoded = this.decodeTransaction(unsignedTransaction);
+    if (!decoded.transaction) {
+      return this.blocked('Failed to decode Substrate transaction', {
+        error: decoded.error,
+      });
+    }
+
+    const { calls } = decoded.transaction;
+    const expectedCalls = TRANSACTION_CALLS[transactionType];
+    if (!isDefined(expectedCalls)) {
+      return this.blocked('Unsupported transaction type', {
+        transactionType,
+      });
+    }
+
+    if (!calls.some(({ method }) => expectedCalls.required.includes(method))) {
+      return this.blocked('Transaction is missing its staking call', {
+        expected: expectedCalls.required,
+        actual: calls.map(({ method }) => method),
+      });
+    }
+
+    const expectedValidators = this.getExpectedValidators(args);
+
+    for (const [callIndex, call] of calls.entries()) {
+      if (!expectedCalls.allowed.includes(call.method)) {
+        return this.blocked('Unexpected call', {
+          callIndex,
+          expected: expectedCalls.allowed,
+          actual: `${call.pallet}.${call.method}`,
+        });
+      }
+
+      const callErr = this.validateCall(
+        call,
+        callIndex,
+        userAddress,
+        expectedValidators,
+      );
+      if (callErr) return callErr;
+    }
+
+    return {
+      ...this.safe(),
+      decoded: { calls },
+    };
+  }
+
+  private validateCall(
+    call: DecodedCall,
+    callIndex: number,
+    userAddress: string,
+    expectedValidators: string[] | null,
+  ): ValidationResult | null {
+    const { value, payee, payeeAccount, targets } = call.args;
+
+    if (
+      AMOUNT_CALLS.includes(call.method) &&
+      (typeof value !== 'string' || !/^[1-9][0-9]*$/.test(value))
+    ) {
+      return this.blocked('Invalid amount', { callIndex, actual: value });
+    }
+
+    // Rewards may be restaked or paid to the user, but not to anyone else
+    if (
+      isDefined(payee) &&
+      payee !== 'Staked' &&
+      payee !== 'Stash' &&
+      !(
+        payee === 'Account' &&
+        typeof payeeAccount === 'string' &&
+        this.isSameAddress(payeeAccount, userAddress)
+      )
+    ) {
+      return this.blocked('Rewards are not paid to the user', {
+        callIndex,
+        expected: userAddress,
+        actual: payeeAccount ?? payee,
+      });
+    }
+
+    if (call.method !== 'nominate') return null;
+
+    const nominated = targets as string[];
+    if (
+      nominated.length === 0 ||
+      nominated.length > this.config.maxNominations
+    ) {
+      return this.blocked('Invalid number of nominations', {
+        callIndex,
+        max: this.config.maxNominations,
+        actual: nominated.length,
+      });
+    }
+
+    if (new Set(nominated).size !== nominated.length) {
+      return this.blocked('Duplicate nomination', { callIndex });
+    }
+
+    const unexpected = nominated.filter(
+      (target) =>
+        expectedValidators !== null &&
+        !expectedValidators.some((validator) =>
+          this.isSameAddress(validator, target),
+        ),
+    );
+    if (unexpected.length > 0) {
+      return this.blocked('Nominates an unexpected validator', {
+        callIndex,
+        expected: expectedValidators,
+        actual: unexpected,
+      });
+    }
+
+    return null;
+  }
+
+  private getExpectedValidators(args?: ActionArguments): string[] | null {
+    if (isNullOrUndefined(args)) return null;
+    if (
+      isDefined(args.validatorAddresses) &&
+      args.validatorAddresses.length > 0
+    ) {
+      return args.validatorAddresses;
+    }
+    if (isNonEmptyString(args.validatorAddress)) {
+      return [args.validatorAddress];
+    }
+    return null;
+  }
+
+  private decodeTransaction(unsignedTransaction: string): {
+    transaction?: SubstrateTransaction;
+    error?: string;
+    notAllowed?: boolean;
+  } {
+    try {
+      return {
+        transaction: decodeSubstrateTransaction(
+          unsignedTransaction,
+          this.config.runtime,
+          this.config.ss58Prefix,
+        ),
+      };
+    } catch (error) {
+      return {
+        error: error instanceof Error ? error.message : String(error),
+        notAllowed: error instanceof PalletNotAllowedError,
+      };
+    }
+  }
+}
@@ -0,0 +1,332 @@
+import { createHash } from 'node:crypto';
+import { ethers } from 'ethers';
+import { DecodedCall } from '../../types';
+import { isNonEmptyString } from '../../utils/validation';
+
+/**
+ * Call indices of the runtime Shield decodes against. Pallet indices are
+ * fixed per chain, call indices by the pallet's code; both are read from
+ * the runtime metadata, e.g. api.tx.staking.bond.callIndex in polkadot.js.
+ */
+export interface SubstrateRuntime {
+  stakingPallet: number;
+  utilityPallet: number;
+  // Signed extension data of signed extrinsics, in order
+  signedExtra: Array<'era' | 'nonce' | 'tip' | 'metadataHashMode'>;
+}
+
+export interface SubstrateTransaction {
+  signer?: string; // SS58, when the transaction names it
+  genesisHash?: string; // Only present in signer payloads
+  calls: DecodedCall[]; // Batches flattened, in execution order
+}
+
+// pallet_staking call indices, stable across Substrate releases
+const STAKING_CALLS: Record<number, string> = {
+  0: 'bond',
+  1: 'bond_extra',
+  2: 'unbond',
+  3: 'withdraw_unbonded',
+  5: 'nominate',
+  6: 'chill',
+  7: 'set_payee',
+  19: 'rebond',
+};
+
+// pallet_utility calls that dispatch a list of calls
+const UTILITY_CALLS: Record<number, string> = {
+  0: 'batch',
+  2: 'batch_all',
+  4: 'force_batch',
+};
+
+// RewardDestination variants, in index order
+const REWARD_DESTINATIONS = [
+  'Staked',
+  'Stash',
+  'Controller',
+  'Account',
+  'None',
+];
+
+// Batches of batches are legal, but staking never needs more than one level
+const MAX_BATCH_DEPTH = 2;
+
+/**
+ * Thrown for calls into pallets other than staking and utility, whose
+ * arguments Shield cannot decode and whose effects it does not validate.
+ */
+export class PalletNotAllowedError extends Error {
+  constructor(readonly pallet: number) {
+    super(`Calls to pallet ${pallet} are not allowed`);
+  }
+}
+
+/**
+ * Decodes a Substrate transaction given as a polkadot.js signer payload
+ * ({ address, genesisHash, method, ... }) or as a hex-encoded extrinsic,
+ * signed or unsigned.
+ */
+export function decodeSubstrateTransaction(
+  encoded: string,
+  runtime: SubstrateRuntime,
+  ss58Prefix: number,
+): SubstrateTransaction {
+  const trimmed = encoded.trim();
+  if (trimmed.startsWith('{')) {
+    const payload: unknown = JSON.parse(trimmed);
+    if (
+      typeof payload !== 'object' ||
+      payload === null ||
+      !('method' in payload) ||
+      !isNonEmptyString(payload.method)
+    ) {
+      throw new Error('Signer payload has no method');
+    }
+    const { address, genesisHash } = payload as Record<string, unknown>;
+    const reader = new ScaleReader(hexToBytes(payload.method));
+    const calls = decodeCall(reader, runtime, ss58Prefix, 0);
+    reader.assertDone();
+    return {
+      signer: isNonEmptyString(address) ? address : undefined,
+      genesisHash: isNonEmptyString(genesisHash)
+        ? genesisHash.toLowerCase()
+        : undefined,
+      calls,
+    };
+  }
+
+  const reader = new ScaleReader(hexToBytes(trimmed));
+  const length = reader.compact();
+  if (length !== BigInt(reader.remaining())) {
+    throw new Error('Extrinsic length prefix does not match its data');
+  }
+
+  const version = reader.byte();
+  if ((version & 0x7f) !== 4) {
+    throw new Error(`Unsupported extrinsic version ${version & 0x7f}`);
+  }
+
+  let signer: string | undefined;
+  if (version & 0x80) {
+    signer = encodeSs58(reader.multiAddress(), ss58Prefix);
+    reader.signature();
+    for (const extra of runtime.signedExtra) {
+      if (extra === 'era') reader.era();
+      else if (extra === 'metadataHashMode') reader.byte();
+      else reader.compact();
+    }
+  }
+
+  const calls = decodeCall(reader, runtime, ss58Prefix, 0);
+  reader.assertDone();
+  return { signer, calls };
+}
+
+function decodeCall(
+  reader: ScaleReader,
+  runtime: SubstrateRuntime,
+  ss58Prefix: number,
+  depth: number,
+): DecodedCall[] {
+  const pallet = reader.byte();
+  const index = reader.byte();
+
+  if (pallet === runtime.utilityPallet) {
+    const method = UTILITY_CALLS[index];
+    if (method === undefined) {
+      throw new Error(`Unsupported utility call ${index}`);
+    }
+    if (depth >= MAX_BATCH_DEPTH) {
+      throw new Error('Batches are nested too deeply');
+    }
+    const count = Number(reader.compact());
+    const calls: DecodedCall[] = [];
+    for (let i = 0; i < count; i++) {
+      calls.push(...decodeCall(reader, runtime, ss58Prefix, depth + 1));
+    }
+    return calls;
+  }
+
+  if (pallet !== runtime.stakingPallet) {
+    throw new PalletNotAllowedError(pallet);
+  }
+
+  const method = STAKING_CALLS[index];
+  if (method === undefined) {
+    throw new Error(`Unsupported staking call ${index}`);
+  }
+
+  const account = () => encodeSs58(reader.multiAddress(), ss58Prefix);
+  const payee = (): Record<string, unknown> => {
+    const destination = REWARD_DESTINATIONS[reader.byte()];
+    if (destination === undefined) {
+      throw new Error('Invalid reward destination');
+    }
+    if (destination !== 'Account') return { payee: destination };
+    return {
+      payee: destination,
+      payeeAccount: encodeSs58(reader.bytes(32), ss58Prefix),
+    };
+  };
+
+  switch (method) {
+    case 'bond':
+      return [
+        {
+          pallet: 'staking',
+          method,
+          args: { value: reader.compact().toString(), ...payee() },
+        },
+      ];
+    case 'bond_extra':
+    case 'unbond':
+    case 'rebond':
+      return [
+        {
+          pallet: 'staking',
+          method,
+          args: { value: reader.compact().toString() },
+        },
+      ];
+    case 'withdraw_unbonded':
+      return [
+        {
+          pallet: 'staking',
+          method,
+          args: { numSlashingSpans: reader.u32() },
+        },
+      ];
+    case 'nominate': {
+      const count = Number(reader.compact());
+      const targets: string[] = [];
+      for (let i = 0; i < count; i++) targets.push(account());
+      return [{ pallet: 'staking', method, args: { targets } }];
+    }
+    case 'set_payee':
+      return [{ pallet: 'staking', method, args: payee() }];
+    default:
+      return [{ pallet: 'staking', method, args: {} }];
+  }
+}
+
+class ScaleReader {
+  private offset = 0;
+
+  constructor(private readonly data: Buffer) {}
+
+  remaining(): number {
+    return this.data.length - this.offset;
+  }
+
+  assertDone(): void {
+    if (this.remaining() !== 0) {
+      throw new Error('Unexpected trailing bytes');
+    }
+  }
+
+  byte(): number {
+    return this.bytes(1)[0];
+  }
+
+  bytes(length: number): Buffer {
+    if (this.offset + length > this.data.length) {
+      throw new Error('Truncated extrinsic');
+    }
+    const value = this.data.subarray(this.offset, this.offset + length);
+    this.offset += length;
+    return value;
+  }
+
+  u32(): number {
+    return this.bytes(4).readUInt32LE(0);
+  }
+
+  // SCALE compact integers: the low two bits of the first byte give the
+  // width, 1, 2 or 4 bytes, or a length-prefixed big integer
+  compact(): bigint {
+    const first = this.data[this.offset];
+    switch (first & 0b11) {
+      case 0:
+        return BigInt(this.byte() >> 2);
+      case 1:
+        return BigInt(this.bytes(2).readUInt16LE(0) >> 2);
+      case 2:
+        return BigInt(this.bytes(4).readUInt32LE(0) >>> 2);
+      default: {
+        const length = (this.byte() >> 2) + 4;
+        const bytes = Buffer.from(this.bytes(length)).reverse();
+        return BigInt('0x' + bytes.toString('hex'));
+      }
+    }
+  }
+
+  // MultiAddress::Id and ::Address32 are account IDs; the other variants
+  // cannot name a staking account
+  multiAddress(): Buffer {
+    const variant = this.byte();
+    if (variant !== 0 && variant !== 3) {
+      throw new Error(`Unsupported address variant ${variant}`);
+    }
+    return this.bytes(32);
+  }
+
+  // MultiSignature: Ed25519 and Sr25519 are 64 bytes, Ecdsa 65
+  signature(): void {
+    const variant = this.byte();
+    if (variant > 2) {
+      throw new Error(`Unsupported signature variant ${variant}`);
+    }
+    this.bytes(variant === 2 ? 65 : 64);
+  }
+
+  // Immortal transactions encode their era as 0x00, mortal ones in 2 bytes
+  era(): void {
+    if (this.byte() !== 0) this.byte();
+  }
+}
+
+function hexToBytes(hex: string): Buffer {
+  const digits = hex.replace(/^0x/, '');
+  if (!/^([0-9a-fA-F]{2})+$/.test(digits)) {
+    throw new Error('Transaction is neither JSON nor hex encoded');
+  }
+  return Buffer.from(digits, 'hex');
+}
+
+function ss58Checksum(data: Buffer): Buffer {
+  return createHash('blake2b512')
+    .update(Buffer.concat([Buffer.from('SS58PRE'), data]))
+    .digest()
+    .subarray(0, 2);
+}
+
+/**
+ * SS58 address of a 32-byte account ID. Only prefixes below 64, which
+ * encode in one byte, are supported; Polkadot's is 0 and Kusama's 2.
+ */
+export function encodeSs58(accountId: Buffer, prefix: number): string {
+  const data = Buffer.concat([Buffer.from([prefix]), accountId]);
+  return ethers.encodeBase58(Buffer.concat([data, ss58Checksum(data)]));
+}
+
+/**
+ * The account ID and prefix of a one-byte-prefix SS58 address, or null
+ * when the address is malformed or its checksum is wrong.
+ */
+export function decodeSs58(
+  address: string,
+): { accountId: Buffer; prefix: number } | null {
+  let bytes: Buffer;
+  try {
+    const hex = ethers.decodeBase58(address).toString(16).padStart(70, '0');
+    bytes = Buffer.from(hex, 'hex');
+  } catch {
+    return null;
+  }
+  if (bytes.length !== 35 || bytes[0] >= 64) return null;
+
+  const data = bytes.subarray(0, 33);
+  if (!ss58Checksum(data).equals(bytes.subarray(33))) return null;
+  return { accountId: data.subarray(1), prefix: data[0] };
+}
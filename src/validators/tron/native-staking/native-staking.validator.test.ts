@@ -48,12 +48,8 @@ describe('TronValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
-
-      const claimAttempt = result.details?.attempts?.find(
-        (a: any) => a.type === TransactionType.CLAIM_REWARDS,
-      );
-      expect(claimAttempt?.reason).toContain('does not match user address');
+      expect(result.reason).toBe('SENDER_MISMATCH');
+      expect(result.details?.expected).toBe(wrongUserAddress);
     });
 
     it('should validate real vote transaction', () => {
@@ -101,12 +97,8 @@ describe('TronValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
-
-      const voteAttempt = result.details?.attempts?.find(
-        (a: any) => a.type === TransactionType.VOTE,
-      );
-      expect(voteAttempt?.reason).toContain('does not match user address');
+      expect(result.reason).toBe('SENDER_MISMATCH');
+      expect(result.details?.expected).toBe(wrongUserAddress);
     });
 
     it('should validate real withdraw transaction', () => {
@@ -136,12 +128,8 @@ describe('TronValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
-
-      const withdrawAttempt = result.details?.attempts?.find(
-        (a: any) => a.type === TransactionType.WITHDRAW,
-      );
-      expect(withdrawAttempt?.reason).toContain('does not match user address');
+      expect(result.reason).toBe('SENDER_MISMATCH');
+      expect(result.details?.expected).toBe(wrongUserAddress);
     });
 
     it('should validate real freeze energy transaction', () => {
@@ -171,12 +159,8 @@ describe('TronValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
-
-      const freezeAttempt = result.details?.attempts?.find(
-        (a: any) => a.type === TransactionType.FREEZE_ENERGY,
-      );
-      expect(freezeAttempt?.reason).toContain('does not match user address');
+      expect(result.reason).toBe('SENDER_MISMATCH');
+      expect(result.details?.expected).toBe(wrongUserAddress);
     });
 
     it('should validate real unfreeze energy transaction', () => {
@@ -206,12 +190,8 @@ describe('TronValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
-
-      const unfreezeAttempt = result.details?.attempts?.find(
-        (a: any) => a.type === TransactionType.UNFREEZE_ENERGY,
-      );
-      expect(unfreezeAttempt?.reason).toContain('does not match user address');
+      expect(result.reason).toBe('SENDER_MISMATCH');
+      expect(result.details?.expected).toBe(wrongUserAddress);
     });
 
     it('should validate real unfreeze bandwidth transaction', () => {
@@ -241,12 +221,8 @@ describe('TronValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
-
-      const unfreezeAttempt = result.details?.attempts?.find(
-        (a: any) => a.type === TransactionType.UNFREEZE_BANDWIDTH,
-      );
-      expect(unfreezeAttempt?.reason).toContain('does not match user address');
+      expect(result.reason).toBe('SENDER_MISMATCH');
+      expect(result.details?.expected).toBe(wrongUserAddress);
     });
 
     it('should validate real freeze bandwidth transaction', () => {
@@ -276,12 +252,8 @@ describe('TronValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
-
-      const freezeAttempt = result.details?.attempts?.find(
-        (a: any) => a.type === TransactionType.FREEZE_BANDWIDTH,
-      );
-      expect(freezeAttempt?.reason).toContain('does not match user address');
+      expect(result.reason).toBe('SENDER_MISMATCH');
+      expect(result.details?.expected).toBe(wrongUserAddress);
     });
 
     it('should validate real legacy unfreeze bandwidth transaction', () => {
@@ -313,14 +285,8 @@ describe('TronValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
-
-      const legacyUnfreezeAttempt = result.details?.attempts?.find(
-        (a: any) => a.type === TransactionType.UNFREEZE_LEGACY_BANDWIDTH,
-      );
-      expect(legacyUnfreezeAttempt?.reason).toContain(
-        'does not match user address',
-      );
+      expect(result.reason).toBe('SENDER_MISMATCH');
+      expect(result.details?.expected).toBe(wrongUserAddress);
     });
 
     it('should validate synthetic undelegate bandwidth transaction', () => {
@@ -506,14 +472,8 @@ describe('TronValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
-
-      const undelegateAttempt = result.details?.attempts?.find(
-        (a: any) => a.type === TransactionType.UNDELEGATE_BANDWIDTH,
-      );
-      expect(undelegateAttempt?.reason).toContain(
-        'does not match user address',
-      );
+      expect(result.reason).toBe('SENDER_MISMATCH');
+      expect(result.details?.expected).toBe(wrongUserAddress);
     });
 
     it('should reject synthetic undelegate energy transaction with wrong user address', () => {
@@ -551,14 +511,151 @@ describe('TronValidator via Shield', () => {
       });
 
       expect(result.isValid).toBe(false);
-      expect(result.reason).toContain('No matching operation pattern found');
+      expect(result.reason).toBe('SENDER_MISMATCH');
+      expect(result.details?.expected).toBe(wrongUserAddress);
+    });
+  });
 
-      const undelegateAttempt = result.details?.attempts?.find(
-        (a: any) => a.type === TransactionType.UNDELEGATE_ENERGY,
-      );
-      expect(undelegateAttempt?.reason).toContain(
-        'does not match user address',
-      );
+  describe('validate - Protobuf hex and delegation', () => {
+    // raw_data_hex of the real claim rewards transaction above
+    const claimRawDataHex =
+      '0a0219322208b9d40780530e893040a0f7d89d9c335a53080d124f0a34747970652e676f6f676c65617069732e636f6d2f70726f746f636f6c2e576974686472617742616c616e6365436f6e747261637412170a154143edd6b0921ac39ae61605085a93dd5140e1c7cf70e0fac29d9c33';
+    const delegateEnergyHex =
+      '0a02e97922089b56037474cd829d40f895b7ae9b335a710839126d0a35747970652e676f6f676c65617069732e636f6d2f70726f746f636f6c2e44656c65676174655265736f75726365436f6e747261637412340a1541cb4a2c67a37fa22a80a10988dd954d9f2c834cf710011880897a221541a4ce68cfcdd27884bde52cec653354048e0aa98970b899a1ae9b33';
+    const delegateBandwidthHex =
+      '0a02e97922089b56037474cd829d40f895b7ae9b335a6f0839126b0a35747970652e676f6f676c65617069732e636f6d2f70726f746f636f6c2e44656c65676174655265736f75726365436f6e747261637412320a1541cb4a2c67a37fa22a80a10988dd954d9f2c834cf71880897a221541a4ce68cfcdd27884bde52cec653354048e0aa98970b899a1ae9b33';
+    const transferHex =
+      '0a02e97922089b56037474cd829d40f895b7ae9b335a67080112630a2d747970652e676f6f676c65617069732e636f6d2f70726f746f636f6c2e5472616e73666572436f6e747261637412320a1541cb4a2c67a37fa22a80a10988dd954d9f2c834cf7121541a4ce68cfcdd27884bde52cec653354048e0aa98918c0843d70b899a1ae9b33';
+    const ownerAddress = 'TUW72fPVWwTQzWWn77jRbrS5CmXD7j4e2a';
+
+    const delegateTx = (value: Record<string, unknown>) =>
+      JSON.stringify({
+        raw_data: {
+          contract: [
+            {
+              parameter: {
+                value: {
+                  owner_address: '41cb4a2c67a37fa22a80a10988dd954d9f2c834cf7',
+                  receiver_address:
+                    '41a4ce68cfcdd27884bde52cec653354048e0aa989',
+                  balance: 2000000,
+                  ...value,
+                },
+                type_url:
+                  'type.googleapis.com/protocol.DelegateResourceContract',
+              },
+              type: 'DelegateResourceContract',
+            },
+          ],
+          ref_block_bytes: 'e979',
+          ref_block_hash: '9b56037474cd829d',
+          expiration: 1759691787000,
+          timestamp: 1759691427000,
+        },
+      });
+
+    it('should validate a transaction given as raw_data_hex', () => {
+      const cases = [claimRawDataHex, '0x' + claimRawDataHex];
+      for (const unsignedTransaction of cases) {
+        const result = shield.validate({
+          yieldId,
+          unsignedTransaction,
+          userAddress: 'TGAPCEGDzbHLF8RzmyAWJZiY9iELhXKYRm',
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.detectedType).toBe(TransactionType.CLAIM_REWARDS);
+      }
+    });
+
+    it('should check the owner of a raw_data_hex transaction', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: claimRawDataHex,
+        userAddress: ownerAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('SENDER_MISMATCH');
+    });
+
+    it('should detect the resource of delegate transactions', () => {
+      const cases = [
+        [delegateEnergyHex, TransactionType.DELEGATE_ENERGY],
+        [delegateBandwidthHex, TransactionType.DELEGATE_BANDWIDTH],
+        [delegateTx({ resource: 'ENERGY' }), TransactionType.DELEGATE_ENERGY],
+        [delegateTx({}), TransactionType.DELEGATE_BANDWIDTH],
+      ];
+      for (const [unsignedTransaction, detectedType] of cases) {
+        const result = shield.validate({
+          yieldId,
+          unsignedTransaction,
+          userAddress: ownerAddress,
+        });
+
+        expect(result.isValid).toBe(true);
+        expect(result.detectedType).toBe(detectedType);
+      }
+    });
+
+    it('should reject delegating resources to the owner', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: delegateTx({
+          receiver_address: '41cb4a2c67a37fa22a80a10988dd954d9f2c834cf7',
+        }),
+        userAddress: ownerAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('NO_MATCHING_PATTERN');
+    });
+
+    it('should reject delegations without a valid receiver', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: delegateTx({ receiver_address: 'not-an-address' }),
+        userAddress: ownerAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('NO_MATCHING_PATTERN');
+    });
+
+    it('should flag contract types no staking transaction uses', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: transferHex,
+        userAddress: ownerAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('CONTRACT_TYPE_NOT_SUPPORTED');
+    });
+
+    it('should reject transactions with more than one contract', () => {
+      const tx = JSON.parse(delegateTx({}));
+      tx.raw_data.contract.push(tx.raw_data.contract[0]);
+
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: JSON.stringify(tx),
+        userAddress: ownerAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('MALFORMED_TRANSACTION');
+    });
+
+    it('should reject input that is neither JSON nor hex', () => {
+      const result = shield.validate({
+        yieldId,
+        unsignedTransaction: 'not a transaction',
+        userAddress: ownerAddress,
+      });
+
+      expect(result.isValid).toBe(false);
+      expect(result.reasonCode).toBe('NO_MATCHING_PATTERN');
     });
   });
 
@@ -933,7 +1030,7 @@ describe('TronValidator via Shield', () => {
       const result = shield.validate({
         yieldId,
         unsignedTransaction: updatePermissionTx,
-        userAddress: 'TWrNNdLHhMYM8oUL5xNZH5GyBHnk5UgBKJ',
+        userAddress: 'TXRqZPuvcZMGwzZyJpekueL6nPa6uPPNTW',
       });
 
       expect(result.isValid).toBe(false);
@@ -943,7 +1040,7 @@ describe('TronValidator via Shield', () => {
       );
     });
 
-    it('should block delegate resource transaction when expecting undelegate', async () => {
+    it('should detect delegate resource transactions as delegations', async () => {
       await delay(500);
 
       const fromAddress = 'TUxd6v64YTWkfpFpNDdtgc5Ps4SfGxwizT';
@@ -963,10 +1060,8 @@ describe('TronValidator via Shield', () => {
         userAddress: fromAddress,
       });
 
-      expect(result.isValid).toBe(false);
-      expect(result.reason).toMatch(
-        /No matching operation pattern found|Transaction may be malicious or corrupted/,
-      );
+      expect(result.isValid).toBe(true);
+      expect(result.detectedType).toBe(TransactionType.DELEGATE_BANDWIDTH);
     });
 
     it('should block exchange transaction', async () => {
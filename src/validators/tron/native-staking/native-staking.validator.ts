@@ -1,10 +1,13 @@
 import { TronWeb } from 'tronweb';
 import {
   ActionArguments,
+  ClaimCall,
+  ReasonCode,
   TransactionType,
   TronResourceType,
   ValidationContext,
   ValidationResult,
+  ValidatorCapabilities,
 } from '../../../types';
 import {
   isDefined,
@@ -12,24 +15,53 @@ import {
   isNullOrUndefined,
 } from '../../../utils/validation';
 import { BaseValidator } from '../../base.validator';
+import { parseTronTransaction } from '../tx-decoder';
+
+// Contract type of each transaction type's transactions. Contracts of any
+// other type are never staking transactions.
+const CONTRACT_TYPES = {
+  VoteWitnessContract: [TransactionType.VOTE],
+  FreezeBalanceV2Contract: [
+    TransactionType.FREEZE_BANDWIDTH,
+    TransactionType.FREEZE_ENERGY,
+  ],
+  UnfreezeBalanceV2Contract: [
+    TransactionType.UNFREEZE_BANDWIDTH,
+    TransactionType.UNFREEZE_ENERGY,
+  ],
+  DelegateResourceContract: [
+    TransactionType.DELEGATE_BANDWIDTH,
+    TransactionType.DELEGATE_ENERGY,
+  ],
+  UnDelegateResourceContract: [
+    TransactionType.UNDELEGATE_BANDWIDTH,
+    TransactionType.UNDELEGATE_ENERGY,
+  ],
+  UnfreezeBalanceContract: [
+    TransactionType.UNFREEZE_LEGACY_BANDWIDTH,
+    TransactionType.UNFREEZE_LEGACY_ENERGY,
+  ],
+  WithdrawExpireUnfreezeContract: [TransactionType.WITHDRAW],
+  WithdrawBalanceContract: [TransactionType.CLAIM_REWARDS],
+};
 
 export class TronValidator extends BaseValidator {
   private readonly MAXIMUM_VALIDATOR_COUNT = 30;
 
   getSupportedTransactionTypes(): TransactionType[] {
-    return [
-      TransactionType.VOTE,
-      TransactionType.FREEZE_BANDWIDTH,
-      TransactionType.FREEZE_ENERGY,
-      TransactionType.UNFREEZE_BANDWIDTH,
-      TransactionType.UNFREEZE_ENERGY,
-      TransactionType.UNDELEGATE_BANDWIDTH,
-      TransactionType.UNDELEGATE_ENERGY,
-      TransactionType.UNFREEZE_LEGACY_BANDWIDTH,
-      TransactionType.UNFREEZE_LEGACY_ENERGY,
-      TransactionType.WITHDRAW,
-      TransactionType.CLAIM_REWARDS,
-    ];
+    return Object.values(CONTRACT_TYPES).flat();
+  }
+
+  // Staking is built into the protocol, so no contracts are involved
+  getCapabilities(): ValidatorCapabilities {
+    return {
+      name: 'Tron native staking',
+      protocol: 'native',
+      network: 'tron',
+      supportsPartialAmounts: true,
+      chainId: 'tron-mainnet',
+      contracts: [],
+    };
   }
 
   validate(
@@ -48,6 +80,64 @@ export class TronValidator extends BaseValidator {
     );
   }
 
+  getMatchPath(_unsignedTransaction: string): string {
+    return 'tron-contract-match';
+  }
+
+  getSigner(unsignedTransaction: string): string | undefined {
+    const decoded =
+      this.decodeTronTransaction<TronTransaction>(unsignedTransaction);
+    const ownerAddress =
+      decoded.transaction?.raw_data?.contract?.[0]?.parameter?.value
+        ?.owner_address;
+    return isNonEmptyString(ownerAddress) ? ownerAddress : undefined;
+  }
+
+  getMismatchCode(unsignedTransaction: string): ReasonCode | undefined {
+    const decoded =
+      this.decodeTronTransaction<TronTransaction>(unsignedTransaction);
+    const contractType = decoded.transaction?.raw_data?.contract?.[0]?.type;
+    return isNonEmptyString(contractType) && !(contractType in CONTRACT_TYPES)
+      ? 'CONTRACT_TYPE_NOT_SUPPORTED'
+      : undefined;
+  }
+
+  // WithdrawBalanceContract pays the rewards to its owner, the sender
+  getClaimCall(unsignedTransaction: string): ClaimCall | undefined {
+    const decoded =
+      this.decodeTronTransaction<TronTransaction>(unsignedTransaction);
+    const contractType = decoded.transaction?.raw_data?.contract?.[0]?.type;
+    return contractType === 'WithdrawBalanceContract'
+      ? { variant: contractType, recipient: null }
+      : undefined;
+  }
+
+  // The network executes only the first contract, and rejects transactions
+  // with more, so any others would be validated for nothing
+  getMalformedError(unsignedTransaction: string): string | undefined {
+    const decoded =
+      this.decodeTronTransaction<TronTransaction>(unsignedTransaction);
+    const contracts = decoded.transaction?.raw_data?.contract ?? [];
+    return contracts.length > 1
+      ? `Tron transactions carry one contract, found ${contracts.length}`
+      : undefined;
+  }
+
+  // Owner addresses are hex encoded, user addresses usually base58
+  isSameAddress(a: string, b: string): boolean {
+    if (!TronWeb.isAddress(a) || !TronWeb.isAddress(b)) return a === b;
+    return this.normalizeAddress(a) === this.normalizeAddress(b);
+  }
+
+  isValidAddress(address: string): boolean {
+    return TronWeb.isAddress(address);
+  }
+
+  // Base58, also for an address given as hex
+  normalizeAddress(address: string): string {
+    return TronWeb.address.fromHex(address);
+  }
+
   private _validate(
     transaction: string,
     transactionType: TransactionType,
@@ -82,6 +172,20 @@ export class TronValidator extends BaseValidator {
           TronResourceType.ENERGY,
         );
 
+      case TransactionType.DELEGATE_BANDWIDTH:
+        return this.validateDelegate(
+          transaction,
+          userAddress,
+          TronResourceType.BANDWIDTH,
+        );
+
+      case TransactionType.DELEGATE_ENERGY:
+        return this.validateDelegate(
+          transaction,
+          userAddress,
+          TronResourceType.ENERGY,
+        );
+
       case TransactionType.UNDELEGATE_BANDWIDTH:
         return this.validateUndelegate(
           transaction,
@@ -131,7 +235,7 @@ export class TronValidator extends BaseValidator {
     error?: string;
   } {
     try {
-      const tx = JSON.parse(transaction) as T;
+      const tx = parseTronTransaction(transaction) as T;
       return { isValid: true, transaction: tx };
     } catch (error) {
       return {
@@ -473,10 +577,6 @@ export class TronValidator extends BaseValidator {
     return this.safe();
   }
 
-  private normalizeAddress(address: string): string {
-    return TronWeb.address.fromHex(address);
-  }
-
   private ensureOwnerMatchesUser(
     ownerAddress: string | undefined | null,
     userAddress: string,
@@ -589,6 +689,98 @@ export class TronValidator extends BaseValidator {
     return { isValid: true };
   }
 
+  private validateDelegate(
+    transaction: string,
+    userAddress: string,
+    expectedResource: TronResourceType,
+  ): ValidationResult {
+    const decoded =
+      this.decodeTronTransaction<DelegateResourceTransaction>(transaction);
+
+    if (!decoded.isValid || isNullOrUndefined(decoded.transaction)) {
+      return this.blocked('Failed to decode Tron transaction', {
+        error: decoded.error,
+      });
+    }
+
+    const tx = decoded.transaction as DelegateResourceTransaction;
+
+    if (
+      isNullOrUndefined(tx.raw_data) ||
+      isNullOrUndefined(tx.raw_data.contract) ||
+      tx.raw_data.contract.length === 0
+    ) {
+      return this.blocked(
+        'Invalid transaction structure: missing contract data',
+      );
+    }
+
+    const contract = tx.raw_data.contract[0];
+
+    if (isNullOrUndefined(contract)) {
+      return this.blocked('Invalid transaction: missing contract');
+    }
+
+    if (contract.type !== 'DelegateResourceContract') {
+      return this.blocked('Invalid contract type for delegate transaction', {
+        expected: 'DelegateResourceContract',
+        actual: contract.type,
+      });
+    }
+
+    const contractValue = contract.parameter?.value;
+    if (isNullOrUndefined(contractValue)) {
+      return this.blocked(
+        'Invalid transaction: missing contract parameter value',
+      );
+    }
+
+    const ownerErr = this.ensureOwnerMatchesUser(
+      contractValue.owner_address,
+      userAddress,
+    );
+    if (ownerErr) return ownerErr;
+
+    if (!isDefined(contractValue.balance)) {
+      return this.blocked('Invalid transaction: missing balance');
+    }
+
+    if (!TronWeb.isAddress(contractValue.receiver_address)) {
+      return this.blocked('Invalid receiver address format', {
+        receiverAddress: contractValue.receiver_address,
+      });
+    }
+
+    // The network refuses delegations to the owner itself
+    if (
+      this.normalizeAddress(contractValue.receiver_address) ===
+      this.normalizeAddress(contractValue.owner_address)
+    ) {
+      return this.blocked('Cannot delegate resources to the owner address');
+    }
+
+    const actualResource = contractValue.resource || TronResourceType.BANDWIDTH;
+
+    if (
+      actualResource !== TronResourceType.BANDWIDTH &&
+      actualResource !== TronResourceType.ENERGY
+    ) {
+      return this.blocked('Invalid resource type', {
+        validTypes: [TronResourceType.BANDWIDTH, TronResourceType.ENERGY],
+        actual: actualResource,
+      });
+    }
+
+    if (actualResource !== expectedResource) {
+      return this.blocked('Resource type mismatch', {
+        expected: expectedResource,
+        actual: actualResource,
+      });
+    }
+
+    return this.safe();
+  }
+
   private validateUndelegate(
     transaction: string,
     userAddress: string,
@@ -766,6 +958,9 @@ type WithdrawTransaction = Awaited<
 type ClaimRewardsTransaction = Awaited<
   ReturnType<TronWeb['transactionBuilder']['withdrawBlockRewards']>
 >;
+type DelegateResourceTransaction = Awaited<
+  ReturnType<TronWeb['transactionBuilder']['delegateResource']>
+>;
 type UndelegateResourceTransaction = Awaited<
   ReturnType<TronWeb['transactionBuilder']['undelegateResource']>
 >;
@@ -777,6 +972,7 @@ type TronTransaction =
   | VoteTransaction
   | FreezeBalanceTransaction
   | UnfreezeBalanceTransaction
+  | DelegateResourceTransaction
   | UndelegateResourceTransaction
   | UnfreezeBalanceV1Transaction
   | WithdrawTransaction
@@ -0,0 +1,185 @@
+import {
+  ProtobufField,
+  asBytes,
+  bytesField,
+  readFields,
+  varintField,
+} from '../../utils/protobuf';
+
+// protocol.Transaction.Contract.ContractType values of the contracts Shield
+// validates. Others are named after their parameter's type URL.
+const CONTRACT_TYPES: Record<number, string> = {
+  1: 'TransferContract',
+  4: 'VoteWitnessContract',
+  11: 'FreezeBalanceContract',
+  12: 'UnfreezeBalanceContract',
+  13: 'WithdrawBalanceContract',
+  31: 'TriggerSmartContract',
+  54: 'FreezeBalanceV2Contract',
+  55: 'UnfreezeBalanceV2Contract',
+  56: 'WithdrawExpireUnfreezeContract',
+  57: 'DelegateResourceContract',
+  58: 'UnDelegateResourceContract',
+  59: 'CancelAllUnfreezeV2Contract',
+};
+
+const RESOURCE_CODES = ['BANDWIDTH', 'ENERGY', 'TRON_POWER'];
+
+type FieldKind = 'address' | 'int' | 'bool' | 'resource' | 'votes';
+
+// Parameter fields by contract type, as named in TronWeb JSON. Contracts
+// not listed here only have their owner_address decoded.
+const PARAMETER_FIELDS: Record<string, Record<number, [string, FieldKind]>> =
+  {
+    VoteWitnessContract: {
+      1: ['owner_address', 'address'],
+      2: ['votes', 'votes'],
+      3: ['support', 'bool'],
+    },
+    FreezeBalanceContract: {
+      1: ['owner_address', 'address'],
+      2: ['frozen_balance', 'int'],
+      3: ['frozen_duration', 'int'],
+      10: ['resource', 'resource'],
+      15: ['receiver_address', 'address'],
+    },
+    UnfreezeBalanceContract: {
+      1: ['owner_address', 'address'],
+      10: ['resource', 'resource'],
+      15: ['receiver_address', 'address'],
+    },
+    FreezeBalanceV2Contract: {
+      1: ['owner_address', 'address'],
+      2: ['frozen_balance', 'int'],
+      3: ['resource', 'resource'],
+    },
+    UnfreezeBalanceV2Contract: {
+      1: ['owner_address', 'address'],
+      2: ['unfreeze_balance', 'int'],
+      3: ['resource', 'resource'],
+    },
+    DelegateResourceContract: {
+      1: ['owner_address', 'address'],
+      2: ['resource', 'resource'],
+      3: ['balance', 'int'],
+      4: ['receiver_address', 'address'],
+      5: ['lock', 'bool'],
+      6: ['lock_period', 'int'],
+    },
+    UnDelegateResourceContract: {
+      1: ['owner_address', 'address'],
+      2: ['resource', 'resource'],
+      3: ['balance', 'int'],
+      4: ['receiver_address', 'address'],
+    },
+  };
+
+/**
+ * Decodes a Tron transaction given as TronWeb JSON ({ raw_data, ... }) or as
+ * the hex of its protobuf raw_data, as TronWeb reports it in raw_data_hex.
+ * Hex is returned in the TronWeb JSON shape, with addresses as hex.
+ */
+export function parseTronTransaction(encoded: string): unknown {
+  const trimmed = encoded.trim();
+  if (trimmed.startsWith('{')) {
+    return JSON.parse(trimmed);
+  }
+
+  const hex = trimmed.replace(/^0x/, '');
+  if (!/^([0-9a-fA-F]{2})+$/.test(hex)) {
+    throw new Error('Transaction is neither JSON nor hex encoded');
+  }
+  return {
+    raw_data: decodeRawData(Buffer.from(hex, 'hex')),
+    raw_data_hex: hex.toLowerCase(),
+  };
+}
+
+function decodeRawData(bytes: Buffer): Record<string, unknown> {
+  const fields = readFields(bytes);
+  const rawData: Record<string, unknown> = {
+    contract: fields
+      .filter((field) => field.field === 11)
+      .map((field) => decodeContract(asBytes(field))),
+  };
+
+  const refBlockBytes = bytesField(fields, 1);
+  if (refBlockBytes) rawData.ref_block_bytes = refBlockBytes.toString('hex');
+  const refBlockHash = bytesField(fields, 4);
+  if (refBlockHash) rawData.ref_block_hash = refBlockHash.toString('hex');
+  for (const [field, name] of [
+    [8, 'expiration'],
+    [14, 'timestamp'],
+    [18, 'fee_limit'],
+  ] as const) {
+    const value = varintField(fields, field);
+    if (value !== null) rawData[name] = toNumber(value);
+  }
+  return rawData;
+}
+
+function decodeContract(bytes: Buffer): Record<string, unknown> {
+  const fields = readFields(bytes);
+  const any = readFields(bytesField(fields, 2) ?? Buffer.alloc(0));
+  const typeUrl = bytesField(any, 1)?.toString('utf8') ?? '';
+  const typeCode = varintField(fields, 1) ?? 0n;
+  const type =
+    CONTRACT_TYPES[Number(typeCode)] ??
+    (typeUrl !== '' ? typeUrl.replace(/^.*\./, '') : String(typeCode));
+
+  const contract: Record<string, unknown> = {
+    parameter: {
+      value: decodeParameter(type, bytesField(any, 2) ?? Buffer.alloc(0)),
+      type_url: typeUrl,
+    },
+    type,
+  };
+  const permissionId = varintField(fields, 5);
+  if (permissionId !== null) contract.Permission_id = toNumber(permissionId);
+  return contract;
+}
+
+function decodeParameter(
+  type: string,
+  bytes: Buffer,
+): Record<string, unknown> {
+  const spec = PARAMETER_FIELDS[type] ?? { 1: ['owner_address', 'address'] };
+  const value: Record<string, unknown> = {};
+
+  for (const field of readFields(bytes)) {
+    const entry = spec[field.field];
+    if (!entry) continue;
+    const [name, kind] = entry;
+    if (kind === 'votes') {
+      value.votes = [...((value.votes as unknown[]) ?? []), decodeVote(field)];
+    } else {
+      value[name] = decodeValue(field, kind);
+    }
+  }
+  return value;
+}
+
+function decodeVote(field: ProtobufField): Record<string, unknown> {
+  const vote = readFields(asBytes(field));
+  return {
+    vote_address: bytesField(vote, 1)?.toString('hex'),
+    vote_count: toNumber(varintField(vote, 2) ?? 0n),
+  };
+}
+
+function decodeValue(field: ProtobufField, kind: FieldKind): unknown {
+  if (kind === 'address') return asBytes(field).toString('hex');
+  if (typeof field.value !== 'bigint') {
+    throw new Error(`Expected varint protobuf field ${field.field}`);
+  }
+  if (kind === 'bool') return field.value !== 0n;
+  if (kind === 'resource') {
+    return RESOURCE_CODES[Number(field.value)] ?? String(field.value);
+  }
+  return toNumber(field.value);
+}
+
+// int64 fields are varints of their two's complement
+function toNumber(value: bigint): number {
+  return Number(BigInt.asIntN(64, value));
+}
@@ -0,0 +1,51 @@
+import { VersionInfo } from './types';
+import { validatorRegistry } from './validators';
+import {
+  getEmbeddedRegistryInfo,
+  getRegistryOverrideHash,
+  VaultRegistryOverride,
+} from './validators/evm/erc4626';
+
+// Injected at build time by scripts/build-info.js. Running from source,
+// e.g. under jest, leaves them undefined.
+declare const __SHIELD_VERSION__: string | undefined;
+declare const __SHIELD_GIT_COMMIT__: string | undefined;
+declare const __SHIELD_BUILD_DATE__: string | undefined;
+
+// Request apiVersion values accepted by the JSON interface, oldest first
+export const SUPPORTED_API_VERSIONS = ['1.0'];
+
+// Supported versions that a future release will stop accepting
+export const DEPRECATED_API_VERSIONS: string[] = [];
+
+/**
+ * Build and registry information. yieldCount defaults to the embedded
+ * registry's; a Shield with a registry override passes its own.
+ */
+export function getVersionInfo(
+  yieldCount = validatorRegistry.size,
+  registryOverride?: VaultRegistryOverride,
+): VersionInfo {
+  return {
+    version:
+      typeof __SHIELD_VERSION__ === 'string' ? __SHIELD_VERSION__ : 'unknown',
+    gitCommit:
+      typeof __SHIELD_GIT_COMMIT__ === 'string'
+        ? __SHIELD_GIT_COMMIT__
+        : 'unknown',
+    buildDate:
+      typeof __SHIELD_BUILD_DATE__ === 'string'
+        ? __SHIELD_BUILD_DATE__
+        : 'unknown',
+    supportedApiVersions: [...SUPPORTED_API_VERSIONS],
+    deprecatedApiVersions: [...DEPRECATED_API_VERSIONS],
+    registry: {
+      ...getEmbeddedRegistryInfo(),
+      yieldCount,
+      overrideActive: registryOverride !== undefined,
+      ...(registryOverride !== undefined && {
+        overrideHash: getRegistryOverrideHash(registryOverride),
+      }),
+    },
+  };
+}